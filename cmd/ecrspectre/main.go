@@ -16,6 +16,6 @@ var (
 func main() {
 	if err := commands.Execute(version, commit, date); err != nil {
 		slog.Warn("Command failed", "error", err)
-		os.Exit(1)
+		os.Exit(commands.ExitCode(err))
 	}
 }
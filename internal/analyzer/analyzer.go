@@ -1,35 +1,379 @@
 package analyzer
 
 import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/pricing"
 	"github.com/ppiankov/ecrspectre/internal/registry"
 )
 
-// Analyze filters findings by minimum cost and computes aggregated summary statistics.
+// Analyze filters findings by minimum cost and disabled checks and computes
+// aggregated summary statistics. Vulnerability, quota, signature, SBOM and build age
+// findings carry no storage cost and are never filtered by cost. With cfg.RollupLongTail, the
+// findings under the minimum cost are rolled up into one LONG_TAIL_WASTE
+// finding per repository instead of being dropped. Findings outside the
+// OlderThan/NewerThan window, those matching an active suppression, and
+// waste findings on images a lifecycle policy will expire soon are dropped
+// before any of this and counted separately. With a daily or annual
+// cfg.CostPeriod, finding and summary waste is also converted to that period.
+// Reported findings covered by an active migration window are tagged
+// suppressed_by_window.
+// The findings are then ordered by cfg.Sort and cut to cfg.Top.
 func Analyze(result *registry.ScanResult, cfg AnalyzerConfig) *AnalysisResult {
-	var filtered []registry.Finding
+	now := cfg.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	statuses := make([]SuppressionStatus, len(cfg.Suppressions))
+	for i, s := range cfg.Suppressions {
+		statuses[i] = SuppressionStatus{
+			ID:       string(s.ID),
+			Resource: s.Resource,
+			Owner:    s.Owner,
+			Reason:   s.Reason,
+			Expires:  s.Expires.Format(time.DateOnly),
+			Expired:  s.Expired(now),
+		}
+	}
+
+	var filtered, below []registry.Finding
+	var suppressedCount, ageFiltered, selfResolving int
+	var suppressedWaste, selfResolvingWaste float64
+	suppressedCounted, selfResolvingCounted := wasteCounter{}, wasteCounter{}
 	for _, f := range result.Findings {
-		if f.EstimatedMonthlyWaste >= cfg.MinMonthlyCost {
+		if cfg.DisabledChecks[f.ID] {
+			continue
+		}
+		if !inAgeWindow(f, cfg, now) {
+			ageFiltered++
+			continue
+		}
+		if i := matchSuppression(cfg.Suppressions, statuses, f); i >= 0 {
+			statuses[i].Matched++
+			if !statuses[i].Expired {
+				suppressedCount++
+				suppressedWaste += suppressedCounted.add(f)
+				continue
+			}
+			if f.Metadata == nil {
+				f.Metadata = make(map[string]any)
+			}
+			f.Metadata[registry.MetadataSuppressionExpired] = statuses[i].Expires
+		}
+		if !isPosture(f.ID) && registry.IsSelfResolving(f) {
+			selfResolving++
+			selfResolvingWaste += selfResolvingCounted.add(f)
+			continue
+		}
+		if isPosture(f.ID) || f.EstimatedMonthlyWaste >= cfg.MinMonthlyCost {
 			filtered = append(filtered, f)
+		} else {
+			below = append(below, f)
 		}
 	}
+	if cfg.RollupLongTail && !cfg.DisabledChecks[registry.FindingLongTailWaste] {
+		var rollups []registry.Finding
+		rollups, below = rollupLongTail(below, cfg.MinMonthlyCost)
+		filtered = append(filtered, rollups...)
+	}
+	belowCount := len(below)
+	var belowWaste float64
+	belowCounted := wasteCounter{}
+	for _, f := range below {
+		belowWaste += belowCounted.add(f)
+	}
 
 	summary := Summary{
-		TotalResourcesScanned: result.ResourcesScanned,
-		TotalFindings:         len(filtered),
-		RepositoriesScanned:   result.RepositoriesScanned,
-		BySeverity:            make(map[string]int),
-		ByResourceType:        make(map[string]int),
+		TotalResourcesScanned:     result.ResourcesScanned,
+		TotalFindings:             len(filtered),
+		RepositoriesScanned:       result.RepositoriesScanned,
+		Coverage:                  result.Coverage,
+		FilteredFindingsCount:     belowCount,
+		FilteredWasteTotal:        belowWaste,
+		AgeFilteredFindings:       ageFiltered,
+		SelfResolvingFindings:     selfResolving,
+		SelfResolvingMonthlyWaste: selfResolvingWaste,
+		SuppressedFindings:        suppressedCount,
+		SuppressedMonthlyWaste:    suppressedWaste,
+		BySeverity:                make(map[string]int),
+		ByResourceType:            make(map[string]int),
 	}
 
-	for _, f := range filtered {
-		summary.TotalMonthlyWaste += f.EstimatedMonthlyWaste
+	if len(result.RepositoriesByProject) > 0 {
+		summary.ByProject = make(map[string]ProjectSummary, len(result.RepositoriesByProject))
+		for project, repos := range result.RepositoriesByProject {
+			summary.ByProject[project] = ProjectSummary{RepositoriesScanned: repos}
+		}
+	}
+
+	period := cfg.CostPeriod
+	if period == registry.CostPeriodMonth {
+		period = ""
+	}
+	summary.CostPeriod = period
+	counted := wasteCounter{}
+	if cfg.GroupBy != "" {
+		summary.GroupBy = cfg.GroupBy
+		summary.ByGroup = make(map[string]GroupSummary)
+	}
+	for i, f := range filtered {
+		if period != "" {
+			if f.Metadata == nil {
+				f.Metadata = make(map[string]any)
+				filtered[i].Metadata = f.Metadata
+			}
+			f.Metadata[registry.MetadataPeriodWaste] = period.FromMonthly(f.EstimatedMonthlyWaste)
+		}
+		waste := counted.add(f)
+		summary.TotalMonthlyWaste += waste
+		summary.OverlappingWaste += f.EstimatedMonthlyWaste - waste
 		summary.BySeverity[string(f.Severity)]++
 		summary.ByResourceType[string(f.ResourceType)]++
+		if cfg.Carbon {
+			addCarbon(&summary, f, waste, cfg.Provider)
+		}
+		if registry.IsInUse(f) {
+			summary.InUseFindings++
+			summary.InUseMonthlyWaste += waste
+		}
+		if summary.ByGroup != nil {
+			group := registry.GroupKey(f, cfg.GroupBy)
+			g := summary.ByGroup[group]
+			g.TotalFindings++
+			g.TotalMonthlyWaste += waste
+			if period != "" {
+				g.TotalPeriodWaste = period.FromMonthly(g.TotalMonthlyWaste)
+			}
+			summary.ByGroup[group] = g
+		}
+		if project, ok := f.Metadata[registry.MetadataProject].(string); ok && summary.ByProject != nil {
+			p := summary.ByProject[project]
+			p.TotalFindings++
+			p.TotalMonthlyWaste += waste
+			if period != "" {
+				p.TotalPeriodWaste = period.FromMonthly(p.TotalMonthlyWaste)
+			}
+			summary.ByProject[project] = p
+		}
+	}
+
+	if summary.WindowedFindings = registry.MarkWindowed(filtered, cfg.Windows, now); summary.WindowedFindings > 0 {
+		summary.MigrationWindows = windowNames(filtered)
+	}
+
+	if period != "" {
+		summary.TotalPeriodWaste = period.FromMonthly(summary.TotalMonthlyWaste)
 	}
 
-	return &AnalysisResult{
+	scorer := cfg.Scorer
+	if scorer == nil {
+		scorer = DefaultScoreModel
+	}
+	for i := range filtered {
+		filtered[i].Score = scorer.Score(filtered[i], now)
+	}
+
+	sortKey := cfg.Sort
+	if sortKey == "" {
+		sortKey = SortScore
+	}
+	sortFindings(filtered, sortKey)
+	summary.Sort = sortKey
+	var omitted []registry.Finding
+	if cfg.Top > 0 && len(filtered) > cfg.Top {
+		filtered, omitted = filtered[:cfg.Top:cfg.Top], filtered[cfg.Top:]
+		summary.Top = cfg.Top
+	}
+
+	analysis := &AnalysisResult{
 		Findings: filtered,
 		Summary:  summary,
 		Errors:   result.Errors,
+		Omitted:  omitted,
+	}
+	if len(statuses) > 0 {
+		analysis.Suppressions = statuses
+	}
+	return analysis
+}
+
+// ProjectWaste returns the total waste of the next twelve months when the
+// monthly waste, currently monthlyWaste, changes by monthlyGrowth every
+// month. Shrinking waste stops at zero.
+func ProjectWaste(monthlyWaste, monthlyGrowth float64) float64 {
+	var total float64
+	for month := 1; month <= 12; month++ {
+		total += max(monthlyWaste+monthlyGrowth*float64(month), 0)
+	}
+	return total
+}
+
+// addCarbon adds the storage and emissions behind waste, the counted waste
+// of f, to the summary. Egress waste moves no stored bytes and is left out.
+func addCarbon(summary *Summary, f registry.Finding, waste float64, provider string) {
+	if egress, ok := f.Metadata["egress_monthly_waste"].(float64); ok {
+		waste -= min(egress, waste)
+	}
+	if waste <= 0 {
+		return
+	}
+	if p, ok := f.Metadata[registry.MetadataProvider].(string); ok {
+		provider = p
+	}
+	provider = pricing.ProviderKey(provider)
+	gb := pricing.StorageGB(provider, f.Region, waste)
+	co2e := pricing.MonthlyCO2eKg(provider, f.Region, gb)
+	if summary.CO2eByRegion == nil {
+		summary.CO2eByRegion = make(map[string]float64)
+	}
+	summary.WastedGB += gb
+	summary.CO2eKgPerMonth += co2e
+	summary.CO2eByRegion[f.Region] += co2e
+}
+
+// windowNames returns the sorted names of the migration windows that tagged
+// findings.
+func windowNames(findings []registry.Finding) []string {
+	var names []string
+	for _, f := range findings {
+		if name, ok := f.Metadata[registry.MetadataSuppressedByWindow].(string); ok && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// inAgeWindow reports whether f was pushed within the OlderThan/NewerThan
+// window of cfg. Without either bound every finding is in the window.
+func inAgeWindow(f registry.Finding, cfg AnalyzerConfig, now time.Time) bool {
+	if cfg.OlderThan <= 0 && cfg.NewerThan <= 0 {
+		return true
+	}
+	pushed, ok := registry.PushedAt(f)
+	if !ok {
+		return false
+	}
+	age := now.Sub(pushed)
+	if cfg.OlderThan > 0 && age < cfg.OlderThan {
+		return false
+	}
+	return cfg.NewerThan <= 0 || age <= cfg.NewerThan
+}
+
+// matchSuppression returns the index of the first suppression matching f,
+// preferring an active one so an expired duplicate does not resurface a
+// finding that a renewed suppression still covers, or -1.
+func matchSuppression(suppressions []registry.Suppression, statuses []SuppressionStatus, f registry.Finding) int {
+	first := -1
+	for i, s := range suppressions {
+		if !s.Match(f) {
+			continue
+		}
+		if !statuses[i].Expired {
+			return i
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	return first
+}
+
+// wasteCounter sums monthly waste counting each resource once: an image
+// with several findings (untagged, stale and oversized) adds the waste of
+// its largest finding rather than the sum. Findings keep their own waste.
+type wasteCounter map[string]float64
+
+// add returns the waste f adds beyond what earlier findings on the same
+// resource already counted. Findings without a resource ID always add their
+// full waste.
+func (c wasteCounter) add(f registry.Finding) float64 {
+	if f.ResourceID == "" {
+		return f.EstimatedMonthlyWaste
+	}
+	key := f.Region + "|" + f.ResourceID
+	prev := c[key]
+	if f.EstimatedMonthlyWaste <= prev {
+		return 0
+	}
+	c[key] = f.EstimatedMonthlyWaste
+	return f.EstimatedMonthlyWaste - prev
+}
+
+// isPosture reports whether a finding is about security or capacity posture
+// rather than storage waste.
+func isPosture(id registry.FindingID) bool {
+	switch id {
+	case registry.FindingVulnerableImage, registry.FindingQuotaPressure, registry.FindingUnsignedImage, registry.FindingMissingSBOM,
+		registry.FindingAgingDeployedImage:
+		return true
+	}
+	return false
+}
+
+// rollupLongTail groups sub-threshold findings by region and repository into
+// LONG_TAIL_WASTE findings. A rollup is emitted only when its combined waste
+// reaches minCost; the findings of smaller groups, and those with no
+// repository, are returned as remaining.
+func rollupLongTail(below []registry.Finding, minCost float64) (rollups, remaining []registry.Finding) {
+	type group struct {
+		region, repository string
+		members            []registry.Finding
+		waste              float64
+		counted            wasteCounter
+	}
+	var order []*group
+	groups := make(map[string]*group)
+	for _, f := range below {
+		if f.Repository == "" {
+			remaining = append(remaining, f)
+			continue
+		}
+		key := f.Region + "|" + f.Repository
+		g, ok := groups[key]
+		if !ok {
+			g = &group{region: f.Region, repository: f.Repository, counted: wasteCounter{}}
+			groups[key] = g
+			order = append(order, g)
+		}
+		g.members = append(g.members, f)
+		g.waste += g.counted.add(f)
+	}
+
+	for _, g := range order {
+		if g.waste < minCost {
+			remaining = append(remaining, g.members...)
+			continue
+		}
+		byID := make(map[string]int)
+		for _, f := range g.members {
+			byID[string(f.ID)]++
+		}
+		rollup := registry.Finding{
+			ID:                    registry.FindingLongTailWaste,
+			Severity:              registry.SeverityLow,
+			ResourceType:          registry.ResourceRepository,
+			ResourceID:            g.repository,
+			Repository:            g.repository,
+			Region:                g.region,
+			Message:               fmt.Sprintf("%d findings under $%.2f/mo add up to $%.2f/mo — a lifecycle policy usually covers them", len(g.members), minCost, g.waste),
+			EstimatedMonthlyWaste: g.waste,
+			Metadata: map[string]any{
+				"finding_count":    len(g.members),
+				"findings_by_id":   byID,
+				"min_monthly_cost": minCost,
+			},
+		}
+		// Carry over attribution shared by the repository's findings.
+		for _, key := range append([]string{registry.MetadataProject}, registry.AttributionKeys...) {
+			if v, ok := g.members[0].Metadata[key]; ok {
+				rollup.Metadata[key] = v
+			}
+		}
+		rollups = append(rollups, rollup)
 	}
+	return rollups, remaining
 }
@@ -1,8 +1,13 @@
 package analyzer
 
 import (
+	"math"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/ppiankov/ecrspectre/internal/pricing"
 	"github.com/ppiankov/ecrspectre/internal/registry"
 )
 
@@ -34,6 +39,63 @@ func TestAnalyzeFiltersbyMinCost(t *testing.T) {
 	if analysis.Summary.RepositoriesScanned != 5 {
 		t.Errorf("RepositoriesScanned = %d, want 5", analysis.Summary.RepositoriesScanned)
 	}
+	if analysis.Summary.FilteredFindingsCount != 1 || analysis.Summary.FilteredWasteTotal != 0.50 {
+		t.Errorf("filtered = %d findings, $%.2f; want 1, $0.50", analysis.Summary.FilteredFindingsCount, analysis.Summary.FilteredWasteTotal)
+	}
+}
+
+func TestAnalyzeDropsDisabledChecks(t *testing.T) {
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			{ID: registry.FindingNoLifecyclePolicy, Severity: registry.SeverityMedium, ResourceType: registry.ResourceRepository, EstimatedMonthlyWaste: 5.0},
+			{ID: registry.FindingStaleImage, Severity: registry.SeverityHigh, ResourceType: registry.ResourceImage, EstimatedMonthlyWaste: 5.0},
+		},
+	}
+
+	analysis := Analyze(result, AnalyzerConfig{DisabledChecks: map[registry.FindingID]bool{registry.FindingNoLifecyclePolicy: true}})
+
+	if len(analysis.Findings) != 1 || analysis.Findings[0].ID != registry.FindingStaleImage {
+		t.Errorf("Findings = %+v, want only STALE_IMAGE", analysis.Findings)
+	}
+}
+
+func TestAnalyzeRollupLongTail(t *testing.T) {
+	small := func(id registry.FindingID, repo string, waste float64) registry.Finding {
+		return registry.Finding{ID: id, Severity: registry.SeverityHigh, ResourceType: registry.ResourceImage, Repository: repo, Region: "us-east-1", EstimatedMonthlyWaste: waste, Metadata: map[string]any{"team": "web"}}
+	}
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			small(registry.FindingUntaggedImage, "api", 0.40),
+			small(registry.FindingUntaggedImage, "api", 0.40),
+			small(registry.FindingStaleImage, "api", 0.30),
+			small(registry.FindingStaleImage, "web", 0.20),
+			{ID: registry.FindingStorageSpike, ResourceType: registry.ResourceRepository, EstimatedMonthlyWaste: 0.50},
+			small(registry.FindingLargeImage, "web", 3.0),
+		},
+	}
+
+	analysis := Analyze(result, AnalyzerConfig{MinMonthlyCost: 1.0, RollupLongTail: true})
+
+	if len(analysis.Findings) != 2 {
+		t.Fatalf("Findings = %+v, want LARGE_IMAGE and one rollup", analysis.Findings)
+	}
+	rollup := analysis.Findings[1]
+	if rollup.ID != registry.FindingLongTailWaste || rollup.ResourceID != "api" || rollup.Region != "us-east-1" {
+		t.Fatalf("unexpected rollup: %+v", rollup)
+	}
+	if rollup.Metadata["finding_count"] != 3 || rollup.Metadata["team"] != "web" {
+		t.Errorf("rollup metadata = %v", rollup.Metadata)
+	}
+	if byID := rollup.Metadata["findings_by_id"].(map[string]int); byID["UNTAGGED_IMAGE"] != 2 || byID["STALE_IMAGE"] != 1 {
+		t.Errorf("findings_by_id = %v", byID)
+	}
+	if rollup.EstimatedMonthlyWaste < 1.09 || rollup.EstimatedMonthlyWaste > 1.11 {
+		t.Errorf("rollup waste = %f, want 1.10", rollup.EstimatedMonthlyWaste)
+	}
+	// The web group and the repository-less spike stay below the threshold.
+	if analysis.Summary.FilteredFindingsCount != 2 {
+		t.Errorf("FilteredFindingsCount = %d, want 2", analysis.Summary.FilteredFindingsCount)
+	}
 }
 
 func TestAnalyzeSeverityHistogram(t *testing.T) {
@@ -61,6 +123,19 @@ func TestAnalyzeSeverityHistogram(t *testing.T) {
 	}
 }
 
+func TestAnalyzeInUseSummary(t *testing.T) {
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			{ID: registry.FindingLargeImage, EstimatedMonthlyWaste: 4.0, Metadata: map[string]any{"in_use": true}},
+			{ID: registry.FindingStaleImage, EstimatedMonthlyWaste: 1.0},
+		},
+	}
+	analysis := Analyze(result, AnalyzerConfig{MinMonthlyCost: 0})
+	if analysis.Summary.InUseFindings != 1 || analysis.Summary.InUseMonthlyWaste != 4.0 {
+		t.Errorf("in-use summary = %d / %.2f, want 1 / 4.00", analysis.Summary.InUseFindings, analysis.Summary.InUseMonthlyWaste)
+	}
+}
+
 func TestAnalyzeNoFindings(t *testing.T) {
 	result := &registry.ScanResult{
 		ResourcesScanned:    50,
@@ -103,3 +178,533 @@ func TestAnalyzeZeroMinCost(t *testing.T) {
 		t.Errorf("TotalFindings = %d, want 2", analysis.Summary.TotalFindings)
 	}
 }
+
+func TestAnalyzeKeepsZeroCostVulnerabilities(t *testing.T) {
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			{ID: registry.FindingVulnerableImage, Severity: registry.SeverityCritical, EstimatedMonthlyWaste: 0},
+			{ID: registry.FindingNoLifecyclePolicy, Severity: registry.SeverityMedium, EstimatedMonthlyWaste: 0},
+		},
+	}
+
+	analysis := Analyze(result, AnalyzerConfig{MinMonthlyCost: 0.10})
+
+	if len(analysis.Findings) != 1 {
+		t.Fatalf("Findings len = %d, want 1", len(analysis.Findings))
+	}
+	if analysis.Findings[0].ID != registry.FindingVulnerableImage {
+		t.Errorf("kept %s, want VULNERABLE_IMAGE", analysis.Findings[0].ID)
+	}
+}
+
+func TestAnalyzeByProject(t *testing.T) {
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			{ID: registry.FindingStaleImage, EstimatedMonthlyWaste: 4.0, Metadata: map[string]any{registry.MetadataProject: "a"}},
+			{ID: registry.FindingStaleImage, EstimatedMonthlyWaste: 1.0, Metadata: map[string]any{registry.MetadataProject: "a"}},
+		},
+		RepositoriesByProject: map[string]int{"a": 3, "b": 1},
+	}
+
+	summary := Analyze(result, AnalyzerConfig{}).Summary
+	if got := summary.ByProject["a"]; got.TotalFindings != 2 || got.TotalMonthlyWaste != 5.0 || got.RepositoriesScanned != 3 {
+		t.Errorf("project a = %+v", got)
+	}
+	if got, ok := summary.ByProject["b"]; !ok || got.TotalFindings != 0 || got.RepositoriesScanned != 1 {
+		t.Errorf("project b = %+v (present %v)", got, ok)
+	}
+
+	if Analyze(&registry.ScanResult{}, AnalyzerConfig{}).Summary.ByProject != nil {
+		t.Error("single-project scans should not have a per-project summary")
+	}
+}
+
+func TestAnalyzeSuppressions(t *testing.T) {
+	active, err := registry.NewSuppression("STALE_IMAGE", "legacy/*", "platform", "frozen", "2026-12-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := registry.NewSuppression("LARGE_IMAGE", "ml/*", "data", "model images", "2026-01-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unused, err := registry.NewSuppression("*", "gone/*", "ops", "removed", "2026-12-31")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			{ID: registry.FindingStaleImage, Severity: registry.SeverityHigh, ResourceID: "legacy/api", EstimatedMonthlyWaste: 4.0},
+			{ID: registry.FindingStaleImage, Severity: registry.SeverityHigh, ResourceID: "app/api", EstimatedMonthlyWaste: 2.0},
+			{ID: registry.FindingLargeImage, Severity: registry.SeverityMedium, ResourceID: "ml/model", EstimatedMonthlyWaste: 3.0},
+		},
+	}
+
+	analysis := Analyze(result, AnalyzerConfig{
+		Suppressions: []registry.Suppression{active, expired, unused},
+		Now:          time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+	})
+
+	if len(analysis.Findings) != 2 || analysis.Summary.TotalFindings != 2 {
+		t.Fatalf("findings = %+v, want the app/api and resurfaced ml/model findings", analysis.Findings)
+	}
+	if analysis.Summary.SuppressedFindings != 1 || analysis.Summary.SuppressedMonthlyWaste != 4.0 {
+		t.Errorf("suppressed = %d, $%.2f; want 1, $4.00", analysis.Summary.SuppressedFindings, analysis.Summary.SuppressedMonthlyWaste)
+	}
+	if analysis.Summary.TotalMonthlyWaste != 5.0 {
+		t.Errorf("TotalMonthlyWaste = %f, want 5.0", analysis.Summary.TotalMonthlyWaste)
+	}
+	if got := analysis.Findings[1].Metadata[registry.MetadataSuppressionExpired]; got != "2026-01-31" {
+		t.Errorf("expired suppression metadata = %v, want 2026-01-31", got)
+	}
+
+	want := []SuppressionStatus{
+		{ID: "STALE_IMAGE", Resource: "legacy/*", Owner: "platform", Reason: "frozen", Expires: "2026-12-31", Matched: 1},
+		{ID: "LARGE_IMAGE", Resource: "ml/*", Owner: "data", Reason: "model images", Expires: "2026-01-31", Expired: true, Matched: 1},
+		{ID: "*", Resource: "gone/*", Owner: "ops", Reason: "removed", Expires: "2026-12-31"},
+	}
+	if len(analysis.Suppressions) != len(want) {
+		t.Fatalf("Suppressions = %+v", analysis.Suppressions)
+	}
+	for i := range want {
+		if analysis.Suppressions[i] != want[i] {
+			t.Errorf("Suppressions[%d] = %+v, want %+v", i, analysis.Suppressions[i], want[i])
+		}
+	}
+}
+
+func TestAnalyzeRenewedSuppressionWins(t *testing.T) {
+	old, _ := registry.NewSuppression("STALE_IMAGE", "legacy/*", "platform", "frozen", "2026-01-31")
+	renewed, _ := registry.NewSuppression("STALE_IMAGE", "legacy/api", "platform", "still frozen", "2026-12-31")
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{{ID: registry.FindingStaleImage, ResourceID: "legacy/api", EstimatedMonthlyWaste: 1.0}},
+	}
+
+	analysis := Analyze(result, AnalyzerConfig{
+		Suppressions: []registry.Suppression{old, renewed},
+		Now:          time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if len(analysis.Findings) != 0 || analysis.Suppressions[1].Matched != 1 {
+		t.Errorf("findings = %+v, suppressions = %+v; want the renewed suppression to hide the finding", analysis.Findings, analysis.Suppressions)
+	}
+}
+
+func TestAnalyzeMigrationWindow(t *testing.T) {
+	window, err := registry.NewMigrationWindow("gar-move", "2026-03-01", "2026-03-14", "moving to GAR", []string{"legacy/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			{ID: registry.FindingStaleImage, Repository: "legacy/api", ResourceID: "legacy/api@sha256:1", EstimatedMonthlyWaste: 4.0},
+			{ID: registry.FindingStaleImage, Repository: "app/api", ResourceID: "app/api@sha256:2", EstimatedMonthlyWaste: 2.0},
+		},
+	}
+
+	analysis := Analyze(result, AnalyzerConfig{
+		Windows: []registry.MigrationWindow{window},
+		Now:     time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC),
+	})
+	if len(analysis.Findings) != 2 || analysis.Summary.TotalMonthlyWaste != 6.0 {
+		t.Fatalf("findings = %+v; windowed findings must stay in the report", analysis.Findings)
+	}
+	if analysis.Summary.WindowedFindings != 1 || len(analysis.Summary.MigrationWindows) != 1 || analysis.Summary.MigrationWindows[0] != "gar-move" {
+		t.Errorf("windowed = %d %v, want 1 [gar-move]", analysis.Summary.WindowedFindings, analysis.Summary.MigrationWindows)
+	}
+	if !registry.SuppressedByWindow(analysis.Findings[0]) || registry.SuppressedByWindow(analysis.Findings[1]) {
+		t.Errorf("suppressed_by_window tags = %v, %v", analysis.Findings[0].Metadata, analysis.Findings[1].Metadata)
+	}
+
+	after := Analyze(&registry.ScanResult{Findings: result.Findings[1:]}, AnalyzerConfig{
+		Windows: []registry.MigrationWindow{window},
+		Now:     time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if after.Summary.WindowedFindings != 0 {
+		t.Errorf("closed window tagged %d findings", after.Summary.WindowedFindings)
+	}
+}
+
+func TestAnalyzeCarbon(t *testing.T) {
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			{ID: registry.FindingStaleImage, ResourceID: "a", Region: "us-east-1", EstimatedMonthlyWaste: 1.0},
+			// Only the $0.50 of storage counts; egress moves no stored bytes.
+			{ID: registry.FindingLargeImage, ResourceID: "b", Region: "eu-north-1", EstimatedMonthlyWaste: 2.0,
+				Metadata: map[string]any{"egress_monthly_waste": 1.5}},
+			{ID: registry.FindingStaleImage, ResourceID: "c", Region: "us-central1", EstimatedMonthlyWaste: 1.0,
+				Metadata: map[string]any{registry.MetadataProvider: "gcp"}},
+		},
+	}
+
+	if off := Analyze(result, AnalyzerConfig{Provider: "ecr"}); off.Summary.CO2eKgPerMonth != 0 || off.Summary.CO2eByRegion != nil {
+		t.Errorf("carbon estimated without Carbon: %+v", off.Summary)
+	}
+	s := Analyze(result, AnalyzerConfig{Carbon: true, Provider: "ecr"}).Summary
+	if math.Abs(s.WastedGB-25) > 0.001 {
+		t.Errorf("WastedGB = %f, want 25", s.WastedGB)
+	}
+	want := map[string]float64{
+		"us-east-1":   pricing.MonthlyCO2eKg("ecr", "us-east-1", 10),
+		"eu-north-1":  pricing.MonthlyCO2eKg("ecr", "eu-north-1", 5),
+		"us-central1": pricing.MonthlyCO2eKg("artifactregistry", "us-central1", 10),
+	}
+	var total float64
+	for region, kg := range want {
+		total += kg
+		if math.Abs(s.CO2eByRegion[region]-kg) > 1e-9 {
+			t.Errorf("CO2eByRegion[%s] = %f, want %f", region, s.CO2eByRegion[region], kg)
+		}
+	}
+	if math.Abs(s.CO2eKgPerMonth-total) > 1e-9 {
+		t.Errorf("CO2eKgPerMonth = %f, want %f", s.CO2eKgPerMonth, total)
+	}
+}
+
+func TestAnalyzeAgeWindow(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	pushed := func(daysAgo int) map[string]any {
+		return map[string]any{registry.MetadataPushedAt: now.AddDate(0, 0, -daysAgo).Format(time.RFC3339)}
+	}
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			{ID: registry.FindingStaleImage, ResourceID: "old", EstimatedMonthlyWaste: 1.0, Metadata: pushed(400)},
+			{ID: registry.FindingStaleImage, ResourceID: "mid", EstimatedMonthlyWaste: 1.0, Metadata: pushed(200)},
+			{ID: registry.FindingUntaggedImage, ResourceID: "new", EstimatedMonthlyWaste: 1.0, Metadata: pushed(10)},
+			{ID: registry.FindingNoLifecyclePolicy, ResourceType: registry.ResourceRepository, ResourceID: "repo"},
+		},
+	}
+
+	tests := []struct {
+		name                 string
+		olderThan, newerThan time.Duration
+		want                 []string
+	}{
+		{"no window", 0, 0, []string{"old", "mid", "new", "repo"}},
+		{"older than 180d", 180 * 24 * time.Hour, 0, []string{"old", "mid"}},
+		{"newer than 30d", 0, 30 * 24 * time.Hour, []string{"new"}},
+		{"between", 180 * 24 * time.Hour, 365 * 24 * time.Hour, []string{"mid"}},
+	}
+	for _, tt := range tests {
+		analysis := Analyze(result, AnalyzerConfig{OlderThan: tt.olderThan, NewerThan: tt.newerThan, Now: now, Sort: SortScan})
+		var got []string
+		for _, f := range analysis.Findings {
+			got = append(got, f.ResourceID)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: findings = %v, want %v", tt.name, got, tt.want)
+		}
+		if analysis.Summary.AgeFilteredFindings != len(result.Findings)-len(tt.want) {
+			t.Errorf("%s: AgeFilteredFindings = %d, want %d", tt.name, analysis.Summary.AgeFilteredFindings, len(result.Findings)-len(tt.want))
+		}
+	}
+}
+
+func TestAnalyzeSelfResolving(t *testing.T) {
+	expiring := []registry.Finding{
+		{ID: registry.FindingUntaggedImage, Severity: registry.SeverityHigh, ResourceID: "app@sha256:a", EstimatedMonthlyWaste: 2.0},
+		{ID: registry.FindingVulnerableImage, Severity: registry.SeverityCritical, ResourceID: "app@sha256:a"},
+	}
+	registry.MarkSelfResolving(expiring, 1)
+	result := &registry.ScanResult{
+		Findings: append(expiring, registry.Finding{ID: registry.FindingStaleImage, Severity: registry.SeverityHigh, ResourceID: "app@sha256:b", EstimatedMonthlyWaste: 3.0}),
+	}
+
+	analysis := Analyze(result, AnalyzerConfig{})
+	if len(analysis.Findings) != 2 {
+		t.Fatalf("findings = %+v, want the vulnerability and the stale image", analysis.Findings)
+	}
+	if analysis.Summary.SelfResolvingFindings != 1 || analysis.Summary.SelfResolvingMonthlyWaste != 2.0 {
+		t.Errorf("self-resolving = %d, $%.2f; want 1, $2.00", analysis.Summary.SelfResolvingFindings, analysis.Summary.SelfResolvingMonthlyWaste)
+	}
+	if analysis.Summary.TotalMonthlyWaste != 3.0 {
+		t.Errorf("TotalMonthlyWaste = %f, want 3.0", analysis.Summary.TotalMonthlyWaste)
+	}
+}
+
+func TestAnalyzeCountsResourceWasteOnce(t *testing.T) {
+	image := func(id registry.FindingID, waste float64) registry.Finding {
+		return registry.Finding{ID: id, Severity: registry.SeverityHigh, ResourceType: registry.ResourceImage, ResourceID: "app@sha256:a", Region: "us-east-1", EstimatedMonthlyWaste: waste, Metadata: map[string]any{registry.MetadataProject: "p1"}}
+	}
+	other := image(registry.FindingStaleImage, 1.0)
+	other.Region = "eu-west-1"
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			image(registry.FindingUntaggedImage, 2.0),
+			image(registry.FindingStaleImage, 2.0),
+			image(registry.FindingMultiArchBloat, 1.5),
+			image(registry.FindingLargeImage, 2.5), // storage plus egress
+			other,
+		},
+		RepositoriesByProject: map[string]int{"p1": 1},
+	}
+
+	analysis := Analyze(result, AnalyzerConfig{})
+	if len(analysis.Findings) != 5 || analysis.Findings[0].EstimatedMonthlyWaste != 2.0 {
+		t.Fatalf("findings = %+v, want all five with their own waste", analysis.Findings)
+	}
+	if analysis.Summary.TotalMonthlyWaste != 3.5 {
+		t.Errorf("TotalMonthlyWaste = %f, want 3.5 (2.5 for the image, 1.0 in the other region)", analysis.Summary.TotalMonthlyWaste)
+	}
+	if analysis.Summary.OverlappingWaste != 5.5 {
+		t.Errorf("OverlappingWaste = %f, want 5.5", analysis.Summary.OverlappingWaste)
+	}
+	if p := analysis.Summary.ByProject["p1"]; p.TotalMonthlyWaste != 3.5 || p.TotalFindings != 5 {
+		t.Errorf("ByProject = %+v", p)
+	}
+}
+
+func TestAnalyzeGroupBy(t *testing.T) {
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			{ID: registry.FindingStaleImage, ResourceID: "a@1", EstimatedMonthlyWaste: 2.0, Metadata: map[string]any{"team": "web"}},
+			{ID: registry.FindingUntaggedImage, ResourceID: "a@1", EstimatedMonthlyWaste: 2.0, Metadata: map[string]any{"team": "web"}},
+			{ID: registry.FindingLargeImage, ResourceID: "b@1", EstimatedMonthlyWaste: 5.0, Metadata: map[string]any{"team": "payments"}},
+			{ID: registry.FindingNoLifecyclePolicy, ResourceID: "c"},
+		},
+	}
+
+	analysis := Analyze(result, AnalyzerConfig{GroupBy: "team"})
+	want := map[string]GroupSummary{
+		"web":                 {TotalFindings: 2, TotalMonthlyWaste: 2.0},
+		"payments":            {TotalFindings: 1, TotalMonthlyWaste: 5.0},
+		registry.Unattributed: {TotalFindings: 1},
+	}
+	if analysis.Summary.GroupBy != "team" || len(analysis.Summary.ByGroup) != len(want) {
+		t.Fatalf("ByGroup = %+v", analysis.Summary.ByGroup)
+	}
+	for g, w := range want {
+		if analysis.Summary.ByGroup[g] != w {
+			t.Errorf("ByGroup[%s] = %+v, want %+v", g, analysis.Summary.ByGroup[g], w)
+		}
+	}
+
+	if Analyze(result, AnalyzerConfig{}).Summary.ByGroup != nil {
+		t.Error("ByGroup set without GroupBy")
+	}
+}
+
+func TestProjectWaste(t *testing.T) {
+	if got := ProjectWaste(100, 0); got != 1200 {
+		t.Errorf("flat ProjectWaste = %.2f, want 1200", got)
+	}
+	// 100+10 ... 100+120: 12*100 + 10*78.
+	if got := ProjectWaste(100, 10); got != 1980 {
+		t.Errorf("growing ProjectWaste = %.2f, want 1980", got)
+	}
+	// Waste shrinking by 30/mo reaches zero after three months.
+	if got := ProjectWaste(100, -30); got != 70+40+10 {
+		t.Errorf("shrinking ProjectWaste = %.2f, want 120", got)
+	}
+}
+
+func TestAnalyzeCostPeriod(t *testing.T) {
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			{ID: registry.FindingStaleImage, ResourceID: "a@1", EstimatedMonthlyWaste: 2.0, Metadata: map[string]any{"team": "web"}},
+			{ID: registry.FindingUntaggedImage, ResourceID: "a@1", EstimatedMonthlyWaste: 1.0},
+			{ID: registry.FindingLargeImage, ResourceID: "b@1", EstimatedMonthlyWaste: 5.0},
+		},
+	}
+
+	analysis := Analyze(result, AnalyzerConfig{CostPeriod: registry.CostPeriodYear, GroupBy: "team"})
+	if s := analysis.Summary; s.CostPeriod != registry.CostPeriodYear || s.TotalMonthlyWaste != 7.0 || s.TotalPeriodWaste != 84.0 {
+		t.Errorf("summary period = %q, monthly %.2f, period %.2f", s.CostPeriod, s.TotalMonthlyWaste, s.TotalPeriodWaste)
+	}
+	if got := analysis.Summary.ByGroup["web"].TotalPeriodWaste; got != 24.0 {
+		t.Errorf("ByGroup[web] period waste = %.2f, want 24", got)
+	}
+	for _, f := range analysis.Findings {
+		if got := registry.PeriodWaste(f); got != f.EstimatedMonthlyWaste*12 {
+			t.Errorf("%s period waste = %.2f, want %.2f", f.ID, got, f.EstimatedMonthlyWaste*12)
+		}
+	}
+
+	monthly := Analyze(&registry.ScanResult{Findings: []registry.Finding{{ID: registry.FindingLargeImage, ResourceID: "c@1", EstimatedMonthlyWaste: 5.0}}}, AnalyzerConfig{CostPeriod: registry.CostPeriodMonth})
+	if monthly.Summary.CostPeriod != "" || monthly.Summary.TotalPeriodWaste != 0 || monthly.Findings[0].Metadata != nil {
+		t.Errorf("monthly period recorded: %+v", monthly.Summary)
+	}
+}
+
+func TestAnalyzeSortAndTop(t *testing.T) {
+	old := time.Now().AddDate(-2, 0, 0).Format(time.RFC3339)
+	newer := time.Now().AddDate(-1, 0, 0).Format(time.RFC3339)
+	result := &registry.ScanResult{
+		Findings: []registry.Finding{
+			{ID: registry.FindingStaleImage, Severity: registry.SeverityLow, ResourceID: "a@1", EstimatedMonthlyWaste: 1.0, Metadata: map[string]any{"size_bytes": int64(300)}},
+			{ID: registry.FindingLargeImage, Severity: registry.SeverityHigh, ResourceID: "b@1", EstimatedMonthlyWaste: 5.0, Metadata: map[string]any{"size_bytes": int64(100), registry.MetadataPushedAt: newer}},
+			{ID: registry.FindingUntaggedImage, Severity: registry.SeverityMedium, ResourceID: "c@1", EstimatedMonthlyWaste: 3.0, Metadata: map[string]any{"size_bytes": 200.0, registry.MetadataPushedAt: old}},
+		},
+	}
+	ids := func(findings []registry.Finding) string {
+		var out []string
+		for _, f := range findings {
+			out = append(out, f.ResourceID)
+		}
+		return strings.Join(out, ",")
+	}
+	tests := []struct {
+		key  SortKey
+		want string
+	}{
+		{"", "b@1,c@1,a@1"},
+		{SortScan, "a@1,b@1,c@1"},
+		{SortWaste, "b@1,c@1,a@1"},
+		{SortSize, "a@1,c@1,b@1"},
+		{SortAge, "c@1,b@1,a@1"},
+		{SortSeverity, "b@1,c@1,a@1"},
+	}
+	for _, tt := range tests {
+		if got := ids(Analyze(result, AnalyzerConfig{Sort: tt.key}).Findings); got != tt.want {
+			t.Errorf("Sort %q = %s, want %s", tt.key, got, tt.want)
+		}
+	}
+
+	top := Analyze(result, AnalyzerConfig{Top: 2})
+	if got := ids(top.Findings); got != "b@1,c@1" {
+		t.Errorf("Top 2 = %s, want b@1,c@1", got)
+	}
+	if got := ids(top.Omitted); got != "a@1" {
+		t.Errorf("Omitted = %s, want a@1", got)
+	}
+	if s := top.Summary; s.Top != 2 || s.Sort != SortScore || s.TotalFindings != 3 || s.TotalMonthlyWaste != 9.0 {
+		t.Errorf("summary = %+v", s)
+	}
+	if all := Analyze(result, AnalyzerConfig{Top: 5}); all.Summary.Top != 0 || len(all.Omitted) != 0 {
+		t.Errorf("Top beyond findings cut the list: top %d, omitted %d", all.Summary.Top, len(all.Omitted))
+	}
+}
+
+func TestParseSortKey(t *testing.T) {
+	if key, err := ParseSortKey("size"); err != nil || key != SortSize {
+		t.Errorf("ParseSortKey(size) = %q, %v", key, err)
+	}
+	if _, err := ParseSortKey("name"); err == nil {
+		t.Error("ParseSortKey(name) succeeded")
+	}
+}
+
+func TestScoreModel(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	pushed := map[string]any{registry.MetadataPushedAt: now.AddDate(0, 0, -180).Format(time.RFC3339)}
+	f := registry.Finding{ID: registry.FindingUntaggedImage, Severity: registry.SeverityHigh, EstimatedMonthlyWaste: 10, Metadata: pushed}
+
+	// (0.3*0.75 + 0.4*0.5 + 0.15*0.5 + 0.15*1) / 1 = 0.65
+	if got := DefaultScoreModel.Score(f, now); got != 65 {
+		t.Errorf("default score = %v, want 65", got)
+	}
+	if got := (ScoreModel{Waste: 1}).Score(f, now); got != 50 {
+		t.Errorf("waste-only score = %v, want 50", got)
+	}
+
+	// A deployed image is less surely waste.
+	deployed := f
+	deployed.Metadata = map[string]any{"in_use": true}
+	if got := (ScoreModel{Confidence: 1}).Score(deployed, now); got != 50 {
+		t.Errorf("in-use confidence score = %v, want 50", got)
+	}
+
+	if err := (ScoreModel{Waste: -1, Severity: 2}).Validate(); err == nil {
+		t.Error("negative weight accepted")
+	}
+	if err := (ScoreModel{}).Validate(); err == nil {
+		t.Error("all-zero weights accepted")
+	}
+}
+
+// fixedScorer scores findings by their resource ID.
+type fixedScorer map[string]float64
+
+func (s fixedScorer) Score(f registry.Finding, _ time.Time) float64 { return s[f.ResourceID] }
+
+func TestAnalyzeScorer(t *testing.T) {
+	result := &registry.ScanResult{Findings: []registry.Finding{
+		{ID: registry.FindingStaleImage, ResourceID: "a", EstimatedMonthlyWaste: 9},
+		{ID: registry.FindingStaleImage, ResourceID: "b", EstimatedMonthlyWaste: 1},
+	}}
+	analysis := Analyze(result, AnalyzerConfig{Scorer: fixedScorer{"a": 10, "b": 90}})
+	if f := analysis.Findings; f[0].ResourceID != "b" || f[0].Score != 90 || f[1].Score != 10 {
+		t.Errorf("findings = %+v, want b (90) before a (10)", f)
+	}
+}
+
+func TestCompareBaseline(t *testing.T) {
+	baseline := []registry.Finding{
+		{ID: registry.FindingStaleImage, Region: "us-east-1", ResourceID: "a", EstimatedMonthlyWaste: 4},
+		{ID: registry.FindingStaleImage, Region: "us-east-1", ResourceID: "b", EstimatedMonthlyWaste: 6},
+	}
+	findings := []registry.Finding{
+		{ID: registry.FindingStaleImage, Region: "us-east-1", ResourceID: "a", EstimatedMonthlyWaste: 5},
+		// Same resource, another finding type.
+		{ID: registry.FindingLargeImage, Region: "us-east-1", ResourceID: "b", EstimatedMonthlyWaste: 2},
+	}
+	omitted := []registry.Finding{
+		{ID: registry.FindingStaleImage, Region: "us-east-1", ResourceID: "c", EstimatedMonthlyWaste: 1},
+	}
+
+	s := MarkBaseline(baseline, findings, omitted)
+	want := BaselineSummary{New: 2, Unchanged: 1, Resolved: 1, NewMonthlyWaste: 3, ResolvedMonthlyWaste: 6}
+	if s != want {
+		t.Errorf("summary = %+v, want %+v", s, want)
+	}
+	if findings[0].Metadata[registry.MetadataBaseline] != registry.BaselineUnchanged || findings[1].Metadata[registry.MetadataBaseline] != registry.BaselineNew {
+		t.Errorf("baseline tags = %v, %v", findings[0].Metadata, findings[1].Metadata)
+	}
+	if omitted[0].Metadata != nil {
+		t.Errorf("omitted finding tagged: %v", omitted[0].Metadata)
+	}
+
+	c := CompareBaseline(baseline, nil)
+	if len(c.New) != 0 || len(c.Unchanged) != 0 || len(c.Resolved) != 2 {
+		t.Errorf("comparison with no findings = %+v", c)
+	}
+}
+
+func TestParseFailOn(t *testing.T) {
+	conditions, err := ParseFailOn([]string{"severity=high", "waste=$100.5", "count=50"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []FailCondition{
+		{Kind: FailOnSeverity, Severity: registry.SeverityHigh},
+		{Kind: FailOnWaste, Waste: 100.5},
+		{Kind: FailOnCount, Count: 50},
+	}
+	if !reflect.DeepEqual(conditions, want) {
+		t.Errorf("conditions = %+v, want %+v", conditions, want)
+	}
+	if got := conditions[1].String(); got != "waste=100.5" {
+		t.Errorf("String() = %q", got)
+	}
+	for _, bad := range []string{"high", "severity=urgent", "waste=-1", "count=many", "size=10", "count="} {
+		if _, err := ParseFailOn([]string{bad}); err == nil {
+			t.Errorf("ParseFailOn(%q) should fail", bad)
+		}
+	}
+}
+
+func TestCheckFailOn(t *testing.T) {
+	findings := []registry.Finding{
+		{ID: registry.FindingStaleImage, Severity: registry.SeverityMedium, Region: "us-east-1", ResourceID: "a", EstimatedMonthlyWaste: 40},
+		// The same image counts once toward the waste budget.
+		{ID: registry.FindingLargeImage, Severity: registry.SeverityMedium, Region: "us-east-1", ResourceID: "a", EstimatedMonthlyWaste: 30},
+		{ID: registry.FindingUnusedRepo, Severity: registry.SeverityHigh, Region: "us-east-1", ResourceID: "b", EstimatedMonthlyWaste: 50,
+			Metadata: map[string]any{registry.MetadataSuppressedByWindow: "migration"}},
+	}
+	conditions, err := ParseFailOn([]string{"severity=high", "waste=39", "count=1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	broken := CheckFailOn(conditions, findings)
+	want := []string{"waste=39: $40.00/mo of waste", "count=1: 2 findings"}
+	if !reflect.DeepEqual(broken, want) {
+		t.Errorf("broken = %q, want %q", broken, want)
+	}
+
+	conditions, _ = ParseFailOn([]string{"severity=medium", "waste=40", "count=2"})
+	if broken := CheckFailOn(conditions, findings); len(broken) != 1 || !strings.HasPrefix(broken[0], "severity=medium: 2 finding(s)") {
+		t.Errorf("broken = %q", broken)
+	}
+	if broken := CheckFailOn(conditions, nil); len(broken) != 0 {
+		t.Errorf("no findings broke %q", broken)
+	}
+}
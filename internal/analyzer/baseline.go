@@ -0,0 +1,89 @@
+package analyzer
+
+import (
+	"slices"
+
+	"github.com/ppiankov/ecrspectre/internal/history"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// BaselineComparison splits the findings of two reports. New findings are
+// in the current report only, resolved findings in the baseline only, and
+// unchanged findings, as reported now, in both. Findings are matched by
+// type, region and resource.
+type BaselineComparison struct {
+	New       []registry.Finding `json:"new"`
+	Unchanged []registry.Finding `json:"unchanged"`
+	Resolved  []registry.Finding `json:"resolved"`
+}
+
+// BaselineSummary counts a BaselineComparison.
+type BaselineSummary struct {
+	// Report is the path of the baseline report, when compared by a scan.
+	Report               string  `json:"report,omitempty"`
+	New                  int     `json:"new"`
+	Unchanged            int     `json:"unchanged"`
+	Resolved             int     `json:"resolved"`
+	NewMonthlyWaste      float64 `json:"new_monthly_waste"`
+	ResolvedMonthlyWaste float64 `json:"resolved_monthly_waste"`
+}
+
+// CompareBaseline compares findings with those of a baseline report. Each
+// list keeps the order of the report it comes from.
+func CompareBaseline(baseline, findings []registry.Finding) BaselineComparison {
+	var c BaselineComparison
+	current := make(map[string]bool, len(findings))
+	for _, f := range findings {
+		current[history.FindingKey(f)] = true
+	}
+	previous := make(map[string]bool, len(baseline))
+	for _, f := range baseline {
+		key := history.FindingKey(f)
+		previous[key] = true
+		if !current[key] {
+			c.Resolved = append(c.Resolved, f)
+		}
+	}
+	for _, f := range findings {
+		if previous[history.FindingKey(f)] {
+			c.Unchanged = append(c.Unchanged, f)
+		} else {
+			c.New = append(c.New, f)
+		}
+	}
+	return c
+}
+
+// Summary counts the comparison and totals the waste of new and resolved
+// findings.
+func (c BaselineComparison) Summary() BaselineSummary {
+	s := BaselineSummary{New: len(c.New), Unchanged: len(c.Unchanged), Resolved: len(c.Resolved)}
+	for _, f := range c.New {
+		s.NewMonthlyWaste += f.EstimatedMonthlyWaste
+	}
+	for _, f := range c.Resolved {
+		s.ResolvedMonthlyWaste += f.EstimatedMonthlyWaste
+	}
+	return s
+}
+
+// MarkBaseline compares the findings of a scan with a baseline report and
+// tags each with its status under registry.MetadataBaseline. omitted are the
+// findings cut from the report by --top, which the summary still counts.
+func MarkBaseline(baseline, findings, omitted []registry.Finding) BaselineSummary {
+	previous := make(map[string]bool, len(baseline))
+	for _, f := range baseline {
+		previous[history.FindingKey(f)] = true
+	}
+	for i := range findings {
+		status := registry.BaselineNew
+		if previous[history.FindingKey(findings[i])] {
+			status = registry.BaselineUnchanged
+		}
+		if findings[i].Metadata == nil {
+			findings[i].Metadata = make(map[string]any)
+		}
+		findings[i].Metadata[registry.MetadataBaseline] = status
+	}
+	return CompareBaseline(baseline, slices.Concat(findings, omitted)).Summary()
+}
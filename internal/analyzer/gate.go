@@ -0,0 +1,116 @@
+package analyzer
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// FailCondition is one --fail-on threshold. A scan fails the condition when
+// it has a finding at Severity or above, more than Waste dollars of monthly
+// waste, or more than Count findings; only the field named by Kind is set.
+type FailCondition struct {
+	Kind     string
+	Severity registry.Severity
+	Waste    float64
+	Count    int
+}
+
+// Fail condition kinds.
+const (
+	FailOnSeverity = "severity"
+	FailOnWaste    = "waste"
+	FailOnCount    = "count"
+)
+
+// ParseFailOn parses thresholds such as "severity=high", "waste=100" and
+// "count=50".
+func ParseFailOn(specs []string) ([]FailCondition, error) {
+	conditions := make([]FailCondition, 0, len(specs))
+	for _, spec := range specs {
+		kind, value, ok := strings.Cut(strings.TrimSpace(spec), "=")
+		if !ok || value == "" {
+			return nil, fmt.Errorf("invalid fail-on %q (want severity=<level>, waste=<dollars> or count=<n>)", spec)
+		}
+		c := FailCondition{Kind: kind}
+		switch kind {
+		case FailOnSeverity:
+			sev, err := registry.ParseSeverity(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid fail-on %q: %w", spec, err)
+			}
+			c.Severity = sev
+		case FailOnWaste:
+			waste, err := strconv.ParseFloat(strings.TrimPrefix(value, "$"), 64)
+			if err != nil || waste < 0 {
+				return nil, fmt.Errorf("invalid fail-on %q: waste must be a non-negative dollar amount", spec)
+			}
+			c.Waste = waste
+		case FailOnCount:
+			count, err := strconv.Atoi(value)
+			if err != nil || count < 0 {
+				return nil, fmt.Errorf("invalid fail-on %q: count must be a non-negative integer", spec)
+			}
+			c.Count = count
+		default:
+			return nil, fmt.Errorf("invalid fail-on %q: unknown threshold %q (use severity, waste or count)", spec, kind)
+		}
+		conditions = append(conditions, c)
+	}
+	return conditions, nil
+}
+
+// String renders the condition as it is written on the command line.
+func (c FailCondition) String() string {
+	switch c.Kind {
+	case FailOnSeverity:
+		return FailOnSeverity + "=" + string(c.Severity)
+	case FailOnWaste:
+		return FailOnWaste + "=" + strconv.FormatFloat(c.Waste, 'f', -1, 64)
+	}
+	return FailOnCount + "=" + strconv.Itoa(c.Count)
+}
+
+// CheckFailOn returns a description of every condition the findings break,
+// in the order given. Findings suppressed by a migration window never count.
+// Waste counts each resource once, like the summary total.
+func CheckFailOn(conditions []FailCondition, findings []registry.Finding) []string {
+	var gated []registry.Finding
+	for _, f := range findings {
+		if !registry.SuppressedByWindow(f) {
+			gated = append(gated, f)
+		}
+	}
+	var waste float64
+	counted := wasteCounter{}
+	for _, f := range gated {
+		waste += counted.add(f)
+	}
+
+	var broken []string
+	for _, c := range conditions {
+		switch c.Kind {
+		case FailOnSeverity:
+			n := 0
+			for _, f := range gated {
+				if rank, ok := severityRank[f.Severity]; ok && rank <= severityRank[c.Severity] {
+					n++
+				}
+			}
+			if n > 0 {
+				broken = append(broken, fmt.Sprintf("%s: %d finding(s) at %s severity or above", c, n, c.Severity))
+			}
+		case FailOnWaste:
+			if waste > c.Waste {
+				broken = append(broken, fmt.Sprintf("%s: $%.2f/mo of waste", c, waste))
+			}
+		case FailOnCount:
+			if len(gated) > c.Count {
+				broken = append(broken, fmt.Sprintf("%s: %d findings", c, len(gated)))
+			}
+		}
+	}
+	return broken
+}
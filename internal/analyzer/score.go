@@ -0,0 +1,106 @@
+package analyzer
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// Scorer assigns a finding its priority score: higher scores are triaged
+// first. AnalyzerConfig.Scorer replaces the default ScoreModel.
+type Scorer interface {
+	Score(f registry.Finding, now time.Time) float64
+}
+
+// ScoreModel scores findings from 0 to 100 as the weighted mean of four
+// factors, each from 0 to 1:
+//
+//   - severity: critical 1, high 0.75, medium 0.5, low 0.25;
+//   - waste: w/(w+$10), so $10/mo scores 0.5 and large waste approaches 1;
+//   - age: days/(days+180) since the image was pushed, 0.5 when unknown;
+//   - confidence: how surely the finding is reclaimable waste, lower for
+//     heuristics such as STALE_IMAGE and halved for deployed images.
+//
+// Weights are relative; a zero weight ignores the factor.
+type ScoreModel struct {
+	Severity   float64
+	Waste      float64
+	Age        float64
+	Confidence float64
+}
+
+// DefaultScoreModel ranks mostly by waste, then severity.
+var DefaultScoreModel = ScoreModel{Severity: 0.3, Waste: 0.4, Age: 0.15, Confidence: 0.15}
+
+const (
+	// scoreWasteMidpoint is the monthly waste that scores 0.5.
+	scoreWasteMidpoint = 10.0
+	// scoreAgeMidpoint is the image age, in days, that scores 0.5.
+	scoreAgeMidpoint = 180.0
+)
+
+// Validate reports negative weights or weights that are all zero.
+func (m ScoreModel) Validate() error {
+	if m.Severity < 0 || m.Waste < 0 || m.Age < 0 || m.Confidence < 0 {
+		return fmt.Errorf("scoring weights must not be negative")
+	}
+	if m.Severity+m.Waste+m.Age+m.Confidence == 0 {
+		return fmt.Errorf("scoring weights are all zero")
+	}
+	return nil
+}
+
+var severityScore = map[registry.Severity]float64{
+	registry.SeverityCritical: 1,
+	registry.SeverityHigh:     0.75,
+	registry.SeverityMedium:   0.5,
+	registry.SeverityLow:      0.25,
+}
+
+// findingConfidence is how surely each finding type is waste that can be
+// reclaimed. Facts read from the registry score 1; findings inferred from
+// pull activity, thresholds or growth score less. Unlisted types score 0.8.
+var findingConfidence = map[registry.FindingID]float64{
+	registry.FindingUntaggedImage:        1,
+	registry.FindingUntaggedAccumulation: 1,
+	registry.FindingOrphanedManifest:     1,
+	registry.FindingNoLifecyclePolicy:    1,
+	registry.FindingMultiArchBloat:       0.9,
+	registry.FindingDuplicateLayers:      0.9,
+	registry.FindingStaleImage:           0.7,
+	registry.FindingStaleRemoteCache:     0.7,
+	registry.FindingUnusedRepo:           0.7,
+	registry.FindingTieringCandidate:     0.6,
+	registry.FindingLargeImage:           0.5,
+	registry.FindingStorageSpike:         0.5,
+	registry.FindingStaleReleaseTrain:    0.5,
+}
+
+// Score returns the priority score of f, rounded to one decimal.
+func (m ScoreModel) Score(f registry.Finding, now time.Time) float64 {
+	total := m.Severity + m.Waste + m.Age + m.Confidence
+	if total <= 0 {
+		return 0
+	}
+	waste := max(f.EstimatedMonthlyWaste, 0)
+	age := 0.5
+	if pushed, ok := registry.PushedAt(f); ok {
+		days := max(now.Sub(pushed).Hours()/24, 0)
+		age = days / (days + scoreAgeMidpoint)
+	}
+	confidence, ok := findingConfidence[f.ID]
+	if !ok {
+		confidence = 0.8
+	}
+	if registry.IsInUse(f) {
+		confidence /= 2
+	}
+
+	score := m.Severity*severityScore[f.Severity] +
+		m.Waste*waste/(waste+scoreWasteMidpoint) +
+		m.Age*age +
+		m.Confidence*confidence
+	return math.Round(score/total*1000) / 10
+}
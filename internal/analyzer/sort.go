@@ -0,0 +1,115 @@
+package analyzer
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// SortKey orders the reported findings.
+type SortKey string
+
+const (
+	// SortWaste puts the most expensive findings first.
+	SortWaste SortKey = "waste"
+	// SortSize puts findings on the largest images first.
+	SortSize SortKey = "size"
+	// SortAge puts findings on the oldest images first.
+	SortAge SortKey = "age"
+	// SortSeverity puts critical findings first, then by waste.
+	SortSeverity SortKey = "severity"
+	// SortScore puts the highest priority scores first, then by waste. It
+	// is the default.
+	SortScore SortKey = "score"
+	// SortScan keeps the order the scanners reported findings in.
+	SortScan SortKey = "scan"
+)
+
+// ParseSortKey parses a --sort value. An empty value sorts by score.
+func ParseSortKey(s string) (SortKey, error) {
+	switch key := SortKey(s); key {
+	case "", SortScore, SortWaste, SortSize, SortAge, SortSeverity, SortScan:
+		return key, nil
+	}
+	return "", fmt.Errorf("unknown sort key %q (use score, waste, size, age, severity or scan)", s)
+}
+
+var severityRank = map[registry.Severity]int{
+	registry.SeverityCritical: 0,
+	registry.SeverityHigh:     1,
+	registry.SeverityMedium:   2,
+	registry.SeverityLow:      3,
+}
+
+// sortFindings orders findings by key, breaking ties by waste. Findings
+// without the key's value (no size or push time) go last.
+func sortFindings(findings []registry.Finding, key SortKey) {
+	byWaste := func(a, b registry.Finding) bool {
+		return a.EstimatedMonthlyWaste > b.EstimatedMonthlyWaste
+	}
+	var less func(a, b registry.Finding) bool
+	switch key {
+	case SortScore:
+		less = func(a, b registry.Finding) bool {
+			if a.Score != b.Score {
+				return a.Score > b.Score
+			}
+			return byWaste(a, b)
+		}
+	case SortWaste:
+		less = byWaste
+	case SortSize:
+		less = func(a, b registry.Finding) bool {
+			sa, sb := findingSize(a), findingSize(b)
+			if sa != sb {
+				return sa > sb
+			}
+			return byWaste(a, b)
+		}
+	case SortAge:
+		less = func(a, b registry.Finding) bool {
+			pa, okA := registry.PushedAt(a)
+			pb, okB := registry.PushedAt(b)
+			if okA != okB {
+				return okA
+			}
+			if !pa.Equal(pb) {
+				return pa.Before(pb)
+			}
+			return byWaste(a, b)
+		}
+	case SortSeverity:
+		less = func(a, b registry.Finding) bool {
+			ra, okA := severityRank[a.Severity]
+			rb, okB := severityRank[b.Severity]
+			if !okA {
+				ra = len(severityRank)
+			}
+			if !okB {
+				rb = len(severityRank)
+			}
+			if ra != rb {
+				return ra < rb
+			}
+			return byWaste(a, b)
+		}
+	default:
+		return
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return less(findings[i], findings[j]) })
+}
+
+// findingSize returns the size_bytes metadata of a finding, or -1. Values
+// read back from JSON reports are float64.
+func findingSize(f registry.Finding) float64 {
+	switch v := f.Metadata["size_bytes"].(type) {
+	case int64:
+		return float64(v)
+	case int:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return -1
+}
@@ -1,17 +1,103 @@
 package analyzer
 
 import (
+	"time"
+
 	"github.com/ppiankov/ecrspectre/internal/registry"
 )
 
 // Summary holds aggregated statistics about scan findings.
 type Summary struct {
-	TotalResourcesScanned int            `json:"total_resources_scanned"`
-	TotalFindings         int            `json:"total_findings"`
-	TotalMonthlyWaste     float64        `json:"total_monthly_waste"`
-	BySeverity            map[string]int `json:"by_severity"`
-	ByResourceType        map[string]int `json:"by_resource_type"`
-	RepositoriesScanned   int            `json:"repositories_scanned"`
+	TotalResourcesScanned int     `json:"total_resources_scanned"`
+	TotalFindings         int     `json:"total_findings"`
+	TotalMonthlyWaste     float64 `json:"total_monthly_waste"`
+	// OverlappingWaste is the finding waste left out of TotalMonthlyWaste
+	// because another finding on the same resource already counts it.
+	OverlappingWaste    float64           `json:"overlapping_waste,omitempty"`
+	BySeverity          map[string]int    `json:"by_severity"`
+	ByResourceType      map[string]int    `json:"by_resource_type"`
+	RepositoriesScanned int               `json:"repositories_scanned"`
+	Coverage            registry.Coverage `json:"coverage"`
+	// FilteredFindingsCount and FilteredWasteTotal cover the findings dropped
+	// for costing less than the minimum monthly cost (and not rolled up into
+	// LONG_TAIL_WASTE).
+	FilteredFindingsCount int     `json:"filtered_findings_count"`
+	FilteredWasteTotal    float64 `json:"filtered_waste_total"`
+	// InUseFindings and InUseMonthlyWaste cover findings on images that are
+	// currently deployed (set only when in-use correlation is enabled).
+	InUseFindings     int     `json:"in_use_findings,omitempty"`
+	InUseMonthlyWaste float64 `json:"in_use_monthly_waste,omitempty"`
+	// AgeFilteredFindings counts the findings outside the --older-than /
+	// --newer-than window.
+	AgeFilteredFindings int `json:"age_filtered_findings,omitempty"`
+	// SelfResolvingFindings and SelfResolvingMonthlyWaste cover the waste
+	// findings on images a lifecycle policy will expire soon.
+	SelfResolvingFindings     int     `json:"self_resolving_findings,omitempty"`
+	SelfResolvingMonthlyWaste float64 `json:"self_resolving_monthly_waste,omitempty"`
+	// SuppressedFindings and SuppressedMonthlyWaste cover the findings hidden
+	// by active suppressions.
+	SuppressedFindings     int     `json:"suppressed_findings,omitempty"`
+	SuppressedMonthlyWaste float64 `json:"suppressed_monthly_waste,omitempty"`
+	// WindowedFindings counts the reported findings tagged
+	// suppressed_by_window by the active MigrationWindows they fall in.
+	WindowedFindings int      `json:"windowed_findings,omitempty"`
+	MigrationWindows []string `json:"migration_windows,omitempty"`
+	// ByProject is set only for multi-project scans.
+	ByProject map[string]ProjectSummary `json:"by_project,omitempty"`
+	// GroupBy and ByGroup break findings and waste down by a tag/label key
+	// such as team, for chargeback. Set only with AnalyzerConfig.GroupBy.
+	GroupBy string                  `json:"group_by,omitempty"`
+	ByGroup map[string]GroupSummary `json:"by_group,omitempty"`
+	// CostPeriod and TotalPeriodWaste restate TotalMonthlyWaste per day or
+	// per year. Set only with a non-monthly AnalyzerConfig.CostPeriod.
+	CostPeriod       registry.CostPeriod `json:"cost_period,omitempty"`
+	TotalPeriodWaste float64             `json:"total_period_waste,omitempty"`
+	// Sort records how the findings list was ordered. Top is set when the
+	// list was cut to its first Top of TotalFindings findings.
+	Sort SortKey `json:"sort,omitempty"`
+	Top  int     `json:"top,omitempty"`
+	// WastedGB and CO2eKgPerMonth estimate the storage behind the counted
+	// waste and its monthly emissions, also broken down by region. Set only
+	// with AnalyzerConfig.Carbon.
+	WastedGB       float64            `json:"wasted_gb,omitempty"`
+	CO2eKgPerMonth float64            `json:"co2e_kg_per_month,omitempty"`
+	CO2eByRegion   map[string]float64 `json:"co2e_kg_by_region,omitempty"`
+	// Projection extrapolates waste over the next year from the growth seen
+	// in scan history. Set only with enough history (see --history-dir).
+	Projection *Projection `json:"projection,omitempty"`
+	// Baseline counts the findings that are new, unchanged and resolved
+	// since a baseline report. Set only with --baseline.
+	Baseline *BaselineSummary `json:"baseline,omitempty"`
+}
+
+// Projection is the waste expected over the next twelve months if the
+// monthly waste keeps growing as it did over recent scans.
+type Projection struct {
+	// MonthlyGrowth is the change in monthly waste per month ($/mo per
+	// month), negative while waste shrinks.
+	MonthlyGrowth float64 `json:"monthly_growth"`
+	// NextYearWaste sums the projected monthly waste of the next twelve
+	// months.
+	NextYearWaste float64 `json:"next_year_waste"`
+	// Scans and Since describe the history the growth was fitted to,
+	// including this scan.
+	Scans int       `json:"scans"`
+	Since time.Time `json:"since"`
+}
+
+// GroupSummary aggregates the findings of one --group-by value.
+type GroupSummary struct {
+	TotalFindings     int     `json:"total_findings"`
+	TotalMonthlyWaste float64 `json:"total_monthly_waste"`
+	TotalPeriodWaste  float64 `json:"total_period_waste,omitempty"`
+}
+
+// ProjectSummary aggregates one project of a multi-project scan.
+type ProjectSummary struct {
+	RepositoriesScanned int     `json:"repositories_scanned"`
+	TotalFindings       int     `json:"total_findings"`
+	TotalMonthlyWaste   float64 `json:"total_monthly_waste"`
+	TotalPeriodWaste    float64 `json:"total_period_waste,omitempty"`
 }
 
 // AnalysisResult holds filtered findings and computed summary.
@@ -19,9 +105,67 @@ type AnalysisResult struct {
 	Findings []registry.Finding `json:"findings"`
 	Summary  Summary            `json:"summary"`
 	Errors   []string           `json:"errors,omitempty"`
+	// Suppressions reports every configured suppression and how many
+	// findings it matched.
+	Suppressions []SuppressionStatus `json:"suppressions,omitempty"`
+	// Omitted holds the findings cut from Findings by AnalyzerConfig.Top,
+	// for consumers such as the scan history that need every finding.
+	Omitted []registry.Finding `json:"-"`
+}
+
+// SuppressionStatus is the audit record of one suppression in a scan.
+type SuppressionStatus struct {
+	ID       string `json:"id"`
+	Resource string `json:"resource"`
+	Owner    string `json:"owner"`
+	Reason   string `json:"reason"`
+	Expires  string `json:"expires"`
+	Expired  bool   `json:"expired"`
+	// Matched counts the findings the suppression hid, or for an expired
+	// suppression, the findings it would have hidden.
+	Matched int `json:"matched"`
 }
 
 // AnalyzerConfig controls analysis behavior.
 type AnalyzerConfig struct {
 	MinMonthlyCost float64
+	// DisabledChecks drops findings with these IDs.
+	DisabledChecks map[registry.FindingID]bool
+	// RollupLongTail reports the findings under MinMonthlyCost as one
+	// LONG_TAIL_WASTE finding per repository.
+	RollupLongTail bool
+	// GroupBy breaks the summary down by "region" or a finding metadata key
+	// such as team or owner (see registry.GroupKey).
+	GroupBy string
+	// CostPeriod additionally reports finding and summary waste per day or
+	// per year (see registry.MetadataPeriodWaste). Empty or month adds
+	// nothing.
+	CostPeriod registry.CostPeriod
+	// Sort orders the findings list (by score when empty) and Top keeps
+	// only its first Top findings. The summary always covers every finding.
+	Sort SortKey
+	Top  int
+	// Scorer gives each reported finding its priority score
+	// (DefaultScoreModel when nil).
+	Scorer Scorer
+	// Suppressions hide matching findings until they expire; findings
+	// matching an expired suppression are reported with its expiry date.
+	Suppressions []registry.Suppression
+	// Windows tag the findings they cover with suppressed_by_window while
+	// active; such findings are still reported but never fail the scan.
+	Windows []registry.MigrationWindow
+	// OlderThan and NewerThan keep only findings whose image or package
+	// version was pushed at least OlderThan ago and at most NewerThan ago
+	// (0 disables each). Findings with no push time are dropped while either
+	// is set.
+	OlderThan time.Duration
+	NewerThan time.Duration
+	// Carbon estimates the CO2e of wasted storage with the pricing carbon
+	// model. Provider is the pricing key ("ecr" or "artifactregistry") of
+	// findings without provider metadata.
+	Carbon   bool
+	Provider string
+	// Now decides which suppressions have expired and which migration
+	// windows are active, and anchors the age window (time.Now when zero).
+	Now time.Time
 }
@@ -0,0 +1,43 @@
+// Package apistats counts the cloud API requests of a run and how many of
+// them were throttled, for the scan dashboard. Retried requests count once
+// per attempt.
+package apistats
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+)
+
+var calls, throttled atomic.Int64
+
+// Record counts one API request, throttled or not.
+func Record(throttle bool) {
+	calls.Add(1)
+	if throttle {
+		throttled.Add(1)
+	}
+}
+
+// Counts returns the requests and throttled requests recorded so far.
+func Counts() (total, throttledTotal int64) {
+	return calls.Load(), throttled.Load()
+}
+
+// Reset zeroes the counters.
+func Reset() {
+	calls.Store(0)
+	throttled.Store(0)
+}
+
+// IsThrottleStatus reports whether an HTTP status means the request was
+// rate limited.
+func IsThrottleStatus(code int) bool {
+	return code == http.StatusTooManyRequests
+}
+
+// IsThrottleCode reports whether an AWS error code means the request was
+// rate limited, such as ThrottlingException or RequestLimitExceeded.
+func IsThrottleCode(code string) bool {
+	return strings.Contains(code, "Throttl") || code == "TooManyRequestsException" || code == "RequestLimitExceeded"
+}
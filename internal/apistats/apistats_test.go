@@ -0,0 +1,31 @@
+package apistats
+
+import "testing"
+
+func TestCounts(t *testing.T) {
+	Reset()
+	Record(false)
+	Record(true)
+	Record(false)
+	if total, throttledTotal := Counts(); total != 3 || throttledTotal != 1 {
+		t.Errorf("Counts() = %d, %d, want 3, 1", total, throttledTotal)
+	}
+	Reset()
+	if total, _ := Counts(); total != 0 {
+		t.Errorf("after Reset, total = %d", total)
+	}
+}
+
+func TestIsThrottle(t *testing.T) {
+	for _, code := range []string{"ThrottlingException", "Throttling", "TooManyRequestsException", "RequestLimitExceeded"} {
+		if !IsThrottleCode(code) {
+			t.Errorf("IsThrottleCode(%q) = false", code)
+		}
+	}
+	if IsThrottleCode("AccessDeniedException") {
+		t.Error("AccessDeniedException is not throttling")
+	}
+	if !IsThrottleStatus(429) || IsThrottleStatus(403) {
+		t.Error("IsThrottleStatus")
+	}
+}
@@ -0,0 +1,252 @@
+// Package archive copies stale images out of a registry into OCI image
+// layout tarballs in object storage (S3, GCS or a local directory), records
+// them in an index, verifies the uploaded copy against its checksum, and only
+// then deletes the image from the registry. Restore pushes an archived image
+// back with its original digest.
+package archive
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+)
+
+// Image is one registry image selected for archiving.
+type Image struct {
+	// Provider is "aws" or "gcp".
+	Provider string
+	// Host is the registry host; empty for ECR images, whose host is
+	// resolved from the account's authorization token.
+	Host       string
+	Region     string
+	Repository string
+	Digest     string
+	Tags       []string
+	// FindingID is the finding that selected the image.
+	FindingID string
+}
+
+// Ref returns repository@digest.
+func (i Image) Ref() string {
+	return i.Repository + "@" + i.Digest
+}
+
+// Source reads images from and deletes them in a registry.
+type Source interface {
+	// Manifest returns the manifest with the given digest and its media type.
+	Manifest(ctx context.Context, repo, digest string) ([]byte, string, error)
+	// Blob returns the content of a layer or config blob. The caller closes it.
+	Blob(ctx context.Context, repo, digest string) (io.ReadCloser, error)
+	// Delete removes the image, with all its tags, from the registry.
+	Delete(ctx context.Context, img Image) error
+}
+
+// Store reads and writes archive objects by key.
+type Store interface {
+	Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error
+	// Get returns the content of an object, or an error wrapping ErrNotFound.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// URL returns the location of an object for the index and log output.
+	URL(key string) string
+}
+
+// ErrNotFound is returned by Store.Get for a missing object.
+var ErrNotFound = errors.New("object not found")
+
+// IndexSchema identifies the archive index format.
+const IndexSchema = "ecrspectre-archive/v1"
+
+// IndexKey is the key of the archive index in a store.
+const IndexKey = "index.json"
+
+// Entry records one archived image.
+type Entry struct {
+	Image      string   `json:"image"`
+	Provider   string   `json:"provider"`
+	Host       string   `json:"host,omitempty"`
+	Region     string   `json:"region,omitempty"`
+	Repository string   `json:"repository"`
+	Digest     string   `json:"digest"`
+	Tags       []string `json:"tags,omitempty"`
+	FindingID  string   `json:"finding_id,omitempty"`
+	// RunID is the run ID of the scan whose report selected the image.
+	RunID      string    `json:"run_id,omitempty"`
+	Object     string    `json:"object"`
+	SizeBytes  int64     `json:"size_bytes"`
+	SHA256     string    `json:"sha256"`
+	ArchivedAt time.Time `json:"archived_at"`
+	// Deleted reports whether the image was deleted from the registry after
+	// its archive was verified; RestoredAt is set once it is pushed back.
+	Deleted    bool       `json:"deleted"`
+	RestoredAt *time.Time `json:"restored_at,omitempty"`
+	// Kept says why an image archived for deletion stayed in the registry.
+	Kept string `json:"-"`
+}
+
+// Index lists the images archived to a store.
+type Index struct {
+	Schema  string  `json:"schema"`
+	Entries []Entry `json:"entries"`
+}
+
+// LoadIndex reads the index of a store. A store without one yields an empty index.
+func LoadIndex(ctx context.Context, store Store) (*Index, error) {
+	rc, err := store.Get(ctx, IndexKey)
+	if errors.Is(err, ErrNotFound) {
+		return &Index{Schema: IndexSchema}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read archive index: %w", err)
+	}
+	defer func() { _ = rc.Close() }()
+	var idx Index
+	if err := json.NewDecoder(rc).Decode(&idx); err != nil {
+		return nil, fmt.Errorf("parse archive index %s: %w", store.URL(IndexKey), err)
+	}
+	if idx.Schema != IndexSchema {
+		return nil, fmt.Errorf("archive index %s has schema %q, want %q", store.URL(IndexKey), idx.Schema, IndexSchema)
+	}
+	return &idx, nil
+}
+
+// Save writes the index to a store.
+func (x *Index) Save(ctx context.Context, store Store) error {
+	data, err := json.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if err := store.Put(ctx, IndexKey, strings.NewReader(string(data)), int64(len(data))); err != nil {
+		return fmt.Errorf("write archive index: %w", err)
+	}
+	return nil
+}
+
+// Lookup returns the entry of an image, if archived.
+func (x *Index) Lookup(img Image) (Entry, bool) {
+	for _, e := range x.Entries {
+		if e.Image == img.Ref() && e.Region == img.Region {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Add records e, replacing an earlier entry for the same image in the same
+// region.
+func (x *Index) Add(e Entry) {
+	for i := range x.Entries {
+		if x.Entries[i].Image == e.Image && x.Entries[i].Region == e.Region {
+			x.Entries[i] = e
+			return
+		}
+	}
+	x.Entries = append(x.Entries, e)
+}
+
+// ObjectKey returns the key of an image's tarball:
+// <region>/<repository>/<digest algorithm>-<hex>.tar.
+func ObjectKey(img Image) string {
+	key := img.Repository + "/" + strings.Replace(img.Digest, ":", "-", 1) + ".tar"
+	if img.Region != "" {
+		key = img.Region + "/" + key
+	}
+	return key
+}
+
+// DeleteGuard is called just before an archived image is deleted and
+// returns why it must stay in the registry, or "" to delete it.
+type DeleteGuard func(ctx context.Context, img Image) (string, error)
+
+// Archive copies img from src into store and verifies the stored copy by
+// reading it back and comparing its SHA-256. Only when del is set, the copy
+// verified and guard (if set) agrees is the image deleted from the registry.
+// The returned entry is ready to add to the index.
+func Archive(ctx context.Context, src Source, store Store, img Image, del bool, guard DeleteGuard, now time.Time) (Entry, error) {
+	tmp, err := os.CreateTemp("", "ecrspectre-archive-*.tar")
+	if err != nil {
+		return Entry{}, fmt.Errorf("create temporary archive: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+
+	h := sha256.New()
+	if err := WriteImage(ctx, src, img, io.MultiWriter(tmp, h)); err != nil {
+		return Entry{}, err
+	}
+	size, err := tmp.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return Entry{}, err
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return Entry{}, err
+	}
+
+	key := ObjectKey(img)
+	if err := store.Put(ctx, key, tmp, size); err != nil {
+		return Entry{}, fmt.Errorf("upload %s: %w", img.Ref(), err)
+	}
+	if err := verify(ctx, store, key, size, sum); err != nil {
+		return Entry{}, fmt.Errorf("verify %s: %w", store.URL(key), err)
+	}
+
+	entry := Entry{
+		Image:      img.Ref(),
+		Provider:   img.Provider,
+		Host:       img.Host,
+		Region:     img.Region,
+		Repository: img.Repository,
+		Digest:     img.Digest,
+		Tags:       img.Tags,
+		FindingID:  img.FindingID,
+		Object:     store.URL(key),
+		SizeBytes:  size,
+		SHA256:     sum,
+		ArchivedAt: now.UTC(),
+	}
+	if del && guard != nil {
+		reason, err := guard(ctx, img)
+		if err != nil {
+			return entry, fmt.Errorf("check %s before deleting: %w", img.Ref(), err)
+		}
+		if reason != "" {
+			entry.Kept = reason
+			return entry, nil
+		}
+	}
+	if del {
+		if err := src.Delete(ctx, img); err != nil {
+			return entry, fmt.Errorf("delete %s: %w", img.Ref(), err)
+		}
+		entry.Deleted = true
+	}
+	return entry, nil
+}
+
+// verify reads an object back and checks its size and SHA-256.
+func verify(ctx context.Context, store Store, key string, size int64, sum string) error {
+	rc, err := store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rc.Close() }()
+	h := sha256.New()
+	n, err := io.Copy(h, rc)
+	if err != nil {
+		return err
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); n != size || got != sum {
+		return fmt.Errorf("stored object is %d bytes with sha256 %s, want %d bytes with sha256 %s", n, got, size, sum)
+	}
+	return nil
+}
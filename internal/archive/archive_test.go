@@ -0,0 +1,352 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/ppiankov/ecrspectre/internal/awsapi"
+	"github.com/ppiankov/ecrspectre/internal/dockerauth"
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+	"golang.org/x/oauth2"
+)
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// fakeSource is an in-memory registry holding one image.
+type fakeSource struct {
+	content map[string][]byte
+	deleted []string
+}
+
+func newFakeSource() (*fakeSource, string) {
+	config := []byte(`{"architecture":"amd64"}`)
+	layer := []byte("layer-bytes")
+	m, _ := json.Marshal(map[string]any{
+		"schemaVersion": 2,
+		"mediaType":     "application/vnd.oci.image.manifest.v1+json",
+		"config":        map[string]any{"mediaType": "application/vnd.oci.image.config.v1+json", "digest": digestOf(config), "size": len(config)},
+		"layers":        []any{map[string]any{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": digestOf(layer), "size": len(layer)}},
+	})
+	src := &fakeSource{content: map[string][]byte{digestOf(config): config, digestOf(layer): layer, digestOf(m): m}}
+	return src, digestOf(m)
+}
+
+func (s *fakeSource) Manifest(_ context.Context, _, digest string) ([]byte, string, error) {
+	data, ok := s.content[digest]
+	if !ok {
+		return nil, "", fmt.Errorf("HTTP 404")
+	}
+	return data, "application/vnd.oci.image.manifest.v1+json", nil
+}
+
+func (s *fakeSource) Blob(_ context.Context, _, digest string) (io.ReadCloser, error) {
+	data, ok := s.content[digest]
+	if !ok {
+		return nil, fmt.Errorf("HTTP 404")
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (s *fakeSource) Delete(_ context.Context, img Image) error {
+	s.deleted = append(s.deleted, img.Ref())
+	return nil
+}
+
+func tarEntries(t *testing.T, data []byte) map[string][]byte {
+	t.Helper()
+	entries := make(map[string][]byte)
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return entries
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(tr)
+		entries[h.Name] = body
+	}
+}
+
+func TestWriteImage(t *testing.T) {
+	src, digest := newFakeSource()
+	var buf bytes.Buffer
+	img := Image{Repository: "app", Digest: digest, Tags: []string{"v1", "latest"}}
+	if err := WriteImage(context.Background(), src, img, &buf); err != nil {
+		t.Fatal(err)
+	}
+	entries := tarEntries(t, buf.Bytes())
+	if len(entries) != 5 {
+		t.Errorf("tarball has %d entries, want oci-layout, index.json and 3 blobs", len(entries))
+	}
+	for d := range src.content {
+		if _, ok := entries[blobPath(d)]; !ok {
+			t.Errorf("missing blob %s", d)
+		}
+	}
+	var index struct {
+		Manifests []descriptor `json:"manifests"`
+	}
+	if err := json.Unmarshal(entries["index.json"], &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index.Manifests) != 2 || index.Manifests[0].Digest != digest || index.Manifests[1].Annotations[refNameAnnotation] != "latest" {
+		t.Errorf("index.json = %s", entries["index.json"])
+	}
+}
+
+func TestWriteImageRejectsCorruptBlob(t *testing.T) {
+	src, digest := newFakeSource()
+	for d, data := range src.content {
+		if string(data) == "layer-bytes" {
+			src.content[d] = []byte("layer-BYTES")
+		}
+	}
+	err := WriteImage(context.Background(), src, Image{Repository: "app", Digest: digest}, io.Discard)
+	if err == nil || !strings.Contains(err.Error(), "has digest") {
+		t.Errorf("expected digest mismatch, got %v", err)
+	}
+}
+
+// corruptStore stores objects but returns different content on read.
+type corruptStore struct{ DirStore }
+
+func (s corruptStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	if key == IndexKey {
+		return s.DirStore.Get(ctx, key)
+	}
+	return io.NopCloser(strings.NewReader("garbage")), nil
+}
+
+func TestArchive(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	src, digest := newFakeSource()
+	store := DirStore{Dir: t.TempDir()}
+	img := Image{Provider: "aws", Region: "us-east-1", Repository: "team/app", Digest: digest, FindingID: "STALE_IMAGE"}
+
+	entry, err := Archive(ctx, src, store, img, true, nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !entry.Deleted || len(src.deleted) != 1 {
+		t.Errorf("image not deleted after a verified archive: %+v", entry)
+	}
+	data, err := os.ReadFile(filepath.Join(store.Dir, "us-east-1", "team", "app", strings.Replace(digest, ":", "-", 1)+".tar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != entry.SHA256 || int64(len(data)) != entry.SizeBytes {
+		t.Errorf("entry checksum does not match the stored tarball")
+	}
+
+	// A copy that does not read back intact is never deleted.
+	src.deleted = nil
+	if _, err := Archive(ctx, src, corruptStore{store}, img, true, nil, now); err == nil || !strings.Contains(err.Error(), "verify") {
+		t.Errorf("expected verification error, got %v", err)
+	}
+	if len(src.deleted) != 0 {
+		t.Errorf("image deleted after failed verification")
+	}
+
+	// Without del the image stays in the registry.
+	entry, err = Archive(ctx, src, store, img, false, nil, now)
+	if err != nil || entry.Deleted || len(src.deleted) != 0 {
+		t.Errorf("image deleted without del: %+v, %v", entry, err)
+	}
+
+	// The guard keeps an image that changed since the report in the registry.
+	keep := func(context.Context, Image) (string, error) { return "tagged v2 since the scan", nil }
+	entry, err = Archive(ctx, src, store, img, true, keep, now)
+	if err != nil || entry.Deleted || entry.Kept != "tagged v2 since the scan" || len(src.deleted) != 0 {
+		t.Errorf("guarded image deleted: %+v, %v", entry, err)
+	}
+	fail := func(context.Context, Image) (string, error) { return "", errors.New("throttled") }
+	if entry, err = Archive(ctx, src, store, img, true, fail, now); err == nil || entry.Object == "" || len(src.deleted) != 0 {
+		t.Errorf("image deleted when the guard failed: %+v, %v", entry, err)
+	}
+}
+
+func TestIndex(t *testing.T) {
+	ctx := context.Background()
+	store := DirStore{Dir: t.TempDir()}
+	idx, err := LoadIndex(ctx, store)
+	if err != nil || len(idx.Entries) != 0 {
+		t.Fatalf("LoadIndex() of an empty store = %+v, %v", idx, err)
+	}
+	img := Image{Region: "us-east-1", Repository: "app", Digest: "sha256:aa"}
+	idx.Add(Entry{Image: img.Ref(), Region: img.Region})
+	idx.Add(Entry{Image: img.Ref(), Region: img.Region, Deleted: true})
+	idx.Add(Entry{Image: img.Ref(), Region: "eu-west-1"})
+	if err := idx.Save(ctx, store); err != nil {
+		t.Fatal(err)
+	}
+	idx, err = LoadIndex(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(idx.Entries) != 2 {
+		t.Errorf("entries = %d, want one per image and region", len(idx.Entries))
+	}
+	if e, ok := idx.Lookup(img); !ok || !e.Deleted {
+		t.Errorf("Lookup() = %+v, %v", e, ok)
+	}
+}
+
+func TestRegistrySource(t *testing.T) {
+	fake, digest := newFakeSource()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, _ := r.BasicAuth(); u != "AWS" || p != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		parts := strings.Split(r.URL.Path, "/")
+		data, ok := fake.content[parts[len(parts)-1]]
+		if !strings.HasPrefix(r.URL.Path, "/v2/team/app/") || !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/vnd.oci.image.manifest.v1+json")
+		_, _ = w.Write(data)
+	}))
+	defer srv.Close()
+
+	src := &RegistrySource{
+		Host:   strings.TrimPrefix(srv.URL, "https://"),
+		Client: srv.Client(),
+		Cred:   dockerauth.Credential{Username: "AWS", Secret: "secret"},
+	}
+	var buf bytes.Buffer
+	if err := WriteImage(context.Background(), src, Image{Repository: "team/app", Digest: digest}, &buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(tarEntries(t, buf.Bytes())) != 5 {
+		t.Errorf("incomplete tarball")
+	}
+	if err := src.Delete(context.Background(), Image{}); err == nil {
+		t.Error("expected error without DeleteFunc")
+	}
+}
+
+func TestParseDestination(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Destination
+		err  bool
+	}{
+		{in: "s3://bucket/archive/ecr/", want: Destination{Scheme: "s3", Bucket: "bucket", Prefix: "archive/ecr"}},
+		{in: "gs://bucket", want: Destination{Scheme: "gs", Bucket: "bucket"}},
+		{in: "./archive", want: Destination{Prefix: "./archive"}},
+		{in: "ftp://host/x", err: true},
+		{in: "s3:///x", err: true},
+		{in: "", err: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseDestination(tt.in)
+		if (err != nil) != tt.err || got != tt.want {
+			t.Errorf("ParseDestination(%q) = %+v, %v", tt.in, got, err)
+		}
+	}
+}
+
+// objectServer stores PUT/POST bodies by the object name and serves them on GET.
+func objectServer(t *testing.T, name func(r *http.Request) string) *httptest.Server {
+	t.Helper()
+	objects := make(map[string][]byte)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut, http.MethodPost:
+			body, _ := io.ReadAll(r.Body)
+			objects[name(r)] = body
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			body, ok := objects[name(r)]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(body)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testStore(t *testing.T, store Store) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := LoadIndex(ctx, store); err != nil {
+		t.Fatalf("LoadIndex() of an empty bucket: %v", err)
+	}
+	src, digest := newFakeSource()
+	entry, err := Archive(ctx, src, store, Image{Region: "r", Repository: "app", Digest: digest}, false, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(entry.Object, "/archive/r/app/"+strings.Replace(digest, ":", "-", 1)+".tar") {
+		t.Errorf("Object = %s", entry.Object)
+	}
+}
+
+func TestS3Store(t *testing.T) {
+	srv := objectServer(t, func(r *http.Request) string { return r.URL.Path })
+	cfg := aws.Config{Region: "us-east-1", Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+	})}
+	store := S3Store{Caller: awsapi.NewCaller(cfg).WithEndpoint(srv.URL), Dest: Destination{Scheme: "s3", Bucket: "b", Prefix: "archive"}}
+	testStore(t, store)
+}
+
+func TestGCSStore(t *testing.T) {
+	srv := objectServer(t, func(r *http.Request) string {
+		if name := r.URL.Query().Get("name"); name != "" {
+			return name
+		}
+		return strings.TrimPrefix(r.URL.Path, "/storage/v1/b/b/o/")
+	})
+	caller := gcpapi.NewCallerFromTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "tok"})).WithEndpoint(srv.URL)
+	store := GCSStore{Caller: caller, Dest: Destination{Scheme: "gs", Bucket: "b", Prefix: "archive"}}
+	testStore(t, store)
+}
+
+func TestSelect(t *testing.T) {
+	const digest = "sha256:0123456789012345678901234567890123456789012345678901234567890123"
+	findings := []registry.Finding{
+		{ID: registry.FindingStaleImage, ResourceType: registry.ResourceImage, ResourceID: "team/app@" + digest, ResourceName: "team/app:v1", Region: "us-east-1"},
+		{ID: registry.FindingTieringCandidate, ResourceType: registry.ResourceImage, ResourceID: "team/app@" + digest, Region: "us-east-1"},
+		{ID: registry.FindingStaleImage, ResourceType: registry.ResourceImage, ResourceID: "us-central1-docker.pkg.dev/p/docker/api@" + digest, Region: "us-central1",
+			Metadata: map[string]any{registry.MetadataProvider: "gcp", "tags": []any{"a", "b"}}},
+		{ID: registry.FindingUnusedRepo, ResourceType: registry.ResourceRepository, ResourceID: "team/app"},
+		{ID: registry.FindingLargeImage, ResourceType: registry.ResourceImage, ResourceID: "team/big@" + digest},
+	}
+	got := Select(findings, "aws", []registry.FindingID{registry.FindingStaleImage, registry.FindingTieringCandidate})
+	if len(got) != 2 {
+		t.Fatalf("Select() = %+v, want 2 images", got)
+	}
+	if got[0].Repository != "team/app" || got[0].Tags[0] != "v1" || got[0].Provider != "aws" {
+		t.Errorf("ECR image = %+v", got[0])
+	}
+	if got[1].Host != "us-central1-docker.pkg.dev" || got[1].Repository != "p/docker/api" || len(got[1].Tags) != 2 {
+		t.Errorf("AR image = %+v", got[1])
+	}
+}
@@ -0,0 +1,167 @@
+package archive
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// descriptor is an OCI content descriptor.
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// manifest holds the fields of an image manifest or index that reference
+// other content.
+type manifest struct {
+	MediaType string       `json:"mediaType"`
+	Config    *descriptor  `json:"config"`
+	Layers    []descriptor `json:"layers"`
+	Manifests []descriptor `json:"manifests"`
+}
+
+// refNameAnnotation names the tag of a manifest in an OCI layout index.
+const refNameAnnotation = "org.opencontainers.image.ref.name"
+
+// WriteImage writes img as an OCI image layout tarball to w: the oci-layout
+// marker, an index.json naming the image (one entry per tag), and every
+// manifest and blob under blobs/, child manifests of multi-platform indexes
+// included. Each manifest and blob is checked against its digest while it is
+// copied, so a complete tarball is a verified copy of the image.
+func WriteImage(ctx context.Context, src Source, img Image, w io.Writer) error {
+	tw := tar.NewWriter(w)
+	if err := writeFile(tw, "oci-layout", []byte(`{"imageLayoutVersion":"1.0.0"}`)); err != nil {
+		return err
+	}
+	lw := &layoutWriter{src: src, repo: img.Repository, tw: tw, written: make(map[string]bool)}
+	root, err := lw.manifest(ctx, img.Digest)
+	if err != nil {
+		return fmt.Errorf("copy %s: %w", img.Ref(), err)
+	}
+
+	index := struct {
+		SchemaVersion int          `json:"schemaVersion"`
+		MediaType     string       `json:"mediaType"`
+		Manifests     []descriptor `json:"manifests"`
+	}{SchemaVersion: 2, MediaType: "application/vnd.oci.image.index.v1+json"}
+	if len(img.Tags) == 0 {
+		index.Manifests = []descriptor{root}
+	}
+	for _, tag := range img.Tags {
+		d := root
+		d.Annotations = map[string]string{refNameAnnotation: tag}
+		index.Manifests = append(index.Manifests, d)
+	}
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(tw, "index.json", data); err != nil {
+		return err
+	}
+	return tw.Close()
+}
+
+type layoutWriter struct {
+	src     Source
+	repo    string
+	tw      *tar.Writer
+	written map[string]bool
+}
+
+// manifest copies a manifest and everything it references, returning its
+// descriptor.
+func (lw *layoutWriter) manifest(ctx context.Context, digest string) (descriptor, error) {
+	body, mediaType, err := lw.src.Manifest(ctx, lw.repo, digest)
+	if err != nil {
+		return descriptor{}, fmt.Errorf("get manifest %s: %w", digest, err)
+	}
+	if got := "sha256:" + hexSHA256(body); got != digest {
+		return descriptor{}, fmt.Errorf("manifest %s has digest %s", digest, got)
+	}
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return descriptor{}, fmt.Errorf("parse manifest %s: %w", digest, err)
+	}
+	if mediaType == "" {
+		mediaType = m.MediaType
+	}
+
+	for _, child := range m.Manifests {
+		if _, err := lw.manifest(ctx, child.Digest); err != nil {
+			return descriptor{}, err
+		}
+	}
+	blobs := m.Layers
+	if m.Config != nil {
+		blobs = append([]descriptor{*m.Config}, blobs...)
+	}
+	for _, b := range blobs {
+		if err := lw.blob(ctx, b); err != nil {
+			return descriptor{}, err
+		}
+	}
+	if !lw.written[digest] {
+		if err := writeFile(lw.tw, blobPath(digest), body); err != nil {
+			return descriptor{}, err
+		}
+		lw.written[digest] = true
+	}
+	return descriptor{MediaType: mediaType, Digest: digest, Size: int64(len(body))}, nil
+}
+
+// blob streams one blob into the tarball, checking its size and digest.
+func (lw *layoutWriter) blob(ctx context.Context, d descriptor) error {
+	if lw.written[d.Digest] {
+		return nil
+	}
+	if !strings.HasPrefix(d.Digest, "sha256:") {
+		return fmt.Errorf("blob %s: unsupported digest algorithm", d.Digest)
+	}
+	rc, err := lw.src.Blob(ctx, lw.repo, d.Digest)
+	if err != nil {
+		return fmt.Errorf("get blob %s: %w", d.Digest, err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	if err := lw.tw.WriteHeader(&tar.Header{Name: blobPath(d.Digest), Mode: 0o644, Size: d.Size, Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	h := sha256.New()
+	if _, err := io.CopyN(io.MultiWriter(lw.tw, h), rc, d.Size); err != nil {
+		return fmt.Errorf("copy blob %s: %w", d.Digest, err)
+	}
+	if n, _ := io.CopyN(io.Discard, rc, 1); n > 0 {
+		return fmt.Errorf("blob %s is larger than its %d-byte descriptor", d.Digest, d.Size)
+	}
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != d.Digest {
+		return fmt.Errorf("blob %s has digest %s", d.Digest, got)
+	}
+	lw.written[d.Digest] = true
+	return nil
+}
+
+func blobPath(digest string) string {
+	return "blobs/" + strings.Replace(digest, ":", "/", 1)
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func hexSHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
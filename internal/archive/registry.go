@@ -0,0 +1,209 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/dockerauth"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// maxManifestBytes bounds the manifests read from a registry.
+const maxManifestBytes = 4 << 20
+
+// RegistrySource reads and pushes images through the Docker Registry HTTP
+// API of one registry host, and deletes them with DeleteFunc.
+type RegistrySource struct {
+	Host   string
+	Client *http.Client
+	// Cred authenticates every request (basic auth, or the bearer token it
+	// is exchanged for). A zero credential sends requests through Client
+	// unchanged, e.g. an OAuth2 client.
+	Cred dockerauth.Credential
+	// DeleteFunc deletes an image with the provider API, which removes its
+	// tags too.
+	DeleteFunc func(ctx context.Context, img Image) error
+}
+
+// Manifest implements Source.
+func (s *RegistrySource) Manifest(ctx context.Context, repo, digest string) ([]byte, string, error) {
+	resp, err := s.get(ctx, "manifests", repo, digest, strings.Join(registry.ManifestMediaTypes, ", "))
+	if err != nil {
+		return nil, "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxManifestBytes))
+	if err != nil {
+		return nil, "", err
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// Blob implements Source.
+func (s *RegistrySource) Blob(ctx context.Context, repo, digest string) (io.ReadCloser, error) {
+	resp, err := s.get(ctx, "blobs", repo, digest, "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete implements Source.
+func (s *RegistrySource) Delete(ctx context.Context, img Image) error {
+	if s.DeleteFunc == nil {
+		return fmt.Errorf("deleting images from %s is not supported", s.Host)
+	}
+	return s.DeleteFunc(ctx, img)
+}
+
+// BlobExists implements Target.
+func (s *RegistrySource) BlobExists(ctx context.Context, repo, digest string) (bool, error) {
+	resp, err := s.do(ctx, http.MethodHead, s.url(repo, "blobs", digest), nil, nil, 0)
+	if err != nil {
+		return false, err
+	}
+	_ = resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return true, nil
+	case http.StatusNotFound:
+		return false, nil
+	}
+	return false, fmt.Errorf("check blob %s: HTTP %d", digest, resp.StatusCode)
+}
+
+// PushBlob implements Target with a single-chunk upload: a POST starts the
+// upload, a PATCH sends the content and a PUT with the digest completes it.
+func (s *RegistrySource) PushBlob(ctx context.Context, repo, digest string, body io.ReadSeeker, size int64) error {
+	resp, err := s.do(ctx, http.MethodPost, s.url(repo, "blobs", "uploads/"), nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	location, err := uploadLocation(resp)
+	if err != nil {
+		return fmt.Errorf("start upload of %s: %w", digest, err)
+	}
+	hdr := http.Header{"Content-Type": {"application/octet-stream"}}
+	resp, err = s.do(ctx, http.MethodPatch, location, hdr, body, size)
+	if err != nil {
+		return err
+	}
+	if location, err = uploadLocation(resp); err != nil {
+		return fmt.Errorf("upload %s: %w", digest, err)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		return err
+	}
+	q := u.Query()
+	q.Set("digest", digest)
+	u.RawQuery = q.Encode()
+	resp, err = s.do(ctx, http.MethodPut, u.String(), nil, nil, 0)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("complete upload of %s: HTTP %d", digest, resp.StatusCode)
+	}
+	return nil
+}
+
+// PushManifest implements Target.
+func (s *RegistrySource) PushManifest(ctx context.Context, repo, ref, mediaType string, body []byte) error {
+	hdr := http.Header{"Content-Type": {mediaType}}
+	resp, err := s.do(ctx, http.MethodPut, s.url(repo, "manifests", ref), hdr, bytes.NewReader(body), int64(len(body)))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("push manifest %s: HTTP %d: %s", ref, resp.StatusCode, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// ManifestDigest implements Target.
+func (s *RegistrySource) ManifestDigest(ctx context.Context, repo, tag string) (string, error) {
+	hdr := http.Header{"Accept": {strings.Join(registry.ManifestMediaTypes, ", ")}}
+	resp, err := s.do(ctx, http.MethodHead, s.url(repo, "manifests", tag), hdr, nil, 0)
+	if err != nil {
+		return "", err
+	}
+	_ = resp.Body.Close()
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Header.Get("Docker-Content-Digest"), nil
+	case http.StatusNotFound:
+		return "", nil
+	}
+	return "", fmt.Errorf("check tag %s: HTTP %d", tag, resp.StatusCode)
+}
+
+func (s *RegistrySource) url(repo, kind, ref string) string {
+	return fmt.Sprintf("https://%s/v2/%s/%s/%s", s.Host, repo, kind, ref)
+}
+
+func (s *RegistrySource) get(ctx context.Context, kind, repo, digest, accept string) (*http.Response, error) {
+	var hdr http.Header
+	if accept != "" {
+		hdr = http.Header{"Accept": {accept}}
+	}
+	resp, err := s.do(ctx, http.MethodGet, s.url(repo, kind, digest), hdr, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		_ = resp.Body.Close()
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// do sends one request with the source's credential. A body is rewound
+// when a bearer token handshake makes the request go out twice.
+func (s *RegistrySource) do(ctx context.Context, method, rawURL string, hdr http.Header, body io.ReadSeeker, size int64) (*http.Response, error) {
+	var reqBody io.Reader
+	if body != nil {
+		reqBody = io.NopCloser(body)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, reqBody)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range hdr {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.ContentLength = size
+		req.GetBody = func() (io.ReadCloser, error) {
+			if _, err := body.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return io.NopCloser(body), nil
+		}
+	}
+	if s.Cred.IsZero() {
+		return s.Client.Do(req)
+	}
+	return dockerauth.Do(ctx, s.Client, req, s.Cred)
+}
+
+// uploadLocation returns the absolute Location of a blob upload response.
+func uploadLocation(resp *http.Response) (string, error) {
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	loc, err := resp.Request.URL.Parse(resp.Header.Get("Location"))
+	if err != nil {
+		return "", fmt.Errorf("invalid upload location: %w", err)
+	}
+	return loc.String(), nil
+}
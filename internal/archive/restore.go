@@ -0,0 +1,261 @@
+package archive
+
+import (
+	"archive/tar"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Target pushes images to a registry.
+type Target interface {
+	// BlobExists reports whether the repository already has a blob.
+	BlobExists(ctx context.Context, repo, digest string) (bool, error)
+	PushBlob(ctx context.Context, repo, digest string, body io.ReadSeeker, size int64) error
+	// PushManifest stores a manifest under ref, a digest or a tag.
+	PushManifest(ctx context.Context, repo, ref, mediaType string, body []byte) error
+	// ManifestDigest returns the digest a tag points at, or "" when the
+	// repository has no such tag.
+	ManifestDigest(ctx context.Context, repo, tag string) (string, error)
+}
+
+// RegistryImage returns the registry image an entry was archived from.
+func (e Entry) RegistryImage() Image {
+	return Image{
+		Provider:   e.Provider,
+		Host:       e.Host,
+		Region:     e.Region,
+		Repository: e.Repository,
+		Digest:     e.Digest,
+		Tags:       e.Tags,
+		FindingID:  e.FindingID,
+	}
+}
+
+// Find returns the entries matching ref: a digest, repository@digest, a tag
+// or repository:tag. A non-empty region limits the match to that region.
+func (x *Index) Find(ref, region string) []Entry {
+	var repo, digest, tag string
+	switch {
+	case strings.Contains(ref, "@"):
+		repo, digest, _ = strings.Cut(ref, "@")
+	case strings.HasPrefix(ref, "sha256:"):
+		digest = ref
+	default:
+		tag = ref
+		if i := strings.LastIndex(ref, ":"); i >= 0 {
+			repo, tag = ref[:i], ref[i+1:]
+		}
+	}
+	var out []Entry
+	for _, e := range x.Entries {
+		if (region != "" && e.Region != region) || (repo != "" && e.Repository != repo) || (digest != "" && e.Digest != digest) {
+			continue
+		}
+		if tag != "" && !slices.Contains(e.Tags, tag) {
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// RestoreResult reports what a restore pushed.
+type RestoreResult struct {
+	// Blobs is the number of blobs uploaded; blobs the repository still
+	// had are not uploaded again.
+	Blobs int
+	// Tags were pointed at the restored image. SkippedTags already point
+	// at another image in the repository and were left alone.
+	Tags        []string
+	SkippedTags []string
+}
+
+// Restore pushes the image of an index entry from store back to its
+// repository through target. The tarball is checked against the entry's
+// SHA-256 before anything is pushed, and manifests are pushed byte for byte,
+// so the image keeps its digest. Each archived tag is restored unless the
+// repository has since moved it to another image.
+func Restore(ctx context.Context, store Store, target Target, e Entry) (RestoreResult, error) {
+	img := e.RegistryImage()
+	rc, err := store.Get(ctx, ObjectKey(img))
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("read archive of %s: %w", img.Ref(), err)
+	}
+	defer func() { _ = rc.Close() }()
+
+	tmp, err := os.CreateTemp("", "ecrspectre-restore-*.tar")
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("create temporary archive: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), rc); err != nil {
+		return RestoreResult{}, fmt.Errorf("download archive of %s: %w", img.Ref(), err)
+	}
+	if got := hex.EncodeToString(h.Sum(nil)); got != e.SHA256 {
+		return RestoreResult{}, fmt.Errorf("archive %s has sha256 %s, but the index records %s", e.Object, got, e.SHA256)
+	}
+
+	files, err := readLayout(tmp)
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("read archive %s: %w", e.Object, err)
+	}
+	rootType, err := files.rootMediaType(e.Digest)
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("read archive %s: %w", e.Object, err)
+	}
+	p := &pusher{target: target, repo: e.Repository, files: files}
+	root, rootType, err := p.manifest(ctx, e.Digest, rootType)
+	if err != nil {
+		return RestoreResult{}, fmt.Errorf("restore %s: %w", img.Ref(), err)
+	}
+
+	res := RestoreResult{Blobs: p.blobs}
+	for _, tag := range e.Tags {
+		current, err := target.ManifestDigest(ctx, e.Repository, tag)
+		if err != nil {
+			return res, fmt.Errorf("restore %s: %w", img.Ref(), err)
+		}
+		if current != "" && current != e.Digest {
+			res.SkippedTags = append(res.SkippedTags, tag)
+			continue
+		}
+		if current == "" {
+			if err := target.PushManifest(ctx, e.Repository, tag, rootType, root); err != nil {
+				return res, fmt.Errorf("restore %s: %w", img.Ref(), err)
+			}
+		}
+		res.Tags = append(res.Tags, tag)
+	}
+	return res, nil
+}
+
+// layoutFiles maps the paths of an OCI layout tarball to their content.
+type layoutFiles map[string]*io.SectionReader
+
+// readLayout indexes the regular files of a tarball by their offset in f.
+func readLayout(f *os.File) (layoutFiles, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	files := make(layoutFiles)
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		offset, err := f.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = io.NewSectionReader(f, offset, hdr.Size)
+	}
+}
+
+// read returns the content of a file.
+func (files layoutFiles) read(name string) ([]byte, error) {
+	sr, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is missing", name)
+	}
+	return io.ReadAll(io.NewSectionReader(sr, 0, sr.Size()))
+}
+
+// rootMediaType returns the media type index.json records for digest.
+func (files layoutFiles) rootMediaType(digest string) (string, error) {
+	data, err := files.read("index.json")
+	if err != nil {
+		return "", err
+	}
+	var index struct {
+		Manifests []descriptor `json:"manifests"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return "", fmt.Errorf("parse index.json: %w", err)
+	}
+	for _, d := range index.Manifests {
+		if d.Digest == digest {
+			return d.MediaType, nil
+		}
+	}
+	return "", fmt.Errorf("index.json does not list %s", digest)
+}
+
+type pusher struct {
+	target Target
+	repo   string
+	files  layoutFiles
+	blobs  int
+}
+
+// manifest pushes the blobs and child manifests a manifest references, then
+// the manifest itself by digest, and returns its content and media type.
+func (p *pusher) manifest(ctx context.Context, digest, mediaType string) ([]byte, string, error) {
+	body, err := p.files.read(blobPath(digest))
+	if err != nil {
+		return nil, "", err
+	}
+	if got := "sha256:" + hexSHA256(body); got != digest {
+		return nil, "", fmt.Errorf("manifest %s has digest %s", digest, got)
+	}
+	var m manifest
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, "", fmt.Errorf("parse manifest %s: %w", digest, err)
+	}
+	if m.MediaType != "" {
+		mediaType = m.MediaType
+	}
+
+	for _, child := range m.Manifests {
+		if _, _, err := p.manifest(ctx, child.Digest, child.MediaType); err != nil {
+			return nil, "", err
+		}
+	}
+	blobs := m.Layers
+	if m.Config != nil {
+		blobs = append([]descriptor{*m.Config}, blobs...)
+	}
+	for _, b := range blobs {
+		if err := p.blob(ctx, b); err != nil {
+			return nil, "", err
+		}
+	}
+	if err := p.target.PushManifest(ctx, p.repo, digest, mediaType, body); err != nil {
+		return nil, "", err
+	}
+	return body, mediaType, nil
+}
+
+func (p *pusher) blob(ctx context.Context, d descriptor) error {
+	exists, err := p.target.BlobExists(ctx, p.repo, d.Digest)
+	if err != nil || exists {
+		return err
+	}
+	sr, ok := p.files[blobPath(d.Digest)]
+	if !ok {
+		return fmt.Errorf("blob %s is missing from the archive", d.Digest)
+	}
+	if err := p.target.PushBlob(ctx, p.repo, d.Digest, io.NewSectionReader(sr, 0, sr.Size()), sr.Size()); err != nil {
+		return fmt.Errorf("push blob %s: %w", d.Digest, err)
+	}
+	p.blobs++
+	return nil
+}
@@ -0,0 +1,157 @@
+package archive
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pushRegistry is an in-memory registry accepting single-chunk blob uploads
+// and manifest pushes.
+type pushRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte // by digest or tag
+	uploads   map[string][]byte
+}
+
+func newPushRegistry(t *testing.T) (*pushRegistry, *RegistrySource) {
+	t.Helper()
+	reg := &pushRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}, uploads: map[string][]byte{}}
+	srv := httptest.NewTLSServer(reg)
+	t.Cleanup(srv.Close)
+	return reg, &RegistrySource{Host: strings.TrimPrefix(srv.URL, "https://"), Client: srv.Client()}
+}
+
+func (reg *pushRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	path := strings.TrimPrefix(r.URL.Path, "/v2/app/")
+	body, _ := io.ReadAll(r.Body)
+	switch {
+	case r.Method == http.MethodPost && path == "blobs/uploads/":
+		delete(reg.uploads, "blobs/uploads/1")
+		w.Header().Set("Location", "/v2/app/blobs/uploads/1")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "blobs/uploads/"):
+		reg.uploads[path] = append(reg.uploads[path], body...)
+		w.Header().Set("Location", r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "blobs/uploads/"):
+		data := reg.uploads[path]
+		if digestOf(data) != r.URL.Query().Get("digest") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		reg.blobs[digestOf(data)] = data
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodHead && strings.HasPrefix(path, "blobs/"):
+		if _, ok := reg.blobs[strings.TrimPrefix(path, "blobs/")]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	case r.Method == http.MethodPut && strings.HasPrefix(path, "manifests/"):
+		reg.manifests[strings.TrimPrefix(path, "manifests/")] = body
+		w.WriteHeader(http.StatusCreated)
+	case r.Method == http.MethodHead && strings.HasPrefix(path, "manifests/"):
+		m, ok := reg.manifests[strings.TrimPrefix(path, "manifests/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digestOf(m))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func archivedEntry(t *testing.T, store Store) (Entry, *fakeSource) {
+	t.Helper()
+	src, digest := newFakeSource()
+	img := Image{Provider: "aws", Region: "us-east-1", Repository: "app", Digest: digest, Tags: []string{"v1", "latest"}}
+	entry, err := Archive(context.Background(), src, store, img, true, nil, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return entry, src
+}
+
+func TestRestore(t *testing.T) {
+	store := DirStore{Dir: t.TempDir()}
+	entry, src := archivedEntry(t, store)
+	reg, target := newPushRegistry(t)
+	reg.manifests["latest"] = []byte(`{"newer":true}`)
+
+	res, err := Restore(context.Background(), store, target, entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(reg.manifests[entry.Digest]) != string(src.content[entry.Digest]) {
+		t.Errorf("manifest not restored byte for byte")
+	}
+	if res.Blobs != 2 || len(reg.blobs) != 2 {
+		t.Errorf("restored %d blobs, registry has %d, want 2", res.Blobs, len(reg.blobs))
+	}
+	if len(res.Tags) != 1 || res.Tags[0] != "v1" || digestOf(reg.manifests["v1"]) != entry.Digest {
+		t.Errorf("Tags = %v", res.Tags)
+	}
+	if len(res.SkippedTags) != 1 || res.SkippedTags[0] != "latest" || string(reg.manifests["latest"]) != `{"newer":true}` {
+		t.Errorf("SkippedTags = %v; a moved tag must be left alone", res.SkippedTags)
+	}
+
+	// Blobs the repository still has are not uploaded again.
+	res, err = Restore(context.Background(), store, target, entry)
+	if err != nil || res.Blobs != 0 {
+		t.Errorf("second restore uploaded %d blobs, %v", res.Blobs, err)
+	}
+}
+
+func TestRestoreRejectsModifiedArchive(t *testing.T) {
+	store := DirStore{Dir: t.TempDir()}
+	entry, _ := archivedEntry(t, store)
+	path := filepath.Join(store.Dir, filepath.FromSlash(ObjectKey(entry.RegistryImage())))
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.Write([]byte("tampered"))
+	_ = f.Close()
+
+	reg, target := newPushRegistry(t)
+	if _, err := Restore(context.Background(), store, target, entry); err == nil || !strings.Contains(err.Error(), "index records") {
+		t.Errorf("expected checksum error, got %v", err)
+	}
+	if len(reg.manifests) != 0 || len(reg.blobs) != 0 {
+		t.Error("pushed content from a modified archive")
+	}
+}
+
+func TestIndexFind(t *testing.T) {
+	idx := &Index{Entries: []Entry{
+		{Image: "app@sha256:aa", Repository: "app", Digest: "sha256:aa", Region: "us-east-1", Tags: []string{"v1"}},
+		{Image: "app@sha256:aa", Repository: "app", Digest: "sha256:aa", Region: "eu-west-1", Tags: []string{"v1"}},
+		{Image: "web@sha256:bb", Repository: "web", Digest: "sha256:bb", Region: "us-east-1", Tags: []string{"v1"}},
+	}}
+	tests := []struct {
+		ref, region string
+		want        int
+	}{
+		{"sha256:aa", "", 2},
+		{"sha256:aa", "eu-west-1", 1},
+		{"web@sha256:bb", "", 1},
+		{"v1", "us-east-1", 2},
+		{"app:v1", "us-east-1", 1},
+		{"app:v2", "", 0},
+	}
+	for _, tt := range tests {
+		if got := idx.Find(tt.ref, tt.region); len(got) != tt.want {
+			t.Errorf("Find(%q, %q) = %d entries, want %d", tt.ref, tt.region, len(got), tt.want)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package archive
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/imageref"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// Select returns the images of findings with one of the given IDs, each
+// image once. provider is the report's provider, used for findings without
+// provider metadata. Findings that do not name a single image by digest
+// (repositories, package versions) are skipped.
+func Select(findings []registry.Finding, provider string, ids []registry.FindingID) []Image {
+	var images []Image
+	seen := make(map[string]bool)
+	for _, f := range findings {
+		if f.ResourceType != registry.ResourceImage || !slices.Contains(ids, f.ID) {
+			continue
+		}
+		p := provider
+		if v, ok := f.Metadata[registry.MetadataProvider].(string); ok && v != "" {
+			p = v
+		}
+		img, ok := imageOf(f, p)
+		if !ok {
+			continue
+		}
+		key := img.Host + "/" + img.Region + "/" + img.Ref()
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		images = append(images, img)
+	}
+	return images
+}
+
+// imageOf maps an image finding to the image it names. ECR findings name
+// repository@digest, Artifact Registry findings the full image URI.
+func imageOf(f registry.Finding, provider string) (Image, bool) {
+	img := Image{Provider: provider, Region: f.Region, Tags: findingTags(f), FindingID: string(f.ID)}
+	switch provider {
+	case "aws":
+		repo, digest, ok := strings.Cut(f.ResourceID, "@")
+		if !ok || repo == "" {
+			return Image{}, false
+		}
+		img.Repository, img.Digest = repo, digest
+	case "gcp":
+		ref, err := imageref.Parse(f.ResourceID)
+		if err != nil || ref.Host == "" {
+			return Image{}, false
+		}
+		img.Host, img.Repository, img.Digest = ref.Host, ref.Repository, ref.Digest
+	default:
+		return Image{}, false
+	}
+	if !strings.HasPrefix(img.Digest, "sha256:") {
+		return Image{}, false
+	}
+	return img, true
+}
+
+// findingTags returns the tags of an image finding: the full tag list when
+// recorded in its metadata, else the tag of its resource name.
+func findingTags(f registry.Finding) []string {
+	if raw, ok := f.Metadata["tags"].([]any); ok {
+		tags := make([]string, 0, len(raw))
+		for _, t := range raw {
+			tags = append(tags, fmt.Sprint(t))
+		}
+		return tags
+	}
+	if raw, ok := f.Metadata["tags"].([]string); ok {
+		return raw
+	}
+	if i := strings.LastIndex(f.ResourceName, ":"); i >= 0 && !strings.Contains(f.ResourceName[i:], "/") {
+		return []string{f.ResourceName[i+1:]}
+	}
+	return nil
+}
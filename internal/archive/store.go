@@ -0,0 +1,160 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/awsapi"
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+)
+
+// Destination is a parsed --to location.
+type Destination struct {
+	// Scheme is "s3", "gs" or "" for a local directory.
+	Scheme string
+	// Bucket is empty for a local directory.
+	Bucket string
+	// Prefix is the key prefix in the bucket, or the directory.
+	Prefix string
+}
+
+// ParseDestination parses s3://bucket/prefix, gs://bucket/prefix or a local
+// directory path.
+func ParseDestination(s string) (Destination, error) {
+	scheme, rest, ok := strings.Cut(s, "://")
+	if !ok {
+		if s == "" {
+			return Destination{}, fmt.Errorf("archive destination is empty")
+		}
+		return Destination{Prefix: s}, nil
+	}
+	if scheme != "s3" && scheme != "gs" {
+		return Destination{}, fmt.Errorf("unsupported archive destination %q (want s3://, gs:// or a directory)", s)
+	}
+	bucket, prefix, _ := strings.Cut(rest, "/")
+	if bucket == "" {
+		return Destination{}, fmt.Errorf("archive destination %q has no bucket", s)
+	}
+	return Destination{Scheme: scheme, Bucket: bucket, Prefix: strings.Trim(prefix, "/")}, nil
+}
+
+func (d Destination) key(key string) string {
+	if d.Prefix == "" {
+		return key
+	}
+	return d.Prefix + "/" + key
+}
+
+// DirStore stores archive objects as files under a local directory.
+type DirStore struct {
+	Dir string
+}
+
+// Put implements Store.
+func (s DirStore) Put(_ context.Context, key string, body io.ReadSeeker, _ int64) error {
+	p := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	f, err := os.Create(p)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Get implements Store.
+func (s DirStore) Get(_ context.Context, key string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", s.path(key), ErrNotFound)
+	}
+	return f, err
+}
+
+// URL implements Store.
+func (s DirStore) URL(key string) string {
+	return s.path(key)
+}
+
+func (s DirStore) path(key string) string {
+	return filepath.Join(s.Dir, filepath.FromSlash(key))
+}
+
+// S3Store stores archive objects in an S3 bucket. Objects are uploaded with
+// a single PUT, which S3 limits to 5 GB.
+type S3Store struct {
+	Caller *awsapi.Caller
+	Dest   Destination
+}
+
+// Put implements Store.
+func (s S3Store) Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error {
+	return s.Caller.PutObject(ctx, s.Dest.Bucket, s.Dest.key(key), body, size)
+}
+
+// Get implements Store.
+func (s S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	rc, err := s.Caller.GetObject(ctx, s.Dest.Bucket, s.Dest.key(key))
+	var apiErr *awsapi.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", s.URL(key), ErrNotFound)
+	}
+	return rc, err
+}
+
+// URL implements Store.
+func (s S3Store) URL(key string) string {
+	return "s3://" + s.Dest.Bucket + "/" + s.Dest.key(key)
+}
+
+// gcsURL is the Cloud Storage JSON API.
+const gcsURL = "https://storage.googleapis.com"
+
+// GCSStore stores archive objects in a Cloud Storage bucket.
+type GCSStore struct {
+	Caller *gcpapi.Caller
+	Dest   Destination
+}
+
+// Put implements Store.
+func (s GCSStore) Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error {
+	u := fmt.Sprintf("%s/upload/storage/v1/b/%s/o?uploadType=media&name=%s", gcsURL, url.PathEscape(s.Dest.Bucket), url.QueryEscape(s.Dest.key(key)))
+	contentType := "application/x-tar"
+	if ext := path.Ext(key); ext != ".tar" {
+		contentType = mime.TypeByExtension(ext)
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+	}
+	return s.Caller.Upload(ctx, u, contentType, body, size)
+}
+
+// Get implements Store.
+func (s GCSStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	u := fmt.Sprintf("%s/storage/v1/b/%s/o/%s?alt=media", gcsURL, url.PathEscape(s.Dest.Bucket), url.PathEscape(s.Dest.key(key)))
+	rc, err := s.Caller.Download(ctx, u)
+	var statusErr *gcpapi.StatusError
+	if errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound {
+		return nil, fmt.Errorf("%s: %w", s.URL(key), ErrNotFound)
+	}
+	return rc, err
+}
+
+// URL implements Store.
+func (s GCSStore) URL(key string) string {
+	return "gs://" + s.Dest.Bucket + "/" + s.Dest.key(key)
+}
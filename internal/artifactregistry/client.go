@@ -2,13 +2,31 @@ package artifactregistry
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	ar "cloud.google.com/go/artifactregistry/apiv1"
 	arpb "cloud.google.com/go/artifactregistry/apiv1/artifactregistrypb"
+	"github.com/ppiankov/ecrspectre/internal/apistats"
+	"github.com/ppiankov/ecrspectre/internal/dockerauth"
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+	"github.com/ppiankov/ecrspectre/internal/imageref"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+	locationpb "google.golang.org/genproto/googleapis/cloud/location"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // Repository represents a GCP Artifact Registry repository.
@@ -17,6 +35,18 @@ type Repository struct {
 	Location string
 	RepoID   string
 	Format   string
+	// Mode is STANDARD_REPOSITORY, REMOTE_REPOSITORY (a pull-through cache
+	// of an upstream) or VIRTUAL_REPOSITORY (a view over other repositories).
+	Mode      string
+	SizeBytes int64
+	// Labels are the repository's labels, used for exclusion and attribution.
+	Labels map[string]string
+	// CleanupPolicies counts the repository's cleanup policies and
+	// CleanupDeletes reports whether any of them deletes images.
+	CleanupPolicies int
+	CleanupDeletes  bool
+	// CleanupDryRun is set when cleanup policies only log what they would delete.
+	CleanupDryRun bool
 }
 
 // DockerImage represents a Docker image in Artifact Registry.
@@ -27,13 +57,65 @@ type DockerImage struct {
 	SizeBytes    int64
 	UploadTime   time.Time
 	MediaType    string
+	ArtifactType string
 	RepositoryID string
+	// Layers is filled in by the scanner in deep mode.
+	Layers *registry.LayerAnalysis
+	// Index is the resolved multi-arch index, filled in when used platforms
+	// are configured or in deep mode.
+	Index *registry.Manifest
+	// Orphaned marks an untagged platform manifest no index references,
+	// filled in by the scanner in deep mode.
+	Orphaned bool
+	// Signed and HasSBOM mark an image with a cosign signature or SBOM tag,
+	// or a signature or SBOM referrer, filled in by the scanner when
+	// signatures or SBOMs are required.
+	Signed  bool
+	HasSBOM bool
+	// BuildDate is read from the config blob of deployed images in deep
+	// mode when a maximum deployed age is configured.
+	BuildDate time.Time
+}
+
+// PackageVersion is a version of a Maven, npm, Python or generic package,
+// aggregated from the files it owns.
+type PackageVersion struct {
+	Name      string // full version resource name
+	Package   string
+	Version   string
+	Files     int
+	SizeBytes int64
+	// CreateTime is the creation of the version's oldest file.
+	CreateTime time.Time
+	// FetchTime is the last download of any of its files, if recorded.
+	FetchTime time.Time
+}
+
+// packageFormats are the non-Docker repository formats scanned by package
+// version. Other formats (APT, YUM, Go, ...) are skipped.
+var packageFormats = map[arpb.Repository_Format]bool{
+	arpb.Repository_MAVEN:   true,
+	arpb.Repository_NPM:     true,
+	arpb.Repository_PYTHON:  true,
+	arpb.Repository_GENERIC: true,
+}
+
+// isPackageFormat reports whether a repository format is scanned by package
+// version rather than Docker image.
+func isPackageFormat(format string) bool {
+	return packageFormats[arpb.Repository_Format(arpb.Repository_Format_value[format])]
 }
 
 // ARAPI defines the subset of the Artifact Registry API used by the scanner.
 type ARAPI interface {
+	ListLocations(ctx context.Context, project string) ([]string, error)
 	ListRepositories(ctx context.Context, project, location string) ([]Repository, error)
+	GetRepository(ctx context.Context, project, location, repoID string) (*Repository, error)
 	ListDockerImages(ctx context.Context, parent string) ([]DockerImage, error)
+	ListPackageVersions(ctx context.Context, parent string) ([]PackageVersion, error)
+	GetManifest(ctx context.Context, imageURI string) (string, error)
+	GetImageConfig(ctx context.Context, imageURI, configDigest string) ([]byte, error)
+	VulnerabilityCounts(ctx context.Context, imageURI string) (map[string]int, error)
 	Close() error
 }
 
@@ -41,23 +123,84 @@ type ARAPI interface {
 type Client struct {
 	inner   *ar.Client
 	project string
+	// registryHTTP is an authenticated client for the Docker registry API,
+	// created on first manifest fetch. registryCred is set instead of OAuth2
+	// when the docker CLI's credentials are used.
+	registryHTTP *http.Client
+	registryCred *dockerauth.Credential
+	// httpBase carries the TLS settings of registry requests; nil uses
+	// http.DefaultClient.
+	httpBase *http.Client
+	// analysis calls the Container Analysis API, created on first use.
+	analysis     *gcpapi.Caller
+	analysisErr  error
+	analysisOnce sync.Once
 }
 
-// NewClient creates a new Artifact Registry client.
-func NewClient(ctx context.Context, project string) (*Client, error) {
-	c, err := ar.NewClient(ctx)
+// containerAnalysisURL is the Container Analysis REST API that stores
+// vulnerability occurrences found by Artifact Registry scanning.
+const containerAnalysisURL = "https://containeranalysis.googleapis.com/v1"
+
+// NewClient creates a new Artifact Registry client. A non-empty endpoint
+// (host:port, e.g. a Private Service Connect endpoint) replaces the default
+// Artifact Registry API endpoint.
+func NewClient(ctx context.Context, project, endpoint string) (*Client, error) {
+	opts := []option.ClientOption{option.WithGRPCDialOption(grpc.WithChainUnaryInterceptor(quotaCalls(project)))}
+	if endpoint != "" {
+		opts = append(opts, option.WithEndpoint(endpoint))
+	}
+	c, err := ar.NewClient(ctx, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("create artifact registry client: %w", err)
 	}
 	return &Client{inner: c, project: project}, nil
 }
 
+// quotaCalls paces the Artifact Registry calls of project with its
+// gcpapi.ProjectLimiter and retries calls denied with RESOURCE_EXHAUSTED.
+// Every attempt is recorded in apistats, denials as throttled.
+func quotaCalls(project string) grpc.UnaryClientInterceptor {
+	limiter := gcpapi.ProjectLimiter(project)
+	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return limiter.Retry(ctx, func() error {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			apistats.Record(isResourceExhausted(err))
+			return err
+		}, isResourceExhausted)
+	}
+}
+
+func isResourceExhausted(err error) bool {
+	return status.Code(err) == codes.ResourceExhausted
+}
+
 // Close releases client resources.
 func (c *Client) Close() error {
 	return c.inner.Close()
 }
 
-// ListRepositories returns all Docker-format repositories in a given location.
+// ListLocations returns the IDs of the locations Artifact Registry serves
+// for a project.
+func (c *Client) ListLocations(ctx context.Context, project string) ([]string, error) {
+	name := "projects/" + project
+	it := c.inner.ListLocations(ctx, &locationpb.ListLocationsRequest{Name: name})
+
+	var locations []string
+	for {
+		loc, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list locations of %s: %w", name, err)
+		}
+		locations = append(locations, loc.GetLocationId())
+	}
+	return locations, nil
+}
+
+// ListRepositories returns all Docker and package (Maven, npm, Python,
+// generic) repositories in a given location.
 func (c *Client) ListRepositories(ctx context.Context, project, location string) ([]Repository, error) {
 	parent := fmt.Sprintf("projects/%s/locations/%s", project, location)
 	it := c.inner.ListRepositories(ctx, &arpb.ListRepositoriesRequest{
@@ -73,14 +216,8 @@ func (c *Client) ListRepositories(ctx context.Context, project, location string)
 		if err != nil {
 			return nil, fmt.Errorf("list repositories in %s: %w", parent, err)
 		}
-		// Only include Docker repositories
-		if repo.GetFormat() == arpb.Repository_DOCKER {
-			repos = append(repos, Repository{
-				Name:     repo.GetName(),
-				Location: location,
-				RepoID:   extractRepoID(repo.GetName()),
-				Format:   "DOCKER",
-			})
+		if supportedFormat(repo.GetFormat()) {
+			repos = append(repos, newRepository(repo, location, extractRepoID(repo.GetName())))
 		}
 	}
 
@@ -88,6 +225,49 @@ func (c *Client) ListRepositories(ctx context.Context, project, location string)
 	return repos, nil
 }
 
+// GetRepository returns a single Docker or package repository, or nil if it does not
+// exist in the location.
+func (c *Client) GetRepository(ctx context.Context, project, location, repoID string) (*Repository, error) {
+	name := fmt.Sprintf("projects/%s/locations/%s/repositories/%s", project, location, repoID)
+	repo, err := c.inner.GetRepository(ctx, &arpb.GetRepositoryRequest{Name: name})
+	if err != nil {
+		if status.Code(err) == codes.NotFound {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("get repository %s: %w", name, err)
+	}
+	if !supportedFormat(repo.GetFormat()) {
+		return nil, fmt.Errorf("repository %s has unsupported format %s", name, repo.GetFormat())
+	}
+	r := newRepository(repo, location, repoID)
+	return &r, nil
+}
+
+func supportedFormat(f arpb.Repository_Format) bool {
+	return f == arpb.Repository_DOCKER || packageFormats[f]
+}
+
+// newRepository converts a repository from the API.
+func newRepository(repo *arpb.Repository, location, repoID string) Repository {
+	r := Repository{
+		Name:            repo.GetName(),
+		Location:        location,
+		RepoID:          repoID,
+		Format:          repo.GetFormat().String(),
+		Mode:            repo.GetMode().String(),
+		Labels:          repo.GetLabels(),
+		SizeBytes:       repo.GetSizeBytes(),
+		CleanupPolicies: len(repo.GetCleanupPolicies()),
+		CleanupDryRun:   repo.GetCleanupPolicyDryRun(),
+	}
+	for _, p := range repo.GetCleanupPolicies() {
+		if p.GetAction() == arpb.CleanupPolicy_DELETE {
+			r.CleanupDeletes = true
+		}
+	}
+	return r
+}
+
 // ListDockerImages returns all Docker images in a repository.
 func (c *Client) ListDockerImages(ctx context.Context, parent string) ([]DockerImage, error) {
 	it := c.inner.ListDockerImages(ctx, &arpb.ListDockerImagesRequest{
@@ -116,6 +296,7 @@ func (c *Client) ListDockerImages(ctx context.Context, parent string) ([]DockerI
 			SizeBytes:    img.GetImageSizeBytes(),
 			UploadTime:   uploadTime,
 			MediaType:    img.GetMediaType(),
+			ArtifactType: img.GetArtifactType(),
 			RepositoryID: extractRepoIDFromImage(img.GetName()),
 		})
 	}
@@ -123,6 +304,211 @@ func (c *Client) ListDockerImages(ctx context.Context, parent string) ([]DockerI
 	return images, nil
 }
 
+// ListPackageVersions returns the package versions in a repository, built
+// from its files: a version's size is the total of the files it owns. Files
+// not owned by a version (e.g. Maven metadata) are ignored.
+func (c *Client) ListPackageVersions(ctx context.Context, parent string) ([]PackageVersion, error) {
+	it := c.inner.ListFiles(ctx, &arpb.ListFilesRequest{
+		Parent: parent,
+	})
+
+	byOwner := make(map[string]*PackageVersion)
+	var order []string
+	for {
+		f, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("list files in %s: %w", parent, err)
+		}
+
+		owner := f.GetOwner()
+		pkg, version, ok := parseVersionName(owner)
+		if !ok {
+			continue
+		}
+		v := byOwner[owner]
+		if v == nil {
+			v = &PackageVersion{Name: owner, Package: pkg, Version: version}
+			byOwner[owner] = v
+			order = append(order, owner)
+		}
+		v.Files++
+		v.SizeBytes += f.GetSizeBytes()
+		if f.GetCreateTime() != nil {
+			if t := f.GetCreateTime().AsTime(); v.CreateTime.IsZero() || t.Before(v.CreateTime) {
+				v.CreateTime = t
+			}
+		}
+		if f.GetFetchTime() != nil {
+			if t := f.GetFetchTime().AsTime(); t.After(v.FetchTime) {
+				v.FetchTime = t
+			}
+		}
+	}
+
+	versions := make([]PackageVersion, 0, len(order))
+	for _, owner := range order {
+		versions = append(versions, *byOwner[owner])
+	}
+	return versions, nil
+}
+
+// parseVersionName splits a version resource name of the form
+// projects/{p}/locations/{l}/repositories/{r}/packages/{package}/versions/{version}
+// into its unescaped package and version.
+func parseVersionName(name string) (pkg, version string, ok bool) {
+	parts := strings.Split(name, "/")
+	if len(parts) != 10 || parts[6] != "packages" || parts[8] != "versions" {
+		return "", "", false
+	}
+	pkg, err := url.PathUnescape(parts[7])
+	if err != nil {
+		pkg = parts[7]
+	}
+	version, err = url.PathUnescape(parts[9])
+	if err != nil {
+		version = parts[9]
+	}
+	return pkg, version, true
+}
+
+// GetManifest fetches an image manifest from the Docker registry API of
+// Artifact Registry. imageURI has the form {host}/{project}/{repo}/{image}@{digest}.
+func (c *Client) GetManifest(ctx context.Context, imageURI string) (string, error) {
+	ref, err := imageref.Parse(imageURI)
+	if err != nil || ref.Host == "" || ref.Digest == "" {
+		return "", fmt.Errorf("invalid image URI %q", imageURI)
+	}
+	body, err := c.registryGet(ctx, ref.Host, fmt.Sprintf("/v2/%s/manifests/%s", ref.Repository, ref.Digest), strings.Join(registry.ManifestMediaTypes, ", "))
+	if err != nil {
+		return "", fmt.Errorf("get manifest %s: %w", imageURI, err)
+	}
+	return string(body), nil
+}
+
+// GetImageConfig fetches the config blob with the given digest from the
+// repository of imageURI, through the Docker registry API.
+func (c *Client) GetImageConfig(ctx context.Context, imageURI, configDigest string) ([]byte, error) {
+	ref, err := imageref.Parse(imageURI)
+	if err != nil || ref.Host == "" {
+		return nil, fmt.Errorf("invalid image URI %q", imageURI)
+	}
+	body, err := c.registryGet(ctx, ref.Host, fmt.Sprintf("/v2/%s/blobs/%s", ref.Repository, configDigest), "")
+	if err != nil {
+		return nil, fmt.Errorf("get config %s of %s: %w", configDigest, imageURI, err)
+	}
+	return body, nil
+}
+
+// SetHTTPClient sets the client whose transport carries Docker registry API
+// requests, e.g. one trusting a private CA.
+func (c *Client) SetHTTPClient(client *http.Client) {
+	c.httpBase = client
+}
+
+func (c *Client) baseHTTP() *http.Client {
+	if c.httpBase == nil {
+		return http.DefaultClient
+	}
+	return c.httpBase
+}
+
+// registryGet reads a document of at most 4 MiB from the Docker registry
+// API of host, authenticating with application default credentials or the
+// docker CLI's login for the host.
+func (c *Client) registryGet(ctx context.Context, host, path, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+
+	if c.registryHTTP == nil {
+		ts, err := google.DefaultTokenSource(ctx, "https://www.googleapis.com/auth/cloud-platform")
+		if err != nil {
+			// Without application default credentials, reuse the docker
+			// CLI's login for the registry host (e.g. gcloud's credential helper).
+			cred, cerr := dockerCredential(ctx, host)
+			if cerr != nil || cred.IsZero() {
+				return nil, fmt.Errorf("registry credentials: %w", errors.Join(err, cerr))
+			}
+			c.registryCred = &cred
+			c.registryHTTP = c.baseHTTP()
+		} else {
+			c.registryHTTP = oauth2.NewClient(context.WithValue(context.Background(), oauth2.HTTPClient, c.baseHTTP()), ts)
+		}
+	}
+
+	var resp *http.Response
+	if c.registryCred != nil {
+		resp, err = dockerauth.Do(ctx, c.registryHTTP, req, *c.registryCred)
+	} else {
+		resp, err = c.registryHTTP.Do(req)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return body, nil
+}
+
+// VulnerabilityCounts returns the vulnerability occurrences Container
+// Analysis recorded for an image, counted by effective severity (CRITICAL,
+// HIGH, ...). Images that were never scanned have no occurrences.
+func (c *Client) VulnerabilityCounts(ctx context.Context, imageURI string) (map[string]int, error) {
+	c.analysisOnce.Do(func() {
+		c.analysis, c.analysisErr = gcpapi.NewCaller(ctx)
+	})
+	if c.analysisErr != nil {
+		return nil, c.analysisErr
+	}
+
+	filter := fmt.Sprintf(`kind="VULNERABILITY" AND resourceUrl="https://%s"`, imageURI)
+	u := fmt.Sprintf("%s/projects/%s/occurrences?filter=%s", containerAnalysisURL, url.PathEscape(c.project), url.QueryEscape(filter))
+	var occurrences []struct {
+		Vulnerability struct {
+			Severity          string `json:"severity"`
+			EffectiveSeverity string `json:"effectiveSeverity"`
+		} `json:"vulnerability"`
+	}
+	if err := c.analysis.List(ctx, u, "occurrences", &occurrences); err != nil {
+		return nil, fmt.Errorf("list vulnerability occurrences for %s: %w", imageURI, err)
+	}
+	if len(occurrences) == 0 {
+		return nil, nil
+	}
+	counts := make(map[string]int)
+	for _, o := range occurrences {
+		severity := o.Vulnerability.EffectiveSeverity
+		if severity == "" || severity == "SEVERITY_UNSPECIFIED" {
+			severity = o.Vulnerability.Severity
+		}
+		counts[severity]++
+	}
+	return counts, nil
+}
+
+// dockerCredential looks up the docker CLI credential for a registry host.
+func dockerCredential(ctx context.Context, host string) (dockerauth.Credential, error) {
+	cfg, err := dockerauth.Load(dockerauth.ConfigPath())
+	if err != nil {
+		return dockerauth.Credential{}, err
+	}
+	return cfg.Lookup(ctx, host)
+}
+
 // extractRepoID extracts the repository ID from a full resource name.
 // Format: projects/{project}/locations/{location}/repositories/{repo}
 func extractRepoID(name string) string {
@@ -36,3 +36,22 @@ func TestExtractRepoIDFromImage(t *testing.T) {
 		}
 	}
 }
+
+func TestParseVersionName(t *testing.T) {
+	tests := []struct {
+		name         string
+		pkg, version string
+		ok           bool
+	}{
+		{"projects/p/locations/l/repositories/r/packages/com.acme:core/versions/1.2.0", "com.acme:core", "1.2.0", true},
+		{"projects/p/locations/l/repositories/r/packages/%40acme%2Fui/versions/2.0.0-rc.1", "@acme/ui", "2.0.0-rc.1", true},
+		{"projects/p/locations/l/repositories/r/packages/app", "", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		pkg, version, ok := parseVersionName(tt.name)
+		if pkg != tt.pkg || version != tt.version || ok != tt.ok {
+			t.Errorf("parseVersionName(%q) = %q, %q, %v, want %q, %q, %v", tt.name, pkg, version, ok, tt.pkg, tt.version, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package artifactregistry
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+	"github.com/ppiankov/ecrspectre/internal/imageref"
+)
+
+// artifactRegistryURL is the Artifact Registry REST API.
+const artifactRegistryURL = "https://artifactregistry.googleapis.com/v1"
+
+// DeleteImage deletes a Docker image version, with all its tags, through the
+// Artifact Registry API. imageURI has the form
+// {location}-docker.pkg.dev/{project}/{repo}/{image}@{digest}. The deletion
+// completes asynchronously as a long-running operation.
+func DeleteImage(ctx context.Context, caller *gcpapi.Caller, imageURI string) error {
+	ref, err := imageref.Parse(imageURI)
+	if err != nil || ref.Provider != imageref.ProviderArtifactRegistry || ref.ARRepository == "" || ref.Digest == "" {
+		return fmt.Errorf("invalid image URI %q", imageURI)
+	}
+	pkg := strings.TrimPrefix(ref.Repository, ref.Project+"/"+ref.ARRepository+"/")
+	u := fmt.Sprintf("%s/projects/%s/locations/%s/repositories/%s/packages/%s/versions/%s?force=true",
+		artifactRegistryURL, url.PathEscape(ref.Project), url.PathEscape(ref.Location), url.PathEscape(ref.ARRepository),
+		url.PathEscape(pkg), url.PathEscape(ref.Digest))
+	var op struct {
+		Name string `json:"name"`
+	}
+	if err := caller.Delete(ctx, u, &op); err != nil {
+		return fmt.Errorf("delete image %s: %w", imageURI, err)
+	}
+	return nil
+}
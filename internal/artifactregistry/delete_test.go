@@ -0,0 +1,37 @@
+package artifactregistry
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+	"golang.org/x/oauth2"
+)
+
+func TestDeleteImage(t *testing.T) {
+	const digest = "sha256:0123456789012345678901234567890123456789012345678901234567890123"
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodDelete {
+			t.Errorf("method = %s", r.Method)
+		}
+		got = r.URL.EscapedPath() + "?" + r.URL.RawQuery
+		_, _ = w.Write([]byte(`{"name":"projects/p/locations/us-central1/operations/1"}`))
+	}))
+	defer srv.Close()
+	caller := gcpapi.NewCallerFromTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "tok"})).WithEndpoint(srv.URL)
+
+	if err := DeleteImage(context.Background(), caller, "us-central1-docker.pkg.dev/my-proj/docker/team/api@"+digest); err != nil {
+		t.Fatal(err)
+	}
+	want := "/v1/projects/my-proj/locations/us-central1/repositories/docker/packages/team%2Fapi/versions/sha256:" + digest[len("sha256:"):] + "?force=true"
+	if got != want {
+		t.Errorf("request = %s\nwant      %s", got, want)
+	}
+
+	if err := DeleteImage(context.Background(), caller, "docker.io/library/nginx@"+digest); err == nil {
+		t.Error("expected error for a non-Artifact Registry image")
+	}
+}
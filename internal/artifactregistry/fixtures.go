@@ -0,0 +1,90 @@
+package artifactregistry
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/ppiankov/ecrspectre/internal/fixtures"
+)
+
+// FixtureProject replaces the GCP project ID in recorded fixtures.
+const FixtureProject = "example-project"
+
+// FixtureSanitizers rewrite project in resource names
+// (projects/<project>/...) and image URIs (<location>-docker.pkg.dev/<project>/...).
+func FixtureSanitizers(project string) []fixtures.Replacement {
+	return []fixtures.Replacement{{
+		Pattern: regexp.MustCompile(`(projects/|\.pkg\.dev/)` + regexp.QuoteMeta(project) + `([^a-z0-9-]|$)`),
+		With:    "${1}" + FixtureProject + "${2}",
+	}}
+}
+
+// fixtureClient records calls to next, or replays them when next is nil.
+// Errors replay as plain messages: the client already maps the status
+// codes the scanner depends on.
+type fixtureClient struct {
+	next  ARAPI
+	store *fixtures.Store
+}
+
+// WithFixtures wraps client so its calls are recorded to or replayed from
+// store. When replaying, client may be nil.
+func WithFixtures(client ARAPI, store *fixtures.Store) ARAPI {
+	return &fixtureClient{next: client, store: store}
+}
+
+func (c *fixtureClient) ListLocations(ctx context.Context, project string) ([]string, error) {
+	return fixtures.Do(c.store, "ListLocations", "projects/"+project, func() ([]string, error) {
+		return c.next.ListLocations(ctx, project)
+	})
+}
+
+func (c *fixtureClient) ListRepositories(ctx context.Context, project, location string) ([]Repository, error) {
+	return fixtures.Do(c.store, "ListRepositories", fmt.Sprintf("projects/%s/locations/%s", project, location), func() ([]Repository, error) {
+		return c.next.ListRepositories(ctx, project, location)
+	})
+}
+
+func (c *fixtureClient) GetRepository(ctx context.Context, project, location, repoID string) (*Repository, error) {
+	return fixtures.Do(c.store, "GetRepository", fmt.Sprintf("projects/%s/locations/%s/repositories/%s", project, location, repoID), func() (*Repository, error) {
+		return c.next.GetRepository(ctx, project, location, repoID)
+	})
+}
+
+func (c *fixtureClient) ListDockerImages(ctx context.Context, parent string) ([]DockerImage, error) {
+	return fixtures.Do(c.store, "ListDockerImages", parent, func() ([]DockerImage, error) {
+		return c.next.ListDockerImages(ctx, parent)
+	})
+}
+
+func (c *fixtureClient) ListPackageVersions(ctx context.Context, parent string) ([]PackageVersion, error) {
+	return fixtures.Do(c.store, "ListPackageVersions", parent, func() ([]PackageVersion, error) {
+		return c.next.ListPackageVersions(ctx, parent)
+	})
+}
+
+func (c *fixtureClient) GetManifest(ctx context.Context, imageURI string) (string, error) {
+	return fixtures.Do(c.store, "GetManifest", imageURI, func() (string, error) {
+		return c.next.GetManifest(ctx, imageURI)
+	})
+}
+
+func (c *fixtureClient) GetImageConfig(ctx context.Context, imageURI, configDigest string) ([]byte, error) {
+	return fixtures.Do(c.store, "GetImageConfig", imageURI+" "+configDigest, func() ([]byte, error) {
+		return c.next.GetImageConfig(ctx, imageURI, configDigest)
+	})
+}
+
+func (c *fixtureClient) VulnerabilityCounts(ctx context.Context, imageURI string) (map[string]int, error) {
+	return fixtures.Do(c.store, "VulnerabilityCounts", imageURI, func() (map[string]int, error) {
+		return c.next.VulnerabilityCounts(ctx, imageURI)
+	})
+}
+
+func (c *fixtureClient) Close() error {
+	if c.next == nil {
+		return nil
+	}
+	return c.next.Close()
+}
@@ -0,0 +1,61 @@
+package artifactregistry
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ppiankov/ecrspectre/internal/fixtures"
+)
+
+func TestFixtureSanitizers(t *testing.T) {
+	r := FixtureSanitizers("prod")[0]
+	in := `projects/prod/locations/us projects/production/x us-docker.pkg.dev/prod/app "prod"`
+	want := `projects/example-project/locations/us projects/production/x us-docker.pkg.dev/example-project/app "prod"`
+	if got := r.Pattern.ReplaceAllString(in, r.With); got != want {
+		t.Errorf("sanitized = %s\nwant        %s", got, want)
+	}
+}
+
+func TestFixturesRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	mock := newMockClient()
+	mock.repos["my-project/us-central1"] = []Repository{
+		makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp"),
+	}
+	mock.images["projects/my-project/locations/us-central1/repositories/myapp"] = []DockerImage{
+		makeImage("us-central1-docker.pkg.dev/my-project/myapp/img@sha256:aaa", nil, halfGB, recent, ""),
+		makeImage("us-central1-docker.pkg.dev/my-project/myapp/img@sha256:bbb", []string{"v1.0"}, halfGB, stale120, ""),
+	}
+
+	recorder, err := fixtures.NewRecorder(dir, fixtures.ErrorCodec{}, FixtureSanitizers("my-project")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := newTestScanner(WithFixtures(mock, recorder)).Scan(context.Background(), defaultCfg(), nil)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, f := range files {
+		data, _ := os.ReadFile(f)
+		if strings.Contains(string(data), "my-project") {
+			t.Errorf("%s still contains the project ID", filepath.Base(f))
+		}
+	}
+
+	// Replaying under a different project resolves to the same fixtures.
+	replayer, err := fixtures.NewReplayer(dir, fixtures.ErrorCodec{}, FixtureSanitizers("other-project")...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewARScanner(WithFixtures(nil, replayer), "other-project", []string{"us-central1"}, false)
+	s.SetNow(now)
+	replayed := s.Scan(context.Background(), defaultCfg(), nil)
+	if len(replayed.Errors) != 0 {
+		t.Fatalf("replay errors: %v", replayed.Errors)
+	}
+	if len(replayed.Findings) == 0 || len(replayed.Findings) != len(recorded.Findings) {
+		t.Errorf("replayed %d findings, recorded %d", len(replayed.Findings), len(recorded.Findings))
+	}
+}
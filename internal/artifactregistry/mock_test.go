@@ -2,26 +2,61 @@ package artifactregistry
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 )
 
 // mockARClient implements ARAPI for testing.
 type mockARClient struct {
-	repos         map[string][]Repository  // keyed by "project/location"
-	images        map[string][]DockerImage // keyed by repo resource name
-	listRepoErr   map[string]error         // keyed by "project/location"
-	listImagesErr map[string]error         // keyed by repo resource name
+	repos         map[string][]Repository     // keyed by "project/location"
+	images        map[string][]DockerImage    // keyed by repo resource name
+	versions      map[string][]PackageVersion // keyed by repo resource name
+	listRepoErr   map[string]error            // keyed by "project/location"
+	listImagesErr map[string]error            // keyed by repo resource name
+	manifests     map[string]string           // keyed by image URI
+	manifestErr   map[string]error            // keyed by image URI
+	configs       map[string]string           // keyed by config digest
+	vulns         map[string]map[string]int   // keyed by image URI
+	vulnErr       map[string]error            // keyed by image URI
 }
 
 func newMockClient() *mockARClient {
 	return &mockARClient{
 		repos:         make(map[string][]Repository),
 		images:        make(map[string][]DockerImage),
+		versions:      make(map[string][]PackageVersion),
 		listRepoErr:   make(map[string]error),
 		listImagesErr: make(map[string]error),
+		manifests:     make(map[string]string),
+		manifestErr:   make(map[string]error),
+		configs:       make(map[string]string),
+		vulns:         make(map[string]map[string]int),
+		vulnErr:       make(map[string]error),
 	}
 }
 
+// ListLocations returns the locations the mock has repositories or listing
+// errors for.
+func (m *mockARClient) ListLocations(_ context.Context, project string) ([]string, error) {
+	keys := make(map[string]bool)
+	for key := range m.repos {
+		keys[key] = true
+	}
+	for key := range m.listRepoErr {
+		keys[key] = true
+	}
+	var locations []string
+	for key := range keys {
+		if location, ok := strings.CutPrefix(key, project+"/"); ok {
+			locations = append(locations, location)
+		}
+	}
+	slices.Sort(locations)
+	return locations, nil
+}
+
 func (m *mockARClient) ListRepositories(_ context.Context, project, location string) ([]Repository, error) {
 	key := project + "/" + location
 	if err, ok := m.listRepoErr[key]; ok {
@@ -30,6 +65,19 @@ func (m *mockARClient) ListRepositories(_ context.Context, project, location str
 	return m.repos[key], nil
 }
 
+func (m *mockARClient) GetRepository(_ context.Context, project, location, repoID string) (*Repository, error) {
+	key := project + "/" + location
+	if err, ok := m.listRepoErr[key]; ok {
+		return nil, err
+	}
+	for _, r := range m.repos[key] {
+		if r.RepoID == repoID {
+			return &r, nil
+		}
+	}
+	return nil, nil
+}
+
 func (m *mockARClient) ListDockerImages(_ context.Context, parent string) ([]DockerImage, error) {
 	if err, ok := m.listImagesErr[parent]; ok {
 		return nil, err
@@ -37,6 +85,39 @@ func (m *mockARClient) ListDockerImages(_ context.Context, parent string) ([]Doc
 	return m.images[parent], nil
 }
 
+func (m *mockARClient) GetManifest(_ context.Context, imageURI string) (string, error) {
+	if err, ok := m.manifestErr[imageURI]; ok {
+		return "", err
+	}
+	manifest, ok := m.manifests[imageURI]
+	if !ok {
+		return "", fmt.Errorf("manifest %s not found", imageURI)
+	}
+	return manifest, nil
+}
+
+func (m *mockARClient) GetImageConfig(_ context.Context, _, configDigest string) ([]byte, error) {
+	config, ok := m.configs[configDigest]
+	if !ok {
+		return nil, fmt.Errorf("config %s not found", configDigest)
+	}
+	return []byte(config), nil
+}
+
+func (m *mockARClient) VulnerabilityCounts(_ context.Context, imageURI string) (map[string]int, error) {
+	if err, ok := m.vulnErr[imageURI]; ok {
+		return nil, err
+	}
+	return m.vulns[imageURI], nil
+}
+
+func (m *mockARClient) ListPackageVersions(_ context.Context, parent string) ([]PackageVersion, error) {
+	if err, ok := m.listImagesErr[parent]; ok {
+		return nil, err
+	}
+	return m.versions[parent], nil
+}
+
 func (m *mockARClient) Close() error {
 	return nil
 }
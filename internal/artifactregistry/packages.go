@@ -0,0 +1,181 @@
+package artifactregistry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// scanPackageRepository emits findings for a Maven, npm, Python or generic
+// repository and returns its package versions, or nil if they could not be
+// listed. Versions are judged by size and by their last download, falling
+// back to creation when Artifact Registry recorded none.
+func (s *ARScanner) scanPackageRepository(ctx context.Context, cfg registry.ScanConfig, repo Repository, result *registry.ScanResult, progress func(registry.ScanProgress)) []PackageVersion {
+	s.reportProgress(progress, repo.Location, registry.ScanProgress{Stage: registry.StageScan, Repository: repo.RepoID, Message: fmt.Sprintf("Scanning %s (%s)", repo.RepoID, repo.Format)})
+
+	versions, err := s.client.ListPackageVersions(ctx, repo.Name)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s/%s: %v", repo.Location, repo.RepoID, err))
+		return nil
+	}
+
+	if len(versions) == 0 {
+		result.Findings = append(result.Findings, registry.Finding{
+			ID:           registry.FindingUnusedRepo,
+			Severity:     registry.SeverityLow,
+			ResourceType: registry.ResourceRepository,
+			ResourceID:   repo.RepoID,
+			Region:       repo.Location,
+			Message:      fmt.Sprintf("%s repository has no package versions", repo.Format),
+			Metadata:     map[string]any{"format": repo.Format},
+		})
+		return []PackageVersion{}
+	}
+
+	if f := cleanupPolicyFinding(repo); f != nil {
+		result.Findings = append(result.Findings, *f)
+	}
+
+	var retained map[string]bool
+	if cfg.KeepLatest > 0 {
+		candidates := make([]registry.Retained, 0, len(versions))
+		for _, v := range versions {
+			candidates = append(candidates, registry.Retained{ID: v.Name, Scope: v.Package, Tags: []string{v.Version}, Created: v.CreateTime})
+		}
+		retained = registry.KeepLatest(candidates, cfg.KeepLatest)
+	}
+
+	staleCount, staleWaste := 0, 0.0
+	for _, v := range versions {
+		result.ResourcesScanned++
+		findings := s.analyzeVersion(cfg, repo, v, retained[v.Name])
+		result.Findings = append(result.Findings, findings...)
+		for _, f := range findings {
+			if f.ID == registry.FindingStaleImage {
+				staleCount++
+				staleWaste += f.EstimatedMonthlyWaste
+			}
+		}
+	}
+
+	if staleCount == len(versions) {
+		result.Findings = append(result.Findings, registry.Finding{
+			ID:                    registry.FindingUnusedRepo,
+			Severity:              registry.SeverityLow,
+			ResourceType:          registry.ResourceRepository,
+			ResourceID:            repo.RepoID,
+			Region:                repo.Location,
+			Message:               fmt.Sprintf("All %d package versions are stale", len(versions)),
+			EstimatedMonthlyWaste: staleWaste,
+			Metadata: map[string]any{
+				"version_count": len(versions),
+				"format":        repo.Format,
+			},
+		})
+	}
+	return versions
+}
+
+// analyzeVersion reports a stale or oversized package version. A retained
+// version (one of the newest cfg.KeepLatest of its release line) or one
+// matching cfg.ProtectedTags is never stale.
+func (s *ARScanner) analyzeVersion(cfg registry.ScanConfig, repo Repository, v PackageVersion, retained bool) []registry.Finding {
+	var findings []registry.Finding
+
+	// cost claims the version's bytes from the project's billable storage,
+	// so it is called only for findings that report them.
+	cost := func() float64 { return s.claim(repo.Location, v.Name, v.SizeBytes) }
+	sizeMB := float64(v.SizeBytes) / (1024 * 1024)
+	resourceName := v.Package + ":" + v.Version
+
+	activity := v.CreateTime
+	if v.FetchTime.After(activity) {
+		activity = v.FetchTime
+	}
+	protected := cfg.ProtectedTags.Match([]string{v.Version})
+	if cfg.StaleDays > 0 && !retained && !protected && !activity.IsZero() && activity.Before(s.now.AddDate(0, 0, -cfg.StaleDays)) {
+		daysSince := int(s.now.Sub(activity).Hours() / 24)
+		f := registry.Finding{
+			ID:                    registry.FindingStaleImage,
+			Severity:              registry.SeverityHigh,
+			ResourceType:          registry.ResourcePackageVersion,
+			ResourceID:            v.Name,
+			ResourceName:          resourceName,
+			Region:                repo.Location,
+			Message:               fmt.Sprintf("Created %d days ago, no download recorded (%.0f MB)", daysSince, sizeMB),
+			EstimatedMonthlyWaste: cost(),
+			Metadata: map[string]any{
+				"format":      repo.Format,
+				"create_time": v.CreateTime.Format(time.RFC3339),
+				"days_stale":  daysSince,
+				"size_bytes":  v.SizeBytes,
+				"file_count":  v.Files,
+				"stale_days":  cfg.StaleDays,
+			},
+		}
+		if !v.FetchTime.IsZero() {
+			f.Message = fmt.Sprintf("Last downloaded %d days ago (%.0f MB)", daysSince, sizeMB)
+			f.Metadata["fetch_time"] = v.FetchTime.Format(time.RFC3339)
+		}
+		findings = append(findings, f)
+	}
+
+	if cfg.MaxSizeBytes > 0 && v.SizeBytes > cfg.MaxSizeBytes {
+		findings = append(findings, registry.Finding{
+			ID:                    registry.FindingLargeImage,
+			Severity:              registry.SeverityMedium,
+			ResourceType:          registry.ResourcePackageVersion,
+			ResourceID:            v.Name,
+			ResourceName:          resourceName,
+			Region:                repo.Location,
+			Message:               fmt.Sprintf("Package version is %.0f MB (threshold: %d MB)", sizeMB, cfg.MaxSizeBytes/(1024*1024)),
+			EstimatedMonthlyWaste: cost(),
+			Metadata: map[string]any{
+				"format":          repo.Format,
+				"size_bytes":      v.SizeBytes,
+				"file_count":      v.Files,
+				"threshold_bytes": cfg.MaxSizeBytes,
+			},
+		})
+	}
+
+	if protected {
+		registry.MarkProtected(findings)
+	}
+	registry.AnnotatePushed(findings, v.CreateTime)
+	return findings
+}
+
+// packageDetail builds the per-version breakdown for a scanned package
+// repository. Versions are listed by package:version in place of tags.
+func (s *ARScanner) packageDetail(repo Repository, versions []PackageVersion, findings []registry.Finding) *registry.RepositoryDetail {
+	detail := &registry.RepositoryDetail{
+		Name:       repo.RepoID,
+		Region:     repo.Location,
+		ImageCount: len(versions),
+	}
+	byVersion := registry.ImageFindings(findings)
+	for _, v := range versions {
+		cost := s.cost(repo.Location, v.SizeBytes)
+		d := registry.ImageDetail{
+			Tags:        []string{v.Package + ":" + v.Version},
+			SizeBytes:   v.SizeBytes,
+			MonthlyCost: cost,
+			Findings:    byVersion[v.Name],
+		}
+		if !v.CreateTime.IsZero() {
+			created := v.CreateTime
+			d.PushedAt = &created
+		}
+		if !v.FetchTime.IsZero() {
+			fetched := v.FetchTime
+			d.LastPulledAt = &fetched
+		}
+		detail.TotalSizeBytes += v.SizeBytes
+		detail.MonthlyCost += cost
+		detail.Images = append(detail.Images, d)
+	}
+	return detail
+}
@@ -0,0 +1,100 @@
+package artifactregistry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+func makePackageRepo(repoID, format string) Repository {
+	r := makeRepo("projects/my-project/locations/us-central1/repositories/"+repoID, "us-central1", repoID)
+	r.Format = format
+	return r
+}
+
+func makeVersion(repo Repository, pkg, version string, sizeBytes int64) PackageVersion {
+	return PackageVersion{
+		Name:      repo.Name + "/packages/" + pkg + "/versions/" + version,
+		Package:   pkg,
+		Version:   version,
+		Files:     2,
+		SizeBytes: sizeBytes,
+	}
+}
+
+func TestScanPackageVersions(t *testing.T) {
+	mock := newMockClient()
+	repo := makePackageRepo("libs", "MAVEN")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+
+	old := makeVersion(repo, "com.acme:core", "1.0.0", hundredMB)
+	old.CreateTime = stale200
+	fetched := makeVersion(repo, "com.acme:core", "1.1.0", hundredMB)
+	fetched.CreateTime = stale200
+	fetched.FetchTime = recent
+	large := makeVersion(repo, "com.acme:bundle", "2.0.0", twoGB)
+	large.CreateTime = recent
+	mock.versions[repo.Name] = []PackageVersion{old, fetched, large}
+
+	result := newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+
+	if result.ResourcesScanned != 3 {
+		t.Errorf("ResourcesScanned = %d, want 3", result.ResourcesScanned)
+	}
+	stale := findByID(result.Findings, registry.FindingStaleImage)
+	if len(stale) != 1 || stale[0].ResourceID != old.Name {
+		t.Fatalf("expected only %s stale, got %+v", old.Name, stale)
+	}
+	if stale[0].ResourceType != registry.ResourcePackageVersion || stale[0].ResourceName != "com.acme:core:1.0.0" {
+		t.Errorf("unexpected stale finding: %+v", stale[0])
+	}
+	if stale[0].EstimatedMonthlyWaste <= 0 {
+		t.Error("stale package version should have estimated waste")
+	}
+	large2 := findByID(result.Findings, registry.FindingLargeImage)
+	if len(large2) != 1 || large2[0].ResourceID != large.Name {
+		t.Errorf("expected %s large, got %+v", large.Name, large2)
+	}
+	if len(findByID(result.Findings, registry.FindingUnusedRepo)) != 0 {
+		t.Error("repository with recent versions should not be unused")
+	}
+	if len(findByID(result.Findings, registry.FindingNoLifecyclePolicy)) != 1 {
+		t.Error("package repository without cleanup policy should be reported")
+	}
+}
+
+func TestScanPackageRepositoryAllStale(t *testing.T) {
+	mock := newMockClient()
+	repo := makePackageRepo("pypi", "PYTHON")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	v := makeVersion(repo, "acme-utils", "0.1.0", halfGB)
+	v.CreateTime = stale120
+	mock.versions[repo.Name] = []PackageVersion{v}
+
+	result := newTestScanner(mock).ScanRepository(context.Background(), defaultCfg(), "pypi", nil)
+
+	unused := findByID(result.Findings, registry.FindingUnusedRepo)
+	if len(unused) != 1 || unused[0].Metadata["format"] != "PYTHON" {
+		t.Fatalf("expected UNUSED_REPO for PYTHON repository, got %+v", unused)
+	}
+	d := result.Detail
+	if d == nil || d.ImageCount != 1 || d.Images[0].Tags[0] != "acme-utils:0.1.0" {
+		t.Fatalf("unexpected detail: %+v", d)
+	}
+	if len(d.Images[0].Findings) != 1 || d.Images[0].Findings[0] != registry.FindingStaleImage {
+		t.Errorf("version findings = %v, want [STALE_IMAGE]", d.Images[0].Findings)
+	}
+}
+
+func TestScanEmptyPackageRepository(t *testing.T) {
+	mock := newMockClient()
+	mock.repos["my-project/us-central1"] = []Repository{makePackageRepo("npm", "NPM")}
+
+	result := newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+
+	unused := findByID(result.Findings, registry.FindingUnusedRepo)
+	if len(unused) != 1 || unused[0].Message != "NPM repository has no package versions" {
+		t.Errorf("expected empty NPM repository finding, got %+v", unused)
+	}
+}
@@ -0,0 +1,124 @@
+package artifactregistry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// Repository modes that are not scanned like standard repositories.
+const (
+	modeRemote  = "REMOTE_REPOSITORY"
+	modeVirtual = "VIRTUAL_REPOSITORY"
+)
+
+// cachedItem is an image or package version held in a remote repository's cache.
+type cachedItem struct {
+	sizeBytes int64
+	activity  time.Time
+}
+
+// scanRemoteRepository audits the cache of a remote (pull-through)
+// repository. Cached upstream artifacts are not the project's own builds, so
+// they get no per-image findings; instead the repository is reported when
+// its cache holds stale content and no cleanup policy deletes it. The cached
+// images or package versions are returned for the repository detail, or nil
+// if they could not be listed.
+func (s *ARScanner) scanRemoteRepository(ctx context.Context, cfg registry.ScanConfig, repo Repository, result *registry.ScanResult, progress func(registry.ScanProgress)) ([]DockerImage, []PackageVersion) {
+	s.reportProgress(progress, repo.Location, registry.ScanProgress{Stage: registry.StageScan, Repository: repo.RepoID, Message: fmt.Sprintf("Scanning %s (remote cache)", repo.RepoID)})
+
+	var items []cachedItem
+	var images []DockerImage
+	var versions []PackageVersion
+	if isPackageFormat(repo.Format) {
+		var err error
+		if versions, err = s.client.ListPackageVersions(ctx, repo.Name); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s: %v", repo.Location, repo.RepoID, err))
+			return nil, nil
+		}
+		for _, v := range versions {
+			activity := v.CreateTime
+			if v.FetchTime.After(activity) {
+				activity = v.FetchTime
+			}
+			items = append(items, cachedItem{sizeBytes: v.SizeBytes, activity: activity})
+		}
+		if versions == nil {
+			versions = []PackageVersion{}
+		}
+	} else {
+		var err error
+		if images, err = s.client.ListDockerImages(ctx, repo.Name); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s: %v", repo.Location, repo.RepoID, err))
+			return nil, nil
+		}
+		for _, img := range images {
+			activity, _ := lastActivity(cfg, img)
+			items = append(items, cachedItem{sizeBytes: img.SizeBytes, activity: activity})
+		}
+		if images == nil {
+			images = []DockerImage{}
+		}
+	}
+	result.ResourcesScanned += len(items)
+
+	if f := s.remoteCacheFinding(cfg, repo, items); f != nil {
+		result.Findings = append(result.Findings, *f)
+	}
+	return images, versions
+}
+
+// remoteCacheFinding reports a remote repository whose cache holds items not
+// used within cfg.StaleDays and whose cleanup policies never delete them.
+// The waste is the stale cached bytes, capped at the repository size since
+// cached images share layers.
+func (s *ARScanner) remoteCacheFinding(cfg registry.ScanConfig, repo Repository, items []cachedItem) *registry.Finding {
+	if cfg.StaleDays <= 0 || (repo.CleanupDeletes && !repo.CleanupDryRun) {
+		return nil
+	}
+	threshold := s.now.AddDate(0, 0, -cfg.StaleDays)
+	var cacheBytes, staleBytes int64
+	stale := 0
+	for _, it := range items {
+		cacheBytes += it.sizeBytes
+		if !it.activity.IsZero() && it.activity.Before(threshold) {
+			stale++
+			staleBytes += it.sizeBytes
+		}
+	}
+	if stale == 0 {
+		return nil
+	}
+	if repo.SizeBytes > 0 && staleBytes > repo.SizeBytes {
+		staleBytes = repo.SizeBytes
+	}
+
+	reason := "no cleanup policy"
+	switch {
+	case repo.CleanupPolicies > 0 && !repo.CleanupDeletes:
+		reason = "cleanup policies only keep images"
+	case repo.CleanupDryRun:
+		reason = "cleanup policies run in dry-run mode"
+	}
+	return &registry.Finding{
+		ID:                    registry.FindingStaleRemoteCache,
+		Severity:              registry.SeverityMedium,
+		ResourceType:          registry.ResourceRepository,
+		ResourceID:            repo.RepoID,
+		Region:                repo.Location,
+		Message:               fmt.Sprintf("Remote repository caches %d of %d artifacts unused for %d+ days (%.0f MB), %s", stale, len(items), cfg.StaleDays, float64(staleBytes)/(1024*1024), reason),
+		EstimatedMonthlyWaste: s.claim(repo.Location, repo.RepoID, staleBytes),
+		Metadata: map[string]any{
+			"mode":             repo.Mode,
+			"format":           repo.Format,
+			"cached_items":     len(items),
+			"stale_items":      stale,
+			"cache_bytes":      cacheBytes,
+			"stale_bytes":      staleBytes,
+			"stale_days":       cfg.StaleDays,
+			"cleanup_policies": repo.CleanupPolicies,
+		},
+	}
+}
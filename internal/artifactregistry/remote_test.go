@@ -0,0 +1,87 @@
+package artifactregistry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+func makeRemoteRepo(repoID string) Repository {
+	r := makeRepo("projects/my-project/locations/us-central1/repositories/"+repoID, "us-central1", repoID)
+	r.Mode = modeRemote
+	return r
+}
+
+func TestScanRemoteRepositoryStaleCache(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRemoteRepo("dockerhub")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	mock.images[repo.Name] = []DockerImage{
+		makeImage("us-central1-docker.pkg.dev/my-project/dockerhub/library/nginx@sha256:aaa", nil, oneGB, stale200, ""),
+		makeImage("us-central1-docker.pkg.dev/my-project/dockerhub/library/redis@sha256:bbb", []string{"7"}, twoGB, recent, ""),
+	}
+
+	result := newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+
+	if len(result.Findings) != 1 {
+		t.Fatalf("expected only the cache finding, got %+v", result.Findings)
+	}
+	f := result.Findings[0]
+	if f.ID != registry.FindingStaleRemoteCache || f.ResourceID != "dockerhub" {
+		t.Fatalf("unexpected finding: %+v", f)
+	}
+	if f.Metadata["stale_items"] != 1 || f.Metadata["stale_bytes"] != oneGB {
+		t.Errorf("unexpected metadata: %v", f.Metadata)
+	}
+	if f.EstimatedMonthlyWaste <= 0 {
+		t.Error("stale cache should have estimated waste")
+	}
+	if !strings.Contains(f.Message, "no cleanup policy") {
+		t.Errorf("message = %q", f.Message)
+	}
+	if result.ResourcesScanned != 2 {
+		t.Errorf("ResourcesScanned = %d, want 2", result.ResourcesScanned)
+	}
+}
+
+func TestScanRemoteRepositoryWithCleanupPolicy(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRemoteRepo("pypi-proxy")
+	repo.Format = "PYTHON"
+	repo.CleanupPolicies = 1
+	repo.CleanupDeletes = true
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	v := makeVersion(repo, "requests", "2.31.0", hundredMB)
+	v.CreateTime = stale200
+	mock.versions[repo.Name] = []PackageVersion{v}
+
+	result := newTestScanner(mock).ScanRepository(context.Background(), defaultCfg(), "pypi-proxy", nil)
+
+	if len(result.Findings) != 0 {
+		t.Errorf("remote cache cleaned by policy should not be reported, got %+v", result.Findings)
+	}
+	if result.Detail == nil || result.Detail.ImageCount != 1 {
+		t.Errorf("expected detail for cached versions, got %+v", result.Detail)
+	}
+}
+
+func TestScanSkipsVirtualRepository(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/all", "us-central1", "all")
+	repo.Mode = modeVirtual
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	mock.images[repo.Name] = []DockerImage{
+		makeImage("us-central1-docker.pkg.dev/my-project/all/img@sha256:aaa", nil, oneGB, stale200, ""),
+	}
+
+	s := newTestScanner(mock)
+	if result := s.Scan(context.Background(), defaultCfg(), nil); len(result.Findings) != 0 {
+		t.Errorf("virtual repository should not be scanned, got %+v", result.Findings)
+	}
+	result := s.ScanRepository(context.Background(), defaultCfg(), "all", nil)
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "virtual repository") {
+		t.Errorf("expected virtual repository error, got %v", result.Errors)
+	}
+}
@@ -3,65 +3,375 @@ package artifactregistry
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ppiankov/ecrspectre/internal/pricing"
 	"github.com/ppiankov/ecrspectre/internal/registry"
+	"github.com/ppiankov/ecrspectre/internal/rules"
 )
 
 // ARScanner audits GCP Artifact Registry repositories for waste.
 type ARScanner struct {
-	client    ARAPI
-	project   string
-	locations []string
-	now       time.Time // injectable for testing
+	client      ARAPI
+	project     string
+	locations   []string
+	includeScan bool
+	// storage prices deletions against the project's total storage, set
+	// once the project's repositories are listed.
+	storage *pricing.ARStorage
+	now     time.Time // injectable for testing
 }
 
+// vulnScanConcurrency bounds the concurrent Container Analysis lookups per repository.
+const vulnScanConcurrency = 8
+
+// locationConcurrency bounds the concurrent repository listings that total
+// the project's storage outside the scanned locations.
+const locationConcurrency = 8
+
 // NewARScanner creates a scanner for the given Artifact Registry client.
-func NewARScanner(client ARAPI, project string, locations []string) *ARScanner {
+// includeScan adds VULNERABLE_IMAGE findings from Container Analysis.
+func NewARScanner(client ARAPI, project string, locations []string, includeScan bool) *ARScanner {
 	return &ARScanner{
-		client:    client,
-		project:   project,
-		locations: locations,
-		now:       time.Now(),
+		client:      client,
+		project:     project,
+		locations:   locations,
+		includeScan: includeScan,
+		storage:     pricing.NewARStorage(0),
+		now:         time.Now(),
 	}
 }
 
-// Scan implements registry.RegistryScanner.
+// SetNow pins the time the scan measures image ages against, e.g. to when
+// replayed fixtures were recorded.
+func (s *ARScanner) SetNow(now time.Time) {
+	s.now = now
+}
+
+// Scan implements registry.RegistryScanner. Progress events are delivered by
+// a registry.ProgressDispatcher, all of them before Scan returns.
 func (s *ARScanner) Scan(ctx context.Context, cfg registry.ScanConfig, progress func(registry.ScanProgress)) *registry.ScanResult {
-	result := &registry.ScanResult{}
+	progress, stopProgress := registry.DispatchProgress(progress)
+	defer stopProgress()
+	result := &registry.ScanResult{Targets: len(s.locations)}
 
+	var repos []Repository
+	var projectBytes int64
+	scanned := make(map[string]int64, len(s.locations))
 	for _, location := range s.locations {
-		s.reportProgress(progress, location, fmt.Sprintf("Scanning location %s", location))
+		s.reportProgress(progress, location, registry.ScanProgress{Stage: registry.StageDiscover, Message: fmt.Sprintf("Scanning location %s", location)})
 
-		repos, err := s.client.ListRepositories(ctx, s.project, location)
+		locRepos, err := s.client.ListRepositories(ctx, s.project, location)
 		if err != nil {
-			result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", location, err))
+			result.AddTargetError(location, err)
 			continue
 		}
 
-		result.RepositoriesScanned += len(repos)
-		s.reportProgress(progress, location, fmt.Sprintf("Found %d Docker repositories", len(repos)))
+		for _, r := range locRepos {
+			scanned[location] += r.SizeBytes
+		}
+		projectBytes += scanned[location]
+		locRepos = registry.FilterRepos(locRepos, func(r Repository) string { return r.RepoID }, cfg.Repos)
+		result.RepositoriesScanned += len(locRepos)
+		s.reportProgress(progress, location, registry.ScanProgress{Stage: registry.StageDiscover, ReposTotal: result.RepositoriesScanned, Message: fmt.Sprintf("Found %d repositories", len(locRepos))})
+		repos = append(repos, locRepos...)
+	}
+
+	// The free tier covers the whole project, so it is priced on every
+	// location's storage, not only the scanned ones'.
+	if len(scanned) == len(s.locations) {
+		s.storage = pricing.NewARStorage(s.projectBytes(ctx, scanned))
+	} else {
+		s.storage = pricing.NewARStorage(0)
+	}
 
+	// Without a seed from a previous scan, prioritize by repository storage size.
+	priority := cfg.RepoPriority
+	if len(priority) == 0 {
+		priority = make(map[string]float64, len(repos))
 		for _, repo := range repos {
-			if cfg.Exclude.ResourceIDs[repo.RepoID] {
-				continue
+			priority[repo.RepoID] += pricing.MonthlyStorageCost("artifactregistry", repo.Location, repo.SizeBytes)
+		}
+	}
+	weights := registry.SortByPriority(repos, func(r Repository) string { return r.RepoID }, priority)
+
+	if f := registry.QuotaFinding(cfg.Quota, registry.ResourceProject, s.project, strings.Join(s.locations, ","), projectBytes, cfg.Quota.ProjectBytes); f != nil {
+		result.Findings = append(result.Findings, *f)
+	}
+
+	completed, reported := 0, 0
+	counted := progress
+	if progress != nil {
+		counted = func(p registry.ScanProgress) {
+			p.ReposDone, p.ReposTotal = completed, len(repos)
+			p.ImagesScanned = result.ResourcesScanned
+			p.Findings = slices.Clone(result.Findings[reported:])
+			reported = len(result.Findings)
+			progress(p)
+		}
+	}
+	for _, repo := range repos {
+		if ctx.Err() != nil {
+			break
+		}
+		excluded := cfg.Exclude.ResourceIDs[repo.RepoID] || cfg.Exclude.MatchesTags(repo.Labels)
+		if excluded {
+			slog.Debug("Skipping excluded repository", "repo", repo.RepoID)
+		} else {
+			start, began, images := len(result.Findings), time.Now(), result.ResourcesScanned
+			if f := registry.QuotaFinding(cfg.Quota, registry.ResourceRepository, repo.RepoID, repo.Location, repo.SizeBytes, cfg.Quota.RepositoryQuota(repo.RepoID)); f != nil {
+				result.Findings = append(result.Findings, *f)
+			}
+			result.RecordUsage(repo.RepoID, repo.Location, repo.SizeBytes)
+			switch {
+			case repo.Mode == modeVirtual:
+				// Virtual repositories store nothing; their upstreams are scanned.
+				s.reportProgress(counted, repo.Location, registry.ScanProgress{Stage: registry.StageSkip, Repository: repo.RepoID, Message: fmt.Sprintf("Skipping virtual repository %s", repo.RepoID)})
+			case repo.Mode == modeRemote:
+				s.scanRemoteRepository(ctx, cfg, repo, result, counted)
+			case isPackageFormat(repo.Format):
+				s.scanPackageRepository(ctx, cfg, repo, result, counted)
+			default:
+				s.scanRepository(ctx, cfg, repo, result, counted)
 			}
-			s.scanRepository(ctx, cfg, repo, result, progress)
+			for i := range result.Findings[start:] {
+				result.Findings[start+i].Repository = repo.RepoID
+			}
+			registry.Annotate(result.Findings[start:], registry.Attribution(repo.Labels, cfg.AttributionKeys...))
+			result.RecordTiming(repo.RepoID, repo.Location, result.ResourcesScanned-images, time.Since(began))
+		}
+		if ctx.Err() != nil {
+			break
 		}
+		completed++
+	}
+
+	s.reportProgress(counted, strings.Join(s.locations, ","), registry.ScanProgress{Stage: registry.StageDone, Message: fmt.Sprintf("Scanned %d of %d repositories", completed, len(repos))})
+
+	result.Coverage = registry.ComputeCoverage(weights, completed, completed < len(repos))
+	if result.Coverage.Truncated {
+		result.Errors = append(result.Errors, fmt.Sprintf("scan deadline reached after %d of %d repositories (%.1f%% coverage)",
+			completed, len(repos), result.Coverage.Percent))
 	}
 
 	return result
 }
 
-func (s *ARScanner) scanRepository(ctx context.Context, cfg registry.ScanConfig, repo Repository, result *registry.ScanResult, progress func(registry.ScanProgress)) {
-	s.reportProgress(progress, repo.Location, fmt.Sprintf("Scanning %s", repo.RepoID))
+// cleanupPolicyFinding reports a repository whose cleanup policies never
+// delete images: none are configured, none has a delete action, or they run
+// in dry-run mode.
+func cleanupPolicyFinding(repo Repository) *registry.Finding {
+	f := &registry.Finding{
+		ID:           registry.FindingNoLifecyclePolicy,
+		Severity:     registry.SeverityMedium,
+		ResourceType: registry.ResourceRepository,
+		ResourceID:   repo.RepoID,
+		Region:       repo.Location,
+		Metadata:     map[string]any{"cleanup_policies": repo.CleanupPolicies},
+	}
+	switch {
+	case repo.CleanupPolicies == 0:
+		f.Message = "No cleanup policy configured — images accumulate indefinitely"
+	case !repo.CleanupDeletes:
+		f.Message = fmt.Sprintf("%d cleanup policies only keep images — none deletes any", repo.CleanupPolicies)
+	case repo.CleanupDryRun:
+		f.Severity = registry.SeverityLow
+		f.Message = "Cleanup policies run in dry-run mode — images are logged but not deleted"
+		f.Metadata["cleanup_dry_run"] = true
+	default:
+		return nil
+	}
+	return f
+}
+
+// ScanRepository audits a single named repository without enumerating the
+// project, searching the configured locations in order, and attaches a
+// per-image breakdown. Cleanup policies are not simulated.
+func (s *ARScanner) ScanRepository(ctx context.Context, cfg registry.ScanConfig, repoID string, progress func(registry.ScanProgress)) *registry.ScanResult {
+	progress, stopProgress := registry.DispatchProgress(progress)
+	defer stopProgress()
+	result := &registry.ScanResult{Targets: len(s.locations)}
+
+	var repo *Repository
+	for _, location := range s.locations {
+		found, err := s.client.GetRepository(ctx, s.project, location, repoID)
+		if err != nil {
+			result.AddTargetError(location, err)
+			continue
+		}
+		if found != nil {
+			repo = found
+			break
+		}
+	}
+	if repo == nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("repository %s not found in %s", repoID, strings.Join(s.locations, ", ")))
+		return result
+	}
+	if cfg.Exclude.MatchesTags(repo.Labels) {
+		slog.Debug("Skipping repository excluded by label", "repo", repoID)
+		return result
+	}
+	if repo.Mode == modeVirtual {
+		result.Errors = append(result.Errors, fmt.Sprintf("repository %s is a virtual repository; scan its upstream repositories instead", repoID))
+		return result
+	}
+	result.RepositoriesScanned = 1
+	s.storage = pricing.NewARStorage(s.projectBytes(ctx, nil))
+
+	if repo.Mode == modeRemote {
+		images, versions := s.scanRemoteRepository(ctx, cfg, *repo, result, progress)
+		switch {
+		case images != nil:
+			result.Detail = s.repositoryDetail(cfg, *repo, images, result.Findings)
+		case versions != nil:
+			result.Detail = s.packageDetail(*repo, versions, result.Findings)
+		}
+	} else if isPackageFormat(repo.Format) {
+		if versions := s.scanPackageRepository(ctx, cfg, *repo, result, progress); versions != nil {
+			result.Detail = s.packageDetail(*repo, versions, result.Findings)
+		}
+	} else if images := s.scanRepository(ctx, cfg, *repo, result, progress); images != nil {
+		result.Detail = s.repositoryDetail(cfg, *repo, images, result.Findings)
+	}
+	for i := range result.Findings {
+		result.Findings[i].Repository = repo.RepoID
+	}
+	registry.Annotate(result.Findings, registry.Attribution(repo.Labels, cfg.AttributionKeys...))
+	result.Coverage = registry.ComputeCoverage(make([]float64, 1), 1, ctx.Err() != nil)
+	return result
+}
+
+// projectBytes totals the project's storage in every location Artifact
+// Registry serves, taking the locations in scanned from the listing the
+// scan already made. It returns 0 (unknown, so the free tier is not
+// applied) if the locations or any location's repositories fail to list.
+func (s *ARScanner) projectBytes(ctx context.Context, scanned map[string]int64) int64 {
+	locations, err := s.client.ListLocations(ctx, s.project)
+	if err != nil {
+		slog.Debug("Project storage unknown", "project", s.project, "error", err)
+		return 0
+	}
+	sizes := make([]int64, len(locations))
+	errs := make([]error, len(locations))
+
+	sem := make(chan struct{}, locationConcurrency)
+	var wg sync.WaitGroup
+	for i, location := range locations {
+		if n, ok := scanned[location]; ok {
+			sizes[i] = n
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, location string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			repos, err := s.client.ListRepositories(ctx, s.project, location)
+			for _, r := range repos {
+				sizes[i] += r.SizeBytes
+			}
+			errs[i] = err
+		}(i, location)
+	}
+	wg.Wait()
+
+	var total int64
+	for i := range locations {
+		if errs[i] != nil {
+			slog.Debug("Project storage unknown", "project", s.project, "error", errs[i])
+			return 0
+		}
+		total += sizes[i]
+	}
+	return total
+}
+
+// cost is the monthly cost of storing sizeBytes in location after the
+// project's free tier, as shown in per-image breakdowns.
+func (s *ARScanner) cost(location string, sizeBytes int64) float64 {
+	return s.storage.MonthlyWaste(location, sizeBytes)
+}
+
+// claim is the monthly saving a finding reports for deleting sizeBytes of
+// resource id in location: the bytes are drawn from the project's billable
+// storage left by earlier findings, so a project's savings never exceed its
+// bill.
+func (s *ARScanner) claim(location, id string, sizeBytes int64) float64 {
+	return s.storage.Claim(location, id, sizeBytes)
+}
+
+// repositoryDetail builds the per-image breakdown for a scanned repository.
+func (s *ARScanner) repositoryDetail(cfg registry.ScanConfig, repo Repository, images []DockerImage, findings []registry.Finding) *registry.RepositoryDetail {
+	detail := &registry.RepositoryDetail{
+		Name:       repo.RepoID,
+		Region:     repo.Location,
+		ImageCount: len(images),
+	}
+	byImage := registry.ImageFindings(findings)
+	for _, img := range images {
+		cost := s.cost(repo.Location, img.SizeBytes)
+		imageID := img.URI
+		if imageID == "" {
+			imageID = img.Name
+		}
+		d := registry.ImageDetail{
+			Digest:       imageDigest(img),
+			Tags:         img.Tags,
+			CanonicalTag: registry.CanonicalTag(img.Tags, cfg.TagPriority),
+			SizeBytes:    img.SizeBytes,
+			MonthlyCost:  cost,
+			Findings:     byImage[imageID],
+			Layers:       img.Layers,
+		}
+		if !img.UploadTime.IsZero() {
+			uploaded := img.UploadTime
+			d.PushedAt = &uploaded
+		}
+		if _, lastPull := lastActivity(cfg, img); !lastPull.IsZero() {
+			d.LastPulledAt = &lastPull
+		}
+		detail.TotalSizeBytes += img.SizeBytes
+		detail.MonthlyCost += cost
+		detail.Images = append(detail.Images, d)
+	}
+	return detail
+}
+
+// lastActivity returns the later of an image's upload and its last pull
+// recorded in cfg.Pulls, along with the last pull (zero if none).
+func lastActivity(cfg registry.ScanConfig, img DockerImage) (time.Time, time.Time) {
+	var lastPull time.Time
+	if cfg.Pulls != nil && img.URI != "" {
+		lastPull = cfg.Pulls.LastPull(registry.ParseImageRef(img.URI).Repository, imageDigest(img), img.Tags)
+	}
+	if lastPull.After(img.UploadTime) {
+		return lastPull, lastPull
+	}
+	return img.UploadTime, lastPull
+}
+
+// imageDigest extracts the sha256 digest from an image URI.
+func imageDigest(img DockerImage) string {
+	if _, digest, ok := strings.Cut(img.URI, "@"); ok {
+		return digest
+	}
+	return img.URI
+}
+
+// scanRepository emits findings for a repository and returns its images, or
+// nil if they could not be listed.
+func (s *ARScanner) scanRepository(ctx context.Context, cfg registry.ScanConfig, repo Repository, result *registry.ScanResult, progress func(registry.ScanProgress)) []DockerImage {
+	s.reportProgress(progress, repo.Location, registry.ScanProgress{Stage: registry.StageScan, Repository: repo.RepoID, Message: fmt.Sprintf("Scanning %s", repo.RepoID)})
 
 	images, err := s.client.ListDockerImages(ctx, repo.Name)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("%s/%s: %v", repo.Location, repo.RepoID, err))
-		return
+		return nil
 	}
 
 	if len(images) == 0 {
@@ -74,28 +384,71 @@ func (s *ARScanner) scanRepository(ctx context.Context, cfg registry.ScanConfig,
 			Message:               "Repository has no Docker images",
 			EstimatedMonthlyWaste: 0,
 		})
-		return
+		return []DockerImage{}
 	}
 
-	staleCount := 0
+	if f := cleanupPolicyFinding(repo); f != nil {
+		result.Findings = append(result.Findings, *f)
+	}
+	var newest time.Time
+	for _, img := range images {
+		if img.UploadTime.After(newest) {
+			newest = img.UploadTime
+		}
+	}
+	if f := registry.StaleReleaseTrain(cfg, repo.RepoID, repo.Location, newest, s.now); f != nil {
+		result.Findings = append(result.Findings, *f)
+	}
+
+	if cfg.DeepLayers {
+		s.imageLayers(ctx, repo, images, result)
+	}
+	if len(cfg.UsedPlatforms) > 0 || cfg.DeepLayers {
+		s.resolveIndexes(ctx, repo, images, result)
+	}
+	if cfg.DeepLayers {
+		markOrphans(images)
+	}
+	if cfg.DeepLayers && cfg.MaxDeployedAge > 0 && cfg.InUse != nil {
+		s.buildDates(ctx, cfg, repo, images, result)
+	}
+	if cfg.RequireSignatures || cfg.RequireSBOM {
+		s.markReferrers(ctx, repo, images, result)
+	}
+	sizes := make(map[string]int64, len(images))
+	for _, img := range images {
+		sizes[imageDigest(img)] = img.SizeBytes
+	}
+
+	var retained map[string]bool
+	if cfg.KeepLatest > 0 {
+		candidates := make([]registry.Retained, 0, len(images))
+		for _, img := range images {
+			candidates = append(candidates, registry.Retained{ID: img.Name, Scope: registry.ParseImageRef(img.URI).Repository, Tags: img.Tags, Created: img.UploadTime})
+		}
+		retained = registry.KeepLatest(candidates, cfg.KeepLatest)
+	}
+
+	staleCount, staleWaste := 0, 0.0
 	for _, img := range images {
 		result.ResourcesScanned++
-		findings := s.analyzeImage(cfg, repo, img)
+		findings := s.analyzeImage(cfg, repo, img, sizes, retained[img.Name])
 		result.Findings = append(result.Findings, findings...)
 
 		for _, f := range findings {
 			if f.ID == registry.FindingStaleImage {
 				staleCount++
+				staleWaste += f.EstimatedMonthlyWaste
 			}
 		}
 	}
 
-	// All images stale = unused repo
+	if s.includeScan {
+		result.Findings = append(result.Findings, s.scanVulnerabilities(ctx, cfg, repo, images, result)...)
+	}
+
+	// All images stale = unused repo, saving what its stale images claimed
 	if staleCount == len(images) && len(images) > 0 {
-		totalWaste := 0.0
-		for _, img := range images {
-			totalWaste += pricing.MonthlyStorageCost("artifactregistry", repo.Location, img.SizeBytes)
-		}
 		result.Findings = append(result.Findings, registry.Finding{
 			ID:                    registry.FindingUnusedRepo,
 			Severity:              registry.SeverityLow,
@@ -103,15 +456,229 @@ func (s *ARScanner) scanRepository(ctx context.Context, cfg registry.ScanConfig,
 			ResourceID:            repo.RepoID,
 			Region:                repo.Location,
 			Message:               fmt.Sprintf("All %d images are stale", len(images)),
-			EstimatedMonthlyWaste: totalWaste,
+			EstimatedMonthlyWaste: staleWaste,
 			Metadata: map[string]any{
 				"image_count": len(images),
 			},
 		})
 	}
+	return images
+}
+
+// scanVulnerabilities looks up Container Analysis occurrences for every image
+// in a repository with bounded concurrency and reports images with critical
+// or high vulnerabilities. Findings are returned in image order.
+func (s *ARScanner) scanVulnerabilities(ctx context.Context, cfg registry.ScanConfig, repo Repository, images []DockerImage, result *registry.ScanResult) []registry.Finding {
+	counts := make([]map[string]int, len(images))
+	errs := make([]error, len(images))
+
+	sem := make(chan struct{}, vulnScanConcurrency)
+	var wg sync.WaitGroup
+	for i, img := range images {
+		if img.URI == "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, uri string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			counts[i], errs[i] = s.client.VulnerabilityCounts(ctx, uri)
+		}(i, img.URI)
+	}
+	wg.Wait()
+
+	var findings []registry.Finding
+	var firstErr error
+	failed := 0
+	for i, img := range images {
+		if errs[i] != nil {
+			if firstErr == nil {
+				firstErr = errs[i]
+			}
+			failed++
+			continue
+		}
+		if f := vulnerabilityFinding(cfg, repo, img, counts[i]); f != nil {
+			findings = append(findings, *f)
+			registry.AnnotatePushed(findings[len(findings)-1:], img.UploadTime)
+		}
+	}
+	// One message per repository: a disabled API fails every image the same way.
+	if firstErr != nil {
+		msg := fmt.Sprintf("%s/%s vulnerability scan: %v", repo.Location, repo.RepoID, firstErr)
+		if failed > 1 {
+			msg += fmt.Sprintf(" (and %d more images)", failed-1)
+		}
+		result.Errors = append(result.Errors, msg)
+	}
+	return findings
+}
+
+// vulnerabilityFinding reports an image with critical or high vulnerabilities,
+// counted the same way as ECR scan findings.
+func vulnerabilityFinding(cfg registry.ScanConfig, repo Repository, img DockerImage, counts map[string]int) *registry.Finding {
+	critCount := counts["CRITICAL"]
+	highCount := counts["HIGH"]
+	if critCount == 0 && highCount == 0 {
+		return nil
+	}
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	return &registry.Finding{
+		ID:           registry.FindingVulnerableImage,
+		Severity:     registry.SeverityCritical,
+		ResourceType: registry.ResourceImage,
+		ResourceID:   img.URI,
+		ResourceName: registry.ImageName(repo.RepoID, img.Tags, cfg.TagPriority),
+		Region:       repo.Location,
+		Message:      fmt.Sprintf("%d vulnerabilities (%d critical, %d high)", total, critCount, highCount),
+		Metadata: map[string]any{
+			"total_findings":  total,
+			"critical_count":  critCount,
+			"high_count":      highCount,
+			"severity_counts": counts,
+		},
+	}
+}
+
+// imageLayers fetches each image manifest and stores its layer analysis on
+// the image. Multi-platform indexes are skipped.
+func (s *ARScanner) imageLayers(ctx context.Context, repo Repository, images []DockerImage, result *registry.ScanResult) {
+	for i := range images {
+		if images[i].URI == "" {
+			continue
+		}
+		text, err := s.client.GetManifest(ctx, images[i].URI)
+		if err == nil {
+			var m *registry.Manifest
+			m, err = registry.ParseManifest(text)
+			if err == nil && m.IsIndex() {
+				images[i].Index = m
+			} else if err == nil {
+				a := registry.AnalyzeLayers(m)
+				images[i].Layers = &a
+			}
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s manifest: %v", repo.Location, repo.RepoID, err))
+		}
+	}
+}
+
+// buildDates reads the build date of each deployed image from its config
+// blob. A multi-arch index takes the date of its first platform image.
+func (s *ARScanner) buildDates(ctx context.Context, cfg registry.ScanConfig, repo Repository, images []DockerImage, result *registry.ScanResult) {
+	byDigest := make(map[string]*DockerImage, len(images))
+	for i := range images {
+		byDigest[imageDigest(images[i])] = &images[i]
+	}
+	for i := range images {
+		img := &images[i]
+		if img.URI == "" || cfg.InUse.Lookup(registry.ParseImageRef(img.URI).Repository, imageDigest(*img), img.Tags) == nil {
+			continue
+		}
+		layers := img.Layers
+		if img.Index != nil {
+			for _, child := range img.Index.Manifests {
+				if child.Platform != nil && child.Platform.IsAttestation() {
+					continue
+				}
+				if c := byDigest[child.Digest]; c != nil && c.Layers != nil {
+					layers = c.Layers
+					break
+				}
+			}
+		}
+		if layers == nil || layers.ConfigDigest == "" {
+			continue
+		}
+		config, err := s.client.GetImageConfig(ctx, img.URI, layers.ConfigDigest)
+		if err == nil {
+			img.BuildDate, err = registry.ImageCreated(config)
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s image config: %v", repo.Location, repo.RepoID, err))
+		}
+	}
+}
+
+// resolveIndexes fetches the manifests of multi-arch index images and stores
+// them on the image so unused platforms can be reported.
+func (s *ARScanner) resolveIndexes(ctx context.Context, repo Repository, images []DockerImage, result *registry.ScanResult) {
+	for i := range images {
+		if !isIndex(images[i]) || images[i].URI == "" || images[i].Index != nil {
+			continue
+		}
+		text, err := s.client.GetManifest(ctx, images[i].URI)
+		if err == nil {
+			var m *registry.Manifest
+			m, err = registry.ParseManifest(text)
+			if err == nil && m.IsIndex() {
+				images[i].Index = m
+			}
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s index manifest: %v", repo.Location, repo.RepoID, err))
+		}
+	}
+}
+
+// markReferrers flags the images that carry a signature or an SBOM: a
+// cosign "sha256-<hex>.sig" or ".sbom" tag, or a signature or SBOM referrer
+// artifact whose manifest names the image as its subject.
+func (s *ARScanner) markReferrers(ctx context.Context, repo Repository, images []DockerImage, result *registry.ScanResult) {
+	var tags []string
+	var referrers []registry.Referrer
+	for _, img := range images {
+		tags = append(tags, img.Tags...)
+		if !registry.IsReferrerArtifact(img.ArtifactType) || img.URI == "" {
+			continue
+		}
+		text, err := s.client.GetManifest(ctx, img.URI)
+		if err == nil {
+			var m *registry.Manifest
+			m, err = registry.ParseManifest(text)
+			if err == nil {
+				if r, ok := m.Referrer(); ok {
+					referrers = append(referrers, r)
+				}
+			}
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s referrer manifest: %v", repo.Location, repo.RepoID, err))
+		}
+	}
+	signed := registry.SignedDigests(tags, referrers)
+	withSBOM := registry.SBOMDigests(tags, referrers)
+	for i := range images {
+		images[i].Signed = signed[imageDigest(images[i])]
+		images[i].HasSBOM = withSBOM[imageDigest(images[i])]
+	}
+}
+
+// isIndex reports whether an image is a multi-arch manifest list or OCI index.
+// markOrphans flags the untagged platform manifests that no resolved index
+// in the repository references.
+func markOrphans(images []DockerImage) {
+	refs := make([]registry.ManifestRef, 0, len(images))
+	for _, img := range images {
+		refs = append(refs, registry.ManifestRef{Digest: imageDigest(img), Tagged: len(img.Tags) > 0, IsIndex: isIndex(img), Index: img.Index})
+	}
+	orphans := registry.OrphanedManifests(refs)
+	for i := range images {
+		images[i].Orphaned = orphans[imageDigest(images[i])]
+	}
+}
+
+func isIndex(img DockerImage) bool {
+	return strings.Contains(img.MediaType, "manifest.list") || strings.Contains(img.MediaType, "image.index")
 }
 
-func (s *ARScanner) analyzeImage(cfg registry.ScanConfig, repo Repository, img DockerImage) []registry.Finding {
+// sizes maps image digests in the repository to their size in bytes.
+func (s *ARScanner) analyzeImage(cfg registry.ScanConfig, repo Repository, img DockerImage, sizes map[string]int64, retained bool) []registry.Finding {
 	var findings []registry.Finding
 
 	imageID := img.URI
@@ -119,38 +686,52 @@ func (s *ARScanner) analyzeImage(cfg registry.ScanConfig, repo Repository, img D
 		imageID = img.Name
 	}
 	sizeBytes := img.SizeBytes
-	cost := pricing.MonthlyStorageCost("artifactregistry", repo.Location, sizeBytes)
+	// cost claims the image's bytes from the project's billable storage, so
+	// it is called only for findings that report them.
+	cost := func() float64 { return s.claim(repo.Location, imageID, sizeBytes) }
 	sizeMB := float64(sizeBytes) / (1024 * 1024)
 
-	// Resource name from tags
-	resourceName := ""
-	if len(img.Tags) > 0 {
-		resourceName = fmt.Sprintf("%s:%s", repo.RepoID, strings.Join(img.Tags, ","))
+	resourceName := registry.ImageName(repo.RepoID, img.Tags, cfg.TagPriority)
+	var inUse []string
+	if img.URI != "" {
+		inUse = cfg.InUse.Lookup(registry.ParseImageRef(img.URI).Repository, imageDigest(img), img.Tags)
 	}
 
-	// Untagged image
+	// Untagged image — downgraded rather than dropped when deployed by digest
 	if len(img.Tags) == 0 {
-		findings = append(findings, registry.Finding{
+		f := registry.Finding{
 			ID:                    registry.FindingUntaggedImage,
 			Severity:              registry.SeverityHigh,
 			ResourceType:          registry.ResourceImage,
 			ResourceID:            imageID,
 			Region:                repo.Location,
 			Message:               fmt.Sprintf("Untagged image (%.0f MB)", sizeMB),
-			EstimatedMonthlyWaste: cost,
+			EstimatedMonthlyWaste: cost(),
 			Metadata: map[string]any{
 				"size_bytes": sizeBytes,
 				"uri":        img.URI,
 			},
-		})
+		}
+		if img.Orphaned {
+			f.ID = registry.FindingOrphanedManifest
+			f.Message = fmt.Sprintf("Platform manifest not referenced by any multi-arch index (%.0f MB)", sizeMB)
+		}
+		if inUse != nil {
+			registry.MarkInUse(&f, inUse)
+		}
+		findings = append(findings, f)
 	}
 
-	// Stale image — uploaded > staleDays ago (GCP has no pull timestamp)
-	if cfg.StaleDays > 0 && !img.UploadTime.IsZero() {
+	// Stale image — not pulled (per audit logs) or, without pull data,
+	// uploaded > staleDays ago, unless it is deployed, kept as a recent
+	// release, or protected
+	protected := cfg.ProtectedTags.Match(img.Tags)
+	activity, lastPull := lastActivity(cfg, img)
+	if cfg.StaleDays > 0 && !activity.IsZero() && inUse == nil && !retained && !protected {
 		staleThreshold := s.now.AddDate(0, 0, -cfg.StaleDays)
-		if img.UploadTime.Before(staleThreshold) {
-			daysSince := int(s.now.Sub(img.UploadTime).Hours() / 24)
-			findings = append(findings, registry.Finding{
+		if activity.Before(staleThreshold) {
+			daysSince := int(s.now.Sub(activity).Hours() / 24)
+			f := registry.Finding{
 				ID:                    registry.FindingStaleImage,
 				Severity:              registry.SeverityHigh,
 				ResourceType:          registry.ResourceImage,
@@ -158,7 +739,7 @@ func (s *ARScanner) analyzeImage(cfg registry.ScanConfig, repo Repository, img D
 				ResourceName:          resourceName,
 				Region:                repo.Location,
 				Message:               fmt.Sprintf("Uploaded %d days ago, no pull data available (%.0f MB)", daysSince, sizeMB),
-				EstimatedMonthlyWaste: cost,
+				EstimatedMonthlyWaste: cost(),
 				Metadata: map[string]any{
 					"upload_time": img.UploadTime.Format(time.RFC3339),
 					"days_stale":  daysSince,
@@ -166,13 +747,48 @@ func (s *ARScanner) analyzeImage(cfg registry.ScanConfig, repo Repository, img D
 					"stale_days":  cfg.StaleDays,
 					"note":        "GCP AR has no pull timestamp; staleness based on upload time",
 				},
-			})
+			}
+			if cfg.Pulls != nil {
+				delete(f.Metadata, "note")
+				f.Metadata["pull_source"] = "audit_logs"
+				f.Metadata["pulls_since"] = cfg.Pulls.Since.Format(time.RFC3339)
+				if lastPull.IsZero() {
+					f.Message = fmt.Sprintf("Uploaded %d days ago, not pulled since %s (%.0f MB)", daysSince, cfg.Pulls.Since.Format("2006-01-02"), sizeMB)
+				} else {
+					f.Message = fmt.Sprintf("Last pulled %d days ago (%.0f MB)", daysSince, sizeMB)
+					f.Metadata["last_pull_time"] = lastPull.Format(time.RFC3339)
+				}
+			}
+			findings = append(findings, f)
+		}
+	}
+
+	// Tiering candidate — stale, but kept by retention so it cannot simply
+	// be deleted; Cloud Storage archive is far cheaper than the registry
+	if cfg.StaleDays > 0 && !activity.IsZero() && inUse == nil && (retained || protected) &&
+		activity.Before(s.now.AddDate(0, 0, -cfg.StaleDays)) {
+		retainedBy := "keep_latest"
+		if protected {
+			retainedBy = "protected_tags"
+		}
+		archiveCost, tier := pricing.MonthlyArchiveCost("artifactregistry", sizeBytes)
+		cold := registry.ColdImage{
+			ID: imageID, Name: resourceName, Region: repo.Location, SizeBytes: sizeBytes,
+			DaysStale: int(s.now.Sub(activity).Hours() / 24), RetainedBy: retainedBy,
+			HotCost: s.cost(repo.Location, sizeBytes), ArchiveCost: archiveCost, ArchiveTier: tier,
+		}
+		// Claim the image's bytes only once it qualifies at its full cost.
+		if registry.TieringCandidate(cfg, cold) != nil {
+			cold.HotCost = cost()
+			if f := registry.TieringCandidate(cfg, cold); f != nil {
+				findings = append(findings, *f)
+			}
 		}
 	}
 
 	// Large image
 	if cfg.MaxSizeBytes > 0 && sizeBytes > cfg.MaxSizeBytes {
-		findings = append(findings, registry.Finding{
+		f := registry.Finding{
 			ID:                    registry.FindingLargeImage,
 			Severity:              registry.SeverityMedium,
 			ResourceType:          registry.ResourceImage,
@@ -180,19 +796,29 @@ func (s *ARScanner) analyzeImage(cfg registry.ScanConfig, repo Repository, img D
 			ResourceName:          resourceName,
 			Region:                repo.Location,
 			Message:               fmt.Sprintf("Image is %.0f MB (threshold: %d MB)", sizeMB, cfg.MaxSizeBytes/(1024*1024)),
-			EstimatedMonthlyWaste: cost,
+			EstimatedMonthlyWaste: cost(),
 			Metadata: map[string]any{
 				"size_bytes":      sizeBytes,
 				"threshold_bytes": cfg.MaxSizeBytes,
 			},
-		})
+		}
+		if img.Layers != nil {
+			f.Metadata["compressed_bytes"] = img.Layers.CompressedBytes
+			f.Metadata["largest_layers"] = img.Layers.Largest
+		}
+		findings = append(findings, f)
 	}
 
-	// Multi-arch bloat
-	if strings.Contains(img.MediaType, "manifest.list") || strings.Contains(img.MediaType, "image.index") {
-		if cfg.StaleDays > 0 && !img.UploadTime.IsZero() {
+	// Multi-arch bloat — platforms outside the fleet's used platforms when the
+	// index is resolved, otherwise the whole stale index (no pull data in AR)
+	if img.Index != nil && len(cfg.UsedPlatforms) > 0 {
+		if f := s.multiArchFinding(cfg, repo, imageID, resourceName, img.Index, sizes); f != nil {
+			findings = append(findings, *f)
+		}
+	} else if isIndex(img) {
+		if cfg.StaleDays > 0 && !activity.IsZero() {
 			staleThreshold := s.now.AddDate(0, 0, -cfg.StaleDays)
-			if img.UploadTime.Before(staleThreshold) {
+			if activity.Before(staleThreshold) {
 				findings = append(findings, registry.Finding{
 					ID:                    registry.FindingMultiArchBloat,
 					Severity:              registry.SeverityLow,
@@ -201,7 +827,7 @@ func (s *ARScanner) analyzeImage(cfg registry.ScanConfig, repo Repository, img D
 					ResourceName:          resourceName,
 					Region:                repo.Location,
 					Message:               fmt.Sprintf("Stale multi-architecture image (%.0f MB)", sizeMB),
-					EstimatedMonthlyWaste: cost,
+					EstimatedMonthlyWaste: cost(),
 					Metadata: map[string]any{
 						"size_bytes": sizeBytes,
 						"media_type": img.MediaType,
@@ -211,16 +837,122 @@ func (s *ARScanner) analyzeImage(cfg registry.ScanConfig, repo Repository, img D
 		}
 	}
 
+	// Duplicate layers — the same large content stored more than once in one image
+	if img.Layers != nil && img.Layers.DuplicateLayers > 0 {
+		findings = append(findings, registry.Finding{
+			ID:                    registry.FindingDuplicateLayers,
+			Severity:              registry.SeverityMedium,
+			ResourceType:          registry.ResourceImage,
+			ResourceID:            imageID,
+			ResourceName:          resourceName,
+			Region:                repo.Location,
+			Message:               fmt.Sprintf("%d duplicate layers add %.0f MB — combine Dockerfile steps that rewrite the same files", img.Layers.DuplicateLayers, float64(img.Layers.DuplicateBytes)/(1024*1024)),
+			EstimatedMonthlyWaste: s.claim(repo.Location, imageID, img.Layers.DuplicateBytes),
+			Metadata: map[string]any{
+				"duplicate_layers": img.Layers.DuplicateLayers,
+				"duplicate_bytes":  img.Layers.DuplicateBytes,
+				"compressed_bytes": img.Layers.CompressedBytes,
+				"reported_bytes":   sizeBytes,
+				"largest_layers":   img.Layers.Largest,
+			},
+		})
+	}
+
+	if cfg.RequiresSignature(img.Tags) && !img.Signed {
+		findings = append(findings, registry.UnsignedFinding(imageID, resourceName, repo.Location, imageDigest(img)))
+	}
+	if cfg.RequiresSBOM(img.Tags, img.UploadTime, s.now) && !img.HasSBOM {
+		findings = append(findings, registry.MissingSBOMFinding(cfg, imageID, resourceName, repo.Location, imageDigest(img)))
+	}
+	if f := registry.AgingDeployedImage(cfg, imageID, resourceName, repo.Location, imageDigest(img), img.BuildDate, inUse, s.now); f != nil {
+		findings = append(findings, *f)
+	}
+	if len(cfg.Rules) > 0 {
+		custom := registry.CustomFindings(cfg, rules.Image{
+			Repository: repo.RepoID,
+			Region:     repo.Location,
+			Digest:     imageDigest(img),
+			MediaType:  img.MediaType,
+			Tags:       img.Tags,
+			SizeBytes:  sizeBytes,
+			Pushed:     img.UploadTime,
+			LastPull:   lastPull,
+		}, s.now, imageID, resourceName, 0)
+		for i := range custom {
+			custom[i].EstimatedMonthlyWaste = cost()
+		}
+		findings = append(findings, custom...)
+	}
+
+	// Flag the remaining findings as affecting a deployed image
+	if inUse != nil {
+		for i := range findings {
+			if !registry.IsInUse(findings[i]) {
+				registry.AnnotateInUse(&findings[i], inUse)
+			}
+		}
+	}
+	if protected {
+		registry.MarkProtected(findings)
+	}
+	registry.AnnotateTags(findings, img.Tags)
+	registry.AnnotatePushed(findings, img.UploadTime)
 	return findings
 }
 
-func (s *ARScanner) reportProgress(progress func(registry.ScanProgress), location, msg string) {
-	if progress != nil {
-		progress(registry.ScanProgress{
-			Region:    location,
-			Scanner:   "artifactregistry",
-			Message:   msg,
-			Timestamp: time.Now(),
+// multiArchFinding reports index platforms that are not in cfg.UsedPlatforms.
+// Attestation entries are ignored.
+func (s *ARScanner) multiArchFinding(cfg registry.ScanConfig, repo Repository, imageID, resourceName string, index *registry.Manifest, sizes map[string]int64) *registry.Finding {
+	var unused []map[string]any
+	var unusedNames []string
+	var wasteBytes int64
+	platforms := 0
+	for _, child := range index.Manifests {
+		if child.Platform == nil || child.Platform.IsAttestation() {
+			continue
+		}
+		platforms++
+		if registry.PlatformUsed(cfg.UsedPlatforms, *child.Platform) {
+			continue
+		}
+		size := sizes[child.Digest]
+		unused = append(unused, map[string]any{
+			"platform":   child.Platform.String(),
+			"digest":     child.Digest,
+			"size_bytes": size,
+			"reason":     "platform not in --used-platforms",
 		})
+		unusedNames = append(unusedNames, child.Platform.String())
+		wasteBytes += size
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+
+	return &registry.Finding{
+		ID:                    registry.FindingMultiArchBloat,
+		Severity:              registry.SeverityLow,
+		ResourceType:          registry.ResourceImage,
+		ResourceID:            imageID,
+		ResourceName:          resourceName,
+		Region:                repo.Location,
+		Message:               fmt.Sprintf("%d of %d platforms unused (%s, %.0f MB)", len(unused), platforms, strings.Join(unusedNames, ", "), float64(wasteBytes)/(1024*1024)),
+		EstimatedMonthlyWaste: s.claim(repo.Location, imageID, wasteBytes),
+		Metadata: map[string]any{
+			"unused_platforms": unused,
+			"platform_count":   platforms,
+			"size_bytes":       wasteBytes,
+		},
+	}
+}
+
+// reportProgress fills in the location, scanner and time of an event and
+// passes it to progress, if set.
+func (s *ARScanner) reportProgress(progress func(registry.ScanProgress), location string, p registry.ScanProgress) {
+	if progress != nil {
+		p.Region = location
+		p.Scanner = "artifactregistry"
+		p.Timestamp = time.Now()
+		progress(p)
 	}
 }
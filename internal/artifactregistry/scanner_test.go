@@ -3,9 +3,12 @@ package artifactregistry
 import (
 	"context"
 	"errors"
+	"math"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/ppiankov/ecrspectre/internal/pricing"
 	"github.com/ppiankov/ecrspectre/internal/registry"
 )
 
@@ -21,7 +24,7 @@ var (
 )
 
 func newTestScanner(client ARAPI) *ARScanner {
-	s := NewARScanner(client, "my-project", []string{"us-central1"})
+	s := NewARScanner(client, "my-project", []string{"us-central1"}, false)
 	s.now = now
 	return s
 }
@@ -75,6 +78,39 @@ func TestScanStaleImage(t *testing.T) {
 	}
 }
 
+func TestScanProtectedTagsNotStale(t *testing.T) {
+	mock := newMockClient()
+	mock.repos["my-project/us-central1"] = []Repository{
+		makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp"),
+	}
+	mock.images["projects/my-project/locations/us-central1/repositories/myapp"] = []DockerImage{
+		makeImage("us-central1-docker.pkg.dev/my-project/myapp/img@sha256:rel", []string{"v1.2.3"}, twoGB, stale200, ""),
+		makeImage("us-central1-docker.pkg.dev/my-project/myapp/img@sha256:dev", []string{"dev-42"}, halfGB, stale200, ""),
+	}
+
+	cfg := defaultCfg()
+	protected, err := registry.NewTagPatterns([]string{`^v\d+\.\d+\.\d+$`, "^prod-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.ProtectedTags = protected
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	stale := findByID(result.Findings, registry.FindingStaleImage)
+	if len(stale) != 1 || !strings.HasSuffix(stale[0].ResourceID, "sha256:dev") {
+		t.Fatalf("expected only the dev image stale, got %+v", stale)
+	}
+	large := findByID(result.Findings, registry.FindingLargeImage)
+	if len(large) != 1 || large[0].Metadata[registry.MetadataProtected] != true {
+		t.Errorf("LARGE_IMAGE on a protected image should be marked protected, got %+v", large)
+	}
+	tiering := findByID(result.Findings, registry.FindingTieringCandidate)
+	if len(tiering) != 1 || !strings.HasSuffix(tiering[0].ResourceID, "sha256:rel") ||
+		tiering[0].Metadata["retained_by"] != "protected_tags" || tiering[0].Metadata["archive_tier"] != "gcs-archive" {
+		t.Errorf("expected the protected release as TIERING_CANDIDATE, got %+v", tiering)
+	}
+}
+
 func TestScanRecentImageNotStale(t *testing.T) {
 	mock := newMockClient()
 	mock.repos["my-project/us-central1"] = []Repository{
@@ -193,6 +229,37 @@ func TestScanExcludeRepo(t *testing.T) {
 	}
 }
 
+func TestScanRepositoryLabels(t *testing.T) {
+	mock := newMockClient()
+	sandbox := makeRepo("projects/my-project/locations/us-central1/repositories/sandbox", "us-central1", "sandbox")
+	sandbox.Labels = map[string]string{"env": "sandbox"}
+	owned := makeRepo("projects/my-project/locations/us-central1/repositories/api", "us-central1", "api")
+	owned.Labels = map[string]string{"team": "payments", "owner": "alice"}
+	mock.repos["my-project/us-central1"] = []Repository{sandbox, owned}
+	mock.images[sandbox.Name] = []DockerImage{
+		makeImage("us-central1-docker.pkg.dev/my-project/sandbox/img@sha256:aaa", nil, halfGB, stale120, ""),
+	}
+	mock.images[owned.Name] = []DockerImage{
+		makeImage("us-central1-docker.pkg.dev/my-project/api/img@sha256:bbb", nil, halfGB, stale120, ""),
+	}
+
+	cfg := defaultCfg()
+	cfg.Exclude.Tags = map[string]string{"env": "sandbox"}
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	if len(result.Findings) == 0 {
+		t.Fatal("expected findings for the labeled repository")
+	}
+	for _, f := range result.Findings {
+		if f.Repository == "sandbox" {
+			t.Errorf("repository excluded by label has finding %s", f.ID)
+		}
+		if f.Metadata["team"] != "payments" || f.Metadata["owner"] != "alice" {
+			t.Errorf("%s metadata lacks attribution labels: %v", f.ID, f.Metadata)
+		}
+	}
+}
+
 func TestScanListReposError(t *testing.T) {
 	mock := newMockClient()
 	mock.listRepoErr["my-project/us-central1"] = errors.New("permission denied")
@@ -203,6 +270,15 @@ func TestScanListReposError(t *testing.T) {
 	if len(result.Errors) == 0 {
 		t.Error("expected error in result.Errors")
 	}
+	if !result.AllTargetsFailed() || result.TargetErrors[0] != (registry.TargetError{Target: "us-central1", Err: "permission denied"}) {
+		t.Errorf("target errors = %d of %d: %+v", len(result.TargetErrors), result.Targets, result.TargetErrors)
+	}
+
+	// A second, working location leaves the scan partial rather than failed.
+	s = NewARScanner(mock, "my-project", []string{"us-central1", "europe-west1"}, false)
+	if result := s.Scan(context.Background(), defaultCfg(), nil); result.AllTargetsFailed() || result.Targets != 2 {
+		t.Errorf("one of two locations failed: targets %d, target errors %+v", result.Targets, result.TargetErrors)
+	}
 }
 
 func TestScanListImagesError(t *testing.T) {
@@ -238,6 +314,249 @@ func TestScanMultiArchBloat(t *testing.T) {
 	}
 }
 
+func TestScanMultiArchUsedPlatforms(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/multiarch", "us-central1", "multiarch")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	idxURI := "us-central1-docker.pkg.dev/my-project/multiarch/img@sha256:idx"
+	mock.images[repo.Name] = []DockerImage{
+		makeImage(idxURI, []string{"latest"}, 1000, recent, "application/vnd.oci.image.index.v1+json"),
+		makeImage("us-central1-docker.pkg.dev/my-project/multiarch/img@sha256:amd", nil, oneGB, recent, ""),
+		makeImage("us-central1-docker.pkg.dev/my-project/multiarch/img@sha256:arm", nil, halfGB, recent, ""),
+	}
+	mock.manifests[idxURI] = `{"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[
+		{"digest":"sha256:amd","platform":{"os":"linux","architecture":"amd64"}},
+		{"digest":"sha256:arm","platform":{"os":"linux","architecture":"arm64"}}]}`
+
+	cfg := defaultCfg()
+	cfg.UsedPlatforms = []string{"linux/amd64"}
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	bloat := findByID(result.Findings, registry.FindingMultiArchBloat)
+	if len(bloat) != 1 {
+		t.Fatalf("expected 1 MULTI_ARCH_BLOAT, got %d", len(bloat))
+	}
+	if bloat[0].Metadata["size_bytes"] != halfGB {
+		t.Errorf("size_bytes = %v, want %d", bloat[0].Metadata["size_bytes"], halfGB)
+	}
+}
+
+func TestScanInUseImages(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	mock.images[repo.Name] = []DockerImage{
+		makeImage("us-central1-docker.pkg.dev/my-project/myapp/api@sha256:running", []string{"v1"}, twoGB, stale200, ""),
+		makeImage("us-central1-docker.pkg.dev/my-project/myapp/api@sha256:old", []string{"v0"}, hundredMB, stale200, ""),
+	}
+
+	cfg := defaultCfg()
+	cfg.InUse = registry.NewInUse()
+	cfg.InUse.Add("us-central1-docker.pkg.dev/my-project/myapp/api:v1", "k8s:gke/default/api-1")
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	stale := findByID(result.Findings, registry.FindingStaleImage)
+	if len(stale) != 1 || !strings.HasSuffix(stale[0].ResourceID, "sha256:old") {
+		t.Fatalf("expected only the undeployed image to be stale, got %v", stale)
+	}
+	large := findByID(result.Findings, registry.FindingLargeImage)
+	if len(large) != 1 || !registry.IsInUse(large[0]) || large[0].Severity != registry.SeverityMedium {
+		t.Errorf("LARGE_IMAGE on a deployed image should be annotated, not downgraded: %v", large)
+	}
+}
+
+func TestScanAuditLogPulls(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	mock.images[repo.Name] = []DockerImage{
+		makeImage("us-central1-docker.pkg.dev/my-project/myapp/api@sha256:pulled", []string{"v1"}, halfGB, stale200, ""),
+		makeImage("us-central1-docker.pkg.dev/my-project/myapp/api@sha256:oldpull", []string{"v2"}, halfGB, stale200, ""),
+		makeImage("us-central1-docker.pkg.dev/my-project/myapp/api@sha256:never", []string{"v3"}, halfGB, stale200, ""),
+	}
+
+	cfg := defaultCfg()
+	cfg.Pulls = registry.NewPullLog(now.AddDate(0, 0, -180))
+	cfg.Pulls.Record("my-project/myapp/api:v1", recent)
+	cfg.Pulls.Record("my-project/myapp/api@sha256:oldpull", stale120)
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	stale := findByID(result.Findings, registry.FindingStaleImage)
+	if len(stale) != 2 {
+		t.Fatalf("expected 2 STALE_IMAGE (recently pulled image excluded), got %v", stale)
+	}
+	for _, f := range stale {
+		if f.Metadata["pull_source"] != "audit_logs" || f.Metadata["note"] != nil {
+			t.Errorf("metadata = %v, want audit log source", f.Metadata)
+		}
+		switch {
+		case strings.HasSuffix(f.ResourceID, "sha256:oldpull"):
+			if f.Metadata["days_stale"] != 120 || !strings.HasPrefix(f.Message, "Last pulled 120 days ago") {
+				t.Errorf("old pull finding = %q %v", f.Message, f.Metadata["days_stale"])
+			}
+		case strings.HasSuffix(f.ResourceID, "sha256:never"):
+			if !strings.Contains(f.Message, "not pulled since") || f.Metadata["days_stale"] != 200 {
+				t.Errorf("never pulled finding = %q %v", f.Message, f.Metadata["days_stale"])
+			}
+		default:
+			t.Errorf("unexpected STALE_IMAGE %s", f.ResourceID)
+		}
+	}
+}
+
+func TestScanIncludeScanEmitsVulnerableImage(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	vulnURI := "us-central1-docker.pkg.dev/my-project/myapp/api@sha256:vuln"
+	lowURI := "us-central1-docker.pkg.dev/my-project/myapp/api@sha256:low"
+	mock.images[repo.Name] = []DockerImage{
+		makeImage(vulnURI, []string{"v1"}, hundredMB, recent, ""),
+		makeImage(lowURI, []string{"v2"}, hundredMB, recent, ""),
+		makeImage("us-central1-docker.pkg.dev/my-project/myapp/api@sha256:unscanned", []string{"v3"}, hundredMB, recent, ""),
+	}
+	mock.vulns[vulnURI] = map[string]int{"CRITICAL": 1, "HIGH": 2, "MEDIUM": 4}
+	mock.vulns[lowURI] = map[string]int{"LOW": 3}
+
+	without := newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+	if got := findByID(without.Findings, registry.FindingVulnerableImage); len(got) != 0 {
+		t.Fatalf("expected no VULNERABLE_IMAGE without include-scan, got %d", len(got))
+	}
+
+	s := NewARScanner(mock, "my-project", []string{"us-central1"}, true)
+	s.now = now
+	result := s.Scan(context.Background(), defaultCfg(), nil)
+	vuln := findByID(result.Findings, registry.FindingVulnerableImage)
+	if len(vuln) != 1 {
+		t.Fatalf("expected 1 VULNERABLE_IMAGE, got %d", len(vuln))
+	}
+	f := vuln[0]
+	if f.ResourceID != vulnURI || f.ResourceName != "myapp:v1" || f.Severity != registry.SeverityCritical || f.Repository != "myapp" {
+		t.Errorf("finding = %+v", f)
+	}
+	if f.Metadata["total_findings"] != 7 || f.Metadata["critical_count"] != 1 || f.Metadata["high_count"] != 2 {
+		t.Errorf("metadata = %v", f.Metadata)
+	}
+}
+
+func TestScanVulnerabilityErrorsCollapsed(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	for _, d := range []string{"a", "b", "c"} {
+		uri := "us-central1-docker.pkg.dev/my-project/myapp/api@sha256:" + d
+		mock.images[repo.Name] = append(mock.images[repo.Name], makeImage(uri, []string{d}, hundredMB, recent, ""))
+		mock.vulnErr[uri] = errors.New("HTTP 403: Container Analysis API has not been used")
+	}
+
+	s := NewARScanner(mock, "my-project", []string{"us-central1"}, true)
+	s.now = now
+	result := s.Scan(context.Background(), defaultCfg(), nil)
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "and 2 more images") {
+		t.Errorf("errors = %v, want one collapsed message", result.Errors)
+	}
+}
+
+func TestScanFreeTierSuppressesPhantomWaste(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+	repo.SizeBytes = 2 * hundredMB
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	mock.images[repo.Name] = []DockerImage{
+		makeImage("us-central1-docker.pkg.dev/my-project/myapp/img@sha256:old", []string{"v1"}, 2*hundredMB, stale200, ""),
+	}
+
+	result := newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+	stale := findByID(result.Findings, registry.FindingStaleImage)
+	if len(stale) != 1 {
+		t.Fatalf("expected 1 STALE_IMAGE, got %d", len(stale))
+	}
+	if stale[0].EstimatedMonthlyWaste != 0 {
+		t.Errorf("waste = %f, want 0 for a project within the free tier", stale[0].EstimatedMonthlyWaste)
+	}
+}
+
+func TestScanFreeTierSharedAcrossFindings(t *testing.T) {
+	mb := int64(1024 * 1024)
+	setup := func() *mockARClient {
+		mock := newMockClient()
+		repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+		repo.SizeBytes = 900 * mb
+		mock.repos["my-project/us-central1"] = []Repository{repo}
+		mock.images[repo.Name] = []DockerImage{
+			makeImage("us-central1-docker.pkg.dev/my-project/myapp/img@sha256:new", []string{"v6"}, 150*mb, recent, ""),
+		}
+		for _, d := range []string{"a", "b", "c", "d", "e"} {
+			mock.images[repo.Name] = append(mock.images[repo.Name],
+				makeImage("us-central1-docker.pkg.dev/my-project/myapp/img@sha256:"+d, []string{"v" + d}, 150*mb, stale200, ""))
+		}
+		return mock
+	}
+	staleWaste := func(mock *mockARClient) float64 {
+		result := newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+		stale := findByID(result.Findings, registry.FindingStaleImage)
+		if len(stale) != 5 {
+			t.Fatalf("expected 5 STALE_IMAGE, got %d", len(stale))
+		}
+		var total float64
+		for _, f := range stale {
+			total += f.EstimatedMonthlyWaste
+		}
+		return total
+	}
+	perGB := pricing.MonthlyStorageCost("artifactregistry", "us-central1", oneGB)
+	billable := func(bytes int64) float64 { return perGB * float64(bytes) / float64(oneGB) }
+
+	// 750 MB of stale images in a 900 MB project save only its 388 MB bill.
+	if got, want := staleWaste(setup()), billable(388*mb); math.Abs(got-want) > 1e-9 {
+		t.Errorf("waste = %f, want %f for the project's billable storage", got, want)
+	}
+
+	// Storage in a location outside the scan still counts toward the total.
+	mock := setup()
+	other := makeRepo("projects/my-project/locations/europe-west1/repositories/other", "europe-west1", "other")
+	other.SizeBytes = 300 * mb
+	mock.repos["my-project/europe-west1"] = []Repository{other}
+	if got, want := staleWaste(mock), billable(688*mb); math.Abs(got-want) > 1e-9 {
+		t.Errorf("waste = %f, want %f with an unscanned location", got, want)
+	}
+
+	// An unlistable location leaves the total unknown: no free tier applies.
+	mock = setup()
+	mock.listRepoErr["my-project/europe-west1"] = errors.New("permission denied")
+	if got, want := staleWaste(mock), billable(750*mb); math.Abs(got-want) > 1e-9 {
+		t.Errorf("waste = %f, want %f with an unknown project total", got, want)
+	}
+}
+
+func TestScanQuotaPressure(t *testing.T) {
+	mock := newMockClient()
+	full := makeRepo("projects/my-project/locations/us-central1/repositories/models", "us-central1", "models")
+	full.SizeBytes = 9 * oneGB
+	small := makeRepo("projects/my-project/locations/us-central1/repositories/api", "us-central1", "api")
+	small.SizeBytes = oneGB
+	mock.repos["my-project/us-central1"] = []Repository{full, small}
+
+	cfg := defaultCfg()
+	cfg.Quota = registry.QuotaConfig{RepositoryBytes: 10 * oneGB, ProjectBytes: 10 * oneGB}
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	quota := findByID(result.Findings, registry.FindingQuotaPressure)
+	if len(quota) != 2 {
+		t.Fatalf("expected repository and project QUOTA_PRESSURE, got %v", quota)
+	}
+	byType := map[registry.ResourceType]registry.Finding{}
+	for _, f := range quota {
+		byType[f.ResourceType] = f
+	}
+	if byType[registry.ResourceRepository].ResourceID != "models" {
+		t.Errorf("repository finding = %v", byType[registry.ResourceRepository])
+	}
+	if p := byType[registry.ResourceProject]; p.ResourceID != "my-project" || p.Severity != registry.SeverityHigh {
+		t.Errorf("project finding = %v", p)
+	}
+}
+
 func TestScanResourcesScannedCount(t *testing.T) {
 	mock := newMockClient()
 	mock.repos["my-project/us-central1"] = []Repository{
@@ -300,7 +619,7 @@ func TestScanMultipleLocations(t *testing.T) {
 		makeImage("uri2", []string{"v1"}, hundredMB, recent, ""),
 	}
 
-	s := NewARScanner(mock, "my-project", []string{"us-central1", "europe-west1"})
+	s := NewARScanner(mock, "my-project", []string{"us-central1", "europe-west1"}, false)
 	s.now = now
 	result := s.Scan(context.Background(), defaultCfg(), nil)
 
@@ -312,21 +631,44 @@ func TestScanMultipleLocations(t *testing.T) {
 	}
 }
 
-func TestNoLifecyclePolicyNotEmittedForGCP(t *testing.T) {
-	mock := newMockClient()
-	mock.repos["my-project/us-central1"] = []Repository{
-		makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp"),
-	}
-	mock.images["projects/my-project/locations/us-central1/repositories/myapp"] = []DockerImage{
-		makeImage("uri", []string{"latest"}, hundredMB, recent, ""),
-	}
-
-	s := newTestScanner(mock)
-	result := s.Scan(context.Background(), defaultCfg(), nil)
-
-	nolp := findByID(result.Findings, registry.FindingNoLifecyclePolicy)
-	if len(nolp) != 0 {
-		t.Error("NO_LIFECYCLE_POLICY should not be emitted for GCP scans")
+func TestScanCleanupPolicy(t *testing.T) {
+	cases := []struct {
+		name         string
+		policies     int
+		deletes      bool
+		dryRun       bool
+		wantSeverity registry.Severity
+		wantMessage  string
+	}{
+		{"none", 0, false, false, registry.SeverityMedium, "No cleanup policy"},
+		{"keep only", 2, false, false, registry.SeverityMedium, "only keep images"},
+		{"dry run", 1, true, true, registry.SeverityLow, "dry-run"},
+		{"active", 1, true, false, "", ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+			repo.CleanupPolicies, repo.CleanupDeletes, repo.CleanupDryRun = c.policies, c.deletes, c.dryRun
+			mock := newMockClient()
+			mock.repos["my-project/us-central1"] = []Repository{repo}
+			mock.images[repo.Name] = []DockerImage{makeImage("uri", []string{"latest"}, hundredMB, recent, "")}
+
+			result := newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+
+			nolp := findByID(result.Findings, registry.FindingNoLifecyclePolicy)
+			if c.wantMessage == "" {
+				if len(nolp) != 0 {
+					t.Errorf("unexpected finding: %+v", nolp)
+				}
+				return
+			}
+			if len(nolp) != 1 {
+				t.Fatalf("NO_LIFECYCLE_POLICY findings = %d, want 1", len(nolp))
+			}
+			if nolp[0].Severity != c.wantSeverity || !strings.Contains(nolp[0].Message, c.wantMessage) {
+				t.Errorf("finding = %s %q", nolp[0].Severity, nolp[0].Message)
+			}
+		})
 	}
 }
 
@@ -425,3 +767,312 @@ func findByID(findings []registry.Finding, id registry.FindingID) []registry.Fin
 	}
 	return out
 }
+
+func TestScanPrioritizesLargestRepositories(t *testing.T) {
+	mock := newMockClient()
+	small := makeRepo("projects/my-project/locations/us-central1/repositories/small", "us-central1", "small")
+	small.SizeBytes = hundredMB
+	small.CleanupPolicies, small.CleanupDeletes = 1, true
+	big := makeRepo("projects/my-project/locations/us-central1/repositories/big", "us-central1", "big")
+	big.SizeBytes = twoGB
+	mock.repos["my-project/us-central1"] = []Repository{small, big}
+	mock.images[small.Name] = []DockerImage{makeImage("small@sha256:a", []string{"v1"}, hundredMB, recent, "")}
+	mock.images[big.Name] = []DockerImage{makeImage("big@sha256:b", nil, halfGB, recent, "")}
+
+	var scanned []string
+	progress := func(p registry.ScanProgress) {
+		if p.Message == "Scanning small" || p.Message == "Scanning big" {
+			scanned = append(scanned, p.Message)
+		}
+	}
+
+	s := newTestScanner(mock)
+	result := s.Scan(context.Background(), defaultCfg(), progress)
+
+	if len(scanned) != 2 || scanned[0] != "Scanning big" {
+		t.Errorf("scan order = %v, want big first", scanned)
+	}
+	if result.Coverage.Truncated || result.Coverage.Percent != 100 {
+		t.Errorf("coverage = %+v, want complete", result.Coverage)
+	}
+	for _, f := range result.Findings {
+		if f.Repository != "big" {
+			t.Errorf("%s Repository = %q, want big", f.ID, f.Repository)
+		}
+	}
+}
+
+func TestScanCancelledContextTruncates(t *testing.T) {
+	mock := newMockClient()
+	mock.repos["my-project/us-central1"] = []Repository{
+		makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp"),
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := newTestScanner(mock)
+	result := s.Scan(ctx, defaultCfg(), nil)
+
+	if !result.Coverage.Truncated || result.Coverage.RepositoriesCompleted != 0 {
+		t.Errorf("coverage = %+v, want truncated with 0 completed", result.Coverage)
+	}
+	if len(result.Errors) == 0 {
+		t.Error("expected deadline error")
+	}
+}
+
+func TestScanRepoFilter(t *testing.T) {
+	mock := newMockClient()
+	keep := makeRepo("projects/my-project/locations/us-central1/repositories/team-a-api", "us-central1", "team-a-api")
+	skip := makeRepo("projects/my-project/locations/us-central1/repositories/team-b-api", "us-central1", "team-b-api")
+	mock.repos["my-project/us-central1"] = []Repository{keep, skip}
+	mock.images[keep.Name] = []DockerImage{makeImage("a@sha256:a", nil, halfGB, recent, "")}
+	mock.images[skip.Name] = []DockerImage{makeImage("b@sha256:b", nil, halfGB, recent, "")}
+
+	cfg := defaultCfg()
+	filter, err := registry.NewRepoFilter([]string{"team-a-*"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg.Repos = filter
+
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+	if result.RepositoriesScanned != 1 {
+		t.Errorf("RepositoriesScanned = %d, want 1", result.RepositoriesScanned)
+	}
+	for _, f := range result.Findings {
+		if f.Repository != "team-a-api" {
+			t.Errorf("unexpected finding for %s", f.Repository)
+		}
+	}
+}
+
+func TestScanRepositoryDetail(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/europe-west1/repositories/myapp", "europe-west1", "myapp")
+	mock.repos["my-project/europe-west1"] = []Repository{repo}
+	mock.images[repo.Name] = []DockerImage{
+		makeImage("europe-west1-docker.pkg.dev/my-project/myapp/img@sha256:aaa", nil, halfGB, recent, ""),
+		makeImage("europe-west1-docker.pkg.dev/my-project/myapp/img@sha256:bbb", []string{"v1"}, halfGB, recent, ""),
+	}
+
+	s := NewARScanner(mock, "my-project", []string{"us-central1", "europe-west1"}, false)
+	s.now = now
+	result := s.ScanRepository(context.Background(), defaultCfg(), "myapp", nil)
+
+	if result.RepositoriesScanned != 1 {
+		t.Errorf("RepositoriesScanned = %d, want 1", result.RepositoriesScanned)
+	}
+	d := result.Detail
+	if d == nil {
+		t.Fatal("expected repository detail")
+	}
+	if d.Region != "europe-west1" || d.ImageCount != 2 || d.LifecycleSimulated {
+		t.Errorf("unexpected detail: %+v", d)
+	}
+	if d.Images[0].Digest != "sha256:aaa" || len(d.Images[0].Findings) != 1 {
+		t.Errorf("unexpected image detail: %+v", d.Images[0])
+	}
+	for _, f := range result.Findings {
+		if f.Repository != "myapp" {
+			t.Errorf("finding repository = %q, want myapp", f.Repository)
+		}
+	}
+}
+
+func TestScanRepositoryNotFound(t *testing.T) {
+	result := newTestScanner(newMockClient()).ScanRepository(context.Background(), defaultCfg(), "missing", nil)
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "not found") {
+		t.Errorf("expected not found error, got %v", result.Errors)
+	}
+}
+
+func TestScanDeepLayersDuplicate(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	uri := "us-central1-docker.pkg.dev/my-project/myapp/img@sha256:aaa"
+	mock.images[repo.Name] = []DockerImage{makeImage(uri, []string{"v1"}, halfGB, recent, "")}
+	mock.manifests[uri] = `{"layers":[{"digest":"sha256:l1","size":52428800},{"digest":"sha256:l1","size":52428800}]}`
+
+	cfg := defaultCfg()
+	cfg.DeepLayers = true
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	dups := findByID(result.Findings, registry.FindingDuplicateLayers)
+	if len(dups) != 1 {
+		t.Fatalf("expected 1 DUPLICATE_LAYERS, got %d", len(dups))
+	}
+	if dups[0].Metadata["duplicate_layers"] != 1 {
+		t.Errorf("duplicate_layers = %v", dups[0].Metadata["duplicate_layers"])
+	}
+}
+
+func TestScanAgingDeployedImage(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	base := "us-central1-docker.pkg.dev/my-project/myapp/api@"
+	mock.images[repo.Name] = []DockerImage{
+		makeImage(base+"sha256:old", []string{"v1"}, 1000, recent, ""),
+		makeImage(base+"sha256:new", []string{"v2"}, 1000, recent, ""),
+	}
+	mock.manifests[base+"sha256:old"] = `{"config":{"digest":"sha256:cfgold","size":10},"layers":[{"digest":"sha256:l1","size":5}]}`
+	mock.manifests[base+"sha256:new"] = `{"config":{"digest":"sha256:cfgnew","size":10},"layers":[{"digest":"sha256:l2","size":5}]}`
+	mock.configs["sha256:cfgold"] = `{"created":"2025-03-01T00:00:00Z"}`
+	mock.configs["sha256:cfgnew"] = `{"created":"2026-02-20T00:00:00Z"}`
+
+	cfg := defaultCfg()
+	cfg.DeepLayers = true
+	cfg.MaxDeployedAge = 90 * 24 * time.Hour
+	cfg.InUse = registry.NewInUse()
+	cfg.InUse.Add("us-central1-docker.pkg.dev/my-project/myapp/api:v1", "k8s:gke/default/api-1")
+	cfg.InUse.Add("us-central1-docker.pkg.dev/my-project/myapp/api:v2", "k8s:gke/default/api-2")
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	aging := findByID(result.Findings, registry.FindingAgingDeployedImage)
+	if len(aging) != 1 || aging[0].ResourceID != base+"sha256:old" {
+		t.Fatalf("expected AGING_DEPLOYED_IMAGE for sha256:old only, got %+v", aging)
+	}
+	if aging[0].Metadata["built_at"] != "2025-03-01T00:00:00Z" {
+		t.Errorf("built_at = %v", aging[0].Metadata["built_at"])
+	}
+	if len(result.Errors) != 0 {
+		t.Errorf("unexpected errors: %v", result.Errors)
+	}
+}
+
+func TestScanOrphanedManifest(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	base := "us-central1-docker.pkg.dev/my-project/myapp/img@"
+	mock.images[repo.Name] = []DockerImage{
+		makeImage(base+"sha256:idx", []string{"v2"}, 1000, recent, "application/vnd.oci.image.index.v1+json"),
+		makeImage(base+"sha256:amd", nil, halfGB, recent, ""),
+		makeImage(base+"sha256:old", nil, oneGB, recent, ""),
+	}
+	mock.manifests[base+"sha256:idx"] = `{"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[{"digest":"sha256:amd","size":1,"platform":{"os":"linux","architecture":"amd64"}}]}`
+	mock.manifests[base+"sha256:amd"] = `{"layers":[{"digest":"sha256:l1","size":5}]}`
+	mock.manifests[base+"sha256:old"] = `{"layers":[{"digest":"sha256:l2","size":5}]}`
+
+	cfg := defaultCfg()
+	cfg.DeepLayers = true
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	orphans := findByID(result.Findings, registry.FindingOrphanedManifest)
+	if len(orphans) != 1 || orphans[0].ResourceID != base+"sha256:old" {
+		t.Fatalf("expected sha256:old orphaned, got %+v", orphans)
+	}
+	if got := len(findByID(result.Findings, registry.FindingUntaggedImage)); got != 1 {
+		t.Errorf("expected UNTAGGED_IMAGE for the referenced child only, got %d", got)
+	}
+	if got := len(findByID(result.Findings, registry.FindingMultiArchBloat)); got != 0 {
+		t.Errorf("deep mode alone should not report MULTI_ARCH_BLOAT, got %d", got)
+	}
+	if len(result.Errors) != 0 {
+		t.Errorf("unexpected errors: %v", result.Errors)
+	}
+}
+
+func TestScanUnsignedImage(t *testing.T) {
+	signedHex := strings.Repeat("a", 64)
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	base := "us-central1-docker.pkg.dev/my-project/myapp/img@"
+	referrer := makeImage(base+"sha256:ref", nil, 100, recent, "application/vnd.oci.image.manifest.v1+json")
+	referrer.ArtifactType = "application/vnd.cncf.notary.signature"
+	mock.images[repo.Name] = []DockerImage{
+		makeImage(base+"sha256:"+signedHex, []string{"prod-1"}, hundredMB, recent, ""),
+		makeImage(base+"sha256:sig", []string{"sha256-" + signedHex + ".sig"}, 100, recent, ""),
+		makeImage(base+"sha256:notary", []string{"prod-2"}, hundredMB, recent, ""),
+		referrer,
+		makeImage(base+"sha256:unsigned", []string{"prod-3", "latest"}, hundredMB, recent, ""),
+	}
+	mock.manifests[base+"sha256:ref"] = `{"mediaType":"application/vnd.oci.image.manifest.v1+json","config":{"mediaType":"application/vnd.cncf.notary.signature","digest":"sha256:c","size":2},"subject":{"digest":"sha256:notary","size":1}}`
+
+	cfg := defaultCfg()
+	cfg.RequireSignatures = true
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	unsigned := findByID(result.Findings, registry.FindingUnsignedImage)
+	if len(unsigned) != 1 || unsigned[0].ResourceID != base+"sha256:unsigned" {
+		t.Fatalf("expected only sha256:unsigned reported, got %+v", unsigned)
+	}
+	if len(result.Errors) != 0 {
+		t.Errorf("unexpected errors: %v", result.Errors)
+	}
+}
+
+func TestScanMissingSBOM(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	base := "us-central1-docker.pkg.dev/my-project/myapp/img@"
+	referrer := makeImage(base+"sha256:cdx", nil, 100, recent, "application/vnd.oci.image.manifest.v1+json")
+	referrer.ArtifactType = "application/vnd.cyclonedx+json"
+	mock.images[repo.Name] = []DockerImage{
+		makeImage(base+"sha256:referred", []string{"v1"}, hundredMB, recent, ""),
+		referrer,
+		makeImage(base+"sha256:missing", []string{"v2"}, hundredMB, recent, ""),
+	}
+	mock.manifests[base+"sha256:cdx"] = `{"artifactType":"application/vnd.cyclonedx+json","subject":{"digest":"sha256:referred","size":1}}`
+
+	cfg := defaultCfg()
+	cfg.RequireSBOM = true
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	missing := findByID(result.Findings, registry.FindingMissingSBOM)
+	if len(missing) != 1 || missing[0].ResourceID != base+"sha256:missing" || missing[0].Severity != registry.SeverityMedium {
+		t.Fatalf("expected sha256:missing at medium severity, got %+v", missing)
+	}
+	if got := len(findByID(result.Findings, registry.FindingUnsignedImage)); got != 0 {
+		t.Errorf("SBOM check alone should not report UNSIGNED_IMAGE, got %d", got)
+	}
+}
+
+func TestScanDeepLayersManifestError(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/myapp", "us-central1", "myapp")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	mock.images[repo.Name] = []DockerImage{makeImage("host/p/myapp/img@sha256:aaa", []string{"v1"}, halfGB, recent, "")}
+
+	cfg := defaultCfg()
+	cfg.DeepLayers = true
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "manifest") {
+		t.Errorf("expected manifest error, got %v", result.Errors)
+	}
+}
+
+func TestScanStaleReleaseTrain(t *testing.T) {
+	mock := newMockClient()
+	repo := makeRepo("projects/my-project/locations/us-central1/repositories/prod-api", "us-central1", "prod-api")
+	mock.repos["my-project/us-central1"] = []Repository{repo}
+	base := "us-central1-docker.pkg.dev/my-project/prod-api/img@"
+	mock.images[repo.Name] = []DockerImage{
+		makeImage(base+"sha256:a", []string{"v1"}, hundredMB, stale200, ""),
+		makeImage(base+"sha256:b", []string{"v2"}, hundredMB, stale120, ""),
+	}
+
+	monthly, err := registry.NewReleaseCadence("prod-*", "monthly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := defaultCfg()
+	cfg.ReleaseCadences = []registry.ReleaseCadence{monthly}
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	stale := findByID(result.Findings, registry.FindingStaleReleaseTrain)
+	if len(stale) != 1 || stale[0].ResourceID != "prod-api" || stale[0].Region != "us-central1" {
+		t.Fatalf("STALE_RELEASE_TRAIN = %+v, want prod-api", stale)
+	}
+
+	cfg.DisabledChecks = map[registry.FindingID]bool{registry.FindingStaleReleaseTrain: true}
+	result = newTestScanner(mock).Scan(context.Background(), cfg, nil)
+	if got := findByID(result.Findings, registry.FindingStaleReleaseTrain); len(got) != 0 {
+		t.Errorf("disabled check reported %+v", got)
+	}
+}
@@ -0,0 +1,247 @@
+// Package attest produces in-toto attestations describing a scan, so consumers
+// can verify which tool version and configuration produced a report.
+package attest
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/report"
+)
+
+const (
+	// StatementType is the in-toto Statement v1 type.
+	StatementType = "https://in-toto.io/Statement/v1"
+	// PredicateType is the SLSA provenance v1 predicate type.
+	PredicateType = "https://slsa.dev/provenance/v1"
+	// BuildType identifies an ecrspectre registry scan.
+	BuildType = "https://github.com/ppiankov/ecrspectre/scan/v1"
+	// PayloadType is the DSSE payload type for in-toto statements.
+	PayloadType = "application/vnd.in-toto+json"
+
+	builderID = "https://github.com/ppiankov/ecrspectre"
+)
+
+// Statement is an in-toto v1 statement with a SLSA provenance predicate.
+type Statement struct {
+	Type          string     `json:"_type"`
+	Subject       []Subject  `json:"subject"`
+	PredicateType string     `json:"predicateType"`
+	Predicate     Provenance `json:"predicate"`
+}
+
+// Subject is an artifact covered by the attestation.
+type Subject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// Provenance is the SLSA v1 provenance predicate.
+type Provenance struct {
+	BuildDefinition BuildDefinition `json:"buildDefinition"`
+	RunDetails      RunDetails      `json:"runDetails"`
+}
+
+// BuildDefinition describes the scan inputs.
+type BuildDefinition struct {
+	BuildType          string         `json:"buildType"`
+	ExternalParameters map[string]any `json:"externalParameters"`
+}
+
+// RunDetails describes the tool and timing of the scan.
+type RunDetails struct {
+	Builder  Builder  `json:"builder"`
+	Metadata Metadata `json:"metadata"`
+}
+
+// Builder identifies the tool version that ran the scan.
+type Builder struct {
+	ID      string            `json:"id"`
+	Version map[string]string `json:"version"`
+}
+
+// Metadata holds the scan's run ID and timing.
+type Metadata struct {
+	InvocationID string    `json:"invocationId,omitempty"`
+	StartedOn    time.Time `json:"startedOn"`
+	FinishedOn   time.Time `json:"finishedOn"`
+}
+
+// Envelope is a DSSE envelope carrying a signed statement.
+type Envelope struct {
+	PayloadType string      `json:"payloadType"`
+	Payload     string      `json:"payload"`
+	Signatures  []Signature `json:"signatures"`
+}
+
+// Signature is a DSSE signature.
+type Signature struct {
+	KeyID string `json:"keyid"`
+	Sig   string `json:"sig"`
+}
+
+// Build creates the statement for a scan. The result subject is the SHA-256 of
+// the report data encoded as JSON; each of reportFiles is added as a further
+// subject with the digest of its contents.
+func Build(data report.Data, reportFiles []string, startedOn time.Time) (*Statement, error) {
+	configDigest, err := digestJSON(data.Config)
+	if err != nil {
+		return nil, err
+	}
+	resultDigest, err := digestJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	st := &Statement{
+		Type: StatementType,
+		Subject: []Subject{{
+			Name:   "ecrspectre-result",
+			Digest: map[string]string{"sha256": resultDigest},
+		}},
+		PredicateType: PredicateType,
+		Predicate: Provenance{
+			BuildDefinition: BuildDefinition{
+				BuildType: BuildType,
+				ExternalParameters: map[string]any{
+					"target":        data.Target,
+					"config":        data.Config,
+					"config_digest": "sha256:" + configDigest,
+				},
+			},
+			RunDetails: RunDetails{
+				Builder: Builder{
+					ID:      builderID + "@" + data.Version,
+					Version: map[string]string{data.Tool: data.Version},
+				},
+				Metadata: Metadata{
+					InvocationID: data.RunID,
+					StartedOn:    startedOn.UTC(),
+					FinishedOn:   data.Timestamp.UTC(),
+				},
+			},
+		},
+	}
+
+	for _, reportFile := range reportFiles {
+		content, err := os.ReadFile(reportFile)
+		if err != nil {
+			return nil, fmt.Errorf("read report for attestation: %w", err)
+		}
+		sum := sha256.Sum256(content)
+		st.Subject = append(st.Subject, Subject{
+			Name:   reportFile,
+			Digest: map[string]string{"sha256": hex.EncodeToString(sum[:])},
+		})
+	}
+	return st, nil
+}
+
+// Sign wraps the statement in a DSSE envelope signed with an Ed25519 key.
+func Sign(st *Statement, key ed25519.PrivateKey) (*Envelope, error) {
+	payload, err := json.Marshal(st)
+	if err != nil {
+		return nil, fmt.Errorf("encode statement: %w", err)
+	}
+	sig := ed25519.Sign(key, pae(PayloadType, payload))
+	return &Envelope{
+		PayloadType: PayloadType,
+		Payload:     base64.StdEncoding.EncodeToString(payload),
+		Signatures: []Signature{{
+			KeyID: KeyID(key.Public().(ed25519.PublicKey)),
+			Sig:   base64.StdEncoding.EncodeToString(sig),
+		}},
+	}, nil
+}
+
+// Verify checks the envelope signature against a public key and returns the statement.
+func Verify(env *Envelope, pub ed25519.PublicKey) (*Statement, error) {
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("decode payload: %w", err)
+	}
+	for _, s := range env.Signatures {
+		sig, err := base64.StdEncoding.DecodeString(s.Sig)
+		if err != nil {
+			continue
+		}
+		if ed25519.Verify(pub, pae(env.PayloadType, payload), sig) {
+			var st Statement
+			if err := json.Unmarshal(payload, &st); err != nil {
+				return nil, fmt.Errorf("decode statement: %w", err)
+			}
+			return &st, nil
+		}
+	}
+	return nil, fmt.Errorf("no valid signature for key %s", KeyID(pub))
+}
+
+// KeyID returns the hex SHA-256 of a public key, used as the DSSE key ID.
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:])
+}
+
+// LoadSigningKey reads a PEM-encoded PKCS#8 Ed25519 private key.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read signing key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse signing key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("signing key %s is not an Ed25519 key", path)
+	}
+	return edKey, nil
+}
+
+// WriteFile writes the statement to path, as a signed DSSE envelope when key
+// is set and as a bare statement otherwise.
+func WriteFile(path string, st *Statement, key ed25519.PrivateKey) error {
+	var out any = st
+	if key != nil {
+		env, err := Sign(st, key)
+		if err != nil {
+			return err
+		}
+		out = env
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode attestation: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write attestation: %w", err)
+	}
+	return nil
+}
+
+// pae is the DSSE pre-authentication encoding.
+func pae(payloadType string, payload []byte) []byte {
+	return fmt.Appendf(nil, "DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload)
+}
+
+func digestJSON(v any) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("encode for digest: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,171 @@
+package attest
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/report"
+)
+
+var started = time.Date(2026, 2, 28, 11, 55, 0, 0, time.UTC)
+
+func sampleData() report.Data {
+	return report.Data{
+		Tool:      "ecrspectre",
+		Version:   "1.2.3",
+		Timestamp: time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC),
+		RunID:     "0b7e5c1a-4f3d-4a8e-9c2b-1d6f0e9a7b35",
+		Target:    report.Target{Type: "ecr", URIHash: "sha256:abc"},
+		Config:    report.ReportConfig{Provider: "aws", Regions: []string{"us-east-1"}, StaleDays: 90},
+	}
+}
+
+func TestBuildStatement(t *testing.T) {
+	st, err := Build(sampleData(), nil, started)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Type != StatementType || st.PredicateType != PredicateType {
+		t.Errorf("unexpected types: %s %s", st.Type, st.PredicateType)
+	}
+	if len(st.Subject) != 1 || len(st.Subject[0].Digest["sha256"]) != 64 {
+		t.Errorf("unexpected subject: %+v", st.Subject)
+	}
+	if st.Predicate.RunDetails.Builder.ID != "https://github.com/ppiankov/ecrspectre@1.2.3" {
+		t.Errorf("builder id = %s", st.Predicate.RunDetails.Builder.ID)
+	}
+	if st.Predicate.RunDetails.Metadata.InvocationID != sampleData().RunID {
+		t.Errorf("invocationId = %q, want the run ID", st.Predicate.RunDetails.Metadata.InvocationID)
+	}
+	digest, _ := st.Predicate.BuildDefinition.ExternalParameters["config_digest"].(string)
+	if !strings.HasPrefix(digest, "sha256:") {
+		t.Errorf("config_digest = %q", digest)
+	}
+
+	changed := sampleData()
+	changed.Config.StaleDays = 30
+	st2, err := Build(changed, nil, started)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st2.Predicate.BuildDefinition.ExternalParameters["config_digest"] == digest {
+		t.Error("config digest should change with config")
+	}
+}
+
+func TestBuildStatementWithReportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	if err := os.WriteFile(path, []byte("hello"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	st, err := Build(sampleData(), []string{path}, started)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Subject) != 2 {
+		t.Fatalf("expected 2 subjects, got %d", len(st.Subject))
+	}
+	want := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	if got := st.Subject[1].Digest["sha256"]; got != want {
+		t.Errorf("report digest = %s, want %s", got, want)
+	}
+
+	if _, err := Build(sampleData(), []string{filepath.Join(t.TempDir(), "missing")}, started); err == nil {
+		t.Error("expected error for missing report file")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, _ := Build(sampleData(), nil, started)
+	env, err := Sign(st, priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if env.PayloadType != PayloadType || env.Signatures[0].KeyID != KeyID(pub) {
+		t.Errorf("unexpected envelope: %+v", env)
+	}
+
+	got, err := Verify(env, pub)
+	if err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+	if got.Subject[0].Digest["sha256"] != st.Subject[0].Digest["sha256"] {
+		t.Error("verified statement differs from signed statement")
+	}
+
+	otherPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	if _, err := Verify(env, otherPub); err == nil {
+		t.Error("expected verification failure with another key")
+	}
+	env.Payload = env.Payload[:len(env.Payload)-4] + "AAAA"
+	if _, err := Verify(env, pub); err == nil {
+		t.Error("expected verification failure for tampered payload")
+	}
+}
+
+func TestLoadSigningKey(t *testing.T) {
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := LoadSigningKey(path)
+	if err != nil {
+		t.Fatalf("LoadSigningKey: %v", err)
+	}
+	if !key.Equal(priv) {
+		t.Error("loaded key differs")
+	}
+
+	bad := filepath.Join(dir, "bad.pem")
+	if err := os.WriteFile(bad, []byte("not pem"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSigningKey(bad); err == nil {
+		t.Error("expected error for non-PEM key")
+	}
+}
+
+func TestWriteFile(t *testing.T) {
+	dir := t.TempDir()
+	st, _ := Build(sampleData(), nil, started)
+
+	plain := filepath.Join(dir, "plain.json")
+	if err := WriteFile(plain, st, nil); err != nil {
+		t.Fatal(err)
+	}
+	var decoded Statement
+	data, _ := os.ReadFile(plain)
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Type != StatementType {
+		t.Errorf("unsigned attestation should be a bare statement: %v", err)
+	}
+
+	_, priv, _ := ed25519.GenerateKey(rand.Reader)
+	signed := filepath.Join(dir, "signed.json")
+	if err := WriteFile(signed, st, priv); err != nil {
+		t.Fatal(err)
+	}
+	var env Envelope
+	data, _ = os.ReadFile(signed)
+	if err := json.Unmarshal(data, &env); err != nil || env.PayloadType != PayloadType || len(env.Signatures) != 1 {
+		t.Errorf("signed attestation should be a DSSE envelope: %v", err)
+	}
+}
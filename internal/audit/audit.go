@@ -0,0 +1,325 @@
+// Package audit resolves an explicit list of image references, such as the
+// images of a release manifest, and reports the age, size, storage cost and
+// vulnerabilities of each.
+package audit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	awsecr "github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+
+	"github.com/ppiankov/ecrspectre/internal/artifactregistry"
+	"github.com/ppiankov/ecrspectre/internal/ecr"
+	"github.com/ppiankov/ecrspectre/internal/imageref"
+	"github.com/ppiankov/ecrspectre/internal/pricing"
+)
+
+// Status is the outcome of auditing one image.
+type Status string
+
+const (
+	// StatusInUse is an image pulled (or, without pull data, pushed) within
+	// the stale window.
+	StatusInUse Status = "in-use"
+	// StatusStale is an image unused for at least the stale window.
+	StatusStale Status = "stale"
+	// StatusNotFound is a reference the registry has no image for.
+	StatusNotFound Status = "not-found"
+	// StatusUnsupported is a reference to a registry ecrspectre cannot audit.
+	StatusUnsupported Status = "unsupported"
+	// StatusError is a reference whose registry lookup failed.
+	StatusError Status = "error"
+)
+
+// Image is the audit result of one reference.
+type Image struct {
+	Reference  string `json:"reference"`
+	Provider   string `json:"provider"`
+	Status     Status `json:"status"`
+	Repository string `json:"repository,omitempty"`
+	// Region is the ECR region or Artifact Registry location.
+	Region   string     `json:"region,omitempty"`
+	Digest   string     `json:"digest,omitempty"`
+	Tags     []string   `json:"tags,omitempty"`
+	Size     int64      `json:"size_bytes,omitempty"`
+	PushedAt *time.Time `json:"pushed_at,omitempty"`
+	// LastPull is the last recorded pull (ECR only).
+	LastPull *time.Time `json:"last_pull,omitempty"`
+	// DaysUnused counts the days since the last pull, or since the push
+	// when no pull is recorded.
+	DaysUnused  int     `json:"days_unused"`
+	MonthlyCost float64 `json:"monthly_cost"`
+	// Vulnerabilities counts findings by lowercase severity.
+	Vulnerabilities map[string]int `json:"vulnerabilities,omitempty"`
+	Error           string         `json:"error,omitempty"`
+}
+
+// Resolved reports whether the image was found in its registry.
+func (img Image) Resolved() bool {
+	return img.Status == StatusInUse || img.Status == StatusStale
+}
+
+// Sources opens registry APIs on demand. Either may be nil, leaving that
+// provider's references unresolved.
+type Sources struct {
+	// ECR returns the ECR API of a region.
+	ECR func(ctx context.Context, region string) (ecr.ECRAPI, error)
+	// AR returns the Artifact Registry API of a project.
+	AR func(ctx context.Context, project string) (artifactregistry.ARAPI, error)
+}
+
+// Options tune an audit.
+type Options struct {
+	// StaleDays is the unused age from which an image is stale.
+	StaleDays int
+	// Now anchors ages (time.Now when zero).
+	Now time.Time
+}
+
+// Auditor resolves references, caching API clients and Artifact Registry
+// image listings across them.
+type Auditor struct {
+	src     Sources
+	opts    Options
+	ecr     map[string]ecr.ECRAPI
+	ar      map[string]artifactregistry.ARAPI
+	arFiles map[string][]artifactregistry.DockerImage
+}
+
+// New creates an Auditor.
+func New(src Sources, opts Options) *Auditor {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	return &Auditor{
+		src:     src,
+		opts:    opts,
+		ecr:     make(map[string]ecr.ECRAPI),
+		ar:      make(map[string]artifactregistry.ARAPI),
+		arFiles: make(map[string][]artifactregistry.DockerImage),
+	}
+}
+
+// Audit resolves each reference in order. Failures are recorded on the
+// image rather than returned, so one bad reference does not hide the rest.
+func (a *Auditor) Audit(ctx context.Context, refs []string) []Image {
+	images := make([]Image, 0, len(refs))
+	for _, raw := range refs {
+		images = append(images, a.audit(ctx, raw))
+	}
+	return images
+}
+
+func (a *Auditor) audit(ctx context.Context, raw string) Image {
+	img := Image{Reference: raw}
+	ref, err := imageref.Parse(raw)
+	if err != nil {
+		img.Status, img.Error = StatusError, err.Error()
+		return img
+	}
+	img.Provider = string(ref.Provider)
+	switch ref.Provider {
+	case imageref.ProviderECR:
+		err = a.resolveECR(ctx, ref, &img)
+	case imageref.ProviderArtifactRegistry:
+		err = a.resolveAR(ctx, ref, &img)
+	default:
+		img.Status, img.Error = StatusUnsupported, fmt.Sprintf("%s references cannot be audited; only ECR and Artifact Registry are supported", ref.Provider)
+		return img
+	}
+	switch {
+	case errors.Is(err, errNotFound):
+		img.Status = StatusNotFound
+	case err != nil:
+		img.Status, img.Error = StatusError, err.Error()
+	default:
+		a.classify(&img)
+	}
+	return img
+}
+
+var errNotFound = errors.New("image not found")
+
+// classify sets the unused age and status of a resolved image.
+func (a *Auditor) classify(img *Image) {
+	last := img.LastPull
+	if last == nil {
+		last = img.PushedAt
+	}
+	if last != nil {
+		img.DaysUnused = int(a.opts.Now.Sub(*last).Hours() / 24)
+	}
+	img.Status = StatusInUse
+	if last != nil && a.opts.StaleDays > 0 && img.DaysUnused >= a.opts.StaleDays {
+		img.Status = StatusStale
+	}
+}
+
+func (a *Auditor) resolveECR(ctx context.Context, ref imageref.Reference, img *Image) error {
+	img.Repository, img.Region = ref.Repository, ref.Region
+	if a.src.ECR == nil {
+		return fmt.Errorf("no ECR credentials")
+	}
+	api, ok := a.ecr[ref.Region]
+	if !ok {
+		var err error
+		if api, err = a.src.ECR(ctx, ref.Region); err != nil {
+			return err
+		}
+		a.ecr[ref.Region] = api
+	}
+
+	id := ecrtypes.ImageIdentifier{}
+	if ref.Digest != "" {
+		id.ImageDigest = &ref.Digest
+	} else {
+		id.ImageTag = &ref.Tag
+	}
+	out, err := api.DescribeImages(ctx, &awsecr.DescribeImagesInput{
+		RepositoryName: &ref.Repository,
+		RegistryId:     &ref.Account,
+		ImageIds:       []ecrtypes.ImageIdentifier{id},
+	})
+	var notFound *ecrtypes.ImageNotFoundException
+	if errors.As(err, &notFound) || (err == nil && len(out.ImageDetails) == 0) {
+		return errNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("describe image: %w", err)
+	}
+
+	d := out.ImageDetails[0]
+	if d.ImageDigest != nil {
+		img.Digest = *d.ImageDigest
+	}
+	img.Tags = d.ImageTags
+	if d.ImageSizeInBytes != nil {
+		img.Size = *d.ImageSizeInBytes
+	}
+	img.PushedAt, img.LastPull = d.ImagePushedAt, d.LastRecordedPullTime
+	img.MonthlyCost = pricing.MonthlyStorageCost("ecr", ref.Region, img.Size)
+	if s := d.ImageScanFindingsSummary; s != nil && len(s.FindingSeverityCounts) > 0 {
+		img.Vulnerabilities = make(map[string]int)
+		for sev, n := range s.FindingSeverityCounts {
+			img.Vulnerabilities[strings.ToLower(sev)] += int(n)
+		}
+	}
+	return nil
+}
+
+func (a *Auditor) resolveAR(ctx context.Context, ref imageref.Reference, img *Image) error {
+	img.Repository, img.Region = ref.Repository, ref.Location
+	if a.src.AR == nil {
+		return fmt.Errorf("no GCP credentials")
+	}
+	api, ok := a.ar[ref.Project]
+	if !ok {
+		var err error
+		if api, err = a.src.AR(ctx, ref.Project); err != nil {
+			return err
+		}
+		a.ar[ref.Project] = api
+	}
+
+	// Artifact Registry cannot look an image up by tag, so the repository
+	// is listed once and every reference into it is matched locally.
+	parent := fmt.Sprintf("projects/%s/locations/%s/repositories/%s", ref.Project, ref.Location, ref.ARRepository)
+	images, ok := a.arFiles[parent]
+	if !ok {
+		var err error
+		if images, err = api.ListDockerImages(ctx, parent); err != nil {
+			return err
+		}
+		a.arFiles[parent] = images
+	}
+	name := ref.Host + "/" + ref.Repository
+	var found *artifactregistry.DockerImage
+	for i, d := range images {
+		uriName, digest, _ := strings.Cut(d.URI, "@")
+		if uriName != name {
+			continue
+		}
+		if (ref.Digest != "" && digest == ref.Digest) || (ref.Digest == "" && containsTag(d.Tags, ref.Tag)) {
+			found = &images[i]
+			break
+		}
+	}
+	if found == nil {
+		return errNotFound
+	}
+
+	_, img.Digest, _ = strings.Cut(found.URI, "@")
+	img.Tags, img.Size = found.Tags, found.SizeBytes
+	if !found.UploadTime.IsZero() {
+		t := found.UploadTime
+		img.PushedAt = &t
+	}
+	img.MonthlyCost = pricing.MonthlyStorageCost("artifactregistry", ref.Location, img.Size)
+	counts, err := api.VulnerabilityCounts(ctx, found.URI)
+	if err != nil {
+		return err
+	}
+	if len(counts) > 0 {
+		img.Vulnerabilities = make(map[string]int)
+		for sev, n := range counts {
+			img.Vulnerabilities[strings.ToLower(sev)] += n
+		}
+	}
+	return nil
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary totals an audit.
+type Summary struct {
+	Images      int     `json:"images"`
+	Resolved    int     `json:"resolved"`
+	Stale       int     `json:"stale"`
+	Unresolved  int     `json:"unresolved"`
+	TotalBytes  int64   `json:"total_bytes"`
+	MonthlyCost float64 `json:"monthly_cost"`
+	// StaleMonthlyCost is the storage cost of the stale images.
+	StaleMonthlyCost float64 `json:"stale_monthly_cost"`
+	Vulnerable       int     `json:"vulnerable"`
+}
+
+// Summarize totals images. An image listed twice under different
+// references counts once.
+func Summarize(images []Image) Summary {
+	s := Summary{Images: len(images)}
+	seen := make(map[string]bool)
+	for _, img := range images {
+		if !img.Resolved() {
+			s.Unresolved++
+			continue
+		}
+		s.Resolved++
+		key := img.Provider + "/" + img.Region + "/" + img.Repository + "@" + img.Digest
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		s.TotalBytes += img.Size
+		s.MonthlyCost += img.MonthlyCost
+		if img.Status == StatusStale {
+			s.Stale++
+			s.StaleMonthlyCost += img.MonthlyCost
+		}
+		if img.Vulnerabilities["critical"]+img.Vulnerabilities["high"] > 0 {
+			s.Vulnerable++
+		}
+	}
+	return s
+}
@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsecr "github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+
+	"github.com/ppiankov/ecrspectre/internal/artifactregistry"
+	"github.com/ppiankov/ecrspectre/internal/ecr"
+)
+
+var now = time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+
+var arDigest = "sha256:" + strings.Repeat("c", 64)
+
+type fakeECR struct {
+	ecr.ECRAPI
+	images map[string]ecrtypes.ImageDetail // by repository:tag
+}
+
+func (f *fakeECR) DescribeImages(_ context.Context, in *awsecr.DescribeImagesInput, _ ...func(*awsecr.Options)) (*awsecr.DescribeImagesOutput, error) {
+	d, ok := f.images[*in.RepositoryName+":"+aws.ToString(in.ImageIds[0].ImageTag)]
+	if !ok {
+		return nil, &ecrtypes.ImageNotFoundException{Message: aws.String("not found")}
+	}
+	return &awsecr.DescribeImagesOutput{ImageDetails: []ecrtypes.ImageDetail{d}}, nil
+}
+
+type fakeAR struct {
+	artifactregistry.ARAPI
+	images []artifactregistry.DockerImage
+	lists  int
+}
+
+func (f *fakeAR) ListDockerImages(context.Context, string) ([]artifactregistry.DockerImage, error) {
+	f.lists++
+	return f.images, nil
+}
+
+func (f *fakeAR) VulnerabilityCounts(context.Context, string) (map[string]int, error) {
+	return map[string]int{"CRITICAL": 1, "HIGH": 2}, nil
+}
+
+func TestAudit(t *testing.T) {
+	const gib = 1 << 30
+	ecrAPI := &fakeECR{images: map[string]ecrtypes.ImageDetail{
+		"api:v1": {
+			ImageDigest:          aws.String("sha256:aaa"),
+			ImageTags:            []string{"v1"},
+			ImageSizeInBytes:     aws.Int64(10 * gib),
+			ImagePushedAt:        aws.Time(now.AddDate(0, 0, -400)),
+			LastRecordedPullTime: aws.Time(now.AddDate(0, 0, -3)),
+		},
+		"old:v1": {
+			ImageDigest:              aws.String("sha256:bbb"),
+			ImageSizeInBytes:         aws.Int64(gib),
+			ImagePushedAt:            aws.Time(now.AddDate(0, 0, -200)),
+			ImageScanFindingsSummary: &ecrtypes.ImageScanFindingsSummary{FindingSeverityCounts: map[string]int32{"CRITICAL": 2}},
+		},
+	}}
+	arAPI := &fakeAR{images: []artifactregistry.DockerImage{{
+		URI:        "europe-west1-docker.pkg.dev/proj/images/app@" + arDigest,
+		Tags:       []string{"v3"},
+		SizeBytes:  2 * gib,
+		UploadTime: now.AddDate(0, 0, -10),
+	}}}
+	var regions []string
+	a := New(Sources{
+		ECR: func(_ context.Context, region string) (ecr.ECRAPI, error) {
+			regions = append(regions, region)
+			return ecrAPI, nil
+		},
+		AR: func(context.Context, string) (artifactregistry.ARAPI, error) { return arAPI, nil },
+	}, Options{StaleDays: 90, Now: now})
+
+	images := a.Audit(context.Background(), []string{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1",
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/old:v1",
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/gone:v1",
+		"europe-west1-docker.pkg.dev/proj/images/app:v3",
+		"europe-west1-docker.pkg.dev/proj/images/app@" + arDigest,
+		"docker.io/library/nginx:1.27",
+	})
+	want := []Status{StatusInUse, StatusStale, StatusNotFound, StatusInUse, StatusInUse, StatusUnsupported}
+	for i, img := range images {
+		if img.Status != want[i] {
+			t.Errorf("%s: status = %s (%s), want %s", img.Reference, img.Status, img.Error, want[i])
+		}
+	}
+	if len(regions) != 1 || arAPI.lists != 1 {
+		t.Errorf("clients opened for %v, AR listed %d times", regions, arAPI.lists)
+	}
+	if images[0].DaysUnused != 3 || images[1].DaysUnused != 200 {
+		t.Errorf("days unused = %d, %d", images[0].DaysUnused, images[1].DaysUnused)
+	}
+	if images[1].Vulnerabilities["critical"] != 2 || images[3].Vulnerabilities["high"] != 2 {
+		t.Errorf("vulnerabilities = %v, %v", images[1].Vulnerabilities, images[3].Vulnerabilities)
+	}
+	if images[3].Digest != arDigest || images[0].MonthlyCost <= 0 {
+		t.Errorf("AR image = %+v, ECR cost = %.2f", images[3], images[0].MonthlyCost)
+	}
+
+	s := Summarize(images)
+	// The AR image is listed by tag and by digest but counted once.
+	if s.Images != 6 || s.Resolved != 4 || s.Unresolved != 2 || s.Stale != 1 || s.Vulnerable != 2 || s.TotalBytes != 13*gib {
+		t.Errorf("summary = %+v", s)
+	}
+	if s.StaleMonthlyCost != images[1].MonthlyCost {
+		t.Errorf("stale cost = %.2f, want %.2f", s.StaleMonthlyCost, images[1].MonthlyCost)
+	}
+}
+
+func TestAuditSourceError(t *testing.T) {
+	a := New(Sources{
+		ECR: func(context.Context, string) (ecr.ECRAPI, error) { return nil, fmt.Errorf("no credentials") },
+	}, Options{StaleDays: 90, Now: now})
+	images := a.Audit(context.Background(), []string{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/api:v1",
+		"europe-west1-docker.pkg.dev/proj/images/app:v3",
+		"not a reference",
+	})
+	for _, img := range images {
+		if img.Status != StatusError || img.Error == "" {
+			t.Errorf("%s: status = %s (%q)", img.Reference, img.Status, img.Error)
+		}
+	}
+}
+
+func TestReadRefs(t *testing.T) {
+	refs, err := ReadRefs(strings.NewReader("# release 42\nrepo/a:v1\n\n  repo/b:v2  \nrepo/a:v1\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(refs, ",") != "repo/a:v1,repo/b:v2" {
+		t.Errorf("refs = %q", refs)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	images := []Image{
+		{Reference: "r/api:v1", Provider: "ecr", Status: StatusStale, Repository: "api", Digest: "sha256:a", Size: 1 << 30, DaysUnused: 120, MonthlyCost: 0.1, Vulnerabilities: map[string]int{"critical": 1}},
+		{Reference: "r/gone:v1", Provider: "ecr", Status: StatusNotFound},
+	}
+	var buf bytes.Buffer
+	if err := WriteText(&buf, images, 90); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"r/api:v1", "120d", "$0.10", "1/0", "not-found", "1 stale (unused for 90+ days)", "1 unresolved"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("text missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, images, 90); err != nil {
+		t.Fatal(err)
+	}
+	var got Report
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.StaleDays != 90 || len(got.Images) != 2 || got.Summary.Stale != 1 {
+		t.Errorf("json = %+v", got)
+	}
+}
@@ -0,0 +1,73 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// ReadRefs reads newline-delimited image references. Blank lines and lines
+// starting with # are skipped, and repeated references are kept once.
+func ReadRefs(r io.Reader) ([]string, error) {
+	var refs []string
+	seen := make(map[string]bool)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") || seen[line] {
+			continue
+		}
+		seen[line] = true
+		refs = append(refs, line)
+	}
+	return refs, sc.Err()
+}
+
+// Report is the JSON form of an audit.
+type Report struct {
+	StaleDays int     `json:"stale_days"`
+	Images    []Image `json:"images"`
+	Summary   Summary `json:"summary"`
+}
+
+// WriteText renders one row per image and the totals.
+func WriteText(w io.Writer, images []Image, staleDays int) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "IMAGE\tSTATUS\tSIZE\tUNUSED\tCOST/MO\tCRIT/HIGH")
+	for _, img := range images {
+		if !img.Resolved() {
+			detail := string(img.Status)
+			if img.Error != "" {
+				detail += ": " + img.Error
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t-\t-\t-\t-\n", img.Reference, detail)
+			continue
+		}
+		vulns := "-"
+		if img.Vulnerabilities != nil {
+			vulns = fmt.Sprintf("%d/%d", img.Vulnerabilities["critical"], img.Vulnerabilities["high"])
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%dd\t$%.2f\t%s\n",
+			img.Reference, img.Status, gb(img.Size), img.DaysUnused, img.MonthlyCost, vulns)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	s := Summarize(images)
+	_, err := fmt.Fprintf(w, "\n%d images, %d resolved: %s costing $%.2f/mo.\n"+
+		"%d stale (unused for %d+ days) costing $%.2f/mo; %d with critical or high vulnerabilities; %d unresolved.\n",
+		s.Images, s.Resolved, gb(s.TotalBytes), s.MonthlyCost, s.Stale, staleDays, s.StaleMonthlyCost, s.Vulnerable, s.Unresolved)
+	return err
+}
+
+// WriteJSON renders the audit as indented JSON.
+func WriteJSON(w io.Writer, images []Image, staleDays int) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(Report{StaleDays: staleDays, Images: images, Summary: Summarize(images)})
+}
+
+func gb(b int64) string { return fmt.Sprintf("%.2f GB", float64(b)/(1<<30)) }
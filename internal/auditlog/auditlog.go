@@ -0,0 +1,98 @@
+// Package auditlog derives the last pull of Artifact Registry images from
+// Cloud Audit Logs, which record Docker manifest fetches when Data Access
+// logging is enabled for the Artifact Registry API.
+package auditlog
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+const (
+	loggingURL = "https://logging.googleapis.com/v2"
+	pageSize   = 1000
+)
+
+// pullFilter selects Docker manifest fetches, which every image pull makes.
+const pullFilter = `protoPayload.serviceName="artifactregistry.googleapis.com" AND protoPayload.methodName="Docker-GetManifest"`
+
+type entriesRequest struct {
+	ResourceNames []string `json:"resourceNames"`
+	Filter        string   `json:"filter"`
+	OrderBy       string   `json:"orderBy"`
+	PageSize      int      `json:"pageSize"`
+	PageToken     string   `json:"pageToken,omitempty"`
+}
+
+type entriesResponse struct {
+	Entries []struct {
+		Timestamp    time.Time `json:"timestamp"`
+		ProtoPayload struct {
+			ResourceName string `json:"resourceName"`
+		} `json:"protoPayload"`
+	} `json:"entries"`
+	NextPageToken string `json:"nextPageToken"`
+}
+
+// CollectPulls records every image pull logged in project since pulls.Since.
+// It returns the number of pull entries read.
+func CollectPulls(ctx context.Context, api *gcpapi.Caller, project string, pulls *registry.PullLog) (int, error) {
+	logName := fmt.Sprintf("projects/%s/logs/%s", project, url.PathEscape("cloudaudit.googleapis.com/data_access"))
+	req := entriesRequest{
+		ResourceNames: []string{"projects/" + project},
+		Filter: fmt.Sprintf(`logName=%q AND %s AND timestamp>=%q`,
+			logName, pullFilter, pulls.Since.UTC().Format(time.RFC3339)),
+		OrderBy:  "timestamp desc",
+		PageSize: pageSize,
+	}
+	read := 0
+	for {
+		var resp entriesResponse
+		if err := api.Post(ctx, loggingURL+"/entries:list", req, &resp); err != nil {
+			return read, fmt.Errorf("read audit logs of %s: %w", project, err)
+		}
+		for _, e := range resp.Entries {
+			if ref := imageRef(e.ProtoPayload.ResourceName); ref != "" {
+				pulls.Record(ref, e.Timestamp)
+			}
+		}
+		read += len(resp.Entries)
+		if resp.NextPageToken == "" {
+			return read, nil
+		}
+		req.PageToken = resp.NextPageToken
+	}
+}
+
+// imageRef converts an audit log resource name such as
+// projects/p/locations/us/repositories/r/dockerImages/team%2Fapi@sha256:abc
+// (or .../packages/team%2Fapi/versions/sha256:abc and .../tags/v1) into the
+// image reference p/r/team/api@sha256:abc used to look up scanned images.
+func imageRef(resourceName string) string {
+	parts := strings.Split(resourceName, "/")
+	if len(parts) < 8 || parts[0] != "projects" || parts[2] != "locations" || parts[4] != "repositories" {
+		return ""
+	}
+	base := parts[1] + "/" + parts[5] + "/"
+	unescape := func(s string) string {
+		if u, err := url.PathUnescape(s); err == nil {
+			return u
+		}
+		return s
+	}
+	switch {
+	case parts[6] == "dockerImages":
+		return base + unescape(strings.Join(parts[7:], "/"))
+	case parts[6] == "packages" && len(parts) == 10 && parts[8] == "versions":
+		return base + unescape(parts[7]) + "@" + parts[9]
+	case parts[6] == "packages" && len(parts) == 10 && parts[8] == "tags":
+		return base + unescape(parts[7]) + ":" + parts[9]
+	}
+	return ""
+}
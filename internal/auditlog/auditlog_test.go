@@ -0,0 +1,74 @@
+package auditlog
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+func TestCollectPulls(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v2/entries:list" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		var req entriesRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.ResourceNames[0] != "projects/proj" || !strings.Contains(req.Filter, `"Docker-GetManifest"`) ||
+			!strings.Contains(req.Filter, `timestamp>="2026-01-01T00:00:00Z"`) {
+			t.Errorf("request = %+v", req)
+		}
+		if req.PageToken == "" {
+			_, _ = w.Write([]byte(`{"entries":[
+				{"timestamp":"2026-02-01T00:00:00Z","protoPayload":{"resourceName":"projects/proj/locations/us/repositories/repo/dockerImages/team%2Fapi@sha256:abc"}},
+				{"timestamp":"2026-01-20T00:00:00Z","protoPayload":{"resourceName":"projects/proj/locations/us/repositories/repo/packages/team%2Fapi/tags/v1"}}
+			],"nextPageToken":"p2"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"entries":[
+			{"timestamp":"2026-01-10T00:00:00Z","protoPayload":{"resourceName":"projects/proj/locations/us/repositories/repo/dockerImages/team%2Fapi@sha256:abc"}},
+			{"timestamp":"2026-01-10T00:00:00Z","protoPayload":{"resourceName":"projects/proj/unrelated"}}
+		]}`))
+	}))
+	defer srv.Close()
+	api := gcpapi.NewCallerFromTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "tok"})).WithEndpoint(srv.URL)
+
+	pulls := registry.NewPullLog(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	n, err := CollectPulls(context.Background(), api, "proj", pulls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 {
+		t.Errorf("read %d entries, want 4", n)
+	}
+	if got := pulls.LastPull("proj/repo/team/api", "sha256:abc", nil); !got.Equal(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("digest last pull = %v", got)
+	}
+	if got := pulls.LastPull("proj/repo/team/api", "sha256:other", []string{"v1"}); !got.Equal(time.Date(2026, 1, 20, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("tag last pull = %v", got)
+	}
+}
+
+func TestImageRef(t *testing.T) {
+	tests := map[string]string{
+		"projects/p/locations/us/repositories/r/dockerImages/api@sha256:abc":      "p/r/api@sha256:abc",
+		"projects/p/locations/us/repositories/r/packages/a%2Fb/versions/sha256:d": "p/r/a/b@sha256:d",
+		"projects/p/locations/us/repositories/r/packages/api/tags/v1":             "p/r/api:v1",
+		"projects/p/locations/us/repositories/r":                                  "",
+	}
+	for in, want := range tests {
+		if got := imageRef(in); got != want {
+			t.Errorf("imageRef(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
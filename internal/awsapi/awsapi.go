@@ -0,0 +1,230 @@
+// Package awsapi sends SigV4-signed requests to AWS services that have no
+// generated SDK client in this module.
+package awsapi
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/ppiankov/ecrspectre/internal/apistats"
+)
+
+// Caller signs and sends requests using credentials from an AWS config.
+type Caller struct {
+	cfg        aws.Config
+	httpClient *http.Client
+	endpoint   string // overrides the regional endpoint; used by tests
+}
+
+// NewCaller creates a Caller from a loaded AWS config.
+func NewCaller(cfg aws.Config) *Caller {
+	return &Caller{cfg: cfg, httpClient: http.DefaultClient}
+}
+
+// WithEndpoint returns a copy of the caller that sends every request to endpoint.
+func (c *Caller) WithEndpoint(endpoint string) *Caller {
+	cp := *c
+	cp.endpoint = strings.TrimRight(endpoint, "/")
+	return &cp
+}
+
+// Region returns the region requests are signed for.
+func (c *Caller) Region() string {
+	return c.cfg.Region
+}
+
+// Query calls an AWS Query-protocol action (form-encoded POST, XML response)
+// and decodes the response body into out.
+func (c *Caller) Query(ctx context.Context, service string, params url.Values, out any) error {
+	body := params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serviceURL(service), strings.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build %s request: %w", service, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+	data, err := c.send(ctx, service, req, []byte(body))
+	if err != nil {
+		return err
+	}
+	if err := xml.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", service, params.Get("Action"), err)
+	}
+	return nil
+}
+
+// JSON calls an AWS JSON 1.1 protocol action (POST with X-Amz-Target) and
+// decodes the response body into out.
+func (c *Caller) JSON(ctx context.Context, service, target string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("encode %s request: %w", target, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.serviceURL(service), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("build %s request: %w", service, err)
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+
+	data, err := c.send(ctx, service, req, body)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode %s response: %w", target, err)
+	}
+	return nil
+}
+
+// Get calls a REST-JSON API path (e.g. /2015-03-31/functions) and decodes the
+// response body into out.
+func (c *Caller) Get(ctx context.Context, service, path string, query url.Values, out any) error {
+	u := c.serviceURL(service) + strings.TrimPrefix(path, "/")
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("build %s request: %w", service, err)
+	}
+
+	data, err := c.send(ctx, service, req, nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decode %s %s response: %w", service, path, err)
+	}
+	return nil
+}
+
+func (c *Caller) serviceURL(service string) string {
+	if c.endpoint != "" {
+		return c.endpoint + "/"
+	}
+	return fmt.Sprintf("https://%s.%s.amazonaws.com/", service, c.cfg.Region)
+}
+
+// PutObject uploads size bytes of body to an S3 object with a single
+// path-style PUT. The body is read twice: once to hash it for the signature
+// and once to send it.
+func (c *Caller) PutObject(ctx context.Context, bucket, key string, body io.ReadSeeker, size int64) error {
+	h := sha256.New()
+	if _, err := io.Copy(h, body); err != nil {
+		return fmt.Errorf("hash s3 object: %w", err)
+	}
+	if _, err := body.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind s3 object: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.objectURL(bucket, key), io.NopCloser(body))
+	if err != nil {
+		return fmt.Errorf("build s3 request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	resp, err := c.do(ctx, "s3", req, hex.EncodeToString(h.Sum(nil)))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+// GetObject returns the content of an S3 object. The caller closes it.
+func (c *Caller) GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.objectURL(bucket, key), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build s3 request: %w", err)
+	}
+	sum := sha256.Sum256(nil)
+	resp, err := c.do(ctx, "s3", req, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *Caller) objectURL(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return c.serviceURL("s3") + url.PathEscape(bucket) + "/" + strings.Join(segments, "/")
+}
+
+func (c *Caller) send(ctx context.Context, service string, req *http.Request, body []byte) ([]byte, error) {
+	sum := sha256.Sum256(body)
+	resp, err := c.do(ctx, service, req, hex.EncodeToString(sum[:]))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read %s response: %w", service, err)
+	}
+	return data, nil
+}
+
+// do signs and sends req, returning the response of a 2xx status with its
+// body still open.
+func (c *Caller) do(ctx context.Context, service string, req *http.Request, payloadHash string) (*http.Response, error) {
+	if c.cfg.Credentials == nil {
+		return nil, fmt.Errorf("%s: no AWS credentials configured", service)
+	}
+	creds, err := c.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("retrieve AWS credentials: %w", err)
+	}
+
+	signer := v4.NewSigner()
+	if service == "s3" {
+		// S3 signs the object path as sent and requires the payload hash header.
+		signer = v4.NewSigner(func(o *v4.SignerOptions) { o.DisableURIPathEscaping = true })
+		req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	}
+	if err := signer.SignHTTP(ctx, creds, req, payloadHash, service, c.cfg.Region, time.Now()); err != nil {
+		return nil, fmt.Errorf("sign %s request: %w", service, err)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		apistats.Record(false)
+		return nil, fmt.Errorf("%s request: %w", service, err)
+	}
+	if resp.StatusCode >= 300 {
+		defer func() { _ = resp.Body.Close() }()
+		data, _ := io.ReadAll(resp.Body)
+		body := strings.TrimSpace(string(data))
+		apistats.Record(apistats.IsThrottleStatus(resp.StatusCode) || strings.Contains(body, "Throttl"))
+		return nil, &APIError{Service: service, StatusCode: resp.StatusCode, Body: body}
+	}
+	apistats.Record(false)
+	return resp, nil
+}
+
+// APIError is returned when an AWS service responds with a non-2xx status.
+type APIError struct {
+	Service    string
+	StatusCode int
+	Body       string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: HTTP %d: %s", e.Service, e.StatusCode, e.Body)
+}
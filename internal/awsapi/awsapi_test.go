@@ -0,0 +1,211 @@
+package awsapi
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+func testConfig() aws.Config {
+	return aws.Config{
+		Region: "us-east-1",
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		}),
+	}
+}
+
+func TestQuerySignsAndDecodes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256") {
+			t.Errorf("missing SigV4 Authorization header")
+		}
+		_, _ = w.Write([]byte(`<Response><Result><Value>42</Value></Result></Response>`))
+	}))
+	defer srv.Close()
+
+	var out struct {
+		Value int `xml:"Result>Value"`
+	}
+	c := NewCaller(testConfig()).WithEndpoint(srv.URL)
+	if err := c.Query(context.Background(), "monitoring", url.Values{"Action": {"Test"}}, &out); err != nil {
+		t.Fatalf("Query() error: %v", err)
+	}
+	if out.Value != 42 {
+		t.Errorf("Value = %d, want 42", out.Value)
+	}
+}
+
+func TestQueryAPIError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`<ErrorResponse><Error><Code>AccessDenied</Code></Error></ErrorResponse>`))
+	}))
+	defer srv.Close()
+
+	var out struct{}
+	c := NewCaller(testConfig()).WithEndpoint(srv.URL)
+	err := c.Query(context.Background(), "monitoring", url.Values{}, &out)
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.StatusCode != http.StatusForbidden {
+		t.Errorf("StatusCode = %d, want 403", apiErr.StatusCode)
+	}
+	if !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("error should include response body, got %q", err)
+	}
+}
+
+func TestQueryNoCredentials(t *testing.T) {
+	c := NewCaller(aws.Config{Region: "us-east-1"})
+	var out struct{}
+	if err := c.Query(context.Background(), "monitoring", url.Values{}, &out); err == nil {
+		t.Error("expected error without credentials")
+	}
+}
+
+func TestJSONSendsTarget(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Amz-Target"); got != "Service.Action" {
+			t.Errorf("X-Amz-Target = %q", got)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != `{"cluster":"prod"}` {
+			t.Errorf("body = %s", body)
+		}
+		_, _ = w.Write([]byte(`{"value":7}`))
+	}))
+	defer srv.Close()
+
+	var out struct {
+		Value int `json:"value"`
+	}
+	c := NewCaller(testConfig()).WithEndpoint(srv.URL)
+	if err := c.JSON(context.Background(), "ecs", "Service.Action", map[string]string{"cluster": "prod"}, &out); err != nil {
+		t.Fatalf("JSON() error: %v", err)
+	}
+	if out.Value != 7 {
+		t.Errorf("Value = %d, want 7", out.Value)
+	}
+}
+
+func TestGetPathAndQuery(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet || r.URL.Path != "/2015-03-31/functions" || r.URL.Query().Get("Marker") != "m1" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		_, _ = w.Write([]byte(`{"NextMarker":"m2"}`))
+	}))
+	defer srv.Close()
+
+	var out struct{ NextMarker string }
+	c := NewCaller(testConfig()).WithEndpoint(srv.URL)
+	if err := c.Get(context.Background(), "lambda", "/2015-03-31/functions", url.Values{"Marker": {"m1"}}, &out); err != nil {
+		t.Fatalf("Get() error: %v", err)
+	}
+	if out.NextMarker != "m2" {
+		t.Errorf("NextMarker = %q", out.NextMarker)
+	}
+}
+
+func TestPutAndGetObject(t *testing.T) {
+	objects := map[string][]byte{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Content-Sha256") == "" {
+			t.Errorf("missing X-Amz-Content-Sha256 header")
+		}
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = body
+		case http.MethodGet:
+			body, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(body)
+		}
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	c := NewCaller(testConfig()).WithEndpoint(srv.URL)
+	if err := c.PutObject(ctx, "archive", "images/app.tar", strings.NewReader("blob"), 4); err != nil {
+		t.Fatalf("PutObject() error: %v", err)
+	}
+	if _, ok := objects["/archive/images/app.tar"]; !ok {
+		t.Fatalf("object stored at unexpected path: %v", objects)
+	}
+	rc, err := c.GetObject(ctx, "archive", "images/app.tar")
+	if err != nil {
+		t.Fatalf("GetObject() error: %v", err)
+	}
+	defer func() { _ = rc.Close() }()
+	if body, _ := io.ReadAll(rc); string(body) != "blob" {
+		t.Errorf("body = %q, want blob", body)
+	}
+
+	_, err = c.GetObject(ctx, "archive", "missing.tar")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 APIError, got %v", err)
+	}
+}
+
+func TestAccountID(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = r.ParseForm()
+		if r.PostForm.Get("Action") != "GetCallerIdentity" {
+			t.Errorf("Action = %q", r.PostForm.Get("Action"))
+		}
+		_, _ = w.Write([]byte(`<GetCallerIdentityResponse><GetCallerIdentityResult><Account>123456789012</Account></GetCallerIdentityResult></GetCallerIdentityResponse>`))
+	}))
+	defer srv.Close()
+
+	id, err := NewCaller(testConfig()).WithEndpoint(srv.URL).AccountID(context.Background())
+	if err != nil || id != "123456789012" {
+		t.Errorf("AccountID() = %q, %v", id, err)
+	}
+}
+
+func TestAccountTagsFollowsPages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("X-Amz-Target"); got != "AWSOrganizationsV20161128.ListTagsForResource" {
+			t.Errorf("X-Amz-Target = %q", got)
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "/us-east-1/organizations/") {
+			t.Errorf("request not signed for us-east-1: %s", r.Header.Get("Authorization"))
+		}
+		body, _ := io.ReadAll(r.Body)
+		if !strings.Contains(string(body), `"ResourceId":"123456789012"`) {
+			t.Errorf("body = %s", body)
+		}
+		if !strings.Contains(string(body), "NextToken") {
+			_, _ = w.Write([]byte(`{"Tags":[{"Key":"env","Value":"prod"}],"NextToken":"p2"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"Tags":[{"Key":"team","Value":"payments"}]}`))
+	}))
+	defer srv.Close()
+
+	cfg := testConfig()
+	cfg.Region = "eu-west-1"
+	tags, err := NewCaller(cfg).WithEndpoint(srv.URL).AccountTags(context.Background(), "123456789012")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tags) != 2 || tags["env"] != "prod" || tags["team"] != "payments" {
+		t.Errorf("tags = %v", tags)
+	}
+}
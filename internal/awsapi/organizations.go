@@ -0,0 +1,51 @@
+package awsapi
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+// organizationsRegion is the region serving the AWS Organizations API.
+const organizationsRegion = "us-east-1"
+
+// AccountID returns the account of the caller's credentials (STS
+// GetCallerIdentity).
+func (c *Caller) AccountID(ctx context.Context) (string, error) {
+	var out struct {
+		Account string `xml:"GetCallerIdentityResult>Account"`
+	}
+	params := url.Values{"Action": {"GetCallerIdentity"}, "Version": {"2011-06-15"}}
+	if err := c.Query(ctx, "sts", params, &out); err != nil {
+		return "", fmt.Errorf("get caller identity: %w", err)
+	}
+	return out.Account, nil
+}
+
+// AccountTags returns the tags of an account in AWS Organizations. Only the
+// management account or a delegated administrator can read them.
+func (c *Caller) AccountTags(ctx context.Context, accountID string) (map[string]string, error) {
+	org := *c
+	org.cfg.Region = organizationsRegion
+	tags := make(map[string]string)
+	in := map[string]string{"ResourceId": accountID}
+	for {
+		var out struct {
+			Tags []struct {
+				Key   string `json:"Key"`
+				Value string `json:"Value"`
+			} `json:"Tags"`
+			NextToken string `json:"NextToken"`
+		}
+		if err := org.JSON(ctx, "organizations", "AWSOrganizationsV20161128.ListTagsForResource", in, &out); err != nil {
+			return nil, fmt.Errorf("list tags of account %s: %w", accountID, err)
+		}
+		for _, t := range out.Tags {
+			tags[t.Key] = t.Value
+		}
+		if out.NextToken == "" {
+			return tags, nil
+		}
+		in["NextToken"] = out.NextToken
+	}
+}
@@ -0,0 +1,293 @@
+// Package clean deletes the ECR images named by the findings of a report,
+// after checking each against the registry's current state.
+package clean
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/archive"
+	"github.com/ppiankov/ecrspectre/internal/pricing"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// DefaultFindings are the findings whose images are deleted by default.
+var DefaultFindings = []registry.FindingID{registry.FindingUntaggedImage}
+
+// DefaultMaxPerRepo is the default limit of deletions per repository.
+const DefaultMaxPerRepo = 100
+
+// Options selects the images to delete.
+type Options struct {
+	// OlderThan keeps images pulled, or pushed when never pulled, more
+	// recently than this. Zero keeps none.
+	OlderThan time.Duration
+	// MaxPerRepo caps the deletions in one repository, oldest images
+	// first. Zero means no limit.
+	MaxPerRepo int
+	// ScannedAt is when the report was written. Images pulled since are
+	// kept, whatever OlderThan says.
+	ScannedAt time.Time
+	Now       time.Time
+	// DryRun plans the deletions without making them.
+	DryRun bool
+}
+
+// Image is an image of the registry as it is now.
+type Image struct {
+	Tags      []string
+	SizeBytes int64
+	PushedAt  time.Time
+	LastPull  time.Time
+	// Referrer marks a signature, SBOM or other artifact with a subject,
+	// which is untagged by design.
+	Referrer bool
+	// Index is the digest of a multi-arch index that references the image,
+	// which the registry refuses to delete first.
+	Index string
+}
+
+// lastUsed returns when the image was last pulled, or pushed if never
+// pulled.
+func (i Image) lastUsed() time.Time {
+	if i.LastPull.After(i.PushedAt) {
+		return i.LastPull
+	}
+	return i.PushedAt
+}
+
+// Registry is the ECR registry of one region.
+type Registry interface {
+	// Images returns the images of a repository by digest.
+	Images(ctx context.Context, repo string) (map[string]Image, error)
+	// Delete deletes images by digest and returns why each image that
+	// could not be deleted failed, keyed by digest.
+	Delete(ctx context.Context, repo string, digests []string) (map[string]string, error)
+}
+
+// Result is the outcome for one image.
+type Result struct {
+	Region     string             `json:"region"`
+	Repository string             `json:"repository"`
+	Digest     string             `json:"digest"`
+	Tags       []string           `json:"tags,omitempty"`
+	FindingID  registry.FindingID `json:"finding_id"`
+	SizeBytes  int64              `json:"size_bytes,omitempty"`
+	// MonthlyCost is the storage cost the deletion saves.
+	MonthlyCost float64   `json:"monthly_cost,omitempty"`
+	LastUsed    time.Time `json:"last_used,omitzero"`
+	// Reason says why the image was kept or its deletion failed.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Ref returns repository@digest.
+func (r Result) Ref() string {
+	return r.Repository + "@" + r.Digest
+}
+
+// Report is the outcome of a clean run.
+type Report struct {
+	DryRun bool `json:"dry_run"`
+	// Deleted lists the images deleted, or those a dry run would delete.
+	Deleted []Result `json:"deleted"`
+	Skipped []Result `json:"skipped,omitempty"`
+	Failed  []Result `json:"failed,omitempty"`
+	// ReclaimedBytes and MonthlySavings total the deleted images.
+	ReclaimedBytes int64   `json:"reclaimed_bytes"`
+	MonthlySavings float64 `json:"monthly_savings"`
+	Repositories   int     `json:"repositories"`
+}
+
+// Select returns the ECR images of findings with one of the given IDs, each
+// image once, and the findings' images that must not be deleted: deployed
+// images, images with a protected tag, images in an active migration window
+// and cosign signatures and SBOMs.
+func Select(findings []registry.Finding, provider string, ids []registry.FindingID) (candidates, kept []Result) {
+	var eligible []registry.Finding
+	for _, f := range findings {
+		if f.ResourceType != registry.ResourceImage || !slices.Contains(ids, f.ID) {
+			continue
+		}
+		reason := Hold(f)
+		if reason == "" {
+			eligible = append(eligible, f)
+			continue
+		}
+		for _, img := range archive.Select([]registry.Finding{f}, provider, ids) {
+			if img.Provider == "aws" {
+				r := resultOf(img)
+				r.Reason = reason
+				kept = append(kept, r)
+			}
+		}
+	}
+	for _, img := range archive.Select(eligible, provider, ids) {
+		if img.Provider != "aws" {
+			continue
+		}
+		r := resultOf(img)
+		// Cosign stores signatures and SBOMs under sha256-<hex>.sig tags.
+		if slices.ContainsFunc(img.Tags, registry.IsCosignTag) {
+			r.Reason = "signature or SBOM of another image"
+			kept = append(kept, r)
+			continue
+		}
+		candidates = append(candidates, r)
+	}
+	return candidates, kept
+}
+
+// Hold returns why the image of a finding must not be deleted whatever the
+// registry says now: it is deployed, has a protected tag or is in an active
+// migration window. It returns "" otherwise.
+func Hold(f registry.Finding) string {
+	switch {
+	case registry.IsInUse(f):
+		return "deployed"
+	case f.Metadata[registry.MetadataProtected] == true:
+		return "protected tag"
+	case registry.SuppressedByWindow(f):
+		return "in a migration window"
+	}
+	return ""
+}
+
+func resultOf(img archive.Image) Result {
+	return Result{
+		Region: img.Region, Repository: img.Repository, Digest: img.Digest,
+		Tags: img.Tags, FindingID: registry.FindingID(img.FindingID),
+	}
+}
+
+// Run checks the candidates against the current images of each repository
+// and deletes those still eligible, oldest first, up to opts.MaxPerRepo per
+// repository. An image is kept when it is gone, was tagged since the scan,
+// was pulled since the scan or was used within opts.OlderThan. registries
+// returns the registry of a region. A repository that cannot be read or
+// deleted from fails its images and the run goes on.
+func Run(ctx context.Context, registries func(region string) (Registry, error), candidates []Result, opts Options) Report {
+	rep := Report{DryRun: opts.DryRun}
+
+	type repoKey struct{ region, repo string }
+	var keys []repoKey
+	byRepo := make(map[repoKey][]Result)
+	for _, c := range candidates {
+		k := repoKey{c.Region, c.Repository}
+		if _, ok := byRepo[k]; !ok {
+			keys = append(keys, k)
+		}
+		byRepo[k] = append(byRepo[k], c)
+	}
+
+	for _, k := range keys {
+		if ctx.Err() != nil {
+			break
+		}
+		results := byRepo[k]
+		fail := func(err error) {
+			for _, r := range results {
+				r.Reason = err.Error()
+				rep.Failed = append(rep.Failed, r)
+			}
+		}
+		reg, err := registries(k.region)
+		if err != nil {
+			fail(err)
+			continue
+		}
+		images, err := reg.Images(ctx, k.repo)
+		if err != nil {
+			fail(err)
+			continue
+		}
+
+		var eligible []Result
+		for _, r := range results {
+			img, ok := images[r.Digest]
+			if !ok {
+				r.Reason = "no longer in the registry"
+				rep.Skipped = append(rep.Skipped, r)
+				continue
+			}
+			r.SizeBytes = img.SizeBytes
+			r.MonthlyCost = pricing.MonthlyStorageCost("ecr", k.region, img.SizeBytes)
+			r.LastUsed = img.lastUsed()
+			if r.Reason = KeepReason(r, img, opts); r.Reason != "" {
+				rep.Skipped = append(rep.Skipped, r)
+				continue
+			}
+			eligible = append(eligible, r)
+		}
+		sort.SliceStable(eligible, func(i, j int) bool { return eligible[i].LastUsed.Before(eligible[j].LastUsed) })
+		if opts.MaxPerRepo > 0 && len(eligible) > opts.MaxPerRepo {
+			for _, r := range eligible[opts.MaxPerRepo:] {
+				r.Reason = fmt.Sprintf("over the limit of %d deletions per repository", opts.MaxPerRepo)
+				rep.Skipped = append(rep.Skipped, r)
+			}
+			eligible = eligible[:opts.MaxPerRepo]
+		}
+		if len(eligible) == 0 {
+			continue
+		}
+
+		failed := map[string]string{}
+		if !opts.DryRun {
+			digests := make([]string, len(eligible))
+			for i, r := range eligible {
+				digests[i] = r.Digest
+			}
+			failed, err = reg.Delete(ctx, k.repo, digests)
+			if err != nil {
+				// Images of batches deleted before the error are reported as
+				// failed too; a re-run skips them as gone.
+				results = eligible
+				fail(err)
+				continue
+			}
+		}
+		deleted := false
+		for _, r := range eligible {
+			if reason, ok := failed[r.Digest]; ok {
+				r.Reason = reason
+				rep.Failed = append(rep.Failed, r)
+				continue
+			}
+			rep.Deleted = append(rep.Deleted, r)
+			rep.ReclaimedBytes += r.SizeBytes
+			rep.MonthlySavings += r.MonthlyCost
+			deleted = true
+		}
+		if deleted {
+			rep.Repositories++
+		}
+	}
+	return rep
+}
+
+// KeepReason returns why the image of r, still in the registry as img, is
+// not deleted, or "" when it is.
+func KeepReason(r Result, img Image, opts Options) string {
+	if img.Referrer {
+		return "signature or SBOM of another image"
+	}
+	if img.Index != "" {
+		return "referenced by index " + img.Index
+	}
+	for _, tag := range img.Tags {
+		if !slices.Contains(r.Tags, tag) {
+			return fmt.Sprintf("tagged %s since the scan", tag)
+		}
+	}
+	if !opts.ScannedAt.IsZero() && img.LastPull.After(opts.ScannedAt) {
+		return "pulled since the scan"
+	}
+	if opts.OlderThan > 0 {
+		if idle := opts.Now.Sub(img.lastUsed()); idle < opts.OlderThan {
+			return fmt.Sprintf("used %d days ago", int(idle.Hours()/24))
+		}
+	}
+	return ""
+}
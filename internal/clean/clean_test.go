@@ -0,0 +1,205 @@
+package clean
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+var (
+	now       = time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC)
+	scannedAt = now.Add(-time.Hour)
+	oneGB     = int64(1024 * 1024 * 1024)
+)
+
+// fakeRegistry is an in-memory ECR registry.
+type fakeRegistry struct {
+	images  map[string]map[string]Image // by repository, then digest
+	fail    map[string]string           // deletion failures by digest
+	deleted []string
+	listErr error
+}
+
+func (r *fakeRegistry) Images(_ context.Context, repo string) (map[string]Image, error) {
+	if r.listErr != nil {
+		return nil, r.listErr
+	}
+	return r.images[repo], nil
+}
+
+func (r *fakeRegistry) Delete(_ context.Context, repo string, digests []string) (map[string]string, error) {
+	failed := map[string]string{}
+	for _, d := range digests {
+		if reason, ok := r.fail[d]; ok {
+			failed[d] = reason
+			continue
+		}
+		r.deleted = append(r.deleted, repo+"@"+d)
+	}
+	return failed, nil
+}
+
+func registries(r *fakeRegistry) func(string) (Registry, error) {
+	return func(string) (Registry, error) { return r, nil }
+}
+
+func candidate(repo, digest string, tags ...string) Result {
+	return Result{Region: "us-east-1", Repository: repo, Digest: digest, Tags: tags, FindingID: registry.FindingUntaggedImage}
+}
+
+func idle(days int) Image {
+	return Image{SizeBytes: oneGB, PushedAt: now.AddDate(0, 0, -days)}
+}
+
+func TestSelect(t *testing.T) {
+	findings := []registry.Finding{
+		{ID: registry.FindingUntaggedImage, ResourceType: registry.ResourceImage, ResourceID: "app@sha256:a", Region: "us-east-1"},
+		{ID: registry.FindingUntaggedImage, ResourceType: registry.ResourceImage, ResourceID: "app@sha256:a", Region: "us-east-1"},
+		{ID: registry.FindingStaleImage, ResourceType: registry.ResourceImage, ResourceID: "app@sha256:b", Region: "us-east-1"},
+		{ID: registry.FindingUntaggedImage, ResourceType: registry.ResourceImage, ResourceID: "app@sha256:c", Region: "us-east-1",
+			Metadata: map[string]any{"in_use": true}},
+		{ID: registry.FindingUntaggedImage, ResourceType: registry.ResourceImage, ResourceID: "app@sha256:d", Region: "us-east-1",
+			Metadata: map[string]any{registry.MetadataProtected: true}},
+		{ID: registry.FindingUntaggedImage, ResourceType: registry.ResourceImage, ResourceID: "us-docker.pkg.dev/p/r/img@sha256:e", Region: "us",
+			Metadata: map[string]any{registry.MetadataProvider: "gcp"}},
+		{ID: registry.FindingNoLifecyclePolicy, ResourceType: registry.ResourceRepository, ResourceID: "app", Region: "us-east-1"},
+		{ID: registry.FindingStaleImage, ResourceType: registry.ResourceImage, ResourceID: "app@sha256:f", Region: "us-east-1",
+			ResourceName: "app:sha256-" + strings.Repeat("a", 64) + ".sig"},
+	}
+
+	candidates, kept := Select(findings, "aws", []registry.FindingID{registry.FindingUntaggedImage})
+	if len(candidates) != 1 || candidates[0].Ref() != "app@sha256:a" {
+		t.Errorf("candidates = %+v, want app@sha256:a once", candidates)
+	}
+	if len(kept) != 2 || kept[0].Reason != "deployed" || kept[1].Reason != "protected tag" {
+		t.Errorf("kept = %+v", kept)
+	}
+
+	// A cosign signature tag is never a stale release.
+	candidates, kept = Select(findings, "aws", []registry.FindingID{registry.FindingStaleImage})
+	if len(candidates) != 1 || candidates[0].Ref() != "app@sha256:b" {
+		t.Errorf("stale candidates = %+v, want app@sha256:b", candidates)
+	}
+	if len(kept) != 1 || kept[0].Ref() != "app@sha256:f" || kept[0].Reason != "signature or SBOM of another image" {
+		t.Errorf("stale kept = %+v", kept)
+	}
+}
+
+func TestRunDryRun(t *testing.T) {
+	reg := &fakeRegistry{images: map[string]map[string]Image{"app": {
+		"sha256:old":    idle(200),
+		"sha256:recent": idle(5),
+		"sha256:tagged": {SizeBytes: oneGB, Tags: []string{"v2"}, PushedAt: now.AddDate(0, 0, -200)},
+		"sha256:pulled": {SizeBytes: oneGB, PushedAt: now.AddDate(0, 0, -200), LastPull: now.Add(-time.Minute)},
+		"sha256:sig":    {SizeBytes: 1000, PushedAt: now.AddDate(0, 0, -200), Referrer: true},
+		"sha256:amd64":  {SizeBytes: oneGB, PushedAt: now.AddDate(0, 0, -200), Index: "sha256:idx"},
+	}}}
+	candidates := []Result{
+		candidate("app", "sha256:old"),
+		candidate("app", "sha256:recent"),
+		candidate("app", "sha256:tagged"),
+		candidate("app", "sha256:pulled"),
+		candidate("app", "sha256:gone"),
+		candidate("app", "sha256:sig"),
+		candidate("app", "sha256:amd64"),
+	}
+	rep := Run(context.Background(), registries(reg), candidates, Options{OlderThan: 30 * 24 * time.Hour, ScannedAt: scannedAt, Now: now, DryRun: true})
+
+	if len(reg.deleted) != 0 {
+		t.Fatalf("dry run deleted %v", reg.deleted)
+	}
+	if len(rep.Deleted) != 1 || rep.Deleted[0].Digest != "sha256:old" || rep.ReclaimedBytes != oneGB || rep.Repositories != 1 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	if rep.MonthlySavings <= 0 {
+		t.Error("expected positive savings")
+	}
+	reasons := map[string]string{}
+	for _, r := range rep.Skipped {
+		reasons[r.Digest] = r.Reason
+	}
+	want := map[string]string{
+		"sha256:recent": "used 5 days ago",
+		"sha256:tagged": "tagged v2 since the scan",
+		"sha256:pulled": "pulled since the scan",
+		"sha256:gone":   "no longer in the registry",
+		"sha256:sig":    "signature or SBOM of another image",
+		"sha256:amd64":  "referenced by index sha256:idx",
+	}
+	for digest, reason := range want {
+		if reasons[digest] != reason {
+			t.Errorf("%s: reason = %q, want %q", digest, reasons[digest], reason)
+		}
+	}
+}
+
+func TestRunDeletesOldestFirstUpToLimit(t *testing.T) {
+	reg := &fakeRegistry{
+		images: map[string]map[string]Image{"app": {"sha256:a": idle(100), "sha256:b": idle(300), "sha256:c": idle(200)}},
+		fail:   map[string]string{"sha256:c": "ImageReferencedByManifestList: referenced by an index"},
+	}
+	candidates := []Result{candidate("app", "sha256:a"), candidate("app", "sha256:b"), candidate("app", "sha256:c")}
+	rep := Run(context.Background(), registries(reg), candidates, Options{MaxPerRepo: 2, Now: now})
+
+	if len(reg.deleted) != 1 || reg.deleted[0] != "app@sha256:b" {
+		t.Errorf("deleted = %v, want the oldest image", reg.deleted)
+	}
+	if len(rep.Failed) != 1 || rep.Failed[0].Digest != "sha256:c" || !strings.Contains(rep.Failed[0].Reason, "ImageReferencedByManifestList") {
+		t.Errorf("failed = %+v", rep.Failed)
+	}
+	if len(rep.Skipped) != 1 || rep.Skipped[0].Digest != "sha256:a" || !strings.Contains(rep.Skipped[0].Reason, "limit of 2") {
+		t.Errorf("skipped = %+v", rep.Skipped)
+	}
+	if rep.ReclaimedBytes != oneGB {
+		t.Errorf("reclaimed = %d", rep.ReclaimedBytes)
+	}
+}
+
+func TestRunRepositoryError(t *testing.T) {
+	reg := &fakeRegistry{listErr: errors.New("access denied")}
+	rep := Run(context.Background(), registries(reg), []Result{candidate("app", "sha256:a")}, Options{Now: now})
+	if len(rep.Failed) != 1 || rep.Failed[0].Reason != "access denied" {
+		t.Errorf("failed = %+v", rep.Failed)
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	rep := Report{
+		DryRun:         true,
+		Deleted:        []Result{{Region: "us-east-1", Repository: "app", Digest: "sha256:a", FindingID: registry.FindingUntaggedImage, SizeBytes: oneGB, MonthlyCost: 0.1}},
+		Skipped:        []Result{{Region: "us-east-1", Repository: "app", Digest: "sha256:b", Reason: "deployed"}},
+		ReclaimedBytes: oneGB, MonthlySavings: 0.1, Repositories: 1,
+	}
+	var buf bytes.Buffer
+	if err := WriteText(&buf, rep); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"would delete app@sha256:a (us-east-1, UNTAGGED_IMAGE, 1.00 GB, $0.10/mo)",
+		"skip app@sha256:b (us-east-1): deployed",
+		"Dry run: 1 images in 1 repositories would be deleted, reclaiming 1.00 GB ($0.10/mo)",
+		"--yes",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, Report{}); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if deleted, ok := decoded["deleted"].([]any); !ok || len(deleted) != 0 {
+		t.Errorf("deleted = %v, want an empty list", decoded["deleted"])
+	}
+}
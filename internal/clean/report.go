@@ -0,0 +1,53 @@
+package clean
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const gb = 1024 * 1024 * 1024
+
+// WriteText writes one line per image and a summary of the storage
+// reclaimed.
+func WriteText(w io.Writer, rep Report) error {
+	action := "deleted"
+	if rep.DryRun {
+		action = "would delete"
+	}
+	for _, r := range rep.Deleted {
+		if _, err := fmt.Fprintf(w, "%s %s (%s, %s, %.2f GB, $%.2f/mo)\n", action, r.Ref(), r.Region, r.FindingID, float64(r.SizeBytes)/gb, r.MonthlyCost); err != nil {
+			return err
+		}
+	}
+	for _, r := range rep.Skipped {
+		if _, err := fmt.Fprintf(w, "skip %s (%s): %s\n", r.Ref(), r.Region, r.Reason); err != nil {
+			return err
+		}
+	}
+	for _, r := range rep.Failed {
+		if _, err := fmt.Fprintf(w, "failed %s (%s): %s\n", r.Ref(), r.Region, r.Reason); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if rep.DryRun {
+		_, err = fmt.Fprintf(w, "Dry run: %d images in %d repositories would be deleted, reclaiming %.2f GB ($%.2f/mo); %d skipped. Re-run with --yes to delete them.\n",
+			len(rep.Deleted), rep.Repositories, float64(rep.ReclaimedBytes)/gb, rep.MonthlySavings, len(rep.Skipped))
+	} else {
+		_, err = fmt.Fprintf(w, "Deleted %d images in %d repositories, reclaiming %.2f GB ($%.2f/mo); %d skipped, %d failed\n",
+			len(rep.Deleted), rep.Repositories, float64(rep.ReclaimedBytes)/gb, rep.MonthlySavings, len(rep.Skipped), len(rep.Failed))
+	}
+	return err
+}
+
+// WriteJSON writes the report as indented JSON.
+func WriteJSON(w io.Writer, rep Report) error {
+	if rep.Deleted == nil {
+		rep.Deleted = []Result{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(rep)
+}
@@ -0,0 +1,425 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/analyzer"
+	"github.com/ppiankov/ecrspectre/internal/config"
+	"github.com/ppiankov/ecrspectre/internal/ecr"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+	"github.com/ppiankov/ecrspectre/internal/report"
+	"github.com/spf13/cobra"
+)
+
+var allFlags struct {
+	staleDays            int
+	maxSizeMB            int
+	format               string
+	outputFile           string
+	minMonthlyCost       float64
+	includeScan          bool
+	noProgress           bool
+	dashboard            bool
+	timeout              time.Duration
+	groupBy              string
+	costPeriod           string
+	top                  int
+	sortBy               string
+	carbon               bool
+	ignoreFile           string
+	baseline             string
+	failOn               []string
+	slackWebhook         string
+	accountTags          []string
+	excludeAccountTags   []string
+	projectLabels        []string
+	excludeProjectLabels []string
+	// CloudEvents webhook and its thresholds.
+	webhook         string
+	webhookWaste    float64
+	webhookSeverity string
+	// Go template rendered by --format template.
+	templateFile string
+}
+
+var allCmd = &cobra.Command{
+	Use:   "all",
+	Short: "Audit every AWS and GCP target in the config in one run",
+	Long: `Scan each AWS account (by profile) and GCP project set listed under targets in
+.ecrspectre.yaml and write one merged report. Findings are tagged with their
+provider and target, and waste is broken down by provider.`,
+	RunE: runAll,
+}
+
+func init() {
+	allCmd.Flags().IntVar(&allFlags.staleDays, "stale-days", 90, "Image age threshold in days since last pull")
+	allCmd.Flags().IntVar(&allFlags.maxSizeMB, "max-size", 1024, "Flag images larger than this (MB)")
+	allCmd.Flags().StringVar(&allFlags.format, "format", "text", "Output format: text, json, yaml, csv, markdown, junit, github, prometheus, sarif, spectrehub, ocsf, template, sqlite, or dataset; several comma-separated with one --output each")
+	allCmd.Flags().StringVarP(&allFlags.outputFile, "output", "o", "", "Output file path, or s3://bucket/key or gs://bucket/key to upload under a date-stamped key; comma-separated, one per --format (default: stdout)")
+	allCmd.Flags().StringVar(&allFlags.templateFile, "template", "", "Go text/template file rendering the report for --format template")
+	allCmd.Flags().StringVar(&allFlags.slackWebhook, "slack-webhook", "", "Post a summary with the top findings to this Slack incoming webhook (default: $ECRSPECTRE_SLACK_WEBHOOK)")
+	allCmd.Flags().StringVar(&allFlags.webhook, "webhook", "", "Post scan.completed and threshold.breached CloudEvents to this URL (default: $ECRSPECTRE_WEBHOOK)")
+	allCmd.Flags().Float64Var(&allFlags.webhookWaste, "webhook-waste-threshold", 0, "Send threshold.breached when the total monthly waste reaches this many dollars (0 disables)")
+	allCmd.Flags().StringVar(&allFlags.webhookSeverity, "webhook-severity-threshold", "", "Send threshold.breached for findings at least this severe: critical, high, medium or low")
+	allCmd.Flags().Float64Var(&allFlags.minMonthlyCost, "min-monthly-cost", 0.10, "Minimum monthly cost to report ($)")
+	allCmd.Flags().BoolVar(&allFlags.includeScan, "include-scan", false, "Include vulnerability scan data if available")
+	allCmd.Flags().BoolVar(&allFlags.noProgress, "no-progress", false, "Disable progress output")
+	allCmd.Flags().BoolVar(&allFlags.dashboard, "dashboard", false, "Show a live dashboard of scan progress, waste found and API calls on stderr (terminals only)")
+	allCmd.Flags().DurationVar(&allFlags.timeout, "timeout", 30*time.Minute, "Timeout for all targets together")
+	allCmd.Flags().StringVar(&allFlags.groupBy, "group-by", registry.MetadataProvider, "Break waste down by provider, target, region, repo or a repository tag/label key")
+	allCmd.Flags().StringVar(&allFlags.costPeriod, "cost-period", "", "Also report waste per day or per year: day, month, year (default: month)")
+	allCmd.Flags().BoolVar(&allFlags.carbon, "carbon", false, "Estimate the CO2e of wasted storage")
+	allCmd.Flags().IntVar(&allFlags.top, "top", 0, "Report only the N worst findings (by --sort, default score); the summary still covers all")
+	allCmd.Flags().StringVar(&allFlags.sortBy, "sort", "", "Order findings by: score (priority score), waste, size, age (oldest first), severity, or scan (scan order)")
+	allCmd.Flags().StringVar(&allFlags.ignoreFile, "ignore-file", "", "Suppression file (default: .ecrspectre-ignore.yaml in the working directory)")
+	allCmd.Flags().StringVar(&allFlags.baseline, "baseline", "", "Previous JSON report; tag findings new or unchanged and exit 2 only when any are new")
+	allCmd.Flags().StringSliceVar(&allFlags.failOn, "fail-on", nil, "Exit 2 when findings break a threshold: severity=<level>, waste=<dollars/mo> or count=<n> (repeatable; with --baseline, new findings only)")
+	allCmd.Flags().StringSliceVar(&allFlags.accountTags, "account-tags", nil, "Only scan AWS targets whose account has these AWS Organizations tags (key=value or key)")
+	allCmd.Flags().StringSliceVar(&allFlags.excludeAccountTags, "exclude-account-tags", nil, "Skip AWS targets whose account has any of these tags (key=value or key)")
+	allCmd.Flags().StringSliceVar(&allFlags.projectLabels, "project-labels", nil, "Only scan GCP projects with these labels (key=value or key)")
+	allCmd.Flags().StringSliceVar(&allFlags.excludeProjectLabels, "exclude-project-labels", nil, "Skip GCP projects with any of these labels (key=value or key)")
+}
+
+func runAll(cmd *cobra.Command, _ []string) error {
+	startedOn := time.Now()
+	runID := startRun()
+	ctx := cmd.Context()
+	if allFlags.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, allFlags.timeout)
+		defer cancel()
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		return configError(err)
+	}
+	applyAllConfigDefaults(cfg)
+	expandPaths(&allFlags.ignoreFile, &allFlags.baseline)
+	// Check the report formats before scanning, not after.
+	if _, err := parseReportTargets(allFlags.format, allFlags.outputFile); err != nil {
+		return err
+	}
+	if _, err := loadReportTemplate(allFlags.format, allFlags.templateFile); err != nil {
+		return err
+	}
+	targets, err := resolveTargets(cfg.Targets)
+	if err != nil {
+		return configError(err)
+	}
+	webhook, err := resolveSlackWebhook(allFlags.slackWebhook)
+	if err != nil {
+		return err
+	}
+	events, err := newEventsReporter(allFlags.webhook, allFlags.webhookWaste, allFlags.webhookSeverity)
+	if err != nil {
+		return err
+	}
+	accounts, err := labelSelector(cfg.Scope.AccountTags, cfg.Scope.ExcludeAccountTags, allFlags.accountTags, allFlags.excludeAccountTags)
+	if err != nil {
+		return configError(fmt.Errorf("account tags: %w", err))
+	}
+	projectLabels, err := labelSelector(cfg.Scope.ProjectLabels, cfg.Scope.ExcludeProjectLabels, allFlags.projectLabels, allFlags.excludeProjectLabels)
+	if err != nil {
+		return configError(fmt.Errorf("project labels: %w", err))
+	}
+	targets, scopeErrors := scopeTargets(ctx, targets, accounts, projectLabels, cfg.Scope.OrganizationsProfile)
+	if len(targets) == 0 {
+		if len(scopeErrors) > 0 {
+			return fmt.Errorf("no targets in scope: %s", strings.Join(scopeErrors, "; "))
+		}
+		return configError(fmt.Errorf("no targets match the account tags and project labels"))
+	}
+
+	repoFilter, err := buildRepoFilter(cfg, nil, nil)
+	if err != nil {
+		return configError(err)
+	}
+	protectedTags, err := registry.NewTagPatterns(cfg.ProtectedTags)
+	if err != nil {
+		return configError(fmt.Errorf("protected_tags: %w", err))
+	}
+	signedTags, err := registry.NewTagPatterns(cfg.SignedTags)
+	if err != nil {
+		return configError(fmt.Errorf("signed_tags: %w", err))
+	}
+	var sbomSeverity registry.Severity
+	if cfg.SBOMSeverity != "" {
+		if sbomSeverity, err = registry.ParseSeverity(cfg.SBOMSeverity); err != nil {
+			return configError(fmt.Errorf("sbom severity: %w", err))
+		}
+	}
+	userRules, err := customRules(cfg)
+	if err != nil {
+		return configError(fmt.Errorf("rules: %w", err))
+	}
+	cadences, err := releaseCadences(cfg)
+	if err != nil {
+		return configError(err)
+	}
+	windows, err := migrationWindows(cfg)
+	if err != nil {
+		return configError(err)
+	}
+	suppressions, err := loadSuppressions(allFlags.ignoreFile)
+	if err != nil {
+		return configError(err)
+	}
+	baseline, err := loadBaseline(allFlags.baseline)
+	if err != nil {
+		return err
+	}
+	failOn, err := parseFailOn(allFlags.failOn)
+	if err != nil {
+		return err
+	}
+	costPeriod, err := registry.ParseCostPeriod(allFlags.costPeriod)
+	if err != nil {
+		return configError(fmt.Errorf("--cost-period: %w", err))
+	}
+	sortKey, err := analyzer.ParseSortKey(allFlags.sortBy)
+	if err != nil {
+		return configError(fmt.Errorf("--sort: %w", err))
+	}
+	scorer, err := scoreModel(cfg.Scoring)
+	if err != nil {
+		return err
+	}
+	if allFlags.top < 0 {
+		return configError(fmt.Errorf("--top must not be negative"))
+	}
+
+	excludeIDs := make(map[string]bool, len(cfg.Exclude.ResourceIDs))
+	for _, id := range cfg.Exclude.ResourceIDs {
+		excludeIDs[id] = true
+	}
+	scanCfg := registry.ScanConfig{
+		StaleDays:      allFlags.staleDays,
+		MaxSizeBytes:   int64(allFlags.maxSizeMB) * 1024 * 1024,
+		MinMonthlyCost: allFlags.minMonthlyCost,
+		Exclude: registry.ExcludeConfig{
+			ResourceIDs: excludeIDs,
+			Tags:        parseExcludeTags(cfg.Exclude.Tags, nil),
+		},
+		Repos:             repoFilter,
+		TagPriority:       cfg.TagPriority,
+		KeepLatest:        cfg.KeepLatest,
+		ProtectedTags:     protectedTags,
+		RequireSignatures: cfg.RequireSignatures,
+		SignedTags:        signedTags,
+		RequireSBOM:       cfg.RequireSBOM,
+		SBOMSeverity:      sbomSeverity,
+		Rules:             userRules,
+		ReleaseCadences:   cadences,
+		TieringMinBytes:   int64(cfg.TieringMinSizeMB) * 1024 * 1024,
+		AttributionKeys:   attributionKeys(allFlags.groupBy),
+		DisabledChecks:    disabledChecks(cfg),
+	}
+
+	progress, err := newProgressSink(allFlags.noProgress, "text", "", runID, allFlags.dashboard)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = progress.Close() }()
+
+	// Targets are scanned one after another so a large account cannot starve
+	// the others of API quota; a target that fails leaves the rest running.
+	names := make([]string, len(targets))
+	providers := make(map[string]string, len(targets))
+	results := make(map[string]*registry.ScanResult, len(targets))
+	var regions, projects []string
+	for i, t := range targets {
+		names[i] = t.Name
+		providers[t.Name] = t.Provider
+		slog.Info("Scanning target", "target", t.Name, "provider", t.Provider)
+		switch t.Provider {
+		case "aws":
+			results[t.Name] = scanAWSTarget(ctx, t, cfg.EndpointURL, scanCfg, progress)
+		case "gcp":
+			results[t.Name] = scanGCPProjects(ctx, t.Projects, t.Regions, scanCfg, allFlags.includeScan, progress, nil)
+			projects = append(projects, t.Projects...)
+		}
+		regions = append(regions, t.Regions...)
+	}
+	result := registry.MergeTargetResults(names, providers, results)
+	// Give the terminal back before anything else is printed.
+	_ = progress.Close()
+	if err := scanFailedError(result, "target"); err != nil {
+		return err
+	}
+	result.Errors = append(scopeErrors, result.Errors...)
+
+	analysis := analyzer.Analyze(result, analyzer.AnalyzerConfig{
+		MinMonthlyCost: allFlags.minMonthlyCost,
+		DisabledChecks: scanCfg.DisabledChecks,
+		Suppressions:   suppressions,
+		Windows:        windows,
+		GroupBy:        allFlags.groupBy,
+		CostPeriod:     costPeriod,
+		Carbon:         allFlags.carbon,
+		Sort:           sortKey,
+		Scorer:         scorer,
+		Top:            allFlags.top,
+	})
+	warnExpiredSuppressions(analysis.Suppressions)
+
+	data := report.Data{
+		Tool:      "ecrspectre",
+		Version:   version,
+		Timestamp: time.Now().UTC(),
+		RunID:     runID,
+		Target: report.Target{
+			Type:    "multi",
+			URIHash: computeTargetHash("all", names, ""),
+		},
+		Config: report.ReportConfig{
+			Provider:       "all",
+			Regions:        dedupe(regions),
+			Projects:       dedupe(projects),
+			StaleDays:      allFlags.staleDays,
+			MaxSizeMB:      allFlags.maxSizeMB,
+			MinMonthlyCost: allFlags.minMonthlyCost,
+		},
+		Findings:        analysis.Findings,
+		Summary:         analysis.Summary,
+		Errors:          analysis.Errors,
+		ScanStats:       gcpScanStats(result.Timings),
+		Suppressions:    analysis.Suppressions,
+		Recommendations: result.Recommendations,
+		Usage:           result.Usage,
+		Duration:        time.Since(startedOn),
+	}
+	sort.Strings(data.Config.Regions)
+
+	baseline.mark(&data, analysis.Omitted)
+
+	reporter, closeOutput, err := selectReporter(allFlags.format, allFlags.outputFile, allFlags.templateFile, "")
+	if err != nil {
+		return err
+	}
+	err = reporter.Generate(data)
+	if closeErr := closeOutput(); err == nil && closeErr != nil {
+		err = fmt.Errorf("close output file: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	if err := notifySlack(webhook, data); err != nil {
+		return err
+	}
+	if err := notifyEvents(events, data); err != nil {
+		return err
+	}
+	if err := findingsGate(failOn, baseline, data, analysis.Omitted); err != nil {
+		return err
+	}
+	return partialScanError(data.Errors)
+}
+
+// resolveTargets validates the targets section of the config and names each
+// target after its profile or projects unless it is named.
+func resolveTargets(targets []config.Target) ([]config.Target, error) {
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets configured: list AWS profiles and GCP projects under targets in .ecrspectre.yaml")
+	}
+	out := make([]config.Target, len(targets))
+	seen := make(map[string]bool, len(targets))
+	for i, t := range targets {
+		t.Provider = strings.ToLower(t.Provider)
+		switch t.Provider {
+		case "aws":
+			if t.Name == "" {
+				t.Name = t.Profile
+			}
+			if t.Name == "" {
+				t.Name = "aws"
+			}
+		case "gcp":
+			if len(t.Projects) == 0 || len(t.Regions) == 0 {
+				return nil, fmt.Errorf("target %d: gcp targets need projects and regions (Artifact Registry locations)", i+1)
+			}
+			if t.Name == "" {
+				t.Name = strings.Join(t.Projects, ",")
+			}
+		default:
+			return nil, fmt.Errorf("target %d: unsupported provider %q (use aws or gcp)", i+1, t.Provider)
+		}
+		if seen[t.Name] {
+			return nil, fmt.Errorf("target %d: name %q is used by another target; set a distinct name", i+1, t.Name)
+		}
+		seen[t.Name] = true
+		out[i] = t
+	}
+	return out, nil
+}
+
+// scanAWSTarget scans every region of an AWS target, or the profile's
+// default region when none are listed.
+func scanAWSTarget(ctx context.Context, t config.Target, endpointURL string, scanCfg registry.ScanConfig, progress *progressSink) *registry.ScanResult {
+	regions := t.Regions
+	if len(regions) == 0 {
+		regions = []string{""}
+	}
+	scanned := make([]string, 0, len(regions))
+	results := make(map[string]*registry.ScanResult, len(regions))
+	for _, region := range regions {
+		client, err := ecr.NewClient(ctx, t.Profile, region, endpointURL)
+		if err == nil && client.Region() == "" {
+			err = configError(fmt.Errorf("no AWS region configured; set regions on the target"))
+		}
+		if err != nil {
+			if region == "" {
+				region = "default region"
+			}
+			failed := &registry.ScanResult{Targets: 1}
+			failed.AddTargetError(region, enhanceError("initialize AWS client", err))
+			scanned = append(scanned, region)
+			results[region] = failed
+			continue
+		}
+		region = client.Region()
+		scanner := ecr.NewECRScanner(client.NewECRClient(), region, allFlags.includeScan)
+		scanned = append(scanned, region)
+		results[region] = scanner.Scan(ctx, scanCfg, progress.callback(t.Name))
+	}
+	return registry.MergeRegionResults(scanned, results)
+}
+
+func applyAllConfigDefaults(cfg config.Config) {
+	if len(allFlags.failOn) == 0 {
+		allFlags.failOn = cfg.FailOn
+	}
+	if allFlags.groupBy == registry.MetadataProvider && cfg.GroupBy != "" {
+		allFlags.groupBy = cfg.GroupBy
+	}
+	if allFlags.costPeriod == "" {
+		allFlags.costPeriod = cfg.CostPeriod
+	}
+	if allFlags.sortBy == "" {
+		allFlags.sortBy = cfg.Sort
+	}
+	if !allFlags.carbon {
+		allFlags.carbon = cfg.Carbon
+	}
+	allFlags.format, allFlags.outputFile = reportsFlags(allFlags.format, allFlags.outputFile, cfg.Reports)
+	if allFlags.templateFile == "" && hasFormat(allFlags.format, "template") {
+		allFlags.templateFile = cfg.Template
+	}
+	if allFlags.format == "text" && cfg.Format != "" {
+		allFlags.format = cfg.Format
+	}
+	if allFlags.staleDays == 90 && cfg.StaleDays > 0 {
+		allFlags.staleDays = cfg.StaleDays
+	}
+	if allFlags.maxSizeMB == 1024 && cfg.MaxSizeMB > 0 {
+		allFlags.maxSizeMB = cfg.MaxSizeMB
+	}
+	if allFlags.minMonthlyCost == 0.10 && cfg.MinMonthlyCost > 0 {
+		allFlags.minMonthlyCost = cfg.MinMonthlyCost
+	}
+}
@@ -0,0 +1,372 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/archive"
+	"github.com/ppiankov/ecrspectre/internal/artifactregistry"
+	"github.com/ppiankov/ecrspectre/internal/awsapi"
+	"github.com/ppiankov/ecrspectre/internal/clean"
+	"github.com/ppiankov/ecrspectre/internal/dockerauth"
+	"github.com/ppiankov/ecrspectre/internal/ecr"
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+	"github.com/ppiankov/ecrspectre/internal/logging"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+	"github.com/ppiankov/ecrspectre/internal/report"
+	"github.com/spf13/cobra"
+)
+
+var archiveFlags struct {
+	input        string
+	to           string
+	findings     []string
+	delete       bool
+	dryRun       bool
+	profile      string
+	bucketRegion string
+	maxReportAge string
+}
+
+var archiveCmd = &cobra.Command{
+	Use:   "archive",
+	Short: "Export stale images to S3, GCS or a directory, then optionally delete them",
+	Long: `Copy the images named by findings of a spectre/v1 JSON report out of the
+registry into OCI image layout tarballs, one per image, under an S3 bucket, a
+GCS bucket or a local directory, and record them in an index.json there.
+
+Every manifest and blob is checked against its digest while it is copied, and
+each uploaded tarball is read back and compared with its SHA-256. Only with
+--delete, and only after that verification, is the image deleted from the
+registry. Like aws clean and restore, it modifies the registry.
+
+--delete refuses reports older than --max-report-age. Deployed images,
+images with a protected tag and images in a migration window are archived
+but never deleted, and just before each deletion the image is checked
+against the registry: an image that was tagged or pulled since the scan
+stays.`,
+	Example: `  ecrspectre aws --format json -o report.json
+  ecrspectre archive --input report.json --to s3://my-archive/ecr --dry-run
+  ecrspectre archive --input report.json --to s3://my-archive/ecr --delete`,
+	RunE: runArchive,
+}
+
+func init() {
+	archiveCmd.Flags().StringVar(&archiveFlags.input, "input", "", "JSON report to take images from (required)")
+	archiveCmd.Flags().StringVar(&archiveFlags.to, "to", "", "Archive destination: s3://bucket/prefix, gs://bucket/prefix or a directory (required)")
+	archiveCmd.Flags().StringSliceVar(&archiveFlags.findings, "finding", []string{string(registry.FindingStaleImage)}, "Finding IDs whose images are archived")
+	archiveCmd.Flags().BoolVar(&archiveFlags.delete, "delete", false, "Delete each image from the registry once its archive is verified")
+	archiveCmd.Flags().BoolVar(&archiveFlags.dryRun, "dry-run", false, "List the images that would be archived without copying them")
+	archiveCmd.Flags().StringVar(&archiveFlags.profile, "profile", "", "AWS profile for ECR and S3")
+	archiveCmd.Flags().StringVar(&archiveFlags.bucketRegion, "bucket-region", "", "Region of the S3 bucket (default: the profile's region)")
+	archiveCmd.Flags().StringVar(&archiveFlags.maxReportAge, "max-report-age", "7d", "With --delete, refuse reports older than this (0 for no limit)")
+}
+
+func runArchive(cmd *cobra.Command, _ []string) error {
+	if archiveFlags.input == "" || archiveFlags.to == "" {
+		return configError(fmt.Errorf("--input and --to are required"))
+	}
+	dest, err := archive.ParseDestination(archiveFlags.to)
+	if err != nil {
+		return configError(err)
+	}
+	if dest.Scheme == "" {
+		expandPaths(&dest.Prefix)
+	}
+	expandPaths(&archiveFlags.input)
+
+	maxReportAge, err := parseAge(archiveFlags.maxReportAge)
+	if err != nil {
+		return configError(fmt.Errorf("--max-report-age: %w", err))
+	}
+
+	data, err := report.ReadJSONFile(archiveFlags.input)
+	if err != nil {
+		return err
+	}
+	if data.RunID != "" {
+		logging.SetRunID(data.RunID)
+	}
+	now := time.Now().UTC()
+	if archiveFlags.delete && maxReportAge > 0 && now.Sub(data.Timestamp) > maxReportAge {
+		return configError(fmt.Errorf("--delete needs a report from the last %s, %s was written %s; rescan or raise --max-report-age",
+			archiveFlags.maxReportAge, archiveFlags.input, data.Timestamp.Format(time.RFC3339)))
+	}
+	ids := make([]registry.FindingID, len(archiveFlags.findings))
+	for i, id := range archiveFlags.findings {
+		ids[i] = registry.FindingID(strings.ToUpper(strings.TrimSpace(id)))
+	}
+	images := archive.Select(data.Findings, data.Config.Provider, ids)
+	holds := archiveHolds(data.Findings, data.Config.Provider, ids)
+
+	w := cmd.OutOrStdout()
+	if len(images) == 0 {
+		_, _ = fmt.Fprintln(w, "No images to archive")
+		return nil
+	}
+	if archiveFlags.dryRun {
+		for _, img := range images {
+			note := ""
+			if reason := holds[archiveKey(img)]; archiveFlags.delete && reason != "" {
+				note = " and keep it in the registry: " + reason
+			}
+			_, _ = fmt.Fprintf(w, "would archive %s (%s) to %s%s\n", img.Ref(), img.Region, archiveFlags.to, note)
+		}
+		return nil
+	}
+
+	ctx := cmd.Context()
+	store, err := archiveStore(ctx, dest, archiveFlags.profile, archiveFlags.bucketRegion)
+	if err != nil {
+		return err
+	}
+	index, err := archive.LoadIndex(ctx, store)
+	if err != nil {
+		return err
+	}
+
+	sources := newArchiveSources(archiveFlags.profile)
+	defer sources.Close()
+	guard := func(ctx context.Context, img archive.Image) (string, error) {
+		return sources.recheck(ctx, img, data.Timestamp)
+	}
+	var errs []string
+	var archived, deleted int
+	var totalBytes int64
+	for _, img := range images {
+		del := archiveFlags.delete && holds[archiveKey(img)] == ""
+		if e, ok := index.Lookup(img); ok && (e.Deleted || !del) {
+			_, _ = fmt.Fprintf(w, "skip %s: already archived to %s\n", img.Ref(), e.Object)
+			continue
+		}
+		entry, err := archiveImage(ctx, sources, store, img, del, guard, now)
+		if archiveFlags.delete && !entry.Deleted && entry.Kept == "" {
+			entry.Kept = holds[archiveKey(img)]
+		}
+		if entry.Object != "" {
+			entry.RunID = data.RunID
+			index.Add(entry)
+			if serr := index.Save(ctx, store); serr != nil {
+				return serr
+			}
+			archived++
+			totalBytes += entry.SizeBytes
+			if entry.Deleted {
+				deleted++
+			}
+			writeArchived(w, entry)
+		}
+		if err != nil {
+			slog.Warn("Archive failed", "image", img.Ref(), "region", img.Region, "error", err)
+			errs = append(errs, err.Error())
+		}
+	}
+
+	_, _ = fmt.Fprintf(w, "Archived %d of %d images (%.1f MB) to %s, deleted %d from the registry\n",
+		archived, len(images), float64(totalBytes)/(1024*1024), archiveFlags.to, deleted)
+	if len(errs) > 0 {
+		return &ExitError{Code: ExitPartial, Err: fmt.Errorf("archive incomplete: %d of %d images failed", len(errs), len(images))}
+	}
+	return nil
+}
+
+func writeArchived(w io.Writer, e archive.Entry) {
+	action := "archived"
+	if e.Deleted {
+		action = "archived and deleted"
+	}
+	kept := ""
+	if e.Kept != "" {
+		kept = ", kept in the registry: " + e.Kept
+	}
+	_, _ = fmt.Fprintf(w, "%s %s -> %s (sha256 %s)%s\n", action, e.Image, e.Object, e.SHA256, kept)
+}
+
+func archiveImage(ctx context.Context, sources *archiveSources, store archive.Store, img archive.Image, del bool, guard archive.DeleteGuard, now time.Time) (archive.Entry, error) {
+	src, err := sources.get(ctx, img)
+	if err != nil {
+		return archive.Entry{}, err
+	}
+	return archive.Archive(ctx, src, store, img, del, guard, now)
+}
+
+// archiveHolds returns, by archiveKey, why the images of findings must stay
+// in the registry whatever --delete says, with the rules of aws clean.
+func archiveHolds(findings []registry.Finding, provider string, ids []registry.FindingID) map[string]string {
+	holds := make(map[string]string)
+	for _, f := range findings {
+		reason := clean.Hold(f)
+		if reason == "" {
+			continue
+		}
+		for _, img := range archive.Select([]registry.Finding{f}, provider, ids) {
+			holds[archiveKey(img)] = reason
+		}
+	}
+	return holds
+}
+
+// archiveKey identifies an image across registries.
+func archiveKey(img archive.Image) string {
+	return img.Provider + "/" + img.Host + "/" + img.Region + "/" + img.Ref()
+}
+
+// archiveStore opens the store of a destination. S3 uses the AWS profile,
+// signing for bucketRegion when set.
+func archiveStore(ctx context.Context, dest archive.Destination, profile, bucketRegion string) (archive.Store, error) {
+	switch dest.Scheme {
+	case "s3":
+		client, err := ecr.NewClient(ctx, profile, bucketRegion, "")
+		if err != nil {
+			return nil, enhanceError("archive to S3", err)
+		}
+		return archive.S3Store{Caller: awsapi.NewCaller(client.Config()), Dest: dest}, nil
+	case "gs":
+		caller, err := gcpapi.NewCaller(ctx)
+		if err != nil {
+			return nil, enhanceError("archive to GCS", err)
+		}
+		return archive.GCSStore{Caller: caller, Dest: dest}, nil
+	default:
+		return archive.DirStore{Dir: dest.Prefix}, nil
+	}
+}
+
+// archiveSources creates registry clients on first use: one per ECR region,
+// and one per Artifact Registry image so its access token stays current.
+type archiveSources struct {
+	profile string
+	ecr     map[string]*archive.RegistrySource
+	gcp     *gcpapi.Caller
+	// ecrImages and arClients list images for the check before deletion.
+	ecrImages func(region string) (clean.Registry, error)
+	arClients map[string]*artifactregistry.Client
+}
+
+func newArchiveSources(profile string) *archiveSources {
+	return &archiveSources{profile: profile, ecr: make(map[string]*archive.RegistrySource), arClients: make(map[string]*artifactregistry.Client)}
+}
+
+// Close closes the Artifact Registry clients.
+func (s *archiveSources) Close() {
+	for _, c := range s.arClients {
+		_ = c.Close()
+	}
+}
+
+// recheck returns why img, selected from a report written at scannedAt,
+// must stay in the registry now: it is gone, or was tagged or pulled since
+// the scan. It reads the image's repository as it is at the time of the call.
+func (s *archiveSources) recheck(ctx context.Context, img archive.Image, scannedAt time.Time) (string, error) {
+	var images map[string]clean.Image
+	var err error
+	switch img.Provider {
+	case "aws":
+		if s.ecrImages == nil {
+			s.ecrImages = newCleanRegistries(ctx, s.profile)
+		}
+		reg, rerr := s.ecrImages(img.Region)
+		if rerr != nil {
+			return "", rerr
+		}
+		images, err = reg.Images(ctx, img.Repository)
+	case "gcp":
+		images, err = s.arImages(ctx, img)
+	default:
+		return "", fmt.Errorf("unsupported provider %q", img.Provider)
+	}
+	if err != nil {
+		return "", err
+	}
+	live, ok := images[img.Digest]
+	if !ok {
+		return "no longer in the registry", nil
+	}
+	return clean.KeepReason(clean.Result{Tags: img.Tags}, live, clean.Options{ScannedAt: scannedAt}), nil
+}
+
+// arImages returns the images of an Artifact Registry image path by digest.
+// Artifact Registry records no pull times, so only tags are compared.
+func (s *archiveSources) arImages(ctx context.Context, img archive.Image) (map[string]clean.Image, error) {
+	location, ok := strings.CutSuffix(img.Host, "-docker.pkg.dev")
+	parts := strings.SplitN(img.Repository, "/", 3)
+	if !ok || len(parts) != 3 {
+		return nil, fmt.Errorf("%s/%s is not an Artifact Registry image", img.Host, img.Repository)
+	}
+	project, repo := parts[0], parts[1]
+	client, ok := s.arClients[project]
+	if !ok {
+		var err error
+		if client, err = artifactregistry.NewClient(ctx, project, ""); err != nil {
+			return nil, enhanceError("initialize GCP client", err)
+		}
+		s.arClients[project] = client
+	}
+	docker, err := client.ListDockerImages(ctx, "projects/"+project+"/locations/"+location+"/repositories/"+repo)
+	if err != nil {
+		return nil, err
+	}
+	images := make(map[string]clean.Image)
+	prefix := img.Host + "/" + img.Repository + "@"
+	for _, d := range docker {
+		if digest, ok := strings.CutPrefix(d.URI, prefix); ok {
+			images[digest] = clean.Image{Tags: d.Tags, SizeBytes: d.SizeBytes, PushedAt: d.UploadTime}
+		}
+	}
+	return images, nil
+}
+
+func (s *archiveSources) get(ctx context.Context, img archive.Image) (*archive.RegistrySource, error) {
+	switch img.Provider {
+	case "aws":
+		if src, ok := s.ecr[img.Region]; ok {
+			return src, nil
+		}
+		client, err := ecr.NewClient(ctx, s.profile, img.Region, "")
+		if err != nil {
+			return nil, err
+		}
+		api := client.NewRegistryClient()
+		host, cred, err := ecr.RegistryCredential(ctx, api)
+		if err != nil {
+			return nil, err
+		}
+		src := &archive.RegistrySource{
+			Host:   host,
+			Client: registryHTTPClient,
+			Cred:   cred,
+			DeleteFunc: func(ctx context.Context, img archive.Image) error {
+				return ecr.DeleteImage(ctx, api, img.Repository, img.Digest)
+			},
+		}
+		s.ecr[img.Region] = src
+		return src, nil
+	case "gcp":
+		if s.gcp == nil {
+			caller, err := gcpapi.NewCaller(ctx)
+			if err != nil {
+				return nil, err
+			}
+			s.gcp = caller
+		}
+		token, err := s.gcp.Token()
+		if err != nil {
+			return nil, fmt.Errorf("GCP access token: %w", err)
+		}
+		caller := s.gcp
+		return &archive.RegistrySource{
+			Host:   img.Host,
+			Client: registryHTTPClient,
+			Cred:   dockerauth.Credential{Username: "oauth2accesstoken", Secret: token},
+			DeleteFunc: func(ctx context.Context, img archive.Image) error {
+				return artifactregistry.DeleteImage(ctx, caller, img.Host+"/"+img.Ref())
+			},
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported provider %q", img.Provider)
+	}
+}
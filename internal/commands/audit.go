@@ -0,0 +1,134 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"github.com/ppiankov/ecrspectre/internal/artifactregistry"
+	"github.com/ppiankov/ecrspectre/internal/audit"
+	"github.com/ppiankov/ecrspectre/internal/config"
+	"github.com/ppiankov/ecrspectre/internal/ecr"
+	"github.com/spf13/cobra"
+)
+
+var auditFlags struct {
+	input      string
+	profile    string
+	staleDays  int
+	format     string
+	outputFile string
+}
+
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Audit a list of image references, such as those of a release manifest",
+	Long: `Resolve every image reference in a newline-delimited file and report its
+size, storage cost, days since it was last pulled (or pushed, without pull
+data) and its critical and high vulnerabilities.
+
+References may mix ECR and Artifact Registry images, by tag or by digest.
+Images in other registries are listed as unsupported. Blank lines and lines
+starting with # are ignored; --input - reads the list from stdin. ECR uses
+--profile and GCP application default credentials. Images that could not be
+resolved are listed in the report and make the command exit 3.`,
+	Example: `  ecrspectre audit --input images.txt
+  kubectl get pods -A -o jsonpath='{range ..containers[*]}{.image}{"\n"}{end}' | ecrspectre audit --input -
+  ecrspectre audit --input release-images.txt --stale-days 30 --format json -o audit.json`,
+	RunE: runAudit,
+}
+
+func init() {
+	auditCmd.Flags().StringVar(&auditFlags.input, "input", "", "File of image references, one per line, or - for stdin (required)")
+	auditCmd.Flags().StringVar(&auditFlags.profile, "profile", "", "AWS profile name")
+	auditCmd.Flags().IntVar(&auditFlags.staleDays, "stale-days", 90, "Days without a pull (or push) after which an image is stale")
+	auditCmd.Flags().StringVar(&auditFlags.format, "format", "text", "Output format: text, json")
+	auditCmd.Flags().StringVarP(&auditFlags.outputFile, "output", "o", "", "Output file path (default: stdout)")
+}
+
+func runAudit(cmd *cobra.Command, _ []string) error {
+	if auditFlags.input == "" {
+		return configError(fmt.Errorf("--input is required"))
+	}
+	if auditFlags.format != "text" && auditFlags.format != "json" {
+		return configError(fmt.Errorf("unsupported format: %s (use text or json)", auditFlags.format))
+	}
+	if auditFlags.staleDays < 1 {
+		return configError(fmt.Errorf("--stale-days must be at least 1"))
+	}
+	expandPaths(&auditFlags.input, &auditFlags.outputFile)
+
+	var in io.Reader = cmd.InOrStdin()
+	if auditFlags.input != "-" {
+		f, err := os.Open(auditFlags.input)
+		if err != nil {
+			return configError(fmt.Errorf("open input: %w", err))
+		}
+		defer func() { _ = f.Close() }()
+		in = f
+	}
+	refs, err := audit.ReadRefs(in)
+	if err != nil {
+		return fmt.Errorf("read input: %w", err)
+	}
+	if len(refs) == 0 {
+		return configError(fmt.Errorf("%s lists no image references", auditFlags.input))
+	}
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		slog.Warn("Failed to load config file", "error", err)
+	}
+	profile := auditFlags.profile
+	if profile == "" {
+		profile = cfg.Profile
+	}
+
+	var arClients []*artifactregistry.Client
+	defer func() {
+		for _, c := range arClients {
+			_ = c.Close()
+		}
+	}()
+	auditor := audit.New(audit.Sources{
+		ECR: func(ctx context.Context, region string) (ecr.ECRAPI, error) {
+			client, err := ecr.NewClient(ctx, profile, region, "")
+			if err != nil {
+				return nil, err
+			}
+			return client.NewECRClient(), nil
+		},
+		AR: func(ctx context.Context, project string) (artifactregistry.ARAPI, error) {
+			client, err := artifactregistry.NewClient(ctx, project, "")
+			if err != nil {
+				return nil, err
+			}
+			client.SetHTTPClient(registryHTTPClient)
+			arClients = append(arClients, client)
+			return client, nil
+		},
+	}, audit.Options{StaleDays: auditFlags.staleDays})
+	images := auditor.Audit(cmd.Context(), refs)
+
+	w, closeOutput, err := openOutput(auditFlags.outputFile, profile)
+	if err != nil {
+		return err
+	}
+	if auditFlags.format == "json" {
+		err = audit.WriteJSON(w, images, auditFlags.staleDays)
+	} else {
+		err = audit.WriteText(w, images, auditFlags.staleDays)
+	}
+	if closeErr := closeOutput(); err == nil && closeErr != nil {
+		err = fmt.Errorf("close output file: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	if s := audit.Summarize(images); s.Unresolved > 0 {
+		return &ExitError{Code: ExitPartial, Err: fmt.Errorf("%d of %d images could not be resolved, see report", s.Unresolved, s.Images)}
+	}
+	return nil
+}
@@ -3,14 +3,18 @@ package commands
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
+	"path/filepath"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/ppiankov/ecrspectre/internal/analyzer"
 	"github.com/ppiankov/ecrspectre/internal/config"
 	"github.com/ppiankov/ecrspectre/internal/ecr"
+	"github.com/ppiankov/ecrspectre/internal/history"
 	"github.com/ppiankov/ecrspectre/internal/registry"
 	"github.com/ppiankov/ecrspectre/internal/report"
 	"github.com/spf13/cobra"
@@ -26,8 +30,62 @@ var awsFlags struct {
 	minMonthlyCost float64
 	includeScan    bool
 	noProgress     bool
+	dashboard      bool
+	progressFormat string
+	progressOutput string
 	timeout        time.Duration
 	excludeTags    []string
+	priorityFrom   string
+	repos          []string
+	repo           string
+	deep           bool
+	usedPlatforms  []string
+	inUseFrom      string
+	kubeconfig     string
+	maxDeployedAge string
+	historyDir     string
+	spikePercent   float64
+	kubeContexts   []string
+	attestation    string
+	attestationKey string
+	noFeaturesUsed bool
+	excludeRepos   []string
+	egressModel    string
+	incremental    bool
+	snapshotFile   string
+	snapshotMaxAge time.Duration
+	tagPriority    []string
+	keepLatest     int
+	rollupTail     bool
+	requireSigs    bool
+	requireSBOM    bool
+	sbomSeverity   string
+	endpointURL    string
+	record         string
+	replay         string
+	ignoreFile     string
+	baseline       string
+	failOn         []string
+	olderThan      string
+	newerThan      string
+	groupBy        string
+	costPeriod     string
+	top            int
+	sortBy         string
+	carbon         bool
+	roleARN        string
+	tokenFile      string
+	oidcAudience   string
+	roleDuration   time.Duration
+	untaggedLimit  int
+	selfResolving  int
+	slackWebhook   string
+	// CloudEvents webhook and its thresholds.
+	webhook         string
+	webhookWaste    float64
+	webhookSeverity string
+	// Go template rendered by --format template.
+	templateFile string
 }
 
 var awsCmd = &cobra.Command{
@@ -41,18 +99,72 @@ container images. Each finding includes an estimated monthly storage waste in US
 func init() {
 	awsCmd.Flags().StringVar(&awsFlags.region, "region", "", "AWS region (default: from AWS config)")
 	awsCmd.Flags().StringVar(&awsFlags.profile, "profile", "", "AWS profile name")
+	awsCmd.Flags().StringVar(&awsFlags.roleARN, "role-arn", "", "Assume this IAM role with a CI OIDC identity token (GitHub Actions, GitLab CI or --web-identity-token-file)")
+	awsCmd.Flags().StringVar(&awsFlags.tokenFile, "web-identity-token-file", "", "File holding the OIDC identity token for --role-arn, re-read on every renewal")
+	awsCmd.Flags().StringVar(&awsFlags.oidcAudience, "oidc-audience", "", "Audience of identity tokens requested from GitHub Actions (default: sts.amazonaws.com)")
+	awsCmd.Flags().DurationVar(&awsFlags.roleDuration, "session-duration", 0, "Session length of the --role-arn role, renewed as needed (default: the role's)")
 	awsCmd.Flags().IntVar(&awsFlags.staleDays, "stale-days", 90, "Image age threshold in days since last pull")
 	awsCmd.Flags().IntVar(&awsFlags.maxSizeMB, "max-size", 1024, "Flag images larger than this (MB)")
-	awsCmd.Flags().StringVar(&awsFlags.format, "format", "text", "Output format: text, json, sarif, spectrehub")
-	awsCmd.Flags().StringVarP(&awsFlags.outputFile, "output", "o", "", "Output file path (default: stdout)")
+	awsCmd.Flags().StringVar(&awsFlags.format, "format", "text", "Output format: text, json, yaml, csv, markdown, junit, github, prometheus, sarif, spectrehub, ocsf, template, sqlite, or dataset; several comma-separated with one --output each")
+	awsCmd.Flags().StringVarP(&awsFlags.outputFile, "output", "o", "", "Output file path, or s3://bucket/key or gs://bucket/key to upload under a date-stamped key; comma-separated, one per --format (default: stdout)")
+	awsCmd.Flags().StringVar(&awsFlags.templateFile, "template", "", "Go text/template file rendering the report for --format template")
+	awsCmd.Flags().StringVar(&awsFlags.slackWebhook, "slack-webhook", "", "Post a summary with the top findings to this Slack incoming webhook (default: $ECRSPECTRE_SLACK_WEBHOOK)")
+	awsCmd.Flags().StringVar(&awsFlags.webhook, "webhook", "", "Post scan.completed and threshold.breached CloudEvents to this URL (default: $ECRSPECTRE_WEBHOOK)")
+	awsCmd.Flags().Float64Var(&awsFlags.webhookWaste, "webhook-waste-threshold", 0, "Send threshold.breached when the total monthly waste reaches this many dollars (0 disables)")
+	awsCmd.Flags().StringVar(&awsFlags.webhookSeverity, "webhook-severity-threshold", "", "Send threshold.breached for findings at least this severe: critical, high, medium or low")
 	awsCmd.Flags().Float64Var(&awsFlags.minMonthlyCost, "min-monthly-cost", 0.10, "Minimum monthly cost to report ($)")
+	awsCmd.Flags().BoolVar(&awsFlags.rollupTail, "rollup-long-tail", false, "Roll findings under --min-monthly-cost into one LONG_TAIL_WASTE finding per repository")
+	awsCmd.Flags().BoolVar(&awsFlags.requireSigs, "require-signatures", false, "Report tagged images without a cosign or OCI referrer signature as UNSIGNED_IMAGE")
+	awsCmd.Flags().BoolVar(&awsFlags.requireSBOM, "require-sbom", false, "Report recent tagged images without an attached SBOM as MISSING_SBOM")
+	awsCmd.Flags().StringVar(&awsFlags.sbomSeverity, "sbom-severity", "", "Severity of MISSING_SBOM findings: critical, high, medium (default), or low")
 	awsCmd.Flags().BoolVar(&awsFlags.includeScan, "include-scan", false, "Include vulnerability scan data if available")
 	awsCmd.Flags().BoolVar(&awsFlags.noProgress, "no-progress", false, "Disable progress output")
+	awsCmd.Flags().BoolVar(&awsFlags.dashboard, "dashboard", false, "Show a live dashboard of scan progress, waste found and API calls on stderr (terminals only)")
+	awsCmd.Flags().StringVar(&awsFlags.progressFormat, "progress-format", "text", "Progress output format: text or ndjson (one JSON event per line)")
+	awsCmd.Flags().StringVar(&awsFlags.progressOutput, "progress-output", "", "Write progress to this file or named pipe instead of stderr")
 	awsCmd.Flags().DurationVar(&awsFlags.timeout, "timeout", 10*time.Minute, "Scan timeout")
 	awsCmd.Flags().StringSliceVar(&awsFlags.excludeTags, "exclude-tags", nil, "Exclude resources by tag (Key=Value, comma-separated)")
+	awsCmd.Flags().IntVar(&awsFlags.selfResolving, "self-resolving-days", 0, "Count waste on images the lifecycle policy will expire within N days as self-resolving instead of reporting it")
+	awsCmd.Flags().IntVar(&awsFlags.untaggedLimit, "untagged-accumulation", 10000, "Report repositories with more untagged images than this as one UNTAGGED_ACCUMULATION finding (0 disables)")
+	awsCmd.Flags().IntVar(&awsFlags.keepLatest, "keep-latest", 0, "Never report the newest N images of each tag family (e.g. v1.*) as stale")
+	awsCmd.Flags().StringSliceVar(&awsFlags.tagPriority, "tag-priority", nil, "Tag patterns preferred when naming images with several tags (e.g. 'v*,release-*'); default: highest semver")
+	awsCmd.Flags().StringSliceVar(&awsFlags.usedPlatforms, "used-platforms", nil, "Platforms the fleet runs (e.g. linux/amd64); other platforms in multi-arch images are reported as bloat")
+	awsCmd.Flags().StringVar(&awsFlags.inUseFrom, "in-use-from", "", "Downgrade findings for deployed images, collected from: aws (running ECS tasks, Lambda)")
+	awsCmd.Flags().StringVar(&awsFlags.historyDir, "history-dir", "", "Record each scan in this directory and report STORAGE_SPIKE against previous scans")
+	awsCmd.Flags().Float64Var(&awsFlags.spikePercent, "spike-percent", history.DefaultSpikePercent, "Repository growth since the previous scan (%) reported as STORAGE_SPIKE")
+	awsCmd.Flags().StringVar(&awsFlags.kubeconfig, "kubeconfig", "", "Kubeconfig whose clusters' running pod images count as in use")
+	awsCmd.Flags().StringSliceVar(&awsFlags.kubeContexts, "kube-context", nil, "Kubeconfig contexts to check (default: current context)")
+	awsCmd.Flags().BoolVar(&awsFlags.deep, "deep", false, "Fetch image manifests to report largest layers and detect duplicate layers")
+	awsCmd.Flags().StringVar(&awsFlags.maxDeployedAge, "max-deployed-age", "", "Report deployed images built longer ago than this as AGING_DEPLOYED_IMAGE (e.g. 365d); needs --deep and an in-use source")
+	awsCmd.Flags().StringVar(&awsFlags.attestation, "attestation", "", "Write an in-toto provenance attestation of the scan to this path")
+	awsCmd.Flags().BoolVar(&awsFlags.noFeaturesUsed, "no-features-used", false, "Omit the anonymous features_used list from the report")
+	awsCmd.Flags().StringVar(&awsFlags.attestationKey, "attestation-key", "", "PEM PKCS#8 Ed25519 private key used to sign the attestation (DSSE)")
+	awsCmd.Flags().StringVar(&awsFlags.repo, "repo", "", "Audit a single repository in depth (per-image breakdown, vulnerability scan, lifecycle simulation)")
+	awsCmd.Flags().StringSliceVar(&awsFlags.repos, "repos", nil, "Only scan repositories matching these globs or re:regex patterns (prefix ! to exclude)")
+	awsCmd.Flags().StringSliceVar(&awsFlags.excludeRepos, "exclude-repos", nil, "Skip repositories matching these globs or re:regex patterns")
+	awsCmd.Flags().StringVar(&awsFlags.endpointURL, "endpoint-url", "", "ECR API endpoint override for private endpoints (e.g. https://vpce-0abc-xyz.api.ecr.us-east-1.vpce.amazonaws.com)")
+	awsCmd.Flags().StringVar(&awsFlags.record, "record", "", "Record sanitized ECR API responses to this directory for a reproducible bug report")
+	awsCmd.Flags().IntVar(&awsFlags.top, "top", 0, "Report only the N worst findings (by --sort, default score); the summary still covers all")
+	awsCmd.Flags().StringVar(&awsFlags.sortBy, "sort", "", "Order findings by: score (priority score), waste, size, age (oldest first), severity, or scan (scan order)")
+	awsCmd.Flags().StringVar(&awsFlags.costPeriod, "cost-period", "", "Also report waste per day or per year: day, month, year (default: month)")
+	awsCmd.Flags().BoolVar(&awsFlags.carbon, "carbon", false, "Estimate the CO2e of wasted storage")
+	awsCmd.Flags().StringVar(&awsFlags.groupBy, "group-by", "", "Break waste down by a repository tag/label key (e.g. team, cost-center), region, or repo to nest text findings by repository")
+	awsCmd.Flags().StringVar(&awsFlags.olderThan, "older-than", "", "Only report findings on images pushed more than this long ago (e.g. 180d, 26w)")
+	awsCmd.Flags().StringVar(&awsFlags.newerThan, "newer-than", "", "Only report findings on images pushed within this long (e.g. 30d, 72h)")
+	awsCmd.Flags().StringVar(&awsFlags.ignoreFile, "ignore-file", "", "Suppression file of accepted findings (default: .ecrspectre-ignore.yaml)")
+	awsCmd.Flags().StringVar(&awsFlags.baseline, "baseline", "", "Previous JSON report; tag findings new or unchanged and exit 2 only when any are new")
+	awsCmd.Flags().StringSliceVar(&awsFlags.failOn, "fail-on", nil, "Exit 2 when findings break a threshold: severity=<level>, waste=<dollars/mo> or count=<n> (repeatable; with --baseline, new findings only)")
+	awsCmd.Flags().StringVar(&awsFlags.replay, "replay", "", "Answer ECR API calls from responses recorded with --record instead of calling AWS")
+	awsCmd.Flags().StringVar(&awsFlags.priorityFrom, "priority-from", "", "Previous JSON report used to scan the most expensive repositories first")
+	awsCmd.Flags().StringVar(&awsFlags.egressModel, "egress-model", "", "Estimate egress waste for large images from CloudWatch pull counts: inter-region, internet")
+	awsCmd.Flags().BoolVar(&awsFlags.incremental, "incremental", false, "Reuse cached inventory for repositories whose image list is unchanged")
+	awsCmd.Flags().StringVar(&awsFlags.snapshotFile, "snapshot-file", "", "Incremental snapshot path (default: user cache directory)")
+	awsCmd.Flags().DurationVar(&awsFlags.snapshotMaxAge, "snapshot-max-age", 7*24*time.Hour, "Refetch repositories whose cached incremental state is older than this")
 }
 
 func runAWS(cmd *cobra.Command, _ []string) error {
+	startedOn := time.Now()
+	runID := startRun()
 	ctx := cmd.Context()
 	if awsFlags.timeout > 0 {
 		var cancel context.CancelFunc
@@ -66,6 +178,43 @@ func runAWS(cmd *cobra.Command, _ []string) error {
 		slog.Warn("Failed to load config file", "error", err)
 	}
 	applyAWSConfigDefaults(cfg)
+	noise := thresholds{staleDays: awsFlags.staleDays, maxSizeMB: awsFlags.maxSizeMB, minMonthlyCost: awsFlags.minMonthlyCost, rollupTail: awsFlags.rollupTail}
+	warnThresholds(noise.startupWarnings())
+	expandPaths(&awsFlags.progressOutput, &awsFlags.historyDir, &awsFlags.kubeconfig, &awsFlags.priorityFrom,
+		&awsFlags.attestation, &awsFlags.attestationKey, &awsFlags.snapshotFile, &awsFlags.record, &awsFlags.replay, &awsFlags.ignoreFile, &awsFlags.tokenFile, &awsFlags.baseline)
+
+	// Check the report formats before scanning, not after.
+	if _, err := parseReportTargets(awsFlags.format, awsFlags.outputFile); err != nil {
+		return err
+	}
+	if _, err := loadReportTemplate(awsFlags.format, awsFlags.templateFile); err != nil {
+		return err
+	}
+	if err := validateEgressModel(awsFlags.egressModel); err != nil {
+		return configError(err)
+	}
+	if err := validateInUseSource(awsFlags.inUseFrom, "aws"); err != nil {
+		return configError(err)
+	}
+	if err := checkReplayFlags(awsFlags.replay,
+		liveFlag{"--egress-model", awsFlags.egressModel != ""},
+		liveFlag{"--in-use-from", awsFlags.inUseFrom != ""},
+		liveFlag{"--kubeconfig", awsFlags.kubeconfig != ""}); err != nil {
+		return err
+	}
+	webhook, err := resolveSlackWebhook(awsFlags.slackWebhook)
+	if err != nil {
+		return err
+	}
+	events, err := newEventsReporter(awsFlags.webhook, awsFlags.webhookWaste, awsFlags.webhookSeverity)
+	if err != nil {
+		return err
+	}
+	if awsFlags.endpointURL != "" {
+		if _, err := parseEndpointURL(awsFlags.endpointURL); err != nil {
+			return configError(fmt.Errorf("--endpoint-url: %w", err))
+		}
+	}
 
 	// Resolve profile
 	profile := awsFlags.profile
@@ -80,17 +229,29 @@ func runAWS(cmd *cobra.Command, _ []string) error {
 	}
 
 	// Initialize AWS client
-	client, err := ecr.NewClient(ctx, profile, region)
+	client, err := ecr.NewClient(ctx, profile, region, awsFlags.endpointURL)
 	if err != nil {
 		return enhanceError("initialize AWS client", err)
 	}
+	identity, err := useAWSWebIdentity(client)
+	if err != nil {
+		return err
+	}
 
 	resolvedRegion := client.Region()
 	if resolvedRegion == "" {
-		return fmt.Errorf("no AWS region configured; use --region or set AWS_REGION")
+		return configError(fmt.Errorf("no AWS region configured; use --region or set AWS_REGION"))
 	}
 	slog.Info("Scanning ECR", "region", resolvedRegion)
 
+	store, err := openFixtures(awsFlags.record, awsFlags.replay, ecr.FixtureCodec, ecr.FixtureSanitizers)
+	if err != nil {
+		return err
+	}
+	if !replaying(store) {
+		warnAWSCredentialExpiry(ctx, client, identity)
+	}
+
 	// Build scan config
 	excludeIDs := make(map[string]bool, len(cfg.Exclude.ResourceIDs))
 	for _, id := range cfg.Exclude.ResourceIDs {
@@ -98,63 +259,300 @@ func runAWS(cmd *cobra.Command, _ []string) error {
 	}
 	excludeTags := parseExcludeTags(cfg.Exclude.Tags, awsFlags.excludeTags)
 
+	priority, err := loadRepoPriority(awsFlags.priorityFrom)
+	if err != nil {
+		return err
+	}
+
+	repoFilter, err := buildRepoFilter(cfg, awsFlags.repos, awsFlags.excludeRepos)
+	if err != nil {
+		return configError(err)
+	}
+	protectedTags, err := registry.NewTagPatterns(cfg.ProtectedTags)
+	if err != nil {
+		return configError(fmt.Errorf("protected_tags: %w", err))
+	}
+	signedTags, err := registry.NewTagPatterns(cfg.SignedTags)
+	if err != nil {
+		return configError(fmt.Errorf("signed_tags: %w", err))
+	}
+	var sbomSeverity registry.Severity
+	if awsFlags.sbomSeverity != "" {
+		if sbomSeverity, err = registry.ParseSeverity(awsFlags.sbomSeverity); err != nil {
+			return configError(fmt.Errorf("sbom severity: %w", err))
+		}
+	}
+	userRules, err := customRules(cfg)
+	if err != nil {
+		return configError(fmt.Errorf("rules: %w", err))
+	}
+	cadences, err := releaseCadences(cfg)
+	if err != nil {
+		return configError(err)
+	}
+	windows, err := migrationWindows(cfg)
+	if err != nil {
+		return configError(err)
+	}
+	suppressions, err := loadSuppressions(awsFlags.ignoreFile)
+	if err != nil {
+		return configError(err)
+	}
+	baseline, err := loadBaseline(awsFlags.baseline)
+	if err != nil {
+		return err
+	}
+	failOn, err := parseFailOn(awsFlags.failOn)
+	if err != nil {
+		return err
+	}
+	olderThan, newerThan, err := parseAgeWindow(awsFlags.olderThan, awsFlags.newerThan)
+	if err != nil {
+		return configError(err)
+	}
+	maxDeployedAge, err := parseMaxDeployedAge(awsFlags.maxDeployedAge, awsFlags.deep, awsFlags.inUseFrom != "" || awsFlags.kubeconfig != "")
+	if err != nil {
+		return configError(err)
+	}
+	costPeriod, err := registry.ParseCostPeriod(awsFlags.costPeriod)
+	if err != nil {
+		return configError(fmt.Errorf("--cost-period: %w", err))
+	}
+	sortKey, err := analyzer.ParseSortKey(awsFlags.sortBy)
+	if err != nil {
+		return configError(fmt.Errorf("--sort: %w", err))
+	}
+	scorer, err := scoreModel(cfg.Scoring)
+	if err != nil {
+		return err
+	}
+	if awsFlags.top < 0 {
+		return configError(fmt.Errorf("--top must not be negative"))
+	}
+
 	scanCfg := registry.ScanConfig{
 		StaleDays:      awsFlags.staleDays,
 		MaxSizeBytes:   int64(awsFlags.maxSizeMB) * 1024 * 1024,
 		MinMonthlyCost: awsFlags.minMonthlyCost,
+		EgressModel:    awsFlags.egressModel,
 		Exclude: registry.ExcludeConfig{
 			ResourceIDs: excludeIDs,
 			Tags:        excludeTags,
 		},
+		RepoPriority:         priority,
+		Repos:                repoFilter,
+		DeepLayers:           awsFlags.deep,
+		MaxDeployedAge:       maxDeployedAge,
+		UsedPlatforms:        awsFlags.usedPlatforms,
+		TagPriority:          awsFlags.tagPriority,
+		KeepLatest:           awsFlags.keepLatest,
+		UntaggedAccumulation: awsFlags.untaggedLimit,
+		SelfResolvingDays:    awsFlags.selfResolving,
+		ProtectedTags:        protectedTags,
+		RequireSignatures:    awsFlags.requireSigs,
+		SignedTags:           signedTags,
+		RequireSBOM:          awsFlags.requireSBOM,
+		SBOMSeverity:         sbomSeverity,
+		Rules:                userRules,
+		ReleaseCadences:      cadences,
+		TieringMinBytes:      int64(cfg.TieringMinSizeMB) * 1024 * 1024,
+		AttributionKeys:      attributionKeys(awsFlags.groupBy),
+		DisabledChecks:       disabledChecks(cfg),
+	}
+
+	var inUseErrors []string
+	if awsFlags.inUseFrom != "" || awsFlags.kubeconfig != "" {
+		scanCfg.InUse = registry.NewInUse()
+		if awsFlags.inUseFrom == "aws" {
+			inUseErrors = collectAWSInUse(ctx, client.Config(), scanCfg.InUse)
+		}
+		if awsFlags.kubeconfig != "" {
+			inUseErrors = append(inUseErrors, collectKubeInUse(ctx, awsFlags.kubeconfig, awsFlags.kubeContexts, scanCfg.InUse)...)
+		}
+		slog.Info("Collected in-use images", "references", scanCfg.InUse.Len())
 	}
 
 	// Run scanner
-	scanner := ecr.NewECRScanner(client.NewECRClient(), resolvedRegion, awsFlags.includeScan)
+	// A single-repository audit always includes vulnerability scan data.
+	includeScan := awsFlags.includeScan || awsFlags.repo != ""
+	api := client.NewECRClient()
+	if store != nil {
+		api = ecr.WithFixtures(api, store)
+	}
+	scanner := ecr.NewECRScanner(api, resolvedRegion, includeScan)
+	if replaying(store) {
+		scanner.SetNow(store.RecordedAt())
+	}
+	if awsFlags.egressModel != "" {
+		scanner.SetPullCounter(client.NewPullCounter())
+	}
+	// Config blobs are fetched from presigned URLs, which are not recorded.
+	if scanCfg.MaxDeployedAge > 0 && !replaying(store) {
+		scanner.SetConfigFetcher(client.NewConfigFetcher(registryHTTPClient))
+	}
 
-	var progressFn func(registry.ScanProgress)
-	if !awsFlags.noProgress {
-		progressFn = func(p registry.ScanProgress) {
-			fmt.Fprintf(os.Stderr, "[%s] %s\n", p.Region, p.Message)
+	snapshotPath := awsFlags.snapshotFile
+	incremental := awsFlags.incremental && awsFlags.repo == ""
+	if incremental {
+		if snapshotPath == "" {
+			snapshotPath, err = defaultSnapshotPath("ecr", computeTargetHash("aws", []string{resolvedRegion}, profile))
+			if err != nil {
+				return err
+			}
 		}
+		scanner.SetSnapshotMaxAge(awsFlags.snapshotMaxAge)
+		scanner.EnableIncremental(loadFreshSnapshot(snapshotPath, awsFlags.snapshotMaxAge))
 	}
 
-	result := scanner.Scan(ctx, scanCfg, progressFn)
+	progress, err := newProgressSink(awsFlags.noProgress, awsFlags.progressFormat, awsFlags.progressOutput, runID, awsFlags.dashboard)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = progress.Close() }()
+	progressFn := progress.callback("")
+
+	var result *registry.ScanResult
+	if awsFlags.repo != "" {
+		result = scanner.ScanRepository(ctx, scanCfg, awsFlags.repo, progressFn)
+	} else {
+		result = scanner.Scan(ctx, scanCfg, progressFn)
+	}
+	// Give the terminal back before anything else is printed.
+	_ = progress.Close()
+	if err := scanFailedError(result, "region"); err != nil {
+		return err
+	}
+
+	result.Errors = append(result.Errors, inUseErrors...)
+
+	if incremental {
+		slog.Info("Incremental scan", "reused", scanner.ReusedRepositories(), "repositories", result.RepositoriesScanned)
+		if err := scanner.Snapshot().Save(snapshotPath); err != nil {
+			slog.Warn("Failed to save incremental snapshot", "error", err)
+		}
+	}
+
+	// Single-repository audits are not part of the scan history.
+	var historyStore *history.Store
+	var pastScans []history.Record
+	if awsFlags.repo == "" {
+		historyStore, pastScans = detectStorageSpikes(awsFlags.historyDir, computeTargetHash("aws", []string{resolvedRegion}, profile), "ecr", awsFlags.spikePercent, result)
+	}
 
 	// Analyze results
 	analysis := analyzer.Analyze(result, analyzer.AnalyzerConfig{
 		MinMonthlyCost: awsFlags.minMonthlyCost,
+		DisabledChecks: scanCfg.DisabledChecks,
+		RollupLongTail: awsFlags.rollupTail,
+		Suppressions:   suppressions,
+		Windows:        windows,
+		GroupBy:        awsFlags.groupBy,
+		CostPeriod:     costPeriod,
+		Carbon:         awsFlags.carbon,
+		Provider:       "ecr",
+		Sort:           sortKey,
+		Scorer:         scorer,
+		Top:            awsFlags.top,
+		OlderThan:      olderThan,
+		NewerThan:      newerThan,
+		Now:            scanClock(store),
 	})
+	warnExpiredSuppressions(analysis.Suppressions)
+	warnThresholds(noise.scaleWarnings(result.RepositoriesScanned, len(analysis.Findings)))
 
 	// Build report data
 	data := report.Data{
 		Tool:      "ecrspectre",
 		Version:   version,
 		Timestamp: time.Now().UTC(),
+		RunID:     runID,
 		Target: report.Target{
 			Type:    "ecr",
 			URIHash: computeTargetHash("aws", []string{resolvedRegion}, profile),
 		},
 		Config: report.ReportConfig{
-			Provider:       "aws",
-			Regions:        []string{resolvedRegion},
-			StaleDays:      awsFlags.staleDays,
-			MaxSizeMB:      awsFlags.maxSizeMB,
-			MinMonthlyCost: awsFlags.minMonthlyCost,
+			Provider:          "aws",
+			Regions:           []string{resolvedRegion},
+			StaleDays:         awsFlags.staleDays,
+			MaxSizeMB:         awsFlags.maxSizeMB,
+			MinMonthlyCost:    awsFlags.minMonthlyCost,
+			OlderThan:         awsFlags.olderThan,
+			NewerThan:         awsFlags.newerThan,
+			SelfResolvingDays: awsFlags.selfResolving,
 		},
-		Findings: analysis.Findings,
-		Summary:  analysis.Summary,
-		Errors:   analysis.Errors,
+		Findings:        analysis.Findings,
+		Summary:         analysis.Summary,
+		Errors:          analysis.Errors,
+		Repository:      result.Detail,
+		ScanStats:       registry.NewScanStats(result.Timings),
+		Suppressions:    analysis.Suppressions,
+		Recommendations: result.Recommendations,
+		Usage:           result.Usage,
+		Duration:        time.Since(startedOn),
 	}
+	if !awsFlags.noFeaturesUsed {
+		data.FeaturesUsed = featuresUsed(cmd, "aws", awsFlags.format, enabledChecks(scanCfg, includeScan))
+	}
+
+	data.Trend = historyTrend(pastScans, data.Summary)
+	data.Summary.Projection = wasteProjection(pastScans, data.Summary, data.Timestamp)
+	recordHistory(historyStore, data, analysis.Omitted, result)
+
+	baseline.mark(&data, analysis.Omitted)
 
 	// Select and run reporter
-	reporter, err := selectReporter(awsFlags.format, awsFlags.outputFile)
+	reporter, closeOutput, err := selectReporter(awsFlags.format, awsFlags.outputFile, awsFlags.templateFile, profile)
+	if err != nil {
+		return err
+	}
+	err = reporter.Generate(data)
+	if closeErr := closeOutput(); err == nil && closeErr != nil {
+		err = fmt.Errorf("close output file: %w", closeErr)
+	}
 	if err != nil {
 		return err
 	}
-	return reporter.Generate(data)
+	if err := writeAttestation(awsFlags.attestation, awsFlags.attestationKey, awsFlags.outputFile, data, startedOn); err != nil {
+		return err
+	}
+	if err := notifySlack(webhook, data); err != nil {
+		return err
+	}
+	if err := notifyEvents(events, data); err != nil {
+		return err
+	}
+	if err := findingsGate(failOn, baseline, data, analysis.Omitted); err != nil {
+		return err
+	}
+	return partialScanError(data.Errors)
 }
 
 func applyAWSConfigDefaults(cfg config.Config) {
+	if awsFlags.maxDeployedAge == "" {
+		awsFlags.maxDeployedAge = cfg.MaxDeployedAge
+	}
+	if len(awsFlags.failOn) == 0 {
+		awsFlags.failOn = cfg.FailOn
+	}
+	if awsFlags.groupBy == "" {
+		awsFlags.groupBy = cfg.GroupBy
+	}
+	if awsFlags.costPeriod == "" {
+		awsFlags.costPeriod = cfg.CostPeriod
+	}
+	if awsFlags.sortBy == "" {
+		awsFlags.sortBy = cfg.Sort
+	}
+	if !awsFlags.carbon {
+		awsFlags.carbon = cfg.Carbon
+	}
+	if awsFlags.endpointURL == "" {
+		awsFlags.endpointURL = cfg.EndpointURL
+	}
+	awsFlags.format, awsFlags.outputFile = reportsFlags(awsFlags.format, awsFlags.outputFile, cfg.Reports)
+	if awsFlags.templateFile == "" && hasFormat(awsFlags.format, "template") {
+		awsFlags.templateFile = cfg.Template
+	}
 	if awsFlags.format == "text" && cfg.Format != "" {
 		awsFlags.format = cfg.Format
 	}
@@ -167,29 +565,142 @@ func applyAWSConfigDefaults(cfg config.Config) {
 	if awsFlags.minMonthlyCost == 0.10 && cfg.MinMonthlyCost > 0 {
 		awsFlags.minMonthlyCost = cfg.MinMonthlyCost
 	}
+	if awsFlags.historyDir == "" && cfg.HistoryDir != "" {
+		awsFlags.historyDir = cfg.HistoryDir
+	}
+	if awsFlags.egressModel == "" && cfg.EgressModel != "" {
+		awsFlags.egressModel = cfg.EgressModel
+	}
+	if len(awsFlags.tagPriority) == 0 {
+		awsFlags.tagPriority = cfg.TagPriority
+	}
+	if !awsFlags.rollupTail {
+		awsFlags.rollupTail = cfg.RollupLongTail
+	}
+	if !awsFlags.requireSigs {
+		awsFlags.requireSigs = cfg.RequireSignatures
+	}
+	if !awsFlags.requireSBOM {
+		awsFlags.requireSBOM = cfg.RequireSBOM
+	}
+	if awsFlags.sbomSeverity == "" {
+		awsFlags.sbomSeverity = cfg.SBOMSeverity
+	}
+	if awsFlags.keepLatest == 0 && cfg.KeepLatest > 0 {
+		awsFlags.keepLatest = cfg.KeepLatest
+	}
+	if awsFlags.selfResolving == 0 && cfg.SelfResolvingDays > 0 {
+		awsFlags.selfResolving = cfg.SelfResolvingDays
+	}
+	if awsFlags.untaggedLimit == 10000 && cfg.UntaggedAccumulation > 0 {
+		awsFlags.untaggedLimit = cfg.UntaggedAccumulation
+	}
+}
+
+func validateEgressModel(model string) error {
+	switch model {
+	case "", "inter-region", "internet":
+		return nil
+	default:
+		return fmt.Errorf("unsupported egress model: %s (use inter-region or internet)", model)
+	}
 }
 
-func selectReporter(format, outputFile string) (report.Reporter, error) {
-	w := os.Stdout
-	if outputFile != "" {
-		f, err := os.Create(outputFile)
+func validateInUseSource(source string, allowed ...string) error {
+	if source == "" {
+		return nil
+	}
+	for _, a := range allowed {
+		if source == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported in-use source: %s (use %s)", source, strings.Join(allowed, ", "))
+}
+
+// selectReporter validates the report formats and opens their outputs. With
+// several comma-separated formats (see parseReportTargets) it returns a
+// report.MultiReporter and a function closing every output.
+func selectReporter(format, outputFile, templateFile, profile string) (report.Reporter, func() error, error) {
+	targets, err := parseReportTargets(format, outputFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl, err := loadReportTemplate(format, templateFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	var reporters report.MultiReporter
+	var closers []func() error
+	closeAll := func() error {
+		var first error
+		for _, c := range closers {
+			if err := c(); err != nil && first == nil {
+				first = err
+			}
+		}
+		return first
+	}
+	for _, t := range targets {
+		switch t.format {
+		case "sqlite":
+			// The database is read and rewritten as a whole, not streamed.
+			reporters = append(reporters, &report.SQLiteReporter{Path: t.output})
+			continue
+		case "dataset":
+			store, err := datasetStore(t.output, profile)
+			if err != nil {
+				_ = closeAll()
+				return nil, nil, err
+			}
+			reporters = append(reporters, &report.DatasetReporter{Store: store})
+			continue
+		}
+		w, closeOutput, err := openOutput(t.output, profile)
 		if err != nil {
-			return nil, fmt.Errorf("create output file: %w", err)
+			_ = closeAll()
+			return nil, nil, err
 		}
-		w = f
+		closers = append(closers, closeOutput)
+		reporters = append(reporters, newReporter(t.format, w, tmpl))
+	}
+	if len(reporters) == 1 {
+		return reporters[0], closeAll, nil
 	}
+	return reporters, closeAll, nil
+}
+
+// reportFormats are the formats newReporter accepts, and sqlite and dataset.
+var reportFormats = []string{"text", "json", "yaml", "csv", "markdown", "junit", "github", "prometheus", "sarif", "spectrehub", "ocsf", "template", "sqlite", "dataset"}
 
+// newReporter returns the reporter for a format from reportFormats. tmpl is
+// the parsed --template, used only by the template format.
+func newReporter(format string, w io.Writer, tmpl *template.Template) report.Reporter {
 	switch format {
 	case "json":
-		return &report.JSONReporter{Writer: w}, nil
-	case "text":
-		return &report.TextReporter{Writer: w}, nil
+		return &report.JSONReporter{Writer: w}
+	case "yaml":
+		return &report.YAMLReporter{Writer: w}
+	case "csv":
+		return &report.CSVReporter{Writer: w}
+	case "markdown":
+		return &report.MarkdownReporter{Writer: w}
+	case "junit":
+		return &report.JUnitReporter{Writer: w}
+	case "github":
+		return &report.GitHubReporter{Writer: w, SummaryPath: os.Getenv("GITHUB_STEP_SUMMARY")}
+	case "prometheus":
+		return &report.PrometheusReporter{Writer: w}
 	case "sarif":
-		return &report.SARIFReporter{Writer: w}, nil
+		return &report.SARIFReporter{Writer: w}
 	case "spectrehub":
-		return &report.SpectreHubReporter{Writer: w}, nil
+		return &report.SpectreHubReporter{Writer: w}
+	case "ocsf":
+		return &report.OCSFReporter{Writer: w}
+	case "template":
+		return &report.TemplateReporter{Writer: w, Template: tmpl}
 	default:
-		return nil, fmt.Errorf("unsupported format: %s (use text, json, sarif, or spectrehub)", format)
+		return &report.TextReporter{Writer: w}
 	}
 }
 
@@ -214,3 +725,37 @@ func parseExcludeTags(configTags, flagTags []string) map[string]string {
 	}
 	return tags
 }
+
+// defaultSnapshotPath returns the incremental snapshot location for a target
+// inside the user cache directory.
+func defaultSnapshotPath(provider, targetHash string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("resolve cache directory: %w", err)
+	}
+	hash := strings.TrimPrefix(targetHash, "sha256:")
+	if len(hash) > 16 {
+		hash = hash[:16]
+	}
+	return filepath.Join(dir, "ecrspectre", fmt.Sprintf("%s-%s.json", provider, hash)), nil
+}
+
+// loadFreshSnapshot loads an incremental snapshot, ignoring it when unreadable
+// or written longer than maxAge ago. Last-pull times change without new
+// digests, so a stale snapshot would hide images that stopped being pulled;
+// the scanner applies the same limit to each repository's cached state.
+func loadFreshSnapshot(path string, maxAge time.Duration) *ecr.Snapshot {
+	snap, err := ecr.LoadSnapshot(path)
+	if err != nil {
+		slog.Warn("Ignoring incremental snapshot", "path", path, "error", err)
+		return nil
+	}
+	if snap == nil {
+		return nil
+	}
+	if maxAge > 0 && time.Since(snap.Timestamp) > maxAge {
+		slog.Info("Incremental snapshot expired, running full scan", "path", path, "age", time.Since(snap.Timestamp).Round(time.Minute))
+		return nil
+	}
+	return snap
+}
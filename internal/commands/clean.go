@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/ppiankov/ecrspectre/internal/clean"
+	"github.com/ppiankov/ecrspectre/internal/ecr"
+	"github.com/ppiankov/ecrspectre/internal/logging"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+	"github.com/ppiankov/ecrspectre/internal/report"
+	"github.com/spf13/cobra"
+)
+
+var awsCleanFlags struct {
+	input      string
+	findings   []string
+	olderThan  string
+	maxPerRepo int
+	yes        bool
+	dryRun     bool
+	profile    string
+	format     string
+	outputFile string
+}
+
+var awsCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Delete the ECR images named by a report's findings (dry run unless --yes)",
+	Long: `Delete the ECR images named by findings of a spectre/v1 JSON report of an
+aws or all scan with BatchDeleteImage, and report the storage and monthly
+cost reclaimed.
+
+Nothing is deleted without --yes: by default the command lists the images it
+would delete. Before deleting, every image is checked against the registry:
+images that are gone, were tagged or pulled since the scan, or were used
+within --older-than are kept, as are deployed images, images with a
+protected tag, images in a migration window, signatures and SBOMs, and
+platform images that a multi-arch index still references. At most --max-per-repo
+images are deleted per repository, the longest unused first.`,
+	Example: `  ecrspectre aws --format json -o report.json
+  ecrspectre aws clean --input report.json
+  ecrspectre aws clean --input report.json --finding UNTAGGED_IMAGE,STALE_IMAGE --older-than 180d --yes`,
+	RunE: runAWSClean,
+}
+
+func init() {
+	awsCleanCmd.Flags().StringVar(&awsCleanFlags.input, "input", "", "JSON report of an aws or all scan (required)")
+	awsCleanCmd.Flags().StringSliceVar(&awsCleanFlags.findings, "finding", []string{string(registry.FindingUntaggedImage)}, "Finding IDs whose images are deleted")
+	awsCleanCmd.Flags().StringVar(&awsCleanFlags.olderThan, "older-than", "", "Keep images pulled, or pushed when never pulled, more recently than this (e.g. 30d)")
+	awsCleanCmd.Flags().IntVar(&awsCleanFlags.maxPerRepo, "max-per-repo", clean.DefaultMaxPerRepo, "Delete at most this many images per repository (0 for no limit)")
+	awsCleanCmd.Flags().BoolVar(&awsCleanFlags.yes, "yes", false, "Delete the images; without it the command is a dry run")
+	awsCleanCmd.Flags().BoolVar(&awsCleanFlags.dryRun, "dry-run", true, "List the images that would be deleted without deleting them; --yes turns it off")
+	awsCleanCmd.Flags().StringVar(&awsCleanFlags.profile, "profile", "", "AWS profile name")
+	awsCleanCmd.Flags().StringVar(&awsCleanFlags.format, "format", "text", "Output format: text or json")
+	awsCleanCmd.Flags().StringVarP(&awsCleanFlags.outputFile, "output", "o", "", "Output file path (default: stdout)")
+	awsCmd.AddCommand(awsCleanCmd)
+}
+
+func runAWSClean(cmd *cobra.Command, _ []string) error {
+	if awsCleanFlags.input == "" {
+		return configError(fmt.Errorf("--input is required"))
+	}
+	if awsCleanFlags.format != "text" && awsCleanFlags.format != "json" {
+		return configError(fmt.Errorf("unsupported format: %s (use text or json)", awsCleanFlags.format))
+	}
+	if awsCleanFlags.maxPerRepo < 0 {
+		return configError(fmt.Errorf("--max-per-repo must not be negative"))
+	}
+	var olderThan time.Duration
+	if awsCleanFlags.olderThan != "" {
+		var err error
+		if olderThan, err = parseAge(awsCleanFlags.olderThan); err != nil {
+			return configError(fmt.Errorf("--older-than: %w", err))
+		}
+	}
+	ids := make([]registry.FindingID, len(awsCleanFlags.findings))
+	for i, id := range awsCleanFlags.findings {
+		ids[i] = registry.FindingID(strings.ToUpper(strings.TrimSpace(id)))
+	}
+	// An explicit --dry-run wins over --yes.
+	dryRun := !awsCleanFlags.yes || (awsCleanFlags.dryRun && cmd.Flags().Changed("dry-run"))
+	expandPaths(&awsCleanFlags.input, &awsCleanFlags.outputFile)
+
+	data, err := report.ReadJSONFile(awsCleanFlags.input)
+	if err != nil {
+		return err
+	}
+	if data.Config.Provider != "aws" && data.Config.Provider != "all" {
+		return configError(fmt.Errorf("%s is a report of a %s scan; aws clean needs an aws or all scan", awsCleanFlags.input, data.Config.Provider))
+	}
+	if data.RunID != "" {
+		logging.SetRunID(data.RunID)
+	}
+
+	candidates, kept := clean.Select(data.Findings, data.Config.Provider, ids)
+	rep := clean.Run(cmd.Context(), newCleanRegistries(cmd.Context(), awsCleanFlags.profile), candidates, clean.Options{
+		OlderThan:  olderThan,
+		MaxPerRepo: awsCleanFlags.maxPerRepo,
+		ScannedAt:  data.Timestamp,
+		Now:        time.Now(),
+		DryRun:     dryRun,
+	})
+	rep.Skipped = append(kept, rep.Skipped...)
+
+	w, closeOutput, err := openOutput(awsCleanFlags.outputFile, "")
+	if err != nil {
+		return err
+	}
+	if awsCleanFlags.format == "json" {
+		err = clean.WriteJSON(w, rep)
+	} else {
+		err = clean.WriteText(w, rep)
+	}
+	if closeErr := closeOutput(); err == nil && closeErr != nil {
+		err = fmt.Errorf("close output file: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	if len(rep.Failed) > 0 {
+		return &ExitError{Code: ExitPartial, Err: fmt.Errorf("clean incomplete: %d of %d images failed", len(rep.Failed), len(rep.Deleted)+len(rep.Failed))}
+	}
+	return nil
+}
+
+// newCleanRegistries returns the ECR registry of a region, creating one
+// client per region on first use.
+func newCleanRegistries(ctx context.Context, profile string) func(string) (clean.Registry, error) {
+	registries := make(map[string]clean.Registry)
+	return func(region string) (clean.Registry, error) {
+		if r, ok := registries[region]; ok {
+			return r, nil
+		}
+		client, err := ecr.NewClient(ctx, profile, region, "")
+		if err != nil {
+			return nil, enhanceError("initialize AWS client", err)
+		}
+		r := ecrCleanRegistry{images: client.NewECRClient(), deleter: client.NewRegistryClient()}
+		registries[region] = r
+		return r, nil
+	}
+}
+
+// ecrCleanRegistry implements clean.Registry with the ECR API.
+type ecrCleanRegistry struct {
+	images  ecr.ECRAPI
+	deleter ecr.RegistryAPI
+}
+
+func (r ecrCleanRegistry) Images(ctx context.Context, repo string) (map[string]clean.Image, error) {
+	details, err := ecr.ListImages(ctx, r.images, repo)
+	if err != nil {
+		return nil, err
+	}
+	children, err := ecr.IndexChildren(ctx, r.images, repo, details)
+	if err != nil {
+		return nil, err
+	}
+	images := make(map[string]clean.Image, len(details))
+	for _, d := range details {
+		img := clean.Image{
+			Tags:     d.ImageTags,
+			Referrer: registry.IsReferrerArtifact(aws.ToString(d.ArtifactMediaType)) || aws.ToString(d.SubjectManifestDigest) != "",
+			Index:    children[aws.ToString(d.ImageDigest)],
+		}
+		if d.ImageSizeInBytes != nil {
+			img.SizeBytes = *d.ImageSizeInBytes
+		}
+		if d.ImagePushedAt != nil {
+			img.PushedAt = *d.ImagePushedAt
+		}
+		if d.LastRecordedPullTime != nil {
+			img.LastPull = *d.LastRecordedPullTime
+		}
+		if d.ImageDigest != nil {
+			images[*d.ImageDigest] = img
+		}
+	}
+	return images, nil
+}
+
+func (r ecrCleanRegistry) Delete(ctx context.Context, repo string, digests []string) (map[string]string, error) {
+	return ecr.DeleteImages(ctx, r.deleter, repo, digests)
+}
@@ -0,0 +1,74 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/ppiankov/ecrspectre/internal/analyzer"
+	"github.com/ppiankov/ecrspectre/internal/diff"
+	"github.com/ppiankov/ecrspectre/internal/report"
+	"github.com/spf13/cobra"
+)
+
+var diffFlags struct {
+	format        string
+	showUnchanged bool
+	outputFile    string
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <old.json> <new.json>",
+	Short: "Compare two reports and fail only on new findings",
+	Long: `Compare two spectre/v1 JSON reports and list the findings that are new in
+the second, resolved since the first, and unchanged. Findings are matched by
+type, region and resource. The command exits 2 when there are new findings
+and 0 otherwise, so a CI gate fails on regressions but not on the findings
+the older report already had.`,
+	Example: `  ecrspectre diff main.json pr.json
+  ecrspectre diff --format json -o diff.json baseline.json report.json`,
+	RunE: runDiff,
+}
+
+func init() {
+	diffCmd.Flags().StringVar(&diffFlags.format, "format", "text", "Output format: text or json")
+	diffCmd.Flags().BoolVar(&diffFlags.showUnchanged, "show-unchanged", false, "Also list unchanged findings in the text output")
+	diffCmd.Flags().StringVarP(&diffFlags.outputFile, "output", "o", "", "Output file path (default: stdout)")
+}
+
+func runDiff(_ *cobra.Command, args []string) error {
+	if len(args) != 2 {
+		return configError(fmt.Errorf("diff takes two reports: old.json new.json"))
+	}
+	if diffFlags.format != "text" && diffFlags.format != "json" {
+		return configError(fmt.Errorf("unsupported format: %s (use text or json)", diffFlags.format))
+	}
+	oldPath, newPath := args[0], args[1]
+	expandPaths(&oldPath, &newPath, &diffFlags.outputFile)
+
+	previous, err := report.ReadJSONFile(oldPath)
+	if err != nil {
+		return err
+	}
+	current, err := report.ReadJSONFile(newPath)
+	if err != nil {
+		return err
+	}
+	c := analyzer.CompareBaseline(previous.Findings, current.Findings)
+
+	w, closeOutput, err := openOutput(diffFlags.outputFile, "")
+	if err != nil {
+		return err
+	}
+	if diffFlags.format == "json" {
+		err = diff.WriteJSON(w, c, oldPath, newPath)
+	} else {
+		err = diff.WriteText(w, c, diffFlags.showUnchanged)
+	}
+	if closeErr := closeOutput(); err == nil && closeErr != nil {
+		err = fmt.Errorf("close output file: %w", closeErr)
+	}
+	if err != nil {
+		return err
+	}
+	summary := c.Summary()
+	return regressionError(&summary)
+}
@@ -0,0 +1,87 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/config"
+	"github.com/ppiankov/ecrspectre/internal/digest"
+	"github.com/ppiankov/ecrspectre/internal/history"
+	"github.com/spf13/cobra"
+)
+
+var digestFlags struct {
+	historyDir string
+	since      string
+	top        int
+	format     string
+	emailFrom  string
+	emailTo    string
+	outputFile string
+	carbon     bool
+}
+
+var digestCmd = &cobra.Command{
+	Use:   "digest",
+	Short: "Summarize scan history into a forwardable report",
+	Long: `Read the scan history recorded with --history-dir and summarize a period:
+the monthly waste trend, the repositories whose waste grew or shrank the most,
+and the cleanup done (deleted repositories, reclaimed storage, resolved findings).`,
+	RunE: runDigest,
+}
+
+func init() {
+	digestCmd.Flags().StringVar(&digestFlags.historyDir, "history-dir", "", "Scan history directory (default: history_dir from config)")
+	digestCmd.Flags().StringVar(&digestFlags.since, "since", "30d", "Period to summarize, ending now (e.g. 30d, 4w, 72h)")
+	digestCmd.Flags().IntVar(&digestFlags.top, "top", digest.DefaultTop, "Number of regressions and improvements to list")
+	digestCmd.Flags().StringVar(&digestFlags.format, "format", "markdown", "Output format: markdown, html, email")
+	digestCmd.Flags().StringVar(&digestFlags.emailFrom, "email-from", "", "From header for --format email")
+	digestCmd.Flags().StringVar(&digestFlags.emailTo, "email-to", "", "To header for --format email")
+	digestCmd.Flags().BoolVar(&digestFlags.carbon, "carbon", false, "Estimate the CO2e of wasted storage (default: carbon from config)")
+	digestCmd.Flags().StringVarP(&digestFlags.outputFile, "output", "o", "", "Output file path (default: stdout)")
+}
+
+func runDigest(_ *cobra.Command, _ []string) error {
+	if cfg, err := config.Load("."); err == nil {
+		if digestFlags.historyDir == "" {
+			digestFlags.historyDir = cfg.HistoryDir
+		}
+		digestFlags.carbon = digestFlags.carbon || cfg.Carbon
+	}
+	expandPaths(&digestFlags.historyDir, &digestFlags.outputFile)
+	if digestFlags.historyDir == "" {
+		return configError(fmt.Errorf("--history-dir is required (or set history_dir in config)"))
+	}
+	since, err := parseAge(digestFlags.since)
+	if err != nil {
+		return configError(fmt.Errorf("invalid --since: %w", err))
+	}
+	switch digestFlags.format {
+	case "markdown", "html", "email":
+	default:
+		return configError(fmt.Errorf("unsupported format: %s (use markdown, html or email)", digestFlags.format))
+	}
+
+	all, err := history.LoadAll(digestFlags.historyDir)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	d := digest.Build(all, now.Add(-since), now, digestFlags.top)
+	d.Carbon = digestFlags.carbon
+
+	w, closeOutput, err := openOutput(digestFlags.outputFile, "")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = closeOutput() }()
+
+	switch digestFlags.format {
+	case "html":
+		return digest.WriteHTML(w, d)
+	case "email":
+		return digest.WriteEmail(w, d, digestFlags.emailFrom, digestFlags.emailTo, now)
+	default:
+		return digest.WriteMarkdown(w, d)
+	}
+}
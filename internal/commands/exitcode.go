@@ -0,0 +1,116 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/analyzer"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// Exit codes shared by all commands so wrappers can branch on the outcome.
+const (
+	ExitOK       = 0 // completed; nothing above the fail-on threshold
+	ExitRuntime  = 1 // runtime error: credentials, API, network or I/O failure
+	ExitFindings = 2 // findings above the fail-on threshold
+	ExitPartial  = 3 // report written, but some regions or repositories failed
+	ExitConfig   = 4 // invalid flags, arguments or configuration
+)
+
+// ExitError carries the process exit code for an error returned by Execute.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode maps an error returned by Execute to a process exit code. Errors
+// without an explicit code are runtime errors.
+func ExitCode(err error) int {
+	if err == nil {
+		return ExitOK
+	}
+	var exitErr *ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.Code
+	}
+	return ExitRuntime
+}
+
+// configError marks err as an invalid flag, argument or configuration.
+func configError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &ExitError{Code: ExitConfig, Err: err}
+}
+
+// partialScanError reports a scan that produced a report despite errors.
+func partialScanError(errs []string) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ExitError{Code: ExitPartial, Err: fmt.Errorf("scan incomplete: %d error(s), see report", len(errs))}
+}
+
+// regressionError fails a scan compared with a baseline report when it found
+// anything new. Findings the baseline already had never fail it.
+func regressionError(baseline *analyzer.BaselineSummary) error {
+	if baseline == nil || baseline.New == 0 {
+		return nil
+	}
+	return &ExitError{Code: ExitFindings, Err: fmt.Errorf("%d new finding(s) since the baseline ($%.2f/mo)", baseline.New, baseline.NewMonthlyWaste)}
+}
+
+// failOnError fails a scan whose findings (scope) broke a --fail-on
+// threshold, naming each one broken.
+func failOnError(broken []string, scope string) error {
+	if len(broken) == 0 {
+		return nil
+	}
+	return &ExitError{Code: ExitFindings, Err: fmt.Errorf("%s exceed --fail-on: %s", scope, strings.Join(broken, "; "))}
+}
+
+// scanFailedError reports a scan in which every region or location (noun)
+// failed, so an empty report is not mistaken for a clean registry. Failures
+// with the same message or the same known cause, such as missing
+// credentials, are reported once with a hint; others are listed.
+func scanFailedError(result *registry.ScanResult, noun string) error {
+	if !result.AllTargetsFailed() {
+		return nil
+	}
+	failed := result.TargetErrors
+	targets := make([]string, len(failed))
+	shared := true
+	for i, e := range failed {
+		targets[i] = e.Target
+		hint := errorHint(e.Err)
+		if e.Err != failed[0].Err && (hint == "" || hint != errorHint(failed[0].Err)) {
+			shared = false
+		}
+	}
+
+	action := fmt.Sprintf("scan failed in %s %s", noun, targets[0])
+	if len(failed) > 1 {
+		action = fmt.Sprintf("scan failed in all %d %ss (%s)", len(failed), noun, strings.Join(targets, ", "))
+	}
+	if !shared {
+		lines := make([]string, len(failed))
+		for i, e := range failed {
+			lines[i] = fmt.Sprintf("  %s: %s", e.Target, e.Err)
+		}
+		return fmt.Errorf("%s:\n%s", action, strings.Join(lines, "\n"))
+	}
+	if strings.Contains(failed[0].Err, "\n  hint: ") {
+		return fmt.Errorf("%s: %s", action, failed[0].Err)
+	}
+	return enhanceError(action, errors.New(failed[0].Err))
+}
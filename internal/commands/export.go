@@ -0,0 +1,65 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/ppiankov/ecrspectre/internal/grafana"
+	"github.com/spf13/cobra"
+)
+
+var exportDashboardFlags struct {
+	title      string
+	uid        string
+	datasource string
+	top        int
+	outputFile string
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export integrations for external tools",
+}
+
+var exportDashboardCmd = &cobra.Command{
+	Use:   "grafana-dashboard",
+	Short: "Emit a Grafana dashboard over the prometheus metrics",
+	Long: `Emit a ready-to-import Grafana dashboard that charts the gauges written by
+--format prometheus: monthly waste by repository and finding, findings by
+severity, scan duration, scan errors and time since the last scan. Scrape the
+metrics with the node_exporter textfile collector from a scheduled scan, then
+import the JSON through Dashboards > New > Import or provision it from a file.`,
+	Example: `  ecrspectre export grafana-dashboard -o ecrspectre.json
+  ecrspectre export grafana-dashboard --datasource prometheus --top 20`,
+	RunE: runExportDashboard,
+}
+
+func init() {
+	exportDashboardCmd.Flags().StringVar(&exportDashboardFlags.title, "title", grafana.DefaultTitle, "Dashboard title")
+	exportDashboardCmd.Flags().StringVar(&exportDashboardFlags.uid, "uid", grafana.DefaultUID, "Dashboard UID; re-importing with the same UID replaces the dashboard")
+	exportDashboardCmd.Flags().StringVar(&exportDashboardFlags.datasource, "datasource", "", "Prometheus data source to preselect, by name or UID")
+	exportDashboardCmd.Flags().IntVar(&exportDashboardFlags.top, "top", 10, "Number of repositories in the waste-by-repository panels")
+	exportDashboardCmd.Flags().StringVarP(&exportDashboardFlags.outputFile, "output", "o", "", "Output file path (default: stdout)")
+	exportCmd.AddCommand(exportDashboardCmd)
+}
+
+func runExportDashboard(_ *cobra.Command, _ []string) error {
+	if exportDashboardFlags.top <= 0 {
+		return configError(fmt.Errorf("--top must be positive"))
+	}
+	expandPaths(&exportDashboardFlags.outputFile)
+
+	w, closeOutput, err := openOutput(exportDashboardFlags.outputFile, "")
+	if err != nil {
+		return err
+	}
+	err = grafana.Write(w, grafana.New(grafana.Options{
+		Title:      exportDashboardFlags.title,
+		UID:        exportDashboardFlags.uid,
+		Datasource: exportDashboardFlags.datasource,
+		Top:        exportDashboardFlags.top,
+	}))
+	if closeErr := closeOutput(); err == nil && closeErr != nil {
+		err = fmt.Errorf("close output file: %w", closeErr)
+	}
+	return err
+}
@@ -0,0 +1,143 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/ecr"
+	"github.com/ppiankov/ecrspectre/internal/oidc"
+)
+
+// awsOIDCAudience is the audience AWS STS expects of identity tokens.
+const awsOIDCAudience = "sts.amazonaws.com"
+
+// googleTokenLifetime is how long a federated Google access token lasts
+// before the identity token is exchanged again.
+const googleTokenLifetime = time.Hour
+
+// useAWSWebIdentity switches client to credentials of --role-arn assumed
+// with a CI identity token, or does nothing without --role-arn. It returns
+// the token source for expiry checks.
+func useAWSWebIdentity(client *ecr.Client) (*oidc.Source, error) {
+	if awsFlags.roleARN == "" {
+		if awsFlags.tokenFile != "" {
+			return nil, configError(fmt.Errorf("--web-identity-token-file requires --role-arn"))
+		}
+		return nil, nil
+	}
+	audience := awsFlags.oidcAudience
+	if audience == "" {
+		audience = awsOIDCAudience
+	}
+	src := oidc.NewSource(awsFlags.tokenFile, audience)
+	if src.Kind() == "" {
+		return nil, configError(fmt.Errorf("--role-arn: no identity token: pass --web-identity-token-file, run in GitHub Actions with id-token: write, or set %s", oidc.TokenEnv))
+	}
+	client.UseWebIdentity(awsFlags.roleARN, "ecrspectre", awsFlags.roleDuration, src)
+	slog.Info("Using web identity federation", "role", awsFlags.roleARN, "token", src.Kind())
+	return src, nil
+}
+
+// warnAWSCredentialExpiry warns when the AWS credentials, or the identity
+// token needed to renew them, expire before the scan deadline. Credentials
+// that cannot be retrieved are left for the scan to report.
+func warnAWSCredentialExpiry(ctx context.Context, client *ecr.Client, src *oidc.Source) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return
+	}
+	creds, err := client.Config().Credentials.Retrieve(ctx)
+	if err != nil || !creds.CanExpire || !creds.Expires.Before(deadline) {
+		return
+	}
+	if src == nil {
+		slog.Warn("AWS credentials expire before the scan timeout; a scan still running then fails unless their source refreshes them (in CI, use --role-arn)",
+			"expires", creds.Expires.Format(time.RFC3339), "deadline", deadline.Format(time.RFC3339))
+		return
+	}
+	warnTokenExpiry(ctx, src, deadline)
+}
+
+// useGCPWorkloadIdentity points the Google client libraries at an
+// external_account configuration for --workload-identity-provider, or does
+// nothing without it. The returned cleanup removes the configuration.
+func useGCPWorkloadIdentity(ctx context.Context) (cleanup func(), err error) {
+	cleanup = func() {}
+	if gcpFlags.wiProvider == "" {
+		if gcpFlags.tokenFile != "" || gcpFlags.serviceAccount != "" {
+			return cleanup, configError(fmt.Errorf("--web-identity-token-file and --service-account require --workload-identity-provider"))
+		}
+		return cleanup, nil
+	}
+	provider, err := oidc.GoogleProvider(gcpFlags.wiProvider)
+	if err != nil {
+		return cleanup, configError(err)
+	}
+	audience := gcpFlags.oidcAudience
+	if audience == "" {
+		audience = oidc.GoogleAudience(provider)
+	}
+	src := oidc.NewSource(gcpFlags.tokenFile, audience)
+	if src.Kind() == "" {
+		return cleanup, configError(fmt.Errorf("--workload-identity-provider: no identity token: pass --web-identity-token-file, run in GitHub Actions with id-token: write, or set %s", oidc.TokenEnv))
+	}
+
+	dir, err := os.MkdirTemp("", "ecrspectre-oidc-")
+	if err != nil {
+		return cleanup, fmt.Errorf("create credentials directory: %w", err)
+	}
+	cleanup = func() { _ = os.RemoveAll(dir) }
+	tokenFile := gcpFlags.tokenFile
+	if src.Kind() == oidc.KindEnv {
+		// The client libraries read external tokens from files or URLs only.
+		token, err := src.Token(ctx)
+		if err != nil {
+			return cleanup, err
+		}
+		tokenFile = filepath.Join(dir, "token")
+		if err := os.WriteFile(tokenFile, []byte(token), 0o600); err != nil {
+			return cleanup, fmt.Errorf("write identity token: %w", err)
+		}
+	}
+	creds, err := src.GoogleCredentials(provider, gcpFlags.serviceAccount, tokenFile)
+	if err != nil {
+		return cleanup, err
+	}
+	path := filepath.Join(dir, "credentials.json")
+	if err := os.WriteFile(path, creds, 0o600); err != nil {
+		return cleanup, fmt.Errorf("write Google credentials: %w", err)
+	}
+	if err := os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", path); err != nil {
+		return cleanup, fmt.Errorf("set GOOGLE_APPLICATION_CREDENTIALS: %w", err)
+	}
+	slog.Info("Using workload identity federation", "provider", provider, "token", src.Kind())
+
+	if deadline, ok := ctx.Deadline(); ok && time.Now().Add(googleTokenLifetime).Before(deadline) {
+		warnTokenExpiry(ctx, src, deadline)
+	}
+	return cleanup, nil
+}
+
+// warnTokenExpiry warns when credentials must be renewed before deadline but
+// the identity token they are renewed with expires first. Tokens issued anew
+// on every request (GitHub Actions) never do.
+func warnTokenExpiry(ctx context.Context, src *oidc.Source, deadline time.Time) {
+	if src.Renews() {
+		return
+	}
+	token, err := src.Token(ctx)
+	if err != nil {
+		slog.Warn("Cannot read the identity token", "error", err)
+		return
+	}
+	expires, err := oidc.Expiry(token)
+	if err != nil || !expires.Before(deadline) {
+		return
+	}
+	slog.Warn("The identity token expires before the scan timeout; renewing credentials after then fails unless the token is replaced",
+		"token", src.Kind(), "expires", expires.Format(time.RFC3339), "deadline", deadline.Format(time.RFC3339))
+}
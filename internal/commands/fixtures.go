@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/fixtures"
+)
+
+// openFixtures opens the fixture store for --record or --replay, or returns
+// nil when neither is set.
+func openFixtures(record, replay string, codec fixtures.ErrorCodec, sanitize []fixtures.Replacement) (*fixtures.Store, error) {
+	switch {
+	case record != "" && replay != "":
+		return nil, configError(fmt.Errorf("--record and --replay cannot be combined"))
+	case record != "":
+		return fixtures.NewRecorder(record, codec, sanitize...)
+	case replay != "":
+		store, err := fixtures.NewReplayer(replay, codec, sanitize...)
+		if err != nil {
+			return nil, configError(fmt.Errorf("--replay: %w", err))
+		}
+		return store, nil
+	}
+	return nil, nil
+}
+
+// liveFlag is a scan option that calls a cloud API --record does not capture.
+type liveFlag struct {
+	name string
+	set  bool
+}
+
+// checkReplayFlags rejects --replay combined with any of flags, which would
+// mix live API calls into an offline replay.
+func checkReplayFlags(replay string, flags ...liveFlag) error {
+	if replay == "" {
+		return nil
+	}
+	for _, f := range flags {
+		if f.set {
+			return configError(fmt.Errorf("--replay cannot be combined with %s: its API calls are not recorded", f.name))
+		}
+	}
+	return nil
+}
+
+// replaying reports whether store answers calls from recorded fixtures.
+func replaying(store *fixtures.Store) bool {
+	return store != nil && store.Mode() == fixtures.Replay
+}
+
+// scanClock returns the recording time of a replayed scan so suppression
+// expiry matches the recording, or the zero time (now) otherwise.
+func scanClock(store *fixtures.Store) time.Time {
+	if replaying(store) {
+		return store.RecordedAt()
+	}
+	return time.Time{}
+}
@@ -4,60 +4,183 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
-	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/ppiankov/ecrspectre/internal/analyzer"
 	"github.com/ppiankov/ecrspectre/internal/artifactregistry"
 	"github.com/ppiankov/ecrspectre/internal/config"
+	"github.com/ppiankov/ecrspectre/internal/fixtures"
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+	"github.com/ppiankov/ecrspectre/internal/history"
 	"github.com/ppiankov/ecrspectre/internal/registry"
 	"github.com/ppiankov/ecrspectre/internal/report"
 	"github.com/spf13/cobra"
 )
 
 var gcpFlags struct {
-	project        string
-	locations      []string
-	staleDays      int
-	maxSizeMB      int
-	format         string
-	outputFile     string
-	minMonthlyCost float64
-	noProgress     bool
-	timeout        time.Duration
-	excludeTags    []string
+	projects             []string
+	folders              []string
+	organizations        []string
+	locations            []string
+	staleDays            int
+	maxSizeMB            int
+	format               string
+	outputFile           string
+	minMonthlyCost       float64
+	noProgress           bool
+	dashboard            bool
+	progressFormat       string
+	progressOutput       string
+	timeout              time.Duration
+	excludeTags          []string
+	priorityFrom         string
+	repos                []string
+	repo                 string
+	deep                 bool
+	usedPlatforms        []string
+	tagPriority          []string
+	keepLatest           int
+	rollupTail           bool
+	requireSigs          bool
+	requireSBOM          bool
+	sbomSeverity         string
+	auditLogPulls        string
+	includeScan          bool
+	inUseFrom            string
+	kubeconfig           string
+	maxDeployedAge       string
+	historyDir           string
+	spikePercent         float64
+	quotaGB              float64
+	projectQuotaGB       float64
+	quotaThreshold       float64
+	kubeContexts         []string
+	attestation          string
+	attestationKey       string
+	noFeaturesUsed       bool
+	excludeRepos         []string
+	record               string
+	replay               string
+	endpointURL          string
+	ignoreFile           string
+	baseline             string
+	failOn               []string
+	olderThan            string
+	newerThan            string
+	groupBy              string
+	costPeriod           string
+	top                  int
+	sortBy               string
+	carbon               bool
+	wiProvider           string
+	serviceAccount       string
+	tokenFile            string
+	oidcAudience         string
+	slackWebhook         string
+	projectLabels        []string
+	excludeProjectLabels []string
+	// CloudEvents webhook and its thresholds.
+	webhook         string
+	webhookWaste    float64
+	webhookSeverity string
+	// Go template rendered by --format template.
+	templateFile string
 }
 
+// gcpProjectConcurrency bounds how many projects are scanned at once.
+const gcpProjectConcurrency = 4
+
 var gcpCmd = &cobra.Command{
 	Use:   "gcp",
 	Short: "Audit GCP Artifact Registry repositories for waste",
-	Long: `Scan all Artifact Registry repositories in a GCP project for stale, untagged,
-and oversized container images. Each finding includes an estimated monthly storage waste in USD.
+	Long: `Scan all Artifact Registry repositories in one or more GCP projects for stale,
+untagged, and oversized container images. Each finding includes an estimated monthly
+storage waste in USD.
+
+Projects are scanned concurrently. --folder and --organization discover every active
+project below them that has the Artifact Registry API enabled.
 
 Note: GCP Artifact Registry does not provide pull timestamps, so stale detection
-is based on upload time only. Lifecycle policies and vulnerability scans are
-ECR-only features and are not checked for GCP.`,
+is based on upload time unless --audit-log-pulls reads pulls from Data Access
+audit logs. Repositories whose cleanup policies never delete images (none
+configured, keep-only, or dry-run) are reported as NO_LIFECYCLE_POLICY.
+--include-scan reports VULNERABLE_IMAGE from Container Analysis occurrences.`,
 	RunE: runGCP,
 }
 
 func init() {
-	gcpCmd.Flags().StringVar(&gcpFlags.project, "project", "", "GCP project ID (required)")
+	gcpCmd.Flags().StringSliceVar(&gcpFlags.projects, "project", nil, "GCP project IDs to scan (repeatable)")
+	gcpCmd.Flags().StringSliceVar(&gcpFlags.folders, "folder", nil, "Scan every project under these folder IDs with Artifact Registry enabled")
+	gcpCmd.Flags().StringSliceVar(&gcpFlags.organizations, "organization", nil, "Scan every project in these organization IDs with Artifact Registry enabled")
+	gcpCmd.Flags().StringSliceVar(&gcpFlags.projectLabels, "project-labels", nil, "Only scan projects with these labels (key=value or key)")
+	gcpCmd.Flags().StringSliceVar(&gcpFlags.excludeProjectLabels, "exclude-project-labels", nil, "Skip projects with any of these labels (key=value or key)")
 	gcpCmd.Flags().StringSliceVar(&gcpFlags.locations, "locations", nil, "Comma-separated location filter (e.g., us-central1,europe-west1)")
 	gcpCmd.Flags().IntVar(&gcpFlags.staleDays, "stale-days", 90, "Image age threshold in days since upload")
 	gcpCmd.Flags().IntVar(&gcpFlags.maxSizeMB, "max-size", 1024, "Flag images larger than this (MB)")
-	gcpCmd.Flags().StringVar(&gcpFlags.format, "format", "text", "Output format: text, json, sarif, spectrehub")
-	gcpCmd.Flags().StringVarP(&gcpFlags.outputFile, "output", "o", "", "Output file path (default: stdout)")
+	gcpCmd.Flags().StringVar(&gcpFlags.format, "format", "text", "Output format: text, json, yaml, csv, markdown, junit, github, prometheus, sarif, spectrehub, ocsf, template, sqlite, or dataset; several comma-separated with one --output each")
+	gcpCmd.Flags().StringVarP(&gcpFlags.outputFile, "output", "o", "", "Output file path, or s3://bucket/key or gs://bucket/key to upload under a date-stamped key; comma-separated, one per --format (default: stdout)")
+	gcpCmd.Flags().StringVar(&gcpFlags.templateFile, "template", "", "Go text/template file rendering the report for --format template")
+	gcpCmd.Flags().StringVar(&gcpFlags.slackWebhook, "slack-webhook", "", "Post a summary with the top findings to this Slack incoming webhook (default: $ECRSPECTRE_SLACK_WEBHOOK)")
+	gcpCmd.Flags().StringVar(&gcpFlags.webhook, "webhook", "", "Post scan.completed and threshold.breached CloudEvents to this URL (default: $ECRSPECTRE_WEBHOOK)")
+	gcpCmd.Flags().Float64Var(&gcpFlags.webhookWaste, "webhook-waste-threshold", 0, "Send threshold.breached when the total monthly waste reaches this many dollars (0 disables)")
+	gcpCmd.Flags().StringVar(&gcpFlags.webhookSeverity, "webhook-severity-threshold", "", "Send threshold.breached for findings at least this severe: critical, high, medium or low")
 	gcpCmd.Flags().Float64Var(&gcpFlags.minMonthlyCost, "min-monthly-cost", 0.10, "Minimum monthly cost to report ($)")
+	gcpCmd.Flags().BoolVar(&gcpFlags.rollupTail, "rollup-long-tail", false, "Roll findings under --min-monthly-cost into one LONG_TAIL_WASTE finding per repository")
+	gcpCmd.Flags().BoolVar(&gcpFlags.requireSigs, "require-signatures", false, "Report tagged images without a cosign or OCI referrer signature as UNSIGNED_IMAGE")
+	gcpCmd.Flags().BoolVar(&gcpFlags.requireSBOM, "require-sbom", false, "Report recent tagged images without an attached SBOM as MISSING_SBOM")
+	gcpCmd.Flags().StringVar(&gcpFlags.sbomSeverity, "sbom-severity", "", "Severity of MISSING_SBOM findings: critical, high, medium (default), or low")
+	gcpCmd.Flags().BoolVar(&gcpFlags.includeScan, "include-scan", false, "Include Container Analysis vulnerability data if available")
 	gcpCmd.Flags().BoolVar(&gcpFlags.noProgress, "no-progress", false, "Disable progress output")
+	gcpCmd.Flags().BoolVar(&gcpFlags.dashboard, "dashboard", false, "Show a live dashboard of scan progress, waste found and API calls on stderr (terminals only)")
+	gcpCmd.Flags().StringVar(&gcpFlags.progressFormat, "progress-format", "text", "Progress output format: text or ndjson (one JSON event per line)")
+	gcpCmd.Flags().StringVar(&gcpFlags.progressOutput, "progress-output", "", "Write progress to this file or named pipe instead of stderr")
 	gcpCmd.Flags().DurationVar(&gcpFlags.timeout, "timeout", 10*time.Minute, "Scan timeout")
 	gcpCmd.Flags().StringSliceVar(&gcpFlags.excludeTags, "exclude-tags", nil, "Exclude resources by label (Key=Value, comma-separated)")
+	gcpCmd.Flags().IntVar(&gcpFlags.keepLatest, "keep-latest", 0, "Never report the newest N images of each tag family (e.g. v1.*) as stale")
+	gcpCmd.Flags().StringSliceVar(&gcpFlags.tagPriority, "tag-priority", nil, "Tag patterns preferred when naming images with several tags (e.g. 'v*,release-*'); default: highest semver")
+	gcpCmd.Flags().StringSliceVar(&gcpFlags.usedPlatforms, "used-platforms", nil, "Platforms the fleet runs (e.g. linux/amd64); other platforms in multi-arch images are reported as bloat")
+	gcpCmd.Flags().Float64Var(&gcpFlags.quotaGB, "quota-gb", 0, "Per-repository storage quota in GB; emits QUOTA_PRESSURE near the limit")
+	gcpCmd.Flags().Float64Var(&gcpFlags.projectQuotaGB, "project-quota-gb", 0, "Project storage quota in GB across scanned locations")
+	gcpCmd.Flags().Float64Var(&gcpFlags.quotaThreshold, "quota-threshold", registry.DefaultQuotaThresholdPercent, "Quota usage percentage that triggers QUOTA_PRESSURE")
+	gcpCmd.Flags().StringVar(&gcpFlags.historyDir, "history-dir", "", "Record each scan in this directory and report STORAGE_SPIKE against previous scans")
+	gcpCmd.Flags().Float64Var(&gcpFlags.spikePercent, "spike-percent", history.DefaultSpikePercent, "Repository growth since the previous scan (%) reported as STORAGE_SPIKE")
+	gcpCmd.Flags().StringVar(&gcpFlags.inUseFrom, "in-use-from", "", "Exclude deployed images from stale findings, collected from: gcp (serving Cloud Run revisions, GKE pods)")
+	gcpCmd.Flags().StringVar(&gcpFlags.auditLogPulls, "audit-log-pulls", "", "Measure staleness from the last pull found in Data Access audit logs over this window (e.g. 30d)")
+	gcpCmd.Flags().StringVar(&gcpFlags.kubeconfig, "kubeconfig", "", "Kubeconfig whose clusters' running pod images count as in use")
+	gcpCmd.Flags().StringSliceVar(&gcpFlags.kubeContexts, "kube-context", nil, "Kubeconfig contexts to check (default: current context)")
+	gcpCmd.Flags().BoolVar(&gcpFlags.deep, "deep", false, "Fetch image manifests to report largest layers and detect duplicate layers")
+	gcpCmd.Flags().StringVar(&gcpFlags.maxDeployedAge, "max-deployed-age", "", "Report deployed images built longer ago than this as AGING_DEPLOYED_IMAGE (e.g. 365d); needs --deep and an in-use source")
+	gcpCmd.Flags().StringVar(&gcpFlags.attestation, "attestation", "", "Write an in-toto provenance attestation of the scan to this path")
+	gcpCmd.Flags().BoolVar(&gcpFlags.noFeaturesUsed, "no-features-used", false, "Omit the anonymous features_used list from the report")
+	gcpCmd.Flags().StringVar(&gcpFlags.attestationKey, "attestation-key", "", "PEM PKCS#8 Ed25519 private key used to sign the attestation (DSSE)")
+	gcpCmd.Flags().StringVar(&gcpFlags.repo, "repo", "", "Audit a single repository in depth (per-image breakdown, vulnerability scan)")
+	gcpCmd.Flags().StringSliceVar(&gcpFlags.repos, "repos", nil, "Only scan repositories matching these globs or re:regex patterns (prefix ! to exclude)")
+	gcpCmd.Flags().StringSliceVar(&gcpFlags.excludeRepos, "exclude-repos", nil, "Skip repositories matching these globs or re:regex patterns")
+	gcpCmd.Flags().StringVar(&gcpFlags.record, "record", "", "Record sanitized Artifact Registry API responses to this directory for a reproducible bug report")
+	gcpCmd.Flags().StringVar(&gcpFlags.wiProvider, "workload-identity-provider", "", "Authenticate with a CI OIDC identity token through this workload identity pool provider (projects/N/locations/global/workloadIdentityPools/POOL/providers/PROVIDER)")
+	gcpCmd.Flags().StringVar(&gcpFlags.serviceAccount, "service-account", "", "Service account to impersonate with --workload-identity-provider")
+	gcpCmd.Flags().StringVar(&gcpFlags.tokenFile, "web-identity-token-file", "", "File holding the OIDC identity token for --workload-identity-provider")
+	gcpCmd.Flags().StringVar(&gcpFlags.oidcAudience, "oidc-audience", "", "Audience of identity tokens requested from GitHub Actions (default: the provider's)")
+	gcpCmd.Flags().IntVar(&gcpFlags.top, "top", 0, "Report only the N worst findings (by --sort, default score); the summary still covers all")
+	gcpCmd.Flags().StringVar(&gcpFlags.sortBy, "sort", "", "Order findings by: score (priority score), waste, size, age (oldest first), severity, or scan (scan order)")
+	gcpCmd.Flags().StringVar(&gcpFlags.costPeriod, "cost-period", "", "Also report waste per day or per year: day, month, year (default: month)")
+	gcpCmd.Flags().BoolVar(&gcpFlags.carbon, "carbon", false, "Estimate the CO2e of wasted storage")
+	gcpCmd.Flags().StringVar(&gcpFlags.groupBy, "group-by", "", "Break waste down by a repository tag/label key (e.g. team, cost-center), region, or repo to nest text findings by repository")
+	gcpCmd.Flags().StringVar(&gcpFlags.olderThan, "older-than", "", "Only report findings on images pushed more than this long ago (e.g. 180d, 26w)")
+	gcpCmd.Flags().StringVar(&gcpFlags.newerThan, "newer-than", "", "Only report findings on images pushed within this long (e.g. 30d, 72h)")
+	gcpCmd.Flags().StringVar(&gcpFlags.ignoreFile, "ignore-file", "", "Suppression file of accepted findings (default: .ecrspectre-ignore.yaml)")
+	gcpCmd.Flags().StringVar(&gcpFlags.baseline, "baseline", "", "Previous JSON report; tag findings new or unchanged and exit 2 only when any are new")
+	gcpCmd.Flags().StringSliceVar(&gcpFlags.failOn, "fail-on", nil, "Exit 2 when findings break a threshold: severity=<level>, waste=<dollars/mo> or count=<n> (repeatable; with --baseline, new findings only)")
+	gcpCmd.Flags().StringVar(&gcpFlags.replay, "replay", "", "Answer Artifact Registry API calls from responses recorded with --record instead of calling GCP")
+	gcpCmd.Flags().StringVar(&gcpFlags.endpointURL, "endpoint-url", "", "Artifact Registry API endpoint override for private endpoints (e.g. https://artifactregistry-myendpoint.p.googleapis.com)")
+	gcpCmd.Flags().StringVar(&gcpFlags.priorityFrom, "priority-from", "", "Previous JSON report used to scan the most expensive repositories first")
 }
 
 func runGCP(cmd *cobra.Command, _ []string) error {
-	if gcpFlags.project == "" {
-		return fmt.Errorf("--project is required for GCP scans")
-	}
-
+	startedOn := time.Now()
+	runID := startRun()
 	ctx := cmd.Context()
 	if gcpFlags.timeout > 0 {
 		var cancel context.CancelFunc
@@ -71,6 +194,52 @@ func runGCP(cmd *cobra.Command, _ []string) error {
 		slog.Warn("Failed to load config file", "error", err)
 	}
 	applyGCPConfigDefaults(cfg)
+	noise := thresholds{staleDays: gcpFlags.staleDays, maxSizeMB: gcpFlags.maxSizeMB, minMonthlyCost: gcpFlags.minMonthlyCost, rollupTail: gcpFlags.rollupTail}
+	warnThresholds(noise.startupWarnings())
+	expandPaths(&gcpFlags.progressOutput, &gcpFlags.historyDir, &gcpFlags.kubeconfig, &gcpFlags.priorityFrom,
+		&gcpFlags.attestation, &gcpFlags.attestationKey, &gcpFlags.record, &gcpFlags.replay, &gcpFlags.ignoreFile, &gcpFlags.tokenFile, &gcpFlags.baseline)
+	// Check the report formats before scanning, not after.
+	if _, err := parseReportTargets(gcpFlags.format, gcpFlags.outputFile); err != nil {
+		return err
+	}
+	if _, err := loadReportTemplate(gcpFlags.format, gcpFlags.templateFile); err != nil {
+		return err
+	}
+	if len(gcpFlags.projects) == 0 && len(gcpFlags.folders) == 0 && len(gcpFlags.organizations) == 0 {
+		return configError(fmt.Errorf("--project (or --folder / --organization) is required for GCP scans"))
+	}
+	if err := validateInUseSource(gcpFlags.inUseFrom, "gcp"); err != nil {
+		return configError(err)
+	}
+	if err := checkReplayFlags(gcpFlags.replay,
+		liveFlag{"--in-use-from", gcpFlags.inUseFrom != ""},
+		liveFlag{"--kubeconfig", gcpFlags.kubeconfig != ""},
+		liveFlag{"--audit-log-pulls", gcpFlags.auditLogPulls != ""}); err != nil {
+		return err
+	}
+	webhook, err := resolveSlackWebhook(gcpFlags.slackWebhook)
+	if err != nil {
+		return err
+	}
+	events, err := newEventsReporter(gcpFlags.webhook, gcpFlags.webhookWaste, gcpFlags.webhookSeverity)
+	if err != nil {
+		return err
+	}
+	projectLabels, err := labelSelector(cfg.Scope.ProjectLabels, cfg.Scope.ExcludeProjectLabels, gcpFlags.projectLabels, gcpFlags.excludeProjectLabels)
+	if err != nil {
+		return configError(fmt.Errorf("project labels: %w", err))
+	}
+	if gcpFlags.endpointURL != "" {
+		if _, err := parseEndpointURL(gcpFlags.endpointURL); err != nil {
+			return configError(fmt.Errorf("--endpoint-url: %w", err))
+		}
+	}
+	var pullWindow time.Duration
+	if gcpFlags.auditLogPulls != "" {
+		if pullWindow, err = parseAge(gcpFlags.auditLogPulls); err != nil {
+			return configError(fmt.Errorf("--audit-log-pulls: %w", err))
+		}
+	}
 
 	// Resolve locations
 	locations := gcpFlags.locations
@@ -78,17 +247,49 @@ func runGCP(cmd *cobra.Command, _ []string) error {
 		locations = cfg.Regions
 	}
 	if len(locations) == 0 {
-		return fmt.Errorf("--locations is required (e.g., us-central1,europe-west1)")
+		return configError(fmt.Errorf("--locations is required (e.g., us-central1,europe-west1)"))
 	}
 
-	slog.Info("Scanning Artifact Registry", "project", gcpFlags.project, "locations", locations)
+	cleanupCredentials, err := useGCPWorkloadIdentity(ctx)
+	defer cleanupCredentials()
+	if err != nil {
+		return err
+	}
 
-	// Initialize client
-	client, err := artifactregistry.NewClient(ctx, gcpFlags.project)
+	projects, discoveryErrors, err := resolveGCPProjects(ctx)
+	if err != nil {
+		return enhanceError("discover GCP projects", err)
+	}
+	if len(projects) == 0 {
+		return configError(fmt.Errorf("no projects with Artifact Registry enabled found"))
+	}
+	if !projectLabels.Empty() {
+		api, err := gcpapi.NewCaller(ctx)
+		if err != nil {
+			return enhanceError("read GCP project labels", err)
+		}
+		var scopeErrors []string
+		projects, scopeErrors = selectProjects(ctx, api, projects, projectLabels)
+		discoveryErrors = append(discoveryErrors, scopeErrors...)
+		if len(projects) == 0 {
+			if len(scopeErrors) > 0 {
+				return fmt.Errorf("no projects in scope: %s", strings.Join(scopeErrors, "; "))
+			}
+			return configError(fmt.Errorf("no projects match the project labels %s", projectLabels))
+		}
+	}
+	if gcpFlags.repo != "" && len(projects) > 1 {
+		return configError(fmt.Errorf("--repo requires a single --project"))
+	}
+	if (gcpFlags.record != "" || gcpFlags.replay != "") && len(projects) > 1 {
+		return configError(fmt.Errorf("--record and --replay require a single --project"))
+	}
+	store, err := openFixtures(gcpFlags.record, gcpFlags.replay, fixtures.ErrorCodec{}, artifactregistry.FixtureSanitizers(projects[0]))
 	if err != nil {
-		return enhanceError("initialize GCP client", err)
+		return err
 	}
-	defer func() { _ = client.Close() }()
+
+	slog.Info("Scanning Artifact Registry", "projects", projects, "locations", locations)
 
 	// Build scan config
 	excludeIDs := make(map[string]bool, len(cfg.Exclude.ResourceIDs))
@@ -97,6 +298,77 @@ func runGCP(cmd *cobra.Command, _ []string) error {
 	}
 	excludeTags := parseExcludeTags(cfg.Exclude.Tags, gcpFlags.excludeTags)
 
+	priority, err := loadRepoPriority(gcpFlags.priorityFrom)
+	if err != nil {
+		return err
+	}
+
+	repoFilter, err := buildRepoFilter(cfg, gcpFlags.repos, gcpFlags.excludeRepos)
+	if err != nil {
+		return configError(err)
+	}
+	protectedTags, err := registry.NewTagPatterns(cfg.ProtectedTags)
+	if err != nil {
+		return configError(fmt.Errorf("protected_tags: %w", err))
+	}
+	signedTags, err := registry.NewTagPatterns(cfg.SignedTags)
+	if err != nil {
+		return configError(fmt.Errorf("signed_tags: %w", err))
+	}
+	var sbomSeverity registry.Severity
+	if gcpFlags.sbomSeverity != "" {
+		if sbomSeverity, err = registry.ParseSeverity(gcpFlags.sbomSeverity); err != nil {
+			return configError(fmt.Errorf("sbom severity: %w", err))
+		}
+	}
+	userRules, err := customRules(cfg)
+	if err != nil {
+		return configError(fmt.Errorf("rules: %w", err))
+	}
+	cadences, err := releaseCadences(cfg)
+	if err != nil {
+		return configError(err)
+	}
+	windows, err := migrationWindows(cfg)
+	if err != nil {
+		return configError(err)
+	}
+	suppressions, err := loadSuppressions(gcpFlags.ignoreFile)
+	if err != nil {
+		return configError(err)
+	}
+	baseline, err := loadBaseline(gcpFlags.baseline)
+	if err != nil {
+		return err
+	}
+	failOn, err := parseFailOn(gcpFlags.failOn)
+	if err != nil {
+		return err
+	}
+	olderThan, newerThan, err := parseAgeWindow(gcpFlags.olderThan, gcpFlags.newerThan)
+	if err != nil {
+		return configError(err)
+	}
+	maxDeployedAge, err := parseMaxDeployedAge(gcpFlags.maxDeployedAge, gcpFlags.deep, gcpFlags.inUseFrom != "" || gcpFlags.kubeconfig != "")
+	if err != nil {
+		return configError(err)
+	}
+	costPeriod, err := registry.ParseCostPeriod(gcpFlags.costPeriod)
+	if err != nil {
+		return configError(fmt.Errorf("--cost-period: %w", err))
+	}
+	sortKey, err := analyzer.ParseSortKey(gcpFlags.sortBy)
+	if err != nil {
+		return configError(fmt.Errorf("--sort: %w", err))
+	}
+	scorer, err := scoreModel(cfg.Scoring)
+	if err != nil {
+		return err
+	}
+	if gcpFlags.top < 0 {
+		return configError(fmt.Errorf("--top must not be negative"))
+	}
+
 	scanCfg := registry.ScanConfig{
 		StaleDays:      gcpFlags.staleDays,
 		MaxSizeBytes:   int64(gcpFlags.maxSizeMB) * 1024 * 1024,
@@ -105,33 +377,102 @@ func runGCP(cmd *cobra.Command, _ []string) error {
 			ResourceIDs: excludeIDs,
 			Tags:        excludeTags,
 		},
+		RepoPriority:      priority,
+		Repos:             repoFilter,
+		DeepLayers:        gcpFlags.deep,
+		MaxDeployedAge:    maxDeployedAge,
+		UsedPlatforms:     gcpFlags.usedPlatforms,
+		Quota:             buildQuota(cfg.Quota),
+		TagPriority:       gcpFlags.tagPriority,
+		KeepLatest:        gcpFlags.keepLatest,
+		ProtectedTags:     protectedTags,
+		RequireSignatures: gcpFlags.requireSigs,
+		SignedTags:        signedTags,
+		RequireSBOM:       gcpFlags.requireSBOM,
+		SBOMSeverity:      sbomSeverity,
+		Rules:             userRules,
+		ReleaseCadences:   cadences,
+		TieringMinBytes:   int64(cfg.TieringMinSizeMB) * 1024 * 1024,
+		AttributionKeys:   attributionKeys(gcpFlags.groupBy),
+		DisabledChecks:    disabledChecks(cfg),
 	}
 
-	// Run scanner
-	scanner := artifactregistry.NewARScanner(client, gcpFlags.project, locations)
-
-	var progressFn func(registry.ScanProgress)
-	if !gcpFlags.noProgress {
-		progressFn = func(p registry.ScanProgress) {
-			fmt.Fprintf(os.Stderr, "[%s] %s\n", p.Region, p.Message)
+	// Images deployed in any scanned project count as in use in all of them.
+	var enrichErrors []string
+	if gcpFlags.inUseFrom != "" || gcpFlags.kubeconfig != "" {
+		scanCfg.InUse = registry.NewInUse()
+		if gcpFlags.inUseFrom == "gcp" {
+			for _, project := range projects {
+				enrichErrors = append(enrichErrors, collectGCPInUse(ctx, project, scanCfg.InUse)...)
+			}
+		}
+		if gcpFlags.kubeconfig != "" {
+			enrichErrors = append(enrichErrors, collectKubeInUse(ctx, gcpFlags.kubeconfig, gcpFlags.kubeContexts, scanCfg.InUse)...)
+		}
+		slog.Info("Collected in-use images", "references", scanCfg.InUse.Len())
+	}
+	if pullWindow > 0 {
+		scanCfg.Pulls = registry.NewPullLog(time.Now().Add(-pullWindow))
+		for _, project := range projects {
+			enrichErrors = append(enrichErrors, collectAuditLogPulls(ctx, project, scanCfg.Pulls)...)
 		}
+		slog.Info("Collected image pulls from audit logs", "references", scanCfg.Pulls.Len())
 	}
 
-	result := scanner.Scan(ctx, scanCfg, progressFn)
+	// A single-repository audit always includes vulnerability scan data.
+	includeScan := gcpFlags.includeScan || gcpFlags.repo != ""
+	progress, err := newProgressSink(gcpFlags.noProgress, gcpFlags.progressFormat, gcpFlags.progressOutput, runID, gcpFlags.dashboard)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = progress.Close() }()
+	result := scanGCPProjects(ctx, projects, locations, scanCfg, includeScan, progress, store)
+	// Give the terminal back before anything else is printed.
+	_ = progress.Close()
+	if err := scanFailedError(result, "location"); err != nil {
+		return err
+	}
+	result.Errors = append(append(discoveryErrors, result.Errors...), enrichErrors...)
+
+	targetHash := computeTargetHash("gcp", locations, strings.Join(projects, ","))
+
+	// Single-repository audits are not part of the scan history.
+	var historyStore *history.Store
+	var pastScans []history.Record
+	if gcpFlags.repo == "" {
+		historyStore, pastScans = detectStorageSpikes(gcpFlags.historyDir, targetHash, "artifactregistry", gcpFlags.spikePercent, result)
+	}
 
 	// Analyze results
 	analysis := analyzer.Analyze(result, analyzer.AnalyzerConfig{
 		MinMonthlyCost: gcpFlags.minMonthlyCost,
+		DisabledChecks: scanCfg.DisabledChecks,
+		RollupLongTail: gcpFlags.rollupTail,
+		Suppressions:   suppressions,
+		Windows:        windows,
+		GroupBy:        gcpFlags.groupBy,
+		CostPeriod:     costPeriod,
+		Carbon:         gcpFlags.carbon,
+		Provider:       "artifactregistry",
+		Sort:           sortKey,
+		Scorer:         scorer,
+		Top:            gcpFlags.top,
+		OlderThan:      olderThan,
+		NewerThan:      newerThan,
+		Now:            scanClock(store),
 	})
+	warnExpiredSuppressions(analysis.Suppressions)
+	warnThresholds(noise.scaleWarnings(result.RepositoriesScanned, len(analysis.Findings)))
 
 	// Build report data
 	data := report.Data{
 		Tool:      "ecrspectre",
 		Version:   version,
 		Timestamp: time.Now().UTC(),
+		RunID:     runID,
 		Target: report.Target{
 			Type:    "artifact-registry",
-			URIHash: computeTargetHash("gcp", locations, gcpFlags.project),
+			URIHash: targetHash,
 		},
 		Config: report.ReportConfig{
 			Provider:       "gcp",
@@ -139,21 +480,231 @@ func runGCP(cmd *cobra.Command, _ []string) error {
 			StaleDays:      gcpFlags.staleDays,
 			MaxSizeMB:      gcpFlags.maxSizeMB,
 			MinMonthlyCost: gcpFlags.minMonthlyCost,
+			OlderThan:      gcpFlags.olderThan,
+			NewerThan:      gcpFlags.newerThan,
 		},
-		Findings: analysis.Findings,
-		Summary:  analysis.Summary,
-		Errors:   analysis.Errors,
+		Findings:     analysis.Findings,
+		Summary:      analysis.Summary,
+		Errors:       analysis.Errors,
+		Repository:   result.Detail,
+		ScanStats:    gcpScanStats(result.Timings),
+		Suppressions: analysis.Suppressions,
+		Usage:        result.Usage,
+		Duration:     time.Since(startedOn),
+	}
+	if len(projects) > 1 {
+		data.Config.Projects = projects
 	}
+	if !gcpFlags.noFeaturesUsed {
+		data.FeaturesUsed = featuresUsed(cmd, "gcp", gcpFlags.format, enabledChecks(scanCfg, includeScan))
+	}
+
+	data.Trend = historyTrend(pastScans, data.Summary)
+	data.Summary.Projection = wasteProjection(pastScans, data.Summary, data.Timestamp)
+	recordHistory(historyStore, data, analysis.Omitted, result)
+
+	baseline.mark(&data, analysis.Omitted)
 
 	// Select and run reporter
-	reporter, err := selectReporter(gcpFlags.format, gcpFlags.outputFile)
+	reporter, closeOutput, err := selectReporter(gcpFlags.format, gcpFlags.outputFile, gcpFlags.templateFile, "")
+	if err != nil {
+		return err
+	}
+	err = reporter.Generate(data)
+	if closeErr := closeOutput(); err == nil && closeErr != nil {
+		err = fmt.Errorf("close output file: %w", closeErr)
+	}
 	if err != nil {
 		return err
 	}
-	return reporter.Generate(data)
+	if err := writeAttestation(gcpFlags.attestation, gcpFlags.attestationKey, gcpFlags.outputFile, data, startedOn); err != nil {
+		return err
+	}
+	if err := notifySlack(webhook, data); err != nil {
+		return err
+	}
+	if err := notifyEvents(events, data); err != nil {
+		return err
+	}
+	if err := findingsGate(failOn, baseline, data, analysis.Omitted); err != nil {
+		return err
+	}
+	return partialScanError(data.Errors)
+}
+
+// resolveGCPProjects returns the --project list plus the projects discovered
+// under --folder and --organization that have Artifact Registry enabled.
+// Projects whose API state cannot be read are skipped and reported.
+func resolveGCPProjects(ctx context.Context) ([]string, []string, error) {
+	projects := append([]string(nil), gcpFlags.projects...)
+	var parents []string
+	for _, f := range gcpFlags.folders {
+		parents = append(parents, "folders/"+strings.TrimPrefix(f, "folders/"))
+	}
+	for _, o := range gcpFlags.organizations {
+		parents = append(parents, "organizations/"+strings.TrimPrefix(o, "organizations/"))
+	}
+	if len(parents) == 0 {
+		return dedupe(projects), nil, nil
+	}
+
+	api, err := gcpapi.NewCaller(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	var errs []string
+	for _, parent := range parents {
+		found, err := api.Projects(ctx, parent)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, project := range found {
+			enabled, err := api.ServiceEnabled(ctx, project, gcpapi.ArtifactRegistryService)
+			if err != nil {
+				errs = append(errs, err.Error())
+				continue
+			}
+			if enabled {
+				projects = append(projects, project)
+			}
+		}
+		slog.Info("Discovered projects", "parent", parent, "projects", len(found))
+	}
+	return dedupe(projects), errs, nil
+}
+
+// dedupe removes repeated values, keeping the first occurrence.
+func dedupe(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := values[:0]
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// scanGCPProjects scans each project with its own client, at most
+// gcpProjectConcurrency at a time, and merges the results.
+// store, when set, records or replays the API calls of a single project.
+func scanGCPProjects(ctx context.Context, projects, locations []string, scanCfg registry.ScanConfig, includeScan bool, progress *progressSink, store *fixtures.Store) *registry.ScanResult {
+	results := make(map[string]*registry.ScanResult, len(projects))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, gcpProjectConcurrency)
+	for _, project := range projects {
+		wg.Add(1)
+		go func(project string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			r := scanGCPProject(ctx, project, locations, scanCfg, includeScan, len(projects) > 1, progress, store)
+			mu.Lock()
+			results[project] = r
+			mu.Unlock()
+		}(project)
+	}
+	wg.Wait()
+	return registry.MergeProjectResults(projects, results)
+}
+
+func scanGCPProject(ctx context.Context, project string, locations []string, scanCfg registry.ScanConfig, includeScan, multi bool, progress *progressSink, store *fixtures.Store) *registry.ScanResult {
+	var api artifactregistry.ARAPI
+	if replaying(store) {
+		// No credentials are needed: every call is answered from fixtures.
+		api = artifactregistry.WithFixtures(nil, store)
+	} else {
+		var endpoint string
+		if gcpFlags.endpointURL != "" {
+			// Validated in runGCP.
+			u, _ := parseEndpointURL(gcpFlags.endpointURL)
+			endpoint = grpcEndpoint(u)
+		}
+		client, err := artifactregistry.NewClient(ctx, project, endpoint)
+		if err != nil {
+			// Every location of the project fails with the client.
+			msg := enhanceError("initialize GCP client", err).Error()
+			result := &registry.ScanResult{Errors: []string{msg}, Targets: len(locations)}
+			for _, location := range locations {
+				result.TargetErrors = append(result.TargetErrors, registry.TargetError{Target: location, Err: msg})
+			}
+			return result
+		}
+		defer func() { _ = client.Close() }()
+		client.SetHTTPClient(registryHTTPClient)
+		api = client
+		if store != nil {
+			api = artifactregistry.WithFixtures(client, store)
+		}
+	}
+
+	scanner := artifactregistry.NewARScanner(api, project, locations, includeScan)
+	if replaying(store) {
+		scanner.SetNow(store.RecordedAt())
+	}
+
+	var progressFn func(registry.ScanProgress)
+	if multi {
+		progressFn = progress.callback(project)
+	} else {
+		progressFn = progress.callback("")
+	}
+
+	if gcpFlags.repo != "" {
+		return scanner.ScanRepository(ctx, scanCfg, gcpFlags.repo, progressFn)
+	}
+	return scanner.Scan(ctx, scanCfg, progressFn)
+}
+
+// buildQuota converts the GB quota flags and per-repository config overrides
+// to a scan quota configuration.
+func buildQuota(q config.Quota) registry.QuotaConfig {
+	quota := registry.QuotaConfig{
+		RepositoryBytes:  gbToBytes(gcpFlags.quotaGB),
+		ProjectBytes:     gbToBytes(gcpFlags.projectQuotaGB),
+		ThresholdPercent: gcpFlags.quotaThreshold,
+	}
+	if len(q.Repositories) > 0 {
+		quota.Repositories = make(map[string]int64, len(q.Repositories))
+		for repo, gb := range q.Repositories {
+			quota.Repositories[repo] = gbToBytes(gb)
+		}
+	}
+	return quota
+}
+
+func gbToBytes(gb float64) int64 {
+	return int64(gb * 1024 * 1024 * 1024)
 }
 
 func applyGCPConfigDefaults(cfg config.Config) {
+	if gcpFlags.maxDeployedAge == "" {
+		gcpFlags.maxDeployedAge = cfg.MaxDeployedAge
+	}
+	if len(gcpFlags.failOn) == 0 {
+		gcpFlags.failOn = cfg.FailOn
+	}
+	if gcpFlags.groupBy == "" {
+		gcpFlags.groupBy = cfg.GroupBy
+	}
+	if gcpFlags.costPeriod == "" {
+		gcpFlags.costPeriod = cfg.CostPeriod
+	}
+	if gcpFlags.sortBy == "" {
+		gcpFlags.sortBy = cfg.Sort
+	}
+	if !gcpFlags.carbon {
+		gcpFlags.carbon = cfg.Carbon
+	}
+	if gcpFlags.endpointURL == "" {
+		gcpFlags.endpointURL = cfg.EndpointURL
+	}
+	gcpFlags.format, gcpFlags.outputFile = reportsFlags(gcpFlags.format, gcpFlags.outputFile, cfg.Reports)
+	if gcpFlags.templateFile == "" && hasFormat(gcpFlags.format, "template") {
+		gcpFlags.templateFile = cfg.Template
+	}
 	if gcpFlags.format == "text" && cfg.Format != "" {
 		gcpFlags.format = cfg.Format
 	}
@@ -166,7 +717,40 @@ func applyGCPConfigDefaults(cfg config.Config) {
 	if gcpFlags.minMonthlyCost == 0.10 && cfg.MinMonthlyCost > 0 {
 		gcpFlags.minMonthlyCost = cfg.MinMonthlyCost
 	}
-	if gcpFlags.project == "" && cfg.Project != "" {
-		gcpFlags.project = cfg.Project
+	if gcpFlags.historyDir == "" && cfg.HistoryDir != "" {
+		gcpFlags.historyDir = cfg.HistoryDir
+	}
+	if len(gcpFlags.projects) == 0 {
+		gcpFlags.projects = cfg.Projects
+		if cfg.Project != "" {
+			gcpFlags.projects = append([]string{cfg.Project}, cfg.Projects...)
+		}
+	}
+	if len(gcpFlags.tagPriority) == 0 {
+		gcpFlags.tagPriority = cfg.TagPriority
+	}
+	if !gcpFlags.rollupTail {
+		gcpFlags.rollupTail = cfg.RollupLongTail
+	}
+	if !gcpFlags.requireSigs {
+		gcpFlags.requireSigs = cfg.RequireSignatures
+	}
+	if !gcpFlags.requireSBOM {
+		gcpFlags.requireSBOM = cfg.RequireSBOM
+	}
+	if gcpFlags.sbomSeverity == "" {
+		gcpFlags.sbomSeverity = cfg.SBOMSeverity
+	}
+	if gcpFlags.keepLatest == 0 && cfg.KeepLatest > 0 {
+		gcpFlags.keepLatest = cfg.KeepLatest
+	}
+	if gcpFlags.quotaGB == 0 && cfg.Quota.RepositoryGB > 0 {
+		gcpFlags.quotaGB = cfg.Quota.RepositoryGB
+	}
+	if gcpFlags.projectQuotaGB == 0 && cfg.Quota.ProjectGB > 0 {
+		gcpFlags.projectQuotaGB = cfg.Quota.ProjectGB
+	}
+	if gcpFlags.quotaThreshold == registry.DefaultQuotaThresholdPercent && cfg.Quota.ThresholdPercent > 0 {
+		gcpFlags.quotaThreshold = cfg.Quota.ThresholdPercent
 	}
 }
@@ -1,37 +1,75 @@
 package commands
 
 import (
+	"context"
+	"crypto/ed25519"
 	"crypto/sha256"
 	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/ppiankov/ecrspectre/internal/analyzer"
+	"github.com/ppiankov/ecrspectre/internal/attest"
+	"github.com/ppiankov/ecrspectre/internal/auditlog"
+	"github.com/ppiankov/ecrspectre/internal/awsapi"
+	"github.com/ppiankov/ecrspectre/internal/config"
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+	"github.com/ppiankov/ecrspectre/internal/history"
+	"github.com/ppiankov/ecrspectre/internal/inuse"
+	"github.com/ppiankov/ecrspectre/internal/kube"
+	"github.com/ppiankov/ecrspectre/internal/logging"
+	"github.com/ppiankov/ecrspectre/internal/pathutil"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+	"github.com/ppiankov/ecrspectre/internal/report"
+	"github.com/ppiankov/ecrspectre/internal/runid"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 )
 
+// startRun generates the run ID of a scan and attaches it to every later log
+// record.
+func startRun() string {
+	id := runid.New()
+	logging.SetRunID(id)
+	return id
+}
+
 // enhanceError wraps an error with context and suggestions for common cloud issues.
 func enhanceError(action string, err error) error {
-	msg := err.Error()
+	if hint := errorHint(err.Error()); hint != "" {
+		return fmt.Errorf("%s: %w\n  hint: %s", action, err, hint)
+	}
+	return fmt.Errorf("%s: %w", action, err)
+}
 
-	var hint string
+// errorHint returns a suggestion for a common cloud error message, or "".
+func errorHint(msg string) string {
 	switch {
 	case strings.Contains(msg, "NoCredentialProviders"):
-		hint = "Configure AWS credentials: set AWS_PROFILE, AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY, or run 'aws configure'"
+		return "Configure AWS credentials: set AWS_PROFILE, AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY, or run 'aws configure'"
 	case strings.Contains(msg, "ExpiredToken"):
-		hint = "AWS session token expired. Refresh credentials or run 'aws sso login'"
+		return "AWS session token expired. Refresh credentials or run 'aws sso login'"
 	case strings.Contains(msg, "AccessDenied") || strings.Contains(msg, "UnauthorizedAccess"):
-		hint = "Insufficient permissions. Apply the IAM policy from 'ecrspectre init' to your role/user"
+		return "Insufficient permissions. Apply the IAM policy from 'ecrspectre init' to your role/user"
 	case strings.Contains(msg, "RequestExpired"):
-		hint = "Request expired. Check system clock synchronization"
+		return "Request expired. Check system clock synchronization"
 	case strings.Contains(msg, "Throttling"):
-		hint = "API rate limit hit. Retry with fewer regions or increase timeout"
+		return "API rate limit hit. Retry with fewer regions or increase timeout"
 	case strings.Contains(msg, "GOOGLE_APPLICATION_CREDENTIALS"):
-		hint = "Configure GCP credentials: set GOOGLE_APPLICATION_CREDENTIALS or run 'gcloud auth application-default login'"
+		return "Configure GCP credentials: set GOOGLE_APPLICATION_CREDENTIALS or run 'gcloud auth application-default login'"
 	case strings.Contains(msg, "could not find default credentials"):
-		hint = "Configure GCP credentials: run 'gcloud auth application-default login'"
+		return "Configure GCP credentials: run 'gcloud auth application-default login'"
 	}
-
-	if hint != "" {
-		return fmt.Errorf("%s: %w\n  hint: %s", action, err, hint)
-	}
-	return fmt.Errorf("%s: %w", action, err)
+	return ""
 }
 
 // computeTargetHash generates a SHA256 hash for the target URI.
@@ -40,3 +78,601 @@ func computeTargetHash(provider string, regions []string, project string) string
 	h := sha256.Sum256([]byte(input))
 	return fmt.Sprintf("sha256:%x", h)
 }
+
+// buildRepoFilter compiles repository include/exclude patterns. Flags replace
+// the corresponding config file lists when set.
+func buildRepoFilter(cfg config.Config, flagRepos, flagExclude []string) (registry.RepoFilter, error) {
+	include := cfg.Repos
+	if len(flagRepos) > 0 {
+		include = flagRepos
+	}
+	exclude := cfg.ExcludeRepos
+	if len(flagExclude) > 0 {
+		exclude = flagExclude
+	}
+	filter, err := registry.NewRepoFilter(include, exclude)
+	if err != nil {
+		return registry.RepoFilter{}, fmt.Errorf("parse repository filter: %w", err)
+	}
+	return filter, nil
+}
+
+// writeAttestation records an in-toto provenance statement for the scan when
+// path is set, signing it if a key is given. Each report file named by the
+// --output value is a subject; uploaded reports cannot be read back, but
+// the result subject still covers their data.
+func writeAttestation(path, keyPath, output string, data report.Data, startedOn time.Time) error {
+	if path == "" {
+		return nil
+	}
+	var key ed25519.PrivateKey
+	if keyPath != "" {
+		var err error
+		if key, err = attest.LoadSigningKey(keyPath); err != nil {
+			return err
+		}
+	}
+	st, err := attest.Build(data, reportOutputs(output), startedOn)
+	if err != nil {
+		return err
+	}
+	return attest.WriteFile(path, st, key)
+}
+
+// slackWebhookEnv holds the Slack incoming webhook URL when --slack-webhook
+// is not set, keeping the secret out of shell history and process lists.
+const slackWebhookEnv = "ECRSPECTRE_SLACK_WEBHOOK"
+
+// resolveSlackWebhook returns the --slack-webhook value, or the URL in
+// $ECRSPECTRE_SLACK_WEBHOOK, after checking it is an https URL. It returns ""
+// when neither is set.
+func resolveSlackWebhook(flag string) (string, error) {
+	webhook := flag
+	if webhook == "" {
+		webhook = os.Getenv(slackWebhookEnv)
+	}
+	if webhook == "" {
+		return "", nil
+	}
+	u, err := url.Parse(webhook)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", configError(fmt.Errorf("--slack-webhook must be an https URL"))
+	}
+	return webhook, nil
+}
+
+// notifySlack posts the scan summary to webhook when it is set.
+func notifySlack(webhook string, data report.Data) error {
+	if webhook == "" {
+		return nil
+	}
+	r := &report.SlackReporter{WebhookURL: webhook, Client: &http.Client{Timeout: 30 * time.Second}}
+	return r.Generate(data)
+}
+
+// eventWebhookEnv holds the CloudEvents webhook URL when --webhook is not set.
+const eventWebhookEnv = "ECRSPECTRE_WEBHOOK"
+
+// newEventsReporter validates the --webhook flags and returns the
+// CloudEvents reporter, or nil when no webhook is set.
+func newEventsReporter(webhook string, wasteThreshold float64, severityThreshold string) (*report.CloudEventsReporter, error) {
+	if webhook == "" {
+		webhook = os.Getenv(eventWebhookEnv)
+	}
+	if webhook == "" {
+		if wasteThreshold > 0 || severityThreshold != "" {
+			return nil, configError(fmt.Errorf("--webhook-waste-threshold and --webhook-severity-threshold need --webhook"))
+		}
+		return nil, nil
+	}
+	u, err := url.Parse(webhook)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, configError(fmt.Errorf("--webhook must be an http or https URL"))
+	}
+	if wasteThreshold < 0 {
+		return nil, configError(fmt.Errorf("--webhook-waste-threshold must not be negative"))
+	}
+	r := &report.CloudEventsReporter{URL: webhook, Client: &http.Client{Timeout: 30 * time.Second}, WasteThreshold: wasteThreshold}
+	if severityThreshold != "" {
+		if r.SeverityThreshold, err = registry.ParseSeverity(severityThreshold); err != nil {
+			return nil, configError(fmt.Errorf("--webhook-severity-threshold: %w", err))
+		}
+	}
+	return r, nil
+}
+
+// notifyEvents posts the scan's CloudEvents when a webhook is set.
+func notifyEvents(r *report.CloudEventsReporter, data report.Data) error {
+	if r == nil {
+		return nil
+	}
+	return r.Generate(data)
+}
+
+// featuresUsed builds the anonymous features_used list embedded in reports:
+// provider, output format, enabled checks and the names of flags set on the
+// command line. Flag values are never recorded.
+func featuresUsed(cmd *cobra.Command, provider, format string, checks []string) []string {
+	features := []string{"provider:" + provider}
+	for _, f := range strings.Split(format, ",") {
+		features = append(features, "format:"+strings.TrimSpace(f))
+	}
+	for _, c := range checks {
+		features = append(features, "check:"+c)
+	}
+	cmd.Flags().Visit(func(f *pflag.Flag) {
+		features = append(features, "flag:"+f.Name)
+	})
+	sort.Strings(features)
+	return features
+}
+
+// disabledChecks maps the finding IDs listed in disable_checks, e.g.
+// NO_LIFECYCLE_POLICY, matched case-insensitively.
+func disabledChecks(cfg config.Config) map[registry.FindingID]bool {
+	if len(cfg.DisableChecks) == 0 {
+		return nil
+	}
+	ids := make(map[registry.FindingID]bool, len(cfg.DisableChecks))
+	for _, id := range cfg.DisableChecks {
+		ids[registry.FindingID(strings.ToUpper(strings.TrimSpace(id)))] = true
+	}
+	return ids
+}
+
+// customRules compiles the rules section of the config.
+func customRules(cfg config.Config) ([]registry.CustomRule, error) {
+	var out []registry.CustomRule
+	seen := make(map[string]bool, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		if seen[r.ID] {
+			return nil, fmt.Errorf("rule id %s is defined twice", r.ID)
+		}
+		seen[r.ID] = true
+		rule, err := registry.NewCustomRule(r.ID, r.Severity, r.Message, r.Expression)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rule)
+	}
+	return out, nil
+}
+
+// releaseCadences compiles the release_cadence section of the config.
+func releaseCadences(cfg config.Config) ([]registry.ReleaseCadence, error) {
+	var out []registry.ReleaseCadence
+	for _, c := range cfg.ReleaseCadence {
+		rc, err := registry.NewReleaseCadence(c.Repo, c.Every)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, rc)
+	}
+	return out, nil
+}
+
+// migrationWindows compiles the migration_windows section of the config.
+func migrationWindows(cfg config.Config) ([]registry.MigrationWindow, error) {
+	var out []registry.MigrationWindow
+	for _, w := range cfg.MigrationWindows {
+		mw, err := registry.NewMigrationWindow(w.Name, w.Start, w.End, w.Reason, w.Repos)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, mw)
+	}
+	return out, nil
+}
+
+// loadSuppressions reads and validates the suppression file at path, or the
+// .ecrspectre-ignore.yaml in the working directory when path is empty.
+func loadSuppressions(path string) ([]registry.Suppression, error) {
+	f, err := config.LoadIgnore(".", path)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]registry.Suppression, 0, len(f.Suppressions))
+	for _, s := range f.Suppressions {
+		suppression, err := registry.NewSuppression(s.ID, s.Resource, s.Owner, s.Reason, s.Expires)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, suppression)
+	}
+	return out, nil
+}
+
+// warnExpiredSuppressions logs the expired suppressions whose findings are
+// reported again.
+func warnExpiredSuppressions(statuses []analyzer.SuppressionStatus) {
+	for _, s := range statuses {
+		if s.Expired && s.Matched > 0 {
+			slog.Warn("Suppression expired, findings reported again", "id", s.ID, "resource", s.Resource, "owner", s.Owner, "expired", s.Expires, "findings", s.Matched)
+		}
+	}
+}
+
+// attributionKeys returns the tag/label key --group-by needs copied into
+// finding metadata, beyond the team and owner keys that always are.
+func attributionKeys(groupBy string) []string {
+	switch groupBy {
+	case "", "region", registry.GroupByRepo, registry.MetadataProject, registry.MetadataProvider, registry.MetadataTarget:
+		return nil
+	}
+	return []string{groupBy}
+}
+
+// enabledChecks lists the optional checks turned on by the scan configuration.
+func enabledChecks(cfg registry.ScanConfig, includeScan bool) []string {
+	var checks []string
+	if includeScan {
+		checks = append(checks, "vulnerabilities")
+	}
+	if cfg.EgressModel != "" {
+		checks = append(checks, "egress")
+	}
+	if cfg.DeepLayers {
+		checks = append(checks, "deep-layers")
+	}
+	if cfg.InUse != nil {
+		checks = append(checks, "in-use")
+	}
+	if cfg.MaxDeployedAge > 0 {
+		checks = append(checks, "deployed-age")
+	}
+	if len(cfg.UsedPlatforms) > 0 {
+		checks = append(checks, "used-platforms")
+	}
+	if cfg.Pulls != nil {
+		checks = append(checks, "audit-log-pulls")
+	}
+	if cfg.RequireSignatures {
+		checks = append(checks, "signatures")
+	}
+	if cfg.RequireSBOM {
+		checks = append(checks, "sbom")
+	}
+	if len(cfg.Rules) > 0 {
+		checks = append(checks, "custom-rules")
+	}
+	return checks
+}
+
+// collectAWSInUse adds the images of running ECS tasks and Lambda functions
+// to set. Source failures are returned as messages so the scan still runs.
+func collectAWSInUse(ctx context.Context, cfg aws.Config, set *registry.InUse) []string {
+	return inuse.NewAWS(awsapi.NewCaller(cfg)).Collect(ctx, set)
+}
+
+// collectGCPInUse adds the images of serving Cloud Run revisions and running
+// GKE pods in project to set.
+func collectGCPInUse(ctx context.Context, project string, set *registry.InUse) []string {
+	api, err := gcpapi.NewCaller(ctx)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return inuse.NewGCP(api, project).Collect(ctx, set)
+}
+
+// collectAuditLogPulls records the image pulls in project's Data Access audit
+// logs. A project without pull entries usually has data access logging off.
+func collectAuditLogPulls(ctx context.Context, project string, pulls *registry.PullLog) []string {
+	api, err := gcpapi.NewCaller(ctx)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	n, err := auditlog.CollectPulls(ctx, api, project, pulls)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	if n == 0 {
+		slog.Warn("No image pulls in audit logs; enable Data Access audit logs for Artifact Registry", "project", project)
+	}
+	return nil
+}
+
+// collectKubeInUse adds the images of running pods in each kubeconfig
+// context (the current context when none are given) to set.
+func collectKubeInUse(ctx context.Context, kubeconfig string, contexts []string, set *registry.InUse) []string {
+	if len(contexts) == 0 {
+		contexts = []string{""}
+	}
+	var errs []string
+	for _, name := range contexts {
+		client, err := kube.Load(kubeconfig, name)
+		if err == nil {
+			err = inuse.CollectKube(ctx, client, set)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("collect cluster images: %v", err))
+		}
+	}
+	return errs
+}
+
+// detectStorageSpikes loads the scan history of target from dir and appends
+// STORAGE_SPIKE findings for repositories that grew unusually since the last
+// scan. It returns the store to record this scan in and the past records, or
+// nil when history is disabled.
+func detectStorageSpikes(dir, target, pricingProvider string, spikePercent float64, result *registry.ScanResult) (*history.Store, []history.Record) {
+	if dir == "" {
+		return nil, nil
+	}
+	store := history.Open(dir, target)
+	past, err := store.Load()
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("history: %v", err))
+		return store, nil
+	}
+	result.Findings = append(result.Findings, history.DetectSpikes(past, result.Usage, pricingProvider, spikePercent)...)
+	return store, past
+}
+
+// trendScans is the number of scans shown in text report sparklines.
+const trendScans = 12
+
+// historyTrend returns the totals of the last trendScans scans, ending with
+// the current summary, or nil without previous scans.
+func historyTrend(past []history.Record, summary analyzer.Summary) *report.Trend {
+	if len(past) == 0 {
+		return nil
+	}
+	if len(past) > trendScans-1 {
+		past = past[len(past)-(trendScans-1):]
+	}
+	trend := &report.Trend{}
+	for _, r := range past {
+		trend.MonthlyWaste = append(trend.MonthlyWaste, r.TotalMonthlyWaste)
+		trend.Findings = append(trend.Findings, float64(r.TotalFindings))
+	}
+	trend.MonthlyWaste = append(trend.MonthlyWaste, summary.TotalMonthlyWaste)
+	trend.Findings = append(trend.Findings, float64(summary.TotalFindings))
+	return trend
+}
+
+// wasteProjection extrapolates the summary's monthly waste over the next
+// year from the growth across past scans, or returns nil without enough
+// history.
+func wasteProjection(past []history.Record, summary analyzer.Summary, now time.Time) *analyzer.Projection {
+	growth, scans, since, ok := history.WasteGrowth(past, now, summary.TotalMonthlyWaste)
+	if !ok {
+		return nil
+	}
+	return &analyzer.Projection{
+		MonthlyGrowth: growth,
+		NextYearWaste: analyzer.ProjectWaste(summary.TotalMonthlyWaste, growth),
+		Scans:         scans,
+		Since:         since,
+	}
+}
+
+// recordHistory appends this scan's summary and repository usage to store.
+// omitted are the findings cut from the report by --top, which the history
+// still counts.
+func recordHistory(store *history.Store, data report.Data, omitted []registry.Finding, result *registry.ScanResult) {
+	if store == nil {
+		return
+	}
+	byType := make(map[string]int)
+	repoWaste := make(map[string]float64)
+	findings := make(map[string]float64)
+	for _, f := range append(omitted, data.Findings...) {
+		byType[string(f.ID)]++
+		findings[history.FindingKey(f)] += f.EstimatedMonthlyWaste
+		if repo := registry.FindingRepository(f); repo != "" {
+			repoWaste[repo] += f.EstimatedMonthlyWaste
+		}
+	}
+	err := store.Append(history.Record{
+		Timestamp:         data.Timestamp,
+		RunID:             data.RunID,
+		Provider:          data.Config.Provider,
+		Target:            data.Target.URIHash,
+		TotalFindings:     data.Summary.TotalFindings,
+		TotalMonthlyWaste: data.Summary.TotalMonthlyWaste,
+		Repositories:      result.Usage,
+		FindingsByType:    byType,
+		RepositoryWaste:   repoWaste,
+		Findings:          findings,
+	})
+	if err != nil {
+		slog.Warn("Failed to record scan history", "error", err)
+	}
+}
+
+// baselineReport is the previous report a scan is compared with by
+// --baseline.
+type baselineReport struct {
+	path     string
+	findings []registry.Finding
+}
+
+// loadBaseline reads the --baseline report at path, or returns nil when
+// path is empty.
+func loadBaseline(path string) (*baselineReport, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := report.ReadJSONFile(path)
+	if err != nil {
+		return nil, configError(fmt.Errorf("--baseline: %w", err))
+	}
+	return &baselineReport{path: path, findings: data.Findings}, nil
+}
+
+// mark tags the findings of data as new or unchanged since the baseline and
+// adds the comparison to the summary. omitted are the findings cut by --top.
+func (b *baselineReport) mark(data *report.Data, omitted []registry.Finding) {
+	if b == nil {
+		return
+	}
+	summary := analyzer.MarkBaseline(b.findings, data.Findings, omitted)
+	summary.Report = b.path
+	data.Summary.Baseline = &summary
+}
+
+// parseFailOn parses the --fail-on or fail_on thresholds.
+func parseFailOn(specs []string) ([]analyzer.FailCondition, error) {
+	conditions, err := analyzer.ParseFailOn(specs)
+	return conditions, configError(err)
+}
+
+// findingsGate decides whether a finished scan exits 2. Without --fail-on,
+// only a finding new since the --baseline report fails it. With --fail-on,
+// the thresholds apply to every finding, including those cut by --top, or
+// with a baseline to the new findings only, so a budget gates regressions
+// rather than legacy debt.
+func findingsGate(conditions []analyzer.FailCondition, baseline *baselineReport, data report.Data, omitted []registry.Finding) error {
+	if len(conditions) == 0 {
+		return regressionError(data.Summary.Baseline)
+	}
+	findings := slices.Concat(data.Findings, omitted)
+	scope := "findings"
+	if baseline != nil {
+		findings = analyzer.CompareBaseline(baseline.findings, findings).New
+		scope = "new findings"
+	}
+	return failOnError(analyzer.CheckFailOn(conditions, findings), scope)
+}
+
+// loadRepoPriority reads a previous JSON report and weights each repository
+// by the monthly waste found there, so expensive repositories are scanned first.
+func loadRepoPriority(path string) (map[string]float64, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := report.ReadJSONFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("load priority seed: %w", err)
+	}
+
+	priority := make(map[string]float64)
+	for _, f := range data.Findings {
+		if repo := registry.FindingRepository(f); repo != "" {
+			priority[repo] += f.EstimatedMonthlyWaste
+		}
+	}
+	return priority, nil
+}
+
+// openOutput returns stdout, or the file created at path with a function
+// that closes it. The close function for stdout does nothing. An s3:// or
+// gs:// path is uploaded on close instead, with profile's AWS credentials.
+func openOutput(path, profile string) (io.Writer, func() error, error) {
+	if path == "" {
+		return os.Stdout, func() error { return nil }, nil
+	}
+	if isRemoteOutput(path) {
+		return openRemoteOutput(path, profile, time.Now())
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create output file: %w", err)
+	}
+	return f, f.Close, nil
+}
+
+// expandPaths expands ~ and environment variables in path flags and config
+// values in place, so "~/reports" and "%USERPROFILE%\\reports" work on every
+// platform.
+func expandPaths(paths ...*string) {
+	for _, p := range paths {
+		*p = pathutil.Expand(*p)
+	}
+}
+
+// parseAge parses a duration that may also be given in days ("30d") or
+// weeks ("4w").
+func parseAge(s string) (time.Duration, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.ParseFloat(n, 64)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid duration %q", s)
+			}
+			return time.Duration(v * float64(unit)), nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
+
+// parseMaxDeployedAge parses --max-deployed-age. AGING_DEPLOYED_IMAGE reads
+// build dates from the manifests --deep fetches and needs a source of
+// deployed images; without either the check is skipped with a warning.
+func parseMaxDeployedAge(s string, deep, inUse bool) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	age, err := parseAge(s)
+	if err != nil {
+		return 0, fmt.Errorf("--max-deployed-age: %w", err)
+	}
+	if age > 0 && (!deep || !inUse) {
+		slog.Warn("--max-deployed-age needs --deep and --in-use-from or --kubeconfig; AGING_DEPLOYED_IMAGE is skipped")
+		return 0, nil
+	}
+	return age, nil
+}
+
+// parseAgeWindow parses --older-than and --newer-than. The window must not
+// be empty: --newer-than has to be longer than --older-than.
+func parseAgeWindow(olderThan, newerThan string) (older, newer time.Duration, err error) {
+	if olderThan != "" {
+		if older, err = parseAge(olderThan); err != nil {
+			return 0, 0, fmt.Errorf("--older-than: %w", err)
+		}
+	}
+	if newerThan != "" {
+		if newer, err = parseAge(newerThan); err != nil {
+			return 0, 0, fmt.Errorf("--newer-than: %w", err)
+		}
+	}
+	if older > 0 && newer > 0 && newer <= older {
+		return 0, 0, fmt.Errorf("--newer-than %s must be longer than --older-than %s", newerThan, olderThan)
+	}
+	return older, newer, nil
+}
+
+// scoreModel applies the weights of the scoring config block to the
+// default score model.
+func scoreModel(s config.Scoring) (analyzer.ScoreModel, error) {
+	m := analyzer.DefaultScoreModel
+	for _, w := range []struct {
+		dst *float64
+		src *float64
+	}{{&m.Severity, s.Severity}, {&m.Waste, s.Waste}, {&m.Age, s.Age}, {&m.Confidence, s.Confidence}} {
+		if w.src != nil {
+			*w.dst = *w.src
+		}
+	}
+	if err := m.Validate(); err != nil {
+		return m, configError(fmt.Errorf("scoring: %w", err))
+	}
+	return m, nil
+}
+
+// gcpScanStats summarizes repository timings like registry.NewScanStats and
+// adds the GCP projects whose requests were denied for quota, logging each.
+func gcpScanStats(timings []registry.RepoTiming) *registry.ScanStats {
+	stats := registry.NewScanStats(timings)
+	quota := gcpapi.QuotaStats()
+	if len(quota) == 0 {
+		return stats
+	}
+	if stats == nil {
+		stats = &registry.ScanStats{}
+	}
+	for _, q := range quota {
+		slog.Warn("GCP API quota exhausted; requests were slowed down and retried",
+			"project", q.Project, "denials", q.Denials, "retries", q.Retries, "requests_per_second", q.Rate)
+		stats.QuotaDenials = append(stats.QuotaDenials, registry.QuotaDenial{
+			Project: q.Project, Denials: q.Denials, Retries: q.Retries, RequestsPerSecond: q.Rate,
+		})
+	}
+	return stats
+}
@@ -80,6 +80,10 @@ const sampleConfig = `# ecrspectre configuration
 # GCP project ID (required for gcp provider)
 # project: my-project-id
 
+# Additional GCP projects, scanned concurrently with per-project summaries
+# projects:
+#   - my-other-project
+
 # Regions to scan (default: all enabled regions)
 # regions:
 #   - us-east-1
@@ -94,12 +98,143 @@ max_size_mb: 1024
 # Minimum monthly cost to report ($)
 min_monthly_cost: 0.10
 
-# Output format: text, json, sarif, or spectrehub
+# Roll the findings under min_monthly_cost into one LONG_TAIL_WASTE finding
+# per repository instead of dropping them.
+# rollup_long_tail: true
+
+# ECR repositories with more untagged images than this (typically kaniko or
+# buildkit caches) get one UNTAGGED_ACCUMULATION finding instead of one
+# UNTAGGED_IMAGE per image.
+# untagged_accumulation: 10000
+
+# Count waste on ECR images that the repository's lifecycle policy will
+# expire within this many days as self-resolving instead of reporting it.
+# self_resolving_days: 7
+
+# Output format: text, json, yaml, csv, markdown, junit, github, prometheus, sarif, spectrehub, ocsf, template, sqlite, or dataset
 format: text
 
+# Go text/template rendering report data for the template format.
+# template: report.tmpl
+
+# Write several formats from one scan instead (replaces format; an entry
+# without output goes to stdout).
+# reports:
+#   - format: json
+#     output: report.json
+#   - format: text
+
+# Findings are ranked by a 0-100 priority score weighing severity, waste,
+# image age and confidence. Change the relative weights:
+# scoring:
+#   severity: 0.3
+#   waste: 0.4
+#   age: 0.15
+#   confidence: 0.15
+
 # Scan timeout
 timeout: 10m
 
+# Estimate egress waste for large ECR images from CloudWatch pull counts
+# (inter-region or internet). Requires cloudwatch:GetMetricStatistics.
+# egress_model: inter-region
+
+# Only scan repositories matching these patterns. Globs use * for any run of
+# characters; prefix with re: for a regular expression and ! to exclude.
+# repos:
+#   - team-a/*
+#   - "!*-cache"
+# exclude_repos:
+#   - sandbox/*
+
+# Artifact Registry storage budgets: emit QUOTA_PRESSURE when a repository or
+# the project reaches threshold_percent of its quota.
+# quota:
+#   repository_gb: 50
+#   project_gb: 500
+#   threshold_percent: 80
+#   repositories:
+#     ml-models: 200
+
+# Exit 2 when findings break any of these thresholds, so CI can enforce a
+# budget. With --baseline they apply to new findings only.
+# fail_on:
+#   - severity=high
+#   - waste=100
+#   - count=50
+
+# With --deep and an in-use source, report deployed images built longer ago
+# than this as AGING_DEPLOYED_IMAGE.
+# max_deployed_age: 365d
+
+# Record every scan here and report STORAGE_SPIKE when a repository grows
+# anomalously since the previous scan.
+# history_dir: ~/.cache/ecrspectre/history
+
+# Never report the newest N images of each tag family as stale, so rollback
+# targets survive: semver tags group by major version (v1.*, v2.*) and all
+# other tags form one family. Package versions are grouped per package.
+# keep_latest: 10
+
+# Images with a tag matching one of these regular expressions are never
+# reported as stale, and their other findings are marked "protected".
+# protected_tags:
+#   - '^v\d+\.\d+\.\d+$'
+#   - ^prod-
+
+# Report tagged images without a cosign or OCI referrer signature as
+# UNSIGNED_IMAGE, optionally only those with a tag matching signed_tags.
+# require_signatures: true
+# signed_tags:
+#   - '^v\d+\.\d+\.\d+$'
+
+# Report images pushed within stale_days that have no SBOM attached (cosign
+# .sbom tag or SPDX/CycloneDX referrer) as MISSING_SBOM.
+# require_sbom: true
+# sbom_severity: medium
+
+# Custom findings: images matching a CEL expression are reported under the
+# rule's ID. Variables: repo, region, digest, media_type, tags, size_bytes,
+# size_mb, age_days, idle_days.
+# rules:
+#   - id: SANDBOX_EXPIRED
+#     severity: low
+#     message: Sandbox image older than 30 days
+#     expression: repo.endsWith("/sandbox") && age_days > 30
+
+# Turn off checks by finding ID. Disabling NO_LIFECYCLE_POLICY also skips the
+# per-repository lifecycle policy lookups on ECR.
+# disable_checks:
+#   - NO_LIFECYCLE_POLICY
+
+# Private networks: call the ECR or Artifact Registry API through a VPC
+# interface / Private Service Connect endpoint, and send all API traffic
+# through a proxy (default: HTTPS_PROXY and NO_PROXY from the environment).
+# endpoint_url: https://vpce-0abc-xyz.api.ecr.us-east-1.vpce.amazonaws.com
+# proxy_url: http://proxy.corp.example:3128
+
+# TLS for Docker registry API requests: extra CA certificates to trust, a
+# client certificate for mutual TLS, and (for testing only) no verification.
+# ca_bundle: /etc/ssl/corp-ca.pem
+# client_cert: /etc/ecrspectre/client.pem
+# client_key: /etc/ecrspectre/client-key.pem
+# insecure_skip_verify: false
+
+# Limit multi-account and multi-project scans (ecrspectre all, gcp --folder /
+# --organization) to accounts with these AWS Organizations tags and projects
+# with these GCP labels. Account tags are read with organizations_profile (the
+# management or a delegated administrator account) and need
+# organizations:ListTagsForResource.
+# scope:
+#   account_tags: [env=prod]
+#   exclude_account_tags: [lifecycle=sunset]
+#   project_labels: [env=prod]
+#   exclude_project_labels: [env=sandbox]
+#   organizations_profile: org-management
+
+# Print a notice when a newer ecrspectre release exists (checked once a day).
+# update_check: false
+
 # Resources to exclude from scanning
 # exclude:
 #   resource_ids:
@@ -121,6 +256,13 @@ const sampleIAMPolicy = `{
         "ecr:BatchGetImage",
         "ecr:GetLifecyclePolicy",
         "ecr:DescribeImageScanFindings",
+        "ecr:ListTagsForResource",
+        "cloudwatch:GetMetricStatistics",
+        "ecs:ListClusters",
+        "ecs:ListTasks",
+        "ecs:DescribeTasks",
+        "lambda:ListFunctions",
+        "lambda:GetFunction",
         "sts:GetCallerIdentity"
       ],
       "Resource": "*"
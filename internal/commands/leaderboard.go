@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/ppiankov/ecrspectre/internal/leaderboard"
+	"github.com/ppiankov/ecrspectre/internal/report"
+	"github.com/spf13/cobra"
+)
+
+var leaderboardFlags struct {
+	previous   string
+	current    string
+	groupBy    string
+	format     string
+	outputFile string
+}
+
+var leaderboardCmd = &cobra.Command{
+	Use:   "leaderboard",
+	Short: "Rank teams or accounts by waste reclaimed since the last scan",
+	Long: `Compare two spectre/v1 JSON reports and rank groups by the monthly waste
+reclaimed between them, then by lowest current waste. Groups come from a finding
+metadata key (for example team or owner) or from the region.`,
+	RunE: runLeaderboard,
+}
+
+func init() {
+	leaderboardCmd.Flags().StringVar(&leaderboardFlags.previous, "previous", "", "Previous JSON report (required)")
+	leaderboardCmd.Flags().StringVar(&leaderboardFlags.current, "current", "", "Current JSON report (required)")
+	leaderboardCmd.Flags().StringVar(&leaderboardFlags.groupBy, "group-by", "team", "Metadata key to group by, or region")
+	leaderboardCmd.Flags().StringVar(&leaderboardFlags.format, "format", "markdown", "Output format: markdown, html")
+	leaderboardCmd.Flags().StringVarP(&leaderboardFlags.outputFile, "output", "o", "", "Output file path (default: stdout)")
+}
+
+func runLeaderboard(_ *cobra.Command, _ []string) error {
+	if leaderboardFlags.previous == "" || leaderboardFlags.current == "" {
+		return configError(fmt.Errorf("--previous and --current are required"))
+	}
+	expandPaths(&leaderboardFlags.previous, &leaderboardFlags.current, &leaderboardFlags.outputFile)
+
+	previous, err := report.ReadJSONFile(leaderboardFlags.previous)
+	if err != nil {
+		return err
+	}
+	current, err := report.ReadJSONFile(leaderboardFlags.current)
+	if err != nil {
+		return err
+	}
+
+	entries := leaderboard.Build(previous, current, leaderboardFlags.groupBy)
+
+	w, closeOutput, err := openOutput(leaderboardFlags.outputFile, "")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = closeOutput() }()
+
+	switch leaderboardFlags.format {
+	case "markdown":
+		return leaderboard.WriteMarkdown(w, leaderboardFlags.groupBy, entries)
+	case "html":
+		return leaderboard.WriteHTML(w, leaderboardFlags.groupBy, entries)
+	default:
+		return configError(fmt.Errorf("unsupported format: %s (use markdown or html)", leaderboardFlags.format))
+	}
+}
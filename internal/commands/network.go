@@ -0,0 +1,136 @@
+package commands
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+)
+
+// proxyURL is the --proxy-url flag shared by every command.
+var proxyURL string
+
+// registryTLS holds the --ca-bundle, --client-cert, --client-key and
+// --insecure-skip-verify flags shared by every command.
+var registryTLS struct {
+	caBundle   string
+	clientCert string
+	clientKey  string
+	insecure   bool
+}
+
+// registryHTTPClient is the client for Docker registry API calls (manifest
+// and config fetches, token exchanges, archive and restore transfers). It is
+// built from registryTLS before any command runs.
+var registryHTTPClient = http.DefaultClient
+
+// newRegistryHTTPClient returns an HTTP client that trusts the system roots
+// plus the PEM certificates of caBundle, presents the client certificate of
+// certFile/keyFile for mutual TLS, and skips certificate verification when
+// insecure is set. Self-hosted registries commonly terminate TLS with an
+// internal CA. With no option set it returns http.DefaultClient.
+func newRegistryHTTPClient(caBundle, certFile, keyFile string, insecure bool) (*http.Client, error) {
+	if caBundle == "" && certFile == "" && keyFile == "" && !insecure {
+		return http.DefaultClient, nil
+	}
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("--client-cert and --client-key must be set together")
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: insecure}
+	if caBundle != "" {
+		pem, err := os.ReadFile(caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA bundle %s has no PEM certificates", caBundle)
+		}
+		cfg.RootCAs = pool
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if insecure {
+		slog.Warn("TLS certificate verification is DISABLED for registry connections (--insecure-skip-verify): anyone on the network path can impersonate the registry and read its credentials")
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = cfg
+	return &http.Client{Transport: transport}, nil
+}
+
+// parseEndpointURL validates an --endpoint-url override: an absolute http or
+// https URL whose host is the private (VPC or Private Service Connect)
+// endpoint to call instead of the public API.
+func parseEndpointURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid endpoint URL %q: %w", raw, err)
+	}
+	if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return nil, fmt.Errorf("invalid endpoint URL %q: want https://host[:port]", raw)
+	}
+	return u, nil
+}
+
+// grpcEndpoint converts an endpoint URL into the host:port form gRPC clients
+// dial, defaulting to port 443.
+func grpcEndpoint(u *url.URL) string {
+	if u.Port() != "" {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), "443")
+}
+
+// applyProxy routes every outgoing connection through raw by exporting it as
+// HTTPS_PROXY and HTTP_PROXY before any client is created. Both the AWS and
+// Google HTTP transports and the gRPC Artifact Registry client read these
+// variables, and NO_PROXY exclusions keep working. An empty raw leaves the
+// environment as it is.
+func applyProxy(raw string) error {
+	if raw == "" {
+		if p := os.Getenv("HTTPS_PROXY"); p != "" {
+			slog.Debug("Using proxy from environment", "proxy", redactProxy(p))
+		}
+		return nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return fmt.Errorf("invalid proxy URL %q: %w", raw, err)
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return fmt.Errorf("invalid proxy URL %q: scheme must be http, https or socks5", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("invalid proxy URL %q: missing host", raw)
+	}
+	for _, name := range []string{"HTTPS_PROXY", "HTTP_PROXY"} {
+		if err := os.Setenv(name, raw); err != nil {
+			return fmt.Errorf("set %s: %w", name, err)
+		}
+	}
+	slog.Debug("Using proxy", "proxy", redactProxy(raw))
+	return nil
+}
+
+// redactProxy hides the password of a proxy URL for logging.
+func redactProxy(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "(invalid)"
+	}
+	return u.Redacted()
+}
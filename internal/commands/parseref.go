@@ -0,0 +1,88 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ppiankov/ecrspectre/internal/imageref"
+	"github.com/spf13/cobra"
+)
+
+var parseRefFlags struct {
+	format string
+}
+
+var parseRefCmd = &cobra.Command{
+	Use:   "parse-ref <image-reference>...",
+	Short: "Show how image references are parsed",
+	Long: `Parse container image references the way ecrspectre matches deployed and
+pulled images, and print the registry host, repository, tag, digest and
+provider, with the account and region of ECR references and the project,
+location and repository of Artifact Registry references. Invalid references
+exit with code 4.`,
+	Example: `  ecrspectre parse-ref 123456789012.dkr.ecr.us-east-1.amazonaws.com/team/api:v1
+  ecrspectre parse-ref --format json us-docker.pkg.dev/my-project/images/app@sha256:...`,
+	RunE: runParseRef,
+}
+
+func init() {
+	parseRefCmd.Flags().StringVar(&parseRefFlags.format, "format", "text", "Output format: text or json")
+}
+
+func runParseRef(cmd *cobra.Command, args []string) error {
+	if len(args) == 0 {
+		return configError(fmt.Errorf("at least one image reference is required"))
+	}
+	if parseRefFlags.format != "text" && parseRefFlags.format != "json" {
+		return configError(fmt.Errorf("unsupported format %q (use text or json)", parseRefFlags.format))
+	}
+	refs := make([]imageref.Reference, 0, len(args))
+	for _, arg := range args {
+		ref, err := imageref.Parse(arg)
+		if err != nil {
+			return configError(fmt.Errorf("parse %q: %w", arg, err))
+		}
+		refs = append(refs, ref)
+	}
+
+	if parseRefFlags.format == "json" {
+		enc := json.NewEncoder(cmd.OutOrStdout())
+		enc.SetIndent("", "  ")
+		return enc.Encode(refs)
+	}
+	return writeRefs(cmd.OutOrStdout(), refs)
+}
+
+func writeRefs(w io.Writer, refs []imageref.Reference) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for i, ref := range refs {
+		if i > 0 {
+			_, _ = fmt.Fprintln(tw)
+		}
+		host := ref.Host
+		if host == "" {
+			host = "docker.io (implied)"
+		}
+		fields := [][2]string{
+			{"reference", ref.String()},
+			{"host", host},
+			{"repository", ref.Repository},
+			{"tag", ref.Tag},
+			{"digest", ref.Digest},
+			{"provider", string(ref.Provider)},
+			{"account", ref.Account},
+			{"region", ref.Region},
+			{"project", ref.Project},
+			{"location", ref.Location},
+			{"ar repository", ref.ARRepository},
+		}
+		for _, f := range fields {
+			if f[1] != "" {
+				_, _ = fmt.Fprintf(tw, "%s:\t%s\n", f[0], f[1])
+			}
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,135 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/apistats"
+	"github.com/ppiankov/ecrspectre/internal/console"
+	"github.com/ppiankov/ecrspectre/internal/dashboard"
+	"github.com/ppiankov/ecrspectre/internal/logging"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// progressSink writes scan progress events as "[region] message" lines or,
+// for --progress-format ndjson, one JSON object per line for wrapper UIs and
+// CI plugins. With --dashboard, events also feed a live dashboard on stderr,
+// which then replaces progress lines there. Multi-project and multi-target
+// scans report from several goroutines, so events go through one dispatcher
+// and are written by its consumer alone: lines from one scanner keep their
+// order, and lines from different scanners never interleave mid-line.
+type progressSink struct {
+	events *registry.ProgressDispatcher[progressEvent]
+	w      io.Writer // nil when only the dashboard is shown
+	ndjson bool
+	runID  string
+	dash   *dashboard.Dashboard
+	close  func() error
+}
+
+// dashboardInterval is how often the dashboard is redrawn.
+const dashboardInterval = 500 * time.Millisecond
+
+// progressEvent is the NDJSON form of a progress event. Project is set for
+// multi-project GCP scans.
+type progressEvent struct {
+	RunID   string `json:"run_id,omitempty"`
+	Project string `json:"project,omitempty"`
+	registry.ScanProgress
+}
+
+// newProgressSink validates the progress flags and opens the output: stderr,
+// or a file or named pipe given by --progress-output. NDJSON events carry
+// runID. With dash set and stderr a terminal, a live dashboard takes over
+// stderr, holding log output back until the scan ends. It returns nil when
+// progress is disabled and no dashboard is shown.
+func newProgressSink(disabled bool, format, output, runID string, dash bool) (*progressSink, error) {
+	switch format {
+	case "text", "ndjson":
+	default:
+		return nil, configError(fmt.Errorf("unsupported progress format: %s (use text or ndjson)", format))
+	}
+	if dash && !console.IsTerminal(os.Stderr) {
+		slog.Warn("--dashboard needs a terminal on stderr, showing progress lines instead")
+		dash = false
+	}
+	if disabled && !dash {
+		return nil, nil
+	}
+	s := &progressSink{ndjson: format == "ndjson", runID: runID, close: func() error { return nil }}
+	if !disabled {
+		s.w = os.Stderr
+		if output != "" {
+			f, err := os.OpenFile(output, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+			if err != nil {
+				return nil, fmt.Errorf("open progress output: %w", err)
+			}
+			s.w, s.close = f, f.Close
+		} else if dash {
+			s.w = nil
+		}
+	}
+	if dash {
+		s.startDashboard(os.Stderr)
+	}
+	s.events = registry.NewProgressDispatcher(registry.ProgressBuffer, s.write)
+	return s, nil
+}
+
+// startDashboard shows the dashboard on w and holds log output back for it.
+func (s *progressSink) startDashboard(w io.Writer) {
+	apistats.Reset()
+	s.dash = dashboard.New(w, s.runID, apistats.Counts)
+	logging.SetOutput(s.dash)
+	s.dash.Start(dashboardInterval)
+}
+
+// callback returns the progress function handed to a scanner, or nil when
+// the sink is disabled. It is safe for concurrent use. A non-empty project
+// prefixes text lines and is included in NDJSON events.
+func (s *progressSink) callback(project string) func(registry.ScanProgress) {
+	if s == nil {
+		return nil
+	}
+	return func(p registry.ScanProgress) {
+		s.events.Send(progressEvent{RunID: s.runID, Project: project, ScanProgress: p})
+	}
+}
+
+// write shows one event. It runs on the dispatcher's goroutine only.
+func (s *progressSink) write(e progressEvent) {
+	if s.dash != nil {
+		s.dash.Update(e.Project, e.ScanProgress)
+	}
+	switch {
+	case s.w == nil:
+	case s.ndjson:
+		_ = json.NewEncoder(s.w).Encode(e)
+	case e.Project != "":
+		_, _ = fmt.Fprintf(s.w, "[%s/%s] %s\n", e.Project, e.Region, e.Message)
+	default:
+		_, _ = fmt.Fprintf(s.w, "[%s] %s\n", e.Region, e.Message)
+	}
+}
+
+// Close writes the events still queued, stops the dashboard, restoring log
+// output to stderr, and releases the progress output file, if one was
+// opened. It is safe to call twice.
+func (s *progressSink) Close() error {
+	if s == nil {
+		return nil
+	}
+	s.events.Close()
+	if s.dash != nil {
+		s.dash.Stop()
+		logging.SetOutput(os.Stderr)
+		s.dash = nil
+	}
+	closeFn := s.close
+	s.close = func() error { return nil }
+	return closeFn()
+}
@@ -0,0 +1,153 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/logging"
+	"github.com/ppiankov/ecrspectre/internal/remediate"
+	"github.com/ppiankov/ecrspectre/internal/report"
+	"github.com/spf13/cobra"
+)
+
+var remediateFlags struct {
+	input        string
+	untaggedDays int
+	keepTagged   int
+	outputFile   string
+	openPR       bool
+	repo         string
+	base         string
+	branch       string
+	path         string
+}
+
+var remediateCmd = &cobra.Command{
+	Use:   "remediate",
+	Short: "Generate Terraform lifecycle policies for flagged ECR repositories, optionally as a pull request",
+	Long: `Generate an aws_ecr_lifecycle_policy Terraform resource for every ECR
+repository that a spectre/v1 JSON report of an aws scan flags as
+NO_LIFECYCLE_POLICY. Each policy expires untagged images and turns the
+report's retention recommendations for the repository into tag rules.
+
+Without --open-pr the Terraform is written to stdout or --output. With
+--open-pr, the infrastructure repository given by --repo is cloned with git,
+one file per region is written under --path on a new branch, and a pull
+request listing the waste behind each policy is opened. The GitHub token is
+read from $GITHUB_TOKEN (or $GH_TOKEN) and needs contents and pull request
+write access.`,
+	Example: `  ecrspectre aws --format json -o report.json
+  ecrspectre remediate --input report.json
+  ecrspectre remediate --input report.json --open-pr --repo org/infrastructure --path terraform/ecr`,
+	RunE: runRemediate,
+}
+
+func init() {
+	remediateCmd.Flags().StringVar(&remediateFlags.input, "input", "", "JSON report of an aws scan (required)")
+	remediateCmd.Flags().IntVar(&remediateFlags.untaggedDays, "untagged-days", remediate.DefaultUntaggedDays, "Expire untagged images this many days after push")
+	remediateCmd.Flags().IntVar(&remediateFlags.keepTagged, "keep-tagged", 0, "Also keep only the newest N images of any tag (0 disables)")
+	remediateCmd.Flags().StringVarP(&remediateFlags.outputFile, "output", "o", "", "Output file path (default: stdout; ignored with --open-pr)")
+	remediateCmd.Flags().BoolVar(&remediateFlags.openPR, "open-pr", false, "Open a GitHub pull request adding the policies to --repo")
+	remediateCmd.Flags().StringVar(&remediateFlags.repo, "repo", "", "GitHub repository owner/name to open the pull request in")
+	remediateCmd.Flags().StringVar(&remediateFlags.base, "base", "", "Branch to merge into (default: the repository's default branch)")
+	remediateCmd.Flags().StringVar(&remediateFlags.branch, "branch", "", "Branch to push (default: ecrspectre/lifecycle-<date>)")
+	remediateCmd.Flags().StringVar(&remediateFlags.path, "path", "", "Directory in the repository for the Terraform files (default: the root)")
+}
+
+var githubRepo = regexp.MustCompile(`^[A-Za-z0-9_.-]+/[A-Za-z0-9_.-]+$`)
+
+func runRemediate(cmd *cobra.Command, _ []string) error {
+	if remediateFlags.input == "" {
+		return configError(fmt.Errorf("--input is required"))
+	}
+	if remediateFlags.untaggedDays < 1 || remediateFlags.keepTagged < 0 {
+		return configError(fmt.Errorf("--untagged-days must be at least 1 and --keep-tagged not negative"))
+	}
+	token := os.Getenv("GITHUB_TOKEN")
+	if token == "" {
+		token = os.Getenv("GH_TOKEN")
+	}
+	if remediateFlags.openPR {
+		if !githubRepo.MatchString(remediateFlags.repo) {
+			return configError(fmt.Errorf("--open-pr needs --repo owner/name, got %q", remediateFlags.repo))
+		}
+		if token == "" {
+			return configError(fmt.Errorf("--open-pr needs a GitHub token in $GITHUB_TOKEN or $GH_TOKEN"))
+		}
+	}
+	expandPaths(&remediateFlags.input, &remediateFlags.outputFile)
+
+	data, err := report.ReadJSONFile(remediateFlags.input)
+	if err != nil {
+		return err
+	}
+	if data.RunID != "" {
+		logging.SetRunID(data.RunID)
+	}
+	if data.Config.Provider != "aws" {
+		return configError(fmt.Errorf("%s is a %q report; remediate needs the JSON report of an aws scan", remediateFlags.input, data.Config.Provider))
+	}
+	repos := remediate.Plan(data.Findings, data.Recommendations, remediate.Options{
+		UntaggedDays: remediateFlags.untaggedDays,
+		KeepTagged:   remediateFlags.keepTagged,
+	})
+	if len(repos) == 0 {
+		_, _ = fmt.Fprintln(cmd.OutOrStdout(), "No repositories without a lifecycle policy in the report")
+		return nil
+	}
+
+	if !remediateFlags.openPR {
+		w, closeOutput, err := openOutput(remediateFlags.outputFile, "")
+		if err != nil {
+			return err
+		}
+		err = remediate.WriteTerraform(w, repos, data.RunID)
+		if closeErr := closeOutput(); err == nil && closeErr != nil {
+			err = fmt.Errorf("close output file: %w", closeErr)
+		}
+		return err
+	}
+
+	files := make(map[string][]byte)
+	for _, region := range remediate.Regions(repos) {
+		var regional []remediate.Repository
+		for _, r := range repos {
+			if r.Region == region {
+				regional = append(regional, r)
+			}
+		}
+		var buf bytes.Buffer
+		if err := remediate.WriteTerraform(&buf, regional, data.RunID); err != nil {
+			return err
+		}
+		files[path.Join(remediateFlags.path, remediate.FileName(region))] = buf.Bytes()
+	}
+	branch := remediateFlags.branch
+	if branch == "" {
+		branch = "ecrspectre/lifecycle-" + time.Now().UTC().Format("20060102-150405")
+	}
+	title, body := remediate.PullRequestText(repos, data.RunID)
+	pr, err := remediate.NewGitHub(token).OpenPR(cmd.Context(), remediate.Change{
+		Repo:   remediateFlags.repo,
+		Base:   remediateFlags.base,
+		Branch: branch,
+		Files:  files,
+		Title:  title,
+		Body:   body,
+	})
+	if errors.Is(err, remediate.ErrNoChanges) {
+		_, _ = fmt.Fprintf(cmd.OutOrStdout(), "No pull request opened: %s already contains these policies\n", remediateFlags.repo)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, _ = fmt.Fprintf(cmd.OutOrStdout(), "Opened pull request #%d for %d repositories ($%.2f/mo waste): %s\n",
+		pr.Number, len(repos), remediate.TotalWaste(repos), pr.URL)
+	return nil
+}
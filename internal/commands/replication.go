@@ -0,0 +1,107 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/config"
+	"github.com/ppiankov/ecrspectre/internal/ecr"
+	"github.com/ppiankov/ecrspectre/internal/replication"
+	"github.com/spf13/cobra"
+)
+
+var replicationFlags struct {
+	routes       []string
+	profile      string
+	repos        []string
+	excludeRepos []string
+	format       string
+	outputFile   string
+}
+
+var replicationPlanCmd = &cobra.Command{
+	Use:   "replication-plan",
+	Short: "Estimate the cost of enabling ECR cross-region replication",
+	Long: `Estimate what a replication topology would add to the monthly bill, from
+the current inventory of the source regions: storage of the replicas at each
+destination's rate, and inter-region transfer of the images pushed to the
+source in the last 30 days. Nothing is changed in the registries.
+
+The storage estimate assumes each destination ends up holding the same images
+as its source, which is where it settles when the same lifecycle policies
+apply on both sides.`,
+	Example: `  ecrspectre replication-plan --route us-east-1=eu-west-1,ap-southeast-1
+  ecrspectre replication-plan --route us-east-1=eu-west-1 --route eu-west-1=us-east-1 --repos 'prod/*' --format json`,
+	RunE: runReplicationPlan,
+}
+
+func init() {
+	replicationPlanCmd.Flags().StringArrayVar(&replicationFlags.routes, "route", nil, "Replication route source=destination[,destination...] (repeatable, required)")
+	replicationPlanCmd.Flags().StringVar(&replicationFlags.profile, "profile", "", "AWS profile name")
+	replicationPlanCmd.Flags().StringSliceVar(&replicationFlags.repos, "repos", nil, "Only replicate repositories matching these globs or re:regex patterns (prefix ! to exclude)")
+	replicationPlanCmd.Flags().StringSliceVar(&replicationFlags.excludeRepos, "exclude-repos", nil, "Do not replicate repositories matching these globs or re:regex patterns")
+	replicationPlanCmd.Flags().StringVar(&replicationFlags.format, "format", "text", "Output format: text, json")
+	replicationPlanCmd.Flags().StringVarP(&replicationFlags.outputFile, "output", "o", "", "Output file path (default: stdout)")
+}
+
+func runReplicationPlan(cmd *cobra.Command, _ []string) error {
+	if len(replicationFlags.routes) == 0 {
+		return configError(fmt.Errorf("--route is required"))
+	}
+	if replicationFlags.format != "text" && replicationFlags.format != "json" {
+		return configError(fmt.Errorf("unsupported format: %s (use text or json)", replicationFlags.format))
+	}
+	routes, err := replication.ParseRoutes(replicationFlags.routes)
+	if err != nil {
+		return configError(err)
+	}
+	expandPaths(&replicationFlags.outputFile)
+
+	cfg, err := config.Load(".")
+	if err != nil {
+		slog.Warn("Failed to load config file", "error", err)
+	}
+	filter, err := buildRepoFilter(cfg, replicationFlags.repos, replicationFlags.excludeRepos)
+	if err != nil {
+		return configError(err)
+	}
+	profile := replicationFlags.profile
+	if profile == "" {
+		profile = cfg.Profile
+	}
+
+	ctx := cmd.Context()
+	since := time.Now().AddDate(0, 0, -30)
+	var repos []replication.Repository
+	for _, route := range routes {
+		client, err := ecr.NewClient(ctx, profile, route.Source, "")
+		if err != nil {
+			return err
+		}
+		inv, err := ecr.Inventory(ctx, client.NewECRClient(), filter, since)
+		if err != nil {
+			return fmt.Errorf("%s: %w", route.Source, err)
+		}
+		for _, r := range inv {
+			repos = append(repos, replication.Repository{
+				Name:        r.Name,
+				Region:      route.Source,
+				Images:      r.Images,
+				SizeBytes:   r.SizeBytes,
+				PushedBytes: r.PushedBytes,
+			})
+		}
+	}
+	plan := replication.Estimate(routes, repos)
+
+	w, closeOutput, err := openOutput(replicationFlags.outputFile, profile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = closeOutput() }()
+	if replicationFlags.format == "json" {
+		return replication.WriteJSON(w, plan)
+	}
+	return replication.WriteText(w, plan)
+}
@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/ppiankov/ecrspectre/internal/archive"
+	"github.com/ppiankov/ecrspectre/internal/config"
+	"github.com/ppiankov/ecrspectre/internal/report"
+)
+
+// reportTarget is one report a scan writes: a format and its output, stdout
+// when empty.
+type reportTarget struct {
+	format string
+	output string
+}
+
+// parseReportTargets pairs comma-separated --format and --output values,
+// e.g. "json,text" with "report.json," writes JSON to report.json and text
+// to stdout. Several formats need one output per format, at most one of
+// them stdout, and no output may be used twice.
+func parseReportTargets(format, output string) ([]reportTarget, error) {
+	formats := strings.Split(format, ",")
+	outputs := strings.Split(output, ",")
+	if len(formats) > 1 && output == "" {
+		return nil, configError(fmt.Errorf("--format %s needs --output with one path per format, e.g. report.json,report.sarif", format))
+	}
+	if len(outputs) != len(formats) {
+		return nil, configError(fmt.Errorf("--format lists %d formats but --output %d outputs", len(formats), len(outputs)))
+	}
+
+	targets := make([]reportTarget, len(formats))
+	seen := make(map[string]bool)
+	for i := range formats {
+		t := reportTarget{format: strings.TrimSpace(formats[i]), output: strings.TrimSpace(outputs[i])}
+		if !slices.Contains(reportFormats, t.format) {
+			return nil, configError(fmt.Errorf("unsupported format: %s (use %s)", t.format, strings.Join(reportFormats, ", ")))
+		}
+		if t.format == "sqlite" && (t.output == "" || isRemoteOutput(t.output)) {
+			return nil, configError(fmt.Errorf("--format sqlite needs a local database file as its --output"))
+		}
+		if t.format == "dataset" && t.output == "" {
+			return nil, configError(fmt.Errorf("--format dataset needs a directory, s3:// or gs:// prefix as its --output"))
+		}
+		expandPaths(&t.output)
+		if seen[t.output] {
+			if t.output == "" {
+				return nil, configError(fmt.Errorf("--output names stdout for more than one format"))
+			}
+			return nil, configError(fmt.Errorf("--output %s is used for more than one format", t.output))
+		}
+		seen[t.output] = true
+		targets[i] = t
+	}
+	return targets, nil
+}
+
+// loadReportTemplate parses the --template file when the template format
+// is among the formats. It returns nil when no template is needed, and a
+// config error for a template format without a file or a file without the
+// template format.
+func loadReportTemplate(format, path string) (*template.Template, error) {
+	wanted := hasFormat(format, "template")
+	switch {
+	case wanted && path == "":
+		return nil, configError(fmt.Errorf("--format template needs --template FILE"))
+	case !wanted && path != "":
+		return nil, configError(fmt.Errorf("--template is only used with --format template"))
+	case !wanted:
+		return nil, nil
+	}
+	expandPaths(&path)
+	tmpl, err := report.ParseTemplate(path)
+	if err != nil {
+		return nil, configError(err)
+	}
+	return tmpl, nil
+}
+
+// hasFormat reports whether a comma-separated --format value includes name.
+func hasFormat(format, name string) bool {
+	for _, f := range strings.Split(format, ",") {
+		if strings.TrimSpace(f) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// reportsFlags joins the reports config block into --format and --output
+// values. It returns the flags unchanged unless they are at their defaults.
+func reportsFlags(format, output string, reports []config.Report) (string, string) {
+	if len(reports) == 0 || format != "text" || output != "" {
+		return format, output
+	}
+	formats := make([]string, len(reports))
+	outputs := make([]string, len(reports))
+	for i, r := range reports {
+		formats[i], outputs[i] = r.Format, r.Output
+	}
+	return strings.Join(formats, ","), strings.Join(outputs, ",")
+}
+
+// reportOutputs returns the files of an --output value that hold a report
+// on disk, skipping stdout, object storage and dataset directories.
+func reportOutputs(output string) []string {
+	var files []string
+	for _, o := range strings.Split(output, ",") {
+		if o = strings.TrimSpace(o); o != "" && !isRemoteOutput(o) {
+			expandPaths(&o)
+			if fi, err := os.Stat(o); err == nil && fi.IsDir() {
+				continue
+			}
+			files = append(files, o)
+		}
+	}
+	return files
+}
+
+// datasetStore opens the directory or object storage prefix a dataset is
+// written to. S3 uses profile, or the default AWS credentials when empty.
+func datasetStore(output, profile string) (report.ObjectStore, error) {
+	dest, err := archive.ParseDestination(output)
+	if err != nil {
+		return nil, configError(fmt.Errorf("--output: %w", err))
+	}
+	return archiveStore(context.Background(), dest, profile, "")
+}
@@ -0,0 +1,105 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/archive"
+	"github.com/spf13/cobra"
+)
+
+var restoreFlags struct {
+	from         string
+	region       string
+	profile      string
+	bucketRegion string
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <digest|tag>",
+	Short: "Push an archived image back to its original repository",
+	Long: `Find an image in the index of an archive written by ecrspectre archive and
+push it back to the repository it came from. The reference is a digest,
+repository@digest, a tag or repository:tag.
+
+The tarball is checked against the SHA-256 recorded in the index before
+anything is pushed, and manifests are pushed byte for byte, so the restored
+image has its original digest. Each archived tag is pointed at it again,
+unless the repository has since moved that tag to another image.`,
+	Example: `  ecrspectre restore sha256:4b1f... --from s3://my-archive/ecr
+  ecrspectre restore team/api:v1.4.2 --from s3://my-archive/ecr --region us-east-1`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestore,
+}
+
+func init() {
+	restoreCmd.Flags().StringVar(&restoreFlags.from, "from", "", "Archive location: s3://bucket/prefix, gs://bucket/prefix or a directory (required)")
+	restoreCmd.Flags().StringVar(&restoreFlags.region, "region", "", "Only match images archived from this region or location")
+	restoreCmd.Flags().StringVar(&restoreFlags.profile, "profile", "", "AWS profile for ECR and S3")
+	restoreCmd.Flags().StringVar(&restoreFlags.bucketRegion, "bucket-region", "", "Region of the S3 bucket (default: the profile's region)")
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	if restoreFlags.from == "" {
+		return configError(fmt.Errorf("--from is required"))
+	}
+	dest, err := archive.ParseDestination(restoreFlags.from)
+	if err != nil {
+		return configError(err)
+	}
+	if dest.Scheme == "" {
+		expandPaths(&dest.Prefix)
+	}
+
+	ctx := cmd.Context()
+	store, err := archiveStore(ctx, dest, restoreFlags.profile, restoreFlags.bucketRegion)
+	if err != nil {
+		return err
+	}
+	index, err := archive.LoadIndex(ctx, store)
+	if err != nil {
+		return err
+	}
+	matches := index.Find(args[0], restoreFlags.region)
+	switch len(matches) {
+	case 0:
+		return configError(fmt.Errorf("no archived image in %s matches %s", restoreFlags.from, args[0]))
+	case 1:
+	default:
+		refs := make([]string, len(matches))
+		for i, e := range matches {
+			refs[i] = fmt.Sprintf("  %s (%s)", e.Image, e.Region)
+		}
+		return configError(fmt.Errorf("%s matches %d archived images; name one as repository@digest with --region:\n%s",
+			args[0], len(matches), strings.Join(refs, "\n")))
+	}
+	entry := matches[0]
+
+	target, err := newArchiveSources(restoreFlags.profile).get(ctx, entry.RegistryImage())
+	if err != nil {
+		return err
+	}
+	res, err := archive.Restore(ctx, store, target, entry)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	entry.Deleted = false
+	entry.RestoredAt = &now
+	index.Add(entry)
+	if err := index.Save(ctx, store); err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	_, _ = fmt.Fprintf(w, "Restored %s (%s) from %s, %d blobs uploaded\n", entry.Image, entry.Region, entry.Object, res.Blobs)
+	if len(res.Tags) > 0 {
+		_, _ = fmt.Fprintf(w, "Tags: %s\n", strings.Join(res.Tags, ", "))
+	}
+	if len(res.SkippedTags) > 0 {
+		_, _ = fmt.Fprintf(w, "Not restored, now on another image: %s\n", strings.Join(res.SkippedTags, ", "))
+	}
+	return nil
+}
@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"github.com/ppiankov/ecrspectre/internal/config"
+	"github.com/ppiankov/ecrspectre/internal/console"
 	"github.com/ppiankov/ecrspectre/internal/logging"
 	"github.com/spf13/cobra"
 )
@@ -19,8 +21,34 @@ var rootCmd = &cobra.Command{
 and GCP Artifact Registry that accumulate storage costs silently.
 
 Each finding includes an estimated monthly waste in USD.`,
-	PersistentPreRun: func(_ *cobra.Command, _ []string) {
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		console.Prepare()
 		logging.Init(verbose)
+		cfg, _ := config.Load(".")
+		proxy := proxyURL
+		if proxy == "" {
+			proxy = cfg.ProxyURL
+		}
+		if err := applyProxy(proxy); err != nil {
+			return configError(err)
+		}
+		tlsOpts := registryTLS
+		if tlsOpts.caBundle == "" {
+			tlsOpts.caBundle = cfg.CABundle
+		}
+		if tlsOpts.clientCert == "" && tlsOpts.clientKey == "" {
+			tlsOpts.clientCert, tlsOpts.clientKey = cfg.ClientCert, cfg.ClientKey
+		}
+		if !tlsOpts.insecure {
+			tlsOpts.insecure = cfg.InsecureSkipVerify
+		}
+		client, err := newRegistryHTTPClient(tlsOpts.caBundle, tlsOpts.clientCert, tlsOpts.clientKey, tlsOpts.insecure)
+		if err != nil {
+			return configError(err)
+		}
+		registryHTTPClient = client
+		startUpdateCheck(cmd)
+		return nil
 	},
 	SilenceUsage:  true,
 	SilenceErrors: true,
@@ -31,13 +59,39 @@ func Execute(v, c, d string) error {
 	version = v
 	commit = c
 	date = d
-	return rootCmd.Execute()
+	err := rootCmd.Execute()
+	// Printed here rather than in a post-run hook, which cobra skips when
+	// the command fails, so that failing scheduled jobs see the notice too.
+	printUpdateNotice(rootCmd.ErrOrStderr())
+	return err
 }
 
 func init() {
 	rootCmd.PersistentFlags().BoolVar(&verbose, "verbose", false, "Enable verbose logging")
+	rootCmd.PersistentFlags().StringVar(&proxyURL, "proxy-url", "", "Send all API traffic through this proxy (default: HTTPS_PROXY / NO_PROXY)")
+	rootCmd.PersistentFlags().StringVar(&registryTLS.caBundle, "ca-bundle", "", "PEM CA certificates to trust for registry connections, in addition to the system roots")
+	rootCmd.PersistentFlags().StringVar(&registryTLS.clientCert, "client-cert", "", "PEM client certificate for registries that require mutual TLS (with --client-key)")
+	rootCmd.PersistentFlags().StringVar(&registryTLS.clientKey, "client-key", "", "PEM private key of --client-cert")
+	rootCmd.PersistentFlags().BoolVar(&registryTLS.insecure, "insecure-skip-verify", false, "Do not verify registry TLS certificates (INSECURE: testing only)")
+	rootCmd.SetFlagErrorFunc(func(_ *cobra.Command, err error) error {
+		return configError(err)
+	})
+	rootCmd.AddCommand(allCmd)
+	rootCmd.AddCommand(archiveCmd)
+	rootCmd.AddCommand(auditCmd)
 	rootCmd.AddCommand(awsCmd)
 	rootCmd.AddCommand(gcpCmd)
+	rootCmd.AddCommand(demoCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(digestCmd)
+	rootCmd.AddCommand(exportCmd)
 	rootCmd.AddCommand(initCmd)
+	rootCmd.AddCommand(leaderboardCmd)
+	rootCmd.AddCommand(parseRefCmd)
+	rootCmd.AddCommand(remediateCmd)
+	rootCmd.AddCommand(replicationPlanCmd)
+	rootCmd.AddCommand(restoreCmd)
+	rootCmd.AddCommand(selfUpdateCmd)
+	rootCmd.AddCommand(trendCmd)
 	rootCmd.AddCommand(versionCmd)
 }
@@ -0,0 +1,151 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/ppiankov/ecrspectre/internal/awsapi"
+	"github.com/ppiankov/ecrspectre/internal/config"
+	"github.com/ppiankov/ecrspectre/internal/ecr"
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// labelSelector builds an account or project selector. Flags replace the
+// corresponding config lists when set.
+func labelSelector(cfgInclude, cfgExclude, flagInclude, flagExclude []string) (registry.LabelSelector, error) {
+	include := cfgInclude
+	if len(flagInclude) > 0 {
+		include = flagInclude
+	}
+	exclude := cfgExclude
+	if len(flagExclude) > 0 {
+		exclude = flagExclude
+	}
+	return registry.NewLabelSelector(include, exclude)
+}
+
+// projectLabeler reads GCP project labels.
+type projectLabeler interface {
+	ProjectLabels(ctx context.Context, project string) (map[string]string, error)
+}
+
+// selectProjects keeps the projects whose labels match sel. Projects whose
+// labels cannot be read are left out and reported, so a permission gap never
+// widens the scan.
+func selectProjects(ctx context.Context, api projectLabeler, projects []string, sel registry.LabelSelector) ([]string, []string) {
+	if sel.Empty() {
+		return projects, nil
+	}
+	var selected, errs []string
+	for _, project := range projects {
+		labels, err := api.ProjectLabels(ctx, project)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if sel.Match(labels) {
+			selected = append(selected, project)
+		} else {
+			slog.Info("Skipping project outside scope", "project", project, "scope", sel.String())
+		}
+	}
+	return selected, errs
+}
+
+// accountTagger reads the account ID of a target and the tags of an account.
+type accountTagger interface {
+	AccountID(ctx context.Context) (string, error)
+	AccountTags(ctx context.Context, accountID string) (map[string]string, error)
+}
+
+// accountSelected reports whether the account behind ids matches sel, reading
+// its tags through tags.
+func accountSelected(ctx context.Context, ids, tags accountTagger, sel registry.LabelSelector) (string, bool, error) {
+	account, err := ids.AccountID(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	labels, err := tags.AccountTags(ctx, account)
+	if err != nil {
+		return account, false, err
+	}
+	return account, sel.Match(labels), nil
+}
+
+// scopeTargets drops the targets of `ecrspectre all` outside the account and
+// project selectors: AWS targets by their account's Organizations tags, and
+// GCP targets' projects by label. Targets whose tags or labels cannot be read
+// are dropped and reported.
+func scopeTargets(ctx context.Context, targets []config.Target, accounts, projects registry.LabelSelector, orgProfile string) ([]config.Target, []string) {
+	if accounts.Empty() && projects.Empty() {
+		return targets, nil
+	}
+	var out []config.Target
+	var errs []string
+	var gcp *gcpapi.Caller
+	for _, t := range targets {
+		switch {
+		case t.Provider == "aws" && !accounts.Empty():
+			selected, err := awsTargetSelected(ctx, t, accounts, orgProfile)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: scope: %v", t.Name, err))
+				continue
+			}
+			if !selected {
+				continue
+			}
+		case t.Provider == "gcp" && !projects.Empty():
+			if gcp == nil {
+				api, err := gcpapi.NewCaller(ctx)
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("%s: scope: %v", t.Name, err))
+					continue
+				}
+				gcp = api
+			}
+			var projectErrs []string
+			t.Projects, projectErrs = selectProjects(ctx, gcp, t.Projects, projects)
+			for _, e := range projectErrs {
+				errs = append(errs, fmt.Sprintf("%s: scope: %s", t.Name, e))
+			}
+			if len(t.Projects) == 0 {
+				slog.Info("Skipping target outside scope", "target", t.Name, "scope", projects.String())
+				continue
+			}
+		}
+		out = append(out, t)
+	}
+	return out, errs
+}
+
+// awsTargetSelected reads the account of an AWS target with the target's
+// credentials and its tags with those of orgProfile, or the target's own.
+func awsTargetSelected(ctx context.Context, t config.Target, sel registry.LabelSelector, orgProfile string) (bool, error) {
+	region := ""
+	if len(t.Regions) > 0 {
+		region = t.Regions[0]
+	}
+	client, err := ecr.NewClient(ctx, t.Profile, region, "")
+	if err != nil {
+		return false, err
+	}
+	ids := awsapi.NewCaller(client.Config())
+	tags := ids
+	if orgProfile != "" && orgProfile != t.Profile {
+		org, err := ecr.NewClient(ctx, orgProfile, region, "")
+		if err != nil {
+			return false, err
+		}
+		tags = awsapi.NewCaller(org.Config())
+	}
+	account, selected, err := accountSelected(ctx, ids, tags, sel)
+	if err != nil {
+		return false, err
+	}
+	if !selected {
+		slog.Info("Skipping target outside scope", "target", t.Name, "account", account, "scope", sel.String())
+	}
+	return selected, nil
+}
@@ -0,0 +1,214 @@
+package commands
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/config"
+	"github.com/ppiankov/ecrspectre/internal/selfupdate"
+	"github.com/spf13/cobra"
+)
+
+// noUpdateCheckEnv disables the startup version notice when set to any value.
+const noUpdateCheckEnv = "ECRSPECTRE_NO_UPDATE_CHECK"
+
+// updateNoticeWait bounds how long a finished command waits for the
+// background version check before exiting without a notice.
+const updateNoticeWait = time.Second
+
+// releasePublicKey is the base64 Ed25519 public key that signs the
+// checksums.txt of official releases, set at build time with -ldflags -X.
+// Development builds leave it empty and verify checksums only.
+var releasePublicKey string
+
+var selfUpdateFlags struct {
+	check     bool
+	publicKey string
+}
+
+// updateCheckEnabled allows tests to turn off the background version check.
+var updateCheckEnabled = true
+
+// updateNotice receives the notice message from the background check.
+var updateNotice chan string
+
+var selfUpdateCmd = &cobra.Command{
+	Use:   "self-update",
+	Short: "Update ecrspectre to the latest release",
+	Long: `Download the latest GitHub release for this platform, verify it against the
+release checksums, and replace the running binary. Release builds also
+require the Ed25519 signature of the checksums (checksums.txt.sig) made with
+the release key built into the binary; --public-key verifies against another
+key.`,
+	RunE: runSelfUpdate,
+}
+
+func init() {
+	selfUpdateCmd.Flags().BoolVar(&selfUpdateFlags.check, "check", false, "Only report whether a newer release exists")
+	selfUpdateCmd.Flags().StringVar(&selfUpdateFlags.publicKey, "public-key", "", "PEM Ed25519 public key that must have signed checksums.txt (default: the release key built into the binary)")
+}
+
+func runSelfUpdate(cmd *cobra.Command, _ []string) error {
+	updater := selfupdate.New()
+	updater.HTTPClient = releaseHTTPClient(updater.HTTPClient.Timeout)
+	pub, err := updatePublicKey(selfUpdateFlags.publicKey)
+	if err != nil {
+		return err
+	}
+	updater.PublicKey = pub
+
+	rel, err := updater.Latest(cmd.Context())
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if !selfupdate.Newer(version, rel.Version()) {
+		_, err := fmt.Fprintf(out, "ecrspectre %s is up to date (latest release: %s)\n", version, rel.Version())
+		return err
+	}
+	if selfUpdateFlags.check {
+		_, err := fmt.Fprintf(out, "ecrspectre %s is available (current: %s): %s\n", rel.Version(), version, rel.HTMLURL)
+		return err
+	}
+
+	binary, err := updater.Download(cmd.Context(), rel)
+	if err != nil {
+		return err
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("locate executable: %w", err)
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return fmt.Errorf("resolve executable: %w", err)
+	}
+	if err := selfupdate.Install(exe, binary); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "Updated ecrspectre %s -> %s\n", version, rel.Version())
+	return err
+}
+
+// startUpdateCheck looks up the latest release in the background so a notice
+// can be printed when the command finishes. Lookups are cached for a day and
+// can be disabled with update_check: false or ECRSPECTRE_NO_UPDATE_CHECK.
+// Commands that never go online, and --replay runs, skip it.
+func startUpdateCheck(cmd *cobra.Command) {
+	updateNotice = nil
+	if !updateCheckEnabled || offline(cmd) || os.Getenv(noUpdateCheckEnv) != "" {
+		return
+	}
+	if !selfupdate.IsRelease(version) {
+		return
+	}
+	cfg, _ := config.Load(".")
+	if cfg.UpdateCheck != nil && !*cfg.UpdateCheck {
+		return
+	}
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return
+	}
+
+	ch := make(chan string, 1)
+	updateNotice = ch
+	current := version
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		updater := selfupdate.New()
+		updater.HTTPClient = releaseHTTPClient(updater.HTTPClient.Timeout)
+		latest, err := updater.CachedLatest(ctx, filepath.Join(cacheDir, "ecrspectre", "version-check.json"), time.Now())
+		if err != nil {
+			slog.Debug("Version check failed", "error", err)
+			ch <- ""
+			return
+		}
+		if selfupdate.Newer(current, latest) {
+			ch <- fmt.Sprintf("A newer ecrspectre release is available: %s (current: %s). Run 'ecrspectre self-update' to upgrade.", latest, current)
+			return
+		}
+		ch <- ""
+	}()
+}
+
+// offline reports whether cmd skips the background version check:
+// self-update looks itself, the other commands listed never go online, and
+// --replay answers API calls from recordings.
+func offline(cmd *cobra.Command) bool {
+	switch cmd {
+	case selfUpdateCmd, demoCmd, diffCmd, digestCmd, exportDashboardCmd, initCmd, leaderboardCmd, parseRefCmd, trendCmd, versionCmd:
+		return true
+	}
+	replay := cmd.Flags().Lookup("replay")
+	return replay != nil && replay.Value.String() != ""
+}
+
+// releaseHTTPClient returns a client for GitHub release lookups that goes
+// through the configured proxy and trusts the configured CA bundle, like
+// registry calls.
+func releaseHTTPClient(timeout time.Duration) *http.Client {
+	return &http.Client{Transport: registryHTTPClient.Transport, Timeout: timeout}
+}
+
+// printUpdateNotice writes the version notice if the background check has
+// finished in time. It never fails the command.
+func printUpdateNotice(w io.Writer) {
+	if updateNotice == nil {
+		return
+	}
+	select {
+	case msg := <-updateNotice:
+		if msg != "" {
+			_, _ = fmt.Fprintln(w, msg)
+		}
+	case <-time.After(updateNoticeWait):
+	}
+}
+
+// updatePublicKey returns the key the release checksums must be signed with:
+// the key at path if set, else the release key built into the binary, else
+// nil for no signature check.
+func updatePublicKey(path string) (ed25519.PublicKey, error) {
+	if path != "" {
+		return loadPublicKey(path)
+	}
+	if releasePublicKey == "" {
+		return nil, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(releasePublicKey)
+	if err != nil || len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid built-in release public key")
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+func loadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read public key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("public key %s is not PEM encoded", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse public key: %w", err)
+	}
+	pub, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key %s is not an Ed25519 key", path)
+	}
+	return pub, nil
+}
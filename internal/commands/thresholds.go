@@ -0,0 +1,55 @@
+package commands
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+// Registry sizes at which a permissive threshold turns into thousands of
+// findings.
+const (
+	noisyRepoCount     = 100
+	largeRegistryRepos = 500
+	findingFlood       = 10000
+)
+
+// thresholds are the settings that decide how many findings a scan reports.
+type thresholds struct {
+	staleDays      int
+	maxSizeMB      int
+	minMonthlyCost float64
+	rollupTail     bool
+}
+
+// startupWarnings flags thresholds that are noisy on any registry, so they
+// can be fixed before a long scan.
+func (t thresholds) startupWarnings() []string {
+	var warnings []string
+	if t.maxSizeMB > 0 && t.maxSizeMB < 100 {
+		warnings = append(warnings, fmt.Sprintf("max_size_mb %d is below common base images (slim python and node images are 100-200 MB), so most images will be LARGE_IMAGE; try 500", t.maxSizeMB))
+	}
+	return warnings
+}
+
+// scaleWarnings flags thresholds that are noisy at the size of the scanned
+// registry, once the repository and finding counts are known.
+func (t thresholds) scaleWarnings(repos, findings int) []string {
+	var warnings []string
+	if t.staleDays > 0 && t.staleDays < 7 && repos >= noisyRepoCount {
+		warnings = append(warnings, fmt.Sprintf("stale_days %d across %d repositories flags images that are only between releases; try 30 or more", t.staleDays, repos))
+	}
+	if t.minMonthlyCost == 0 && !t.rollupTail && repos >= largeRegistryRepos {
+		warnings = append(warnings, fmt.Sprintf("min_monthly_cost 0 across %d repositories reports every sub-cent image; try --min-monthly-cost 1 or --rollup-long-tail", repos))
+	}
+	if findings >= findingFlood {
+		warnings = append(warnings, fmt.Sprintf("%d findings: raise --min-monthly-cost, --stale-days or --max-size-mb, or add --rollup-long-tail to group the long tail per repository", findings))
+	}
+	return warnings
+}
+
+// warnThresholds logs threshold warnings.
+func warnThresholds(warnings []string) {
+	for _, w := range warnings {
+		slog.Warn("Noisy threshold: " + w)
+	}
+}
@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/config"
+	"github.com/ppiankov/ecrspectre/internal/history"
+	"github.com/ppiankov/ecrspectre/internal/report"
+	"github.com/ppiankov/ecrspectre/internal/trend"
+	"github.com/spf13/cobra"
+)
+
+var trendFlags struct {
+	historyDir string
+	database   string
+	since      string
+	top        int
+	format     string
+	outputFile string
+}
+
+var trendCmd = &cobra.Command{
+	Use:   "trend",
+	Short: "Show how waste changed across recorded scans",
+	Long: `Read the scan history recorded with --history-dir, or a findings database
+written with --format sqlite, and show how waste moved over a period: the
+monthly waste of every provider and of the repositories that changed the most,
+and for every scan the findings that appeared and were resolved since the
+previous scan of the same target, and the net change in monthly waste.`,
+	RunE: runTrend,
+}
+
+func init() {
+	trendCmd.Flags().StringVar(&trendFlags.historyDir, "history-dir", "", "Scan history directory (default: history_dir from config)")
+	trendCmd.Flags().StringVar(&trendFlags.database, "database", "", "Read the scans of this SQLite findings database instead of --history-dir")
+	trendCmd.Flags().StringVar(&trendFlags.since, "since", "90d", "Period to show, ending now (e.g. 90d, 12w, 720h)")
+	trendCmd.Flags().IntVar(&trendFlags.top, "top", trend.DefaultTop, "Number of repositories to list (0 for all that changed)")
+	trendCmd.Flags().StringVar(&trendFlags.format, "format", "text", "Output format: text or json")
+	trendCmd.Flags().StringVarP(&trendFlags.outputFile, "output", "o", "", "Output file path (default: stdout)")
+}
+
+func runTrend(_ *cobra.Command, _ []string) error {
+	if cfg, err := config.Load("."); err == nil && trendFlags.historyDir == "" && trendFlags.database == "" {
+		trendFlags.historyDir = cfg.HistoryDir
+	}
+	expandPaths(&trendFlags.historyDir, &trendFlags.database, &trendFlags.outputFile)
+	if trendFlags.historyDir != "" && trendFlags.database != "" {
+		return configError(fmt.Errorf("--history-dir and --database are mutually exclusive"))
+	}
+	if trendFlags.historyDir == "" && trendFlags.database == "" {
+		return configError(fmt.Errorf("--history-dir or --database is required (or set history_dir in config)"))
+	}
+	since, err := parseAge(trendFlags.since)
+	if err != nil {
+		return configError(fmt.Errorf("invalid --since: %w", err))
+	}
+	if trendFlags.top < 0 {
+		return configError(fmt.Errorf("--top must not be negative"))
+	}
+	switch trendFlags.format {
+	case "text", "json":
+	default:
+		return configError(fmt.Errorf("unsupported format: %s (use text or json)", trendFlags.format))
+	}
+
+	var all map[string][]history.Record
+	if trendFlags.database != "" {
+		all, err = report.LoadSQLiteHistory(trendFlags.database)
+	} else {
+		all, err = history.LoadAll(trendFlags.historyDir)
+	}
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	t := trend.Build(all, now.Add(-since), now, trendFlags.top)
+
+	w, closeOutput, err := openOutput(trendFlags.outputFile, "")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = closeOutput() }()
+
+	if trendFlags.format == "json" {
+		return trend.WriteJSON(w, t)
+	}
+	return trend.WriteText(w, t)
+}
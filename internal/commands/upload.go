@@ -0,0 +1,59 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/archive"
+)
+
+// isRemoteOutput reports whether an --output value names an S3 or GCS
+// object rather than a local file.
+func isRemoteOutput(output string) bool {
+	return strings.HasPrefix(output, "s3://") || strings.HasPrefix(output, "gs://")
+}
+
+// reportKey date-stamps the key of a report uploaded to object storage so
+// scheduled scans never overwrite each other: reports/ecr.json becomes
+// reports/2026/03/01/ecr-120000.json (UTC).
+func reportKey(key string, now time.Time) string {
+	dir, name := path.Split(key)
+	ext := path.Ext(name)
+	now = now.UTC()
+	return dir + now.Format("2006/01/02/") + strings.TrimSuffix(name, ext) + now.Format("-150405") + ext
+}
+
+// openRemoteOutput buffers a report and uploads it to s3://bucket/key or
+// gs://bucket/key, under a date-stamped key, when the returned function is
+// called. S3 uploads use profile, or the default AWS credentials when empty.
+func openRemoteOutput(output, profile string, now time.Time) (io.Writer, func() error, error) {
+	dest, err := archive.ParseDestination(output)
+	if err != nil {
+		return nil, nil, configError(fmt.Errorf("--output: %w", err))
+	}
+	if dest.Prefix == "" || strings.HasSuffix(output, "/") {
+		return nil, nil, configError(fmt.Errorf("--output %s names no object (use e.g. %s://%s/reports/ecrspectre.json)", output, dest.Scheme, dest.Bucket))
+	}
+	key := reportKey(dest.Prefix, now)
+	ctx := context.Background()
+	store, err := archiveStore(ctx, archive.Destination{Scheme: dest.Scheme, Bucket: dest.Bucket}, profile, "")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var buf bytes.Buffer
+	upload := func() error {
+		if err := store.Put(ctx, key, bytes.NewReader(buf.Bytes()), int64(buf.Len())); err != nil {
+			return fmt.Errorf("upload report to %s: %w", store.URL(key), err)
+		}
+		slog.Info("Uploaded report", "url", store.URL(key), "bytes", buf.Len())
+		return nil
+	}
+	return &buf, upload, nil
+}
@@ -15,12 +15,155 @@ type Config struct {
 	Regions        []string `yaml:"regions"`
 	Profile        string   `yaml:"profile"`
 	Project        string   `yaml:"project"`
+	Projects       []string `yaml:"projects"`
 	StaleDays      int      `yaml:"stale_days"`
 	MaxSizeMB      int      `yaml:"max_size_mb"`
 	MinMonthlyCost float64  `yaml:"min_monthly_cost"`
+	RollupLongTail bool     `yaml:"rollup_long_tail"`
 	Format         string   `yaml:"format"`
 	Timeout        string   `yaml:"timeout"`
-	Exclude        Exclude  `yaml:"exclude"`
+	EgressModel    string   `yaml:"egress_model"`
+	Repos          []string `yaml:"repos"`
+	ExcludeRepos   []string `yaml:"exclude_repos"`
+	UpdateCheck    *bool    `yaml:"update_check"`
+	HistoryDir     string   `yaml:"history_dir"`
+	TagPriority    []string `yaml:"tag_priority"`
+	// MaxDeployedAge reports deployed images built longer ago than this,
+	// e.g. 365d, as AGING_DEPLOYED_IMAGE.
+	MaxDeployedAge string `yaml:"max_deployed_age"`
+	// FailOn are the thresholds above which a scan exits 2, such as
+	// severity=high, waste=100 or count=50.
+	FailOn []string `yaml:"fail_on"`
+	// GroupBy breaks report waste down by a repository tag/label key.
+	GroupBy string `yaml:"group_by"`
+	// CostPeriod additionally reports waste per day or per year.
+	CostPeriod string `yaml:"cost_period"`
+	// Sort orders reported findings: waste, size, age or severity.
+	Sort string `yaml:"sort"`
+	// Reports writes several formats from one scan, each to its own
+	// output; it replaces Format.
+	Reports []Report `yaml:"reports"`
+	// Template is the Go text/template file of the template format.
+	Template string `yaml:"template"`
+	// Carbon estimates the CO2e of wasted storage in reports and digests.
+	Carbon     bool `yaml:"carbon"`
+	KeepLatest int  `yaml:"keep_latest"`
+	// UntaggedAccumulation is the untagged image count above which an ECR
+	// repository is reported as one UNTAGGED_ACCUMULATION finding.
+	UntaggedAccumulation int `yaml:"untagged_accumulation"`
+	// TieringMinSizeMB is the size from which stale images kept by
+	// keep_latest or protected_tags are reported as TIERING_CANDIDATE.
+	TieringMinSizeMB int `yaml:"tiering_min_size_mb"`
+	// SelfResolvingDays counts waste on ECR images a lifecycle policy will
+	// expire within this many days as self-resolving.
+	SelfResolvingDays int      `yaml:"self_resolving_days"`
+	ProtectedTags     []string `yaml:"protected_tags"`
+	// RequireSignatures enables UNSIGNED_IMAGE for tagged images matching
+	// SignedTags (every tagged image when empty).
+	RequireSignatures bool     `yaml:"require_signatures"`
+	SignedTags        []string `yaml:"signed_tags"`
+	// RequireSBOM enables MISSING_SBOM for recent tagged images, reported
+	// at SBOMSeverity (medium by default).
+	RequireSBOM   bool     `yaml:"require_sbom"`
+	SBOMSeverity  string   `yaml:"sbom_severity"`
+	EndpointURL   string   `yaml:"endpoint_url"`
+	DisableChecks []string `yaml:"disable_checks"`
+	ProxyURL      string   `yaml:"proxy_url"`
+	CABundle      string   `yaml:"ca_bundle"`
+	ClientCert    string   `yaml:"client_cert"`
+	ClientKey     string   `yaml:"client_key"`
+	// InsecureSkipVerify disables registry TLS certificate verification.
+	InsecureSkipVerify bool    `yaml:"insecure_skip_verify"`
+	Quota              Quota   `yaml:"quota"`
+	Exclude            Exclude `yaml:"exclude"`
+	Rules              []Rule  `yaml:"rules"`
+	// ReleaseCadence declares how often matching repositories ship.
+	ReleaseCadence []ReleaseCadence `yaml:"release_cadence"`
+	// MigrationWindows mark planned registry migrations whose findings
+	// should not fail the scan.
+	MigrationWindows []MigrationWindow `yaml:"migration_windows"`
+	// Targets lists the AWS accounts and GCP projects `ecrspectre all`
+	// scans in one run.
+	Targets []Target `yaml:"targets"`
+	// Scope selects the accounts and projects scanned by their tags and
+	// labels.
+	Scope Scope `yaml:"scope"`
+	// Scoring weighs the factors of each finding's priority score.
+	Scoring Scoring `yaml:"scoring"`
+}
+
+// Scoring holds the relative weights of the finding priority score. An
+// unset weight keeps its default.
+type Scoring struct {
+	Severity   *float64 `yaml:"severity"`
+	Waste      *float64 `yaml:"waste"`
+	Age        *float64 `yaml:"age"`
+	Confidence *float64 `yaml:"confidence"`
+}
+
+// Scope selects AWS accounts by their AWS Organizations tags and GCP projects
+// by their labels. Entries are "key=value" or "key".
+type Scope struct {
+	AccountTags          []string `yaml:"account_tags"`
+	ExcludeAccountTags   []string `yaml:"exclude_account_tags"`
+	ProjectLabels        []string `yaml:"project_labels"`
+	ExcludeProjectLabels []string `yaml:"exclude_project_labels"`
+	// OrganizationsProfile is the AWS profile of the management or delegated
+	// administrator account that reads account tags; default: each target's.
+	OrganizationsProfile string `yaml:"organizations_profile"`
+}
+
+// Target is one AWS account (by profile) or set of GCP projects scanned by
+// `ecrspectre all`. Regions are AWS regions or Artifact Registry locations.
+// Name labels the target's findings; it defaults to the profile or project.
+type Target struct {
+	Name     string   `yaml:"name"`
+	Provider string   `yaml:"provider"`
+	Profile  string   `yaml:"profile"`
+	Projects []string `yaml:"projects"`
+	Regions  []string `yaml:"regions"`
+}
+
+// Report is one report a scan writes: a format and its output file, or
+// stdout when Output is empty.
+type Report struct {
+	Format string `yaml:"format"`
+	Output string `yaml:"output"`
+}
+
+// ReleaseCadence expects repositories matching the Repo glob or re:regex to
+// push a new image Every interval (daily, weekly, monthly, 14d, ...).
+type ReleaseCadence struct {
+	Repo  string `yaml:"repo"`
+	Every string `yaml:"every"`
+}
+
+// MigrationWindow covers findings on repositories matching any of the Repos
+// globs or re:regexes (all repositories when empty) from Start to End, both
+// YYYY-MM-DD dates and included.
+type MigrationWindow struct {
+	Name   string   `yaml:"name"`
+	Start  string   `yaml:"start"`
+	End    string   `yaml:"end"`
+	Repos  []string `yaml:"repos"`
+	Reason string   `yaml:"reason"`
+}
+
+// Rule defines a custom finding: images matching the CEL expression are
+// reported under ID at Severity (medium by default).
+type Rule struct {
+	ID         string `yaml:"id"`
+	Severity   string `yaml:"severity"`
+	Message    string `yaml:"message"`
+	Expression string `yaml:"expression"`
+}
+
+// Quota defines Artifact Registry storage budgets in GB.
+type Quota struct {
+	RepositoryGB     float64            `yaml:"repository_gb"`
+	ProjectGB        float64            `yaml:"project_gb"`
+	ThresholdPercent float64            `yaml:"threshold_percent"`
+	Repositories     map[string]float64 `yaml:"repositories"`
 }
 
 // Exclude defines resources to skip during scanning.
@@ -72,3 +215,50 @@ func Load(dir string) (Config, error) {
 
 	return Config{}, nil
 }
+
+// IgnoreFile holds finding suppressions loaded from .ecrspectre-ignore.yaml.
+type IgnoreFile struct {
+	Suppressions []Suppression `yaml:"suppressions"`
+}
+
+// Suppression accepts findings with ID ("*" for any) on resources matching
+// the Resource glob or re:regex until Expires (YYYY-MM-DD). Owner and Reason
+// are required so every accepted finding has an audit trail.
+type Suppression struct {
+	ID       string `yaml:"id"`
+	Resource string `yaml:"resource"`
+	Owner    string `yaml:"owner"`
+	Reason   string `yaml:"reason"`
+	Expires  string `yaml:"expires"`
+}
+
+// LoadIgnore reads the suppression file at path. With an empty path it
+// searches dir for .ecrspectre-ignore.yaml or .ecrspectre-ignore.yml and
+// returns an empty IgnoreFile if neither exists.
+func LoadIgnore(dir, path string) (IgnoreFile, error) {
+	candidates := []string{path}
+	if path == "" {
+		candidates = []string{
+			filepath.Join(dir, ".ecrspectre-ignore.yaml"),
+			filepath.Join(dir, ".ecrspectre-ignore.yml"),
+		}
+	}
+
+	for _, candidate := range candidates {
+		data, err := os.ReadFile(candidate)
+		if err != nil {
+			if os.IsNotExist(err) && path == "" {
+				continue
+			}
+			return IgnoreFile{}, fmt.Errorf("read ignore file %s: %w", candidate, err)
+		}
+
+		var f IgnoreFile
+		if err := yaml.Unmarshal(data, &f); err != nil {
+			return IgnoreFile{}, fmt.Errorf("parse ignore file %s: %w", candidate, err)
+		}
+		return f, nil
+	}
+
+	return IgnoreFile{}, nil
+}
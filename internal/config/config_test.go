@@ -141,3 +141,33 @@ func TestMaxSizeBytes(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadIgnore(t *testing.T) {
+	dir := t.TempDir()
+	content := `suppressions:
+  - id: STALE_IMAGE
+    resource: "legacy/*"
+    owner: platform-team
+    reason: Frozen until the Q3 migration
+    expires: "2026-09-30"
+`
+	if err := os.WriteFile(filepath.Join(dir, ".ecrspectre-ignore.yml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := LoadIgnore(dir, "")
+	if err != nil {
+		t.Fatalf("LoadIgnore() error: %v", err)
+	}
+	want := Suppression{ID: "STALE_IMAGE", Resource: "legacy/*", Owner: "platform-team", Reason: "Frozen until the Q3 migration", Expires: "2026-09-30"}
+	if len(f.Suppressions) != 1 || f.Suppressions[0] != want {
+		t.Errorf("Suppressions = %+v, want [%+v]", f.Suppressions, want)
+	}
+
+	if f, err := LoadIgnore(t.TempDir(), ""); err != nil || len(f.Suppressions) != 0 {
+		t.Errorf("LoadIgnore(empty dir) = %+v, %v; want no suppressions", f, err)
+	}
+	if _, err := LoadIgnore(dir, filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Error("LoadIgnore(missing explicit path) should fail")
+	}
+}
@@ -0,0 +1,29 @@
+// Package console detects terminals and prepares them for output, including
+// turning on ANSI escape sequence processing in Windows consoles.
+package console
+
+import "os"
+
+// IsTerminal reports whether f is an interactive terminal rather than a
+// file or pipe.
+func IsTerminal(f *os.File) bool {
+	if f == nil {
+		return false
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Prepare readies stdout and stderr for terminal output. It enables
+// virtual terminal processing on Windows so ANSI sequences are interpreted
+// instead of printed; elsewhere it does nothing.
+func Prepare() {
+	for _, f := range []*os.File{os.Stdout, os.Stderr} {
+		if IsTerminal(f) {
+			_ = enableVirtualTerminal(f)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package console
+
+import "os"
+
+// enableVirtualTerminal is a no-op: Unix terminals interpret ANSI sequences.
+func enableVirtualTerminal(*os.File) error { return nil }
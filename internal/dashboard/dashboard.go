@@ -0,0 +1,242 @@
+// Package dashboard redraws a live summary of a running scan in the
+// terminal: progress per region or location, the waste found so far, the
+// biggest findings, API call and throttle counts, and the latest log lines.
+package dashboard
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// TopFindings is the number of biggest findings shown.
+const TopFindings = 5
+
+// logLines is the number of latest log lines shown.
+const logLines = 3
+
+// maxWidth truncates frame lines so they do not wrap in narrow terminals,
+// which would break redrawing.
+const maxWidth = 120
+
+// barWidth is the width of a progress bar in characters.
+const barWidth = 20
+
+// scope is the progress of one region or location, optionally within a
+// project or target.
+type scope struct {
+	done, total, images int
+	stage, repo         string
+}
+
+// Dashboard collects scan progress and redraws it in place.
+type Dashboard struct {
+	w     io.Writer
+	runID string
+	now   func() time.Time
+	// counts returns the API calls and throttled calls so far.
+	counts func() (int64, int64)
+
+	mu       sync.Mutex
+	start    time.Time
+	order    []string
+	scopes   map[string]*scope
+	findings int
+	waste    float64
+	top      []registry.Finding
+	logs     bytes.Buffer
+	lines    int // lines of the last frame, erased before the next
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// New creates a dashboard drawing to w (a terminal) for the run with the
+// given ID. counts reports the API calls and throttled calls so far.
+func New(w io.Writer, runID string, counts func() (int64, int64)) *Dashboard {
+	return &Dashboard{
+		w:      w,
+		runID:  runID,
+		now:    time.Now,
+		counts: counts,
+		start:  time.Now(),
+		scopes: make(map[string]*scope),
+	}
+}
+
+// Update records a progress event. prefix, when set, names the project or
+// target the event's region belongs to.
+func (d *Dashboard) Update(prefix string, p registry.ScanProgress) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := p.Region
+	if prefix != "" {
+		key = prefix + "/" + p.Region
+	}
+	s, ok := d.scopes[key]
+	if !ok {
+		s = &scope{}
+		d.scopes[key] = s
+		d.order = append(d.order, key)
+	}
+	s.stage, s.repo = p.Stage, p.Repository
+	s.done, s.total, s.images = p.ReposDone, p.ReposTotal, p.ImagesScanned
+	if p.Stage == registry.StageDone {
+		s.done = s.total
+	}
+
+	for _, f := range p.Findings {
+		d.findings++
+		d.waste += f.EstimatedMonthlyWaste
+		d.top = append(d.top, f)
+	}
+	sort.SliceStable(d.top, func(i, j int) bool { return d.top[i].EstimatedMonthlyWaste > d.top[j].EstimatedMonthlyWaste })
+	if len(d.top) > TopFindings {
+		d.top = d.top[:TopFindings]
+	}
+}
+
+// Write keeps log output for the dashboard, which shows the latest lines
+// and prints all of them once it stops.
+func (d *Dashboard) Write(p []byte) (int, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.logs.Write(p)
+}
+
+// Start redraws the dashboard every interval until Stop.
+func (d *Dashboard) Start(interval time.Duration) {
+	d.stop, d.done = make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(d.done)
+		t := time.NewTicker(interval)
+		defer t.Stop()
+		d.draw()
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-t.C:
+				d.draw()
+			}
+		}
+	}()
+}
+
+// Stop draws the final frame and prints the log output held back while the
+// dashboard was shown.
+func (d *Dashboard) Stop() {
+	if d.stop != nil {
+		close(d.stop)
+		<-d.done
+	}
+	d.draw()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	_, _ = d.w.Write(d.logs.Bytes())
+	d.logs.Reset()
+}
+
+// draw erases the previous frame and writes the current one.
+func (d *Dashboard) draw() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	frame := d.frame()
+	var b strings.Builder
+	if d.lines > 0 {
+		fmt.Fprintf(&b, "\x1b[%dA\x1b[J", d.lines)
+	}
+	b.WriteString(frame)
+	d.lines = strings.Count(frame, "\n")
+	_, _ = io.WriteString(d.w, b.String())
+}
+
+// frame renders the dashboard. The caller holds d.mu.
+func (d *Dashboard) frame() string {
+	var lines []string
+	header := fmt.Sprintf("ecrspectre scan, elapsed %s", d.now().Sub(d.start).Truncate(time.Second))
+	if d.runID != "" {
+		header += ", run " + d.runID
+	}
+	lines = append(lines, header, "")
+
+	width := 0
+	for _, k := range d.order {
+		width = max(width, len(k))
+	}
+	for _, k := range d.order {
+		s := d.scopes[k]
+		status := s.stage
+		if s.repo != "" && s.stage != registry.StageDone {
+			status += " " + s.repo
+		}
+		lines = append(lines, fmt.Sprintf("  %-*s %s %d/%d repos, %d images, %s",
+			width, k, bar(s.done, s.total), s.done, s.total, s.images, status))
+	}
+	if len(d.order) == 0 {
+		lines = append(lines, "  discovering...")
+	}
+
+	lines = append(lines, "", fmt.Sprintf("Waste found: $%.2f/mo in %d findings (before filters)", d.waste, d.findings))
+	for _, f := range d.top {
+		name := f.ResourceName
+		if name == "" {
+			name = f.ResourceID
+		}
+		lines = append(lines, fmt.Sprintf("  $%9.2f  %-18s %s (%s)", f.EstimatedMonthlyWaste, f.ID, name, f.Region))
+	}
+	if d.counts != nil {
+		calls, throttled := d.counts()
+		lines = append(lines, "", fmt.Sprintf("API calls: %d, throttled: %d", calls, throttled))
+	}
+	if recent := lastLines(d.logs.String(), logLines); len(recent) > 0 {
+		lines = append(lines, "", "Log:")
+		for _, l := range recent {
+			lines = append(lines, "  "+l)
+		}
+	}
+
+	var b strings.Builder
+	for _, l := range lines {
+		b.WriteString(truncate(l, maxWidth))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// bar renders done of total as a fixed-width progress bar.
+func bar(done, total int) string {
+	filled := 0
+	if total > 0 {
+		filled = min(barWidth, done*barWidth/total)
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", barWidth-filled) + "]"
+}
+
+// lastLines returns the last n non-empty lines of s.
+func lastLines(s string, n int) []string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return lines
+}
+
+// truncate shortens s to at most n runes.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,70 @@
+package dashboard
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+func TestFrame(t *testing.T) {
+	var buf bytes.Buffer
+	d := New(&buf, "run-1", func() (int64, int64) { return 120, 3 })
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.start = start
+	d.now = func() time.Time { return start.Add(90 * time.Second) }
+
+	d.Update("prod", registry.ScanProgress{Region: "us-east-1", Stage: registry.StageScan, Repository: "team/api", ReposDone: 5, ReposTotal: 10, ImagesScanned: 40})
+	var findings []registry.Finding
+	for i := range 7 {
+		findings = append(findings, registry.Finding{ID: registry.FindingStaleImage, ResourceID: fmt.Sprintf("img-%d", i), Region: "us-east-1", EstimatedMonthlyWaste: float64(i)})
+	}
+	d.Update("prod", registry.ScanProgress{Region: "us-east-1", Stage: registry.StageScan, Repository: "team/web", ReposDone: 6, ReposTotal: 10, Findings: findings})
+	d.Update("", registry.ScanProgress{Region: "eu-west-1", Stage: registry.StageDone, ReposTotal: 4})
+	_, _ = d.Write([]byte("level=WARN msg=\"slow region\"\n"))
+
+	frame := d.frame()
+	for _, want := range []string{
+		"elapsed 1m30s, run run-1",
+		"prod/us-east-1 [############--------] 6/10 repos",
+		"scan team/web",
+		"eu-west-1      [####################] 4/4 repos",
+		"Waste found: $21.00/mo in 7 findings",
+		"img-6",
+		"API calls: 120, throttled: 3",
+		`level=WARN msg="slow region"`,
+	} {
+		if !strings.Contains(frame, want) {
+			t.Errorf("frame missing %q:\n%s", want, frame)
+		}
+	}
+	if strings.Contains(frame, "img-1 ") || strings.Contains(frame, "img-0") {
+		t.Errorf("frame shows more than %d findings:\n%s", TopFindings, frame)
+	}
+}
+
+func TestRedrawErasesPreviousFrame(t *testing.T) {
+	var buf bytes.Buffer
+	d := New(&buf, "", nil)
+	d.draw()
+	first := d.lines
+	buf.Reset()
+	d.draw()
+	if !strings.HasPrefix(buf.String(), fmt.Sprintf("\x1b[%dA\x1b[J", first)) {
+		t.Errorf("second frame does not erase the first: %q", buf.String())
+	}
+}
+
+func TestStopFlushesLogs(t *testing.T) {
+	var buf bytes.Buffer
+	d := New(&buf, "", nil)
+	d.Start(time.Hour)
+	_, _ = d.Write([]byte("line 1\nline 2\nline 3\nline 4\n"))
+	d.Stop()
+	if !strings.HasSuffix(buf.String(), "line 1\nline 2\nline 3\nline 4\n") {
+		t.Errorf("logs not flushed after the final frame: %q", buf.String())
+	}
+}
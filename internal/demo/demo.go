@@ -0,0 +1,269 @@
+// Package demo generates a synthetic ECR registry so that reports can be
+// explored without cloud credentials. The registry implements ecr.ECRAPI, so
+// the demo runs the real scanner, analyzer and reporters.
+package demo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// Region and Account identify the demo registry.
+const (
+	Region  = "us-east-1"
+	Account = "123456789012"
+)
+
+// UsedPlatforms are the platforms the demo fleet runs; other platforms in
+// multi-arch images show up as MULTI_ARCH_BLOAT.
+var UsedPlatforms = []string{"linux/amd64", "linux/arm64"}
+
+const mb = 1024 * 1024
+
+// profile shapes the images of one demo repository.
+type profile struct {
+	name       string
+	team       string
+	images     int
+	minMB      int
+	maxMB      int
+	lifecycle  bool
+	untagged   float64 // share of images left untagged
+	multiArch  int     // newest images pushed as multi-arch indexes
+	vulnerable int     // newest images with critical CVEs
+	abandoned  bool    // nothing pushed or pulled for over a year
+}
+
+var profiles = []profile{
+	{name: "platform/api-gateway", team: "platform", images: 24, minMB: 180, maxMB: 260, lifecycle: true, untagged: 0.1},
+	{name: "platform/base-images", team: "platform", images: 16, minMB: 90, maxMB: 140, lifecycle: true, multiArch: 2},
+	{name: "payments/checkout", team: "payments", images: 36, minMB: 220, maxMB: 320, untagged: 0.25, vulnerable: 2},
+	{name: "payments/billing-worker", team: "payments", images: 18, minMB: 150, maxMB: 200, lifecycle: true, vulnerable: 1},
+	{name: "data/etl-pipeline", team: "data", images: 40, minMB: 600, maxMB: 1100, untagged: 0.4},
+	{name: "data/spark-jobs", team: "data", images: 12, minMB: 2000, maxMB: 3500, untagged: 0.2},
+	{name: "web/frontend", team: "web", images: 60, minMB: 60, maxMB: 120, untagged: 0.3},
+	{name: "web/storybook", team: "web", images: 8, minMB: 300, maxMB: 450, abandoned: true},
+	{name: "ml/training", team: "ml", images: 10, minMB: 4000, maxMB: 7000, untagged: 0.3},
+	{name: "ml/inference", team: "ml", images: 14, minMB: 1800, maxMB: 2600, lifecycle: true, multiArch: 1},
+	{name: "sandbox/experiments", team: "platform"},
+}
+
+// Registry is an in-memory ECR registry of realistic repositories: active
+// services with and without lifecycle policies, untagged build leftovers,
+// oversized ML images, multi-arch bases, an abandoned repository and an
+// empty one.
+type Registry struct {
+	repos     []ecrtypes.Repository
+	images    map[string][]ecrtypes.ImageDetail
+	tags      map[string]string // repository ARN → team
+	lifecycle map[string]bool
+	vulns     map[string]bool   // repo@digest with critical CVEs
+	manifests map[string]string // repo@digest → manifest document
+}
+
+// New builds the demo registry. The same seed always yields the same
+// repositories and images, dated relative to now.
+func New(seed int64, now time.Time) *Registry {
+	rng := rand.New(rand.NewSource(seed))
+	r := &Registry{
+		images:    make(map[string][]ecrtypes.ImageDetail),
+		tags:      make(map[string]string),
+		lifecycle: make(map[string]bool),
+		vulns:     make(map[string]bool),
+		manifests: make(map[string]string),
+	}
+	for _, p := range profiles {
+		arn := fmt.Sprintf("arn:aws:ecr:%s:%s:repository/%s", Region, Account, p.name)
+		r.repos = append(r.repos, ecrtypes.Repository{
+			RepositoryName: aws.String(p.name),
+			RepositoryArn:  aws.String(arn),
+			RepositoryUri:  aws.String(fmt.Sprintf("%s.dkr.ecr.%s.amazonaws.com/%s", Account, Region, p.name)),
+			CreatedAt:      aws.Time(now.AddDate(-2, 0, 0)),
+		})
+		r.tags[arn] = p.team
+		r.lifecycle[p.name] = p.lifecycle
+		r.images[p.name] = r.generate(rng, p, now)
+	}
+	return r
+}
+
+// generate creates a repository's images, newest first: a release every few
+// days, the newest ones still pulled, older ones last pulled soon after they
+// were replaced.
+func (r *Registry) generate(rng *rand.Rand, p profile, now time.Time) []ecrtypes.ImageDetail {
+	var images []ecrtypes.ImageDetail
+	age := 0
+	if p.abandoned {
+		age = 400
+	}
+	for i := 0; i < p.images; i++ {
+		age += 2 + rng.Intn(12)
+		pushed := now.AddDate(0, 0, -age)
+		pulled := pushed.AddDate(0, 0, rng.Intn(45))
+		if i < 3 && !p.abandoned {
+			pulled = now.AddDate(0, 0, -rng.Intn(3))
+		}
+		if pulled.After(now) {
+			pulled = now
+		}
+		size := int64(p.minMB+rng.Intn(p.maxMB-p.minMB+1)) * mb
+		digest := digestOf(p.name, i)
+
+		var tags []string
+		if rng.Float64() >= p.untagged || i == 0 {
+			tags = []string{fmt.Sprintf("v1.%d.%d", p.images-i, rng.Intn(4))}
+			if i == 0 {
+				tags = append(tags, "latest")
+			}
+		}
+		img := ecrtypes.ImageDetail{
+			RepositoryName:         aws.String(p.name),
+			ImageDigest:            aws.String(digest),
+			ImageTags:              tags,
+			ImageSizeInBytes:       aws.Int64(size),
+			ImagePushedAt:          aws.Time(pushed),
+			LastRecordedPullTime:   aws.Time(pulled),
+			ImageManifestMediaType: aws.String("application/vnd.oci.image.manifest.v1+json"),
+		}
+		if i < p.vulnerable {
+			r.vulns[p.name+"@"+digest] = true
+		}
+		if i < p.multiArch {
+			images = append(images, r.multiArch(p.name, img, i)...)
+			continue
+		}
+		images = append(images, img)
+	}
+	return images
+}
+
+// multiArch turns img into a multi-arch index over amd64, arm64 and s390x
+// platform manifests of the same size.
+func (r *Registry) multiArch(repo string, img ecrtypes.ImageDetail, i int) []ecrtypes.ImageDetail {
+	platforms := []string{"amd64", "arm64", "s390x"}
+	index := `{"schemaVersion":2,"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[`
+	children := make([]ecrtypes.ImageDetail, 0, len(platforms))
+	for j, arch := range platforms {
+		child := img
+		child.ImageDigest = aws.String(digestOf(repo+"/"+arch, i))
+		child.ImageTags = nil
+		if j > 0 {
+			index += ","
+		}
+		index += fmt.Sprintf(`{"mediaType":"application/vnd.oci.image.manifest.v1+json","digest":%q,"size":1024,"platform":{"os":"linux","architecture":%q}}`,
+			aws.ToString(child.ImageDigest), arch)
+		children = append(children, child)
+	}
+	index += "]}"
+
+	img.ImageSizeInBytes = aws.Int64(4096)
+	img.ImageManifestMediaType = aws.String("application/vnd.oci.image.index.v1+json")
+	r.manifests[repo+"@"+aws.ToString(img.ImageDigest)] = index
+	return append([]ecrtypes.ImageDetail{img}, children...)
+}
+
+func digestOf(repo string, i int) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s#%d", repo, i)))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// DescribeRepositories implements ecr.ECRAPI.
+func (r *Registry) DescribeRepositories(_ context.Context, input *ecr.DescribeRepositoriesInput, _ ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error) {
+	if len(input.RepositoryNames) == 0 {
+		return &ecr.DescribeRepositoriesOutput{Repositories: r.repos}, nil
+	}
+	out := &ecr.DescribeRepositoriesOutput{}
+	for _, name := range input.RepositoryNames {
+		for _, repo := range r.repos {
+			if aws.ToString(repo.RepositoryName) == name {
+				out.Repositories = append(out.Repositories, repo)
+			}
+		}
+	}
+	if len(out.Repositories) == 0 {
+		return nil, &ecrtypes.RepositoryNotFoundException{Message: aws.String("repository not found in the demo registry")}
+	}
+	return out, nil
+}
+
+// DescribeImages implements ecr.ECRAPI.
+func (r *Registry) DescribeImages(_ context.Context, input *ecr.DescribeImagesInput, _ ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
+	return &ecr.DescribeImagesOutput{ImageDetails: r.images[aws.ToString(input.RepositoryName)]}, nil
+}
+
+// ListImages implements ecr.ECRAPI.
+func (r *Registry) ListImages(_ context.Context, input *ecr.ListImagesInput, _ ...func(*ecr.Options)) (*ecr.ListImagesOutput, error) {
+	out := &ecr.ListImagesOutput{}
+	for _, img := range r.images[aws.ToString(input.RepositoryName)] {
+		if len(img.ImageTags) == 0 {
+			out.ImageIds = append(out.ImageIds, ecrtypes.ImageIdentifier{ImageDigest: img.ImageDigest})
+		}
+		for _, tag := range img.ImageTags {
+			out.ImageIds = append(out.ImageIds, ecrtypes.ImageIdentifier{ImageDigest: img.ImageDigest, ImageTag: aws.String(tag)})
+		}
+	}
+	return out, nil
+}
+
+// GetLifecyclePolicy implements ecr.ECRAPI.
+func (r *Registry) GetLifecyclePolicy(_ context.Context, input *ecr.GetLifecyclePolicyInput, _ ...func(*ecr.Options)) (*ecr.GetLifecyclePolicyOutput, error) {
+	repo := aws.ToString(input.RepositoryName)
+	if !r.lifecycle[repo] {
+		return nil, &ecrtypes.LifecyclePolicyNotFoundException{Message: aws.String("no lifecycle policy")}
+	}
+	return &ecr.GetLifecyclePolicyOutput{
+		RepositoryName:      aws.String(repo),
+		LifecyclePolicyText: aws.String(`{"rules":[{"rulePriority":1,"selection":{"tagStatus":"untagged","countType":"sinceImagePushed","countUnit":"days","countNumber":14},"action":{"type":"expire"}}]}`),
+	}, nil
+}
+
+// DescribeImageScanFindings implements ecr.ECRAPI.
+func (r *Registry) DescribeImageScanFindings(_ context.Context, input *ecr.DescribeImageScanFindingsInput, _ ...func(*ecr.Options)) (*ecr.DescribeImageScanFindingsOutput, error) {
+	repo := aws.ToString(input.RepositoryName)
+	if !r.vulns[repo+"@"+aws.ToString(input.ImageId.ImageDigest)] {
+		return nil, &ecrtypes.ScanNotFoundException{Message: aws.String("image not scanned")}
+	}
+	findings := []ecrtypes.ImageScanFinding{
+		{Name: aws.String("CVE-2024-3094"), Severity: ecrtypes.FindingSeverityCritical},
+		{Name: aws.String("CVE-2023-4911"), Severity: ecrtypes.FindingSeverityHigh},
+		{Name: aws.String("CVE-2023-44487"), Severity: ecrtypes.FindingSeverityHigh},
+		{Name: aws.String("CVE-2024-2961"), Severity: ecrtypes.FindingSeverityMedium},
+	}
+	return &ecr.DescribeImageScanFindingsOutput{ImageScanFindings: &ecrtypes.ImageScanFindings{Findings: findings}}, nil
+}
+
+// ListTagsForResource implements ecr.ECRAPI.
+func (r *Registry) ListTagsForResource(_ context.Context, input *ecr.ListTagsForResourceInput, _ ...func(*ecr.Options)) (*ecr.ListTagsForResourceOutput, error) {
+	team, ok := r.tags[aws.ToString(input.ResourceArn)]
+	if !ok {
+		return &ecr.ListTagsForResourceOutput{}, nil
+	}
+	return &ecr.ListTagsForResourceOutput{Tags: []ecrtypes.Tag{
+		{Key: aws.String("team"), Value: aws.String(team)},
+		{Key: aws.String("owner"), Value: aws.String(team + "@example.com")},
+	}}, nil
+}
+
+// BatchGetImage implements ecr.ECRAPI. Only multi-arch indexes have
+// manifests in the demo registry.
+func (r *Registry) BatchGetImage(_ context.Context, input *ecr.BatchGetImageInput, _ ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error) {
+	repo := aws.ToString(input.RepositoryName)
+	out := &ecr.BatchGetImageOutput{}
+	for _, id := range input.ImageIds {
+		manifest, ok := r.manifests[repo+"@"+aws.ToString(id.ImageDigest)]
+		if !ok {
+			out.Failures = append(out.Failures, ecrtypes.ImageFailure{ImageId: &id, FailureCode: ecrtypes.ImageFailureCodeImageNotFound})
+			continue
+		}
+		out.Images = append(out.Images, ecrtypes.Image{ImageId: &id, ImageManifest: aws.String(manifest)})
+	}
+	return out, nil
+}
@@ -0,0 +1,46 @@
+package demo
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+func TestNewIsDeterministic(t *testing.T) {
+	now := time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC)
+	a, b := New(7, now), New(7, now)
+	if !reflect.DeepEqual(a.images, b.images) {
+		t.Error("the same seed should yield the same images")
+	}
+	if reflect.DeepEqual(a.images, New(8, now).images) {
+		t.Error("a different seed should yield different images")
+	}
+}
+
+func TestRegistryServesIndexManifests(t *testing.T) {
+	r := New(1, time.Now())
+	ctx := context.Background()
+	out, err := r.DescribeImages(ctx, &ecr.DescribeImagesInput{RepositoryName: aws.String("platform/base-images")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	index := out.ImageDetails[0]
+	if aws.ToString(index.ImageManifestMediaType) != "application/vnd.oci.image.index.v1+json" {
+		t.Fatalf("newest base image should be an index, got %s", aws.ToString(index.ImageManifestMediaType))
+	}
+	got, err := r.BatchGetImage(ctx, &ecr.BatchGetImageInput{
+		RepositoryName: aws.String("platform/base-images"),
+		ImageIds:       []ecrtypes.ImageIdentifier{{ImageDigest: index.ImageDigest}},
+	})
+	if err != nil || len(got.Images) != 1 {
+		t.Errorf("BatchGetImage = %+v, %v", got, err)
+	}
+	if _, err := r.GetLifecyclePolicy(ctx, &ecr.GetLifecyclePolicyInput{RepositoryName: aws.String("ml/training")}); err == nil {
+		t.Error("ml/training should have no lifecycle policy")
+	}
+}
@@ -0,0 +1,75 @@
+// Package diff renders the comparison of two scan reports: the findings that
+// are new, resolved and unchanged since the older one.
+package diff
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ppiankov/ecrspectre/internal/analyzer"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// Report is the JSON form of a comparison.
+type Report struct {
+	Old       string                   `json:"old"`
+	New       string                   `json:"new"`
+	Summary   analyzer.BaselineSummary `json:"summary"`
+	Findings  []registry.Finding       `json:"new_findings"`
+	Resolved  []registry.Finding       `json:"resolved_findings"`
+	Unchanged []registry.Finding       `json:"unchanged_findings"`
+}
+
+// WriteText renders the new and resolved findings, then the totals.
+// Unchanged findings are listed only with showUnchanged.
+func WriteText(w io.Writer, c analyzer.BaselineComparison, showUnchanged bool) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "STATUS\tID\tSEVERITY\tRESOURCE\tREGION\tWASTE/MO")
+	rows := func(status string, findings []registry.Finding) {
+		for _, f := range findings {
+			name := f.ResourceName
+			if name == "" {
+				name = f.ResourceID
+			}
+			_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t$%.2f\n", status, f.ID, f.Severity, name, f.Region, f.EstimatedMonthlyWaste)
+		}
+	}
+	rows(registry.BaselineNew, c.New)
+	rows(registry.BaselineResolved, c.Resolved)
+	if showUnchanged {
+		rows(registry.BaselineUnchanged, c.Unchanged)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	s := c.Summary()
+	_, err := fmt.Fprintf(w, "\n%d new ($%.2f/mo), %d resolved ($%.2f/mo), %d unchanged.\n",
+		s.New, s.NewMonthlyWaste, s.Resolved, s.ResolvedMonthlyWaste, s.Unchanged)
+	return err
+}
+
+// WriteJSON renders the comparison of the reports at oldPath and newPath as
+// indented JSON.
+func WriteJSON(w io.Writer, c analyzer.BaselineComparison, oldPath, newPath string) error {
+	r := Report{
+		Old:       oldPath,
+		New:       newPath,
+		Summary:   c.Summary(),
+		Findings:  nonNil(c.New),
+		Resolved:  nonNil(c.Resolved),
+		Unchanged: nonNil(c.Unchanged),
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// nonNil keeps empty lists as [] rather than null in JSON.
+func nonNil(findings []registry.Finding) []registry.Finding {
+	if findings == nil {
+		return []registry.Finding{}
+	}
+	return findings
+}
@@ -0,0 +1,70 @@
+package diff
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ppiankov/ecrspectre/internal/analyzer"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+func sampleComparison() analyzer.BaselineComparison {
+	return analyzer.CompareBaseline(
+		[]registry.Finding{
+			{ID: registry.FindingStaleImage, Severity: registry.SeverityMedium, Region: "us-east-1", ResourceID: "sha256:a", ResourceName: "api:v1", EstimatedMonthlyWaste: 4},
+			{ID: registry.FindingUntaggedImage, Severity: registry.SeverityHigh, Region: "us-east-1", ResourceID: "sha256:b", EstimatedMonthlyWaste: 6},
+		},
+		[]registry.Finding{
+			{ID: registry.FindingStaleImage, Severity: registry.SeverityMedium, Region: "us-east-1", ResourceID: "sha256:a", ResourceName: "api:v1", EstimatedMonthlyWaste: 4},
+			{ID: registry.FindingLargeImage, Severity: registry.SeverityLow, Region: "us-east-1", ResourceID: "sha256:c", ResourceName: "web:v2", EstimatedMonthlyWaste: 2.5},
+		},
+	)
+}
+
+func TestWriteText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteText(&buf, sampleComparison(), false); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"new       LARGE_IMAGE", "resolved  UNTAGGED_IMAGE", "sha256:b", "1 new ($2.50/mo), 1 resolved ($6.00/mo), 1 unchanged."} {
+		if !strings.Contains(out, want) {
+			t.Errorf("text missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "api:v1") {
+		t.Errorf("unchanged finding listed without showUnchanged:\n%s", out)
+	}
+
+	buf.Reset()
+	if err := WriteText(&buf, sampleComparison(), true); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "unchanged  STALE_IMAGE") {
+		t.Errorf("unchanged finding missing:\n%s", buf.String())
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, analyzer.CompareBaseline(nil, nil), "old.json", "new.json"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"resolved_findings": []`) {
+		t.Errorf("empty lists not encoded as []:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, sampleComparison(), "old.json", "new.json"); err != nil {
+		t.Fatal(err)
+	}
+	var r Report
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Old != "old.json" || r.Summary.New != 1 || len(r.Findings) != 1 || r.Findings[0].ResourceID != "sha256:c" || len(r.Resolved) != 1 || len(r.Unchanged) != 1 {
+		t.Errorf("report = %+v", r)
+	}
+}
@@ -0,0 +1,236 @@
+// Package digest summarizes scan history over a period into a narrative
+// report: waste trend, top regressions and improvements, and cleanup done.
+package digest
+
+import (
+	"sort"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/history"
+	"github.com/ppiankov/ecrspectre/internal/pricing"
+)
+
+// DefaultTop is the number of regressions and improvements listed.
+const DefaultTop = 5
+
+// Point is the total monthly waste across all targets after one scan.
+type Point struct {
+	Time         time.Time `json:"time"`
+	MonthlyWaste float64   `json:"monthly_waste"`
+}
+
+// RepoChange is how one repository moved between the start and end of the period.
+type RepoChange struct {
+	Provider   string  `json:"provider"`
+	Repository string  `json:"repository"`
+	Region     string  `json:"region,omitempty"`
+	StartBytes int64   `json:"start_bytes"`
+	EndBytes   int64   `json:"end_bytes"`
+	StartWaste float64 `json:"start_monthly_waste"`
+	EndWaste   float64 `json:"end_monthly_waste"`
+	Deleted    bool    `json:"deleted,omitempty"`
+}
+
+// WasteDelta is the change in monthly waste over the period.
+func (c RepoChange) WasteDelta() float64 { return c.EndWaste - c.StartWaste }
+
+// BytesDelta is the change in stored bytes over the period.
+func (c RepoChange) BytesDelta() int64 { return c.EndBytes - c.StartBytes }
+
+// Cleanup describes the cleanup actions visible in the history.
+type Cleanup struct {
+	DeletedRepositories []RepoChange   `json:"deleted_repositories,omitempty"`
+	ReclaimedBytes      int64          `json:"reclaimed_bytes"`
+	ResolvedFindings    map[string]int `json:"resolved_findings,omitempty"`
+}
+
+// Digest is the summary of a period of scan history.
+type Digest struct {
+	From          time.Time    `json:"from"`
+	To            time.Time    `json:"to"`
+	Scans         int          `json:"scans"`
+	Targets       int          `json:"targets"`
+	StartWaste    float64      `json:"start_monthly_waste"`
+	EndWaste      float64      `json:"end_monthly_waste"`
+	StartFindings int          `json:"start_findings"`
+	EndFindings   int          `json:"end_findings"`
+	StartBytes    int64        `json:"start_bytes"`
+	EndBytes      int64        `json:"end_bytes"`
+	Trend         []Point      `json:"trend"`
+	Regressions   []RepoChange `json:"regressions"`
+	Improvements  []RepoChange `json:"improvements"`
+	Cleanup       Cleanup      `json:"cleanup"`
+	// StartCO2eKg and EndCO2eKg estimate the monthly emissions of the wasted
+	// storage at the start and end of the period. Carbon shows them in the
+	// rendered digest.
+	StartCO2eKg float64 `json:"start_co2e_kg_per_month,omitempty"`
+	EndCO2eKg   float64 `json:"end_co2e_kg_per_month,omitempty"`
+	Carbon      bool    `json:"-"`
+}
+
+// Build summarizes the history of every target (as returned by
+// history.LoadAll) between from and to. Each target is compared from its last
+// scan at or before from (or its first scan in the period) to its last scan
+// in the period. top limits the regressions and improvements listed.
+func Build(all map[string][]history.Record, from, to time.Time, top int) Digest {
+	d := Digest{From: from, To: to, Cleanup: Cleanup{ResolvedFindings: make(map[string]int)}}
+
+	type scan struct {
+		target string
+		record history.Record
+	}
+	var window []scan
+	var changes []RepoChange
+	latest := make(map[string]float64)
+
+	targets := make([]string, 0, len(all))
+	for target := range all {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	for _, target := range targets {
+		start, end, scans := -1, -1, 0
+		for i, r := range all[target] {
+			if r.Timestamp.After(to) {
+				break
+			}
+			if !r.Timestamp.After(from) {
+				start = i
+				continue
+			}
+			if start < 0 {
+				start = i
+			}
+			end, scans = i, scans+1
+			window = append(window, scan{target, r})
+		}
+		if end < 0 {
+			continue
+		}
+		first, last := all[target][start], all[target][end]
+		latest[target] = first.TotalMonthlyWaste
+		d.Targets++
+		d.Scans += scans
+		d.StartWaste += first.TotalMonthlyWaste
+		d.EndWaste += last.TotalMonthlyWaste
+		d.StartCO2eKg += recordCO2e(first)
+		d.EndCO2eKg += recordCO2e(last)
+		d.StartFindings += first.TotalFindings
+		d.EndFindings += last.TotalFindings
+		for id, n := range first.FindingsByType {
+			if resolved := n - last.FindingsByType[id]; resolved > 0 {
+				d.Cleanup.ResolvedFindings[id] += resolved
+			}
+		}
+
+		// Usage is keyed by region and repository, waste by repository.
+		for key, u := range first.Repositories {
+			repo := u.Name(key)
+			d.StartBytes += u.SizeBytes
+			c := RepoChange{Provider: first.Provider, Repository: repo, Region: u.Region,
+				StartBytes: u.SizeBytes, StartWaste: first.RepositoryWaste[repo]}
+			if now, ok := last.Repositories[key]; ok {
+				c.EndBytes, c.EndWaste = now.SizeBytes, last.RepositoryWaste[repo]
+			} else {
+				c.Deleted = true
+				d.Cleanup.DeletedRepositories = append(d.Cleanup.DeletedRepositories, c)
+			}
+			changes = append(changes, c)
+		}
+		for key, u := range last.Repositories {
+			d.EndBytes += u.SizeBytes
+			if _, ok := first.Repositories[key]; !ok {
+				repo := u.Name(key)
+				changes = append(changes, RepoChange{Provider: last.Provider, Repository: repo, Region: u.Region,
+					EndBytes: u.SizeBytes, EndWaste: last.RepositoryWaste[repo]})
+			}
+		}
+	}
+
+	// The trend sums each target's latest waste after every scan in the period,
+	// starting from the baseline waste of targets not yet scanned.
+	sort.SliceStable(window, func(i, j int) bool { return window[i].record.Timestamp.Before(window[j].record.Timestamp) })
+	for _, s := range window {
+		latest[s.target] = s.record.TotalMonthlyWaste
+		total := 0.0
+		for _, w := range latest {
+			total += w
+		}
+		d.Trend = append(d.Trend, Point{Time: s.record.Timestamp, MonthlyWaste: total})
+	}
+
+	for _, c := range changes {
+		if delta := c.BytesDelta(); delta < 0 {
+			d.Cleanup.ReclaimedBytes -= delta
+		}
+	}
+	sort.Slice(d.Cleanup.DeletedRepositories, func(i, j int) bool {
+		return d.Cleanup.DeletedRepositories[i].StartBytes > d.Cleanup.DeletedRepositories[j].StartBytes
+	})
+	if len(d.Cleanup.ResolvedFindings) == 0 {
+		d.Cleanup.ResolvedFindings = nil
+	}
+
+	d.Regressions, d.Improvements = rank(changes, top)
+	return d
+}
+
+// recordCO2e estimates the monthly emissions of the repository waste of one
+// scan. Records without per-repository waste estimate nothing.
+func recordCO2e(r history.Record) float64 {
+	provider := pricing.ProviderKey(r.Provider)
+	regions := make(map[string]string, len(r.Repositories))
+	for key, u := range r.Repositories {
+		regions[u.Name(key)] = u.Region
+	}
+	var total float64
+	for repo, waste := range r.RepositoryWaste {
+		region := regions[repo]
+		total += pricing.MonthlyCO2eKg(provider, region, pricing.StorageGB(provider, region, waste))
+	}
+	return total
+}
+
+// rank returns the repositories whose waste (then storage) grew the most and
+// those that shrank the most. Deleted repositories are reported as cleanup.
+func rank(changes []RepoChange, top int) (regressions, improvements []RepoChange) {
+	for _, c := range changes {
+		switch {
+		case c.Deleted:
+		case c.WasteDelta() > 0 || (c.WasteDelta() == 0 && c.BytesDelta() > 0):
+			regressions = append(regressions, c)
+		case c.WasteDelta() < 0 || (c.WasteDelta() == 0 && c.BytesDelta() < 0):
+			improvements = append(improvements, c)
+		}
+	}
+	sort.Slice(regressions, func(i, j int) bool { return worse(regressions[i], regressions[j]) })
+	sort.Slice(improvements, func(i, j int) bool { return worse(improvements[j], improvements[i]) })
+	if top > 0 && len(regressions) > top {
+		regressions = regressions[:top]
+	}
+	if top > 0 && len(improvements) > top {
+		improvements = improvements[:top]
+	}
+	return regressions, improvements
+}
+
+// worse orders changes by waste growth, then storage growth, then name.
+func worse(a, b RepoChange) bool {
+	if a.WasteDelta() != b.WasteDelta() {
+		return a.WasteDelta() > b.WasteDelta()
+	}
+	if a.BytesDelta() != b.BytesDelta() {
+		return a.BytesDelta() > b.BytesDelta()
+	}
+	return a.Repository < b.Repository
+}
+
+// WasteChangePercent returns the relative change in monthly waste, or 0 when
+// the period started without waste.
+func (d Digest) WasteChangePercent() float64 {
+	if d.StartWaste == 0 {
+		return 0
+	}
+	return (d.EndWaste - d.StartWaste) / d.StartWaste * 100
+}
@@ -0,0 +1,192 @@
+package digest
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/history"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+const gib = int64(1 << 30)
+
+var t0 = time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+
+func record(day int, waste float64, sizes map[string]int64, repoWaste map[string]float64, byType map[string]int) history.Record {
+	usage := make(map[string]registry.RepoUsage, len(sizes))
+	total := 0
+	for repo, size := range sizes {
+		usage[repo] = registry.RepoUsage{Region: "us-east-1", SizeBytes: size}
+	}
+	for _, n := range byType {
+		total += n
+	}
+	return history.Record{
+		Timestamp:         t0.AddDate(0, 0, day),
+		Provider:          "ecr",
+		TotalFindings:     total,
+		TotalMonthlyWaste: waste,
+		Repositories:      usage,
+		RepositoryWaste:   repoWaste,
+		FindingsByType:    byType,
+	}
+}
+
+func sampleHistory() map[string][]history.Record {
+	return map[string][]history.Record{
+		"aaaa": {
+			// Before the period: ignored except as the baseline.
+			record(-40, 90, map[string]int64{"api": 1 * gib}, nil, nil),
+			record(-2, 60, map[string]int64{"api": 2 * gib, "ci": 10 * gib, "old": 5 * gib},
+				map[string]float64{"api": 5, "ci": 40, "old": 15}, map[string]int{"STALE_IMAGE": 8, "UNTAGGED_IMAGE": 2}),
+			record(10, 55, map[string]int64{"api": 6 * gib, "ci": 3 * gib},
+				map[string]float64{"api": 20, "ci": 35}, map[string]int{"STALE_IMAGE": 5, "UNTAGGED_IMAGE": 2}),
+			record(20, 30, map[string]int64{"api": 6 * gib, "ci": 1 * gib, "new": 1 * gib},
+				map[string]float64{"api": 20, "ci": 8, "new": 2}, map[string]int{"STALE_IMAGE": 3, "UNTAGGED_IMAGE": 3}),
+			// After the period.
+			record(40, 100, map[string]int64{"api": 50 * gib}, nil, nil),
+		},
+		"bbbb": {
+			record(5, 10, map[string]int64{"web": 1 * gib}, map[string]float64{"web": 10}, nil),
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	d := Build(sampleHistory(), t0, t0.AddDate(0, 0, 30), DefaultTop)
+
+	if d.Targets != 2 || d.Scans != 3 {
+		t.Errorf("targets/scans = %d/%d, want 2/3", d.Targets, d.Scans)
+	}
+	if d.StartWaste != 70 || d.EndWaste != 40 {
+		t.Errorf("waste = %.0f -> %.0f, want 70 -> 40", d.StartWaste, d.EndWaste)
+	}
+	if d.StartFindings != 10 || d.EndFindings != 6 {
+		t.Errorf("findings = %d -> %d, want 10 -> 6", d.StartFindings, d.EndFindings)
+	}
+
+	wantTrend := []float64{70, 65, 40}
+	if len(d.Trend) != len(wantTrend) {
+		t.Fatalf("trend = %v", d.Trend)
+	}
+	for i, w := range wantTrend {
+		if d.Trend[i].MonthlyWaste != w {
+			t.Errorf("trend[%d] = %.0f, want %.0f", i, d.Trend[i].MonthlyWaste, w)
+		}
+	}
+
+	if len(d.Regressions) != 2 || d.Regressions[0].Repository != "api" || d.Regressions[1].Repository != "new" {
+		t.Errorf("regressions = %+v", d.Regressions)
+	}
+	if len(d.Improvements) != 1 || d.Improvements[0].Repository != "ci" || d.Improvements[0].WasteDelta() != -32 {
+		t.Errorf("improvements = %+v", d.Improvements)
+	}
+	if len(d.Cleanup.DeletedRepositories) != 1 || d.Cleanup.DeletedRepositories[0].Repository != "old" {
+		t.Errorf("deleted = %+v", d.Cleanup.DeletedRepositories)
+	}
+	if d.Cleanup.ReclaimedBytes != 14*gib {
+		t.Errorf("reclaimed = %d, want %d", d.Cleanup.ReclaimedBytes, 14*gib)
+	}
+	if d.Cleanup.ResolvedFindings["STALE_IMAGE"] != 5 || d.Cleanup.ResolvedFindings["UNTAGGED_IMAGE"] != 0 {
+		t.Errorf("resolved = %v", d.Cleanup.ResolvedFindings)
+	}
+}
+
+func TestBuildTopLimit(t *testing.T) {
+	d := Build(sampleHistory(), t0, t0.AddDate(0, 0, 30), 1)
+	if len(d.Regressions) != 1 || d.Regressions[0].Repository != "api" {
+		t.Errorf("regressions = %+v", d.Regressions)
+	}
+}
+
+func TestBuildEmpty(t *testing.T) {
+	d := Build(sampleHistory(), t0.AddDate(1, 0, 0), t0.AddDate(1, 1, 0), DefaultTop)
+	if d.Scans != 0 || d.Targets != 0 {
+		t.Errorf("expected empty digest, got %+v", d)
+	}
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, d); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "No scans were recorded") {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	d := Build(sampleHistory(), t0, t0.AddDate(0, 0, 30), DefaultTop)
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, d); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"Monthly registry waste fell from $70.00 to $40.00 (-43%) across 3 scans of 2 targets",
+		"Resolved findings: 5 STALE_IMAGE.",
+		"| api | ecr | $20.00 | +$15.00 | 6.0 GB | +4.0 GB |",
+		"| ci | ecr | $8.00 | -$32.00 | 1.0 GB | -9.0 GB |",
+		"- old (ecr, 5.0 GB)",
+		"Waste trend: `",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("markdown missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	d := Build(sampleHistory(), t0, t0.AddDate(0, 0, 30), DefaultTop)
+	d.Regressions[0].Repository = "<script>"
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, d); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "<h2>Top regressions</h2>") || !strings.Contains(out, "&lt;script&gt;") {
+		t.Errorf("unexpected HTML:\n%s", out)
+	}
+}
+
+func TestWriteHTMLCarbon(t *testing.T) {
+	d := Build(sampleHistory(), t0, t0.AddDate(0, 0, 30), DefaultTop)
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, d); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "CO2e") {
+		t.Error("carbon estimate shown without Carbon")
+	}
+
+	// $70 and $40 of waste at $0.10/GB are 700 and 400 GB in us-east-1.
+	d.Carbon = true
+	buf.Reset()
+	if err := WriteHTML(&buf, d); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<li>Estimated emissions of wasted storage: 0.42 → 0.24 kg CO2e/mo.</li>"; !strings.Contains(buf.String(), want) {
+		t.Errorf("HTML missing %q:\n%s", want, buf.String())
+	}
+}
+
+func TestWriteEmail(t *testing.T) {
+	d := Build(sampleHistory(), t0, t0.AddDate(0, 0, 30), DefaultTop)
+	var buf bytes.Buffer
+	if err := WriteEmail(&buf, d, "spectre@example.com", "director@example.com", t0); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"From: spectre@example.com\r\n",
+		"To: director@example.com\r\n",
+		"Subject: =?utf-8?q?",
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Type: text/html; charset=utf-8",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("email missing %q", want)
+		}
+	}
+}
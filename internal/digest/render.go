@@ -0,0 +1,264 @@
+package digest
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/report"
+)
+
+const dateFormat = "2006-01-02"
+
+// Headline is the one-sentence summary of the period.
+func (d Digest) Headline() string {
+	if d.Scans == 0 {
+		return fmt.Sprintf("No scans were recorded between %s and %s.", d.From.Format(dateFormat), d.To.Format(dateFormat))
+	}
+	verb := "was unchanged at"
+	switch {
+	case d.EndWaste < d.StartWaste:
+		verb = "fell from " + usd(d.StartWaste) + " to"
+	case d.EndWaste > d.StartWaste:
+		verb = "rose from " + usd(d.StartWaste) + " to"
+	}
+	change := ""
+	if pct := d.WasteChangePercent(); pct != 0 {
+		change = fmt.Sprintf(" (%+.0f%%)", pct)
+	}
+	return fmt.Sprintf("Monthly registry waste %s %s%s across %d scans of %d targets between %s and %s.",
+		verb, usd(d.EndWaste), change, d.Scans, d.Targets, d.From.Format(dateFormat), d.To.Format(dateFormat))
+}
+
+// highlights are the narrative bullet points below the headline.
+func (d Digest) highlights() []string {
+	if d.Scans == 0 {
+		return nil
+	}
+	lines := []string{
+		fmt.Sprintf("Open findings: %d → %d.", d.StartFindings, d.EndFindings),
+		fmt.Sprintf("Stored: %s → %s.", gb(d.StartBytes), gb(d.EndBytes)),
+	}
+	if n := len(d.Cleanup.DeletedRepositories); n > 0 || d.Cleanup.ReclaimedBytes > 0 {
+		lines = append(lines, fmt.Sprintf("Cleanup reclaimed %s, including %d deleted repositories.", gb(d.Cleanup.ReclaimedBytes), n))
+	}
+	if d.Carbon {
+		lines = append(lines, fmt.Sprintf("Estimated emissions of wasted storage: %.2f → %.2f kg CO2e/mo.", d.StartCO2eKg, d.EndCO2eKg))
+	}
+	if resolved := d.resolvedText(); resolved != "" {
+		lines = append(lines, "Resolved findings: "+resolved+".")
+	}
+	return lines
+}
+
+func (d Digest) resolvedText() string {
+	ids := make([]string, 0, len(d.Cleanup.ResolvedFindings))
+	for id := range d.Cleanup.ResolvedFindings {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("%d %s", d.Cleanup.ResolvedFindings[id], id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// Subject is the email subject line for the digest.
+func (d Digest) Subject() string {
+	return fmt.Sprintf("ecrspectre digest %s – %s: %s/mo waste", d.From.Format(dateFormat), d.To.Format(dateFormat), usd(d.EndWaste))
+}
+
+func usd(v float64) string { return fmt.Sprintf("$%.2f", v) }
+
+func signedUSD(v float64) string {
+	if v < 0 {
+		return "-" + usd(-v)
+	}
+	return "+" + usd(v)
+}
+
+func gb(b int64) string { return fmt.Sprintf("%.1f GB", float64(b)/(1<<30)) }
+
+func signedGB(b int64) string {
+	if b < 0 {
+		return "-" + gb(-b)
+	}
+	return "+" + gb(b)
+}
+
+// sparkline renders the waste trend with block characters.
+func (d Digest) sparkline() string {
+	values := make([]float64, len(d.Trend))
+	for i, p := range d.Trend {
+		values[i] = p.MonthlyWaste
+	}
+	return report.Sparkline(values)
+}
+
+// WriteMarkdown renders the digest as Markdown.
+func WriteMarkdown(w io.Writer, d Digest) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## ecrspectre digest: %s – %s\n\n", d.From.Format(dateFormat), d.To.Format(dateFormat))
+	b.WriteString(d.Headline() + "\n")
+	if d.Scans == 0 {
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	b.WriteString("\n")
+	for _, line := range d.highlights() {
+		b.WriteString("- " + line + "\n")
+	}
+	if spark := d.sparkline(); spark != "" {
+		fmt.Fprintf(&b, "\nWaste trend: `%s` (%s → %s)\n", spark, usd(d.Trend[0].MonthlyWaste), usd(d.Trend[len(d.Trend)-1].MonthlyWaste))
+	}
+	writeChangeTable(&b, "Top regressions", d.Regressions)
+	writeChangeTable(&b, "Top improvements", d.Improvements)
+	if len(d.Cleanup.DeletedRepositories) > 0 {
+		b.WriteString("\n### Deleted repositories\n\n")
+		for _, c := range d.Cleanup.DeletedRepositories {
+			fmt.Fprintf(&b, "- %s (%s, %s)\n", escapeMarkdown(c.Repository), c.Provider, gb(c.StartBytes))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeChangeTable(b *strings.Builder, title string, changes []RepoChange) {
+	fmt.Fprintf(b, "\n### %s\n\n", title)
+	if len(changes) == 0 {
+		b.WriteString("None.\n")
+		return
+	}
+	b.WriteString("| Repository | Provider | Waste/mo | Change | Stored | Change |\n")
+	b.WriteString("|------------|----------|---------:|-------:|-------:|-------:|\n")
+	for _, c := range changes {
+		fmt.Fprintf(b, "| %s | %s | %s | %s | %s | %s |\n", escapeMarkdown(c.Repository), c.Provider,
+			usd(c.EndWaste), signedUSD(c.WasteDelta()), gb(c.EndBytes), signedGB(c.BytesDelta()))
+	}
+}
+
+func escapeMarkdown(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+var htmlTemplate = template.Must(template.New("digest").Funcs(template.FuncMap{
+	"usd":       usd,
+	"signedUSD": signedUSD,
+	"gb":        gb,
+	"signedGB":  signedGB,
+	"date":      func(t time.Time) string { return t.Format(dateFormat) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>ecrspectre digest</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>ecrspectre digest: {{date .D.From}} – {{date .D.To}}</h1>
+<p>{{.D.Headline}}</p>
+{{if .D.Scans}}<ul>
+{{range .Highlights}}<li>{{.}}</li>
+{{end}}</ul>
+{{if .Sparkline}}<p>Waste trend: <code>{{.Sparkline}}</code></p>
+{{end}}{{range .Tables}}<h2>{{.Title}}</h2>
+{{if .Changes}}<table>
+<tr><th>Repository</th><th>Provider</th><th>Waste/mo</th><th>Change</th><th>Stored</th><th>Change</th></tr>
+{{range .Changes}}<tr><td>{{.Repository}}</td><td>{{.Provider}}</td><td class="num">{{usd .EndWaste}}</td><td class="num">{{signedUSD .WasteDelta}}</td><td class="num">{{gb .EndBytes}}</td><td class="num">{{signedGB .BytesDelta}}</td></tr>
+{{end}}</table>{{else}}<p>None.</p>{{end}}
+{{end}}{{if .D.Cleanup.DeletedRepositories}}<h2>Deleted repositories</h2>
+<ul>
+{{range .D.Cleanup.DeletedRepositories}}<li>{{.Repository}} ({{.Provider}}, {{gb .StartBytes}})</li>
+{{end}}</ul>
+{{end}}{{end}}</body>
+</html>
+`))
+
+type changeTable struct {
+	Title   string
+	Changes []RepoChange
+}
+
+// WriteHTML renders the digest as a standalone HTML page.
+func WriteHTML(w io.Writer, d Digest) error {
+	return htmlTemplate.Execute(w, struct {
+		D          Digest
+		Highlights []string
+		Sparkline  string
+		Tables     []changeTable
+	}{d, d.highlights(), d.sparkline(), []changeTable{
+		{"Top regressions", d.Regressions},
+		{"Top improvements", d.Improvements},
+	}})
+}
+
+// WriteEmail renders the digest as a MIME message with Markdown and HTML
+// alternatives, ready to pipe into sendmail or attach to a mail API call.
+func WriteEmail(w io.Writer, d Digest, from, to string, now time.Time) error {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+
+	var header strings.Builder
+	if from != "" {
+		fmt.Fprintf(&header, "From: %s\r\n", from)
+	}
+	if to != "" {
+		fmt.Fprintf(&header, "To: %s\r\n", to)
+	}
+	fmt.Fprintf(&header, "Subject: %s\r\n", mimeHeader(d.Subject()))
+	fmt.Fprintf(&header, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	header.WriteString("MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&header, "Content-Type: multipart/alternative; boundary=%q\r\n\r\n", mw.Boundary())
+
+	parts := []struct {
+		contentType string
+		render      func(io.Writer, Digest) error
+	}{
+		{"text/plain; charset=utf-8", WriteMarkdown},
+		{"text/html; charset=utf-8", WriteHTML},
+	}
+	for _, p := range parts {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {p.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return fmt.Errorf("create email part: %w", err)
+		}
+		if err := p.render(pw, d); err != nil {
+			return err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return fmt.Errorf("close email: %w", err)
+	}
+
+	if _, err := io.WriteString(w, header.String()); err != nil {
+		return err
+	}
+	_, err := w.Write(body.Bytes())
+	return err
+}
+
+// mimeHeader encodes a non-ASCII header value per RFC 2047.
+func mimeHeader(s string) string {
+	for _, r := range s {
+		if r > 127 {
+			return mime.QEncoding.Encode("utf-8", s)
+		}
+	}
+	return s
+}
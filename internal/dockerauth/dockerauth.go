@@ -0,0 +1,241 @@
+// Package dockerauth reuses the registry credentials the docker CLI already
+// has: static auths and credential helpers from ~/.docker/config.json (or
+// $DOCKER_CONFIG/config.json), and the Docker registry token handshake that
+// turns them into a bearer token.
+package dockerauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// Credential is a username and secret for one registry host. An identity
+// token is returned by helpers with the username "<token>".
+type Credential struct {
+	Username string
+	Secret   string
+}
+
+// IsZero reports whether no credential was found.
+func (c Credential) IsZero() bool {
+	return c.Username == "" && c.Secret == ""
+}
+
+// Config is the credential part of a docker CLI config file.
+type Config struct {
+	Auths map[string]struct {
+		Auth string `json:"auth"`
+	} `json:"auths"`
+	CredHelpers map[string]string `json:"credHelpers"`
+	CredsStore  string            `json:"credsStore"`
+
+	// runHelper invokes docker-credential-<name> get; replaced in tests.
+	runHelper func(ctx context.Context, name, host string) ([]byte, error)
+}
+
+// ConfigPath returns the docker CLI config file: $DOCKER_CONFIG/config.json
+// or ~/.docker/config.json.
+func ConfigPath() string {
+	if dir := os.Getenv("DOCKER_CONFIG"); dir != "" {
+		return filepath.Join(dir, "config.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".docker", "config.json")
+}
+
+// Load reads a docker CLI config. A missing file yields an empty config.
+func Load(path string) (*Config, error) {
+	cfg := &Config{runHelper: runHelper}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read docker config %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parse docker config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Lookup returns the credential for a registry host, trying the host's
+// credential helper, then a static auth entry, then the default credential
+// store, as the docker CLI does. It returns a zero Credential if none is
+// configured.
+func (c *Config) Lookup(ctx context.Context, host string) (Credential, error) {
+	if helper := c.CredHelpers[host]; helper != "" {
+		return c.fromHelper(ctx, helper, host)
+	}
+	for key, a := range c.Auths {
+		if normalizeHost(key) != host || a.Auth == "" {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(a.Auth)
+		if err != nil {
+			return Credential{}, fmt.Errorf("decode docker auth for %s: %w", host, err)
+		}
+		user, secret, _ := strings.Cut(string(decoded), ":")
+		return Credential{Username: user, Secret: secret}, nil
+	}
+	if c.CredsStore != "" {
+		return c.fromHelper(ctx, c.CredsStore, host)
+	}
+	return Credential{}, nil
+}
+
+func (c *Config) fromHelper(ctx context.Context, helper, host string) (Credential, error) {
+	out, err := c.runHelper(ctx, helper, host)
+	if err != nil {
+		// Helpers report unknown hosts on stdout with a non-zero exit.
+		if strings.Contains(string(out), "credentials not found") {
+			return Credential{}, nil
+		}
+		return Credential{}, fmt.Errorf("docker-credential-%s get %s: %w", helper, host, err)
+	}
+	var resp struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return Credential{}, fmt.Errorf("parse docker-credential-%s output: %w", helper, err)
+	}
+	return Credential{Username: resp.Username, Secret: resp.Secret}, nil
+}
+
+func runHelper(ctx context.Context, name, host string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "docker-credential-"+name, "get")
+	cmd.Stdin = strings.NewReader(host)
+	return cmd.Output()
+}
+
+// normalizeHost strips the scheme and path from an auths key such as
+// https://index.docker.io/v1/.
+func normalizeHost(key string) string {
+	key = strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://")
+	host, _, _ := strings.Cut(key, "/")
+	return host
+}
+
+// Do sends a request to a registry with cred. A registry that answers 401
+// with a Bearer challenge gets the credential at its token realm and the
+// request is retried with the issued token; a request with a body is only
+// retried when it has GetBody to rewind it.
+func Do(ctx context.Context, client *http.Client, req *http.Request, cred Credential) (*http.Response, error) {
+	req.SetBasicAuth(cred.Username, cred.Secret)
+	resp, err := client.Do(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	challenge := resp.Header.Get("WWW-Authenticate")
+	_ = resp.Body.Close()
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return nil, fmt.Errorf("registry rejected credentials (HTTP 401)")
+	}
+	token, err := fetchToken(ctx, client, parseChallenge(challenge[len("bearer "):]), cred)
+	if err != nil {
+		return nil, err
+	}
+	retry := req.Clone(ctx)
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, fmt.Errorf("registry requires a bearer token; request body cannot be resent")
+		}
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, err
+		}
+	}
+	retry.Header.Set("Authorization", "Bearer "+token)
+	return client.Do(retry)
+}
+
+// parseChallenge parses the comma-separated key="value" parameters of a
+// WWW-Authenticate Bearer challenge.
+func parseChallenge(s string) map[string]string {
+	params := make(map[string]string)
+	for s != "" {
+		key, rest, ok := strings.Cut(strings.TrimLeft(s, " ,"), "=")
+		if !ok {
+			break
+		}
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.IndexByte(rest[1:], '"')
+			if end < 0 {
+				break
+			}
+			value, s = rest[1:end+1], rest[end+2:]
+		} else {
+			value, s, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return params
+}
+
+func fetchToken(ctx context.Context, client *http.Client, challenge map[string]string, cred Credential) (string, error) {
+	realm := challenge["realm"]
+	if realm == "" {
+		return "", fmt.Errorf("registry token challenge has no realm")
+	}
+	u, err := url.Parse(realm)
+	if err != nil {
+		return "", fmt.Errorf("invalid token realm %q: %w", realm, err)
+	}
+	q := u.Query()
+	for _, k := range []string{"service", "scope"} {
+		if v := challenge[k]; v != "" {
+			q.Set(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", fmt.Errorf("build token request: %w", err)
+	}
+	req.SetBasicAuth(cred.Username, cred.Secret)
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("get registry token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("read registry token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("get registry token: HTTP %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	var tok struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", fmt.Errorf("parse registry token: %w", err)
+	}
+	if tok.Token != "" {
+		return tok.Token, nil
+	}
+	if tok.AccessToken != "" {
+		return tok.AccessToken, nil
+	}
+	return "", fmt.Errorf("registry token response has no token")
+}
@@ -0,0 +1,156 @@
+package dockerauth
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLookupOrder(t *testing.T) {
+	// "user:pass" in base64
+	cfg, err := Load(writeConfig(t, `{
+		"auths": {"https://registry.corp:5000/v1/": {"auth": "dXNlcjpwYXNz"}},
+		"credHelpers": {"us-docker.pkg.dev": "gcloud"},
+		"credsStore": "desktop"
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls []string
+	cfg.runHelper = func(_ context.Context, name, host string) ([]byte, error) {
+		calls = append(calls, name+" "+host)
+		if name == "desktop" {
+			return []byte("credentials not found in native keychain\n"), errors.New("exit status 1")
+		}
+		return []byte(`{"ServerURL":"us-docker.pkg.dev","Username":"oauth2accesstoken","Secret":"ya29.tok"}`), nil
+	}
+	ctx := context.Background()
+
+	got, err := cfg.Lookup(ctx, "us-docker.pkg.dev")
+	if err != nil || got != (Credential{Username: "oauth2accesstoken", Secret: "ya29.tok"}) {
+		t.Errorf("helper credential = %+v, %v", got, err)
+	}
+	got, err = cfg.Lookup(ctx, "registry.corp:5000")
+	if err != nil || got != (Credential{Username: "user", Secret: "pass"}) {
+		t.Errorf("static credential = %+v, %v", got, err)
+	}
+	got, err = cfg.Lookup(ctx, "ghcr.io")
+	if err != nil || !got.IsZero() {
+		t.Errorf("unknown host = %+v, %v; want zero credential", got, err)
+	}
+	if strings.Join(calls, ",") != "gcloud us-docker.pkg.dev,desktop ghcr.io" {
+		t.Errorf("helper calls = %v", calls)
+	}
+}
+
+func TestLoadMissingConfig(t *testing.T) {
+	cfg, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := cfg.Lookup(context.Background(), "us-docker.pkg.dev"); err != nil || !got.IsZero() {
+		t.Errorf("Lookup = %+v, %v", got, err)
+	}
+	if _, err := Load(writeConfig(t, "{")); err == nil {
+		t.Error("expected parse error")
+	}
+}
+
+func TestDoTokenHandshake(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			user, pass, _ := r.BasicAuth()
+			if user != "u" || pass != "p" || r.URL.Query().Get("scope") != "repository:team/api:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = w.Write([]byte(`{"token":"bearer-tok"}`))
+		case "/v2/team/api/manifests/sha256:abc":
+			if r.Header.Get("Authorization") != "Bearer bearer-tok" {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token",service="registry",scope="repository:team/api:pull"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_, _ = w.Write([]byte(`{"schemaVersion":2}`))
+		}
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v2/team/api/manifests/sha256:abc", nil)
+	resp, err := Do(context.Background(), srv.Client(), req, Credential{Username: "u", Secret: "p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want 200", resp.StatusCode)
+	}
+}
+
+func TestDoResendsBody(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			_, _ = w.Write([]byte(`{"token":"bearer-tok"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer bearer-tok" {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="`+srv.URL+`/token"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "manifest" {
+			t.Errorf("retried body = %q", body)
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/v2/team/api/manifests/v1", strings.NewReader("manifest"))
+	resp, err := Do(context.Background(), srv.Client(), req, Credential{Username: "u", Secret: "p"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("status = %d, want 201", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, srv.URL+"/v2/team/api/manifests/v1", io.NopCloser(strings.NewReader("manifest")))
+	req.GetBody = nil
+	if _, err := Do(context.Background(), srv.Client(), req, Credential{Username: "u", Secret: "p"}); err == nil {
+		t.Error("expected error for a body that cannot be resent")
+	}
+}
+
+func TestParseChallenge(t *testing.T) {
+	got := parseChallenge(`realm="https://auth.example/token",service="reg",scope="repository:a/b:pull,push"`)
+	if got["realm"] != "https://auth.example/token" || got["service"] != "reg" || got["scope"] != "repository:a/b:pull,push" {
+		t.Errorf("parseChallenge = %v", got)
+	}
+}
+
+func TestConfigPathHonorsDockerConfig(t *testing.T) {
+	t.Setenv("DOCKER_CONFIG", "/etc/docker-ci")
+	if got := ConfigPath(); got != filepath.Join("/etc/docker-ci", "config.json") {
+		t.Errorf("ConfigPath = %s", got)
+	}
+}
@@ -5,11 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
 	"github.com/aws/aws-sdk-go-v2/service/ecr"
 	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/ppiankov/ecrspectre/internal/apistats"
+	"github.com/ppiankov/ecrspectre/internal/registry"
 )
 
 // ECRAPI defines the subset of the ECR API used by the scanner.
@@ -18,15 +27,25 @@ type ECRAPI interface {
 	DescribeImages(ctx context.Context, input *ecr.DescribeImagesInput, opts ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error)
 	GetLifecyclePolicy(ctx context.Context, input *ecr.GetLifecyclePolicyInput, opts ...func(*ecr.Options)) (*ecr.GetLifecyclePolicyOutput, error)
 	DescribeImageScanFindings(ctx context.Context, input *ecr.DescribeImageScanFindingsInput, opts ...func(*ecr.Options)) (*ecr.DescribeImageScanFindingsOutput, error)
+	ListTagsForResource(ctx context.Context, input *ecr.ListTagsForResourceInput, opts ...func(*ecr.Options)) (*ecr.ListTagsForResourceOutput, error)
+	ListImages(ctx context.Context, input *ecr.ListImagesInput, opts ...func(*ecr.Options)) (*ecr.ListImagesOutput, error)
+	BatchGetImage(ctx context.Context, input *ecr.BatchGetImageInput, opts ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error)
 }
 
+// batchGetImageLimit is the maximum number of image IDs per BatchGetImage call.
+const batchGetImageLimit = 100
+
 // Client wraps the AWS SDK configuration for creating ECR service clients.
 type Client struct {
 	cfg aws.Config
+	// endpointURL overrides the ECR API endpoint, e.g. an interface VPC
+	// endpoint when the public regional endpoint is unreachable.
+	endpointURL string
 }
 
 // NewClient creates a new AWS client using the specified profile and region.
-func NewClient(ctx context.Context, profile, region string) (*Client, error) {
+// A non-empty endpointURL replaces the resolved ECR API endpoint.
+func NewClient(ctx context.Context, profile, region, endpointURL string) (*Client, error) {
 	var opts []func(*awsconfig.LoadOptions) error
 
 	if profile != "" {
@@ -41,7 +60,26 @@ func NewClient(ctx context.Context, profile, region string) (*Client, error) {
 		return nil, fmt.Errorf("load AWS config: %w", err)
 	}
 
-	return &Client{cfg: cfg}, nil
+	return &Client{cfg: cfg, endpointURL: endpointURL}, nil
+}
+
+// credentialRefreshWindow renews credentials this long before they expire,
+// so no request of a long scan is signed with credentials about to lapse.
+const credentialRefreshWindow = 5 * time.Minute
+
+// UseWebIdentity replaces the configured credentials with those of roleARN,
+// assumed with identity tokens from tokens (AssumeRoleWithWebIdentity), for
+// CI OIDC federation. The role is assumed again shortly before each session
+// expires, so scans may outlast one session. A zero duration uses the role's
+// default session length.
+func (c *Client) UseWebIdentity(roleARN, sessionName string, duration time.Duration, tokens stscreds.IdentityTokenRetriever) {
+	provider := stscreds.NewWebIdentityRoleProvider(sts.NewFromConfig(c.cfg), roleARN, tokens, func(o *stscreds.WebIdentityRoleOptions) {
+		o.RoleSessionName = sessionName
+		o.Duration = duration
+	})
+	c.cfg.Credentials = aws.NewCredentialsCache(provider, func(o *aws.CredentialsCacheOptions) {
+		o.ExpiryWindow = credentialRefreshWindow
+	})
 }
 
 // Config returns the underlying AWS config.
@@ -51,7 +89,23 @@ func (c *Client) Config() aws.Config {
 
 // NewECRClient creates an ECR service client from the stored config.
 func (c *Client) NewECRClient() ECRAPI {
-	return ecr.NewFromConfig(c.cfg)
+	return ecr.NewFromConfig(c.cfg, func(o *ecr.Options) {
+		if c.endpointURL != "" {
+			o.BaseEndpoint = aws.String(c.endpointURL)
+		}
+		o.APIOptions = append(o.APIOptions, addAPIStats)
+	})
+}
+
+// addAPIStats counts every attempt of a request, after the retry
+// middleware, in apistats.
+func addAPIStats(stack *middleware.Stack) error {
+	return stack.Finalize.Add(middleware.FinalizeMiddlewareFunc("APIStats", func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+		out, md, err := next.HandleFinalize(ctx, in)
+		var apiErr smithy.APIError
+		apistats.Record(errors.As(err, &apiErr) && apistats.IsThrottleCode(apiErr.ErrorCode()))
+		return out, md, err
+	}), middleware.After)
 }
 
 // Region returns the configured region.
@@ -80,6 +134,20 @@ func ListRepositories(ctx context.Context, client ECRAPI) ([]ecrtypes.Repository
 	return repos, nil
 }
 
+// DescribeRepository returns a single repository by name.
+func DescribeRepository(ctx context.Context, client ECRAPI, repoName string) (ecrtypes.Repository, error) {
+	out, err := client.DescribeRepositories(ctx, &ecr.DescribeRepositoriesInput{
+		RepositoryNames: []string{repoName},
+	})
+	if err != nil {
+		return ecrtypes.Repository{}, fmt.Errorf("describe repository %s: %w", repoName, err)
+	}
+	if len(out.Repositories) == 0 {
+		return ecrtypes.Repository{}, fmt.Errorf("repository %s not found", repoName)
+	}
+	return out.Repositories[0], nil
+}
+
 // ListImages returns all image details for a given repository using pagination.
 func ListImages(ctx context.Context, client ECRAPI, repoName string) ([]ecrtypes.ImageDetail, error) {
 	var images []ecrtypes.ImageDetail
@@ -102,17 +170,122 @@ func ListImages(ctx context.Context, client ECRAPI, repoName string) ([]ecrtypes
 	return images, nil
 }
 
-// HasLifecyclePolicy checks if a repository has a lifecycle policy configured.
-func HasLifecyclePolicy(ctx context.Context, client ECRAPI, repoName string) (bool, error) {
-	_, err := client.GetLifecyclePolicy(ctx, &ecr.GetLifecyclePolicyInput{
+// ListImageIDs returns the sorted "digest tag" identifiers of a repository
+// using the cheap ListImages call. Untagged images are listed by digest alone.
+func ListImageIDs(ctx context.Context, client ECRAPI, repoName string) ([]string, error) {
+	var ids []string
+	input := &ecr.ListImagesInput{
+		RepositoryName: aws.String(repoName),
+	}
+
+	for {
+		out, err := client.ListImages(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("list images for %s: %w", repoName, err)
+		}
+		for _, id := range out.ImageIds {
+			ids = append(ids, strings.TrimSpace(aws.ToString(id.ImageDigest)+" "+aws.ToString(id.ImageTag)))
+		}
+		if out.NextToken == nil {
+			break
+		}
+		input.NextToken = out.NextToken
+	}
+
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// GetManifests returns the manifest documents of the given image digests,
+// keyed by digest. Images the API reports as failures are omitted.
+func GetManifests(ctx context.Context, client ECRAPI, repoName string, digests []string) (map[string]string, error) {
+	manifests := make(map[string]string, len(digests))
+	for start := 0; start < len(digests); start += batchGetImageLimit {
+		end := min(start+batchGetImageLimit, len(digests))
+		ids := make([]ecrtypes.ImageIdentifier, 0, end-start)
+		for _, d := range digests[start:end] {
+			ids = append(ids, ecrtypes.ImageIdentifier{ImageDigest: aws.String(d)})
+		}
+
+		out, err := client.BatchGetImage(ctx, &ecr.BatchGetImageInput{
+			RepositoryName:     aws.String(repoName),
+			ImageIds:           ids,
+			AcceptedMediaTypes: registry.ManifestMediaTypes,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("batch get image for %s: %w", repoName, err)
+		}
+		for _, img := range out.Images {
+			if img.ImageId != nil {
+				manifests[aws.ToString(img.ImageId.ImageDigest)] = aws.ToString(img.ImageManifest)
+			}
+		}
+		for _, f := range out.Failures {
+			slog.Debug("BatchGetImage failure", "repo", repoName, "code", f.FailureCode, "reason", aws.ToString(f.FailureReason))
+		}
+	}
+	return manifests, nil
+}
+
+// IndexChildren returns the digests of the manifests that the multi-arch
+// indexes among images reference, each mapped to the digest of an index
+// referencing it. ECR refuses to delete such a manifest while the index
+// exists.
+func IndexChildren(ctx context.Context, client ECRAPI, repoName string, images []ecrtypes.ImageDetail) (map[string]string, error) {
+	var indexes []string
+	for _, img := range images {
+		if isIndex(img) {
+			indexes = append(indexes, aws.ToString(img.ImageDigest))
+		}
+	}
+	if len(indexes) == 0 {
+		return nil, nil
+	}
+	manifests, err := GetManifests(ctx, client, repoName, indexes)
+	if err != nil {
+		return nil, err
+	}
+	children := make(map[string]string)
+	for digest, text := range manifests {
+		m, err := registry.ParseManifest(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s@%s: %w", repoName, digest, err)
+		}
+		for _, child := range m.Manifests {
+			children[child.Digest] = digest
+		}
+	}
+	return children, nil
+}
+
+// LifecyclePolicyText returns the lifecycle policy document of a repository,
+// or an empty string if none is configured.
+func LifecyclePolicyText(ctx context.Context, client ECRAPI, repoName string) (string, error) {
+	out, err := client.GetLifecyclePolicy(ctx, &ecr.GetLifecyclePolicyInput{
 		RepositoryName: aws.String(repoName),
 	})
 	if err != nil {
 		var notFound *ecrtypes.LifecyclePolicyNotFoundException
 		if errors.As(err, &notFound) {
-			return false, nil
+			return "", nil
 		}
-		return false, fmt.Errorf("get lifecycle policy for %s: %w", repoName, err)
+		return "", fmt.Errorf("get lifecycle policy for %s: %w", repoName, err)
+	}
+	return aws.ToString(out.LifecyclePolicyText), nil
+}
+
+// ListRepositoryTags returns the resource tags of a repository as a map.
+func ListRepositoryTags(ctx context.Context, client ECRAPI, repoARN string) (map[string]string, error) {
+	out, err := client.ListTagsForResource(ctx, &ecr.ListTagsForResourceInput{
+		ResourceArn: aws.String(repoARN),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("list tags for %s: %w", repoARN, err)
+	}
+
+	tags := make(map[string]string, len(out.Tags))
+	for _, t := range out.Tags {
+		tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
 	}
-	return true, nil
+	return tags, nil
 }
@@ -0,0 +1,72 @@
+package ecr
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+)
+
+// maxConfigBytes bounds the size of an image config blob read for its build
+// date.
+const maxConfigBytes = 4 << 20
+
+// ConfigFetcher reads image config blobs, which record when an image was
+// built.
+type ConfigFetcher interface {
+	ImageConfig(ctx context.Context, repoName, configDigest string) ([]byte, error)
+}
+
+// LayerURLAPI defines the ECR call that returns a download URL for a blob.
+type LayerURLAPI interface {
+	GetDownloadUrlForLayer(ctx context.Context, input *ecr.GetDownloadUrlForLayerInput, opts ...func(*ecr.Options)) (*ecr.GetDownloadUrlForLayerOutput, error)
+}
+
+// BlobConfigFetcher downloads config blobs from the presigned URLs returned
+// by GetDownloadUrlForLayer.
+type BlobConfigFetcher struct {
+	client LayerURLAPI
+	http   *http.Client
+}
+
+// NewBlobConfigFetcher creates a config fetcher for the given ECR client.
+func NewBlobConfigFetcher(client LayerURLAPI, httpClient *http.Client) *BlobConfigFetcher {
+	return &BlobConfigFetcher{client: client, http: httpClient}
+}
+
+// NewConfigFetcher creates a config fetcher from the stored config that
+// downloads blobs with httpClient.
+func (c *Client) NewConfigFetcher(httpClient *http.Client) *BlobConfigFetcher {
+	return NewBlobConfigFetcher(c.NewECRClient().(LayerURLAPI), httpClient)
+}
+
+// ImageConfig returns the config blob with the given digest.
+func (f *BlobConfigFetcher) ImageConfig(ctx context.Context, repoName, configDigest string) ([]byte, error) {
+	out, err := f.client.GetDownloadUrlForLayer(ctx, &ecr.GetDownloadUrlForLayerInput{
+		RepositoryName: aws.String(repoName),
+		LayerDigest:    aws.String(configDigest),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get download url for %s@%s: %w", repoName, configDigest, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, aws.ToString(out.DownloadUrl), nil)
+	if err != nil {
+		return nil, fmt.Errorf("build config request: %w", err)
+	}
+	resp, err := f.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("get config %s@%s: %w", repoName, configDigest, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("get config %s@%s: HTTP %d", repoName, configDigest, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxConfigBytes))
+	if err != nil {
+		return nil, fmt.Errorf("read config %s@%s: %w", repoName, configDigest, err)
+	}
+	return body, nil
+}
@@ -0,0 +1,100 @@
+package ecr
+
+import (
+	"context"
+	"errors"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/ppiankov/ecrspectre/internal/fixtures"
+)
+
+// FixtureAccount replaces AWS account IDs in recorded fixtures.
+const FixtureAccount = "000000000000"
+
+// FixtureSanitizers rewrite the account ID in ARNs, registry IDs and
+// repository URIs.
+var FixtureSanitizers = []fixtures.Replacement{
+	{Pattern: regexp.MustCompile(`(arn:aws[a-z-]*:[a-z0-9-]+:[a-z0-9-]*:)\d{12}`), With: "${1}" + FixtureAccount},
+	{Pattern: regexp.MustCompile(`("RegistryId":")\d{12}"`), With: `${1}` + FixtureAccount + `"`},
+	{Pattern: regexp.MustCompile(`\d{12}(\.dkr\.ecr\.)`), With: FixtureAccount + "${1}"},
+}
+
+// FixtureCodec records ECR API error codes and replays the error types the
+// scanner checks for.
+var FixtureCodec = fixtures.ErrorCodec{
+	Code: func(err error) string {
+		var apiErr interface{ ErrorCode() string }
+		if errors.As(err, &apiErr) {
+			return apiErr.ErrorCode()
+		}
+		return ""
+	},
+	Error: func(code, message string) error {
+		switch code {
+		case "LifecyclePolicyNotFoundException":
+			return &ecrtypes.LifecyclePolicyNotFoundException{Message: aws.String(message)}
+		case "ScanNotFoundException":
+			return &ecrtypes.ScanNotFoundException{Message: aws.String(message)}
+		case "RepositoryNotFoundException":
+			return &ecrtypes.RepositoryNotFoundException{Message: aws.String(message)}
+		}
+		return errors.New(message)
+	},
+}
+
+// fixtureClient records calls to next, or replays them when next is nil.
+type fixtureClient struct {
+	next  ECRAPI
+	store *fixtures.Store
+}
+
+// WithFixtures wraps client so its calls are recorded to or replayed from
+// store. When replaying, client may be nil.
+func WithFixtures(client ECRAPI, store *fixtures.Store) ECRAPI {
+	return &fixtureClient{next: client, store: store}
+}
+
+func (c *fixtureClient) DescribeRepositories(ctx context.Context, input *ecr.DescribeRepositoriesInput, opts ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error) {
+	return fixtures.Do(c.store, "DescribeRepositories", input, func() (*ecr.DescribeRepositoriesOutput, error) {
+		return c.next.DescribeRepositories(ctx, input, opts...)
+	})
+}
+
+func (c *fixtureClient) DescribeImages(ctx context.Context, input *ecr.DescribeImagesInput, opts ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
+	return fixtures.Do(c.store, "DescribeImages", input, func() (*ecr.DescribeImagesOutput, error) {
+		return c.next.DescribeImages(ctx, input, opts...)
+	})
+}
+
+func (c *fixtureClient) GetLifecyclePolicy(ctx context.Context, input *ecr.GetLifecyclePolicyInput, opts ...func(*ecr.Options)) (*ecr.GetLifecyclePolicyOutput, error) {
+	return fixtures.Do(c.store, "GetLifecyclePolicy", input, func() (*ecr.GetLifecyclePolicyOutput, error) {
+		return c.next.GetLifecyclePolicy(ctx, input, opts...)
+	})
+}
+
+func (c *fixtureClient) DescribeImageScanFindings(ctx context.Context, input *ecr.DescribeImageScanFindingsInput, opts ...func(*ecr.Options)) (*ecr.DescribeImageScanFindingsOutput, error) {
+	return fixtures.Do(c.store, "DescribeImageScanFindings", input, func() (*ecr.DescribeImageScanFindingsOutput, error) {
+		return c.next.DescribeImageScanFindings(ctx, input, opts...)
+	})
+}
+
+func (c *fixtureClient) ListTagsForResource(ctx context.Context, input *ecr.ListTagsForResourceInput, opts ...func(*ecr.Options)) (*ecr.ListTagsForResourceOutput, error) {
+	return fixtures.Do(c.store, "ListTagsForResource", input, func() (*ecr.ListTagsForResourceOutput, error) {
+		return c.next.ListTagsForResource(ctx, input, opts...)
+	})
+}
+
+func (c *fixtureClient) ListImages(ctx context.Context, input *ecr.ListImagesInput, opts ...func(*ecr.Options)) (*ecr.ListImagesOutput, error) {
+	return fixtures.Do(c.store, "ListImages", input, func() (*ecr.ListImagesOutput, error) {
+		return c.next.ListImages(ctx, input, opts...)
+	})
+}
+
+func (c *fixtureClient) BatchGetImage(ctx context.Context, input *ecr.BatchGetImageInput, opts ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error) {
+	return fixtures.Do(c.store, "BatchGetImage", input, func() (*ecr.BatchGetImageOutput, error) {
+		return c.next.BatchGetImage(ctx, input, opts...)
+	})
+}
@@ -0,0 +1,71 @@
+package ecr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/ppiankov/ecrspectre/internal/fixtures"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+func findingKeys(findings []registry.Finding) []string {
+	keys := make([]string, 0, len(findings))
+	for _, f := range findings {
+		keys = append(keys, string(f.ID)+" "+f.ResourceID)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestFixturesRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp"), makeRepo("legacy")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:old", []string{"v1"}, hundredMB, stale200, stale200),
+		makeImage("sha256:untagged", nil, hundredMB, recent, recent),
+	}
+	mock.images["legacy"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:big", []string{"latest"}, 2*1024*hundredMB/100, recent, recent),
+	}
+	mock.lifecycleRepos["myapp"] = true
+
+	recorder, err := fixtures.NewRecorder(dir, FixtureCodec, FixtureSanitizers...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := newTestScanner(WithFixtures(mock, recorder)).Scan(context.Background(), defaultCfg(), nil)
+	if len(recorded.Errors) != 0 {
+		t.Fatalf("record errors: %v", recorded.Errors)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, f := range files {
+		data, _ := os.ReadFile(f)
+		if strings.Contains(string(data), "123456789012") {
+			t.Errorf("%s still contains the account ID", filepath.Base(f))
+		}
+	}
+
+	replayer, err := fixtures.NewReplayer(dir, FixtureCodec, FixtureSanitizers...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := newTestScanner(WithFixtures(nil, replayer)).Scan(context.Background(), defaultCfg(), nil)
+	if len(replayed.Errors) != 0 {
+		t.Fatalf("replay errors: %v", replayed.Errors)
+	}
+
+	want, got := findingKeys(recorded.Findings), findingKeys(replayed.Findings)
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("replayed findings\n%v\nwant\n%v", got, want)
+	}
+	if len(findByID(replayed.Findings, registry.FindingNoLifecyclePolicy)) != 1 {
+		t.Error("lifecycle-not-found error not replayed as its ECR type")
+	}
+}
@@ -0,0 +1,49 @@
+package ecr
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// RepositoryInventory is the image count and storage of one repository.
+type RepositoryInventory struct {
+	Name      string
+	Images    int
+	SizeBytes int64
+	// PushedBytes is the size of the images pushed at or after the since
+	// time passed to Inventory.
+	PushedBytes int64
+}
+
+// Inventory lists the repositories matching filter with their image count,
+// size, and the size of the images pushed since the given time.
+func Inventory(ctx context.Context, client ECRAPI, filter registry.RepoFilter, since time.Time) ([]RepositoryInventory, error) {
+	repos, err := ListRepositories(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+	var inv []RepositoryInventory
+	for _, repo := range repos {
+		name := aws.ToString(repo.RepositoryName)
+		if !filter.Match(name) {
+			continue
+		}
+		images, err := ListImages(ctx, client, name)
+		if err != nil {
+			return nil, err
+		}
+		r := RepositoryInventory{Name: name, Images: len(images)}
+		for _, img := range images {
+			size := derefInt64(img.ImageSizeInBytes)
+			r.SizeBytes += size
+			if img.ImagePushedAt != nil && !img.ImagePushedAt.Before(since) {
+				r.PushedBytes += size
+			}
+		}
+		inv = append(inv, r)
+	}
+	return inv, nil
+}
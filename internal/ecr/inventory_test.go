@@ -0,0 +1,37 @@
+package ecr
+
+import (
+	"context"
+	"testing"
+
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+func TestInventory(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("api"), makeRepo("scratch")}
+	mock.images["api"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:aaa", []string{"v1"}, oneGB, stale120, stale120),
+		makeImage("sha256:bbb", []string{"v2"}, halfGB, recent, recent),
+	}
+	mock.images["scratch"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:ccc", nil, twoGB, recent, recent),
+	}
+	filter, err := registry.NewRepoFilter(nil, []string{"scratch"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inv, err := Inventory(context.Background(), mock, filter, now.AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(inv) != 1 {
+		t.Fatalf("inventory = %+v", inv)
+	}
+	if r := inv[0]; r.Name != "api" || r.Images != 2 || r.SizeBytes != oneGB+halfGB || r.PushedBytes != halfGB {
+		t.Errorf("api = %+v", r)
+	}
+}
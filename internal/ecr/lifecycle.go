@@ -0,0 +1,163 @@
+package ecr
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+// LifecyclePolicy is the subset of the ECR lifecycle policy document needed
+// to simulate which images would be expired.
+type LifecyclePolicy struct {
+	Rules []LifecycleRule `json:"rules"`
+}
+
+// LifecycleRule is a single ECR lifecycle policy rule.
+type LifecycleRule struct {
+	RulePriority int    `json:"rulePriority"`
+	Description  string `json:"description"`
+	Selection    struct {
+		TagStatus      string   `json:"tagStatus"`
+		TagPrefixList  []string `json:"tagPrefixList"`
+		TagPatternList []string `json:"tagPatternList"`
+		CountType      string   `json:"countType"`
+		CountUnit      string   `json:"countUnit"`
+		CountNumber    int      `json:"countNumber"`
+	} `json:"selection"`
+	Action struct {
+		Type string `json:"type"`
+	} `json:"action"`
+}
+
+// ParseLifecyclePolicy decodes an ECR lifecycle policy document.
+func ParseLifecyclePolicy(text string) (*LifecyclePolicy, error) {
+	var p LifecyclePolicy
+	if err := json.Unmarshal([]byte(text), &p); err != nil {
+		return nil, fmt.Errorf("parse lifecycle policy: %w", err)
+	}
+	sort.SliceStable(p.Rules, func(i, j int) bool {
+		return p.Rules[i].RulePriority < p.Rules[j].RulePriority
+	})
+	return &p, nil
+}
+
+// Simulate evaluates the policy against images and returns, per digest, the
+// priority of the rule that would expire it. As in ECR, each image is claimed
+// by the highest-priority rule whose tag selection matches it, and lower
+// priority rules cannot expire it.
+func (p *LifecyclePolicy) Simulate(images []ecrtypes.ImageDetail, now time.Time) map[string]int {
+	claimed := make(map[int][]ecrtypes.ImageDetail)
+	for _, img := range images {
+		for i, rule := range p.Rules {
+			if rule.selects(img) {
+				claimed[i] = append(claimed[i], img)
+				break
+			}
+		}
+	}
+
+	expired := make(map[string]int)
+	for i, rule := range p.Rules {
+		if rule.Action.Type != "" && rule.Action.Type != "expire" {
+			continue
+		}
+		for _, img := range rule.expire(claimed[i], now) {
+			expired[deref(img.ImageDigest)] = rule.RulePriority
+		}
+	}
+	return expired
+}
+
+func (r LifecycleRule) selects(img ecrtypes.ImageDetail) bool {
+	switch r.Selection.TagStatus {
+	case "untagged":
+		return len(img.ImageTags) == 0
+	case "tagged":
+		// As in ECR, every prefix and pattern listed must match one of the
+		// image's tags.
+		if len(r.Selection.TagPrefixList) == 0 && len(r.Selection.TagPatternList) == 0 {
+			return false
+		}
+		for _, prefix := range r.Selection.TagPrefixList {
+			if !slices.ContainsFunc(img.ImageTags, func(tag string) bool { return strings.HasPrefix(tag, prefix) }) {
+				return false
+			}
+		}
+		for _, pattern := range r.Selection.TagPatternList {
+			if !slices.ContainsFunc(img.ImageTags, func(tag string) bool { return matchTagPattern(pattern, tag) }) {
+				return false
+			}
+		}
+		return true
+	case "any":
+		return true
+	default:
+		return false
+	}
+}
+
+// matchTagPattern reports whether tag matches an ECR tagPatternList entry,
+// where `*` matches any run of characters and every other character,
+// including `?` and `[`, matches itself.
+func matchTagPattern(pattern, tag string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return tag == pattern
+	}
+	if !strings.HasPrefix(tag, parts[0]) {
+		return false
+	}
+	tag = tag[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(tag, part)
+		if i < 0 {
+			return false
+		}
+		tag = tag[i+len(part):]
+	}
+	return len(tag) >= len(last) && strings.HasSuffix(tag, last)
+}
+
+func (r LifecycleRule) expire(images []ecrtypes.ImageDetail, now time.Time) []ecrtypes.ImageDetail {
+	switch r.Selection.CountType {
+	case "imageCountMoreThan":
+		sorted := slices.Clone(images)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			return pushedAt(sorted[i]).After(pushedAt(sorted[j]))
+		})
+		if len(sorted) <= r.Selection.CountNumber {
+			return nil
+		}
+		return sorted[r.Selection.CountNumber:]
+	case "sinceImagePushed", "sinceImagePulled":
+		cutoff := now.AddDate(0, 0, -r.Selection.CountNumber)
+		var out []ecrtypes.ImageDetail
+		for _, img := range images {
+			t := pushedAt(img)
+			if r.Selection.CountType == "sinceImagePulled" {
+				if last := lastActivityTime(img); last != nil {
+					t = *last
+				}
+			}
+			if t.Before(cutoff) {
+				out = append(out, img)
+			}
+		}
+		return out
+	default:
+		return nil
+	}
+}
+
+func pushedAt(img ecrtypes.ImageDetail) time.Time {
+	if img.ImagePushedAt == nil {
+		return time.Time{}
+	}
+	return *img.ImagePushedAt
+}
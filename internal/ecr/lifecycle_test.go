@@ -0,0 +1,132 @@
+package ecr
+
+import (
+	"testing"
+	"time"
+
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+func TestParseLifecyclePolicyInvalid(t *testing.T) {
+	if _, err := ParseLifecyclePolicy("not json"); err == nil {
+		t.Error("expected error for invalid policy")
+	}
+}
+
+func TestLifecycleSimulateCountAndAge(t *testing.T) {
+	policy, err := ParseLifecyclePolicy(`{"rules":[
+		{"rulePriority":2,"selection":{"tagStatus":"tagged","tagPrefixList":["v"],"countType":"imageCountMoreThan","countNumber":1},"action":{"type":"expire"}},
+		{"rulePriority":1,"selection":{"tagStatus":"untagged","countType":"sinceImagePushed","countUnit":"days","countNumber":14},"action":{"type":"expire"}}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	day := 24 * time.Hour
+	images := []ecrtypes.ImageDetail{
+		makeImage("sha256:new", []string{"v2"}, 1, now.Add(-1*day), time.Time{}),
+		makeImage("sha256:old", []string{"v1"}, 1, now.Add(-5*day), time.Time{}),
+		makeImage("sha256:latest", []string{"latest"}, 1, now.Add(-50*day), time.Time{}),
+		makeImage("sha256:dangling", nil, 1, now.Add(-30*day), time.Time{}),
+		makeImage("sha256:fresh", nil, 1, now.Add(-2*day), time.Time{}),
+	}
+
+	got := policy.Simulate(images, now)
+	want := map[string]int{"sha256:old": 2, "sha256:dangling": 1}
+	if len(got) != len(want) {
+		t.Fatalf("Simulate() = %v, want %v", got, want)
+	}
+	for digest, rule := range want {
+		if got[digest] != rule {
+			t.Errorf("%s expired by rule %d, want %d", digest, got[digest], rule)
+		}
+	}
+}
+
+func TestLifecycleSimulateHigherPriorityClaimsImage(t *testing.T) {
+	// Rule 1 keeps up to 5 "prod" images; rule 2 would expire everything, but
+	// cannot touch images already claimed by rule 1.
+	policy, err := ParseLifecyclePolicy(`{"rules":[
+		{"rulePriority":1,"selection":{"tagStatus":"tagged","tagPatternList":["prod-*"],"countType":"imageCountMoreThan","countNumber":5},"action":{"type":"expire"}},
+		{"rulePriority":2,"selection":{"tagStatus":"any","countType":"sinceImagePushed","countUnit":"days","countNumber":1},"action":{"type":"expire"}}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := now.Add(-100 * 24 * time.Hour)
+	images := []ecrtypes.ImageDetail{
+		makeImage("sha256:prod", []string{"prod-1"}, 1, old, time.Time{}),
+		makeImage("sha256:dev", []string{"dev-1"}, 1, old, time.Time{}),
+	}
+
+	got := policy.Simulate(images, now)
+	if _, ok := got["sha256:prod"]; ok {
+		t.Error("prod image should be protected by higher-priority rule")
+	}
+	if got["sha256:dev"] != 2 {
+		t.Errorf("dev image expired by rule %d, want 2", got["sha256:dev"])
+	}
+}
+
+func TestLifecycleSimulateSincePulled(t *testing.T) {
+	policy, err := ParseLifecyclePolicy(`{"rules":[
+		{"rulePriority":1,"selection":{"tagStatus":"any","countType":"sinceImagePulled","countUnit":"days","countNumber":30},"action":{"type":"expire"}}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := now.Add(-100 * 24 * time.Hour)
+	images := []ecrtypes.ImageDetail{
+		makeImage("sha256:pulled", []string{"a"}, 1, old, now.Add(-time.Hour)),
+		makeImage("sha256:idle", []string{"b"}, 1, old, old),
+	}
+	got := policy.Simulate(images, now)
+	if _, ok := got["sha256:pulled"]; ok {
+		t.Error("recently pulled image should be kept")
+	}
+	if got["sha256:idle"] != 1 {
+		t.Error("idle image should be expired")
+	}
+}
+
+func TestLifecycleSimulateTagListsMatchAll(t *testing.T) {
+	policy, err := ParseLifecyclePolicy(`{"rules":[
+		{"rulePriority":1,"selection":{"tagStatus":"tagged","tagPrefixList":["prod","release"],"countType":"sinceImagePushed","countUnit":"days","countNumber":1},"action":{"type":"expire"}}
+	]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := now.Add(-100 * 24 * time.Hour)
+	images := []ecrtypes.ImageDetail{
+		makeImage("sha256:both", []string{"prod-1", "release-1"}, 1, old, time.Time{}),
+		makeImage("sha256:prod", []string{"prod-2"}, 1, old, time.Time{}),
+	}
+	got := policy.Simulate(images, now)
+	if got["sha256:both"] != 1 {
+		t.Error("image with both prefixes should be expired")
+	}
+	if _, ok := got["sha256:prod"]; ok {
+		t.Error("image with only one of the prefixes should be kept")
+	}
+}
+
+func TestMatchTagPattern(t *testing.T) {
+	tests := []struct {
+		pattern, tag string
+		want         bool
+	}{
+		{"prod-*", "prod-1", true},
+		{"*-rc*", "v1-rc2", true},
+		{"v*.*", "v1", false},
+		{"v?", "v1", false},
+		{"v?", "v?", true},
+		{"[ab]", "a", false},
+		{"a*a", "a", false},
+		{"*", "", true},
+	}
+	for _, tt := range tests {
+		if got := matchTagPattern(tt.pattern, tt.tag); got != tt.want {
+			t.Errorf("matchTagPattern(%q, %q) = %v, want %v", tt.pattern, tt.tag, got, tt.want)
+		}
+	}
+}
@@ -2,6 +2,8 @@ package ecr
 
 import (
 	"context"
+	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -11,34 +13,69 @@ import (
 
 // mockECRClient implements ECRAPI for testing.
 type mockECRClient struct {
-	repos          []ecrtypes.Repository
-	images         map[string][]ecrtypes.ImageDetail
-	lifecycleRepos map[string]bool // repos with lifecycle policy
-	scanFindings   map[string]*ecr.DescribeImageScanFindingsOutput
-	descRepoErr    error
-	descImagesErr  map[string]error
-	lifecycleErr   map[string]error
+	repos             []ecrtypes.Repository
+	images            map[string][]ecrtypes.ImageDetail
+	lifecycleRepos    map[string]bool   // repos with lifecycle policy
+	lifecyclePolicies map[string]string // policy text, implies a policy exists
+	scanFindings      map[string]*ecr.DescribeImageScanFindingsOutput
+	scanErr           map[string]error             // keyed by "repo@digest"
+	tags              map[string]map[string]string // keyed by repository ARN
+	tagsErr           map[string]error
+	descRepoErr       error
+	descImagesErr     map[string]error
+	lifecycleErr      map[string]error
+	listImagesErr     map[string]error
+	manifests         map[string]string // keyed by "repo@digest"
+	batchGetErr       map[string]error
+	onDescribe        func(repo string) // called before DescribeImages returns
+	lifecycleCalls    atomic.Int32
 }
 
 func newMockClient() *mockECRClient {
 	return &mockECRClient{
-		images:         make(map[string][]ecrtypes.ImageDetail),
-		lifecycleRepos: make(map[string]bool),
-		scanFindings:   make(map[string]*ecr.DescribeImageScanFindingsOutput),
-		descImagesErr:  make(map[string]error),
-		lifecycleErr:   make(map[string]error),
+		images:            make(map[string][]ecrtypes.ImageDetail),
+		lifecycleRepos:    make(map[string]bool),
+		lifecyclePolicies: make(map[string]string),
+		scanFindings:      make(map[string]*ecr.DescribeImageScanFindingsOutput),
+		scanErr:           make(map[string]error),
+		tags:              make(map[string]map[string]string),
+		tagsErr:           make(map[string]error),
+		descImagesErr:     make(map[string]error),
+		lifecycleErr:      make(map[string]error),
+		listImagesErr:     make(map[string]error),
+		manifests:         make(map[string]string),
+		batchGetErr:       make(map[string]error),
 	}
 }
 
-func (m *mockECRClient) DescribeRepositories(_ context.Context, _ *ecr.DescribeRepositoriesInput, _ ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error) {
+func (m *mockECRClient) DescribeRepositories(_ context.Context, input *ecr.DescribeRepositoriesInput, _ ...func(*ecr.Options)) (*ecr.DescribeRepositoriesOutput, error) {
 	if m.descRepoErr != nil {
 		return nil, m.descRepoErr
 	}
-	return &ecr.DescribeRepositoriesOutput{Repositories: m.repos}, nil
+	if len(input.RepositoryNames) == 0 {
+		return &ecr.DescribeRepositoriesOutput{Repositories: m.repos}, nil
+	}
+	out := &ecr.DescribeRepositoriesOutput{}
+	for _, name := range input.RepositoryNames {
+		found := false
+		for _, r := range m.repos {
+			if aws.ToString(r.RepositoryName) == name {
+				out.Repositories = append(out.Repositories, r)
+				found = true
+			}
+		}
+		if !found {
+			return nil, &ecrtypes.RepositoryNotFoundException{Message: aws.String("repository " + name + " not found")}
+		}
+	}
+	return out, nil
 }
 
 func (m *mockECRClient) DescribeImages(_ context.Context, input *ecr.DescribeImagesInput, _ ...func(*ecr.Options)) (*ecr.DescribeImagesOutput, error) {
 	repo := aws.ToString(input.RepositoryName)
+	if m.onDescribe != nil {
+		m.onDescribe(repo)
+	}
 	if err, ok := m.descImagesErr[repo]; ok {
 		return nil, err
 	}
@@ -46,10 +83,14 @@ func (m *mockECRClient) DescribeImages(_ context.Context, input *ecr.DescribeIma
 }
 
 func (m *mockECRClient) GetLifecyclePolicy(_ context.Context, input *ecr.GetLifecyclePolicyInput, _ ...func(*ecr.Options)) (*ecr.GetLifecyclePolicyOutput, error) {
+	m.lifecycleCalls.Add(1)
 	repo := aws.ToString(input.RepositoryName)
 	if err, ok := m.lifecycleErr[repo]; ok {
 		return nil, err
 	}
+	if text, ok := m.lifecyclePolicies[repo]; ok {
+		return &ecr.GetLifecyclePolicyOutput{LifecyclePolicyText: aws.String(text)}, nil
+	}
 	if m.lifecycleRepos[repo] {
 		return &ecr.GetLifecyclePolicyOutput{
 			LifecyclePolicyText: aws.String(`{"rules":[]}`),
@@ -62,12 +103,77 @@ func (m *mockECRClient) GetLifecyclePolicy(_ context.Context, input *ecr.GetLife
 
 func (m *mockECRClient) DescribeImageScanFindings(_ context.Context, input *ecr.DescribeImageScanFindingsInput, _ ...func(*ecr.Options)) (*ecr.DescribeImageScanFindingsOutput, error) {
 	key := aws.ToString(input.RepositoryName) + "@" + aws.ToString(input.ImageId.ImageDigest)
+	if err, ok := m.scanErr[key]; ok {
+		return nil, err
+	}
 	if out, ok := m.scanFindings[key]; ok {
 		return out, nil
 	}
 	return &ecr.DescribeImageScanFindingsOutput{}, nil
 }
 
+func (m *mockECRClient) ListTagsForResource(_ context.Context, input *ecr.ListTagsForResourceInput, _ ...func(*ecr.Options)) (*ecr.ListTagsForResourceOutput, error) {
+	arn := aws.ToString(input.ResourceArn)
+	if err, ok := m.tagsErr[arn]; ok {
+		return nil, err
+	}
+	out := &ecr.ListTagsForResourceOutput{}
+	for k, v := range m.tags[arn] {
+		out.Tags = append(out.Tags, ecrtypes.Tag{Key: aws.String(k), Value: aws.String(v)})
+	}
+	return out, nil
+}
+
+func (m *mockECRClient) ListImages(_ context.Context, input *ecr.ListImagesInput, _ ...func(*ecr.Options)) (*ecr.ListImagesOutput, error) {
+	repo := aws.ToString(input.RepositoryName)
+	if err, ok := m.listImagesErr[repo]; ok {
+		return nil, err
+	}
+	out := &ecr.ListImagesOutput{}
+	for _, img := range m.images[repo] {
+		if len(img.ImageTags) == 0 {
+			out.ImageIds = append(out.ImageIds, ecrtypes.ImageIdentifier{ImageDigest: img.ImageDigest})
+			continue
+		}
+		for _, tag := range img.ImageTags {
+			out.ImageIds = append(out.ImageIds, ecrtypes.ImageIdentifier{ImageDigest: img.ImageDigest, ImageTag: aws.String(tag)})
+		}
+	}
+	return out, nil
+}
+
+func (m *mockECRClient) BatchGetImage(_ context.Context, input *ecr.BatchGetImageInput, _ ...func(*ecr.Options)) (*ecr.BatchGetImageOutput, error) {
+	repo := aws.ToString(input.RepositoryName)
+	if err, ok := m.batchGetErr[repo]; ok {
+		return nil, err
+	}
+	out := &ecr.BatchGetImageOutput{}
+	for _, id := range input.ImageIds {
+		manifest, ok := m.manifests[repo+"@"+aws.ToString(id.ImageDigest)]
+		if !ok {
+			out.Failures = append(out.Failures, ecrtypes.ImageFailure{ImageId: &id, FailureCode: ecrtypes.ImageFailureCodeImageNotFound})
+			continue
+		}
+		out.Images = append(out.Images, ecrtypes.Image{ImageId: &id, ImageManifest: aws.String(manifest)})
+	}
+	return out, nil
+}
+
+// mockPullCounter implements PullCounter for testing.
+type mockPullCounter struct {
+	pulls map[string]int64
+	err   error
+	calls int
+}
+
+func (m *mockPullCounter) MonthlyPulls(_ context.Context, repoName string) (int64, error) {
+	m.calls++
+	if m.err != nil {
+		return 0, m.err
+	}
+	return m.pulls[repoName], nil
+}
+
 // Test helper to create an image detail.
 func makeImage(digest string, tags []string, sizeBytes int64, pushedAt, lastPull time.Time) ecrtypes.ImageDetail {
 	img := ecrtypes.ImageDetail{
@@ -85,5 +191,25 @@ func makeImage(digest string, tags []string, sizeBytes int64, pushedAt, lastPull
 func makeRepo(name string) ecrtypes.Repository {
 	return ecrtypes.Repository{
 		RepositoryName: aws.String(name),
+		RepositoryArn:  aws.String(repoARN(name)),
+	}
+}
+
+func repoARN(name string) string {
+	return "arn:aws:ecr:us-east-1:123456789012:repository/" + name
+}
+
+// mockConfigFetcher implements ConfigFetcher for testing.
+type mockConfigFetcher struct {
+	configs map[string]string // keyed by repository@config digest
+	calls   int
+}
+
+func (m *mockConfigFetcher) ImageConfig(_ context.Context, repoName, configDigest string) ([]byte, error) {
+	m.calls++
+	config, ok := m.configs[repoName+"@"+configDigest]
+	if !ok {
+		return nil, fmt.Errorf("config %s not found", configDigest)
 	}
+	return []byte(config), nil
 }
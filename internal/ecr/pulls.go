@@ -0,0 +1,114 @@
+package ecr
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+
+	"github.com/ppiankov/ecrspectre/internal/awsapi"
+)
+
+// pullWindowDays is the period MonthlyPulls counts pulls over.
+const pullWindowDays = 30
+
+// PullCounter reports how often a repository was pulled over the last 30 days.
+type PullCounter interface {
+	MonthlyPulls(ctx context.Context, repoName string) (int64, error)
+}
+
+// CloudWatchPullCounter reads the AWS/ECR RepositoryPullCount metric.
+type CloudWatchPullCounter struct {
+	caller *awsapi.Caller
+	now    func() time.Time
+}
+
+// NewCloudWatchPullCounter creates a pull counter backed by CloudWatch metrics.
+func NewCloudWatchPullCounter(caller *awsapi.Caller) *CloudWatchPullCounter {
+	return &CloudWatchPullCounter{caller: caller, now: time.Now}
+}
+
+// NewPullCounter creates a CloudWatch pull counter from the stored config.
+func (c *Client) NewPullCounter() *CloudWatchPullCounter {
+	return NewCloudWatchPullCounter(awsapi.NewCaller(c.cfg))
+}
+
+type getMetricStatisticsResponse struct {
+	Datapoints []struct {
+		Sum float64 `xml:"Sum"`
+	} `xml:"GetMetricStatisticsResult>Datapoints>member"`
+}
+
+// MonthlyPulls sums daily RepositoryPullCount datapoints over the last 30 days.
+func (p *CloudWatchPullCounter) MonthlyPulls(ctx context.Context, repoName string) (int64, error) {
+	end := p.now().UTC().Truncate(24 * time.Hour)
+	start := end.AddDate(0, 0, -pullWindowDays)
+
+	params := url.Values{}
+	params.Set("Action", "GetMetricStatistics")
+	params.Set("Version", "2010-08-01")
+	params.Set("Namespace", "AWS/ECR")
+	params.Set("MetricName", "RepositoryPullCount")
+	params.Set("Dimensions.member.1.Name", "RepositoryName")
+	params.Set("Dimensions.member.1.Value", repoName)
+	params.Set("StartTime", start.Format(time.RFC3339))
+	params.Set("EndTime", end.Format(time.RFC3339))
+	params.Set("Period", "86400")
+	params.Set("Statistics.member.1", "Sum")
+
+	var out getMetricStatisticsResponse
+	if err := p.caller.Query(ctx, "monitoring", params, &out); err != nil {
+		return 0, fmt.Errorf("get pull count for %s: %w", repoName, err)
+	}
+
+	var total float64
+	for _, dp := range out.Datapoints {
+		total += dp.Sum
+	}
+	return int64(total), nil
+}
+
+// apportionPulls splits a repository's pull count across its images, by
+// digest. RepositoryPullCount only counts the repository, so charging it in
+// full to every image would multiply the egress by the number of images.
+// The pulls go evenly to the images last pulled within the window, the
+// remainder to the most recently pulled; when no image records a pull in the
+// window (ECR records pull times with a lag of up to a day) they go evenly to
+// all images.
+func apportionPulls(total int64, images []ecrtypes.ImageDetail, now time.Time) map[string]int64 {
+	if total <= 0 || len(images) == 0 {
+		return nil
+	}
+	since := now.AddDate(0, 0, -pullWindowDays)
+	var pulled []ecrtypes.ImageDetail
+	for _, img := range images {
+		if img.LastRecordedPullTime != nil && img.LastRecordedPullTime.After(since) {
+			pulled = append(pulled, img)
+		}
+	}
+	if len(pulled) == 0 {
+		pulled = images
+	}
+	pulled = append([]ecrtypes.ImageDetail(nil), pulled...)
+	last := func(img ecrtypes.ImageDetail) time.Time {
+		if t := lastActivityTime(img); t != nil {
+			return *t
+		}
+		return time.Time{}
+	}
+	sort.SliceStable(pulled, func(i, j int) bool { return last(pulled[i]).After(last(pulled[j])) })
+
+	share, rest := total/int64(len(pulled)), total%int64(len(pulled))
+	shares := make(map[string]int64, len(pulled))
+	for i, img := range pulled {
+		n := share
+		if int64(i) < rest {
+			n++
+		}
+		shares[deref(img.ImageDigest)] += n
+	}
+	return shares
+}
@@ -0,0 +1,76 @@
+package ecr
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+
+	"github.com/ppiankov/ecrspectre/internal/awsapi"
+)
+
+func TestCloudWatchPullCounter(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Form.Get("MetricName"); got != "RepositoryPullCount" {
+			t.Errorf("MetricName = %q", got)
+		}
+		if got := r.Form.Get("Dimensions.member.1.Value"); got != "myapp" {
+			t.Errorf("RepositoryName = %q", got)
+		}
+		_, _ = w.Write([]byte(`<GetMetricStatisticsResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/">
+  <GetMetricStatisticsResult>
+    <Datapoints>
+      <member><Sum>120.0</Sum><Unit>Count</Unit></member>
+      <member><Sum>30.0</Sum><Unit>Count</Unit></member>
+    </Datapoints>
+    <Label>RepositoryPullCount</Label>
+  </GetMetricStatisticsResult>
+</GetMetricStatisticsResponse>`))
+	}))
+	defer srv.Close()
+
+	cfg := aws.Config{
+		Region: "us-east-1",
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		}),
+	}
+	pc := NewCloudWatchPullCounter(awsapi.NewCaller(cfg).WithEndpoint(srv.URL))
+	pc.now = func() time.Time { return now }
+
+	pulls, err := pc.MonthlyPulls(context.Background(), "myapp")
+	if err != nil {
+		t.Fatalf("MonthlyPulls() error: %v", err)
+	}
+	if pulls != 150 {
+		t.Errorf("pulls = %d, want 150", pulls)
+	}
+}
+
+func TestApportionPulls(t *testing.T) {
+	images := []ecrtypes.ImageDetail{
+		makeImage("sha256:a", []string{"v1"}, oneGB, stale200, now.AddDate(0, 0, -1)),
+		makeImage("sha256:b", []string{"v2"}, oneGB, stale200, now.AddDate(0, 0, -2)),
+		makeImage("sha256:old", []string{"v0"}, oneGB, stale200, stale120),
+	}
+	shares := apportionPulls(101, images, now)
+	if shares["sha256:a"] != 51 || shares["sha256:b"] != 50 || shares["sha256:old"] != 0 {
+		t.Errorf("shares = %v, want 51/50/0", shares)
+	}
+
+	// No pull recorded in the window: split across all images.
+	shares = apportionPulls(10, images[2:], now)
+	if shares["sha256:old"] != 10 {
+		t.Errorf("shares = %v, want all 10 pulls on the only image", shares)
+	}
+	if apportionPulls(0, images, now) != nil {
+		t.Error("expected no shares without pulls")
+	}
+}
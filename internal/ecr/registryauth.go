@@ -0,0 +1,102 @@
+package ecr
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/ppiankov/ecrspectre/internal/dockerauth"
+)
+
+// RegistryAPI defines the ECR calls used to copy images out of a registry
+// and delete them, which the read-only scanner never makes.
+type RegistryAPI interface {
+	GetAuthorizationToken(ctx context.Context, input *ecr.GetAuthorizationTokenInput, opts ...func(*ecr.Options)) (*ecr.GetAuthorizationTokenOutput, error)
+	BatchDeleteImage(ctx context.Context, input *ecr.BatchDeleteImageInput, opts ...func(*ecr.Options)) (*ecr.BatchDeleteImageOutput, error)
+}
+
+// NewRegistryClient creates an ECR client for the registry calls of RegistryAPI.
+func (c *Client) NewRegistryClient() RegistryAPI {
+	return c.NewECRClient().(RegistryAPI)
+}
+
+// RegistryCredential returns the registry host of the account and a
+// credential for its Docker Registry API, from an authorization token.
+func RegistryCredential(ctx context.Context, client RegistryAPI) (string, dockerauth.Credential, error) {
+	out, err := client.GetAuthorizationToken(ctx, &ecr.GetAuthorizationTokenInput{})
+	if err != nil {
+		return "", dockerauth.Credential{}, fmt.Errorf("get authorization token: %w", err)
+	}
+	if len(out.AuthorizationData) == 0 {
+		return "", dockerauth.Credential{}, fmt.Errorf("get authorization token: no authorization data")
+	}
+	data := out.AuthorizationData[0]
+	decoded, err := base64.StdEncoding.DecodeString(aws.ToString(data.AuthorizationToken))
+	if err != nil {
+		return "", dockerauth.Credential{}, fmt.Errorf("decode authorization token: %w", err)
+	}
+	user, secret, ok := strings.Cut(string(decoded), ":")
+	if !ok {
+		return "", dockerauth.Credential{}, fmt.Errorf("decode authorization token: missing separator")
+	}
+	host := aws.ToString(data.ProxyEndpoint)
+	if u, err := url.Parse(host); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	return host, dockerauth.Credential{Username: user, Secret: secret}, nil
+}
+
+// DeleteImage deletes an image, with all its tags, by digest.
+func DeleteImage(ctx context.Context, client RegistryAPI, repoName, digest string) error {
+	out, err := client.BatchDeleteImage(ctx, &ecr.BatchDeleteImageInput{
+		RepositoryName: aws.String(repoName),
+		ImageIds:       []ecrtypes.ImageIdentifier{{ImageDigest: aws.String(digest)}},
+	})
+	if err != nil {
+		return fmt.Errorf("delete image %s@%s: %w", repoName, digest, err)
+	}
+	if len(out.Failures) > 0 {
+		f := out.Failures[0]
+		return fmt.Errorf("delete image %s@%s: %s: %s", repoName, digest, f.FailureCode, aws.ToString(f.FailureReason))
+	}
+	return nil
+}
+
+// batchDeleteImageLimit is the maximum number of image IDs per
+// BatchDeleteImage call.
+const batchDeleteImageLimit = 100
+
+// DeleteImages deletes images, with all their tags, by digest, in batches.
+// It returns the reason each image that could not be deleted failed, keyed
+// by digest. An error stops the deletion; the images of earlier batches are
+// gone by then.
+func DeleteImages(ctx context.Context, client RegistryAPI, repoName string, digests []string) (map[string]string, error) {
+	failed := make(map[string]string)
+	for start := 0; start < len(digests); start += batchDeleteImageLimit {
+		end := min(start+batchDeleteImageLimit, len(digests))
+		ids := make([]ecrtypes.ImageIdentifier, 0, end-start)
+		for _, digest := range digests[start:end] {
+			ids = append(ids, ecrtypes.ImageIdentifier{ImageDigest: aws.String(digest)})
+		}
+		out, err := client.BatchDeleteImage(ctx, &ecr.BatchDeleteImageInput{
+			RepositoryName: aws.String(repoName),
+			ImageIds:       ids,
+		})
+		if err != nil {
+			return failed, fmt.Errorf("delete images in %s: %w", repoName, err)
+		}
+		for _, f := range out.Failures {
+			digest := ""
+			if f.ImageId != nil {
+				digest = aws.ToString(f.ImageId.ImageDigest)
+			}
+			failed[digest] = fmt.Sprintf("%s: %s", f.FailureCode, aws.ToString(f.FailureReason))
+		}
+	}
+	return failed, nil
+}
@@ -0,0 +1,90 @@
+package ecr
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecr"
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+type mockRegistryAPI struct {
+	token   string
+	deleted []string
+	failure bool
+	fail    map[string]bool // digests whose deletion fails
+	calls   int
+}
+
+func (m *mockRegistryAPI) GetAuthorizationToken(_ context.Context, _ *ecr.GetAuthorizationTokenInput, _ ...func(*ecr.Options)) (*ecr.GetAuthorizationTokenOutput, error) {
+	return &ecr.GetAuthorizationTokenOutput{AuthorizationData: []ecrtypes.AuthorizationData{{
+		AuthorizationToken: aws.String(m.token),
+		ProxyEndpoint:      aws.String("https://123456789012.dkr.ecr.us-east-1.amazonaws.com"),
+	}}}, nil
+}
+
+func (m *mockRegistryAPI) BatchDeleteImage(_ context.Context, input *ecr.BatchDeleteImageInput, _ ...func(*ecr.Options)) (*ecr.BatchDeleteImageOutput, error) {
+	if m.failure {
+		return &ecr.BatchDeleteImageOutput{Failures: []ecrtypes.ImageFailure{{FailureCode: ecrtypes.ImageFailureCodeImageNotFound, FailureReason: aws.String("not found")}}}, nil
+	}
+	m.calls++
+	out := &ecr.BatchDeleteImageOutput{}
+	for _, id := range input.ImageIds {
+		if m.fail[aws.ToString(id.ImageDigest)] {
+			out.Failures = append(out.Failures, ecrtypes.ImageFailure{ImageId: &id, FailureCode: ecrtypes.ImageFailureCodeImageReferencedByManifestList, FailureReason: aws.String("referenced by an index")})
+			continue
+		}
+		m.deleted = append(m.deleted, aws.ToString(input.RepositoryName)+"@"+aws.ToString(id.ImageDigest))
+	}
+	return out, nil
+}
+
+func TestRegistryCredential(t *testing.T) {
+	m := &mockRegistryAPI{token: base64.StdEncoding.EncodeToString([]byte("AWS:secret"))}
+	host, cred, err := RegistryCredential(context.Background(), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if host != "123456789012.dkr.ecr.us-east-1.amazonaws.com" {
+		t.Errorf("host = %q", host)
+	}
+	if cred.Username != "AWS" || cred.Secret != "secret" {
+		t.Errorf("cred = %+v", cred)
+	}
+}
+
+func TestDeleteImage(t *testing.T) {
+	m := &mockRegistryAPI{}
+	if err := DeleteImage(context.Background(), m, "app", "sha256:abc"); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.deleted) != 1 || m.deleted[0] != "app@sha256:abc" {
+		t.Errorf("deleted = %v", m.deleted)
+	}
+	m.failure = true
+	if err := DeleteImage(context.Background(), m, "app", "sha256:abc"); err == nil {
+		t.Error("expected error for a failed deletion")
+	}
+}
+
+func TestDeleteImages(t *testing.T) {
+	m := &mockRegistryAPI{fail: map[string]bool{"sha256:d42": true}}
+	digests := make([]string, 150)
+	for i := range digests {
+		digests[i] = fmt.Sprintf("sha256:d%d", i)
+	}
+	failed, err := DeleteImages(context.Background(), m, "app", digests)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.calls != 2 || len(m.deleted) != 149 {
+		t.Errorf("calls = %d, deleted = %d; want 2 batches deleting 149", m.calls, len(m.deleted))
+	}
+	if len(failed) != 1 || !strings.Contains(failed["sha256:d42"], "ImageReferencedByManifestList") {
+		t.Errorf("failed = %v", failed)
+	}
+}
@@ -2,9 +2,12 @@ package ecr
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	awsecr "github.com/aws/aws-sdk-go-v2/service/ecr"
@@ -12,14 +15,43 @@ import (
 
 	"github.com/ppiankov/ecrspectre/internal/pricing"
 	"github.com/ppiankov/ecrspectre/internal/registry"
+	"github.com/ppiankov/ecrspectre/internal/rules"
 )
 
+// vulnScanConcurrency bounds parallel DescribeImageScanFindings calls per repository.
+const vulnScanConcurrency = 5
+
+// lifecycleConcurrency bounds parallel GetLifecyclePolicy calls per scan.
+const lifecycleConcurrency = 10
+
+// lifecyclePolicy is a repository's lifecycle policy document ("" if none)
+// or the error fetching it.
+type lifecyclePolicy struct {
+	text string
+	err  error
+}
+
 // ECRScanner audits AWS ECR repositories for waste.
 type ECRScanner struct {
 	client      ECRAPI
 	region      string
 	includeScan bool
+	pullCounter PullCounter
+	configs     ConfigFetcher
 	now         time.Time // injectable for testing
+
+	// Incremental mode: previous is the loaded snapshot (may be nil),
+	// current collects repository state for the next run.
+	incremental    bool
+	snapshotMaxAge time.Duration
+	previous       *Snapshot
+	current        *Snapshot
+	reused         int
+
+	// policies caches lifecycle policies fetched during a scan, so each
+	// repository's policy is requested once.
+	policiesMu sync.Mutex
+	policies   map[string]lifecyclePolicy
 }
 
 // NewECRScanner creates a scanner for the given ECR client and region.
@@ -32,41 +64,368 @@ func NewECRScanner(client ECRAPI, region string, includeScan bool) *ECRScanner {
 	}
 }
 
-// Scan implements registry.RegistryScanner.
+// SetNow pins the time the scan measures image ages against, e.g. to when
+// replayed fixtures were recorded. Call it before EnableIncremental.
+func (s *ECRScanner) SetNow(now time.Time) {
+	s.now = now
+}
+
+// SetPullCounter enables egress estimation for large images using repository pull counts.
+func (s *ECRScanner) SetPullCounter(pc PullCounter) {
+	s.pullCounter = pc
+}
+
+// SetConfigFetcher enables AGING_DEPLOYED_IMAGE: in deep mode, the config
+// blobs of deployed images are read for their build date.
+func (s *ECRScanner) SetConfigFetcher(f ConfigFetcher) {
+	s.configs = f
+}
+
+// EnableIncremental re-analyzes repositories whose image list is unchanged
+// since prev from the cached state instead of calling the API. prev may be nil
+// for the first run; a snapshot from another region is ignored.
+func (s *ECRScanner) EnableIncremental(prev *Snapshot) {
+	if prev != nil && prev.Region != s.region {
+		prev = nil
+	}
+	s.incremental = true
+	s.previous = prev
+	s.current = NewSnapshot(s.region, s.now)
+}
+
+// SetSnapshotMaxAge refetches repositories whose cached state is older than
+// maxAge in incremental mode, even when their image list is unchanged: last
+// pull times, lifecycle policies and scan findings change without new
+// digests. Zero reuses cached state of any age.
+func (s *ECRScanner) SetSnapshotMaxAge(maxAge time.Duration) {
+	s.snapshotMaxAge = maxAge
+}
+
+// Snapshot returns the inventory collected during the last incremental scan.
+func (s *ECRScanner) Snapshot() *Snapshot {
+	return s.current
+}
+
+// ReusedRepositories returns how many repositories were served from the snapshot.
+func (s *ECRScanner) ReusedRepositories() int {
+	return s.reused
+}
+
+// Scan implements registry.RegistryScanner. Progress events are delivered by
+// a registry.ProgressDispatcher, all of them before Scan returns.
 func (s *ECRScanner) Scan(ctx context.Context, cfg registry.ScanConfig, progress func(registry.ScanProgress)) *registry.ScanResult {
-	result := &registry.ScanResult{}
+	progress, stopProgress := registry.DispatchProgress(progress)
+	defer stopProgress()
+	result := &registry.ScanResult{Targets: 1}
 
 	repos, err := ListRepositories(ctx, s.client)
 	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("%s: %v", s.region, err))
+		result.AddTargetError(s.region, err)
 		return result
 	}
 
+	repos = registry.FilterRepos(repos, func(r ecrtypes.Repository) string {
+		return deref(r.RepositoryName)
+	}, cfg.Repos)
 	result.RepositoriesScanned = len(repos)
-	s.reportProgress(progress, fmt.Sprintf("Found %d repositories", len(repos)))
+	s.reportProgress(progress, registry.ScanProgress{Stage: registry.StageDiscover, ReposTotal: len(repos), Message: fmt.Sprintf("Found %d repositories", len(repos))})
+
+	weights := registry.SortByPriority(repos, func(r ecrtypes.Repository) string {
+		return deref(r.RepositoryName)
+	}, cfg.RepoPriority)
+
+	// Incremental scans reuse cached policy status for unchanged
+	// repositories, so only a full scan prefetches every policy.
+	if cfg.CheckEnabled(registry.FindingNoLifecyclePolicy) && !s.incremental {
+		var names []string
+		for _, r := range repos {
+			if name := deref(r.RepositoryName); !cfg.Exclude.ResourceIDs[name] {
+				names = append(names, name)
+			}
+		}
+		s.prefetchLifecyclePolicies(ctx, names)
+	}
 
+	completed, reported := 0, 0
+	counted := progress
+	if progress != nil {
+		counted = func(p registry.ScanProgress) {
+			p.ReposDone, p.ReposTotal = completed, len(repos)
+			p.ImagesScanned = result.ResourcesScanned
+			p.Findings = slices.Clone(result.Findings[reported:])
+			reported = len(result.Findings)
+			progress(p)
+		}
+	}
 	for _, repo := range repos {
-		repoName := deref(repo.RepositoryName)
-		if cfg.Exclude.ResourceIDs[repoName] {
-			continue
+		if ctx.Err() != nil {
+			break
 		}
+		start, images := time.Now(), result.ResourcesScanned
+		s.scanOne(ctx, cfg, repo, result, counted)
+		if name := deref(repo.RepositoryName); !cfg.Exclude.ResourceIDs[name] {
+			result.RecordTiming(name, s.region, result.ResourcesScanned-images, time.Since(start))
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		completed++
+	}
+	s.reportProgress(counted, registry.ScanProgress{Stage: registry.StageDone, Message: fmt.Sprintf("Scanned %d of %d repositories", completed, len(repos))})
 
-		s.scanRepository(ctx, cfg, repo, result, progress)
+	result.Coverage = registry.ComputeCoverage(weights, completed, completed < len(repos))
+	if result.Coverage.Truncated {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s: scan deadline reached after %d of %d repositories (%.1f%% coverage)",
+			s.region, completed, len(repos), result.Coverage.Percent))
 	}
 
 	return result
 }
 
-func (s *ECRScanner) scanRepository(ctx context.Context, cfg registry.ScanConfig, repo ecrtypes.Repository, result *registry.ScanResult, progress func(registry.ScanProgress)) {
+// ScanRepository audits a single named repository without enumerating the
+// registry and attaches a per-image breakdown with lifecycle simulation.
+func (s *ECRScanner) ScanRepository(ctx context.Context, cfg registry.ScanConfig, repoName string, progress func(registry.ScanProgress)) *registry.ScanResult {
+	progress, stopProgress := registry.DispatchProgress(progress)
+	defer stopProgress()
+	result := &registry.ScanResult{Targets: 1}
+
+	repo, err := DescribeRepository(ctx, s.client, repoName)
+	if err != nil {
+		result.AddTargetError(s.region, err)
+		return result
+	}
+	result.RepositoriesScanned = 1
+
+	state := s.scanOne(ctx, cfg, repo, result, progress)
+	result.Coverage = registry.ComputeCoverage(make([]float64, 1), 1, ctx.Err() != nil)
+	if state != nil {
+		result.Detail = s.repositoryDetail(ctx, cfg, repoName, state, result)
+	}
+	return result
+}
+
+// repositoryDetail builds the per-image breakdown for a scanned repository.
+func (s *ECRScanner) repositoryDetail(ctx context.Context, cfg registry.ScanConfig, repoName string, state *RepoState, result *registry.ScanResult) *registry.RepositoryDetail {
+	detail := &registry.RepositoryDetail{
+		Name:               repoName,
+		Region:             s.region,
+		ImageCount:         len(state.Images),
+		HasLifecyclePolicy: state.HasLifecyclePolicy,
+	}
+
+	var expired map[string]int
+	if state.HasLifecyclePolicy && len(state.Images) > 0 {
+		text, err := s.lifecyclePolicy(ctx, repoName)
+		if err == nil {
+			detail.HasLifecyclePolicy = text != ""
+		}
+		if err == nil && text != "" {
+			var policy *LifecyclePolicy
+			policy, err = ParseLifecyclePolicy(text)
+			if err == nil {
+				expired = policy.Simulate(state.Images, s.now)
+				detail.LifecycleSimulated = true
+			}
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s lifecycle simulation: %v", s.region, repoName, err))
+		}
+	}
+
+	findings := registry.ImageFindings(result.Findings)
+	for _, img := range state.Images {
+		digest := deref(img.ImageDigest)
+		size := derefInt64(img.ImageSizeInBytes)
+		cost := pricing.MonthlyStorageCost("ecr", s.region, size)
+		d := registry.ImageDetail{
+			Digest:        digest,
+			Tags:          img.ImageTags,
+			CanonicalTag:  registry.CanonicalTag(img.ImageTags, cfg.TagPriority),
+			SizeBytes:     size,
+			PushedAt:      img.ImagePushedAt,
+			LastPulledAt:  img.LastRecordedPullTime,
+			MonthlyCost:   cost,
+			Findings:      findings[fmt.Sprintf("%s@%s", repoName, digest)],
+			ExpiredByRule: expired[digest],
+			Layers:        state.Layers[digest],
+		}
+		detail.TotalSizeBytes += size
+		detail.MonthlyCost += cost
+		if d.ExpiredByRule > 0 {
+			detail.ExpiringImages++
+			detail.ExpiringMonthlySavings += cost
+		}
+		detail.Images = append(detail.Images, d)
+	}
+	return detail
+}
+
+// scanOne applies exclusions to a repository, scans it, and stamps its findings
+// with the repository name and owner attribution. In incremental mode an
+// unchanged repository is re-analyzed from the cached snapshot. Returns the
+// repository state, or nil if it was skipped or could not be listed.
+func (s *ECRScanner) scanOne(ctx context.Context, cfg registry.ScanConfig, repo ecrtypes.Repository, result *registry.ScanResult, progress func(registry.ScanProgress)) *RepoState {
 	repoName := deref(repo.RepositoryName)
-	s.reportProgress(progress, fmt.Sprintf("Scanning %s", repoName))
+	if cfg.Exclude.ResourceIDs[repoName] {
+		return nil
+	}
 
+	var state *RepoState
+	var imageIDs []string
+	if s.incremental {
+		var err error
+		imageIDs, err = ListImageIDs(ctx, s.client, repoName)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s: %v", s.region, repoName, err))
+			return nil
+		}
+		var notBefore time.Time
+		if s.snapshotMaxAge > 0 {
+			notBefore = s.now.Add(-s.snapshotMaxAge)
+		}
+		state = s.previous.unchanged(repoName, imageIDs, s.includeScan, notBefore)
+		if state != nil && state.LifecycleSkipped && cfg.CheckEnabled(registry.FindingNoLifecyclePolicy) {
+			state = nil
+		}
+		if state != nil {
+			s.reused++
+			s.reportProgress(progress, registry.ScanProgress{Stage: registry.StageReuse, Repository: repoName, Message: fmt.Sprintf("Reusing cached inventory for %s (unchanged)", repoName)})
+		}
+	}
+
+	if state == nil {
+		tags, ok := s.repositoryTags(ctx, repo, result)
+		if cfg.Exclude.MatchesTags(tags) {
+			slog.Debug("Skipping repository excluded by tag", "repo", repoName)
+			return nil
+		}
+		s.reportProgress(progress, registry.ScanProgress{Stage: registry.StageScan, Repository: repoName, Message: fmt.Sprintf("Scanning %s", repoName)})
+		state, ok = s.fetchState(ctx, cfg, repoName, tags, ok, result)
+		if state == nil {
+			return nil
+		}
+		if s.incremental && ok {
+			state.ImageIDs = imageIDs
+			s.current.Repositories[repoName] = state
+		}
+	} else {
+		if cfg.Exclude.MatchesTags(state.Tags) {
+			slog.Debug("Skipping repository excluded by tag", "repo", repoName)
+			return nil
+		}
+		s.current.Repositories[repoName] = state
+	}
+
+	var sizeBytes int64
+	for _, img := range state.Images {
+		sizeBytes += derefInt64(img.ImageSizeInBytes)
+	}
+	result.RecordUsage(repoName, s.region, sizeBytes)
+
+	start := len(result.Findings)
+	s.analyzeRepository(ctx, cfg, repoName, state, result)
+	for i := range result.Findings[start:] {
+		result.Findings[start+i].Repository = repoName
+	}
+	registry.Annotate(result.Findings[start:], registry.Attribution(state.Tags, cfg.AttributionKeys...))
+	return state
+}
+
+// repositoryTags fetches repository resource tags. Failures are recorded and
+// treated as an untagged repository; ok is false in that case.
+func (s *ECRScanner) repositoryTags(ctx context.Context, repo ecrtypes.Repository, result *registry.ScanResult) (map[string]string, bool) {
+	arn := deref(repo.RepositoryArn)
+	if arn == "" {
+		return nil, true
+	}
+	tags, err := ListRepositoryTags(ctx, s.client, arn)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s/%s tags: %v", s.region, deref(repo.RepositoryName), err))
+		return nil, false
+	}
+	return tags, true
+}
+
+// fetchState loads images, lifecycle policy status, and vulnerability findings
+// for a repository. Returns nil if images cannot be listed. complete reports
+// whether every lookup succeeded, which makes the state safe to cache.
+func (s *ECRScanner) fetchState(ctx context.Context, cfg registry.ScanConfig, repoName string, tags map[string]string, tagsOK bool, result *registry.ScanResult) (state *RepoState, complete bool) {
 	images, err := ListImages(ctx, s.client, repoName)
 	if err != nil {
 		result.Errors = append(result.Errors, fmt.Sprintf("%s/%s: %v", s.region, repoName, err))
-		return
+		return nil, false
 	}
 
+	state = &RepoState{FetchedAt: s.now, Tags: tags, Images: images, HasLifecyclePolicy: true}
+	complete = tagsOK
+	if len(images) == 0 {
+		return state, complete
+	}
+
+	if !cfg.CheckEnabled(registry.FindingNoLifecyclePolicy) {
+		state.LifecycleSkipped = true
+	} else if text, err := s.lifecyclePolicy(ctx, repoName); err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s/%s lifecycle: %v", s.region, repoName, err))
+		complete = false
+	} else {
+		state.HasLifecyclePolicy = text != ""
+	}
+
+	if s.includeScan {
+		vulns, errs := s.scanRepositoryVulnerabilities(ctx, repoName, images)
+		state.Vulnerabilities = vulns
+		state.VulnerabilitiesChecked = len(errs) == 0
+		for _, e := range errs {
+			result.Errors = append(result.Errors, e)
+			complete = false
+		}
+	}
+
+	return state, complete
+}
+
+// prefetchLifecyclePolicies fetches the lifecycle policies of the named
+// repositories concurrently into the scan's policy cache.
+func (s *ECRScanner) prefetchLifecyclePolicies(ctx context.Context, names []string) {
+	sem := make(chan struct{}, lifecycleConcurrency)
+	var wg sync.WaitGroup
+	for _, name := range names {
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			_, _ = s.lifecyclePolicy(ctx, name)
+		}(name)
+	}
+	wg.Wait()
+}
+
+// lifecyclePolicy returns a repository's lifecycle policy document from the
+// scan's cache, fetching it on first use.
+func (s *ECRScanner) lifecyclePolicy(ctx context.Context, repoName string) (string, error) {
+	s.policiesMu.Lock()
+	p, ok := s.policies[repoName]
+	s.policiesMu.Unlock()
+	if ok {
+		return p.text, p.err
+	}
+	text, err := LifecyclePolicyText(ctx, s.client, repoName)
+	s.policiesMu.Lock()
+	if s.policies == nil {
+		s.policies = make(map[string]lifecyclePolicy)
+	}
+	s.policies[repoName] = lifecyclePolicy{text: text, err: err}
+	s.policiesMu.Unlock()
+	return text, err
+}
+
+// analyzeRepository emits findings for a repository from its fetched or cached state.
+func (s *ECRScanner) analyzeRepository(ctx context.Context, cfg registry.ScanConfig, repoName string, state *RepoState, result *registry.ScanResult) {
+	images := state.Images
 	if len(images) == 0 {
 		result.Findings = append(result.Findings, registry.Finding{
 			ID:                    registry.FindingUnusedRepo,
@@ -80,11 +439,7 @@ func (s *ECRScanner) scanRepository(ctx context.Context, cfg registry.ScanConfig
 		return
 	}
 
-	// Check lifecycle policy
-	hasPolicy, err := HasLifecyclePolicy(ctx, s.client, repoName)
-	if err != nil {
-		result.Errors = append(result.Errors, fmt.Sprintf("%s/%s lifecycle: %v", s.region, repoName, err))
-	} else if !hasPolicy {
+	if !state.HasLifecyclePolicy {
 		result.Findings = append(result.Findings, registry.Finding{
 			ID:           registry.FindingNoLifecyclePolicy,
 			Severity:     registry.SeverityMedium,
@@ -95,10 +450,129 @@ func (s *ECRScanner) scanRepository(ctx context.Context, cfg registry.ScanConfig
 		})
 	}
 
+	var newest time.Time
+	for _, img := range images {
+		if pushed := pushedAt(img); pushed.After(newest) {
+			newest = pushed
+		}
+	}
+	if f := registry.StaleReleaseTrain(cfg, repoName, s.region, newest, s.now); f != nil {
+		result.Findings = append(result.Findings, *f)
+	}
+
+	tagged := make([]registry.TaggedImage, 0, len(images))
+	for _, img := range images {
+		if len(img.ImageTags) == 0 {
+			continue
+		}
+		t := registry.TaggedImage{Tags: img.ImageTags, PushedAt: pushedAt(img)}
+		if img.LastRecordedPullTime != nil {
+			t.LastPull = *img.LastRecordedPullTime
+		}
+		tagged = append(tagged, t)
+	}
+	result.Recommendations = append(result.Recommendations, registry.RecommendRetention(repoName, s.region, tagged, cfg.StaleDays, s.now)...)
+
+	// Build caches (kaniko, buildkit) can hold 100k+ untagged digests: above
+	// the threshold those images become one finding and skip the per-image
+	// checks and API calls.
+	images, cached := splitUntaggedAccumulation(cfg, repoName, images)
+	cachedStale := 0
+	if len(cached) > 0 {
+		var f registry.Finding
+		f, cachedStale = s.untaggedAccumulationFinding(cfg, repoName, cached)
+		result.Findings = append(result.Findings, f)
+		result.ResourcesScanned += len(cached)
+	}
+
+	repoPulls := s.monthlyPulls(ctx, cfg, repoName, images, result)
+	pulls := apportionPulls(repoPulls, images, s.now)
+	if cfg.DeepLayers && state.Layers == nil {
+		state.Layers = s.imageLayers(ctx, repoName, images, result)
+	}
+
+	if state.Indexes == nil {
+		state.Indexes = s.indexManifests(ctx, repoName, images, result)
+	}
+	if cfg.DeepLayers && cfg.MaxDeployedAge > 0 && s.configs != nil {
+		s.buildDates(ctx, cfg, repoName, images, state, result)
+	}
+	byDigest := make(map[string]ecrtypes.ImageDetail, len(state.Images))
+	for _, img := range state.Images {
+		byDigest[deref(img.ImageDigest)] = img
+	}
+	// Platform manifests of an index cannot be deleted while it exists.
+	inIndex := make(map[string]string)
+	for digest, index := range state.Indexes {
+		if index == nil {
+			continue
+		}
+		for _, child := range index.Manifests {
+			inIndex[child.Digest] = digest
+		}
+	}
+	var orphaned map[string]bool
+	if cfg.DeepLayers {
+		refs := make([]registry.ManifestRef, 0, len(images))
+		for _, img := range images {
+			digest := deref(img.ImageDigest)
+			refs = append(refs, registry.ManifestRef{Digest: digest, Tagged: len(img.ImageTags) > 0, IsIndex: isIndex(img), Index: state.Indexes[digest]})
+		}
+		orphaned = registry.OrphanedManifests(refs)
+	}
+
+	var signed, withSBOM map[string]bool
+	if cfg.RequireSignatures || cfg.RequireSBOM {
+		if state.Referrers == nil {
+			state.Referrers = s.referrers(ctx, repoName, images, result)
+		}
+		var tags []string
+		for _, img := range images {
+			tags = append(tags, img.ImageTags...)
+		}
+		referrers := make([]registry.Referrer, 0, len(state.Referrers))
+		for _, r := range state.Referrers {
+			referrers = append(referrers, r)
+		}
+		signed = registry.SignedDigests(tags, referrers)
+		withSBOM = registry.SBOMDigests(tags, referrers)
+	}
+
+	var retained map[string]bool
+	if cfg.KeepLatest > 0 {
+		candidates := make([]registry.Retained, 0, len(images))
+		for _, img := range images {
+			candidates = append(candidates, registry.Retained{ID: deref(img.ImageDigest), Tags: img.ImageTags, Created: pushedAt(img)})
+		}
+		retained = registry.KeepLatest(candidates, cfg.KeepLatest)
+	}
+
+	var expiring map[string]int
+	if cfg.SelfResolvingDays > 0 && state.HasLifecyclePolicy {
+		expiring = s.expiringImages(ctx, cfg, repoName, images, result)
+	}
+
 	staleCount := 0
 	for _, img := range images {
 		result.ResourcesScanned++
-		findings := s.analyzeImage(ctx, cfg, repoName, img)
+		digest := deref(img.ImageDigest)
+		findings := s.analyzeImage(ctx, cfg, repoName, img, imageInputs{
+			repoPulls:    repoPulls,
+			monthlyPulls: pulls[digest],
+			layers:       state.Layers[digest],
+			index:        state.Indexes[digest],
+			images:       byDigest,
+			retained:     retained[digest],
+			orphaned:     orphaned[digest],
+			inIndex:      inIndex[digest],
+			referrer:     isReferrer(img, state.Referrers),
+			signed:       signed[digest],
+			sbom:         withSBOM[digest],
+			built:        state.BuildDates[digest],
+		})
+		if rule, ok := expiring[digest]; ok && cfg.InUse.Lookup(repoName, digest, img.ImageTags) == nil {
+			registry.MarkSelfResolving(findings, rule)
+		}
 		result.Findings = append(result.Findings, findings...)
 
 		for _, f := range findings {
@@ -108,8 +582,13 @@ func (s *ECRScanner) scanRepository(ctx context.Context, cfg registry.ScanConfig
 		}
 	}
 
+	if s.includeScan {
+		result.Findings = append(result.Findings, state.Vulnerabilities...)
+	}
+
 	// All images stale = unused repo
-	if staleCount == len(images) && len(images) > 0 {
+	if staleCount == len(images) && cachedStale == len(cached) {
+		images = state.Images
 		totalWaste := 0.0
 		for _, img := range images {
 			totalWaste += pricing.MonthlyStorageCost("ecr", s.region, derefInt64(img.ImageSizeInBytes))
@@ -129,8 +608,297 @@ func (s *ECRScanner) scanRepository(ctx context.Context, cfg registry.ScanConfig
 	}
 }
 
-func (s *ECRScanner) analyzeImage(_ context.Context, cfg registry.ScanConfig, repoName string, img ecrtypes.ImageDetail) []registry.Finding {
+// expiringImages simulates the repository's lifecycle policy
+// cfg.SelfResolvingDays ahead and returns, per digest, the priority of the
+// rule that will have expired the image by then.
+func (s *ECRScanner) expiringImages(ctx context.Context, cfg registry.ScanConfig, repoName string, images []ecrtypes.ImageDetail, result *registry.ScanResult) map[string]int {
+	text, err := s.lifecyclePolicy(ctx, repoName)
+	if err == nil && text != "" {
+		var policy *LifecyclePolicy
+		if policy, err = ParseLifecyclePolicy(text); err == nil {
+			return policy.Simulate(images, s.now.AddDate(0, 0, cfg.SelfResolvingDays))
+		}
+	}
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s/%s lifecycle simulation: %v", s.region, repoName, err))
+	}
+	return nil
+}
+
+// splitUntaggedAccumulation separates the untagged, undeployed images of a
+// repository holding more of them than cfg.UntaggedAccumulation. cached is
+// nil below the threshold or when UNTAGGED_ACCUMULATION is disabled.
+func splitUntaggedAccumulation(cfg registry.ScanConfig, repoName string, images []ecrtypes.ImageDetail) (kept, cached []ecrtypes.ImageDetail) {
+	if cfg.UntaggedAccumulation <= 0 || !cfg.CheckEnabled(registry.FindingUntaggedAccumulation) || len(images) <= cfg.UntaggedAccumulation {
+		return images, nil
+	}
+	isCached := func(img ecrtypes.ImageDetail) bool {
+		return len(img.ImageTags) == 0 && cfg.InUse.Lookup(repoName, deref(img.ImageDigest), nil) == nil
+	}
+	n := 0
+	for _, img := range images {
+		if isCached(img) {
+			n++
+		}
+	}
+	if n <= cfg.UntaggedAccumulation {
+		return images, nil
+	}
+	kept = make([]ecrtypes.ImageDetail, 0, len(images)-n)
+	cached = make([]ecrtypes.ImageDetail, 0, n)
+	for _, img := range images {
+		if isCached(img) {
+			cached = append(cached, img)
+		} else {
+			kept = append(kept, img)
+		}
+	}
+	return kept, cached
+}
+
+// untaggedAccumulationFinding summarizes cached untagged images as one
+// UNTAGGED_ACCUMULATION finding. It also returns how many of them are
+// stale, so UNUSED_REPO still sees the whole repository.
+func (s *ECRScanner) untaggedAccumulationFinding(cfg registry.ScanConfig, repoName string, cached []ecrtypes.ImageDetail) (registry.Finding, int) {
+	var sizeBytes int64
+	var waste float64
+	var oldest, newest time.Time
+	stale := 0
+	staleThreshold := s.now.AddDate(0, 0, -cfg.StaleDays)
+	for _, img := range cached {
+		size := derefInt64(img.ImageSizeInBytes)
+		sizeBytes += size
+		waste += pricing.MonthlyStorageCost("ecr", s.region, size)
+		if pushed := pushedAt(img); !pushed.IsZero() {
+			if oldest.IsZero() || pushed.Before(oldest) {
+				oldest = pushed
+			}
+			if pushed.After(newest) {
+				newest = pushed
+			}
+		}
+		if last := lastActivityTime(img); cfg.StaleDays > 0 && last != nil && last.Before(staleThreshold) {
+			stale++
+		}
+	}
+
+	metadata := map[string]any{
+		"untagged_count": len(cached),
+		"stale_count":    stale,
+		"size_bytes":     sizeBytes,
+		"threshold":      cfg.UntaggedAccumulation,
+	}
+	if !oldest.IsZero() {
+		metadata["oldest_push"] = oldest.Format(time.RFC3339)
+		metadata["newest_push"] = newest.Format(time.RFC3339)
+	}
+	return registry.Finding{
+		ID:                    registry.FindingUntaggedAccumulation,
+		Severity:              registry.SeverityHigh,
+		ResourceType:          registry.ResourceRepository,
+		ResourceID:            repoName,
+		Region:                s.region,
+		Message:               fmt.Sprintf("%d untagged images (%.1f GB), likely a build cache; expire untagged images with a lifecycle rule", len(cached), float64(sizeBytes)/(1024*1024*1024)),
+		EstimatedMonthlyWaste: waste,
+		Metadata:              metadata,
+	}, stale
+}
+
+// monthlyPulls fetches the repository pull count when egress estimation is
+// enabled and at least one image exceeds the size threshold.
+func (s *ECRScanner) monthlyPulls(ctx context.Context, cfg registry.ScanConfig, repoName string, images []ecrtypes.ImageDetail, result *registry.ScanResult) int64 {
+	if cfg.EgressModel == "" || s.pullCounter == nil || cfg.MaxSizeBytes <= 0 {
+		return 0
+	}
+	hasLarge := false
+	for _, img := range images {
+		if derefInt64(img.ImageSizeInBytes) > cfg.MaxSizeBytes {
+			hasLarge = true
+			break
+		}
+	}
+	if !hasLarge {
+		return 0
+	}
+
+	pulls, err := s.pullCounter.MonthlyPulls(ctx, repoName)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s/%s pull count: %v", s.region, repoName, err))
+		return 0
+	}
+	return pulls
+}
+
+// imageLayers fetches manifests for a repository's images and analyzes their
+// layers. Multi-platform indexes have no layers of their own and are skipped.
+// Returns nil if manifests cannot be fetched.
+func (s *ECRScanner) imageLayers(ctx context.Context, repoName string, images []ecrtypes.ImageDetail, result *registry.ScanResult) map[string]*registry.LayerAnalysis {
+	digests := make([]string, 0, len(images))
+	for _, img := range images {
+		if d := deref(img.ImageDigest); d != "" {
+			digests = append(digests, d)
+		}
+	}
+	manifests, err := GetManifests(ctx, s.client, repoName, digests)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s/%s manifests: %v", s.region, repoName, err))
+		return nil
+	}
+
+	layers := make(map[string]*registry.LayerAnalysis, len(manifests))
+	for digest, text := range manifests {
+		m, err := registry.ParseManifest(text)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s@%s: %v", s.region, repoName, digest, err))
+			continue
+		}
+		if m.IsIndex() {
+			continue
+		}
+		a := registry.AnalyzeLayers(m)
+		layers[digest] = &a
+	}
+	return layers
+}
+
+// indexManifests fetches the manifests of multi-arch index images so their
+// platforms can be resolved. Returns nil if there are no indexes or the
+// manifests cannot be fetched.
+func (s *ECRScanner) indexManifests(ctx context.Context, repoName string, images []ecrtypes.ImageDetail, result *registry.ScanResult) map[string]*registry.Manifest {
+	var digests []string
+	for _, img := range images {
+		if isIndex(img) {
+			digests = append(digests, deref(img.ImageDigest))
+		}
+	}
+	if len(digests) == 0 {
+		return nil
+	}
+	manifests, err := GetManifests(ctx, s.client, repoName, digests)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s/%s index manifests: %v", s.region, repoName, err))
+		return nil
+	}
+
+	indexes := make(map[string]*registry.Manifest, len(manifests))
+	for digest, text := range manifests {
+		m, err := registry.ParseManifest(text)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s@%s: %v", s.region, repoName, digest, err))
+			continue
+		}
+		if m.IsIndex() {
+			indexes[digest] = m
+		}
+	}
+	return indexes
+}
+
+// buildDates reads the build date of each deployed image from its config
+// blob into state.BuildDates. A multi-arch index takes the date of its
+// first platform image. Dates already in the state, such as those of an
+// incremental snapshot, are not fetched again.
+func (s *ECRScanner) buildDates(ctx context.Context, cfg registry.ScanConfig, repoName string, images []ecrtypes.ImageDetail, state *RepoState, result *registry.ScanResult) {
+	for _, img := range images {
+		digest := deref(img.ImageDigest)
+		if _, ok := state.BuildDates[digest]; ok || cfg.InUse.Lookup(repoName, digest, img.ImageTags) == nil {
+			continue
+		}
+		layers := state.Layers[digest]
+		if index := state.Indexes[digest]; index != nil {
+			for _, child := range index.Manifests {
+				if child.Platform != nil && child.Platform.IsAttestation() {
+					continue
+				}
+				if layers = state.Layers[child.Digest]; layers != nil {
+					break
+				}
+			}
+		}
+		if layers == nil || layers.ConfigDigest == "" {
+			continue
+		}
+		config, err := s.configs.ImageConfig(ctx, repoName, layers.ConfigDigest)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s image config: %v", s.region, repoName, err))
+			continue
+		}
+		built, err := registry.ImageCreated(config)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s@%s: %v", s.region, repoName, digest, err))
+			continue
+		}
+		if state.BuildDates == nil {
+			state.BuildDates = make(map[string]time.Time)
+		}
+		state.BuildDates[digest] = built
+	}
+}
+
+// isReferrer reports whether img is an artifact that refers to another
+// image: a signature or SBOM by artifact type, or any manifest with a
+// subject, as listed by ECR or found in the fetched referrer manifests.
+func isReferrer(img ecrtypes.ImageDetail, referrers map[string]registry.Referrer) bool {
+	if registry.IsReferrerArtifact(deref(img.ArtifactMediaType)) || deref(img.SubjectManifestDigest) != "" {
+		return true
+	}
+	_, ok := referrers[deref(img.ImageDigest)]
+	return ok
+}
+
+// referrers fetches the manifests of signature and SBOM artifacts pushed
+// with the OCI referrers API and returns them by digest with the digest each
+// one refers to. Returns nil if the repository has no such artifacts or
+// their manifests cannot be fetched.
+func (s *ECRScanner) referrers(ctx context.Context, repoName string, images []ecrtypes.ImageDetail, result *registry.ScanResult) map[string]registry.Referrer {
+	var digests []string
+	for _, img := range images {
+		if registry.IsReferrerArtifact(deref(img.ArtifactMediaType)) {
+			digests = append(digests, deref(img.ImageDigest))
+		}
+	}
+	if len(digests) == 0 {
+		return nil
+	}
+	manifests, err := GetManifests(ctx, s.client, repoName, digests)
+	if err != nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("%s/%s referrer manifests: %v", s.region, repoName, err))
+		return nil
+	}
+
+	referrers := make(map[string]registry.Referrer, len(manifests))
+	for digest, text := range manifests {
+		m, err := registry.ParseManifest(text)
+		if err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("%s/%s@%s: %v", s.region, repoName, digest, err))
+			continue
+		}
+		if r, ok := m.Referrer(); ok {
+			referrers[digest] = r
+		}
+	}
+	return referrers
+}
+
+// imageInputs carries per-repository context needed to analyze one image.
+type imageInputs struct {
+	repoPulls    int64 // pulls of the whole repository
+	monthlyPulls int64 // the image's share of repoPulls
+	layers       *registry.LayerAnalysis
+	index        *registry.Manifest              // resolved manifest if the image is a multi-arch index
+	images       map[string]ecrtypes.ImageDetail // repository images by digest
+	retained     bool                            // among the newest cfg.KeepLatest of a tag family
+	orphaned     bool                            // untagged platform manifest no index references
+	inIndex      string                          // digest of an index referencing the image
+	referrer     bool                            // signature, SBOM or other artifact with a subject
+	signed       bool                            // has a cosign or referrer signature
+	sbom         bool                            // has a cosign or referrer SBOM
+	built        time.Time                       // build date from the config blob, for deployed images
+}
+
+func (s *ECRScanner) analyzeImage(_ context.Context, cfg registry.ScanConfig, repoName string, img ecrtypes.ImageDetail, in imageInputs) []registry.Finding {
 	var findings []registry.Finding
+	monthlyPulls, layers := in.monthlyPulls, in.layers
 
 	digest := deref(img.ImageDigest)
 	imageID := fmt.Sprintf("%s@%s", repoName, digest)
@@ -138,15 +906,15 @@ func (s *ECRScanner) analyzeImage(_ context.Context, cfg registry.ScanConfig, re
 	cost := pricing.MonthlyStorageCost("ecr", s.region, sizeBytes)
 	sizeMB := float64(sizeBytes) / (1024 * 1024)
 
-	// Resource name from tags
-	resourceName := ""
-	if len(img.ImageTags) > 0 {
-		resourceName = fmt.Sprintf("%s:%s", repoName, strings.Join(img.ImageTags, ","))
-	}
+	resourceName := registry.ImageName(repoName, img.ImageTags, cfg.TagPriority)
+	inUse := cfg.InUse.Lookup(repoName, digest, img.ImageTags)
+	protected := cfg.ProtectedTags.Match(img.ImageTags)
 
-	// Untagged image
-	if len(img.ImageTags) == 0 {
-		findings = append(findings, registry.Finding{
+	// Untagged image — still reported when deployed by digest, since a
+	// lifecycle policy would delete it, but at low severity. Signatures and
+	// SBOMs are untagged by design, and platform manifests go with their index.
+	if len(img.ImageTags) == 0 && !in.referrer && in.inIndex == "" {
+		f := registry.Finding{
 			ID:                    registry.FindingUntaggedImage,
 			Severity:              registry.SeverityHigh,
 			ResourceType:          registry.ResourceImage,
@@ -158,11 +926,20 @@ func (s *ECRScanner) analyzeImage(_ context.Context, cfg registry.ScanConfig, re
 				"size_bytes": sizeBytes,
 				"digest":     digest,
 			},
-		})
+		}
+		if in.orphaned {
+			f.ID = registry.FindingOrphanedManifest
+			f.Message = fmt.Sprintf("Platform manifest not referenced by any multi-arch index (%.0f MB)", sizeMB)
+		}
+		if inUse != nil {
+			registry.MarkInUse(&f, inUse)
+		}
+		findings = append(findings, f)
 	}
 
-	// Stale image — not pulled in > staleDays
-	if cfg.StaleDays > 0 {
+	// Stale image — not pulled in > staleDays, unless it is deployed, kept
+	// as a recent release, or protected
+	if cfg.StaleDays > 0 && inUse == nil && !in.retained && !protected {
 		staleThreshold := s.now.AddDate(0, 0, -cfg.StaleDays)
 		lastActivity := lastActivityTime(img)
 		if lastActivity != nil && lastActivity.Before(staleThreshold) {
@@ -186,9 +963,29 @@ func (s *ECRScanner) analyzeImage(_ context.Context, cfg registry.ScanConfig, re
 		}
 	}
 
+	// Tiering candidate — stale, but kept by retention so it cannot simply
+	// be deleted; archive storage is far cheaper than the registry
+	if cfg.StaleDays > 0 && inUse == nil && (in.retained || protected) {
+		lastActivity := lastActivityTime(img)
+		if lastActivity != nil && lastActivity.Before(s.now.AddDate(0, 0, -cfg.StaleDays)) {
+			retainedBy := "keep_latest"
+			if protected {
+				retainedBy = "protected_tags"
+			}
+			archiveCost, tier := pricing.MonthlyArchiveCost("ecr", sizeBytes)
+			if f := registry.TieringCandidate(cfg, registry.ColdImage{
+				ID: imageID, Name: resourceName, Region: s.region, SizeBytes: sizeBytes,
+				DaysStale: int(s.now.Sub(*lastActivity).Hours() / 24), RetainedBy: retainedBy,
+				HotCost: cost, ArchiveCost: archiveCost, ArchiveTier: tier,
+			}); f != nil {
+				findings = append(findings, *f)
+			}
+		}
+	}
+
 	// Large image
 	if cfg.MaxSizeBytes > 0 && sizeBytes > cfg.MaxSizeBytes {
-		findings = append(findings, registry.Finding{
+		f := registry.Finding{
 			ID:                    registry.FindingLargeImage,
 			Severity:              registry.SeverityMedium,
 			ResourceType:          registry.ResourceImage,
@@ -201,12 +998,33 @@ func (s *ECRScanner) analyzeImage(_ context.Context, cfg registry.ScanConfig, re
 				"size_bytes":      sizeBytes,
 				"threshold_bytes": cfg.MaxSizeBytes,
 			},
-		})
+		}
+
+		// Egress waste — bytes above the threshold transferred on every pull
+		if cfg.EgressModel != "" && monthlyPulls > 0 {
+			egress := pricing.MonthlyTransferCost("ecr", cfg.EgressModel, sizeBytes-cfg.MaxSizeBytes, monthlyPulls)
+			f.EstimatedMonthlyWaste += egress
+			f.Message += fmt.Sprintf(", $%.2f/mo %s egress waste over %d of the repository's %d pulls", egress, cfg.EgressModel, monthlyPulls, in.repoPulls)
+			f.Metadata["monthly_pulls"] = monthlyPulls
+			f.Metadata["repository_monthly_pulls"] = in.repoPulls
+			f.Metadata["pull_count_scope"] = "apportioned"
+			f.Metadata["egress_model"] = cfg.EgressModel
+			f.Metadata["egress_monthly_waste"] = egress
+		}
+		if layers != nil {
+			f.Metadata["compressed_bytes"] = layers.CompressedBytes
+			f.Metadata["largest_layers"] = layers.Largest
+		}
+		findings = append(findings, f)
 	}
 
-	// Multi-arch bloat: image manifest list with multiple platforms
-	if img.ImageManifestMediaType != nil && strings.Contains(deref(img.ImageManifestMediaType), "manifest.list") {
-		// Image index (multi-arch) — check if individual platforms are stale
+	// Multi-arch bloat: platforms of an index that are never pulled
+	if isIndex(img) && in.index != nil {
+		if f := s.multiArchFinding(cfg, imageID, resourceName, in.index, in.images); f != nil {
+			findings = append(findings, *f)
+		}
+	} else if isIndex(img) {
+		// Index could not be resolved — fall back to flagging the whole stale index
 		if cfg.StaleDays > 0 {
 			lastActivity := lastActivityTime(img)
 			staleThreshold := s.now.AddDate(0, 0, -cfg.StaleDays)
@@ -229,9 +1047,141 @@ func (s *ECRScanner) analyzeImage(_ context.Context, cfg registry.ScanConfig, re
 		}
 	}
 
+	// Duplicate layers — the same large content stored more than once in one image
+	if layers != nil && layers.DuplicateLayers > 0 {
+		dupCost := pricing.MonthlyStorageCost("ecr", s.region, layers.DuplicateBytes)
+		findings = append(findings, registry.Finding{
+			ID:                    registry.FindingDuplicateLayers,
+			Severity:              registry.SeverityMedium,
+			ResourceType:          registry.ResourceImage,
+			ResourceID:            imageID,
+			ResourceName:          resourceName,
+			Region:                s.region,
+			Message:               fmt.Sprintf("%d duplicate layers add %.0f MB — combine Dockerfile steps that rewrite the same files", layers.DuplicateLayers, float64(layers.DuplicateBytes)/(1024*1024)),
+			EstimatedMonthlyWaste: dupCost,
+			Metadata: map[string]any{
+				"duplicate_layers": layers.DuplicateLayers,
+				"duplicate_bytes":  layers.DuplicateBytes,
+				"compressed_bytes": layers.CompressedBytes,
+				"reported_bytes":   sizeBytes,
+				"largest_layers":   layers.Largest,
+			},
+		})
+	}
+
+	if cfg.RequiresSignature(img.ImageTags) && !in.signed {
+		findings = append(findings, registry.UnsignedFinding(imageID, resourceName, s.region, digest))
+	}
+	if cfg.RequiresSBOM(img.ImageTags, pushedAt(img), s.now) && !in.sbom {
+		findings = append(findings, registry.MissingSBOMFinding(cfg, imageID, resourceName, s.region, digest))
+	}
+	if f := registry.AgingDeployedImage(cfg, imageID, resourceName, s.region, digest, in.built, inUse, s.now); f != nil {
+		findings = append(findings, *f)
+	}
+	if len(cfg.Rules) > 0 {
+		var lastPull time.Time
+		if img.LastRecordedPullTime != nil {
+			lastPull = *img.LastRecordedPullTime
+		}
+		findings = append(findings, registry.CustomFindings(cfg, rules.Image{
+			Repository: repoName,
+			Region:     s.region,
+			Digest:     digest,
+			MediaType:  deref(img.ImageManifestMediaType),
+			Tags:       img.ImageTags,
+			SizeBytes:  sizeBytes,
+			Pushed:     pushedAt(img),
+			LastPull:   lastPull,
+		}, s.now, imageID, resourceName, cost)...)
+	}
+
+	// Flag the remaining findings as affecting a deployed image
+	if inUse != nil {
+		for i := range findings {
+			if !registry.IsInUse(findings[i]) {
+				registry.AnnotateInUse(&findings[i], inUse)
+			}
+		}
+	}
+	if protected {
+		registry.MarkProtected(findings)
+	}
+	registry.AnnotateTags(findings, img.ImageTags)
+	registry.AnnotatePushed(findings, pushedAt(img))
 	return findings
 }
 
+// multiArchFinding reports the platforms of a resolved index that are unused:
+// not in cfg.UsedPlatforms (when set), or whose child manifest has not been
+// pulled within the stale window. Attestation entries are ignored.
+func (s *ECRScanner) multiArchFinding(cfg registry.ScanConfig, imageID, resourceName string, index *registry.Manifest, images map[string]ecrtypes.ImageDetail) *registry.Finding {
+	staleThreshold := s.now.AddDate(0, 0, -cfg.StaleDays)
+
+	var unused []map[string]any
+	var unusedNames []string
+	var wasteBytes int64
+	platforms := 0
+	for _, child := range index.Manifests {
+		if child.Platform == nil || child.Platform.IsAttestation() {
+			continue
+		}
+		platforms++
+		childImg, found := images[child.Digest]
+		size := derefInt64(childImg.ImageSizeInBytes)
+
+		reason := ""
+		switch {
+		case len(cfg.UsedPlatforms) > 0 && !registry.PlatformUsed(cfg.UsedPlatforms, *child.Platform):
+			reason = "platform not in --used-platforms"
+		case found && cfg.StaleDays > 0:
+			if last := lastActivityTime(childImg); last != nil && last.Before(staleThreshold) {
+				reason = fmt.Sprintf("not pulled in %d days", int(s.now.Sub(*last).Hours()/24))
+			}
+		}
+		if reason == "" {
+			continue
+		}
+
+		entry := map[string]any{
+			"platform":   child.Platform.String(),
+			"digest":     child.Digest,
+			"size_bytes": size,
+			"reason":     reason,
+		}
+		if childImg.LastRecordedPullTime != nil {
+			entry["last_pull"] = childImg.LastRecordedPullTime.Format(time.RFC3339)
+		}
+		unused = append(unused, entry)
+		unusedNames = append(unusedNames, child.Platform.String())
+		wasteBytes += size
+	}
+	if len(unused) == 0 {
+		return nil
+	}
+
+	return &registry.Finding{
+		ID:                    registry.FindingMultiArchBloat,
+		Severity:              registry.SeverityLow,
+		ResourceType:          registry.ResourceImage,
+		ResourceID:            imageID,
+		ResourceName:          resourceName,
+		Region:                s.region,
+		Message:               fmt.Sprintf("%d of %d platforms unused (%s, %.0f MB)", len(unused), platforms, strings.Join(unusedNames, ", "), float64(wasteBytes)/(1024*1024)),
+		EstimatedMonthlyWaste: pricing.MonthlyStorageCost("ecr", s.region, wasteBytes),
+		Metadata: map[string]any{
+			"unused_platforms": unused,
+			"platform_count":   platforms,
+			"size_bytes":       wasteBytes,
+		},
+	}
+}
+
+// isIndex reports whether an image is a multi-arch manifest list or OCI index.
+func isIndex(img ecrtypes.ImageDetail) bool {
+	mt := deref(img.ImageManifestMediaType)
+	return strings.Contains(mt, "manifest.list") || strings.Contains(mt, "image.index")
+}
+
 // lastActivityTime returns the most recent activity time for an image.
 // Prefers lastRecordedPullTime, falls back to imagePushedAt.
 func lastActivityTime(img ecrtypes.ImageDetail) *time.Time {
@@ -241,14 +1191,14 @@ func lastActivityTime(img ecrtypes.ImageDetail) *time.Time {
 	return img.ImagePushedAt
 }
 
-func (s *ECRScanner) reportProgress(progress func(registry.ScanProgress), msg string) {
+// reportProgress fills in the region, scanner and time of an event and
+// passes it to progress, if set.
+func (s *ECRScanner) reportProgress(progress func(registry.ScanProgress), p registry.ScanProgress) {
 	if progress != nil {
-		progress(registry.ScanProgress{
-			Region:    s.region,
-			Scanner:   "ecr",
-			Message:   msg,
-			Timestamp: time.Now(),
-		})
+		p.Region = s.region
+		p.Scanner = "ecr"
+		p.Timestamp = time.Now()
+		progress(p)
 	}
 }
 
@@ -266,15 +1216,56 @@ func derefInt64(p *int64) int64 {
 	return *p
 }
 
+// scanRepositoryVulnerabilities looks up scan findings for every image in a
+// repository with bounded concurrency. Findings are returned in image order.
+func (s *ECRScanner) scanRepositoryVulnerabilities(ctx context.Context, repoName string, images []ecrtypes.ImageDetail) ([]registry.Finding, []string) {
+	findings := make([][]registry.Finding, len(images))
+	errs := make([]error, len(images))
+
+	sem := make(chan struct{}, vulnScanConcurrency)
+	var wg sync.WaitGroup
+	for i, img := range images {
+		digest := deref(img.ImageDigest)
+		if digest == "" {
+			continue
+		}
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, digest string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			findings[i], errs[i] = s.ScanVulnerabilities(ctx, repoName, digest)
+		}(i, digest)
+	}
+	wg.Wait()
+
+	var out []registry.Finding
+	var errMsgs []string
+	for i := range images {
+		if errs[i] != nil {
+			errMsgs = append(errMsgs, fmt.Sprintf("%s/%s scan findings: %v", s.region, repoName, errs[i]))
+			continue
+		}
+		registry.AnnotatePushed(findings[i], pushedAt(images[i]))
+		out = append(out, findings[i]...)
+	}
+	return out, errMsgs
+}
+
 // ScanVulnerabilities checks an image for CVE findings from ECR's built-in scan.
+// Images that were never scanned return no findings and no error.
 func (s *ECRScanner) ScanVulnerabilities(ctx context.Context, repoName, digest string) ([]registry.Finding, error) {
 	out, err := s.client.DescribeImageScanFindings(ctx, &awsecr.DescribeImageScanFindingsInput{
 		RepositoryName: &repoName,
 		ImageId:        &ecrtypes.ImageIdentifier{ImageDigest: &digest},
 	})
 	if err != nil {
-		slog.Debug("No scan findings available", "repo", repoName, "error", err)
-		return nil, nil
+		var notFound *ecrtypes.ScanNotFoundException
+		if errors.As(err, &notFound) {
+			slog.Debug("No scan findings available", "repo", repoName, "digest", digest)
+			return nil, nil
+		}
+		return nil, fmt.Errorf("describe image scan findings for %s@%s: %w", repoName, digest, err)
 	}
 
 	if out.ImageScanFindings == nil || len(out.ImageScanFindings.Findings) == 0 {
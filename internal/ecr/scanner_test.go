@@ -3,6 +3,8 @@ package ecr
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -78,6 +80,66 @@ func TestScanStaleImage(t *testing.T) {
 	}
 }
 
+func TestScanKeepLatestNotStale(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:v100", []string{"v1.0.0"}, halfGB, stale200.AddDate(0, 0, -2), stale200),
+		makeImage("sha256:v110", []string{"v1.1.0"}, halfGB, stale200.AddDate(0, 0, -1), stale200),
+		makeImage("sha256:v120", []string{"v1.2.0"}, halfGB, stale200, stale120),
+	}
+
+	cfg := defaultCfg()
+	cfg.KeepLatest = 2
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	stale := findByID(result.Findings, registry.FindingStaleImage)
+	if len(stale) != 1 || stale[0].ResourceID != "myapp@sha256:v100" {
+		t.Fatalf("expected only the oldest v1 release stale, got %+v", stale)
+	}
+	if len(findByID(result.Findings, registry.FindingUnusedRepo)) != 0 {
+		t.Error("repository with retained releases should not be UNUSED_REPO")
+	}
+	tiering := findByID(result.Findings, registry.FindingTieringCandidate)
+	if len(tiering) != 2 || tiering[0].Metadata["retained_by"] != "keep_latest" || tiering[0].Metadata["archive_tier"] != "s3-glacier-deep-archive" {
+		t.Fatalf("expected the two retained stale releases as TIERING_CANDIDATE, got %+v", tiering)
+	}
+	if tiering[0].EstimatedMonthlyWaste <= 0 || tiering[0].EstimatedMonthlyWaste >= 0.05 {
+		t.Errorf("savings = %f, want under the $0.05/mo hot cost of 0.5 GB", tiering[0].EstimatedMonthlyWaste)
+	}
+}
+
+func TestScanNamesImageByCanonicalTag(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:bbb", []string{"latest", "v1.9.0", "v1.10.0", "abc123"}, halfGB, stale200, stale120),
+	}
+
+	s := newTestScanner(mock)
+	result := s.Scan(context.Background(), defaultCfg(), nil)
+
+	stale := findByID(result.Findings, registry.FindingStaleImage)
+	if len(stale) != 1 {
+		t.Fatalf("expected 1 STALE_IMAGE, got %d", len(stale))
+	}
+	if stale[0].ResourceName != "myapp:v1.10.0" {
+		t.Errorf("ResourceName = %q, want myapp:v1.10.0", stale[0].ResourceName)
+	}
+	tags, _ := stale[0].Metadata["tags"].([]string)
+	if strings.Join(tags, ",") != "abc123,latest,v1.10.0,v1.9.0" {
+		t.Errorf("tags = %v, want all four sorted", tags)
+	}
+
+	cfg := defaultCfg()
+	cfg.TagPriority = []string{"abc*"}
+	result = s.Scan(context.Background(), cfg, nil)
+	stale = findByID(result.Findings, registry.FindingStaleImage)
+	if len(stale) != 1 || stale[0].ResourceName != "myapp:abc123" {
+		t.Errorf("with priority, findings = %+v, want myapp:abc123", stale)
+	}
+}
+
 func TestScanRecentImageNotStale(t *testing.T) {
 	mock := newMockClient()
 	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
@@ -113,6 +175,118 @@ func TestScanLargeImage(t *testing.T) {
 	}
 }
 
+func TestScanLargeImageEgressWaste(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:big", []string{"latest"}, 3*oneGB, recent, recent),
+	}
+	pc := &mockPullCounter{pulls: map[string]int64{"myapp": 1000}}
+
+	cfg := defaultCfg()
+	cfg.EgressModel = "inter-region"
+
+	s := newTestScanner(mock)
+	s.SetPullCounter(pc)
+	result := s.Scan(context.Background(), cfg, nil)
+
+	large := findByID(result.Findings, registry.FindingLargeImage)
+	if len(large) != 1 {
+		t.Fatalf("expected 1 LARGE_IMAGE, got %d", len(large))
+	}
+	// 2 GB over threshold * 1000 pulls * $0.02/GB = $40 egress + $0.30 storage
+	egress := large[0].Metadata["egress_monthly_waste"].(float64)
+	if egress < 39.99 || egress > 40.01 {
+		t.Errorf("egress waste = $%.2f, want ~$40", egress)
+	}
+	if large[0].EstimatedMonthlyWaste < 40.29 || large[0].EstimatedMonthlyWaste > 40.31 {
+		t.Errorf("total waste = $%.2f, want ~$40.30", large[0].EstimatedMonthlyWaste)
+	}
+	if !strings.Contains(large[0].Message, "egress waste") {
+		t.Errorf("message should mention egress waste, got %q", large[0].Message)
+	}
+}
+
+func TestScanEgressApportionsRepositoryPulls(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:big1", []string{"v1"}, 3*oneGB, recent, recent),
+		makeImage("sha256:big2", []string{"v2"}, 3*oneGB, recent, recent),
+	}
+	pc := &mockPullCounter{pulls: map[string]int64{"myapp": 1000}}
+
+	cfg := defaultCfg()
+	cfg.EgressModel = "inter-region"
+
+	s := newTestScanner(mock)
+	s.SetPullCounter(pc)
+	result := s.Scan(context.Background(), cfg, nil)
+
+	large := findByID(result.Findings, registry.FindingLargeImage)
+	if len(large) != 2 {
+		t.Fatalf("expected 2 LARGE_IMAGE, got %d", len(large))
+	}
+	// The repository's 1000 pulls are charged once: 2 GB * 1000 * $0.02/GB
+	var egress float64
+	for _, f := range large {
+		egress += f.Metadata["egress_monthly_waste"].(float64)
+		if f.Metadata["monthly_pulls"] != int64(500) || f.Metadata["repository_monthly_pulls"] != int64(1000) {
+			t.Errorf("%s pulls = %v of %v, want 500 of 1000", f.ResourceID, f.Metadata["monthly_pulls"], f.Metadata["repository_monthly_pulls"])
+		}
+	}
+	if egress < 39.99 || egress > 40.01 {
+		t.Errorf("total egress waste = $%.2f, want ~$40", egress)
+	}
+}
+
+func TestScanEgressSkipsPullCountWithoutLargeImages(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:small", []string{"latest"}, hundredMB, recent, recent),
+	}
+	pc := &mockPullCounter{pulls: map[string]int64{"myapp": 1000}}
+
+	cfg := defaultCfg()
+	cfg.EgressModel = "internet"
+
+	s := newTestScanner(mock)
+	s.SetPullCounter(pc)
+	s.Scan(context.Background(), cfg, nil)
+
+	if pc.calls != 0 {
+		t.Errorf("pull counter called %d times, want 0", pc.calls)
+	}
+}
+
+func TestScanEgressPullCountError(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:big", []string{"latest"}, twoGB, recent, recent),
+	}
+	pc := &mockPullCounter{err: errors.New("access denied")}
+
+	cfg := defaultCfg()
+	cfg.EgressModel = "internet"
+
+	s := newTestScanner(mock)
+	s.SetPullCounter(pc)
+	result := s.Scan(context.Background(), cfg, nil)
+
+	if len(result.Errors) != 1 {
+		t.Errorf("expected 1 error, got %v", result.Errors)
+	}
+	large := findByID(result.Findings, registry.FindingLargeImage)
+	if len(large) != 1 {
+		t.Fatalf("expected 1 LARGE_IMAGE, got %d", len(large))
+	}
+	if _, ok := large[0].Metadata["egress_monthly_waste"]; ok {
+		t.Error("egress waste should be absent when pull count fails")
+	}
+}
+
 func TestScanSmallImageNotLarge(t *testing.T) {
 	mock := newMockClient()
 	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
@@ -146,6 +320,50 @@ func TestScanNoLifecyclePolicy(t *testing.T) {
 	}
 }
 
+func TestScanFetchesEachLifecyclePolicyOnce(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("a"), makeRepo("b"), makeRepo("c")}
+	for _, r := range []string{"a", "b", "c"} {
+		mock.images[r] = []ecrtypes.ImageDetail{makeImage("sha256:"+r, []string{"latest"}, halfGB, recent, recent)}
+	}
+	mock.lifecycleRepos["b"] = true
+
+	result := newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+
+	if n := mock.lifecycleCalls.Load(); n != 3 {
+		t.Errorf("GetLifecyclePolicy calls = %d, want 3", n)
+	}
+	if nolp := findByID(result.Findings, registry.FindingNoLifecyclePolicy); len(nolp) != 2 {
+		t.Errorf("expected 2 NO_LIFECYCLE_POLICY, got %d", len(nolp))
+	}
+
+	// The single-repository audit reuses the policy for its simulation.
+	mock.lifecycleCalls.Store(0)
+	newTestScanner(mock).ScanRepository(context.Background(), defaultCfg(), "b", nil)
+	if n := mock.lifecycleCalls.Load(); n != 1 {
+		t.Errorf("ScanRepository GetLifecyclePolicy calls = %d, want 1", n)
+	}
+}
+
+func TestScanLifecycleCheckDisabled(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:fff", []string{"latest"}, halfGB, recent, recent),
+	}
+
+	cfg := defaultCfg()
+	cfg.DisabledChecks = map[registry.FindingID]bool{registry.FindingNoLifecyclePolicy: true}
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	if n := mock.lifecycleCalls.Load(); n != 0 {
+		t.Errorf("GetLifecyclePolicy calls = %d, want 0", n)
+	}
+	if nolp := findByID(result.Findings, registry.FindingNoLifecyclePolicy); len(nolp) != 0 {
+		t.Errorf("disabled check emitted %d findings", len(nolp))
+	}
+}
+
 func TestScanWithLifecyclePolicy(t *testing.T) {
 	mock := newMockClient()
 	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
@@ -221,6 +439,121 @@ func TestScanExcludeRepo(t *testing.T) {
 	}
 }
 
+func TestScanExcludeRepoByTag(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("prod"), makeRepo("dev")}
+	mock.tags[repoARN("prod")] = map[string]string{"env": "production"}
+	mock.tags[repoARN("dev")] = map[string]string{"env": "dev"}
+	mock.images["prod"] = []ecrtypes.ImageDetail{makeImage("sha256:p", nil, halfGB, recent, recent)}
+	mock.images["dev"] = []ecrtypes.ImageDetail{makeImage("sha256:d", nil, halfGB, recent, recent)}
+
+	cfg := defaultCfg()
+	cfg.Exclude.Tags = map[string]string{"env": "production"}
+
+	s := newTestScanner(mock)
+	result := s.Scan(context.Background(), cfg, nil)
+
+	for _, f := range result.Findings {
+		if strings.HasPrefix(f.ResourceID, "prod") {
+			t.Errorf("repo excluded by tag should have no findings, got %s %s", f.ID, f.ResourceID)
+		}
+	}
+	if len(findByID(result.Findings, registry.FindingUntaggedImage)) != 1 {
+		t.Error("expected UNTAGGED_IMAGE for non-excluded repo")
+	}
+}
+
+func TestScanAttributesOwnerTags(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.tags[repoARN("myapp")] = map[string]string{"Team": "payments", "Owner": "alice"}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{makeImage("sha256:a", nil, halfGB, recent, recent)}
+
+	s := newTestScanner(mock)
+	result := s.Scan(context.Background(), defaultCfg(), nil)
+
+	if len(result.Findings) == 0 {
+		t.Fatal("expected findings")
+	}
+	for _, f := range result.Findings {
+		if f.Metadata["team"] != "payments" || f.Metadata["owner"] != "alice" {
+			t.Errorf("%s metadata = %v, want team/owner attribution", f.ID, f.Metadata)
+		}
+	}
+}
+
+func TestScanTagsErrorRecorded(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.tagsErr[repoARN("myapp")] = errors.New("access denied")
+	mock.images["myapp"] = []ecrtypes.ImageDetail{makeImage("sha256:a", nil, halfGB, recent, recent)}
+
+	s := newTestScanner(mock)
+	result := s.Scan(context.Background(), defaultCfg(), nil)
+
+	if len(result.Errors) != 1 {
+		t.Errorf("expected 1 error, got %v", result.Errors)
+	}
+	if len(findByID(result.Findings, registry.FindingUntaggedImage)) != 1 {
+		t.Error("scan should continue when tags cannot be listed")
+	}
+}
+
+func TestScanPriorityOrderAndCoverage(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("cheap"), makeRepo("pricey"), makeRepo("mid")}
+	for _, r := range []string{"cheap", "pricey", "mid"} {
+		mock.images[r] = []ecrtypes.ImageDetail{makeImage("sha256:"+r, nil, halfGB, recent, recent)}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var order []string
+	mock.onDescribe = func(repo string) {
+		order = append(order, repo)
+		if len(order) == 2 {
+			cancel() // deadline hits while scanning the second repository
+		}
+	}
+
+	cfg := defaultCfg()
+	cfg.RepoPriority = map[string]float64{"pricey": 80, "mid": 15, "cheap": 5}
+
+	s := newTestScanner(mock)
+	result := s.Scan(ctx, cfg, nil)
+
+	if len(order) != 2 || order[0] != "pricey" || order[1] != "mid" {
+		t.Errorf("scan order = %v, want [pricey mid]", order)
+	}
+	if !result.Coverage.Truncated {
+		t.Error("coverage should be truncated")
+	}
+	if result.Coverage.RepositoriesCompleted != 1 || result.Coverage.RepositoriesPlanned != 3 {
+		t.Errorf("coverage = %+v, want 1 of 3", result.Coverage)
+	}
+	if result.Coverage.Percent < 79.9 || result.Coverage.Percent > 80.1 {
+		t.Errorf("coverage percent = %.1f, want 80", result.Coverage.Percent)
+	}
+}
+
+func TestScanFullCoverage(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{makeImage("sha256:a", nil, halfGB, recent, recent)}
+
+	s := newTestScanner(mock)
+	result := s.Scan(context.Background(), defaultCfg(), nil)
+
+	if result.Coverage.Truncated || result.Coverage.Percent != 100 {
+		t.Errorf("coverage = %+v, want complete", result.Coverage)
+	}
+	for _, f := range result.Findings {
+		if f.Repository != "myapp" {
+			t.Errorf("%s Repository = %q, want myapp", f.ID, f.Repository)
+		}
+	}
+}
+
 func TestScanDescribeRepositoriesError(t *testing.T) {
 	mock := newMockClient()
 	mock.descRepoErr = errors.New("access denied")
@@ -234,6 +567,9 @@ func TestScanDescribeRepositoriesError(t *testing.T) {
 	if len(result.Findings) != 0 {
 		t.Error("expected no findings on error")
 	}
+	if !result.AllTargetsFailed() || result.TargetErrors[0].Target != "us-east-1" {
+		t.Errorf("target errors = %d of %d: %+v", len(result.TargetErrors), result.Targets, result.TargetErrors)
+	}
 }
 
 func TestScanDescribeImagesError(t *testing.T) {
@@ -266,6 +602,98 @@ func TestScanMultiArchBloat(t *testing.T) {
 	}
 }
 
+const platformIndex = `{"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[
+	{"digest":"sha256:amd","size":1,"platform":{"os":"linux","architecture":"amd64"}},
+	{"digest":"sha256:arm","size":1,"platform":{"os":"linux","architecture":"arm64","variant":"v8"}},
+	{"digest":"sha256:att","size":1,"platform":{"os":"unknown","architecture":"unknown"}}]}`
+
+func TestScanMultiArchUnpulledPlatform(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("multiarch")}
+	idx := makeImage("sha256:idx", []string{"latest"}, 1000, stale200, recent)
+	idx.ImageManifestMediaType = aws.String("application/vnd.oci.image.index.v1+json")
+	mock.images["multiarch"] = []ecrtypes.ImageDetail{
+		idx,
+		makeImage("sha256:amd", nil, oneGB, stale200, recent),
+		makeImage("sha256:arm", nil, halfGB, stale200, stale120),
+		makeImage("sha256:att", nil, 1000, stale200, time.Time{}),
+	}
+	mock.manifests["multiarch@sha256:idx"] = platformIndex
+
+	result := newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+
+	bloat := findByID(result.Findings, registry.FindingMultiArchBloat)
+	if len(bloat) != 1 {
+		t.Fatalf("expected 1 MULTI_ARCH_BLOAT, got %d", len(bloat))
+	}
+	if bloat[0].Metadata["platform_count"] != 2 {
+		t.Errorf("platform_count = %v, want 2 (attestation skipped)", bloat[0].Metadata["platform_count"])
+	}
+	if bloat[0].Metadata["size_bytes"] != halfGB {
+		t.Errorf("size_bytes = %v, want %d", bloat[0].Metadata["size_bytes"], halfGB)
+	}
+	if !strings.Contains(bloat[0].Message, "linux/arm64/v8") {
+		t.Errorf("message should name the unused platform: %s", bloat[0].Message)
+	}
+}
+
+func TestScanMultiArchUsedPlatforms(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("multiarch")}
+	idx := makeImage("sha256:idx", []string{"latest"}, 1000, stale200, recent)
+	idx.ImageManifestMediaType = aws.String("application/vnd.oci.image.index.v1+json")
+	mock.images["multiarch"] = []ecrtypes.ImageDetail{
+		idx,
+		makeImage("sha256:amd", nil, oneGB, stale200, recent),
+		makeImage("sha256:arm", nil, halfGB, stale200, recent),
+	}
+	mock.manifests["multiarch@sha256:idx"] = platformIndex
+
+	cfg := defaultCfg()
+	cfg.UsedPlatforms = []string{"linux/amd64"}
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	bloat := findByID(result.Findings, registry.FindingMultiArchBloat)
+	if len(bloat) != 1 {
+		t.Fatalf("expected 1 MULTI_ARCH_BLOAT, got %d", len(bloat))
+	}
+	unused := bloat[0].Metadata["unused_platforms"].([]map[string]any)
+	if len(unused) != 1 || unused[0]["platform"] != "linux/arm64/v8" {
+		t.Errorf("unused_platforms = %v", unused)
+	}
+
+	cfg.UsedPlatforms = []string{"linux/amd64", "linux/arm64"}
+	result = newTestScanner(mock).Scan(context.Background(), cfg, nil)
+	if got := len(findByID(result.Findings, registry.FindingMultiArchBloat)); got != 0 {
+		t.Errorf("expected no MULTI_ARCH_BLOAT when all platforms are used, got %d", got)
+	}
+}
+
+func TestScanInUseImages(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:old", []string{"v1"}, hundredMB, stale200, stale120),
+		makeImage("sha256:pinned", nil, hundredMB, stale200, stale120),
+		makeImage("sha256:gone", []string{"v0"}, hundredMB, stale200, stale120),
+	}
+
+	cfg := defaultCfg()
+	cfg.InUse = registry.NewInUse()
+	cfg.InUse.Add("123456789012.dkr.ecr.us-east-1.amazonaws.com/myapp:v1", "ecs:prod/service:api")
+	cfg.InUse.Add("123456789012.dkr.ecr.us-east-1.amazonaws.com/myapp@sha256:pinned", "lambda:worker")
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	stale := findByID(result.Findings, registry.FindingStaleImage)
+	if len(stale) != 1 || stale[0].ResourceID != "myapp@sha256:gone" {
+		t.Fatalf("expected only the undeployed image to be stale, got %v", stale)
+	}
+	untagged := findByID(result.Findings, registry.FindingUntaggedImage)
+	if len(untagged) != 1 || untagged[0].Severity != registry.SeverityLow || untagged[0].Metadata["in_use"] != true {
+		t.Errorf("deployed untagged image should be downgraded, got %v", untagged)
+	}
+}
+
 func TestScanVulnerabilities(t *testing.T) {
 	mock := newMockClient()
 	mock.scanFindings["myapp@sha256:vuln"] = &awsecr.DescribeImageScanFindingsOutput{
@@ -313,48 +741,133 @@ func TestScanVulnerabilitiesLowOnly(t *testing.T) {
 	}
 }
 
-func TestScanResourcesScannedCount(t *testing.T) {
+func TestScanVulnerabilitiesScanNotFound(t *testing.T) {
 	mock := newMockClient()
-	mock.repos = []ecrtypes.Repository{makeRepo("repo1"), makeRepo("repo2")}
-	mock.images["repo1"] = []ecrtypes.ImageDetail{
-		makeImage("sha256:r1a", []string{"v1"}, hundredMB, recent, recent),
-		makeImage("sha256:r1b", []string{"v2"}, hundredMB, recent, recent),
+	mock.scanErr["myapp@sha256:none"] = &ecrtypes.ScanNotFoundException{Message: aws.String("no scan")}
+
+	s := newTestScanner(mock)
+	findings, err := s.ScanVulnerabilities(context.Background(), "myapp", "sha256:none")
+	if err != nil {
+		t.Fatalf("ScanVulnerabilities() should ignore SCAN_NOT_FOUND, got: %v", err)
 	}
-	mock.images["repo2"] = []ecrtypes.ImageDetail{
-		makeImage("sha256:r2a", []string{"v1"}, hundredMB, recent, recent),
+	if len(findings) != 0 {
+		t.Errorf("expected no findings, got %d", len(findings))
 	}
-	mock.lifecycleRepos["repo1"] = true
-	mock.lifecycleRepos["repo2"] = true
+}
 
-	s := newTestScanner(mock)
-	result := s.Scan(context.Background(), defaultCfg(), nil)
+func TestScanVulnerabilitiesAPIError(t *testing.T) {
+	mock := newMockClient()
+	mock.scanErr["myapp@sha256:err"] = errors.New("throttled")
 
-	if result.ResourcesScanned != 3 {
-		t.Errorf("ResourcesScanned = %d, want 3", result.ResourcesScanned)
-	}
-	if result.RepositoriesScanned != 2 {
-		t.Errorf("RepositoriesScanned = %d, want 2", result.RepositoriesScanned)
+	s := newTestScanner(mock)
+	if _, err := s.ScanVulnerabilities(context.Background(), "myapp", "sha256:err"); err == nil {
+		t.Error("expected error for non-SCAN_NOT_FOUND failure")
 	}
 }
 
-func TestScanProgress(t *testing.T) {
+func TestScanIncludeScanEmitsVulnerableImage(t *testing.T) {
 	mock := newMockClient()
 	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.lifecycleRepos["myapp"] = true
 	mock.images["myapp"] = []ecrtypes.ImageDetail{
-		makeImage("sha256:prog", []string{"latest"}, hundredMB, recent, recent),
+		makeImage("sha256:v1", []string{"v1"}, hundredMB, recent, recent),
+		makeImage("sha256:v2", []string{"v2"}, hundredMB, recent, recent),
+		makeImage("sha256:v3", []string{"v3"}, hundredMB, recent, recent),
 	}
-
-	var messages []string
+	mock.scanFindings["myapp@sha256:v1"] = &awsecr.DescribeImageScanFindingsOutput{
+		ImageScanFindings: &ecrtypes.ImageScanFindings{
+			Findings: []ecrtypes.ImageScanFinding{{Severity: ecrtypes.FindingSeverityCritical}},
+		},
+	}
+	mock.scanErr["myapp@sha256:v2"] = &ecrtypes.ScanNotFoundException{Message: aws.String("no scan")}
+	mock.scanErr["myapp@sha256:v3"] = errors.New("throttled")
+
+	s := NewECRScanner(mock, "us-east-1", true)
+	s.now = now
+	result := s.Scan(context.Background(), defaultCfg(), nil)
+
+	vuln := findByID(result.Findings, registry.FindingVulnerableImage)
+	if len(vuln) != 1 {
+		t.Fatalf("expected 1 VULNERABLE_IMAGE, got %d", len(vuln))
+	}
+	if vuln[0].ResourceID != "myapp@sha256:v1" {
+		t.Errorf("ResourceID = %q, want myapp@sha256:v1", vuln[0].ResourceID)
+	}
+	if len(result.Errors) != 1 {
+		t.Errorf("expected 1 error for failed lookup, got %d: %v", len(result.Errors), result.Errors)
+	}
+}
+
+func TestScanWithoutIncludeScanSkipsVulnerabilities(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:v1", []string{"v1"}, hundredMB, recent, recent),
+	}
+	mock.scanFindings["myapp@sha256:v1"] = &awsecr.DescribeImageScanFindingsOutput{
+		ImageScanFindings: &ecrtypes.ImageScanFindings{
+			Findings: []ecrtypes.ImageScanFinding{{Severity: ecrtypes.FindingSeverityCritical}},
+		},
+	}
+
+	s := newTestScanner(mock)
+	result := s.Scan(context.Background(), defaultCfg(), nil)
+
+	if vuln := findByID(result.Findings, registry.FindingVulnerableImage); len(vuln) != 0 {
+		t.Errorf("expected 0 VULNERABLE_IMAGE without --include-scan, got %d", len(vuln))
+	}
+}
+
+func TestScanResourcesScannedCount(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("repo1"), makeRepo("repo2")}
+	mock.images["repo1"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:r1a", []string{"v1"}, hundredMB, recent, recent),
+		makeImage("sha256:r1b", []string{"v2"}, hundredMB, recent, recent),
+	}
+	mock.images["repo2"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:r2a", []string{"v1"}, hundredMB, recent, recent),
+	}
+	mock.lifecycleRepos["repo1"] = true
+	mock.lifecycleRepos["repo2"] = true
+
+	s := newTestScanner(mock)
+	result := s.Scan(context.Background(), defaultCfg(), nil)
+
+	if result.ResourcesScanned != 3 {
+		t.Errorf("ResourcesScanned = %d, want 3", result.ResourcesScanned)
+	}
+	if result.RepositoriesScanned != 2 {
+		t.Errorf("RepositoriesScanned = %d, want 2", result.RepositoriesScanned)
+	}
+}
+
+func TestScanProgress(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:prog", []string{"latest"}, hundredMB, recent, recent),
+	}
+
+	var messages []string
+	var last registry.ScanProgress
 	progress := func(p registry.ScanProgress) {
 		messages = append(messages, p.Message)
+		last = p
 	}
 
 	s := newTestScanner(mock)
-	s.Scan(context.Background(), defaultCfg(), progress)
+	result := s.Scan(context.Background(), defaultCfg(), progress)
 
 	if len(messages) < 2 {
 		t.Errorf("expected at least 2 progress messages, got %d", len(messages))
 	}
+	if last.Stage != registry.StageDone || last.ImagesScanned != 1 {
+		t.Errorf("final progress = %+v, want done with 1 image scanned", last)
+	}
+	if len(result.Timings) != 1 || result.Timings[0].Repository != "myapp" || result.Timings[0].Images != 1 {
+		t.Errorf("timings = %+v, want one entry for myapp with 1 image", result.Timings)
+	}
 }
 
 func TestLastActivityTimePrefersPull(t *testing.T) {
@@ -427,6 +940,421 @@ func TestLastActivityTimeNilBothTimes(t *testing.T) {
 	}
 }
 
+func TestScanIncrementalReusesUnchangedRepository(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:aaa", []string{"v1"}, 1000, now.Add(-100*24*time.Hour), now.Add(-100*24*time.Hour)),
+	}
+
+	first := newTestScanner(mock)
+	first.EnableIncremental(nil)
+	first.Scan(context.Background(), defaultCfg(), nil)
+	snap := first.Snapshot()
+	if _, ok := snap.Repositories["myapp"]; !ok {
+		t.Fatal("expected myapp in snapshot")
+	}
+
+	described := 0
+	mock.onDescribe = func(string) { described++ }
+
+	second := newTestScanner(mock)
+	second.now = now.Add(10 * 24 * time.Hour)
+	second.EnableIncremental(snap)
+	result := second.Scan(context.Background(), defaultCfg(), nil)
+
+	if described != 0 {
+		t.Errorf("DescribeImages called %d times for unchanged repo, want 0", described)
+	}
+	if second.ReusedRepositories() != 1 {
+		t.Errorf("reused = %d, want 1", second.ReusedRepositories())
+	}
+	stale := findByID(result.Findings, registry.FindingStaleImage)
+	if len(stale) != 1 {
+		t.Fatalf("expected 1 STALE_IMAGE, got %d", len(stale))
+	}
+	if days := stale[0].Metadata["days_stale"]; days != 110 {
+		t.Errorf("days_stale = %v, want 110 (re-aged from cache)", days)
+	}
+	if _, ok := second.Snapshot().Repositories["myapp"]; !ok {
+		t.Error("reused repository should be carried into the new snapshot")
+	}
+}
+
+func TestScanIncrementalRefetchesExpiredState(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:aaa", []string{"v1"}, 1000, stale200, stale200),
+	}
+
+	first := newTestScanner(mock)
+	first.EnableIncremental(nil)
+	first.Scan(context.Background(), defaultCfg(), nil)
+
+	// Reused a day later: the state keeps its original fetch time.
+	second := newTestScanner(mock)
+	second.now = now.Add(24 * time.Hour)
+	second.SetSnapshotMaxAge(7 * 24 * time.Hour)
+	second.EnableIncremental(first.Snapshot())
+	second.Scan(context.Background(), defaultCfg(), nil)
+	if second.ReusedRepositories() != 1 {
+		t.Fatalf("reused = %d, want 1", second.ReusedRepositories())
+	}
+	if got := second.Snapshot().Repositories["myapp"].FetchedAt; !got.Equal(now) {
+		t.Errorf("fetched_at = %v, want the original %v", got, now)
+	}
+
+	// The image is pulled without a digest change; past the max age the
+	// repository is fetched again and the pull is seen.
+	mock.images["myapp"][0].LastRecordedPullTime = aws.Time(now.Add(7 * 24 * time.Hour))
+	third := newTestScanner(mock)
+	third.now = now.Add(8 * 24 * time.Hour)
+	third.SetSnapshotMaxAge(7 * 24 * time.Hour)
+	third.EnableIncremental(second.Snapshot())
+	result := third.Scan(context.Background(), defaultCfg(), nil)
+	if third.ReusedRepositories() != 0 {
+		t.Errorf("reused = %d, want 0 for expired state", third.ReusedRepositories())
+	}
+	if got := len(findByID(result.Findings, registry.FindingStaleImage)); got != 0 {
+		t.Errorf("expected no STALE_IMAGE after the refetch, got %d", got)
+	}
+	if got := third.Snapshot().Repositories["myapp"].FetchedAt; !got.Equal(third.now) {
+		t.Errorf("fetched_at = %v, want %v", got, third.now)
+	}
+}
+
+func TestScanIncrementalRescansChangedRepository(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:aaa", []string{"v1"}, 1000, now, now),
+	}
+
+	first := newTestScanner(mock)
+	first.EnableIncremental(nil)
+	first.Scan(context.Background(), defaultCfg(), nil)
+
+	mock.images["myapp"] = append(mock.images["myapp"],
+		makeImage("sha256:bbb", nil, 1000, now, time.Time{}))
+	described := 0
+	mock.onDescribe = func(string) { described++ }
+
+	second := newTestScanner(mock)
+	second.EnableIncremental(first.Snapshot())
+	result := second.Scan(context.Background(), defaultCfg(), nil)
+
+	if described != 1 {
+		t.Errorf("DescribeImages called %d times, want 1", described)
+	}
+	if second.ReusedRepositories() != 0 {
+		t.Errorf("reused = %d, want 0", second.ReusedRepositories())
+	}
+	if got := len(findByID(result.Findings, registry.FindingUntaggedImage)); got != 1 {
+		t.Errorf("expected 1 UNTAGGED_IMAGE, got %d", got)
+	}
+}
+
+func TestScanIncrementalSkipsCachingFailedRepository(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:aaa", []string{"v1"}, 1000, now, now),
+	}
+	mock.lifecycleErr["myapp"] = errors.New("throttled")
+
+	s := newTestScanner(mock)
+	s.EnableIncremental(nil)
+	s.Scan(context.Background(), defaultCfg(), nil)
+
+	if _, ok := s.Snapshot().Repositories["myapp"]; ok {
+		t.Error("repository with lookup errors should not be cached")
+	}
+}
+
+func TestEnableIncrementalIgnoresOtherRegion(t *testing.T) {
+	s := newTestScanner(newMockClient())
+	s.EnableIncremental(NewSnapshot("eu-west-1", now))
+	if s.previous != nil {
+		t.Error("snapshot from another region should be ignored")
+	}
+}
+
+func TestScanRepoFilter(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("team-a/api"), makeRepo("team-a/build-cache"), makeRepo("team-b/api")}
+	for _, r := range []string{"team-a/api", "team-a/build-cache", "team-b/api"} {
+		mock.images[r] = []ecrtypes.ImageDetail{makeImage("sha256:"+r, nil, 1000, now, now)}
+	}
+
+	filter, err := registry.NewRepoFilter([]string{"team-a/*", "!*-cache"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := defaultCfg()
+	cfg.Repos = filter
+
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+	if result.RepositoriesScanned != 1 {
+		t.Errorf("RepositoriesScanned = %d, want 1", result.RepositoriesScanned)
+	}
+	for _, f := range result.Findings {
+		if f.Repository != "team-a/api" {
+			t.Errorf("unexpected finding for %s", f.Repository)
+		}
+	}
+	if result.Coverage.Percent != 100 {
+		t.Errorf("coverage = %.1f, want 100 for filtered scan", result.Coverage.Percent)
+	}
+}
+
+func TestScanRepositoryDetail(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp"), makeRepo("other")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:keep", []string{"v1"}, oneGB/2, now, now),
+		makeImage("sha256:old", nil, oneGB/2, now.Add(-30*24*time.Hour), time.Time{}),
+	}
+	mock.lifecyclePolicies["myapp"] = `{"rules":[{"rulePriority":1,"selection":{"tagStatus":"untagged","countType":"sinceImagePushed","countUnit":"days","countNumber":7},"action":{"type":"expire"}}]}`
+	mock.images["other"] = []ecrtypes.ImageDetail{makeImage("sha256:x", nil, 1000, now, now)}
+
+	described := map[string]bool{}
+	mock.onDescribe = func(repo string) { described[repo] = true }
+
+	s := NewECRScanner(mock, "us-east-1", true)
+	s.now = now
+	result := s.ScanRepository(context.Background(), defaultCfg(), "myapp", nil)
+
+	if described["other"] {
+		t.Error("single-repository scan should not touch other repositories")
+	}
+	if result.RepositoriesScanned != 1 {
+		t.Errorf("RepositoriesScanned = %d, want 1", result.RepositoriesScanned)
+	}
+	d := result.Detail
+	if d == nil {
+		t.Fatal("expected repository detail")
+	}
+	if d.ImageCount != 2 || !d.LifecycleSimulated || d.ExpiringImages != 1 {
+		t.Errorf("unexpected detail: %+v", d)
+	}
+	for _, img := range d.Images {
+		switch img.Digest {
+		case "sha256:old":
+			if img.ExpiredByRule != 1 {
+				t.Errorf("old image ExpiredByRule = %d, want 1", img.ExpiredByRule)
+			}
+			if len(img.Findings) == 0 || img.Findings[0] != registry.FindingUntaggedImage {
+				t.Errorf("old image findings = %v", img.Findings)
+			}
+		case "sha256:keep":
+			if img.ExpiredByRule != 0 {
+				t.Error("tagged image should be kept")
+			}
+		}
+	}
+}
+
+func TestScanRepositoryNotFound(t *testing.T) {
+	mock := newMockClient()
+	result := newTestScanner(mock).ScanRepository(context.Background(), defaultCfg(), "missing", nil)
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "missing") {
+		t.Errorf("expected not found error, got %v", result.Errors)
+	}
+	if result.Detail != nil {
+		t.Error("expected no detail for missing repository")
+	}
+}
+
+const dupManifest = `{"mediaType":"application/vnd.docker.distribution.manifest.v2+json",
+	"config":{"digest":"sha256:cfg","size":1000},
+	"layers":[{"digest":"sha256:l1","size":104857600},{"digest":"sha256:l1","size":104857600},{"digest":"sha256:l3","size":5}]}`
+
+func TestScanDeepLayersDuplicate(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:aaa", []string{"v1"}, 2*oneGB, now, now),
+		makeImage("sha256:idx", []string{"multi"}, 1000, now, now),
+	}
+	mock.manifests["myapp@sha256:aaa"] = dupManifest
+	mock.manifests["myapp@sha256:idx"] = `{"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[{"digest":"sha256:aaa","size":1}]}`
+
+	cfg := defaultCfg()
+	cfg.DeepLayers = true
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	dups := findByID(result.Findings, registry.FindingDuplicateLayers)
+	if len(dups) != 1 {
+		t.Fatalf("expected 1 DUPLICATE_LAYERS, got %d", len(dups))
+	}
+	if dups[0].Metadata["duplicate_bytes"] != int64(104857600) {
+		t.Errorf("duplicate_bytes = %v", dups[0].Metadata["duplicate_bytes"])
+	}
+	if dups[0].EstimatedMonthlyWaste <= 0 {
+		t.Error("expected positive waste")
+	}
+	large := findByID(result.Findings, registry.FindingLargeImage)
+	if len(large) != 1 || large[0].Metadata["compressed_bytes"] != int64(209716205) {
+		t.Errorf("LARGE_IMAGE should carry compressed_bytes, got %v", large)
+	}
+}
+
+func TestScanOrphanedManifest(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("multiarch")}
+	idx := makeImage("sha256:idx", []string{"latest"}, 1000, recent, recent)
+	idx.ImageManifestMediaType = aws.String("application/vnd.oci.image.index.v1+json")
+	mock.images["multiarch"] = []ecrtypes.ImageDetail{
+		idx,
+		makeImage("sha256:amd", nil, halfGB, recent, recent),
+		makeImage("sha256:arm", nil, halfGB, recent, recent),
+		makeImage("sha256:att", nil, 1000, recent, time.Time{}),
+		makeImage("sha256:old", nil, oneGB, stale200, stale120),
+	}
+	mock.manifests["multiarch@sha256:idx"] = platformIndex
+	for _, d := range []string{"amd", "arm", "att", "old"} {
+		mock.manifests["multiarch@sha256:"+d] = dupManifest
+	}
+
+	cfg := defaultCfg()
+	cfg.DeepLayers = true
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	orphans := findByID(result.Findings, registry.FindingOrphanedManifest)
+	if len(orphans) != 1 || orphans[0].ResourceID != "multiarch@sha256:old" {
+		t.Fatalf("expected sha256:old orphaned, got %+v", orphans)
+	}
+	if orphans[0].Metadata["size_bytes"] != oneGB || orphans[0].EstimatedMonthlyWaste <= 0 {
+		t.Errorf("orphan should carry its reclaimable size, got %+v", orphans[0])
+	}
+	// Children of the index cannot be deleted before it, so they are not
+	// untagged waste.
+	if got := findByID(result.Findings, registry.FindingUntaggedImage); len(got) != 0 {
+		t.Errorf("expected no UNTAGGED_IMAGE for referenced children, got %+v", got)
+	}
+
+	// Without deep inspection the orphan is reported as a plain untagged image.
+	result = newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+	if got := len(findByID(result.Findings, registry.FindingOrphanedManifest)); got != 0 {
+		t.Errorf("expected no ORPHANED_MANIFEST without deep mode, got %d", got)
+	}
+	if got := findByID(result.Findings, registry.FindingUntaggedImage); len(got) != 1 || got[0].ResourceID != "multiarch@sha256:old" {
+		t.Errorf("expected UNTAGGED_IMAGE for sha256:old only, got %+v", got)
+	}
+}
+
+func TestScanUntaggedSkipsReferrers(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("app")}
+	sig := makeImage("sha256:sig", nil, 1000, stale200, time.Time{})
+	sig.ArtifactMediaType = aws.String("application/vnd.dev.cosign.artifact.sig.v1+json")
+	sbom := makeImage("sha256:sbom", nil, 1000, stale200, time.Time{})
+	sbom.SubjectManifestDigest = aws.String("sha256:app")
+	mock.images["app"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:app", []string{"v1"}, oneGB, recent, recent),
+		sig,
+		sbom,
+		makeImage("sha256:junk", nil, oneGB, stale200, time.Time{}),
+	}
+
+	result := newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+	untagged := findByID(result.Findings, registry.FindingUntaggedImage)
+	if len(untagged) != 1 || untagged[0].ResourceID != "app@sha256:junk" {
+		t.Errorf("expected UNTAGGED_IMAGE for sha256:junk only, got %+v", untagged)
+	}
+}
+
+func TestScanDeepLayersDisabled(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{makeImage("sha256:aaa", []string{"v1"}, 1000, now, now)}
+	mock.manifests["myapp@sha256:aaa"] = dupManifest
+
+	result := newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+	if got := len(findByID(result.Findings, registry.FindingDuplicateLayers)); got != 0 {
+		t.Errorf("expected no DUPLICATE_LAYERS without deep mode, got %d", got)
+	}
+}
+
+func TestScanDeepLayersError(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{makeImage("sha256:aaa", []string{"v1"}, 1000, now, now)}
+	mock.batchGetErr["myapp"] = errors.New("access denied")
+
+	cfg := defaultCfg()
+	cfg.DeepLayers = true
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "manifests") {
+		t.Errorf("expected manifest error, got %v", result.Errors)
+	}
+}
+
+func TestScanAgingDeployedImage(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:aaa", []string{"v1"}, 1000, now, now),
+		makeImage("sha256:bbb", []string{"v2"}, 1000, now, now),
+		makeImage("sha256:ccc", []string{"v3"}, 1000, now, now),
+	}
+	mock.manifests["myapp@sha256:aaa"] = dupManifest
+	mock.manifests["myapp@sha256:bbb"] = `{"mediaType":"application/vnd.docker.distribution.manifest.v2+json","config":{"digest":"sha256:fresh","size":10},"layers":[]}`
+	mock.manifests["myapp@sha256:ccc"] = dupManifest
+	configs := &mockConfigFetcher{configs: map[string]string{
+		"myapp@sha256:cfg":   `{"created":"2025-01-10T08:00:00Z"}`,
+		"myapp@sha256:fresh": `{"created":"2026-02-01T08:00:00Z"}`,
+	}}
+
+	cfg := defaultCfg()
+	cfg.DeepLayers = true
+	cfg.MaxDeployedAge = 180 * 24 * time.Hour
+	cfg.InUse = registry.NewInUse()
+	cfg.InUse.Add("123456789012.dkr.ecr.us-east-1.amazonaws.com/myapp:v1", "ecs:prod/service:api")
+	cfg.InUse.Add("123456789012.dkr.ecr.us-east-1.amazonaws.com/myapp:v2", "ecs:prod/service:web")
+	scanner := newTestScanner(mock)
+	scanner.SetConfigFetcher(configs)
+	result := scanner.Scan(context.Background(), cfg, nil)
+
+	aging := findByID(result.Findings, registry.FindingAgingDeployedImage)
+	if len(aging) != 1 || aging[0].ResourceID != "myapp@sha256:aaa" {
+		t.Fatalf("expected AGING_DEPLOYED_IMAGE for sha256:aaa only, got %+v", aging)
+	}
+	if aging[0].Metadata["build_age_days"] != 414 {
+		t.Errorf("build_age_days = %v", aging[0].Metadata["build_age_days"])
+	}
+	if configs.calls != 2 {
+		t.Errorf("expected configs fetched for the 2 deployed images only, got %d", configs.calls)
+	}
+
+	// Without a config fetcher the check is skipped.
+	result = newTestScanner(mock).Scan(context.Background(), cfg, nil)
+	if got := len(findByID(result.Findings, registry.FindingAgingDeployedImage)); got != 0 {
+		t.Errorf("expected no AGING_DEPLOYED_IMAGE without a config fetcher, got %d", got)
+	}
+}
+
+func TestScanAgingDeployedImageConfigError(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	mock.images["myapp"] = []ecrtypes.ImageDetail{makeImage("sha256:aaa", []string{"v1"}, 1000, now, now)}
+	mock.manifests["myapp@sha256:aaa"] = dupManifest
+
+	cfg := defaultCfg()
+	cfg.DeepLayers = true
+	cfg.MaxDeployedAge = 180 * 24 * time.Hour
+	cfg.InUse = registry.NewInUse()
+	cfg.InUse.Add("123456789012.dkr.ecr.us-east-1.amazonaws.com/myapp:v1", "ecs:prod/service:api")
+	scanner := newTestScanner(mock)
+	scanner.SetConfigFetcher(&mockConfigFetcher{})
+	result := scanner.Scan(context.Background(), cfg, nil)
+
+	if len(result.Errors) != 1 || !strings.Contains(result.Errors[0], "image config") {
+		t.Errorf("expected image config error, got %v", result.Errors)
+	}
+}
+
 // findByID filters findings by FindingID.
 func findByID(findings []registry.Finding, id registry.FindingID) []registry.Finding {
 	var out []registry.Finding
@@ -437,3 +1365,248 @@ func findByID(findings []registry.Finding, id registry.FindingID) []registry.Fin
 	}
 	return out
 }
+
+func TestScanUnsignedImage(t *testing.T) {
+	signedHex := strings.Repeat("a", 64)
+	referredHex := strings.Repeat("b", 64)
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	referrer := makeImage("sha256:ref", nil, 100, recent, recent)
+	referrer.ArtifactMediaType = aws.String("application/vnd.dev.cosign.artifact.sig.v1+json")
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:"+signedHex, []string{"v1.0.0"}, hundredMB, recent, recent),
+		makeImage("sha256:sig", []string{"sha256-" + signedHex + ".sig"}, 100, recent, recent),
+		makeImage("sha256:"+referredHex, []string{"v1.1.0"}, hundredMB, recent, recent),
+		referrer,
+		makeImage("sha256:unsigned", []string{"v1.2.0"}, hundredMB, recent, recent),
+		makeImage("sha256:dev", []string{"dev-3f2a"}, hundredMB, recent, recent),
+	}
+	mock.manifests["myapp@sha256:ref"] = `{"mediaType":"application/vnd.oci.image.manifest.v1+json","artifactType":"application/vnd.dev.cosign.artifact.sig.v1+json","subject":{"digest":"sha256:` + referredHex + `","size":1}}`
+
+	cfg := defaultCfg()
+	cfg.RequireSignatures = true
+	cfg.SignedTags, _ = registry.NewTagPatterns([]string{`^v\d+\.\d+\.\d+$`})
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	unsigned := findByID(result.Findings, registry.FindingUnsignedImage)
+	if len(unsigned) != 1 || unsigned[0].ResourceID != "myapp@sha256:unsigned" {
+		t.Fatalf("expected only sha256:unsigned reported, got %+v", unsigned)
+	}
+	if len(result.Errors) != 0 {
+		t.Errorf("unexpected errors: %v", result.Errors)
+	}
+
+	cfg.RequireSignatures = false
+	result = newTestScanner(mock).Scan(context.Background(), cfg, nil)
+	if got := len(findByID(result.Findings, registry.FindingUnsignedImage)); got != 0 {
+		t.Errorf("signature check should be off by default, got %d findings", got)
+	}
+}
+
+func TestScanMissingSBOM(t *testing.T) {
+	attachedHex := strings.Repeat("c", 64)
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("myapp")}
+	referrer := makeImage("sha256:spdx", nil, 100, recent, recent)
+	referrer.ArtifactMediaType = aws.String("application/spdx+json")
+	mock.images["myapp"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:"+attachedHex, []string{"v1.0.0"}, hundredMB, recent, recent),
+		makeImage("sha256:sbomtag", []string{"sha256-" + attachedHex + ".sbom"}, 100, recent, recent),
+		makeImage("sha256:referred", []string{"v1.1.0"}, hundredMB, recent, recent),
+		referrer,
+		makeImage("sha256:missing", []string{"v1.2.0"}, hundredMB, recent, recent),
+		makeImage("sha256:old", []string{"v0.9.0"}, hundredMB, stale200, stale200),
+	}
+	mock.manifests["myapp@sha256:spdx"] = `{"artifactType":"application/spdx+json","subject":{"digest":"sha256:referred","size":1}}`
+
+	cfg := defaultCfg()
+	cfg.RequireSBOM = true
+	cfg.SBOMSeverity = registry.SeverityHigh
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	missing := findByID(result.Findings, registry.FindingMissingSBOM)
+	if len(missing) != 1 || missing[0].ResourceID != "myapp@sha256:missing" {
+		t.Fatalf("expected only sha256:missing reported, got %+v", missing)
+	}
+	if missing[0].Severity != registry.SeverityHigh {
+		t.Errorf("severity = %s, want configured high", missing[0].Severity)
+	}
+}
+
+func TestScanCustomRules(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("team/sandbox"), makeRepo("team/api")}
+	mock.images["team/sandbox"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:old", []string{"exp-1"}, hundredMB, stale200, stale200),
+		makeImage("sha256:new", []string{"exp-2"}, hundredMB, recent, recent),
+	}
+	mock.images["team/api"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:api", []string{"v1.0.0"}, hundredMB, stale200, stale200),
+	}
+
+	rule, err := registry.NewCustomRule("SANDBOX_EXPIRED", "low", "Sandbox image older than 30 days", `repo.endsWith("/sandbox") && age_days > 30`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := defaultCfg()
+	cfg.Rules = []registry.CustomRule{rule}
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	custom := findByID(result.Findings, "SANDBOX_EXPIRED")
+	if len(custom) != 1 || custom[0].ResourceID != "team/sandbox@sha256:old" {
+		t.Fatalf("expected only the old sandbox image, got %+v", custom)
+	}
+	if custom[0].Severity != registry.SeverityLow || custom[0].EstimatedMonthlyWaste <= 0 {
+		t.Errorf("finding = %+v", custom[0])
+	}
+}
+
+func TestScanUntaggedAccumulation(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("build-cache"), makeRepo("app")}
+	for i := 0; i < 5; i++ {
+		mock.images["build-cache"] = append(mock.images["build-cache"], makeImage(fmt.Sprintf("sha256:cache%d", i), nil, hundredMB, stale200, stale200))
+	}
+	mock.images["build-cache"] = append(mock.images["build-cache"], makeImage("sha256:tagged", []string{"v1"}, hundredMB, recent, recent))
+	mock.images["app"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:u1", nil, hundredMB, recent, recent),
+		makeImage("sha256:u2", nil, hundredMB, recent, recent),
+	}
+
+	cfg := defaultCfg()
+	cfg.UntaggedAccumulation = 3
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	acc := findByID(result.Findings, registry.FindingUntaggedAccumulation)
+	if len(acc) != 1 || acc[0].ResourceID != "build-cache" {
+		t.Fatalf("UNTAGGED_ACCUMULATION = %+v, want one for build-cache", acc)
+	}
+	if acc[0].Metadata["untagged_count"] != 5 || acc[0].Metadata["stale_count"] != 5 || acc[0].Metadata["size_bytes"] != int64(5*hundredMB) {
+		t.Errorf("metadata = %+v", acc[0].Metadata)
+	}
+	for _, f := range result.Findings {
+		if strings.HasPrefix(f.ResourceID, "build-cache@sha256:cache") {
+			t.Errorf("per-image finding for a cached image: %+v", f)
+		}
+	}
+	if untagged := findByID(result.Findings, registry.FindingUntaggedImage); len(untagged) != 2 {
+		t.Errorf("UNTAGGED_IMAGE = %d, want 2 for app below the threshold", len(untagged))
+	}
+	if result.ResourcesScanned != 8 {
+		t.Errorf("ResourcesScanned = %d, want 8", result.ResourcesScanned)
+	}
+}
+
+func TestScanUntaggedAccumulationKeepsDeployed(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("build-cache")}
+	for i := 0; i < 4; i++ {
+		mock.images["build-cache"] = append(mock.images["build-cache"], makeImage(fmt.Sprintf("sha256:cache%d", i), nil, hundredMB, stale200, stale200))
+	}
+
+	cfg := defaultCfg()
+	cfg.UntaggedAccumulation = 2
+	cfg.InUse = registry.NewInUse()
+	cfg.InUse.Add("123456789012.dkr.ecr.us-east-1.amazonaws.com/build-cache@sha256:cache0", "deploy/api")
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	acc := findByID(result.Findings, registry.FindingUntaggedAccumulation)
+	if len(acc) != 1 || acc[0].Metadata["untagged_count"] != 3 {
+		t.Fatalf("UNTAGGED_ACCUMULATION = %+v, want 3 undeployed images", acc)
+	}
+	untagged := findByID(result.Findings, registry.FindingUntaggedImage)
+	if len(untagged) != 1 || untagged[0].ResourceID != "build-cache@sha256:cache0" {
+		t.Errorf("UNTAGGED_IMAGE = %+v, want the deployed image only", untagged)
+	}
+	if unused := findByID(result.Findings, registry.FindingUnusedRepo); len(unused) != 0 {
+		t.Errorf("UNUSED_REPO reported for a repository with a deployed image: %+v", unused)
+	}
+}
+
+func TestScanRecordsPushTime(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("app")}
+	mock.images["app"] = []ecrtypes.ImageDetail{makeImage("sha256:old", nil, hundredMB, stale200, stale200)}
+
+	result := newTestScanner(mock).Scan(context.Background(), defaultCfg(), nil)
+	findings := append(findByID(result.Findings, registry.FindingUntaggedImage), findByID(result.Findings, registry.FindingStaleImage)...)
+	if len(findings) != 2 {
+		t.Fatalf("findings = %+v, want UNTAGGED_IMAGE and STALE_IMAGE", result.Findings)
+	}
+	for _, f := range findings {
+		if got, ok := registry.PushedAt(f); !ok || !got.Equal(stale200.UTC().Truncate(time.Second)) {
+			t.Errorf("%s pushed_at = %v, want %v", f.ID, f.Metadata[registry.MetadataPushedAt], stale200)
+		}
+	}
+}
+
+func TestScanSelfResolving(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("app")}
+	mock.lifecyclePolicies["app"] = `{"rules":[{"rulePriority":1,"selection":{"tagStatus":"untagged","countType":"sinceImagePushed","countUnit":"days","countNumber":14},"action":{"type":"expire"}}]}`
+	mock.images["app"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:soon", nil, hundredMB, recent, recent),
+		makeImage("sha256:later", nil, hundredMB, now.AddDate(0, 0, -2), time.Time{}),
+		makeImage("sha256:tagged", []string{"v1"}, hundredMB, stale200, stale200),
+	}
+
+	cfg := defaultCfg()
+	cfg.SelfResolvingDays = 7
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	for _, f := range result.Findings {
+		want := f.ResourceID == "app@sha256:soon"
+		if registry.IsSelfResolving(f) != want {
+			t.Errorf("%s %s self-resolving = %v, want %v", f.ID, f.ResourceID, !want, want)
+		}
+	}
+	if untagged := findByID(result.Findings, registry.FindingUntaggedImage); len(untagged) != 2 {
+		t.Errorf("UNTAGGED_IMAGE = %d, want both untagged images still reported by the scanner", len(untagged))
+	}
+}
+
+func TestScanStaleReleaseTrain(t *testing.T) {
+	mock := newMockClient()
+	mock.repos = []ecrtypes.Repository{makeRepo("prod/api"), makeRepo("prod/web"), makeRepo("tools")}
+	mock.images["prod/api"] = []ecrtypes.ImageDetail{
+		makeImage("sha256:old", []string{"v1"}, hundredMB, stale200, stale200),
+		makeImage("sha256:last", []string{"v2"}, hundredMB, now.AddDate(0, 0, -30), recent),
+	}
+	mock.images["prod/web"] = []ecrtypes.ImageDetail{makeImage("sha256:new", []string{"v9"}, hundredMB, recent, recent)}
+	mock.images["tools"] = []ecrtypes.ImageDetail{makeImage("sha256:tool", []string{"v1"}, hundredMB, stale200, stale200)}
+
+	weekly, err := registry.NewReleaseCadence("prod/*", "weekly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := defaultCfg()
+	cfg.ReleaseCadences = []registry.ReleaseCadence{weekly}
+	result := newTestScanner(mock).Scan(context.Background(), cfg, nil)
+
+	stale := findByID(result.Findings, registry.FindingStaleReleaseTrain)
+	if len(stale) != 1 || stale[0].ResourceID != "prod/api" {
+		t.Fatalf("STALE_RELEASE_TRAIN = %+v, want prod/api only", stale)
+	}
+	if got := stale[0].Metadata["days_since_release"]; got != 30 {
+		t.Errorf("days_since_release = %v, want 30", got)
+	}
+}
+
+func TestIndexChildren(t *testing.T) {
+	mock := newMockClient()
+	idx := makeImage("sha256:idx", nil, 1000, recent, recent)
+	idx.ImageManifestMediaType = aws.String("application/vnd.oci.image.index.v1+json")
+	images := []ecrtypes.ImageDetail{idx, makeImage("sha256:amd", nil, halfGB, recent, recent), makeImage("sha256:old", nil, halfGB, recent, recent)}
+	mock.manifests["multiarch@sha256:idx"] = platformIndex
+
+	children, err := IndexChildren(context.Background(), mock, "multiarch", images)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if children["sha256:amd"] != "sha256:idx" || children["sha256:arm"] != "sha256:idx" || children["sha256:old"] != "" {
+		t.Errorf("children = %v", children)
+	}
+	if children, err := IndexChildren(context.Background(), mock, "multiarch", images[1:]); err != nil || children != nil {
+		t.Errorf("without indexes = %v, %v", children, err)
+	}
+}
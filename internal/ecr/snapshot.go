@@ -0,0 +1,116 @@
+package ecr
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// SnapshotSchema identifies the incremental snapshot file format.
+const SnapshotSchema = "ecrspectre-snapshot/v1"
+
+// Snapshot is the per-repository inventory cached between incremental scans.
+// Timestamp is when the snapshot was written; each repository records when
+// its state was fetched, which a reused state keeps.
+type Snapshot struct {
+	Schema       string                `json:"schema"`
+	Region       string                `json:"region"`
+	Timestamp    time.Time             `json:"timestamp"`
+	Repositories map[string]*RepoState `json:"repositories"`
+}
+
+// RepoState is the cached API state of one repository. Findings are always
+// re-derived from it so that age-based checks reflect the current time.
+type RepoState struct {
+	// FetchedAt is when the state was read from the API. Reusing the state
+	// keeps it, so cached last-pull times, lifecycle status and scan
+	// findings expire after the snapshot max age.
+	FetchedAt          time.Time              `json:"fetched_at"`
+	ImageIDs           []string               `json:"image_ids"`
+	Tags               map[string]string      `json:"tags,omitempty"`
+	Images             []ecrtypes.ImageDetail `json:"images"`
+	HasLifecyclePolicy bool                   `json:"has_lifecycle_policy"`
+	// LifecycleSkipped is set when the lifecycle check was disabled, so
+	// HasLifecyclePolicy was not looked up.
+	LifecycleSkipped       bool               `json:"lifecycle_skipped,omitempty"`
+	VulnerabilitiesChecked bool               `json:"vulnerabilities_checked,omitempty"`
+	Vulnerabilities        []registry.Finding `json:"vulnerabilities,omitempty"`
+	// Layers holds per-digest layer analysis once deep mode has fetched manifests.
+	Layers map[string]*registry.LayerAnalysis `json:"layers,omitempty"`
+	// Indexes holds the resolved manifests of multi-arch index images.
+	Indexes map[string]*registry.Manifest `json:"indexes,omitempty"`
+	// Referrers holds the signature and SBOM referrer artifacts by digest.
+	Referrers map[string]registry.Referrer `json:"referrers,omitempty"`
+	// BuildDates holds the build dates read from the config blobs of
+	// deployed images, zero when the config has none.
+	BuildDates map[string]time.Time `json:"build_dates,omitempty"`
+}
+
+// NewSnapshot creates an empty snapshot for a region.
+func NewSnapshot(region string, now time.Time) *Snapshot {
+	return &Snapshot{
+		Schema:       SnapshotSchema,
+		Region:       region,
+		Timestamp:    now,
+		Repositories: make(map[string]*RepoState),
+	}
+}
+
+// LoadSnapshot reads a snapshot file. A missing file returns nil without error.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read snapshot: %w", err)
+	}
+
+	var snap Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("parse snapshot: %w", err)
+	}
+	if snap.Schema != SnapshotSchema {
+		return nil, fmt.Errorf("unsupported snapshot schema %q", snap.Schema)
+	}
+	return &snap, nil
+}
+
+// Save writes the snapshot to path, creating parent directories as needed.
+func (s *Snapshot) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("create snapshot directory: %w", err)
+	}
+	data, err := json.Marshal(s)
+	if err != nil {
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return fmt.Errorf("write snapshot: %w", err)
+	}
+	return nil
+}
+
+// unchanged returns the cached state of a repository if its image list still
+// matches imageIDs and it was fetched no earlier than notBefore (zero for no
+// limit). When vulnerability scanning is requested the cached state must also
+// include vulnerability results.
+func (s *Snapshot) unchanged(repoName string, imageIDs []string, needVulns bool, notBefore time.Time) *RepoState {
+	if s == nil {
+		return nil
+	}
+	state, ok := s.Repositories[repoName]
+	if !ok || !slices.Equal(state.ImageIDs, imageIDs) || state.FetchedAt.Before(notBefore) {
+		return nil
+	}
+	if needVulns && len(state.Images) > 0 && !state.VulnerabilitiesChecked {
+		return nil
+	}
+	return state
+}
@@ -0,0 +1,84 @@
+package ecr
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	ecrtypes "github.com/aws/aws-sdk-go-v2/service/ecr/types"
+)
+
+func TestSnapshotSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "snap.json")
+	snap := NewSnapshot("us-east-1", now)
+	snap.Repositories["myapp"] = &RepoState{
+		ImageIDs:           []string{"sha256:aaa v1"},
+		Images:             []ecrtypes.ImageDetail{makeImage("sha256:aaa", []string{"v1"}, 1000, now, now)},
+		HasLifecyclePolicy: true,
+	}
+	if err := snap.Save(path); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := LoadSnapshot(path)
+	if err != nil {
+		t.Fatalf("LoadSnapshot: %v", err)
+	}
+	if got.Region != "us-east-1" || !got.Timestamp.Equal(now) {
+		t.Errorf("unexpected header: %+v", got)
+	}
+	state := got.unchanged("myapp", []string{"sha256:aaa v1"}, false, time.Time{})
+	if state == nil {
+		t.Fatal("expected unchanged state after round trip")
+	}
+	if deref(state.Images[0].ImageDigest) != "sha256:aaa" {
+		t.Errorf("digest = %q", deref(state.Images[0].ImageDigest))
+	}
+}
+
+func TestLoadSnapshotMissingFile(t *testing.T) {
+	snap, err := LoadSnapshot(filepath.Join(t.TempDir(), "missing.json"))
+	if err != nil || snap != nil {
+		t.Errorf("LoadSnapshot(missing) = %v, %v; want nil, nil", snap, err)
+	}
+}
+
+func TestLoadSnapshotRejectsUnknownSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snap.json")
+	if err := os.WriteFile(path, []byte(`{"schema":"other/v9"}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadSnapshot(path); err == nil {
+		t.Error("expected error for unknown schema")
+	}
+}
+
+func TestSnapshotUnchangedRequiresVulnerabilities(t *testing.T) {
+	snap := NewSnapshot("us-east-1", now)
+	snap.Repositories["myapp"] = &RepoState{
+		ImageIDs: []string{"sha256:aaa v1"},
+		Images:   []ecrtypes.ImageDetail{makeImage("sha256:aaa", []string{"v1"}, 1000, now, now)},
+	}
+	if snap.unchanged("myapp", []string{"sha256:aaa v1"}, true, time.Time{}) != nil {
+		t.Error("state without vulnerability results should not satisfy --include-scan")
+	}
+	if snap.unchanged("myapp", []string{"sha256:bbb v1"}, false, time.Time{}) != nil {
+		t.Error("changed image list should not match")
+	}
+	var nilSnap *Snapshot
+	if nilSnap.unchanged("myapp", nil, false, time.Time{}) != nil {
+		t.Error("nil snapshot should never match")
+	}
+}
+
+func TestSnapshotUnchangedExpires(t *testing.T) {
+	snap := NewSnapshot("us-east-1", now)
+	snap.Repositories["myapp"] = &RepoState{FetchedAt: now.Add(-48 * time.Hour), ImageIDs: []string{"sha256:aaa v1"}}
+	if snap.unchanged("myapp", []string{"sha256:aaa v1"}, false, now.Add(-24*time.Hour)) != nil {
+		t.Error("state fetched before notBefore should not be reused")
+	}
+	if snap.unchanged("myapp", []string{"sha256:aaa v1"}, false, now.Add(-72*time.Hour)) == nil {
+		t.Error("state fetched after notBefore should be reused")
+	}
+}
@@ -0,0 +1,182 @@
+// Package fixtures records cloud API responses to a directory and replays
+// them, so a scan can be reproduced offline from a user's bug report.
+//
+// Each call is stored as one JSON file named after the method and a hash of
+// its sanitized input. Sanitizing rewrites account and project identifiers
+// in both inputs and outputs, so a recording can be shared and replayed
+// under any account or project.
+package fixtures
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Mode selects whether a Store calls through and records, or replays.
+type Mode int
+
+const (
+	Record Mode = iota
+	Replay
+)
+
+// metaFile records when the fixtures were captured.
+const metaFile = "meta.json"
+
+// Replacement rewrites identifying text before it is written or hashed.
+type Replacement struct {
+	Pattern *regexp.Regexp
+	With    string
+}
+
+// ErrorCodec converts provider errors to and from their recorded form so a
+// replayed error satisfies the same errors.As checks as the original.
+type ErrorCodec struct {
+	Code  func(err error) string
+	Error func(code, message string) error
+}
+
+// Store reads and writes recorded calls in one directory.
+type Store struct {
+	dir        string
+	mode       Mode
+	recordedAt time.Time
+	sanitize   []Replacement
+	codec      ErrorCodec
+	mu         sync.Mutex
+}
+
+type entry struct {
+	Method string          `json:"method"`
+	Input  json.RawMessage `json:"input"`
+	Output json.RawMessage `json:"output,omitempty"`
+	Error  *recordedError  `json:"error,omitempty"`
+}
+
+type recordedError struct {
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+type meta struct {
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// NewRecorder creates dir and returns a store that records every call.
+func NewRecorder(dir string, codec ErrorCodec, sanitize ...Replacement) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create fixtures directory: %w", err)
+	}
+	now := time.Now().UTC()
+	data, _ := json.MarshalIndent(meta{RecordedAt: now}, "", "  ")
+	if err := os.WriteFile(filepath.Join(dir, metaFile), data, 0o644); err != nil {
+		return nil, fmt.Errorf("write fixtures metadata: %w", err)
+	}
+	return &Store{dir: dir, mode: Record, recordedAt: now, sanitize: sanitize, codec: codec}, nil
+}
+
+// NewReplayer returns a store that answers calls from the recordings in dir.
+func NewReplayer(dir string, codec ErrorCodec, sanitize ...Replacement) (*Store, error) {
+	data, err := os.ReadFile(filepath.Join(dir, metaFile))
+	if err != nil {
+		return nil, fmt.Errorf("read fixtures metadata: %w", err)
+	}
+	var m meta
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse fixtures metadata: %w", err)
+	}
+	return &Store{dir: dir, mode: Replay, recordedAt: m.RecordedAt, sanitize: sanitize, codec: codec}, nil
+}
+
+// RecordedAt returns when the fixtures were captured. Replayed scans use it
+// as the current time so image ages match the recording.
+func (s *Store) RecordedAt() time.Time { return s.recordedAt }
+
+// Mode reports whether the store records or replays.
+func (s *Store) Mode() Mode { return s.mode }
+
+func (s *Store) clean(data []byte) []byte {
+	text := string(data)
+	for _, r := range s.sanitize {
+		if r.Pattern != nil {
+			text = r.Pattern.ReplaceAllString(text, r.With)
+		}
+	}
+	return []byte(text)
+}
+
+func (s *Store) path(method string, input []byte) string {
+	sum := sha256.Sum256(input)
+	return filepath.Join(s.dir, method+"-"+hex.EncodeToString(sum[:8])+".json")
+}
+
+// Do answers one API call. When recording it runs call and saves the
+// sanitized result; when replaying it returns the saved result without
+// calling. A replayed call that was never recorded is an error.
+func Do[T any](s *Store, method string, input any, call func() (T, error)) (T, error) {
+	var zero T
+	in, err := json.Marshal(input)
+	if err != nil {
+		return zero, fmt.Errorf("encode %s input: %w", method, err)
+	}
+	in = s.clean(in)
+	path := s.path(method, in)
+
+	if s.mode == Replay {
+		data, err := os.ReadFile(path)
+		if errors.Is(err, os.ErrNotExist) {
+			return zero, fmt.Errorf("no recorded response for %s %s", method, in)
+		}
+		if err != nil {
+			return zero, fmt.Errorf("read fixture: %w", err)
+		}
+		var e entry
+		if err := json.Unmarshal(data, &e); err != nil {
+			return zero, fmt.Errorf("parse fixture %s: %w", filepath.Base(path), err)
+		}
+		if e.Error != nil {
+			if s.codec.Error != nil {
+				return zero, s.codec.Error(e.Error.Code, e.Error.Message)
+			}
+			return zero, errors.New(e.Error.Message)
+		}
+		var out T
+		if err := json.Unmarshal(e.Output, &out); err != nil {
+			return zero, fmt.Errorf("parse fixture %s: %w", filepath.Base(path), err)
+		}
+		return out, nil
+	}
+
+	out, callErr := call()
+	e := entry{Method: method, Input: in}
+	if callErr != nil {
+		e.Error = &recordedError{Message: string(s.clean([]byte(callErr.Error())))}
+		if s.codec.Code != nil {
+			e.Error.Code = s.codec.Code(callErr)
+		}
+	} else {
+		data, err := json.Marshal(out)
+		if err != nil {
+			return out, fmt.Errorf("encode %s output: %w", method, err)
+		}
+		e.Output = s.clean(data)
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return out, fmt.Errorf("encode fixture: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return out, fmt.Errorf("write fixture: %w", err)
+	}
+	return out, callErr
+}
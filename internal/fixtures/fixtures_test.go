@@ -0,0 +1,85 @@
+package fixtures
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+type sample struct {
+	Name string
+	Size int
+}
+
+func TestRecordReplay(t *testing.T) {
+	dir := t.TempDir()
+	sanitize := Replacement{Pattern: regexp.MustCompile(`acct-\d+`), With: "acct-0"}
+
+	rec, err := NewRecorder(dir, ErrorCodec{}, sanitize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := 0
+	out, err := Do(rec, "Get", "acct-42/app", func() (sample, error) {
+		calls++
+		return sample{Name: "acct-42/app", Size: 7}, nil
+	})
+	if err != nil || out.Name != "acct-42/app" || calls != 1 {
+		t.Fatalf("record = %+v, %v (calls %d)", out, err, calls)
+	}
+	if _, err := Do(rec, "Get", "acct-42/gone", func() (sample, error) {
+		return sample{}, errors.New("not found in acct-42")
+	}); err == nil {
+		t.Fatal("recorded call should return the live error")
+	}
+
+	rep, err := NewReplayer(dir, ErrorCodec{}, sanitize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.RecordedAt().Equal(rec.RecordedAt()) {
+		t.Errorf("RecordedAt = %v, want %v", rep.RecordedAt(), rec.RecordedAt())
+	}
+	never := func() (sample, error) {
+		t.Fatal("replay must not call through")
+		return sample{}, nil
+	}
+	// Another account's input sanitizes to the same fixture.
+	out, err = Do(rep, "Get", "acct-7/app", never)
+	if err != nil || out != (sample{Name: "acct-0/app", Size: 7}) {
+		t.Errorf("replay = %+v, %v", out, err)
+	}
+	if _, err := Do(rep, "Get", "acct-7/gone", never); err == nil || err.Error() != "not found in acct-0" {
+		t.Errorf("replayed error = %v", err)
+	}
+	if _, err := Do(rep, "Get", "other", never); err == nil || !strings.Contains(err.Error(), "no recorded response") {
+		t.Errorf("missing fixture error = %v", err)
+	}
+}
+
+func TestErrorCodec(t *testing.T) {
+	type coded struct{ error }
+	codec := ErrorCodec{
+		Code: func(err error) string { return "Gone" },
+		Error: func(code, message string) error {
+			return coded{errors.New(code + ": " + message)}
+		},
+	}
+	dir := t.TempDir()
+	rec, _ := NewRecorder(dir, codec)
+	_, _ = Do(rec, "Get", 1, func() (int, error) { return 0, errors.New("missing") })
+
+	rep, _ := NewReplayer(dir, codec)
+	_, err := Do(rep, "Get", 1, func() (int, error) { return 0, nil })
+	var c coded
+	if !errors.As(err, &c) || err.Error() != "Gone: missing" {
+		t.Errorf("replayed error = %#v", err)
+	}
+}
+
+func TestNewReplayerMissingDir(t *testing.T) {
+	if _, err := NewReplayer(t.TempDir()+"/none", ErrorCodec{}); err == nil {
+		t.Error("expected error for a directory without recordings")
+	}
+}
@@ -0,0 +1,234 @@
+// Package gcpapi calls Google Cloud REST APIs that have no generated client
+// in this module, authenticated with OAuth2 access tokens.
+package gcpapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/apistats"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
+)
+
+// CloudPlatformScope grants read access to every API the tool calls.
+const CloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+// pageSize is the number of items requested per list call.
+const pageSize = "500"
+
+// Caller sends authenticated requests to Google Cloud REST APIs.
+type Caller struct {
+	tokens     oauth2.TokenSource
+	httpClient *http.Client
+	endpoint   string // replaces the scheme and host of every URL; used by tests
+}
+
+// NewCaller creates a Caller using application default credentials.
+func NewCaller(ctx context.Context) (*Caller, error) {
+	ts, err := google.DefaultTokenSource(ctx, CloudPlatformScope)
+	if err != nil {
+		return nil, fmt.Errorf("GCP credentials: %w", err)
+	}
+	return NewCallerFromTokenSource(ts), nil
+}
+
+// NewCallerFromTokenSource creates a Caller that authenticates with ts.
+func NewCallerFromTokenSource(ts oauth2.TokenSource) *Caller {
+	return &Caller{tokens: ts, httpClient: oauth2.NewClient(context.Background(), ts)}
+}
+
+// WithEndpoint returns a copy of the caller that sends every request to
+// endpoint, keeping the request path.
+func (c *Caller) WithEndpoint(endpoint string) *Caller {
+	cp := *c
+	cp.endpoint = strings.TrimRight(endpoint, "/")
+	return &cp
+}
+
+// Token returns a current access token, e.g. to authenticate to a GKE API server.
+func (c *Caller) Token() (string, error) {
+	tok, err := c.tokens.Token()
+	if err != nil {
+		return "", err
+	}
+	return tok.AccessToken, nil
+}
+
+// List follows nextPageToken pagination of a list method at rawURL, decoding
+// the items under field from every page into out (a pointer to a slice).
+func (c *Caller) List(ctx context.Context, rawURL, field string, out any) error {
+	base, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("parse URL: %w", err)
+	}
+	var all []json.RawMessage
+	token := ""
+	for {
+		q := base.Query()
+		q.Set("pageSize", pageSize)
+		if token != "" {
+			q.Set("pageToken", token)
+		}
+		page := *base
+		page.RawQuery = q.Encode()
+
+		var body map[string]json.RawMessage
+		if err := c.Get(ctx, page.String(), &body); err != nil {
+			return err
+		}
+		var items []json.RawMessage
+		if raw, ok := body[field]; ok {
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return fmt.Errorf("decode %s: %w", field, err)
+			}
+		}
+		all = append(all, items...)
+		token = ""
+		if raw, ok := body["nextPageToken"]; ok {
+			_ = json.Unmarshal(raw, &token)
+		}
+		if token == "" {
+			break
+		}
+	}
+	data, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, out)
+}
+
+// Get fetches rawURL and decodes the JSON response into out.
+func (c *Caller) Get(ctx context.Context, rawURL string, out any) error {
+	return c.do(ctx, http.MethodGet, rawURL, nil, out)
+}
+
+// Post sends in as a JSON request body to rawURL and decodes the JSON
+// response into out, for methods such as Cloud Logging entries:list that
+// take their parameters in the body.
+func (c *Caller) Post(ctx context.Context, rawURL string, in, out any) error {
+	data, err := json.Marshal(in)
+	if err != nil {
+		return fmt.Errorf("encode request: %w", err)
+	}
+	return c.do(ctx, http.MethodPost, rawURL, data, out)
+}
+
+// Delete sends a DELETE to rawURL and decodes the JSON response (such as a
+// long-running operation) into out.
+func (c *Caller) Delete(ctx context.Context, rawURL string, out any) error {
+	return c.do(ctx, http.MethodDelete, rawURL, nil, out)
+}
+
+// Upload sends size bytes of body to rawURL as a single media upload, such
+// as a Cloud Storage objects.insert with uploadType=media.
+func (c *Caller) Upload(ctx context.Context, rawURL, contentType string, body io.Reader, size int64) error {
+	resp, err := c.send(ctx, http.MethodPost, rawURL, contentType, body, size)
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	return nil
+}
+
+// Download returns the raw response body of rawURL, such as a Cloud Storage
+// object fetched with alt=media. The caller closes it.
+func (c *Caller) Download(ctx context.Context, rawURL string) (io.ReadCloser, error) {
+	resp, err := c.send(ctx, http.MethodGet, rawURL, "", nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (c *Caller) do(ctx context.Context, method, rawURL string, payload []byte, out any) error {
+	var (
+		reqBody     io.Reader
+		contentType string
+	)
+	if payload != nil {
+		reqBody, contentType = bytes.NewReader(payload), "application/json"
+	}
+	resp, err := c.send(ctx, method, rawURL, contentType, reqBody, int64(len(payload)))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(body, out)
+}
+
+// send issues the request and returns the response of a 200 status with its
+// body still open. Requests to a project's resources are paced by the
+// project's Limiter and retried when denied for quota, unless their body
+// cannot be rewound.
+func (c *Caller) send(ctx context.Context, method, rawURL, contentType string, body io.Reader, size int64) (*http.Response, error) {
+	project := projectOf(rawURL)
+	seeker, rewindable := body.(io.Seeker)
+	if project == "" || (body != nil && !rewindable) {
+		return c.sendOnce(ctx, method, rawURL, contentType, body, size)
+	}
+	var resp *http.Response
+	err := ProjectLimiter(project).Retry(ctx, func() error {
+		if rewindable {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+		}
+		var err error
+		resp, err = c.sendOnce(ctx, method, rawURL, contentType, body, size)
+		return err
+	}, IsQuotaDenial)
+	return resp, err
+}
+
+func (c *Caller) sendOnce(ctx context.Context, method, rawURL, contentType string, body io.Reader, size int64) (*http.Response, error) {
+	if c.endpoint != "" {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			return nil, fmt.Errorf("parse URL: %w", err)
+		}
+		u.Scheme, u.Host = "", ""
+		rawURL = c.endpoint + u.String()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		apistats.Record(false)
+		return nil, err
+	}
+	apistats.Record(apistats.IsThrottleStatus(resp.StatusCode))
+	if resp.StatusCode != http.StatusOK {
+		defer func() { _ = resp.Body.Close() }()
+		data, _ := io.ReadAll(resp.Body)
+		return nil, &StatusError{Code: resp.StatusCode, Body: strings.TrimSpace(string(data))}
+	}
+	return resp, nil
+}
+
+// StatusError is returned for non-200 responses.
+type StatusError struct {
+	Code int
+	Body string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("HTTP %d: %s", e.Code, e.Body)
+}
@@ -0,0 +1,244 @@
+package gcpapi
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+func newTestCaller(t *testing.T, handler http.HandlerFunc) *Caller {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	return NewCallerFromTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "tok"})).WithEndpoint(srv.URL)
+}
+
+func TestListFollowsPages(t *testing.T) {
+	c := newTestCaller(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("missing bearer token")
+		}
+		if r.URL.Query().Get("filter") != "x" {
+			t.Errorf("query lost: %s", r.URL.RawQuery)
+		}
+		if r.URL.Query().Get("pageToken") == "" {
+			_, _ = w.Write([]byte(`{"items":[{"n":1}],"nextPageToken":"p2"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"items":[{"n":2}]}`))
+	})
+
+	var items []struct{ N int }
+	if err := c.List(context.Background(), "https://example.googleapis.com/v1/things?filter=x", "items", &items); err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[1].N != 2 {
+		t.Errorf("items = %v", items)
+	}
+}
+
+func TestGetStatusError(t *testing.T) {
+	c := newTestCaller(t, func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "denied", http.StatusForbidden)
+	})
+	err := c.Get(context.Background(), "https://example.googleapis.com/v1/x", &struct{}{})
+	se, ok := err.(*StatusError)
+	if !ok || se.Code != http.StatusForbidden || !strings.Contains(err.Error(), "denied") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestProjectsRecursesFolders(t *testing.T) {
+	c := newTestCaller(t, func(w http.ResponseWriter, r *http.Request) {
+		parent := r.URL.Query().Get("parent")
+		switch r.URL.Path + " " + parent {
+		case "/v3/projects organizations/1":
+			_, _ = w.Write([]byte(`{"projects":[{"projectId":"root-app","state":"ACTIVE"},{"projectId":"gone","state":"DELETE_REQUESTED"}]}`))
+		case "/v3/folders organizations/1":
+			_, _ = w.Write([]byte(`{"folders":[{"name":"folders/2","state":"ACTIVE"}]}`))
+		case "/v3/projects folders/2":
+			_, _ = w.Write([]byte(`{"projects":[{"projectId":"team-app","state":"ACTIVE"}]}`))
+		case "/v3/folders folders/2":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			http.NotFound(w, r)
+		}
+	})
+
+	projects, err := c.Projects(context.Background(), "organizations/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(projects, ",") != "root-app,team-app" {
+		t.Errorf("projects = %v", projects)
+	}
+}
+
+func TestServiceEnabled(t *testing.T) {
+	c := newTestCaller(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/projects/on/services/" + ArtifactRegistryService:
+			_, _ = w.Write([]byte(`{"state":"ENABLED"}`))
+		case "/v1/projects/off/services/" + ArtifactRegistryService:
+			_, _ = w.Write([]byte(`{"state":"DISABLED"}`))
+		case "/v1/projects/denied/services/" + ArtifactRegistryService:
+			http.Error(w, "denied", http.StatusForbidden)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+
+	ctx := context.Background()
+	for project, want := range map[string]bool{"on": true, "off": false, "missing": false} {
+		got, err := c.ServiceEnabled(ctx, project, ArtifactRegistryService)
+		if err != nil || got != want {
+			t.Errorf("ServiceEnabled(%s) = %v, %v; want %v", project, got, err, want)
+		}
+	}
+	if _, err := c.ServiceEnabled(ctx, "denied", ArtifactRegistryService); err == nil {
+		t.Error("expected error when the service state cannot be read")
+	}
+}
+
+func TestUploadAndDownload(t *testing.T) {
+	var stored string
+	c := newTestCaller(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPost:
+			if r.URL.Query().Get("name") != "images/app.tar" {
+				t.Errorf("name = %q", r.URL.Query().Get("name"))
+			}
+			body, _ := io.ReadAll(r.Body)
+			stored = string(body)
+			_, _ = w.Write([]byte(`{}`))
+		case http.MethodGet:
+			_, _ = w.Write([]byte(stored))
+		}
+	})
+
+	ctx := context.Background()
+	if err := c.Upload(ctx, "https://storage.googleapis.com/upload/storage/v1/b/archive/o?uploadType=media&name=images%2Fapp.tar", "application/x-tar", strings.NewReader("blob"), 4); err != nil {
+		t.Fatalf("Upload() error: %v", err)
+	}
+	rc, err := c.Download(ctx, "https://storage.googleapis.com/storage/v1/b/archive/o/images%2Fapp.tar?alt=media")
+	if err != nil {
+		t.Fatalf("Download() error: %v", err)
+	}
+	defer func() { _ = rc.Close() }()
+	if body, _ := io.ReadAll(rc); string(body) != "blob" {
+		t.Errorf("body = %q, want blob", body)
+	}
+}
+
+func TestProjectLabels(t *testing.T) {
+	c := newTestCaller(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v3/projects/platform-prod" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		_, _ = w.Write([]byte(`{"projectId":"platform-prod","labels":{"env":"prod"}}`))
+	})
+	labels, err := c.ProjectLabels(context.Background(), "platform-prod")
+	if err != nil || labels["env"] != "prod" {
+		t.Errorf("ProjectLabels() = %v, %v", labels, err)
+	}
+}
+
+func TestSendRetriesQuotaDenials(t *testing.T) {
+	ResetQuota()
+	defer ResetQuota()
+	quotaBackoff = time.Millisecond
+	defer func() { quotaBackoff = time.Second }()
+
+	calls := 0
+	c := newTestCaller(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls <= 2 {
+			http.Error(w, `{"error":{"code":429,"status":"RESOURCE_EXHAUSTED"}}`, http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{"name":"ok"}`))
+	})
+	var out struct{ Name string }
+	if err := c.Get(context.Background(), "https://artifactregistry.googleapis.com/v1/projects/p1/locations/us/repositories", &out); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || out.Name != "ok" {
+		t.Errorf("calls = %d, out = %+v; want a success on the third attempt", calls, out)
+	}
+	stats := QuotaStats()
+	if len(stats) != 1 || stats[0].Project != "p1" || stats[0].Denials != 2 || stats[0].Retries != 2 {
+		t.Fatalf("QuotaStats() = %+v", stats)
+	}
+	// Two halvings, then one additive step.
+	if want := InitialRate/4 + 0.5; stats[0].Rate != want {
+		t.Errorf("rate = %v, want %v", stats[0].Rate, want)
+	}
+}
+
+func TestSendGivesUpOnQuota(t *testing.T) {
+	ResetQuota()
+	defer ResetQuota()
+	quotaBackoff = time.Millisecond
+	defer func() { quotaBackoff = time.Second }()
+
+	calls := 0
+	c := newTestCaller(t, func(w http.ResponseWriter, _ *http.Request) {
+		calls++
+		http.Error(w, "quota", http.StatusTooManyRequests)
+	})
+	err := c.Get(context.Background(), "https://example.googleapis.com/v1/projects/p2/things", &struct{}{})
+	if !IsQuotaDenial(err) || calls != MaxQuotaRetries+1 {
+		t.Errorf("err = %v after %d calls, want a quota denial after %d", err, calls, MaxQuotaRetries+1)
+	}
+
+	// Other errors and requests outside a project are not retried.
+	calls = 0
+	_ = c.Get(context.Background(), "https://example.googleapis.com/v1/things", &struct{}{})
+	if calls != 1 {
+		t.Errorf("request without a project sent %d times", calls)
+	}
+}
+
+func TestLimiterPaces(t *testing.T) {
+	l := &Limiter{rate: 100}
+	start := time.Now()
+	for range 6 {
+		if err := l.Wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Six requests at 100/s take at least five intervals of 10ms.
+	if elapsed := time.Since(start); elapsed < 45*time.Millisecond {
+		t.Errorf("6 requests took %v, want at least 50ms", elapsed)
+	}
+	l.Denied()
+	l.Denied()
+	if l.rate != 25 {
+		t.Errorf("rate after two denials = %v, want 25", l.rate)
+	}
+	for range 1000 {
+		l.Allowed()
+	}
+	if l.rate != MaxRate {
+		t.Errorf("rate = %v, want capped at %v", l.rate, MaxRate)
+	}
+}
+
+func TestProjectOf(t *testing.T) {
+	for url, want := range map[string]string{
+		"https://artifactregistry.googleapis.com/v1/projects/my-proj/locations/us": "my-proj",
+		"https://serviceusage.googleapis.com/v1/projects/p:batchGet":               "p",
+		"https://cloudresourcemanager.googleapis.com/v3/projects?parent=folders/1": "",
+	} {
+		if got := projectOf(url); got != want {
+			t.Errorf("projectOf(%s) = %q, want %q", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package gcpapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+)
+
+const (
+	resourceManagerURL = "https://cloudresourcemanager.googleapis.com/v3"
+	serviceUsageURL    = "https://serviceusage.googleapis.com/v1"
+)
+
+// ArtifactRegistryService is the service name of the Artifact Registry API.
+const ArtifactRegistryService = "artifactregistry.googleapis.com"
+
+// Projects returns the sorted IDs of active projects under parent
+// (folders/ID or organizations/ID), descending into nested folders.
+func (c *Caller) Projects(ctx context.Context, parent string) ([]string, error) {
+	seen := make(map[string]bool)
+	if err := c.collectProjects(ctx, parent, seen); err != nil {
+		return nil, err
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func (c *Caller) collectProjects(ctx context.Context, parent string, seen map[string]bool) error {
+	q := url.Values{"parent": {parent}}
+	var projects []struct {
+		ProjectID string `json:"projectId"`
+		State     string `json:"state"`
+	}
+	if err := c.List(ctx, resourceManagerURL+"/projects?"+q.Encode(), "projects", &projects); err != nil {
+		return fmt.Errorf("list projects in %s: %w", parent, err)
+	}
+	for _, p := range projects {
+		if p.State == "ACTIVE" {
+			seen[p.ProjectID] = true
+		}
+	}
+
+	var folders []struct {
+		Name  string `json:"name"`
+		State string `json:"state"`
+	}
+	if err := c.List(ctx, resourceManagerURL+"/folders?"+q.Encode(), "folders", &folders); err != nil {
+		return fmt.Errorf("list folders in %s: %w", parent, err)
+	}
+	for _, f := range folders {
+		if f.State != "ACTIVE" {
+			continue
+		}
+		if err := c.collectProjects(ctx, f.Name, seen); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ServiceEnabled reports whether service is enabled in project.
+func (c *Caller) ServiceEnabled(ctx context.Context, project, service string) (bool, error) {
+	var out struct {
+		State string `json:"state"`
+	}
+	u := fmt.Sprintf("%s/projects/%s/services/%s", serviceUsageURL, url.PathEscape(project), url.PathEscape(service))
+	if err := c.Get(ctx, u, &out); err != nil {
+		var se *StatusError
+		if errors.As(err, &se) && se.Code == http.StatusNotFound {
+			return false, nil
+		}
+		return false, fmt.Errorf("get %s state in %s: %w", service, project, err)
+	}
+	return out.State == "ENABLED", nil
+}
+
+// ProjectLabels returns the labels of project.
+func (c *Caller) ProjectLabels(ctx context.Context, project string) (map[string]string, error) {
+	var out struct {
+		Labels map[string]string `json:"labels"`
+	}
+	if err := c.Get(ctx, resourceManagerURL+"/projects/"+url.PathEscape(project), &out); err != nil {
+		return nil, fmt.Errorf("get labels of %s: %w", project, err)
+	}
+	return out.Labels, nil
+}
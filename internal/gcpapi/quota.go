@@ -0,0 +1,192 @@
+package gcpapi
+
+import (
+	"context"
+	"math/rand/v2"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Google Cloud API quotas, Artifact Registry's included, are per project.
+// Each project's requests are paced by its own Limiter, which backs off when
+// the project runs into its quota and speeds up again while requests pass,
+// so a multi-project, multi-location scan runs as fast as each project's
+// quota allows.
+const (
+	// InitialRate is the requests per second a project starts at.
+	InitialRate = 50.0
+	// MaxRate caps the requests per second of a project.
+	MaxRate = 200.0
+	// MinRate is the slowest pace a project is throttled down to.
+	MinRate = 1.0
+	// MaxQuotaRetries is how often a request denied for quota is retried.
+	MaxQuotaRetries = 5
+)
+
+// quotaBackoff is the wait before the first retry of a denied request. It
+// doubles with each further retry.
+var quotaBackoff = time.Second
+
+// maxQuotaBackoff caps the wait between retries.
+const maxQuotaBackoff = 30 * time.Second
+
+// Limiter paces the API requests of one project: additive increase while
+// requests pass, multiplicative decrease on every quota denial. It is safe
+// for concurrent use.
+type Limiter struct {
+	mu      sync.Mutex
+	rate    float64
+	next    time.Time
+	denials int
+	retries int
+}
+
+// Wait blocks until the limiter allows the next request or ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(time.Duration(float64(time.Second) / l.rate))
+	l.mu.Unlock()
+	return sleep(ctx, slot.Sub(now))
+}
+
+// Allowed records a request that passed, raising the rate a little.
+func (l *Limiter) Allowed() {
+	l.mu.Lock()
+	l.rate = min(l.rate+0.5, MaxRate)
+	l.mu.Unlock()
+}
+
+// Denied records a quota denial, halving the rate.
+func (l *Limiter) Denied() {
+	l.mu.Lock()
+	l.rate = max(l.rate/2, MinRate)
+	l.denials++
+	l.mu.Unlock()
+}
+
+// Retry waits for the limiter, then calls fn, and retries it with
+// exponential backoff for as long as denied reports its error as a quota
+// denial, at most MaxQuotaRetries times. It returns the last error.
+func (l *Limiter) Retry(ctx context.Context, fn func() error, denied func(error) bool) error {
+	backoff := quotaBackoff
+	for attempt := 0; ; attempt++ {
+		if err := l.Wait(ctx); err != nil {
+			return err
+		}
+		err := fn()
+		if err == nil || !denied(err) {
+			l.Allowed()
+			return err
+		}
+		l.Denied()
+		if attempt == MaxQuotaRetries {
+			return err
+		}
+		l.mu.Lock()
+		l.retries++
+		l.mu.Unlock()
+		// Full jitter keeps concurrent workers from retrying in lockstep.
+		if err := sleep(ctx, backoff/2+rand.N(backoff/2+1)); err != nil {
+			return err
+		}
+		backoff = min(backoff*2, maxQuotaBackoff)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+var (
+	limitersMu sync.Mutex
+	limiters   = make(map[string]*Limiter)
+)
+
+// ProjectLimiter returns the limiter shared by every request of project in
+// this process.
+func ProjectLimiter(project string) *Limiter {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	l := limiters[project]
+	if l == nil {
+		l = &Limiter{rate: InitialRate}
+		limiters[project] = l
+	}
+	return l
+}
+
+// QuotaStat is how often a project's requests were denied for quota and
+// retried, and the rate its limiter settled at.
+type QuotaStat struct {
+	Project string
+	Denials int
+	Retries int
+	Rate    float64
+}
+
+// QuotaStats returns the projects whose requests were denied for quota,
+// most denials first.
+func QuotaStats() []QuotaStat {
+	limitersMu.Lock()
+	defer limitersMu.Unlock()
+	var stats []QuotaStat
+	for project, l := range limiters {
+		l.mu.Lock()
+		if l.denials > 0 {
+			stats = append(stats, QuotaStat{Project: project, Denials: l.denials, Retries: l.retries, Rate: l.rate})
+		}
+		l.mu.Unlock()
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Denials != stats[j].Denials {
+			return stats[i].Denials > stats[j].Denials
+		}
+		return stats[i].Project < stats[j].Project
+	})
+	return stats
+}
+
+// ResetQuota forgets every project's limiter.
+func ResetQuota() {
+	limitersMu.Lock()
+	limiters = make(map[string]*Limiter)
+	limitersMu.Unlock()
+}
+
+// IsQuotaDenial reports whether err is a REST response denying a request
+// for quota: HTTP 429, or a RESOURCE_EXHAUSTED or rate limit status.
+func IsQuotaDenial(err error) bool {
+	se, ok := err.(*StatusError)
+	if !ok {
+		return false
+	}
+	return se.Code == 429 || strings.Contains(se.Body, "RESOURCE_EXHAUSTED") || strings.Contains(se.Body, "rateLimitExceeded")
+}
+
+var projectPath = regexp.MustCompile(`/projects/([^/:?]+)`)
+
+// projectOf returns the project named in a Google Cloud API URL, or "".
+func projectOf(rawURL string) string {
+	if m := projectPath.FindStringSubmatch(rawURL); m != nil {
+		return m[1]
+	}
+	return ""
+}
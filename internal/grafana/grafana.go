@@ -0,0 +1,289 @@
+// Package grafana generates a Grafana dashboard over the Prometheus gauges
+// written by --format prometheus.
+package grafana
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+)
+
+// DefaultTitle is the title of the dashboard.
+const DefaultTitle = "ecrspectre registry waste"
+
+// DefaultUID is the dashboard UID, stable so a re-import replaces the
+// dashboard instead of adding a copy.
+const DefaultUID = "ecrspectre-waste"
+
+// Options configures the dashboard.
+type Options struct {
+	Title string
+	UID   string
+	// Datasource preselects a Prometheus data source by name or UID; users
+	// can switch it with the dashboard's datasource variable.
+	Datasource string
+	// Top is the number of repositories in the waste-by-repository panels.
+	Top int
+}
+
+// Dashboard is the JSON model of a Grafana dashboard, as imported through
+// Dashboards > New > Import or provisioned from a file.
+type Dashboard struct {
+	UID           string     `json:"uid"`
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	Tags          []string   `json:"tags"`
+	Timezone      string     `json:"timezone"`
+	SchemaVersion int        `json:"schemaVersion"`
+	Version       int        `json:"version"`
+	Editable      bool       `json:"editable"`
+	Refresh       string     `json:"refresh"`
+	Time          TimeRange  `json:"time"`
+	Templating    Templating `json:"templating"`
+	Panels        []Panel    `json:"panels"`
+}
+
+// TimeRange is the default time range of the dashboard.
+type TimeRange struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Templating holds the dashboard variables.
+type Templating struct {
+	List []Variable `json:"list"`
+}
+
+// Variable is a dashboard variable.
+type Variable struct {
+	Name       string          `json:"name"`
+	Label      string          `json:"label"`
+	Type       string          `json:"type"`
+	Query      any             `json:"query"`
+	Datasource *DatasourceRef  `json:"datasource,omitempty"`
+	Current    *VariableOption `json:"current,omitempty"`
+	Refresh    int             `json:"refresh,omitempty"`
+	Multi      bool            `json:"multi,omitempty"`
+	IncludeAll bool            `json:"includeAll,omitempty"`
+	AllValue   string          `json:"allValue,omitempty"`
+	Sort       int             `json:"sort,omitempty"`
+}
+
+// VariableOption is the selected value of a variable.
+type VariableOption struct {
+	Text  any `json:"text"`
+	Value any `json:"value"`
+}
+
+// DatasourceRef points a panel or variable at a data source.
+type DatasourceRef struct {
+	Type string `json:"type"`
+	UID  string `json:"uid"`
+}
+
+// Panel is a dashboard panel.
+type Panel struct {
+	ID          int            `json:"id"`
+	Type        string         `json:"type"`
+	Title       string         `json:"title"`
+	Description string         `json:"description,omitempty"`
+	GridPos     GridPos        `json:"gridPos"`
+	Datasource  *DatasourceRef `json:"datasource,omitempty"`
+	Targets     []Target       `json:"targets,omitempty"`
+	FieldConfig FieldConfig    `json:"fieldConfig"`
+	Options     map[string]any `json:"options,omitempty"`
+}
+
+// GridPos places a panel on the dashboard's 24-column grid.
+type GridPos struct {
+	H int `json:"h"`
+	W int `json:"w"`
+	X int `json:"x"`
+	Y int `json:"y"`
+}
+
+// Target is a Prometheus query of a panel.
+type Target struct {
+	RefID        string         `json:"refId"`
+	Datasource   *DatasourceRef `json:"datasource"`
+	Expr         string         `json:"expr"`
+	LegendFormat string         `json:"legendFormat,omitempty"`
+	Instant      bool           `json:"instant,omitempty"`
+	Range        bool           `json:"range,omitempty"`
+	Format       string         `json:"format,omitempty"`
+}
+
+// FieldConfig sets the unit and display of a panel's values.
+type FieldConfig struct {
+	Defaults  FieldDefaults `json:"defaults"`
+	Overrides []any         `json:"overrides"`
+}
+
+// FieldDefaults are the field settings shared by every series of a panel.
+type FieldDefaults struct {
+	Unit       string      `json:"unit,omitempty"`
+	Min        *float64    `json:"min,omitempty"`
+	Thresholds *Thresholds `json:"thresholds,omitempty"`
+}
+
+// Thresholds color values by step.
+type Thresholds struct {
+	Mode  string `json:"mode"`
+	Steps []Step `json:"steps"`
+}
+
+// Step is one threshold; the first has a nil value.
+type Step struct {
+	Color string   `json:"color"`
+	Value *float64 `json:"value"`
+}
+
+// datasource is the reference every query uses: the dashboard's datasource
+// variable.
+var datasource = &DatasourceRef{Type: "prometheus", UID: "${datasource}"}
+
+// regionFilter limits the per-finding gauges to the selected regions.
+const regionFilter = `{region=~"$region"}`
+
+// New builds the dashboard: the current waste, findings and scan health in
+// stat panels, then the waste over time, the repositories with the most
+// waste, the findings by severity and the scan duration.
+func New(opts Options) Dashboard {
+	if opts.Title == "" {
+		opts.Title = DefaultTitle
+	}
+	if opts.UID == "" {
+		opts.UID = DefaultUID
+	}
+	if opts.Top <= 0 {
+		opts.Top = 10
+	}
+	top := strconv.Itoa(opts.Top)
+
+	dsVar := Variable{Name: "datasource", Label: "Data source", Type: "datasource", Query: "prometheus"}
+	if opts.Datasource != "" {
+		dsVar.Current = &VariableOption{Text: opts.Datasource, Value: opts.Datasource}
+	}
+	regionVar := Variable{
+		Name: "region", Label: "Region", Type: "query", Datasource: datasource,
+		Query: map[string]any{
+			"query": "label_values(ecrspectre_monthly_waste_dollars, region)",
+			"refId": "region",
+		},
+		Refresh: 2, Multi: true, IncludeAll: true, AllValue: ".*", Sort: 1,
+		Current: &VariableOption{Text: []string{"All"}, Value: []string{"$__all"}},
+	}
+
+	panels := []Panel{
+		stat(1, "Monthly waste", "Estimated monthly waste of the latest scans, each resource counted once.",
+			GridPos{H: 4, W: 6, X: 0, Y: 0}, "sum(ecrspectre_total_monthly_waste_dollars)", "currencyUSD", wasteSteps()),
+		stat(2, "Findings", "Reported findings in the selected regions.",
+			GridPos{H: 4, W: 6, X: 6, Y: 0}, "sum(ecrspectre_findings_total)", "short", nil),
+		stat(3, "Last scan", "Time since the newest scan finished. A growing value means the scheduled scan stopped running.",
+			GridPos{H: 4, W: 6, X: 12, Y: 0}, "time() - max(ecrspectre_scan_timestamp_seconds)", "s", staleSteps()),
+		stat(4, "Scan errors", "Regions, locations or repositories that failed to scan.",
+			GridPos{H: 4, W: 6, X: 18, Y: 0}, "sum(ecrspectre_scan_errors)", "short", errorSteps()),
+		timeseries(5, "Monthly waste over time", GridPos{H: 8, W: 12, X: 0, Y: 4}, "currencyUSD",
+			query("A", "sum(ecrspectre_total_monthly_waste_dollars)", "total"),
+			query("B", "sum by (finding) (ecrspectre_monthly_waste_dollars"+regionFilter+")", "{{finding}}")),
+		timeseries(6, "Findings by severity", GridPos{H: 8, W: 12, X: 12, Y: 4}, "short",
+			query("A", "sum by (severity) (ecrspectre_findings_total)", "{{severity}}")),
+		{
+			ID: 7, Type: "bargauge", Title: "Waste by repository",
+			Description: "Repositories with the most waste in the latest scans, summing their findings.",
+			GridPos:     GridPos{H: 10, W: 12, X: 0, Y: 12}, Datasource: datasource,
+			Targets: []Target{{
+				RefID: "A", Datasource: datasource, Instant: true, Format: "time_series",
+				Expr:         "topk(" + top + ", sum by (repo) (ecrspectre_monthly_waste_dollars" + regionFilter + "))",
+				LegendFormat: "{{repo}}",
+			}},
+			FieldConfig: FieldConfig{Defaults: FieldDefaults{Unit: "currencyUSD", Min: float(0)}, Overrides: []any{}},
+			Options: map[string]any{
+				"orientation":   "horizontal",
+				"displayMode":   "gradient",
+				"showUnfilled":  true,
+				"reduceOptions": map[string]any{"calcs": []string{"lastNotNull"}, "fields": "", "values": false},
+			},
+		},
+		timeseries(8, "Waste by repository over time", GridPos{H: 10, W: 12, X: 12, Y: 12}, "currencyUSD",
+			query("A", "topk("+top+", sum by (repo) (ecrspectre_monthly_waste_dollars"+regionFilter+"))", "{{repo}}")),
+		timeseries(9, "Scan duration", GridPos{H: 8, W: 12, X: 0, Y: 22}, "s",
+			query("A", "max(ecrspectre_scan_duration_seconds)", "duration")),
+		timeseries(10, "Scanned", GridPos{H: 8, W: 12, X: 12, Y: 22}, "short",
+			query("A", "sum(ecrspectre_repositories_scanned)", "repositories"),
+			query("B", "sum(ecrspectre_resources_scanned)", "images and repositories")),
+	}
+
+	return Dashboard{
+		UID:           opts.UID,
+		Title:         opts.Title,
+		Description:   "Container registry waste found by ecrspectre --format prometheus.",
+		Tags:          []string{"ecrspectre", "containers", "cost"},
+		Timezone:      "browser",
+		SchemaVersion: 39,
+		Version:       1,
+		Editable:      true,
+		Refresh:       "5m",
+		Time:          TimeRange{From: "now-30d", To: "now"},
+		Templating:    Templating{List: []Variable{dsVar, regionVar}},
+		Panels:        panels,
+	}
+}
+
+// Write renders the dashboard as indented JSON.
+func Write(w io.Writer, d Dashboard) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d)
+}
+
+func query(ref, expr, legend string) Target {
+	return Target{RefID: ref, Datasource: datasource, Expr: expr, LegendFormat: legend, Range: true}
+}
+
+func stat(id int, title, description string, pos GridPos, expr, unit string, thresholds *Thresholds) Panel {
+	return Panel{
+		ID: id, Type: "stat", Title: title, Description: description, GridPos: pos, Datasource: datasource,
+		Targets:     []Target{{RefID: "A", Datasource: datasource, Expr: expr, Instant: true}},
+		FieldConfig: FieldConfig{Defaults: FieldDefaults{Unit: unit, Thresholds: thresholds}, Overrides: []any{}},
+		Options: map[string]any{
+			"colorMode":     "value",
+			"graphMode":     "area",
+			"reduceOptions": map[string]any{"calcs": []string{"lastNotNull"}, "fields": "", "values": false},
+		},
+	}
+}
+
+func timeseries(id int, title string, pos GridPos, unit string, targets ...Target) Panel {
+	return Panel{
+		ID: id, Type: "timeseries", Title: title, GridPos: pos, Datasource: datasource,
+		Targets:     targets,
+		FieldConfig: FieldConfig{Defaults: FieldDefaults{Unit: unit, Min: float(0)}, Overrides: []any{}},
+		Options: map[string]any{
+			"legend":  map[string]any{"displayMode": "list", "placement": "bottom", "showLegend": true},
+			"tooltip": map[string]any{"mode": "multi", "sort": "desc"},
+		},
+	}
+}
+
+// steps colors values green below warn, yellow from warn and red from
+// critical.
+func steps(warn, critical float64) *Thresholds {
+	return &Thresholds{Mode: "absolute", Steps: []Step{
+		{Color: "green"},
+		{Color: "yellow", Value: float(warn)},
+		{Color: "red", Value: float(critical)},
+	}}
+}
+
+func wasteSteps() *Thresholds { return steps(100, 1000) }
+
+// staleSteps turn the last scan yellow after a day and red after two.
+func staleSteps() *Thresholds { return steps(86400, 172800) }
+
+// errorSteps turn any scan error red.
+func errorSteps() *Thresholds {
+	return &Thresholds{Mode: "absolute", Steps: []Step{{Color: "green"}, {Color: "red", Value: float(1)}}}
+}
+
+func float(v float64) *float64 { return &v }
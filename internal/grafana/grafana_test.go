@@ -0,0 +1,82 @@
+package grafana
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/analyzer"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+	"github.com/ppiankov/ecrspectre/internal/report"
+)
+
+func TestWriteIsValidJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Write(&buf, New(Options{Datasource: "prom", Top: 5})); err != nil {
+		t.Fatal(err)
+	}
+	var d map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &d); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	if d["title"] != DefaultTitle || d["uid"] != DefaultUID {
+		t.Errorf("title/uid = %v/%v", d["title"], d["uid"])
+	}
+	if !strings.Contains(buf.String(), `"uid": "${datasource}"`) {
+		t.Error("queries should use the datasource variable")
+	}
+	if !strings.Contains(buf.String(), "topk(5, ") {
+		t.Error("waste by repository should honor Top")
+	}
+	if !strings.Contains(buf.String(), `"value": "prom"`) {
+		t.Error("datasource should be preselected")
+	}
+}
+
+func TestPanelsHaveUniqueIDs(t *testing.T) {
+	seen := make(map[int]bool)
+	for _, p := range New(Options{}).Panels {
+		if seen[p.ID] {
+			t.Errorf("duplicate panel id %d", p.ID)
+		}
+		seen[p.ID] = true
+		if len(p.Targets) == 0 {
+			t.Errorf("panel %q has no queries", p.Title)
+		}
+	}
+}
+
+// TestQueriesUseExportedMetrics keeps the dashboard in step with the
+// prometheus report format.
+func TestQueriesUseExportedMetrics(t *testing.T) {
+	var buf bytes.Buffer
+	reporter := &report.PrometheusReporter{Writer: &buf}
+	err := reporter.Generate(report.Data{
+		Timestamp: time.Now(),
+		Duration:  time.Second,
+		Findings: []registry.Finding{{
+			ID: registry.FindingUntaggedImage, Severity: registry.SeverityHigh,
+			ResourceID: "sha256:abc", Region: "us-east-1", EstimatedMonthlyWaste: 1,
+			Metadata: map[string]any{"repository": "app"},
+		}},
+		Summary: analyzer.Summary{},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	exported := buf.String()
+
+	metric := regexp.MustCompile(`ecrspectre_[a-z_]+`)
+	for _, p := range New(Options{}).Panels {
+		for _, target := range p.Targets {
+			for _, name := range metric.FindAllString(target.Expr, -1) {
+				if !strings.Contains(exported, "\n"+name+" ") && !strings.Contains(exported, "\n"+name+"{") {
+					t.Errorf("panel %q queries %s, which the prometheus format does not export", p.Title, name)
+				}
+			}
+		}
+	}
+}
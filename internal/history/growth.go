@@ -0,0 +1,49 @@
+package history
+
+import "time"
+
+// growthWindow bounds the scans the waste growth is fitted to, so a
+// clean-up long ago does not flatten today's trend.
+const growthWindow = 365 * 24 * time.Hour
+
+// daysPerMonth converts daily growth to monthly, a month being a twelfth of
+// a 365-day year as in the cost estimates.
+const daysPerMonth = 365.0 / 12
+
+// WasteGrowth fits a least-squares line through the total monthly waste of
+// the past year's scans and the current scan and returns its slope in
+// dollars of monthly waste per month, the number of scans used and the time
+// of the oldest. ok is false without a past scan at least a day older than
+// now.
+func WasteGrowth(past []Record, now time.Time, current float64) (perMonth float64, scans int, since time.Time, ok bool) {
+	type point struct{ days, waste float64 }
+	var points []point
+	for _, r := range past {
+		age := now.Sub(r.Timestamp)
+		if age <= 0 || age > growthWindow {
+			continue
+		}
+		if since.IsZero() || r.Timestamp.Before(since) {
+			since = r.Timestamp
+		}
+		points = append(points, point{-age.Hours() / 24, r.TotalMonthlyWaste})
+	}
+	if len(points) == 0 || now.Sub(since) < 24*time.Hour {
+		return 0, 0, time.Time{}, false
+	}
+	points = append(points, point{0, current})
+
+	var sumX, sumY float64
+	for _, p := range points {
+		sumX += p.days
+		sumY += p.waste
+	}
+	n := float64(len(points))
+	meanX, meanY := sumX/n, sumY/n
+	var cov, variance float64
+	for _, p := range points {
+		cov += (p.days - meanX) * (p.waste - meanY)
+		variance += (p.days - meanX) * (p.days - meanX)
+	}
+	return cov / variance * daysPerMonth, len(points), since, true
+}
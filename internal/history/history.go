@@ -0,0 +1,133 @@
+// Package history persists a compact record of every scan so later scans can
+// detect storage anomalies and show trends.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// RecordSchema identifies the history record format.
+const RecordSchema = "ecrspectre-history/v1"
+
+// recordTimeFormat names record files so they sort chronologically.
+const recordTimeFormat = "20060102T150405Z"
+
+// Record is the persisted summary of one scan.
+type Record struct {
+	Schema            string                        `json:"schema"`
+	Timestamp         time.Time                     `json:"timestamp"`
+	RunID             string                        `json:"run_id,omitempty"`
+	Provider          string                        `json:"provider"`
+	Target            string                        `json:"target"`
+	TotalFindings     int                           `json:"total_findings"`
+	TotalMonthlyWaste float64                       `json:"total_monthly_waste"`
+	Repositories      map[string]registry.RepoUsage `json:"repositories"`
+	// FindingsByType and RepositoryWaste break the totals down by finding
+	// type and by repository. Records written before they existed omit them.
+	FindingsByType  map[string]int     `json:"findings_by_type,omitempty"`
+	RepositoryWaste map[string]float64 `json:"repository_waste,omitempty"`
+	// Findings is the monthly waste of each finding, keyed by FindingKey, so
+	// scans can be compared finding by finding. Older records omit it.
+	Findings map[string]float64 `json:"findings,omitempty"`
+}
+
+// FindingKey identifies a finding across scans: its type, region and
+// resource.
+func FindingKey(f registry.Finding) string {
+	return string(f.ID) + "|" + f.Region + "|" + f.ResourceID
+}
+
+// Store keeps the records of one scan target in a directory of JSON files.
+type Store struct {
+	dir string
+}
+
+// Open returns the store for target (a target URI hash) under dir. The
+// directory is created on the first Append.
+func Open(dir, target string) *Store {
+	hash := strings.TrimPrefix(target, "sha256:")
+	if len(hash) > 16 {
+		hash = hash[:16]
+	}
+	return &Store{dir: filepath.Join(dir, hash)}
+}
+
+// Load returns all records in chronological order. A missing store has no records.
+func (s *Store) Load() ([]Record, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+
+	var records []Record
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".json" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(s.dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read history record: %w", err)
+		}
+		var r Record
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("parse history record %s: %w", e.Name(), err)
+		}
+		if r.Schema != RecordSchema {
+			continue
+		}
+		records = append(records, r)
+	}
+	sort.Slice(records, func(i, j int) bool { return records[i].Timestamp.Before(records[j].Timestamp) })
+	return records, nil
+}
+
+// LoadAll returns the records of every target under dir, keyed by the
+// target's store directory name.
+func LoadAll(dir string) (map[string][]Record, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+	all := make(map[string][]Record)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		records, err := (&Store{dir: filepath.Join(dir, e.Name())}).Load()
+		if err != nil {
+			return nil, err
+		}
+		if len(records) > 0 {
+			all[e.Name()] = records
+		}
+	}
+	return all, nil
+}
+
+// Append writes r as a new record.
+func (s *Store) Append(r Record) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("create history directory: %w", err)
+	}
+	r.Schema = RecordSchema
+	data, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("encode history record: %w", err)
+	}
+	name := r.Timestamp.UTC().Format(recordTimeFormat) + ".json"
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, 0o600); err != nil {
+		return fmt.Errorf("write history record: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package history
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+const gb = int64(1 << 30)
+
+var t0 = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func record(day int, sizes map[string]int64) Record {
+	usage := make(map[string]registry.RepoUsage, len(sizes))
+	for repo, size := range sizes {
+		usage[repo] = registry.RepoUsage{Region: "us-east-1", SizeBytes: size}
+	}
+	return Record{Timestamp: t0.AddDate(0, 0, day), Provider: "aws", Repositories: usage}
+}
+
+func TestStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := Open(dir, "sha256:0123456789abcdef0123456789")
+
+	if records, err := store.Load(); err != nil || records != nil {
+		t.Fatalf("empty store: %v, %v", records, err)
+	}
+	for _, r := range []Record{record(7, map[string]int64{"api": 2}), record(0, map[string]int64{"api": 1})} {
+		if err := store.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "0123456789abcdef")); err != nil {
+		t.Errorf("expected per-target directory: %v", err)
+	}
+
+	records, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || !records[0].Timestamp.Before(records[1].Timestamp) {
+		t.Fatalf("records not in chronological order: %v", records)
+	}
+	if records[1].Schema != RecordSchema || records[1].Repositories["api"].SizeBytes != 2 {
+		t.Errorf("unexpected record %+v", records[1])
+	}
+}
+
+func usage(sizes map[string]int64) map[string]registry.RepoUsage {
+	return record(0, sizes).Repositories
+}
+
+func TestDetectSpikesPercent(t *testing.T) {
+	past := []Record{record(0, map[string]int64{"ci": 4 * gb, "steady": 10 * gb, "small": 100})}
+	current := usage(map[string]int64{"ci": 10 * gb, "steady": 11 * gb, "small": 900, "new": 50 * gb})
+
+	findings := DetectSpikes(past, current, "ecr", 0)
+	if len(findings) != 1 {
+		t.Fatalf("expected one STORAGE_SPIKE, got %v", findings)
+	}
+	f := findings[0]
+	if f.ID != registry.FindingStorageSpike || f.ResourceID != "ci" || f.Metadata["delta_bytes"] != 6*gb {
+		t.Errorf("unexpected finding %+v", f)
+	}
+	if f.EstimatedMonthlyWaste <= 0 {
+		t.Error("expected the growth to be priced")
+	}
+}
+
+func TestDetectSpikesSigma(t *testing.T) {
+	var past []Record
+	for i, size := range []int64{100, 101, 104, 105, 108} {
+		past = append(past, record(i, map[string]int64{"api": size * gb}))
+	}
+	// +12 GB is only 11% growth but far outside the usual 1-3 GB per scan.
+	findings := DetectSpikes(past, usage(map[string]int64{"api": 120 * gb}), "ecr", 50)
+	if len(findings) != 1 || findings[0].Metadata["sigma"] == nil {
+		t.Fatalf("expected a 3σ spike, got %v", findings)
+	}
+
+	if got := DetectSpikes(past, usage(map[string]int64{"api": 110 * gb}), "ecr", 50); len(got) != 0 {
+		t.Errorf("usual growth should not be reported, got %v", got)
+	}
+}
+
+func TestDetectSpikesKeepsRegionsApart(t *testing.T) {
+	scan := func(east, west int64) map[string]registry.RepoUsage {
+		r := &registry.ScanResult{}
+		r.RecordUsage("api", "us-east-1", east)
+		r.RecordUsage("api", "eu-west-1", west)
+		return registry.MergeProjectResults([]string{"prod", "dev"}, map[string]*registry.ScanResult{"prod": r, "dev": {}}).Usage
+	}
+	past := []Record{{Timestamp: t0, Repositories: scan(1*gb, 100*gb)}}
+
+	if got := DetectSpikes(past, scan(1*gb, 100*gb), "ecr", 0); len(got) != 0 {
+		t.Errorf("unchanged repositories of the same name should not spike, got %v", got)
+	}
+	findings := DetectSpikes(past, scan(10*gb, 100*gb), "ecr", 0)
+	if len(findings) != 1 {
+		t.Fatalf("expected one STORAGE_SPIKE, got %v", findings)
+	}
+	f := findings[0]
+	if f.ResourceID != "api" || f.Region != "us-east-1" || f.Metadata[registry.MetadataProject] != "prod" || f.Metadata["previous_bytes"] != 1*gb {
+		t.Errorf("unexpected finding %+v", f)
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	dir := t.TempDir()
+	for _, target := range []string{"sha256:aaaaaaaaaaaaaaaaaaaa", "sha256:bbbbbbbbbbbbbbbbbbbb"} {
+		if err := Open(dir, target).Append(record(0, map[string]int64{"api": 1})); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, err := LoadAll(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 || len(all["aaaaaaaaaaaaaaaa"]) != 1 {
+		t.Errorf("LoadAll() = %v", all)
+	}
+	if _, err := LoadAll(filepath.Join(dir, "missing")); err == nil {
+		t.Error("expected error for missing history directory")
+	}
+}
+
+func TestWasteGrowth(t *testing.T) {
+	const month = 730 * time.Hour // a twelfth of 365 days
+	now := t0.Add(2 * month)
+	past := []Record{
+		{Timestamp: t0.AddDate(-2, 0, 0), TotalMonthlyWaste: 1000}, // outside the window
+		{Timestamp: t0, TotalMonthlyWaste: 100},
+		{Timestamp: t0.Add(month), TotalMonthlyWaste: 110},
+	}
+	growth, scans, since, ok := WasteGrowth(past, now, 120)
+	if !ok || math.Abs(growth-10) > 1e-9 || scans != 3 || !since.Equal(t0) {
+		t.Errorf("WasteGrowth = %.4f, %d, %v, %v; want 10/mo over 3 scans since %v", growth, scans, since, ok, t0)
+	}
+
+	if _, _, _, ok := WasteGrowth(nil, now, 120); ok {
+		t.Error("growth without history")
+	}
+	if _, _, _, ok := WasteGrowth([]Record{{Timestamp: now.Add(-time.Hour), TotalMonthlyWaste: 1}}, now, 120); ok {
+		t.Error("growth from a scan less than a day old")
+	}
+}
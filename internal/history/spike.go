@@ -0,0 +1,125 @@
+package history
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/pricing"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+const (
+	// DefaultSpikePercent is the growth since the previous scan that is
+	// reported as STORAGE_SPIKE.
+	DefaultSpikePercent = 50
+	// MinSpikeBytes ignores growth too small to matter, however sudden.
+	MinSpikeBytes = 1 << 30
+	// minSigmaSamples is the number of past growth deltas needed before the
+	// 3σ rule applies.
+	minSigmaSamples = 3
+)
+
+// DetectSpikes compares current repository usage with the most recent
+// record containing each repository, matched by usage key so repositories
+// of the same name in other regions or projects are not compared. Growth of at least MinSpikeBytes is
+// reported when it exceeds spikePercent of the previous size or three
+// standard deviations above the repository's usual growth between scans.
+// pricingProvider is the pricing key ("ecr" or "artifactregistry").
+func DetectSpikes(past []Record, usage map[string]registry.RepoUsage, pricingProvider string, spikePercent float64) []registry.Finding {
+	if spikePercent <= 0 {
+		spikePercent = DefaultSpikePercent
+	}
+
+	keys := make([]string, 0, len(usage))
+	for key := range usage {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var findings []registry.Finding
+	for _, key := range keys {
+		cur := usage[key]
+		var sizes []int64
+		var prevTime time.Time
+		for _, r := range past {
+			if u, ok := r.Repositories[key]; ok {
+				sizes = append(sizes, u.SizeBytes)
+				prevTime = r.Timestamp
+			}
+		}
+		if len(sizes) == 0 {
+			continue
+		}
+		prev := sizes[len(sizes)-1]
+		delta := cur.SizeBytes - prev
+		if delta < MinSpikeBytes {
+			continue
+		}
+
+		growth := math.Inf(1)
+		if prev > 0 {
+			growth = float64(delta) / float64(prev) * 100
+		}
+		mean, stddev, ok := deltaStats(sizes)
+		sigma := 0.0
+		if ok && stddev > 0 {
+			sigma = (float64(delta) - mean) / stddev
+		}
+		if growth <= spikePercent && sigma <= 3 {
+			continue
+		}
+
+		metadata := map[string]any{
+			"previous_bytes": prev,
+			"current_bytes":  cur.SizeBytes,
+			"delta_bytes":    delta,
+			"previous_scan":  prevTime.Format(time.RFC3339),
+		}
+		growthText := "new storage"
+		if prev > 0 {
+			metadata["growth_percent"] = growth
+			growthText = fmt.Sprintf("+%.0f%%", growth)
+		}
+		if sigma > 0 {
+			metadata["sigma"] = sigma
+		}
+		for k, v := range cur.Metadata {
+			metadata[k] = v
+		}
+		repo := cur.Name(key)
+		findings = append(findings, registry.Finding{
+			ID:                    registry.FindingStorageSpike,
+			Severity:              registry.SeverityMedium,
+			ResourceType:          registry.ResourceRepository,
+			ResourceID:            repo,
+			Repository:            repo,
+			Region:                cur.Region,
+			Message:               fmt.Sprintf("Storage grew %.1f GB (%s) since %s", float64(delta)/(1<<30), growthText, prevTime.Format("2006-01-02")),
+			EstimatedMonthlyWaste: pricing.MonthlyStorageCost(pricingProvider, cur.Region, delta),
+			Metadata:              metadata,
+		})
+	}
+	return findings
+}
+
+// deltaStats returns the mean and standard deviation of the growth between
+// consecutive sizes, and false when there are too few samples.
+func deltaStats(sizes []int64) (mean, stddev float64, ok bool) {
+	if len(sizes) <= minSigmaSamples {
+		return 0, 0, false
+	}
+	deltas := make([]float64, 0, len(sizes)-1)
+	for i := 1; i < len(sizes); i++ {
+		d := float64(sizes[i] - sizes[i-1])
+		deltas = append(deltas, d)
+		mean += d
+	}
+	mean /= float64(len(deltas))
+	for _, d := range deltas {
+		stddev += (d - mean) * (d - mean)
+	}
+	stddev = math.Sqrt(stddev / float64(len(deltas)))
+	return mean, stddev, true
+}
@@ -0,0 +1,189 @@
+// Package imageref parses container image references such as
+// 123456789012.dkr.ecr.us-east-1.amazonaws.com/team/api:v1@sha256:... and
+// identifies the registry provider (ECR, Artifact Registry, Container
+// Registry, Docker Hub) and the account, project and location they encode.
+package imageref
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Provider identifies the registry service a reference points at.
+type Provider string
+
+const (
+	ProviderECR              Provider = "ecr"
+	ProviderECRPublic        Provider = "ecr-public"
+	ProviderArtifactRegistry Provider = "artifact-registry"
+	ProviderGCR              Provider = "gcr"
+	ProviderDockerHub        Provider = "docker-hub"
+	ProviderOther            Provider = "other"
+)
+
+// Reference is a parsed image reference.
+type Reference struct {
+	// Host is the registry host[:port]; empty for Docker Hub short names
+	// such as nginx or library/nginx.
+	Host       string   `json:"host,omitempty"`
+	Repository string   `json:"repository"`
+	Tag        string   `json:"tag,omitempty"`
+	Digest     string   `json:"digest,omitempty"`
+	Provider   Provider `json:"provider"`
+	// Account and Region are set for ECR references.
+	Account string `json:"account,omitempty"`
+	Region  string `json:"region,omitempty"`
+	// Project is set for Artifact Registry and Container Registry
+	// references, Location for Artifact Registry and regional gcr.io hosts,
+	// and ARRepository (the repository within the project) for Artifact
+	// Registry.
+	Project      string `json:"project,omitempty"`
+	Location     string `json:"location,omitempty"`
+	ARRepository string `json:"ar_repository,omitempty"`
+}
+
+var (
+	ecrHost   = regexp.MustCompile(`^(\d{12})\.dkr\.ecr(?:-fips)?\.([a-z0-9-]+)\.amazonaws\.com(?:\.cn)?$`)
+	arHost    = regexp.MustCompile(`^([a-z0-9-]+)-docker\.pkg\.dev$`)
+	gcrHost   = regexp.MustCompile(`^(?:([a-z]+)\.)?gcr\.io$`)
+	hostPart  = regexp.MustCompile(`^(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9])(?:\.(?:[a-zA-Z0-9]|[a-zA-Z0-9][a-zA-Z0-9-]*[a-zA-Z0-9]))*(?::[0-9]+)?$`)
+	pathPart  = regexp.MustCompile(`^[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*$`)
+	tagFormat = regexp.MustCompile(`^[\w][\w.-]{0,127}$`)
+	digestFmt = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(?:[-_+.][A-Za-z][A-Za-z0-9]*)*:[0-9a-fA-F]{32,}$`)
+	sha256Hex = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+)
+
+// maxNameLength is the longest host/repository name registries accept.
+const maxNameLength = 255
+
+// Split breaks ref into host, repository, tag and digest without
+// validating it, so it never fails. A leading scheme (Kubernetes reports
+// docker-pullable://repo@digest) is dropped, and a reference with neither
+// tag nor digest gets the latest tag.
+func Split(ref string) Reference {
+	var r Reference
+	if _, rest, ok := strings.Cut(ref, "://"); ok {
+		ref = rest
+	}
+	if name, digest, ok := strings.Cut(ref, "@"); ok {
+		ref, r.Digest = name, digest
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref, r.Tag = ref[:i], ref[i+1:]
+	}
+	if host, path, ok := strings.Cut(ref, "/"); ok && (strings.ContainsAny(host, ".:") || host == "localhost") {
+		r.Host, ref = host, path
+	}
+	r.Repository = ref
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+	r.identify()
+	return r
+}
+
+// Parse splits ref like Split and validates every part against the OCI
+// distribution reference grammar.
+func Parse(ref string) (Reference, error) {
+	if strings.TrimSpace(ref) == "" {
+		return Reference{}, fmt.Errorf("empty image reference")
+	}
+	// Split would read "app:" and "app@" as app:latest.
+	name := ref
+	if _, rest, ok := strings.Cut(name, "://"); ok {
+		name = rest
+	}
+	name, digest, ok := strings.Cut(name, "@")
+	if ok && digest == "" {
+		return Reference{}, fmt.Errorf("empty digest after '@' in %q", ref)
+	}
+	if strings.HasSuffix(name, ":") {
+		return Reference{}, fmt.Errorf("empty tag after ':' in %q", ref)
+	}
+	r := Split(ref)
+	if r.Host != "" && !hostPart.MatchString(r.Host) {
+		return Reference{}, fmt.Errorf("invalid registry host %q", r.Host)
+	}
+	if r.Repository == "" {
+		return Reference{}, fmt.Errorf("missing repository in %q", ref)
+	}
+	for _, part := range strings.Split(r.Repository, "/") {
+		if !pathPart.MatchString(part) {
+			return Reference{}, fmt.Errorf("invalid repository path component %q (lowercase letters, digits and . _ - separators only)", part)
+		}
+	}
+	if len(r.Name()) > maxNameLength {
+		return Reference{}, fmt.Errorf("repository name longer than %d characters", maxNameLength)
+	}
+	if r.Tag != "" && !tagFormat.MatchString(r.Tag) {
+		return Reference{}, fmt.Errorf("invalid tag %q", r.Tag)
+	}
+	if r.Digest != "" {
+		if !digestFmt.MatchString(r.Digest) {
+			return Reference{}, fmt.Errorf("invalid digest %q (want algorithm:hex)", r.Digest)
+		}
+		if strings.HasPrefix(r.Digest, "sha256:") && !sha256Hex.MatchString(r.Digest) {
+			return Reference{}, fmt.Errorf("invalid sha256 digest %q (want 64 lowercase hex characters)", r.Digest)
+		}
+	}
+	switch r.Provider {
+	case ProviderArtifactRegistry:
+		if r.ARRepository == "" {
+			return Reference{}, fmt.Errorf("artifact registry reference needs project/repository/image, got %q", r.Repository)
+		}
+	case ProviderGCR:
+		if r.Project == "" {
+			return Reference{}, fmt.Errorf("container registry reference needs project/image, got %q", r.Repository)
+		}
+	}
+	return r, nil
+}
+
+// identify fills in the provider and the identifiers its host and path encode.
+func (r *Reference) identify() {
+	segments := strings.Split(r.Repository, "/")
+	switch {
+	case r.Host == "" || r.Host == "docker.io" || r.Host == "index.docker.io" || r.Host == "registry-1.docker.io":
+		r.Provider = ProviderDockerHub
+	case r.Host == "public.ecr.aws":
+		r.Provider = ProviderECRPublic
+	case ecrHost.MatchString(r.Host):
+		m := ecrHost.FindStringSubmatch(r.Host)
+		r.Provider, r.Account, r.Region = ProviderECR, m[1], m[2]
+	case arHost.MatchString(r.Host):
+		r.Provider = ProviderArtifactRegistry
+		r.Location = arHost.FindStringSubmatch(r.Host)[1]
+		if len(segments) >= 3 {
+			r.Project, r.ARRepository = segments[0], segments[1]
+		}
+	case gcrHost.MatchString(r.Host):
+		r.Provider = ProviderGCR
+		r.Location = gcrHost.FindStringSubmatch(r.Host)[1]
+		if len(segments) >= 2 {
+			r.Project = segments[0]
+		}
+	default:
+		r.Provider = ProviderOther
+	}
+}
+
+// Name returns host/repository, or the repository alone without a host.
+func (r Reference) Name() string {
+	if r.Host == "" {
+		return r.Repository
+	}
+	return r.Host + "/" + r.Repository
+}
+
+// String returns the reference in canonical name[:tag][@digest] form.
+func (r Reference) String() string {
+	s := r.Name()
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
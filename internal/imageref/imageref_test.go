@@ -0,0 +1,107 @@
+package imageref
+
+import (
+	"strings"
+	"testing"
+)
+
+var hex64 = strings.Repeat("a", 64)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want Reference
+	}{
+		{
+			"123456789012.dkr.ecr.us-east-1.amazonaws.com/team/api:v1",
+			Reference{Host: "123456789012.dkr.ecr.us-east-1.amazonaws.com", Repository: "team/api", Tag: "v1", Provider: ProviderECR, Account: "123456789012", Region: "us-east-1"},
+		},
+		{
+			"123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com/api@sha256:" + hex64,
+			Reference{Host: "123456789012.dkr.ecr-fips.us-gov-west-1.amazonaws.com", Repository: "api", Digest: "sha256:" + hex64, Provider: ProviderECR, Account: "123456789012", Region: "us-gov-west-1"},
+		},
+		{
+			"123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn/api:v1",
+			Reference{Host: "123456789012.dkr.ecr.cn-north-1.amazonaws.com.cn", Repository: "api", Tag: "v1", Provider: ProviderECR, Account: "123456789012", Region: "cn-north-1"},
+		},
+		{
+			"public.ecr.aws/nginx/nginx:1.27",
+			Reference{Host: "public.ecr.aws", Repository: "nginx/nginx", Tag: "1.27", Provider: ProviderECRPublic},
+		},
+		{
+			"europe-west1-docker.pkg.dev/my-project/images/team/app:v3",
+			Reference{Host: "europe-west1-docker.pkg.dev", Repository: "my-project/images/team/app", Tag: "v3", Provider: ProviderArtifactRegistry, Project: "my-project", Location: "europe-west1", ARRepository: "images"},
+		},
+		{
+			"eu.gcr.io/my-project/app",
+			Reference{Host: "eu.gcr.io", Repository: "my-project/app", Tag: "latest", Provider: ProviderGCR, Project: "my-project", Location: "eu"},
+		},
+		{
+			"docker-pullable://registry:5000/api:v2@sha256:" + hex64,
+			Reference{Host: "registry:5000", Repository: "api", Tag: "v2", Digest: "sha256:" + hex64, Provider: ProviderOther},
+		},
+		{"nginx", Reference{Repository: "nginx", Tag: "latest", Provider: ProviderDockerHub}},
+		{"docker.io/library/nginx:1.27", Reference{Host: "docker.io", Repository: "library/nginx", Tag: "1.27", Provider: ProviderDockerHub}},
+		{"localhost/app:dev", Reference{Host: "localhost", Repository: "app", Tag: "dev", Provider: ProviderOther}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.ref)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.ref, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) =\n%+v\nwant\n%+v", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"", "empty"},
+		{"Team/API:v1", "invalid repository path component"},
+		{"host.example.com/app:-bad", "invalid tag"},
+		{"app@sha256:abc", "invalid digest"},
+		{"app@sha256:" + strings.Repeat("A", 64), "invalid sha256 digest"},
+		{"bad_host.example.com/app", "invalid registry host"},
+		{"us-docker.pkg.dev/project/app", "needs project/repository/image"},
+		{"gcr.io/app", "needs project/image"},
+		{"host.example.com/" + strings.Repeat("a", 256), "longer than"},
+		{"host.example.com//app", "invalid repository path component"},
+		{"app:", "empty tag after ':'"},
+		{"app@", "empty digest after '@'"},
+		{"registry:5000/app:@sha256:" + hex64, "empty tag after ':'"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.ref)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.ref, err, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	for _, ref := range []string{
+		"123456789012.dkr.ecr.us-east-1.amazonaws.com/team/api:v1@sha256:" + hex64,
+		"nginx:latest",
+		"us-docker.pkg.dev/p/r/img@sha256:" + hex64,
+	} {
+		r, err := Parse(ref)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.String() != ref {
+			t.Errorf("String() = %q, want %q", r.String(), ref)
+		}
+	}
+}
+
+func TestSplitIsLenient(t *testing.T) {
+	r := Split("Some/Odd_Path:tag")
+	if r.Repository != "Some/Odd_Path" || r.Tag != "tag" {
+		t.Errorf("Split = %+v", r)
+	}
+}
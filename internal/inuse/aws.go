@@ -0,0 +1,177 @@
+// Package inuse collects the container images currently deployed to compute
+// platforms, so findings for running images can be suppressed or downgraded.
+package inuse
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+
+	"github.com/ppiankov/ecrspectre/internal/awsapi"
+	"github.com/ppiankov/ecrspectre/internal/kube"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+const (
+	ecsTargetPrefix       = "AmazonEC2ContainerServiceV20141113."
+	ecsDescribeTasksLimit = 100
+	lambdaFunctionsPath   = "/2015-03-31/functions"
+)
+
+// AWS collects image references from running ECS tasks and Lambda functions.
+type AWS struct {
+	caller *awsapi.Caller
+}
+
+// NewAWS creates a collector that calls ECS and Lambda with caller.
+func NewAWS(caller *awsapi.Caller) *AWS {
+	return &AWS{caller: caller}
+}
+
+// Collect adds every ECS and Lambda image reference to set. Failures of one
+// source do not stop the others and are returned as messages.
+func (a *AWS) Collect(ctx context.Context, set *registry.InUse) []string {
+	var errs []string
+	if err := a.collectECS(ctx, set); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := a.collectLambda(ctx, set); err != nil {
+		errs = append(errs, err.Error())
+	}
+	return errs
+}
+
+type ecsContainer struct {
+	Image       string `json:"image"`
+	ImageDigest string `json:"imageDigest"`
+}
+
+func (a *AWS) collectECS(ctx context.Context, set *registry.InUse) error {
+	var clusters []string
+	token := ""
+	for {
+		var out struct {
+			ClusterArns []string `json:"clusterArns"`
+			NextToken   string   `json:"nextToken"`
+		}
+		in := map[string]any{}
+		if token != "" {
+			in["nextToken"] = token
+		}
+		if err := a.caller.JSON(ctx, "ecs", ecsTargetPrefix+"ListClusters", in, &out); err != nil {
+			return fmt.Errorf("list ECS clusters: %w", err)
+		}
+		clusters = append(clusters, out.ClusterArns...)
+		if out.NextToken == "" {
+			break
+		}
+		token = out.NextToken
+	}
+
+	for _, cluster := range clusters {
+		tasks, err := a.runningTasks(ctx, cluster)
+		if err != nil {
+			return err
+		}
+		for start := 0; start < len(tasks); start += ecsDescribeTasksLimit {
+			end := min(start+ecsDescribeTasksLimit, len(tasks))
+			var out struct {
+				Tasks []struct {
+					Group      string         `json:"group"`
+					Containers []ecsContainer `json:"containers"`
+				} `json:"tasks"`
+			}
+			in := map[string]any{"cluster": cluster, "tasks": tasks[start:end]}
+			if err := a.caller.JSON(ctx, "ecs", ecsTargetPrefix+"DescribeTasks", in, &out); err != nil {
+				return fmt.Errorf("describe ECS tasks in %s: %w", path.Base(cluster), err)
+			}
+			for _, t := range out.Tasks {
+				source := "ecs:" + path.Base(cluster) + "/" + t.Group
+				for _, c := range t.Containers {
+					set.Add(c.Image, source)
+					if c.ImageDigest != "" {
+						set.Add("@"+c.ImageDigest, source)
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (a *AWS) runningTasks(ctx context.Context, cluster string) ([]string, error) {
+	var tasks []string
+	token := ""
+	for {
+		var out struct {
+			TaskArns  []string `json:"taskArns"`
+			NextToken string   `json:"nextToken"`
+		}
+		in := map[string]any{"cluster": cluster, "desiredStatus": "RUNNING"}
+		if token != "" {
+			in["nextToken"] = token
+		}
+		if err := a.caller.JSON(ctx, "ecs", ecsTargetPrefix+"ListTasks", in, &out); err != nil {
+			return nil, fmt.Errorf("list ECS tasks in %s: %w", path.Base(cluster), err)
+		}
+		tasks = append(tasks, out.TaskArns...)
+		if out.NextToken == "" {
+			return tasks, nil
+		}
+		token = out.NextToken
+	}
+}
+
+func (a *AWS) collectLambda(ctx context.Context, set *registry.InUse) error {
+	marker := ""
+	for {
+		q := url.Values{}
+		if marker != "" {
+			q.Set("Marker", marker)
+		}
+		var out struct {
+			Functions []struct {
+				FunctionName string
+				PackageType  string
+			}
+			NextMarker string
+		}
+		if err := a.caller.Get(ctx, "lambda", lambdaFunctionsPath, q, &out); err != nil {
+			return fmt.Errorf("list Lambda functions: %w", err)
+		}
+		for _, fn := range out.Functions {
+			if fn.PackageType != "Image" {
+				continue
+			}
+			var detail struct {
+				Code struct {
+					ImageUri         string
+					ResolvedImageUri string
+				}
+			}
+			if err := a.caller.Get(ctx, "lambda", lambdaFunctionsPath+"/"+url.PathEscape(fn.FunctionName), nil, &detail); err != nil {
+				return fmt.Errorf("get Lambda function %s: %w", fn.FunctionName, err)
+			}
+			source := "lambda:" + fn.FunctionName
+			set.Add(detail.Code.ImageUri, source)
+			set.Add(detail.Code.ResolvedImageUri, source)
+		}
+		if out.NextMarker == "" {
+			return nil
+		}
+		marker = out.NextMarker
+	}
+}
+
+// CollectKube adds the images of running pods in the client's cluster to set.
+func CollectKube(ctx context.Context, client *kube.Client, set *registry.InUse) error {
+	images, err := client.RunningImages(ctx)
+	if err != nil {
+		return err
+	}
+	for _, img := range images {
+		set.Add(img.Image, fmt.Sprintf("k8s:%s/%s/%s", client.Context, img.Namespace, img.Pod))
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package inuse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/ppiankov/ecrspectre/internal/awsapi"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+func testCaller(url string) *awsapi.Caller {
+	return awsapi.NewCaller(aws.Config{
+		Region: "us-east-1",
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "SECRET"}, nil
+		}),
+	}).WithEndpoint(url)
+}
+
+func TestCollectAWS(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch target := r.Header.Get("X-Amz-Target"); {
+		case strings.HasSuffix(target, "ListClusters"):
+			_, _ = w.Write([]byte(`{"clusterArns":["arn:aws:ecs:us-east-1:1:cluster/prod"]}`))
+		case strings.HasSuffix(target, "ListTasks"):
+			_, _ = w.Write([]byte(`{"taskArns":["arn:aws:ecs:us-east-1:1:task/prod/t1"]}`))
+		case strings.HasSuffix(target, "DescribeTasks"):
+			_, _ = w.Write([]byte(`{"tasks":[{"group":"service:api","containers":[{"image":"1.dkr.ecr.us-east-1.amazonaws.com/api:v1","imageDigest":"sha256:api"}]}]}`))
+		case r.URL.Path == "/2015-03-31/functions":
+			_, _ = w.Write([]byte(`{"Functions":[{"FunctionName":"zip-fn","PackageType":"Zip"},{"FunctionName":"img-fn","PackageType":"Image"}]}`))
+		case r.URL.Path == "/2015-03-31/functions/img-fn":
+			_, _ = w.Write([]byte(`{"Code":{"ImageUri":"1.dkr.ecr.us-east-1.amazonaws.com/fn:latest","ResolvedImageUri":"1.dkr.ecr.us-east-1.amazonaws.com/fn@sha256:fn"}}`))
+		default:
+			t.Errorf("unexpected request %s %s", r.URL.Path, target)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	set := registry.NewInUse()
+	if errs := NewAWS(testCaller(srv.URL)).Collect(context.Background(), set); len(errs) != 0 {
+		t.Fatalf("Collect() errors: %v", errs)
+	}
+	if got := set.Lookup("api", "sha256:api", nil); len(got) != 1 || got[0] != "ecs:prod/service:api" {
+		t.Errorf("ECS digest lookup = %v", got)
+	}
+	if got := set.Lookup("fn", "sha256:fn", []string{"latest"}); len(got) != 1 || got[0] != "lambda:img-fn" {
+		t.Errorf("Lambda lookup = %v", got)
+	}
+}
+
+func TestCollectAWSPartialFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "" {
+			http.Error(w, `{"__type":"AccessDeniedException"}`, http.StatusForbidden)
+			return
+		}
+		_, _ = w.Write([]byte(`{"Functions":[]}`))
+	}))
+	defer srv.Close()
+
+	errs := NewAWS(testCaller(srv.URL)).Collect(context.Background(), registry.NewInUse())
+	if len(errs) != 1 || !strings.Contains(errs[0], "ECS") {
+		t.Errorf("expected only the ECS error, got %v", errs)
+	}
+}
@@ -0,0 +1,139 @@
+package inuse
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+	"github.com/ppiankov/ecrspectre/internal/kube"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+const (
+	cloudRunURL = "https://run.googleapis.com/v2"
+	gkeURL      = "https://container.googleapis.com/v1"
+)
+
+// GCP collects image references from serving Cloud Run revisions and the
+// running pods of GKE clusters in a project.
+type GCP struct {
+	api     *gcpapi.Caller
+	project string
+}
+
+// NewGCP creates a collector for project.
+func NewGCP(api *gcpapi.Caller, project string) *GCP {
+	return &GCP{api: api, project: project}
+}
+
+// Collect adds every Cloud Run and GKE image reference to set. Failures of
+// one source do not stop the others and are returned as messages.
+func (g *GCP) Collect(ctx context.Context, set *registry.InUse) []string {
+	var errs []string
+	if err := g.collectCloudRun(ctx, set); err != nil {
+		errs = append(errs, err.Error())
+	}
+	errs = append(errs, g.collectGKE(ctx, set)...)
+	return errs
+}
+
+type runContainer struct {
+	Image string `json:"image"`
+}
+
+func (g *GCP) collectCloudRun(ctx context.Context, set *registry.InUse) error {
+	var services []struct {
+		Name     string `json:"name"`
+		Template struct {
+			Containers []runContainer `json:"containers"`
+		} `json:"template"`
+		TrafficStatuses []struct {
+			Revision string `json:"revision"`
+			Percent  int    `json:"percent"`
+		} `json:"trafficStatuses"`
+		LatestReadyRevision string `json:"latestReadyRevision"`
+	}
+	u := fmt.Sprintf("%s/projects/%s/locations/-/services", cloudRunURL, url.PathEscape(g.project))
+	if err := g.api.List(ctx, u, "services", &services); err != nil {
+		return fmt.Errorf("list Cloud Run services: %w", err)
+	}
+
+	for _, svc := range services {
+		source := "cloudrun:" + path.Base(svc.Name)
+		for _, c := range svc.Template.Containers {
+			set.Add(c.Image, source)
+		}
+
+		serving := map[string]bool{path.Base(svc.LatestReadyRevision): true}
+		for _, t := range svc.TrafficStatuses {
+			if t.Percent > 0 && t.Revision != "" {
+				serving[t.Revision] = true
+			}
+		}
+		var revisions []struct {
+			Name       string         `json:"name"`
+			Containers []runContainer `json:"containers"`
+		}
+		if err := g.api.List(ctx, cloudRunURL+"/"+svc.Name+"/revisions", "revisions", &revisions); err != nil {
+			return fmt.Errorf("list Cloud Run revisions of %s: %w", path.Base(svc.Name), err)
+		}
+		for _, rev := range revisions {
+			if !serving[path.Base(rev.Name)] {
+				continue
+			}
+			for _, c := range rev.Containers {
+				set.Add(c.Image, source)
+			}
+		}
+	}
+	return nil
+}
+
+func (g *GCP) collectGKE(ctx context.Context, set *registry.InUse) []string {
+	var out struct {
+		Clusters []struct {
+			Name       string `json:"name"`
+			Location   string `json:"location"`
+			Endpoint   string `json:"endpoint"`
+			MasterAuth struct {
+				ClusterCACertificate string `json:"clusterCaCertificate"`
+			} `json:"masterAuth"`
+		} `json:"clusters"`
+	}
+	u := fmt.Sprintf("%s/projects/%s/locations/-/clusters", gkeURL, url.PathEscape(g.project))
+	if err := g.api.Get(ctx, u, &out); err != nil {
+		return []string{fmt.Sprintf("list GKE clusters: %v", err)}
+	}
+	if len(out.Clusters) == 0 {
+		return nil
+	}
+
+	token, err := g.api.Token()
+	if err != nil {
+		return []string{fmt.Sprintf("GKE token: %v", err)}
+	}
+	var errs []string
+	for _, c := range out.Clusters {
+		ca, err := base64.StdEncoding.DecodeString(c.MasterAuth.ClusterCACertificate)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("GKE cluster %s: decode CA: %v", c.Name, err))
+			continue
+		}
+		server := c.Endpoint
+		if !strings.Contains(server, "://") {
+			server = "https://" + server
+		}
+		client, err := kube.NewClient("gke_"+g.project+"_"+c.Location+"_"+c.Name, server, ca, token)
+		if err == nil {
+			err = CollectKube(ctx, client, set)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("GKE cluster %s: %v", c.Name, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,65 @@
+package inuse
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ppiankov/ecrspectre/internal/gcpapi"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+	"golang.org/x/oauth2"
+)
+
+func TestCollectGCP(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/v2/projects/p/locations/-/services":
+			if r.URL.Query().Get("pageToken") == "" {
+				_, _ = w.Write([]byte(`{"services":[{"name":"projects/p/locations/us-central1/services/api",
+					"template":{"containers":[{"image":"us-docker.pkg.dev/p/repo/api:v3"}]},
+					"latestReadyRevision":"projects/p/locations/us-central1/services/api/revisions/api-3",
+					"trafficStatuses":[{"revision":"api-2","percent":10},{"revision":"api-3","percent":90}]}],"nextPageToken":"more"}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		case r.URL.Path == "/v2/projects/p/locations/us-central1/services/api/revisions":
+			_, _ = w.Write([]byte(`{"revisions":[
+				{"name":"projects/p/locations/us-central1/services/api/revisions/api-1","containers":[{"image":"us-docker.pkg.dev/p/repo/api@sha256:one"}]},
+				{"name":"projects/p/locations/us-central1/services/api/revisions/api-2","containers":[{"image":"us-docker.pkg.dev/p/repo/api@sha256:two"}]}]}`))
+		case r.URL.Path == "/v1/projects/p/locations/-/clusters":
+			_, _ = w.Write([]byte(`{"clusters":[{"name":"prod","location":"us-central1","endpoint":"` + srv.URL + `"}]}`))
+		case r.URL.Path == "/api/v1/pods":
+			if r.Header.Get("Authorization") != "Bearer gke-token" {
+				t.Errorf("missing GKE bearer token")
+			}
+			_, _ = w.Write([]byte(`{"metadata":{},"items":[{"metadata":{"namespace":"default","name":"web-1"},"spec":{"containers":[{"image":"us-docker.pkg.dev/p/repo/web:v1"}]}}]}`))
+		default:
+			t.Errorf("unexpected request %s", r.URL)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	api := gcpapi.NewCallerFromTokenSource(oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "gke-token"})).WithEndpoint(srv.URL)
+	g := NewGCP(api, "p")
+	set := registry.NewInUse()
+	if errs := g.Collect(context.Background(), set); len(errs) != 0 {
+		t.Fatalf("Collect() errors: %v", errs)
+	}
+
+	if got := set.Lookup("p/repo/api", "sha256:two", nil); len(got) != 1 || got[0] != "cloudrun:api" {
+		t.Errorf("serving revision lookup = %v", got)
+	}
+	if got := set.Lookup("p/repo/api", "sha256:one", nil); got != nil {
+		t.Errorf("revision without traffic should not be in use, got %v", got)
+	}
+	if got := set.Lookup("p/repo/api", "", []string{"v3"}); got == nil {
+		t.Error("service template image should be in use")
+	}
+	if got := set.Lookup("p/repo/web", "", []string{"v1"}); len(got) != 1 || !strings.HasPrefix(got[0], "k8s:gke_p_us-central1_prod/") {
+		t.Errorf("GKE lookup = %v", got)
+	}
+}
@@ -0,0 +1,365 @@
+// Package kube lists the container images running in a Kubernetes cluster
+// using a kubeconfig, without depending on client-go.
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// podPageSize is the number of pods requested per list call.
+const podPageSize = 500
+
+// kubeconfig is the subset of the kubeconfig file format needed to connect.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string      `yaml:"token"`
+			TokenFile             string      `yaml:"tokenFile"`
+			ClientCertificate     string      `yaml:"client-certificate"`
+			ClientCertificateData string      `yaml:"client-certificate-data"`
+			ClientKey             string      `yaml:"client-key"`
+			ClientKeyData         string      `yaml:"client-key-data"`
+			Exec                  *execConfig `yaml:"exec"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// execConfig describes a credential plugin such as `aws eks get-token`.
+type execConfig struct {
+	APIVersion string   `yaml:"apiVersion"`
+	Command    string   `yaml:"command"`
+	Args       []string `yaml:"args"`
+	Env        []struct {
+		Name  string `yaml:"name"`
+		Value string `yaml:"value"`
+	} `yaml:"env"`
+}
+
+// Client talks to one cluster's API server.
+type Client struct {
+	// Context is the kubeconfig context the client was built from.
+	Context    string
+	server     string
+	httpClient *http.Client
+	token      string
+	exec       *execConfig
+}
+
+// DefaultPath returns $KUBECONFIG (first entry) or ~/.kube/config.
+func DefaultPath() string {
+	if env := os.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)[0]
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kube", "config")
+}
+
+// Load reads the kubeconfig at path and builds a client for the named context,
+// or the current context when contextName is empty.
+func Load(path, contextName string) (*Client, error) {
+	if path == "" {
+		path = DefaultPath()
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read kubeconfig: %w", err)
+	}
+	var kc kubeconfig
+	if err := yaml.Unmarshal(data, &kc); err != nil {
+		return nil, fmt.Errorf("parse kubeconfig %s: %w", path, err)
+	}
+	if contextName == "" {
+		contextName = kc.CurrentContext
+	}
+	return kc.client(contextName, filepath.Dir(path))
+}
+
+func (kc *kubeconfig) client(contextName, baseDir string) (*Client, error) {
+	var clusterName, userName string
+	found := false
+	for _, c := range kc.Contexts {
+		if c.Name == contextName {
+			clusterName, userName, found = c.Context.Cluster, c.Context.User, true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("kubeconfig context %q not found", contextName)
+	}
+
+	c := &Client{Context: contextName}
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	for _, cl := range kc.Clusters {
+		if cl.Name != clusterName {
+			continue
+		}
+		c.server = strings.TrimRight(cl.Cluster.Server, "/")
+		tlsCfg.InsecureSkipVerify = cl.Cluster.InsecureSkipTLSVerify
+		ca, err := dataOrFile(cl.Cluster.CertificateAuthorityData, cl.Cluster.CertificateAuthority, baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("load cluster CA: %w", err)
+		}
+		if len(ca) > 0 {
+			pool := x509.NewCertPool()
+			if !pool.AppendCertsFromPEM(ca) {
+				return nil, fmt.Errorf("cluster %s: invalid certificate authority", clusterName)
+			}
+			tlsCfg.RootCAs = pool
+		}
+	}
+	if c.server == "" {
+		return nil, fmt.Errorf("kubeconfig cluster %q not found", clusterName)
+	}
+
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		c.token = u.User.Token
+		if u.User.TokenFile != "" {
+			tok, err := os.ReadFile(resolvePath(u.User.TokenFile, baseDir))
+			if err != nil {
+				return nil, fmt.Errorf("read token file: %w", err)
+			}
+			c.token = strings.TrimSpace(string(tok))
+		}
+		c.exec = u.User.Exec
+		cert, err := dataOrFile(u.User.ClientCertificateData, u.User.ClientCertificate, baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		key, err := dataOrFile(u.User.ClientKeyData, u.User.ClientKey, baseDir)
+		if err != nil {
+			return nil, fmt.Errorf("load client key: %w", err)
+		}
+		if len(cert) > 0 && len(key) > 0 {
+			pair, err := tls.X509KeyPair(cert, key)
+			if err != nil {
+				return nil, fmt.Errorf("parse client certificate: %w", err)
+			}
+			tlsCfg.Certificates = []tls.Certificate{pair}
+		}
+	}
+
+	c.httpClient = &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{TLSClientConfig: tlsCfg, Proxy: http.ProxyFromEnvironment},
+	}
+	return c, nil
+}
+
+// NewClient builds a client for an API server reached with a bearer token,
+// such as a GKE cluster discovered through the GKE API. caPEM may be empty
+// to use the system roots.
+func NewClient(name, server string, caPEM []byte, token string) (*Client, error) {
+	tlsCfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if len(caPEM) > 0 {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, fmt.Errorf("cluster %s: invalid certificate authority", name)
+		}
+		tlsCfg.RootCAs = pool
+	}
+	return &Client{
+		Context: name,
+		server:  strings.TrimRight(server, "/"),
+		token:   token,
+		httpClient: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsCfg, Proxy: http.ProxyFromEnvironment},
+		},
+	}, nil
+}
+
+// dataOrFile returns base64-decoded inline data, or the contents of file.
+func dataOrFile(data, file, baseDir string) ([]byte, error) {
+	if data != "" {
+		return base64.StdEncoding.DecodeString(data)
+	}
+	if file != "" {
+		return os.ReadFile(resolvePath(file, baseDir))
+	}
+	return nil, nil
+}
+
+// resolvePath makes kubeconfig-relative paths absolute.
+func resolvePath(path, baseDir string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+// PodImage is an image reference used by a running pod.
+type PodImage struct {
+	Namespace string
+	Pod       string
+	Image     string
+}
+
+type podList struct {
+	Metadata struct {
+		Continue string `json:"continue"`
+	} `json:"metadata"`
+	Items []struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+			Name      string `json:"name"`
+		} `json:"metadata"`
+		Spec struct {
+			Containers     []struct{ Image string } `json:"containers"`
+			InitContainers []struct{ Image string } `json:"initContainers"`
+		} `json:"spec"`
+		Status struct {
+			ContainerStatuses []struct {
+				ImageID string `json:"imageID"`
+			} `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// RunningImages lists the images of running pods in all namespaces: the image
+// references from the pod specs plus the digests the kubelet resolved.
+func (c *Client) RunningImages(ctx context.Context) ([]PodImage, error) {
+	var images []PodImage
+	cont := ""
+	for {
+		q := url.Values{}
+		q.Set("fieldSelector", "status.phase=Running")
+		q.Set("limit", fmt.Sprint(podPageSize))
+		if cont != "" {
+			q.Set("continue", cont)
+		}
+		var page podList
+		if err := c.get(ctx, "/api/v1/pods?"+q.Encode(), &page); err != nil {
+			return nil, fmt.Errorf("list pods in %s: %w", c.Context, err)
+		}
+		for _, p := range page.Items {
+			add := func(ref string) {
+				if ref != "" {
+					images = append(images, PodImage{Namespace: p.Metadata.Namespace, Pod: p.Metadata.Name, Image: ref})
+				}
+			}
+			for _, ctr := range p.Spec.Containers {
+				add(ctr.Image)
+			}
+			for _, ctr := range p.Spec.InitContainers {
+				add(ctr.Image)
+			}
+			for _, st := range p.Status.ContainerStatuses {
+				if strings.Contains(st.ImageID, "@") {
+					add(st.ImageID)
+				}
+			}
+		}
+		if page.Metadata.Continue == "" {
+			return images, nil
+		}
+		cont = page.Metadata.Continue
+	}
+}
+
+func (c *Client) get(ctx context.Context, path string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.server+path, nil)
+	if err != nil {
+		return err
+	}
+	token := c.token
+	if c.exec != nil {
+		if token, err = c.execToken(ctx); err != nil {
+			return err
+		}
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, out)
+}
+
+// execToken runs the kubeconfig credential plugin and caches the token for
+// the lifetime of the client.
+func (c *Client) execToken(ctx context.Context) (string, error) {
+	if c.token != "" {
+		return c.token, nil
+	}
+	cmd := exec.CommandContext(ctx, c.exec.Command, c.exec.Args...)
+	cmd.Env = os.Environ()
+	for _, e := range c.exec.Env {
+		cmd.Env = append(cmd.Env, e.Name+"="+e.Value)
+	}
+	info, _ := json.Marshal(map[string]any{
+		"apiVersion": c.exec.APIVersion,
+		"kind":       "ExecCredential",
+		"spec":       map[string]any{"interactive": false},
+	})
+	cmd.Env = append(cmd.Env, "KUBERNETES_EXEC_INFO="+string(info))
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("run credential plugin %s: %w: %s", c.exec.Command, err, strings.TrimSpace(stderr.String()))
+	}
+	var cred struct {
+		Status struct {
+			Token string `json:"token"`
+		} `json:"status"`
+	}
+	if err := json.Unmarshal(out, &cred); err != nil {
+		return "", fmt.Errorf("parse credential plugin output: %w", err)
+	}
+	if cred.Status.Token == "" {
+		return "", fmt.Errorf("credential plugin %s returned no token", c.exec.Command)
+	}
+	c.token = cred.Status.Token
+	return c.token, nil
+}
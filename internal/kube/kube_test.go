@@ -0,0 +1,96 @@
+package kube
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeKubeconfig(t *testing.T, server string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	content := fmt.Sprintf(`apiVersion: v1
+kind: Config
+current-context: prod
+clusters:
+- name: prod-cluster
+  cluster:
+    server: %s
+contexts:
+- name: prod
+  context:
+    cluster: prod-cluster
+    user: prod-user
+users:
+- name: prod-user
+  user:
+    token: secret-token
+`, server)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRunningImages(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret-token" {
+			t.Errorf("missing bearer token")
+		}
+		if r.URL.Query().Get("fieldSelector") != "status.phase=Running" {
+			t.Errorf("fieldSelector = %q", r.URL.Query().Get("fieldSelector"))
+		}
+		if r.URL.Query().Get("continue") == "" {
+			_, _ = w.Write([]byte(`{"metadata":{"continue":"next"},"items":[{"metadata":{"namespace":"default","name":"api-1"},
+				"spec":{"containers":[{"image":"repo/api:v1"}],"initContainers":[{"image":"repo/init:v1"}]},
+				"status":{"containerStatuses":[{"imageID":"docker-pullable://repo/api@sha256:abc"}]}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"metadata":{},"items":[{"metadata":{"namespace":"jobs","name":"worker-1"},"spec":{"containers":[{"image":"repo/worker:v2"}]}}]}`))
+	}))
+	defer srv.Close()
+
+	client, err := Load(writeKubeconfig(t, srv.URL), "")
+	if err != nil {
+		t.Fatalf("Load() error: %v", err)
+	}
+	if client.Context != "prod" {
+		t.Errorf("Context = %q, want current context", client.Context)
+	}
+	images, err := client.RunningImages(context.Background())
+	if err != nil {
+		t.Fatalf("RunningImages() error: %v", err)
+	}
+	if len(images) != 4 {
+		t.Fatalf("expected 4 images across pages, got %v", images)
+	}
+	if images[3].Namespace != "jobs" || images[3].Image != "repo/worker:v2" {
+		t.Errorf("unexpected second page image %+v", images[3])
+	}
+}
+
+func TestLoadUnknownContext(t *testing.T) {
+	if _, err := Load(writeKubeconfig(t, "https://example.invalid"), "staging"); err == nil || !strings.Contains(err.Error(), "staging") {
+		t.Errorf("expected unknown context error, got %v", err)
+	}
+}
+
+func TestRunningImagesHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	client, err := Load(writeKubeconfig(t, srv.URL), "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.RunningImages(context.Background()); err == nil || !strings.Contains(err.Error(), "403") {
+		t.Errorf("expected HTTP 403 error, got %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+// Package leaderboard ranks teams or accounts by reclaimed and current registry waste.
+package leaderboard
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+	"github.com/ppiankov/ecrspectre/internal/report"
+)
+
+// Unattributed is the group name for findings without a value for the group key.
+const Unattributed = registry.Unattributed
+
+// Entry is one ranked row of the leaderboard.
+type Entry struct {
+	Rank             int     `json:"rank"`
+	Group            string  `json:"group"`
+	ReclaimedWaste   float64 `json:"reclaimed_monthly_waste"`
+	ResolvedFindings int     `json:"resolved_findings"`
+	CurrentWaste     float64 `json:"current_monthly_waste"`
+	CurrentFindings  int     `json:"current_findings"`
+}
+
+// Build compares the previous and current reports and ranks groups by waste
+// reclaimed since the previous scan, then by lowest current waste.
+// groupBy is "region" or a finding metadata key such as "team" or "owner".
+func Build(previous, current report.Data, groupBy string) []Entry {
+	byGroup := make(map[string]*Entry)
+	entry := func(group string) *Entry {
+		e, ok := byGroup[group]
+		if !ok {
+			e = &Entry{Group: group}
+			byGroup[group] = e
+		}
+		return e
+	}
+
+	currentKeys := make(map[string]bool, len(current.Findings))
+	for _, f := range current.Findings {
+		currentKeys[findingKey(f)] = true
+		e := entry(GroupKey(f, groupBy))
+		e.CurrentWaste += f.EstimatedMonthlyWaste
+		e.CurrentFindings++
+	}
+
+	for _, f := range previous.Findings {
+		if currentKeys[findingKey(f)] {
+			continue
+		}
+		e := entry(GroupKey(f, groupBy))
+		e.ReclaimedWaste += f.EstimatedMonthlyWaste
+		e.ResolvedFindings++
+	}
+
+	entries := make([]Entry, 0, len(byGroup))
+	for _, e := range byGroup {
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ReclaimedWaste != entries[j].ReclaimedWaste {
+			return entries[i].ReclaimedWaste > entries[j].ReclaimedWaste
+		}
+		if entries[i].CurrentWaste != entries[j].CurrentWaste {
+			return entries[i].CurrentWaste < entries[j].CurrentWaste
+		}
+		return entries[i].Group < entries[j].Group
+	})
+	for i := range entries {
+		entries[i].Rank = i + 1
+	}
+	return entries
+}
+
+// GroupKey returns the attribution group of a finding.
+func GroupKey(f registry.Finding, groupBy string) string {
+	return registry.GroupKey(f, groupBy)
+}
+
+func findingKey(f registry.Finding) string {
+	return fmt.Sprintf("%s|%s|%s", f.ID, f.Region, f.ResourceID)
+}
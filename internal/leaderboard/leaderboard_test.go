@@ -0,0 +1,101 @@
+package leaderboard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+	"github.com/ppiankov/ecrspectre/internal/report"
+)
+
+func finding(id, team string, waste float64) registry.Finding {
+	f := registry.Finding{
+		ID:                    registry.FindingStaleImage,
+		ResourceID:            id,
+		Region:                "us-east-1",
+		EstimatedMonthlyWaste: waste,
+	}
+	if team != "" {
+		f.Metadata = map[string]any{"team": team}
+	}
+	return f
+}
+
+func TestBuildRanksByReclaimedWaste(t *testing.T) {
+	previous := report.Data{Findings: []registry.Finding{
+		finding("a/1", "alpha", 10),
+		finding("a/2", "alpha", 5),
+		finding("b/1", "beta", 50),
+		finding("c/1", "", 1),
+	}}
+	current := report.Data{Findings: []registry.Finding{
+		finding("a/2", "alpha", 5),
+		finding("b/2", "beta", 3),
+	}}
+
+	entries := Build(previous, current, "team")
+
+	if len(entries) != 3 {
+		t.Fatalf("entries len = %d, want 3", len(entries))
+	}
+	if entries[0].Group != "beta" || entries[0].ReclaimedWaste != 50 {
+		t.Errorf("rank 1 = %+v, want beta with $50 reclaimed", entries[0])
+	}
+	if entries[1].Group != "alpha" || entries[1].ResolvedFindings != 1 || entries[1].CurrentWaste != 5 {
+		t.Errorf("rank 2 = %+v, want alpha with 1 resolved and $5 current", entries[1])
+	}
+	if entries[2].Group != Unattributed {
+		t.Errorf("rank 3 group = %q, want %q", entries[2].Group, Unattributed)
+	}
+	if entries[2].Rank != 3 {
+		t.Errorf("rank = %d, want 3", entries[2].Rank)
+	}
+}
+
+func TestBuildTieBreaksOnCurrentWaste(t *testing.T) {
+	current := report.Data{Findings: []registry.Finding{
+		finding("a/1", "alpha", 20),
+		finding("b/1", "beta", 2),
+	}}
+
+	entries := Build(report.Data{}, current, "team")
+
+	if entries[0].Group != "beta" {
+		t.Errorf("rank 1 = %q, want beta (lower current waste)", entries[0].Group)
+	}
+}
+
+func TestGroupKeyRegion(t *testing.T) {
+	f := finding("x", "alpha", 1)
+	if got := GroupKey(f, "region"); got != "us-east-1" {
+		t.Errorf("GroupKey(region) = %q, want us-east-1", got)
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	entries := []Entry{{Rank: 1, Group: "team|a", ReclaimedWaste: 12.5, CurrentWaste: 3}}
+	if err := WriteMarkdown(&buf, "team", entries); err != nil {
+		t.Fatalf("WriteMarkdown() error: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "| 1 | team\\|a | $12.50 |") {
+		t.Errorf("unexpected markdown:\n%s", out)
+	}
+}
+
+func TestWriteHTMLEscapes(t *testing.T) {
+	var buf bytes.Buffer
+	entries := []Entry{{Rank: 1, Group: "<script>", ReclaimedWaste: 1}}
+	if err := WriteHTML(&buf, "team", entries); err != nil {
+		t.Fatalf("WriteHTML() error: %v", err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "<script>") {
+		t.Error("group name should be HTML-escaped")
+	}
+	if !strings.Contains(out, "<table>") {
+		t.Error("missing table")
+	}
+}
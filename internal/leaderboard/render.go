@@ -0,0 +1,63 @@
+package leaderboard
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// WriteMarkdown renders the leaderboard as a Markdown table.
+func WriteMarkdown(w io.Writer, groupBy string, entries []Entry) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## ecrspectre waste leaderboard (by %s)\n\n", groupBy)
+	if len(entries) == 0 {
+		b.WriteString("No findings in either scan.\n")
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+	fmt.Fprintf(&b, "| Rank | %s | Reclaimed/mo | Resolved | Current waste/mo | Open findings |\n", groupBy)
+	b.WriteString("|-----:|------|-------------:|---------:|-----------------:|--------------:|\n")
+	for _, e := range entries {
+		fmt.Fprintf(&b, "| %d | %s | $%.2f | %d | $%.2f | %d |\n",
+			e.Rank, escapeMarkdown(e.Group), e.ReclaimedWaste, e.ResolvedFindings, e.CurrentWaste, e.CurrentFindings)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func escapeMarkdown(s string) string {
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+var htmlTemplate = template.Must(template.New("leaderboard").Funcs(template.FuncMap{
+	"usd": func(v float64) string { return fmt.Sprintf("$%.2f", v) },
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>ecrspectre waste leaderboard</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 10px; }
+td.num { text-align: right; }
+</style>
+</head>
+<body>
+<h1>ecrspectre waste leaderboard (by {{.GroupBy}})</h1>
+{{if .Entries}}<table>
+<tr><th>Rank</th><th>{{.GroupBy}}</th><th>Reclaimed/mo</th><th>Resolved</th><th>Current waste/mo</th><th>Open findings</th></tr>
+{{range .Entries}}<tr><td class="num">{{.Rank}}</td><td>{{.Group}}</td><td class="num">{{usd .ReclaimedWaste}}</td><td class="num">{{.ResolvedFindings}}</td><td class="num">{{usd .CurrentWaste}}</td><td class="num">{{.CurrentFindings}}</td></tr>
+{{end}}</table>{{else}}<p>No findings in either scan.</p>{{end}}
+</body>
+</html>
+`))
+
+// WriteHTML renders the leaderboard as a standalone HTML page.
+func WriteHTML(w io.Writer, groupBy string, entries []Entry) error {
+	return htmlTemplate.Execute(w, struct {
+		GroupBy string
+		Entries []Entry
+	}{groupBy, entries})
+}
@@ -1,17 +1,45 @@
 package logging
 
 import (
+	"io"
 	"log/slog"
 	"os"
 )
 
+var (
+	// base is the logger configured by Init, before any run ID is attached.
+	base  *slog.Logger
+	level slog.Level
+	runID string
+)
+
 // Init configures the default slog logger. Debug level is enabled when verbose is true.
 func Init(verbose bool) {
-	level := slog.LevelInfo
+	level = slog.LevelInfo
 	if verbose {
 		level = slog.LevelDebug
 	}
-	opts := &slog.HandlerOptions{Level: level}
-	handler := slog.NewTextHandler(os.Stderr, opts)
-	slog.SetDefault(slog.New(handler))
+	runID = ""
+	SetOutput(os.Stderr)
+}
+
+// SetRunID adds a run_id attribute to every later log record. Calling it
+// again replaces the ID rather than adding a second one.
+func SetRunID(id string) {
+	if base == nil {
+		base = slog.Default()
+	}
+	runID = id
+	slog.SetDefault(base.With("run_id", id))
+}
+
+// SetOutput sends later log records to w, keeping the level and run ID, e.g.
+// to hold them back while the scan dashboard owns the terminal.
+func SetOutput(w io.Writer) {
+	base = slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: level}))
+	if runID != "" {
+		slog.SetDefault(base.With("run_id", runID))
+		return
+	}
+	slog.SetDefault(base)
 }
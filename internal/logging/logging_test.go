@@ -1,6 +1,11 @@
 package logging
 
-import "testing"
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
 
 func TestInitVerbose(t *testing.T) {
 	// Smoke test: should not panic.
@@ -10,3 +15,32 @@ func TestInitVerbose(t *testing.T) {
 func TestInitQuiet(t *testing.T) {
 	Init(false)
 }
+
+func TestSetRunID(t *testing.T) {
+	var buf bytes.Buffer
+	base = slog.New(slog.NewTextHandler(&buf, nil))
+	defer Init(false)
+
+	SetRunID("first")
+	SetRunID("second")
+	slog.Info("scan")
+	out := buf.String()
+	if !strings.Contains(out, "run_id=second") || strings.Contains(out, "run_id=first") {
+		t.Errorf("log = %q, want only run_id=second", out)
+	}
+}
+
+func TestSetOutputKeepsRunID(t *testing.T) {
+	Init(false)
+	defer Init(false)
+	SetRunID("abc")
+
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	slog.Debug("hidden")
+	slog.Warn("shown")
+	out := buf.String()
+	if !strings.Contains(out, "msg=shown") || !strings.Contains(out, "run_id=abc") || strings.Contains(out, "hidden") {
+		t.Errorf("log = %q", out)
+	}
+}
@@ -0,0 +1,61 @@
+package oidc
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// googleIAM prefixes workload identity pool provider resource names.
+const googleIAM = "//iam.googleapis.com/"
+
+// GoogleProvider normalizes a workload identity pool provider to its
+// resource name, projects/N/locations/global/workloadIdentityPools/P/providers/X.
+func GoogleProvider(provider string) (string, error) {
+	name := strings.TrimPrefix(provider, googleIAM)
+	parts := strings.Split(name, "/")
+	if len(parts) != 8 || parts[0] != "projects" || parts[2] != "locations" || parts[4] != "workloadIdentityPools" || parts[6] != "providers" {
+		return "", fmt.Errorf("workload identity provider %q is not projects/N/locations/global/workloadIdentityPools/POOL/providers/PROVIDER", provider)
+	}
+	return name, nil
+}
+
+// GoogleAudience returns the token audience Google expects by default for a
+// normalized provider.
+func GoogleAudience(provider string) string {
+	return "https://iam.googleapis.com/" + provider
+}
+
+// GoogleCredentials returns an external_account credential configuration,
+// the format of GOOGLE_APPLICATION_CREDENTIALS, that exchanges the source's
+// tokens at a normalized provider for Google access tokens, impersonating
+// serviceAccount when set. The Google client libraries fetch a new token
+// whenever the access token expires: GitHub Actions sources are requested
+// from GitHub, others are read from tokenFile.
+func (s *Source) GoogleCredentials(provider, serviceAccount, tokenFile string) ([]byte, error) {
+	source := map[string]any{"file": tokenFile}
+	if s.Kind() == KindGitHub {
+		source = map[string]any{
+			"url":     s.GitHubRequestURL(),
+			"headers": map[string]string{"Authorization": "Bearer " + s.getenv(GitHubRequestTokenEnv)},
+			"format":  map[string]string{"type": "json", "subject_token_field_name": "value"},
+		}
+	}
+	cfg := map[string]any{
+		"type":               "external_account",
+		"audience":           googleIAM + provider,
+		"subject_token_type": "urn:ietf:params:oauth:token-type:jwt",
+		"token_url":          "https://sts.googleapis.com/v1/token",
+		"credential_source":  source,
+	}
+	if serviceAccount != "" {
+		cfg["service_account_impersonation_url"] = "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/" +
+			url.PathEscape(serviceAccount) + ":generateAccessToken"
+	}
+	data, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("encode Google credentials: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,154 @@
+// Package oidc supplies CI identity tokens (GitHub Actions, GitLab CI or a
+// token file) for AWS and GCP workload identity federation, so scans in CI
+// need no long-lived cloud keys.
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// GitHubRequestURLEnv and GitHubRequestTokenEnv are set by GitHub Actions
+	// in jobs with the id-token: write permission.
+	GitHubRequestURLEnv   = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	GitHubRequestTokenEnv = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
+	// TokenEnv holds an ID token declared under id_tokens in GitLab CI.
+	TokenEnv = "ECRSPECTRE_ID_TOKEN"
+)
+
+// Kinds of token source, in the order they are tried.
+const (
+	KindFile   = "file"
+	KindGitHub = "github"
+	KindEnv    = "env"
+)
+
+// Source fetches identity tokens from a token file, the GitHub Actions
+// token endpoint or the TokenEnv variable.
+type Source struct {
+	file       string
+	audience   string
+	getenv     func(string) string
+	httpClient *http.Client
+}
+
+// NewSource returns a source reading file, or when file is empty, requesting
+// tokens for audience from GitHub Actions or taking them from TokenEnv.
+func NewSource(file, audience string) *Source {
+	return &Source{file: file, audience: audience, getenv: os.Getenv, httpClient: http.DefaultClient}
+}
+
+// Kind returns where tokens come from, or "" when no source is available.
+func (s *Source) Kind() string {
+	switch {
+	case s.file != "":
+		return KindFile
+	case s.getenv(GitHubRequestURLEnv) != "" && s.getenv(GitHubRequestTokenEnv) != "":
+		return KindGitHub
+	case s.getenv(TokenEnv) != "":
+		return KindEnv
+	}
+	return ""
+}
+
+// Renews reports whether every token is newly issued. A token file may be
+// rotated by whoever wrote it, but is not assumed to be.
+func (s *Source) Renews() bool {
+	return s.Kind() == KindGitHub
+}
+
+// Token returns an identity token. Files are re-read on every call.
+func (s *Source) Token(ctx context.Context) (string, error) {
+	switch s.Kind() {
+	case KindFile:
+		data, err := os.ReadFile(s.file)
+		if err != nil {
+			return "", fmt.Errorf("read identity token: %w", err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	case KindGitHub:
+		return s.githubToken(ctx)
+	case KindEnv:
+		return strings.TrimSpace(s.getenv(TokenEnv)), nil
+	}
+	return "", fmt.Errorf("no identity token: set a token file, run in GitHub Actions with id-token: write, or set %s", TokenEnv)
+}
+
+// GetIdentityToken implements the AWS SDK's stscreds.IdentityTokenRetriever.
+func (s *Source) GetIdentityToken() ([]byte, error) {
+	token, err := s.Token(context.Background())
+	return []byte(token), err
+}
+
+// GitHubRequestURL returns the GitHub Actions token endpoint for the
+// source's audience.
+func (s *Source) GitHubRequestURL() string {
+	u := s.getenv(GitHubRequestURLEnv)
+	if s.audience == "" {
+		return u
+	}
+	sep := "?"
+	if strings.Contains(u, "?") {
+		sep = "&"
+	}
+	return u + sep + "audience=" + url.QueryEscape(s.audience)
+}
+
+func (s *Source) githubToken(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.GitHubRequestURL(), nil)
+	if err != nil {
+		return "", fmt.Errorf("build GitHub ID token request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+s.getenv(GitHubRequestTokenEnv))
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("request GitHub ID token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("read GitHub ID token: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("request GitHub ID token: HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	var out struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil || out.Value == "" {
+		return "", fmt.Errorf("decode GitHub ID token response")
+	}
+	return out.Value, nil
+}
+
+// Expiry returns the exp claim of a JWT. The signature is not verified: the
+// cloud's token exchange does that.
+func Expiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("identity token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("decode identity token: %w", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("decode identity token claims: %w", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, fmt.Errorf("identity token has no exp claim")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
@@ -0,0 +1,173 @@
+package oidc
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func env(vars map[string]string) func(string) string {
+	return func(key string) string { return vars[key] }
+}
+
+func jwt(exp int64) string {
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"exp":%d}`, exp)))
+	return "e30." + claims + ".sig"
+}
+
+func TestSourceKind(t *testing.T) {
+	github := map[string]string{GitHubRequestURLEnv: "https://gh/token?api-version=2.0", GitHubRequestTokenEnv: "req"}
+	tests := []struct {
+		file string
+		vars map[string]string
+		want string
+	}{
+		{"/tmp/token", github, KindFile},
+		{"", github, KindGitHub},
+		{"", map[string]string{GitHubRequestURLEnv: "https://gh/token"}, ""},
+		{"", map[string]string{TokenEnv: "tok"}, KindEnv},
+		{"", nil, ""},
+	}
+	for _, tt := range tests {
+		s := NewSource(tt.file, "aud")
+		s.getenv = env(tt.vars)
+		if got := s.Kind(); got != tt.want {
+			t.Errorf("Kind(file=%q, env=%v) = %q, want %q", tt.file, tt.vars, got, tt.want)
+		}
+	}
+}
+
+func TestSourceTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	s := NewSource(path, "")
+	if tok, err := s.Token(context.Background()); err != nil || tok != "first" {
+		t.Fatalf("Token = %q, %v", tok, err)
+	}
+	// A rotated file is picked up on the next call.
+	if err := os.WriteFile(path, []byte("second"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if tok, err := s.GetIdentityToken(); err != nil || string(tok) != "second" {
+		t.Errorf("GetIdentityToken = %q, %v", tok, err)
+	}
+	if s.Renews() {
+		t.Error("token file reported as renewing")
+	}
+}
+
+func TestSourceTokenGitHub(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer req" || r.URL.Query().Get("audience") != "sts.amazonaws.com" || r.URL.Query().Get("api-version") != "2.0" {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(`{"value":"gh-token"}`))
+	}))
+	defer srv.Close()
+
+	s := NewSource("", "sts.amazonaws.com")
+	s.getenv = env(map[string]string{GitHubRequestURLEnv: srv.URL + "/token?api-version=2.0", GitHubRequestTokenEnv: "req"})
+	if tok, err := s.Token(context.Background()); err != nil || tok != "gh-token" {
+		t.Fatalf("Token = %q, %v", tok, err)
+	}
+	if !s.Renews() {
+		t.Error("GitHub source not reported as renewing")
+	}
+
+	s.getenv = env(map[string]string{GitHubRequestURLEnv: srv.URL + "/token", GitHubRequestTokenEnv: "wrong"})
+	if _, err := s.Token(context.Background()); err == nil || !strings.Contains(err.Error(), "HTTP 400") {
+		t.Errorf("rejected request error = %v", err)
+	}
+}
+
+func TestSourceTokenMissing(t *testing.T) {
+	s := NewSource("", "")
+	s.getenv = env(nil)
+	if _, err := s.Token(context.Background()); err == nil || !strings.Contains(err.Error(), TokenEnv) {
+		t.Errorf("Token without a source error = %v", err)
+	}
+}
+
+func TestExpiry(t *testing.T) {
+	got, err := Expiry(jwt(1767225600))
+	if err != nil || !got.Equal(time.Unix(1767225600, 0)) {
+		t.Errorf("Expiry = %v, %v", got, err)
+	}
+	for _, bad := range []string{"opaque", "e30.!!.sig", "e30." + base64.RawURLEncoding.EncodeToString([]byte(`{}`)) + ".sig"} {
+		if _, err := Expiry(bad); err == nil {
+			t.Errorf("Expiry(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestGoogleProvider(t *testing.T) {
+	const name = "projects/123/locations/global/workloadIdentityPools/ci/providers/github"
+	for _, in := range []string{name, "//iam.googleapis.com/" + name} {
+		if got, err := GoogleProvider(in); err != nil || got != name {
+			t.Errorf("GoogleProvider(%q) = %q, %v", in, got, err)
+		}
+	}
+	if _, err := GoogleProvider("projects/123/providers/github"); err == nil {
+		t.Error("short provider accepted")
+	}
+	if got := GoogleAudience(name); got != "https://iam.googleapis.com/"+name {
+		t.Errorf("GoogleAudience = %q", got)
+	}
+}
+
+func TestGoogleCredentials(t *testing.T) {
+	const provider = "projects/123/locations/global/workloadIdentityPools/ci/providers/github"
+	decode := func(data []byte) map[string]any {
+		t.Helper()
+		var cfg map[string]any
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			t.Fatal(err)
+		}
+		return cfg
+	}
+
+	file := NewSource("/run/token", "")
+	data, err := file.GoogleCredentials(provider, "", "/run/token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := decode(data)
+	if cfg["type"] != "external_account" || cfg["audience"] != "//iam.googleapis.com/"+provider {
+		t.Errorf("credentials = %v", cfg)
+	}
+	if src := cfg["credential_source"].(map[string]any); src["file"] != "/run/token" {
+		t.Errorf("credential_source = %v", src)
+	}
+	if _, ok := cfg["service_account_impersonation_url"]; ok {
+		t.Error("impersonation without a service account")
+	}
+
+	github := NewSource("", GoogleAudience(provider))
+	github.getenv = env(map[string]string{GitHubRequestURLEnv: "https://gh/token?api-version=2.0", GitHubRequestTokenEnv: "req"})
+	data, err = github.GoogleCredentials(provider, "scanner@p.iam.gserviceaccount.com", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = decode(data)
+	src := cfg["credential_source"].(map[string]any)
+	if !strings.HasPrefix(src["url"].(string), "https://gh/token?api-version=2.0&audience=https%3A%2F%2Fiam.googleapis.com") {
+		t.Errorf("credential_source url = %v", src["url"])
+	}
+	if src["headers"].(map[string]any)["Authorization"] != "Bearer req" {
+		t.Errorf("credential_source headers = %v", src["headers"])
+	}
+	if cfg["service_account_impersonation_url"] != "https://iamcredentials.googleapis.com/v1/projects/-/serviceAccounts/scanner@p.iam.gserviceaccount.com:generateAccessToken" {
+		t.Errorf("impersonation url = %v", cfg["service_account_impersonation_url"])
+	}
+}
@@ -0,0 +1,94 @@
+// Package pathutil expands user-supplied paths from flags and config files
+// the same way on every platform: a leading ~ is the home directory and
+// environment variables are substituted ($VAR and ${VAR}, plus %VAR% on
+// Windows). The target OS is a field so Windows rules can be tested anywhere.
+package pathutil
+
+import (
+	"os"
+	"runtime"
+	"strings"
+)
+
+// Resolver expands paths for one operating system.
+type Resolver struct {
+	// GOOS selects separator and variable syntax ("windows" or any Unix).
+	GOOS string
+	// Home is the user's home directory; ~ is left alone when it is empty.
+	Home string
+	// Getenv looks up environment variables.
+	Getenv func(string) string
+}
+
+// Default returns the resolver for the running system.
+func Default() Resolver {
+	home, _ := os.UserHomeDir()
+	return Resolver{GOOS: runtime.GOOS, Home: home, Getenv: os.Getenv}
+}
+
+// Expand expands the home directory and environment variables in p.
+func Expand(p string) string {
+	return Default().Expand(p)
+}
+
+// Expand expands the home directory and environment variables in p. Empty
+// paths and the "-" stdout placeholder are returned unchanged.
+func (r Resolver) Expand(p string) string {
+	if p == "" || p == "-" {
+		return p
+	}
+	getenv := r.Getenv
+	if getenv == nil {
+		getenv = func(string) string { return "" }
+	}
+	if r.GOOS == "windows" {
+		p = expandPercent(p, getenv)
+	}
+	p = os.Expand(p, func(name string) string {
+		if v := getenv(name); v != "" {
+			return v
+		}
+		return "$" + name
+	})
+	if r.Home != "" && (p == "~" || strings.HasPrefix(p, "~/") || (r.GOOS == "windows" && strings.HasPrefix(p, `~\`))) {
+		p = strings.TrimRight(r.Home, `/\`) + r.separator() + strings.TrimLeft(p[1:], `/\`)
+		p = strings.TrimRight(p, `/\`)
+		if p == "" || strings.HasSuffix(p, ":") {
+			p += r.separator()
+		}
+	}
+	return p
+}
+
+func (r Resolver) separator() string {
+	if r.GOOS == "windows" {
+		return `\`
+	}
+	return "/"
+}
+
+// expandPercent substitutes %VAR% references, leaving unknown ones intact as
+// cmd.exe does.
+func expandPercent(p string, getenv func(string) string) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(p, '%')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(p[start+1:], '%')
+		if end < 0 {
+			break
+		}
+		end += start + 1
+		name := p[start+1 : end]
+		if v := getenv(name); name != "" && v != "" {
+			b.WriteString(p[:start] + v)
+			p = p[end+1:]
+			continue
+		}
+		b.WriteString(p[:end])
+		p = p[end:]
+	}
+	return b.String() + p
+}
@@ -0,0 +1,40 @@
+package pathutil
+
+import "testing"
+
+func TestExpand(t *testing.T) {
+	env := map[string]string{"HOME": "/home/dev", "USERPROFILE": `C:\Users\dev`, "DIR": "reports"}
+	getenv := func(k string) string { return env[k] }
+	unix := Resolver{GOOS: "linux", Home: "/home/dev", Getenv: getenv}
+	windows := Resolver{GOOS: "windows", Home: `C:\Users\dev`, Getenv: getenv}
+
+	tests := []struct {
+		name string
+		r    Resolver
+		in   string
+		want string
+	}{
+		{"empty", unix, "", ""},
+		{"stdout placeholder", unix, "-", "-"},
+		{"relative", unix, "out/report.json", "out/report.json"},
+		{"home", unix, "~", "/home/dev"},
+		{"home subdir", unix, "~/.cache/ecrspectre", "/home/dev/.cache/ecrspectre"},
+		{"other user untouched", unix, "~bob/x", "~bob/x"},
+		{"dollar var", unix, "$HOME/$DIR/a.json", "/home/dev/reports/a.json"},
+		{"braced var", unix, "${DIR}/a.json", "reports/a.json"},
+		{"unknown var kept", unix, "$NOPE/a", "$NOPE/a"},
+		{"percent is literal on unix", unix, "%DIR%/a", "%DIR%/a"},
+		{"windows home slash", windows, "~/history", `C:\Users\dev\history`},
+		{"windows home backslash", windows, `~\history\scans`, `C:\Users\dev\history\scans`},
+		{"windows percent var", windows, `%USERPROFILE%\reports\a.json`, `C:\Users\dev\reports\a.json`},
+		{"windows unknown percent kept", windows, `%NOPE%\%DIR%`, `%NOPE%\reports`},
+		{"windows unmatched percent", windows, `50%\a`, `50%\a`},
+		{"windows drive path", windows, `D:\scans\out.json`, `D:\scans\out.json`},
+		{"no home", Resolver{GOOS: "linux"}, "~/x", "~/x"},
+	}
+	for _, tt := range tests {
+		if got := tt.r.Expand(tt.in); got != tt.want {
+			t.Errorf("%s: Expand(%q) = %q, want %q", tt.name, tt.in, got, tt.want)
+		}
+	}
+}
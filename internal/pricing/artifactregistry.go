@@ -0,0 +1,76 @@
+package pricing
+
+import "strings"
+
+// ARFreeTierBytes is the Artifact Registry storage that is free every month.
+const ARFreeTierBytes int64 = 512 * 1024 * 1024
+
+// ARStorage prices Artifact Registry storage for one project. Storage is
+// billed on the project's total across locations, so deleting images only
+// saves money for the bytes above the free tier. Findings share that
+// billable storage: each one claims its bytes from what earlier findings
+// left, so the savings reported for a project never exceed its bill.
+type ARStorage struct {
+	projectBytes int64
+	remaining    int64
+	claimed      map[string]int64
+}
+
+// NewARStorage creates a model for a project storing projectBytes across all
+// of its locations. A zero total means the size is unknown and the free tier
+// is not applied.
+func NewARStorage(projectBytes int64) *ARStorage {
+	s := &ARStorage{projectBytes: projectBytes, claimed: make(map[string]int64)}
+	s.remaining = s.BillableBytes()
+	return s
+}
+
+// BillableBytes returns the project storage above the free tier.
+func (s *ARStorage) BillableBytes() int64 {
+	return max(0, s.projectBytes-ARFreeTierBytes)
+}
+
+// MonthlyWaste returns the monthly cost of sizeBytes stored in location on
+// its own: no more than the project's billable storage is ever charged. It
+// does not claim from the shared budget; use Claim to price a finding.
+func (s *ARStorage) MonthlyWaste(location string, sizeBytes int64) float64 {
+	if s.projectBytes > 0 {
+		sizeBytes = min(sizeBytes, s.BillableBytes())
+	}
+	return MonthlyStorageCost("artifactregistry", arRateKey(location), sizeBytes)
+}
+
+// Claim returns the monthly saving from deleting sizeBytes of the resource
+// id in location, drawing the bytes from the project's remaining billable
+// storage. Claims on the same resource overlap rather than add up, the way
+// the report totals findings, so a second finding on an image draws only
+// the bytes beyond the first.
+func (s *ARStorage) Claim(location, id string, sizeBytes int64) float64 {
+	if s.projectBytes <= 0 {
+		return MonthlyStorageCost("artifactregistry", arRateKey(location), sizeBytes)
+	}
+	key := location + "|" + id
+	prev := s.claimed[key]
+	if sizeBytes > prev {
+		drawn := min(sizeBytes-prev, s.remaining)
+		s.remaining -= drawn
+		s.claimed[key] = prev + drawn
+	}
+	return MonthlyStorageCost("artifactregistry", arRateKey(location), min(sizeBytes, s.claimed[key]))
+}
+
+// IsARMultiRegion reports whether an Artifact Registry location is a
+// multi-region (us, europe, asia) rather than a single region such as
+// us-central1.
+func IsARMultiRegion(location string) bool {
+	return location != "" && !strings.Contains(location, "-")
+}
+
+// arRateKey maps a location to its StorageCosts entry, falling back to the
+// multi-region rate for multi-regions without an entry of their own.
+func arRateKey(location string) string {
+	if _, ok := StorageCosts["artifactregistry"][location]; !ok && IsARMultiRegion(location) {
+		return "multi-region"
+	}
+	return location
+}
@@ -0,0 +1,107 @@
+package pricing
+
+// The carbon model follows the Cloud Carbon Footprint methodology: stored
+// bytes draw StorageWhPerTBHour of HDD power, multiplied by the provider's
+// replication factor and data-center PUE, and the energy emits the grid
+// intensity of the region.
+
+// StorageWhPerTBHour is the power drawn by one terabyte of HDD storage.
+const StorageWhPerTBHour = 0.65
+
+// hoursPerMonth is the average month length used by cloud billing.
+const hoursPerMonth = 730
+
+// StorageReplication is how many copies of each stored byte the provider
+// keeps: ECR stores images in S3, Artifact Registry in Cloud Storage.
+var StorageReplication = map[string]float64{
+	"ecr":              3,
+	"artifactregistry": 2,
+}
+
+// PUE is the power usage effectiveness of the provider's data centers.
+var PUE = map[string]float64{
+	"ecr":              1.135,
+	"artifactregistry": 1.1,
+}
+
+// GridIntensity maps provider and region to the grid emission factor in kg
+// CO2e per kWh, from the Cloud Carbon Footprint coefficients. Regions fall
+// back to "default" and multi-region locations to "multi-region".
+var GridIntensity = map[string]map[string]float64{
+	"ecr": {
+		"us-east-1":      0.379069,
+		"us-east-2":      0.410608,
+		"us-west-1":      0.322167,
+		"us-west-2":      0.322167,
+		"ca-central-1":   0.00013,
+		"eu-west-1":      0.2786,
+		"eu-west-2":      0.225,
+		"eu-west-3":      0.0511,
+		"eu-central-1":   0.338,
+		"eu-north-1":     0.0088,
+		"ap-south-1":     0.708,
+		"ap-southeast-1": 0.408,
+		"ap-southeast-2": 0.79,
+		"ap-northeast-1": 0.463,
+		"sa-east-1":      0.0617,
+		"default":        0.379069,
+	},
+	"artifactregistry": {
+		"us-central1":     0.454,
+		"us-east1":        0.56,
+		"us-east4":        0.37,
+		"us-west1":        0.078,
+		"us-west2":        0.253,
+		"europe-west1":    0.212,
+		"europe-west2":    0.228,
+		"europe-west4":    0.328,
+		"asia-east1":      0.54,
+		"asia-southeast1": 0.493,
+		"multi-region":    0.454,
+		"default":         0.454,
+	},
+}
+
+// ProviderKey maps a report provider ("aws" or "gcp") to its pricing key.
+// Pricing keys are returned unchanged.
+func ProviderKey(provider string) string {
+	switch provider {
+	case "aws":
+		return "ecr"
+	case "gcp":
+		return "artifactregistry"
+	}
+	return provider
+}
+
+// StorageGB returns the stored gigabytes that cost monthlyCost per month in
+// a provider's region, the inverse of MonthlyStorageCost.
+func StorageGB(provider, region string, monthlyCost float64) float64 {
+	if provider == "artifactregistry" {
+		region = arRateKey(region)
+	}
+	costPerGB := lookupCostPerGB(provider, region)
+	if costPerGB <= 0 {
+		return 0
+	}
+	return monthlyCost / costPerGB
+}
+
+// MonthlyCO2eKg estimates the kg CO2e emitted in a month by storing gb
+// gigabytes in a provider's region.
+func MonthlyCO2eKg(provider, region string, gb float64) float64 {
+	replication, ok := StorageReplication[provider]
+	if !ok {
+		provider = "ecr"
+		replication = StorageReplication[provider]
+	}
+	if provider == "artifactregistry" && IsARMultiRegion(region) {
+		region = "multi-region"
+	}
+	intensity, ok := GridIntensity[provider][region]
+	if !ok {
+		intensity = GridIntensity[provider]["default"]
+	}
+	kWh := gb / 1024 * StorageWhPerTBHour * hoursPerMonth * replication * PUE[provider] / 1000
+	return kWh * intensity
+}
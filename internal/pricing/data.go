@@ -2,8 +2,9 @@ package pricing
 
 // StorageCosts maps provider and region to per-GB monthly storage cost in USD.
 // ECR: $0.10/GB/month in all regions.
-// GCP Artifact Registry: $0.10/GB/month (us/europe/asia single-region),
-// varies by multi-region location.
+// GCP Artifact Registry: $0.10/GB/month above a 0.5 GB monthly free tier (see
+// ARStorage). Regional locations fall back to "default" and multi-region
+// locations (us, europe, asia) to "multi-region".
 var StorageCosts = map[string]map[string]float64{
 	"ecr": {
 		"default": 0.10, // ECR is $0.10/GB/month in all regions
@@ -22,6 +23,34 @@ var StorageCosts = map[string]map[string]float64{
 		"europe-west4":    0.10,
 		"asia-east1":      0.10,
 		"asia-southeast1": 0.10,
+		"multi-region":    0.10,
 		"default":         0.10,
 	},
 }
+
+// TransferCosts maps provider and transfer kind to per-GB data transfer cost in USD.
+// ECR: inter-region transfer is $0.02/GB; internet egress starts at $0.09/GB
+// (first 10 TB tier). Transfer within the same region is free.
+var TransferCosts = map[string]map[string]float64{
+	"ecr": {
+		"inter-region": 0.02,
+		"internet":     0.09,
+	},
+}
+
+// ArchiveTier is the cheapest storage class an image can be exported to
+// before it is deleted from the registry.
+type ArchiveTier struct {
+	Name      string
+	CostPerGB float64 // USD per GB-month
+}
+
+// ArchiveTiers maps a provider to its archive tier.
+// ECR: images exported with `docker save` to S3 Glacier Deep Archive at
+// $0.00099/GB/month (180-day minimum, retrieval within 12 hours).
+// GCP Artifact Registry: Cloud Storage Archive class at $0.0012/GB/month
+// (365-day minimum), reachable from a remote repository.
+var ArchiveTiers = map[string]ArchiveTier{
+	"ecr":              {Name: "s3-glacier-deep-archive", CostPerGB: 0.00099},
+	"artifactregistry": {Name: "gcs-archive", CostPerGB: 0.0012},
+}
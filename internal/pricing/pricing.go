@@ -21,3 +21,21 @@ func lookupCostPerGB(provider, region string) float64 {
 	}
 	return cost
 }
+
+// MonthlyTransferCost calculates the monthly data transfer cost in USD for
+// moving sizeBytes the given number of times. Unknown kinds cost nothing.
+func MonthlyTransferCost(provider, kind string, sizeBytes, transfers int64) float64 {
+	costPerGB := TransferCosts[provider][kind]
+	sizeGB := float64(sizeBytes) / (1024 * 1024 * 1024)
+	return sizeGB * float64(transfers) * costPerGB
+}
+
+// MonthlyArchiveCost returns the monthly cost in USD of keeping sizeBytes in
+// the provider's archive tier, and that tier's name.
+func MonthlyArchiveCost(provider string, sizeBytes int64) (float64, string) {
+	tier, ok := ArchiveTiers[provider]
+	if !ok {
+		tier = ArchiveTiers["ecr"]
+	}
+	return float64(sizeBytes) / (1024 * 1024 * 1024) * tier.CostPerGB, tier.Name
+}
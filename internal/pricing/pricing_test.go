@@ -81,3 +81,127 @@ func TestLookupCostPerGB(t *testing.T) {
 		}
 	}
 }
+
+func TestMonthlyTransferCost(t *testing.T) {
+	tests := []struct {
+		kind      string
+		sizeBytes int64
+		transfers int64
+		want      float64
+	}{
+		{"inter-region", 1073741824, 100, 2.0},
+		{"internet", 2 * 1073741824, 10, 1.8},
+		{"same-region", 1073741824, 100, 0},
+		{"inter-region", 1073741824, 0, 0},
+	}
+	for _, tt := range tests {
+		got := MonthlyTransferCost("ecr", tt.kind, tt.sizeBytes, tt.transfers)
+		if !almostEqual(got, tt.want) {
+			t.Errorf("MonthlyTransferCost(%q, %d, %d) = %f, want %f", tt.kind, tt.sizeBytes, tt.transfers, got, tt.want)
+		}
+	}
+}
+
+func TestARStorageFreeTier(t *testing.T) {
+	gb := int64(1073741824)
+	tests := []struct {
+		name         string
+		projectBytes int64
+		sizeBytes    int64
+		want         float64
+	}{
+		{"project within free tier", 400 * 1024 * 1024, 300 * 1024 * 1024, 0},
+		{"only billable bytes saved", gb, gb, 0.05},
+		{"image below billable storage", 10 * gb, gb, 0.10},
+		{"unknown project size", 0, gb, 0.10},
+	}
+	for _, tt := range tests {
+		got := NewARStorage(tt.projectBytes).MonthlyWaste("us-central1", tt.sizeBytes)
+		if !almostEqual(got, tt.want) {
+			t.Errorf("%s: MonthlyWaste = %f, want %f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestARStorageClaimSharesBillableBytes(t *testing.T) {
+	mb := int64(1024 * 1024)
+	// A 900 MB project bills 388 MB; five stale 150 MB images share it.
+	s := NewARStorage(900 * mb)
+	var total float64
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		total += s.Claim("us-central1", id, 150*mb)
+	}
+	if want := MonthlyStorageCost("artifactregistry", "us-central1", 388*mb); !almostEqual(total, want) {
+		t.Errorf("total waste = %f, want %f for the billable storage", total, want)
+	}
+	if got := s.Claim("us-central1", "f", 150*mb); got != 0 {
+		t.Errorf("claim after the budget ran out = %f, want 0", got)
+	}
+
+	// A second finding on the same image overlaps the first.
+	s = NewARStorage(10 * 1024 * mb)
+	s.Claim("us-central1", "img", 300*mb)
+	if got := s.Claim("us-central1", "img", 100*mb); !almostEqual(got, MonthlyStorageCost("artifactregistry", "us-central1", 100*mb)) {
+		t.Errorf("overlapping claim = %f", got)
+	}
+	if s.remaining != s.BillableBytes()-300*mb {
+		t.Errorf("remaining = %d, want %d after overlapping claims", s.remaining, s.BillableBytes()-300*mb)
+	}
+
+	// An unknown project size claims nothing from a budget.
+	if got := NewARStorage(0).Claim("us-central1", "img", 1073741824); !almostEqual(got, 0.10) {
+		t.Errorf("unknown project claim = %f, want 0.10", got)
+	}
+}
+
+func TestARMultiRegionRate(t *testing.T) {
+	if !IsARMultiRegion("us") || !IsARMultiRegion("asia") || IsARMultiRegion("us-central1") || IsARMultiRegion("") {
+		t.Error("IsARMultiRegion misclassified a location")
+	}
+	if got := arRateKey("southamerica"); got != "multi-region" {
+		t.Errorf("arRateKey(southamerica) = %q, want multi-region", got)
+	}
+	if got := arRateKey("me-west1"); got != "me-west1" {
+		t.Errorf("arRateKey(me-west1) = %q, want the region (default rate)", got)
+	}
+}
+
+func TestStorageGB(t *testing.T) {
+	if gb := StorageGB("ecr", "us-east-1", 0.50); !almostEqual(gb, 5) {
+		t.Errorf("StorageGB($0.50) = %f, want 5", gb)
+	}
+	if gb := StorageGB("artifactregistry", "europe", 0.10); !almostEqual(gb, 1) {
+		t.Errorf("StorageGB(AR multi-region) = %f, want 1", gb)
+	}
+}
+
+func TestMonthlyCO2eKg(t *testing.T) {
+	// 1 TB in us-east-1: 0.65 Wh × 730 h × 3 copies × 1.135 PUE = 1.6157 kWh.
+	got := MonthlyCO2eKg("ecr", "us-east-1", 1024)
+	if want := 1.6157 * 0.379069; math.Abs(got-want) > 0.001 {
+		t.Errorf("MonthlyCO2eKg(1 TB) = %f, want %f", got, want)
+	}
+	if clean, dirty := MonthlyCO2eKg("ecr", "eu-north-1", 100), MonthlyCO2eKg("ecr", "ap-south-1", 100); clean >= dirty {
+		t.Errorf("eu-north-1 = %f should emit less than ap-south-1 = %f", clean, dirty)
+	}
+	if MonthlyCO2eKg("artifactregistry", "asia", 100) != MonthlyCO2eKg("artifactregistry", "multi-region", 100) {
+		t.Error("AR multi-region should use the multi-region intensity")
+	}
+	if MonthlyCO2eKg("ecr", "mars-1", 100) != MonthlyCO2eKg("ecr", "default", 100) {
+		t.Error("unknown region should use the default intensity")
+	}
+	if ProviderKey("aws") != "ecr" || ProviderKey("gcp") != "artifactregistry" || ProviderKey("ecr") != "ecr" {
+		t.Error("ProviderKey mapping")
+	}
+}
+
+func TestMonthlyArchiveCost(t *testing.T) {
+	cost, tier := MonthlyArchiveCost("ecr", 1000*1073741824)
+	if !almostEqual(cost, 0.99) || tier != "s3-glacier-deep-archive" {
+		t.Errorf("ECR archive = %f %s, want 0.99 s3-glacier-deep-archive", cost, tier)
+	}
+	cost, tier = MonthlyArchiveCost("artifactregistry", 1000*1073741824)
+	if !almostEqual(cost, 1.2) || tier != "gcs-archive" {
+		t.Errorf("AR archive = %f %s, want 1.2 gcs-archive", cost, tier)
+	}
+}
@@ -0,0 +1,36 @@
+package registry
+
+import "time"
+
+// MetadataPushedAt is the finding metadata key holding the RFC 3339 push
+// (ECR), upload or create (Artifact Registry) time of the image or package
+// version a finding is about.
+const MetadataPushedAt = "pushed_at"
+
+// AnnotatePushed records when the image or package version behind findings
+// was pushed. A zero time records nothing.
+func AnnotatePushed(findings []Finding, pushed time.Time) {
+	if pushed.IsZero() {
+		return
+	}
+	stamp := pushed.UTC().Format(time.RFC3339)
+	for i := range findings {
+		if findings[i].Metadata == nil {
+			findings[i].Metadata = make(map[string]any)
+		}
+		findings[i].Metadata[MetadataPushedAt] = stamp
+	}
+}
+
+// PushedAt returns the push time recorded on a finding, if any.
+func PushedAt(f Finding) (time.Time, bool) {
+	s, ok := f.Metadata[MetadataPushedAt].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return t, true
+}
@@ -0,0 +1,24 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAnnotatePushed(t *testing.T) {
+	pushed := time.Date(2026, 2, 3, 4, 5, 6, 0, time.FixedZone("CET", 3600))
+	findings := []Finding{{ID: FindingStaleImage}, {ID: FindingLargeImage, Metadata: map[string]any{"size_bytes": 1}}}
+	AnnotatePushed(findings, pushed)
+	for _, f := range findings {
+		got, ok := PushedAt(f)
+		if !ok || !got.Equal(pushed) || f.Metadata[MetadataPushedAt] != "2026-02-03T03:05:06Z" {
+			t.Errorf("%s pushed_at = %v (%v)", f.ID, f.Metadata[MetadataPushedAt], ok)
+		}
+	}
+
+	none := []Finding{{ID: FindingStaleImage}}
+	AnnotatePushed(none, time.Time{})
+	if _, ok := PushedAt(none[0]); ok || none[0].Metadata != nil {
+		t.Errorf("zero push time recorded: %+v", none[0].Metadata)
+	}
+}
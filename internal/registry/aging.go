@@ -0,0 +1,57 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ImageCreated returns the build date recorded in an image config blob (the
+// "created" field of the OCI and Docker image configs). Reproducible builds
+// pin it to the Unix epoch, which is reported as zero like a missing date.
+func ImageCreated(config []byte) (time.Time, error) {
+	var c struct {
+		Created time.Time `json:"created"`
+	}
+	if err := json.Unmarshal(config, &c); err != nil {
+		return time.Time{}, fmt.Errorf("parse image config: %w", err)
+	}
+	if c.Created.Unix() <= 0 {
+		return time.Time{}, nil
+	}
+	return c.Created, nil
+}
+
+// AgingDeployedImage returns an AGING_DEPLOYED_IMAGE finding when a deployed
+// image was built more than cfg.MaxDeployedAge before now: production runs
+// a build that has missed every base image and dependency update since. It
+// is a hygiene signal with no storage cost. It returns nil when the check is
+// disabled, the image is not deployed or its build date is unknown.
+func AgingDeployedImage(cfg ScanConfig, resourceID, resourceName, region, digest string, built time.Time, inUse []string, now time.Time) *Finding {
+	if cfg.MaxDeployedAge <= 0 || len(inUse) == 0 || built.IsZero() || !cfg.CheckEnabled(FindingAgingDeployedImage) {
+		return nil
+	}
+	age := now.Sub(built)
+	if age <= cfg.MaxDeployedAge {
+		return nil
+	}
+	days := int(age.Hours() / 24)
+	f := &Finding{
+		ID:           FindingAgingDeployedImage,
+		Severity:     SeverityMedium,
+		ResourceType: ResourceImage,
+		ResourceID:   resourceID,
+		ResourceName: resourceName,
+		Region:       region,
+		Message:      fmt.Sprintf("Deployed image was built %d days ago, in use by %s", days, strings.Join(inUse, ", ")),
+		Metadata: map[string]any{
+			"digest":         digest,
+			"built_at":       built.UTC().Format(time.RFC3339),
+			"build_age_days": days,
+			"max_age_days":   int(cfg.MaxDeployedAge.Hours() / 24),
+		},
+	}
+	AnnotateInUse(f, inUse)
+	return f
+}
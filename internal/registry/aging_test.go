@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestImageCreated(t *testing.T) {
+	built, err := ImageCreated([]byte(`{"architecture":"amd64","created":"2025-06-01T10:00:00.123Z"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 6, 1, 10, 0, 0, 123000000, time.UTC); !built.Equal(want) {
+		t.Errorf("ImageCreated() = %v, want %v", built, want)
+	}
+	for _, config := range []string{`{}`, `{"created":"1970-01-01T00:00:00Z"}`} {
+		if built, err := ImageCreated([]byte(config)); err != nil || !built.IsZero() {
+			t.Errorf("ImageCreated(%s) = %v, %v; want zero", config, built, err)
+		}
+	}
+	if _, err := ImageCreated([]byte("{")); err == nil {
+		t.Error("expected error for invalid config")
+	}
+}
+
+func TestAgingDeployedImage(t *testing.T) {
+	now := time.Date(2026, 2, 28, 12, 0, 0, 0, time.UTC)
+	old := now.AddDate(0, 0, -200)
+	cfg := ScanConfig{MaxDeployedAge: 180 * 24 * time.Hour}
+	inUse := []string{"k8s:prod/default/api"}
+
+	f := AgingDeployedImage(cfg, "repo@sha256:a", "repo:v1", "us-east-1", "sha256:a", old, inUse, now)
+	if f == nil {
+		t.Fatal("expected finding")
+	}
+	if f.ID != FindingAgingDeployedImage || f.Severity != SeverityMedium || f.EstimatedMonthlyWaste != 0 {
+		t.Errorf("unexpected finding: %+v", f)
+	}
+	if f.Metadata["build_age_days"] != 200 || f.Metadata["max_age_days"] != 180 || !IsInUse(*f) {
+		t.Errorf("unexpected metadata: %v", f.Metadata)
+	}
+
+	tests := []struct {
+		name  string
+		cfg   ScanConfig
+		built time.Time
+		inUse []string
+	}{
+		{"recent build", cfg, now.AddDate(0, 0, -30), inUse},
+		{"not deployed", cfg, old, nil},
+		{"unknown build date", cfg, time.Time{}, inUse},
+		{"disabled", ScanConfig{}, old, inUse},
+		{"excluded", ScanConfig{MaxDeployedAge: cfg.MaxDeployedAge, DisabledChecks: map[FindingID]bool{FindingAgingDeployedImage: true}}, old, inUse},
+	}
+	for _, tt := range tests {
+		if f := AgingDeployedImage(tt.cfg, "repo@sha256:a", "repo:v1", "us-east-1", "sha256:a", tt.built, tt.inUse, now); f != nil {
+			t.Errorf("%s: expected no finding, got %+v", tt.name, f)
+		}
+	}
+}
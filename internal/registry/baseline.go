@@ -0,0 +1,13 @@
+package registry
+
+// MetadataBaseline is the finding metadata key set on findings of a scan
+// compared with a baseline report: BaselineNew for findings the baseline
+// does not have, BaselineUnchanged for the others.
+const MetadataBaseline = "baseline"
+
+// Baseline statuses of a finding.
+const (
+	BaselineNew       = "new"
+	BaselineUnchanged = "unchanged"
+	BaselineResolved  = "resolved"
+)
@@ -0,0 +1,109 @@
+package registry
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ReleaseTrainSlack is how many release intervals may pass without a new
+// image before a repository is reported as STALE_RELEASE_TRAIN, so a
+// release that slips by a few days is not flagged.
+const ReleaseTrainSlack = 2
+
+// ReleaseCadence declares how often repositories matching a pattern are
+// expected to ship a new image.
+type ReleaseCadence struct {
+	Repo  string
+	Every time.Duration
+	// Label is the cadence as configured, e.g. "weekly" or "14d".
+	Label   string
+	pattern *regexp.Regexp
+}
+
+// NewReleaseCadence validates one release cadence. repo is a glob or
+// re:regex repository pattern and every is daily, weekly, biweekly, monthly,
+// quarterly or a duration in days ("14d") or hours ("36h").
+func NewReleaseCadence(repo, every string) (ReleaseCadence, error) {
+	if repo == "" {
+		return ReleaseCadence{}, fmt.Errorf("release cadence is missing repo")
+	}
+	d, err := parseCadence(every)
+	if err != nil {
+		return ReleaseCadence{}, fmt.Errorf("release cadence %s: %w", repo, err)
+	}
+	pattern, err := compileRepoPattern(repo)
+	if err != nil {
+		return ReleaseCadence{}, fmt.Errorf("release cadence: %w", err)
+	}
+	return ReleaseCadence{Repo: repo, Every: d, Label: every, pattern: pattern}, nil
+}
+
+var namedCadences = map[string]time.Duration{
+	"daily":     24 * time.Hour,
+	"weekly":    7 * 24 * time.Hour,
+	"biweekly":  14 * 24 * time.Hour,
+	"monthly":   30 * 24 * time.Hour,
+	"quarterly": 91 * 24 * time.Hour,
+}
+
+func parseCadence(s string) (time.Duration, error) {
+	if d, ok := namedCadences[strings.ToLower(s)]; ok {
+		return d, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour, nil
+		}
+	} else if d, err := time.ParseDuration(s); err == nil && d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("invalid every %q (use daily, weekly, biweekly, monthly, quarterly, Nd or a duration)", s)
+}
+
+// ReleaseCadenceFor returns the first cadence whose pattern matches repo.
+func (c ScanConfig) ReleaseCadenceFor(repo string) (ReleaseCadence, bool) {
+	for _, rc := range c.ReleaseCadences {
+		if rc.pattern != nil && rc.pattern.MatchString(repo) {
+			return rc, true
+		}
+	}
+	return ReleaseCadence{}, false
+}
+
+// StaleReleaseTrain returns a STALE_RELEASE_TRAIN finding when the newest
+// image of a repository with a release cadence was pushed more than
+// ReleaseTrainSlack intervals before now: the service has quietly stopped
+// shipping. It returns nil when the check is disabled, the repository has
+// no cadence or newest is zero.
+func StaleReleaseTrain(cfg ScanConfig, repo, region string, newest, now time.Time) *Finding {
+	if newest.IsZero() || !cfg.CheckEnabled(FindingStaleReleaseTrain) {
+		return nil
+	}
+	rc, ok := cfg.ReleaseCadenceFor(repo)
+	if !ok {
+		return nil
+	}
+	since := now.Sub(newest)
+	if since <= ReleaseTrainSlack*rc.Every {
+		return nil
+	}
+	days := int(since.Hours() / 24)
+	return &Finding{
+		ID:           FindingStaleReleaseTrain,
+		Severity:     SeverityMedium,
+		ResourceType: ResourceRepository,
+		ResourceID:   repo,
+		Region:       region,
+		Message:      fmt.Sprintf("Newest image is %d days old; repository is expected to release %s", days, rc.Label),
+		Metadata: map[string]any{
+			"release_cadence":    rc.Label,
+			"cadence_days":       math.Round(rc.Every.Hours()/24*10) / 10,
+			"days_since_release": days,
+			"newest_push":        newest.UTC().Format(time.RFC3339),
+		},
+	}
+}
@@ -0,0 +1,58 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewReleaseCadence(t *testing.T) {
+	tests := []struct {
+		every string
+		want  time.Duration
+	}{
+		{"daily", 24 * time.Hour},
+		{"Weekly", 7 * 24 * time.Hour},
+		{"monthly", 30 * 24 * time.Hour},
+		{"14d", 14 * 24 * time.Hour},
+		{"36h", 36 * time.Hour},
+	}
+	for _, tt := range tests {
+		rc, err := NewReleaseCadence("prod/*", tt.every)
+		if err != nil || rc.Every != tt.want {
+			t.Errorf("NewReleaseCadence(%q) = %v, %v, want %v", tt.every, rc.Every, err, tt.want)
+		}
+	}
+	for _, bad := range [][2]string{{"", "weekly"}, {"prod/*", ""}, {"prod/*", "sometimes"}, {"prod/*", "0d"}, {"prod/*", "-1h"}, {"re:(", "weekly"}} {
+		if _, err := NewReleaseCadence(bad[0], bad[1]); err == nil {
+			t.Errorf("NewReleaseCadence(%q, %q) succeeded", bad[0], bad[1])
+		}
+	}
+}
+
+func TestStaleReleaseTrain(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	weekly, _ := NewReleaseCadence("prod/*", "weekly")
+	quarterly, _ := NewReleaseCadence("re:^prod/batch-", "quarterly")
+	// The first matching cadence wins, so specific patterns go first.
+	cfg := ScanConfig{ReleaseCadences: []ReleaseCadence{quarterly, weekly}}
+
+	f := StaleReleaseTrain(cfg, "prod/api", "us-east-1", now.AddDate(0, 0, -15), now)
+	if f == nil || f.ID != FindingStaleReleaseTrain || f.ResourceType != ResourceRepository {
+		t.Fatalf("15 days on a weekly cadence = %+v, want STALE_RELEASE_TRAIN", f)
+	}
+	if f.Metadata["release_cadence"] != "weekly" || f.Metadata["cadence_days"] != 7.0 || f.Metadata["days_since_release"] != 15 {
+		t.Errorf("metadata = %v", f.Metadata)
+	}
+	if f := StaleReleaseTrain(cfg, "prod/api", "us-east-1", now.AddDate(0, 0, -13), now); f != nil {
+		t.Errorf("13 days on a weekly cadence reported: %+v", f)
+	}
+	if f := StaleReleaseTrain(cfg, "prod/batch-report", "us-east-1", now.AddDate(0, 0, -60), now); f != nil {
+		t.Errorf("60 days on a quarterly cadence reported: %+v", f)
+	}
+	if f := StaleReleaseTrain(cfg, "dev/api", "us-east-1", now.AddDate(-1, 0, 0), now); f != nil {
+		t.Errorf("repository without a cadence reported: %+v", f)
+	}
+	if f := StaleReleaseTrain(cfg, "prod/api", "us-east-1", time.Time{}, now); f != nil {
+		t.Errorf("repository without push times reported: %+v", f)
+	}
+}
@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CanonicalTag picks the tag that names an image carrying several tags: a
+// tag matching the first matching priority glob (e.g. "v*", "release-*"),
+// otherwise the highest semantic version, otherwise the first tag in sort
+// order, preferring any tag over "latest". It returns "" for untagged images.
+func CanonicalTag(tags, priority []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	for _, pattern := range priority {
+		var matched []string
+		for _, t := range tags {
+			if ok, _ := path.Match(pattern, t); ok {
+				matched = append(matched, t)
+			}
+		}
+		if len(matched) > 0 {
+			return bestTag(matched)
+		}
+	}
+	return bestTag(tags)
+}
+
+// bestTag returns the highest semantic version among tags, or the first tag
+// in sort order other than "latest".
+func bestTag(tags []string) string {
+	best, bestVer := "", semver{}
+	for _, t := range tags {
+		if v, ok := parseSemver(t); ok && (best == "" || bestVer.less(v)) {
+			best, bestVer = t, v
+		}
+	}
+	if best != "" {
+		return best
+	}
+	sorted := append([]string(nil), tags...)
+	sort.Strings(sorted)
+	for _, t := range sorted {
+		if t != "latest" {
+			return t
+		}
+	}
+	return sorted[0]
+}
+
+// ImageName returns repo:tag for an image's canonical tag, or "" for an
+// untagged image.
+func ImageName(repo string, tags, priority []string) string {
+	tag := CanonicalTag(tags, priority)
+	if tag == "" {
+		return ""
+	}
+	return repo + ":" + tag
+}
+
+// AnnotateTags records the full tag list of an image with more than one tag
+// in the metadata of its findings, since ResourceName shows only one.
+func AnnotateTags(findings []Finding, tags []string) {
+	if len(tags) < 2 {
+		return
+	}
+	sorted := append([]string(nil), tags...)
+	sort.Strings(sorted)
+	for i := range findings {
+		if findings[i].Metadata == nil {
+			findings[i].Metadata = make(map[string]any)
+		}
+		findings[i].Metadata["tags"] = sorted
+	}
+}
+
+// semver is a parsed MAJOR[.MINOR[.PATCH]][-PRERELEASE] version.
+type semver struct {
+	parts      [3]int
+	prerelease string
+}
+
+// parseSemver accepts versions with an optional "v" prefix and build metadata.
+func parseSemver(tag string) (semver, bool) {
+	var v semver
+	s := strings.TrimPrefix(tag, "v")
+	s, _, _ = strings.Cut(s, "+")
+	s, v.prerelease, _ = strings.Cut(s, "-")
+	fields := strings.Split(s, ".")
+	if len(fields) > 3 {
+		return v, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		v.parts[i] = n
+	}
+	// A bare number such as a build ID is not treated as a version.
+	if len(fields) == 1 {
+		return v, false
+	}
+	return v, true
+}
+
+// less orders versions; a prerelease sorts before its release.
+func (v semver) less(o semver) bool {
+	for i := range v.parts {
+		if v.parts[i] != o.parts[i] {
+			return v.parts[i] < o.parts[i]
+		}
+	}
+	if (v.prerelease == "") != (o.prerelease == "") {
+		return v.prerelease != ""
+	}
+	return v.prerelease < o.prerelease
+}
@@ -0,0 +1,52 @@
+package registry
+
+import "testing"
+
+func TestCanonicalTag(t *testing.T) {
+	cases := []struct {
+		tags     []string
+		priority []string
+		want     string
+	}{
+		{nil, nil, ""},
+		{[]string{"latest"}, nil, "latest"},
+		{[]string{"latest", "v1.2.0", "v1.10.0", "sha-abc123"}, nil, "v1.10.0"},
+		{[]string{"1.2.0-rc1", "1.2.0", "latest"}, nil, "1.2.0"},
+		{[]string{"latest", "main", "sha-abc"}, nil, "main"},
+		{[]string{"20240101", "latest"}, nil, "20240101"},
+		{[]string{"latest", "v2.0.0", "prod"}, []string{"prod", "v*"}, "prod"},
+		{[]string{"latest", "v2.0.0", "v1.0.0"}, []string{"stable", "v*"}, "v2.0.0"},
+		{[]string{"latest", "main"}, []string{"release-*"}, "main"},
+	}
+	for _, c := range cases {
+		if got := CanonicalTag(c.tags, c.priority); got != c.want {
+			t.Errorf("CanonicalTag(%v, %v) = %q, want %q", c.tags, c.priority, got, c.want)
+		}
+	}
+}
+
+func TestImageName(t *testing.T) {
+	if got := ImageName("api", []string{"latest", "v3.1.0"}, nil); got != "api:v3.1.0" {
+		t.Errorf("ImageName() = %q", got)
+	}
+	if got := ImageName("api", nil, nil); got != "" {
+		t.Errorf("ImageName(untagged) = %q, want empty", got)
+	}
+}
+
+func TestAnnotateTags(t *testing.T) {
+	findings := []Finding{{ID: FindingStaleImage}, {ID: FindingLargeImage, Metadata: map[string]any{"size_bytes": 1}}}
+	AnnotateTags(findings, []string{"v2", "latest"})
+	for _, f := range findings {
+		tags, _ := f.Metadata["tags"].([]string)
+		if len(tags) != 2 || tags[0] != "latest" {
+			t.Errorf("tags metadata = %v", f.Metadata["tags"])
+		}
+	}
+
+	single := []Finding{{ID: FindingStaleImage}}
+	AnnotateTags(single, []string{"v1"})
+	if single[0].Metadata != nil {
+		t.Error("single-tag images need no tag list")
+	}
+}
@@ -0,0 +1,55 @@
+package registry
+
+import "sort"
+
+// Coverage describes how much of the planned scan completed before the deadline.
+// Percent is weighted by repository priority (estimated spend) when known,
+// otherwise by repository count.
+type Coverage struct {
+	RepositoriesPlanned   int     `json:"repositories_planned"`
+	RepositoriesCompleted int     `json:"repositories_completed"`
+	Percent               float64 `json:"percent"`
+	Truncated             bool    `json:"truncated"`
+}
+
+// SortByPriority orders items by descending priority weight so the most
+// expensive repositories are scanned first. Items without a weight keep their
+// relative order at the end. Returns the weight of each item in the new order.
+func SortByPriority[T any](items []T, name func(T) string, priority map[string]float64) []float64 {
+	sort.SliceStable(items, func(i, j int) bool {
+		return priority[name(items[i])] > priority[name(items[j])]
+	})
+	weights := make([]float64, len(items))
+	for i, item := range items {
+		weights[i] = priority[name(item)]
+	}
+	return weights
+}
+
+// ComputeCoverage returns the coverage achieved when the first completed
+// repositories (in scan order) finished out of the planned weights.
+func ComputeCoverage(weights []float64, completed int, truncated bool) Coverage {
+	c := Coverage{
+		RepositoriesPlanned:   len(weights),
+		RepositoriesCompleted: completed,
+		Truncated:             truncated,
+	}
+	if len(weights) == 0 {
+		c.Percent = 100
+		return c
+	}
+
+	var total, done float64
+	for i, w := range weights {
+		total += w
+		if i < completed {
+			done += w
+		}
+	}
+	if total > 0 {
+		c.Percent = done / total * 100
+	} else {
+		c.Percent = float64(completed) / float64(len(weights)) * 100
+	}
+	return c
+}
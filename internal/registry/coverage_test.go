@@ -0,0 +1,46 @@
+package registry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestSortByPriority(t *testing.T) {
+	repos := []string{"small", "unknown", "big", "medium"}
+	priority := map[string]float64{"small": 1, "big": 50, "medium": 10}
+
+	weights := SortByPriority(repos, func(s string) string { return s }, priority)
+
+	want := []string{"big", "medium", "small", "unknown"}
+	for i := range want {
+		if repos[i] != want[i] {
+			t.Fatalf("order = %v, want %v", repos, want)
+		}
+	}
+	if weights[0] != 50 || weights[3] != 0 {
+		t.Errorf("weights = %v", weights)
+	}
+}
+
+func TestComputeCoverageWeighted(t *testing.T) {
+	c := ComputeCoverage([]float64{60, 30, 10}, 1, true)
+	if math.Abs(c.Percent-60) > 0.001 {
+		t.Errorf("Percent = %f, want 60", c.Percent)
+	}
+	if !c.Truncated || c.RepositoriesPlanned != 3 || c.RepositoriesCompleted != 1 {
+		t.Errorf("coverage = %+v", c)
+	}
+}
+
+func TestComputeCoverageUnweighted(t *testing.T) {
+	c := ComputeCoverage([]float64{0, 0, 0, 0}, 3, true)
+	if math.Abs(c.Percent-75) > 0.001 {
+		t.Errorf("Percent = %f, want 75", c.Percent)
+	}
+}
+
+func TestComputeCoverageEmpty(t *testing.T) {
+	if c := ComputeCoverage(nil, 0, false); c.Percent != 100 {
+		t.Errorf("Percent = %f, want 100", c.Percent)
+	}
+}
@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/rules"
+)
+
+var customIDPattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+
+var builtinFindingIDs = map[FindingID]bool{
+	FindingUntaggedImage: true, FindingStaleImage: true, FindingLargeImage: true,
+	FindingNoLifecyclePolicy: true, FindingVulnerableImage: true, FindingUnusedRepo: true,
+	FindingMultiArchBloat: true, FindingDuplicateLayers: true, FindingQuotaPressure: true,
+	FindingStorageSpike: true, FindingStaleRemoteCache: true, FindingLongTailWaste: true,
+	FindingOrphanedManifest: true, FindingUnsignedImage: true, FindingMissingSBOM: true,
+	FindingUntaggedAccumulation: true, FindingStaleReleaseTrain: true, FindingTieringCandidate: true,
+	FindingAgingDeployedImage: true,
+}
+
+// CustomRule is a user-defined image check: every image matching Program is
+// reported under ID.
+type CustomRule struct {
+	ID       FindingID
+	Severity Severity
+	Message  string
+	Program  *rules.Program
+}
+
+// NewCustomRule validates and compiles a rule from config. The ID must be
+// upper snake case and must not shadow a built-in finding; severity
+// defaults to medium.
+func NewCustomRule(id, severity, message, expression string) (CustomRule, error) {
+	if !customIDPattern.MatchString(id) {
+		return CustomRule{}, fmt.Errorf("rule id %q must be upper snake case, e.g. SANDBOX_EXPIRED", id)
+	}
+	if builtinFindingIDs[FindingID(id)] {
+		return CustomRule{}, fmt.Errorf("rule id %s is a built-in finding", id)
+	}
+	rule := CustomRule{ID: FindingID(id), Severity: SeverityMedium, Message: message}
+	if severity != "" {
+		sev, err := ParseSeverity(severity)
+		if err != nil {
+			return CustomRule{}, fmt.Errorf("rule %s: %w", id, err)
+		}
+		rule.Severity = sev
+	}
+	if rule.Message == "" {
+		rule.Message = "Matched custom rule " + id
+	}
+	program, err := rules.Compile(expression)
+	if err != nil {
+		return CustomRule{}, fmt.Errorf("rule %s: %w", id, err)
+	}
+	rule.Program = program
+	return rule, nil
+}
+
+// CustomFindings evaluates cfg.Rules against one image. A match is reported
+// with the image's storage cost as waste, so min_monthly_cost applies to
+// custom findings like any other image finding.
+func CustomFindings(cfg ScanConfig, img rules.Image, now time.Time, resourceID, resourceName string, cost float64) []Finding {
+	var findings []Finding
+	for _, rule := range cfg.Rules {
+		if !cfg.CheckEnabled(rule.ID) || !rule.Program.Match(img, now) {
+			continue
+		}
+		findings = append(findings, Finding{
+			ID:                    rule.ID,
+			Severity:              rule.Severity,
+			ResourceType:          ResourceImage,
+			ResourceID:            resourceID,
+			ResourceName:          resourceName,
+			Region:                img.Region,
+			Message:               rule.Message,
+			EstimatedMonthlyWaste: cost,
+			Metadata: map[string]any{
+				"digest":     img.Digest,
+				"size_bytes": img.SizeBytes,
+				"rule":       rule.Program.String(),
+			},
+		})
+	}
+	return findings
+}
@@ -0,0 +1,56 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/rules"
+)
+
+func TestNewCustomRule(t *testing.T) {
+	rule, err := NewCustomRule("NO_OWNER_TAG", "", "", `!tags.exists(t, t.startsWith("owner-"))`)
+	if err != nil {
+		t.Fatalf("NewCustomRule() error: %v", err)
+	}
+	if rule.Severity != SeverityMedium || rule.Message != "Matched custom rule NO_OWNER_TAG" {
+		t.Errorf("defaults = %+v", rule)
+	}
+
+	tests := []struct {
+		id, severity, expr string
+		want               string
+	}{
+		{"sandbox", "", "true", "upper snake case"},
+		{"STALE_IMAGE", "", "true", "built-in"},
+		{"X", "urgent", "true", "severity"},
+		{"X", "", "repo", "must be bool"},
+	}
+	for _, tt := range tests {
+		_, err := NewCustomRule(tt.id, tt.severity, "", tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("NewCustomRule(%s, %s, %s) error = %v, want %q", tt.id, tt.severity, tt.expr, err, tt.want)
+		}
+	}
+}
+
+func TestCustomFindings(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	big, _ := NewCustomRule("BIG", "high", "Over 1 GB", "size_mb > 1024")
+	old, _ := NewCustomRule("OLD", "", "", "age_days > 30")
+	img := rules.Image{Repository: "app", Region: "us-east-1", Digest: "sha256:abc", SizeBytes: 2 << 30, Pushed: now.AddDate(0, 0, -5)}
+
+	cfg := ScanConfig{Rules: []CustomRule{big, old}}
+	findings := CustomFindings(cfg, img, now, "app@sha256:abc", "app:v1", 0.2)
+	if len(findings) != 1 || findings[0].ID != "BIG" || findings[0].Severity != SeverityHigh {
+		t.Fatalf("findings = %+v", findings)
+	}
+	if findings[0].EstimatedMonthlyWaste != 0.2 || findings[0].Metadata["rule"] != "size_mb > 1024" {
+		t.Errorf("finding = %+v", findings[0])
+	}
+
+	cfg.DisabledChecks = map[FindingID]bool{"BIG": true}
+	if got := CustomFindings(cfg, img, now, "app@sha256:abc", "app:v1", 0.2); len(got) != 0 {
+		t.Errorf("disabled rule still reported: %+v", got)
+	}
+}
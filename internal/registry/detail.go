@@ -0,0 +1,48 @@
+package registry
+
+import "time"
+
+// RepositoryDetail is the per-image breakdown produced when a single
+// repository is scanned in depth.
+type RepositoryDetail struct {
+	Name           string  `json:"name"`
+	Region         string  `json:"region"`
+	ImageCount     int     `json:"image_count"`
+	TotalSizeBytes int64   `json:"total_size_bytes"`
+	MonthlyCost    float64 `json:"monthly_cost"`
+	// HasLifecyclePolicy reports whether a cleanup/lifecycle policy exists;
+	// LifecycleSimulated is true when its rules were evaluated against the images.
+	HasLifecyclePolicy     bool          `json:"has_lifecycle_policy"`
+	LifecycleSimulated     bool          `json:"lifecycle_simulated"`
+	ExpiringImages         int           `json:"expiring_images"`
+	ExpiringMonthlySavings float64       `json:"expiring_monthly_savings"`
+	Images                 []ImageDetail `json:"images"`
+}
+
+// ImageDetail describes one image in a RepositoryDetail.
+type ImageDetail struct {
+	Digest       string      `json:"digest"`
+	Tags         []string    `json:"tags,omitempty"`
+	CanonicalTag string      `json:"canonical_tag,omitempty"`
+	SizeBytes    int64       `json:"size_bytes"`
+	PushedAt     *time.Time  `json:"pushed_at,omitempty"`
+	LastPulledAt *time.Time  `json:"last_pulled_at,omitempty"`
+	MonthlyCost  float64     `json:"monthly_cost"`
+	Findings     []FindingID `json:"findings,omitempty"`
+	// ExpiredByRule is the priority of the lifecycle rule that would expire
+	// the image, or 0 if it would be kept.
+	ExpiredByRule int `json:"expired_by_rule,omitempty"`
+	// Layers is set when the scan fetched image manifests.
+	Layers *LayerAnalysis `json:"layers,omitempty"`
+}
+
+// ImageFindings maps resource IDs to the IDs of the findings raised on them.
+func ImageFindings(findings []Finding) map[string][]FindingID {
+	out := make(map[string][]FindingID)
+	for _, f := range findings {
+		if f.ResourceType == ResourceImage || f.ResourceType == ResourcePackageVersion {
+			out[f.ResourceID] = append(out[f.ResourceID], f.ID)
+		}
+	}
+	return out
+}
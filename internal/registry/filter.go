@@ -0,0 +1,101 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// RepoFilter selects repositories by name. Patterns are globs where `*`
+// matches any run of characters (including `/`) and `?` one character, or
+// regular expressions when prefixed with `re:`. A leading `!` in an include
+// pattern turns it into an exclusion. The zero value matches everything.
+type RepoFilter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+}
+
+// NewRepoFilter compiles include and exclude patterns.
+func NewRepoFilter(include, exclude []string) (RepoFilter, error) {
+	var f RepoFilter
+	// Negated includes are added to a copy, not to the caller's slice.
+	exclude = slices.Clone(exclude)
+	for _, p := range include {
+		if rest, ok := strings.CutPrefix(p, "!"); ok {
+			exclude = append(exclude, rest)
+			continue
+		}
+		re, err := compileRepoPattern(p)
+		if err != nil {
+			return RepoFilter{}, err
+		}
+		f.include = append(f.include, re)
+	}
+	for _, p := range exclude {
+		re, err := compileRepoPattern(strings.TrimPrefix(p, "!"))
+		if err != nil {
+			return RepoFilter{}, err
+		}
+		f.exclude = append(f.exclude, re)
+	}
+	return f, nil
+}
+
+// Match reports whether a repository is in scope: it matches at least one
+// include pattern (or none are set) and no exclude pattern.
+func (f RepoFilter) Match(name string) bool {
+	for _, re := range f.exclude {
+		if re.MatchString(name) {
+			return false
+		}
+	}
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, re := range f.include {
+		if re.MatchString(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterRepos returns the items whose name is in scope of the filter.
+func FilterRepos[T any](items []T, name func(T) string, f RepoFilter) []T {
+	if len(f.include) == 0 && len(f.exclude) == 0 {
+		return items
+	}
+	out := items[:0:0]
+	for _, item := range items {
+		if f.Match(name(item)) {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func compileRepoPattern(p string) (*regexp.Regexp, error) {
+	if expr, ok := strings.CutPrefix(p, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid repository pattern %q: %w", p, err)
+		}
+		return re, nil
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range p {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String()), nil
+}
@@ -0,0 +1,64 @@
+package registry
+
+import "testing"
+
+func TestRepoFilterMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		repo    string
+		want    bool
+	}{
+		{"empty matches all", nil, nil, "anything", true},
+		{"glob include", []string{"team-a/*"}, nil, "team-a/api", true},
+		{"glob include nested", []string{"team-a/*"}, nil, "team-a/svc/api", true},
+		{"glob include miss", []string{"team-a/*"}, nil, "team-b/api", false},
+		{"negated include", []string{"team-a/*", "!*-cache"}, nil, "team-a/build-cache", false},
+		{"exclude flag", nil, []string{"*-cache"}, "team-a/build-cache", false},
+		{"exclude flag keeps others", nil, []string{"*-cache"}, "team-a/api", true},
+		{"question mark", []string{"app?"}, nil, "app1", true},
+		{"literal dot", []string{"a.b"}, nil, "axb", false},
+		{"regex", []string{"re:^svc-[0-9]+$"}, nil, "svc-42", true},
+		{"regex miss", []string{"re:^svc-[0-9]+$"}, nil, "svc-x", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := NewRepoFilter(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("NewRepoFilter: %v", err)
+			}
+			if got := f.Match(tt.repo); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.repo, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewRepoFilterInvalidRegex(t *testing.T) {
+	if _, err := NewRepoFilter([]string{"re:("}, nil); err == nil {
+		t.Error("expected error for invalid regex")
+	}
+	if _, err := NewRepoFilter(nil, []string{"re:["}); err == nil {
+		t.Error("expected error for invalid exclude regex")
+	}
+}
+
+func TestNewRepoFilterLeavesExcludeUntouched(t *testing.T) {
+	backing := []string{"tmp-*", "spare"}
+	exclude := backing[:1]
+	if _, err := NewRepoFilter([]string{"!legacy-*"}, exclude); err != nil {
+		t.Fatal(err)
+	}
+	if backing[1] != "spare" {
+		t.Errorf("caller's backing array overwritten: %v", backing)
+	}
+}
+
+func TestFilterRepos(t *testing.T) {
+	f, _ := NewRepoFilter([]string{"team-a/*"}, nil)
+	got := FilterRepos([]string{"team-a/x", "team-b/y", "team-a/z"}, func(s string) string { return s }, f)
+	if len(got) != 2 || got[0] != "team-a/x" || got[1] != "team-a/z" {
+		t.Errorf("FilterRepos = %v", got)
+	}
+}
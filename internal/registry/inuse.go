@@ -0,0 +1,130 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/imageref"
+)
+
+// InUse records container image references that are currently deployed,
+// keyed by digest and by repository:tag, with the workloads that use them.
+// A nil *InUse matches nothing.
+type InUse struct {
+	refs map[string]map[string]bool
+}
+
+// NewInUse creates an empty in-use set.
+func NewInUse() *InUse {
+	return &InUse{refs: make(map[string]map[string]bool)}
+}
+
+// ImageRef is a parsed container image reference.
+type ImageRef struct {
+	Host       string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseImageRef splits a reference such as
+// 123456789012.dkr.ecr.us-east-1.amazonaws.com/team/api:v1@sha256:abc into
+// host, repository path, tag and digest. References without a tag or digest
+// default to the latest tag. See imageref.Parse for validation and
+// provider details.
+func ParseImageRef(ref string) ImageRef {
+	r := imageref.Split(ref)
+	return ImageRef{Host: r.Host, Repository: r.Repository, Tag: r.Tag, Digest: r.Digest}
+}
+
+// Add records an image reference used by source (e.g. "ecs:cluster/service").
+func (u *InUse) Add(ref, source string) {
+	if ref == "" {
+		return
+	}
+	r := ParseImageRef(ref)
+	if r.Digest != "" {
+		u.add(r.Digest, source)
+	}
+	if r.Tag != "" {
+		u.add(r.Repository+":"+r.Tag, source)
+	}
+}
+
+func (u *InUse) add(key, source string) {
+	if u.refs[key] == nil {
+		u.refs[key] = make(map[string]bool)
+	}
+	u.refs[key][source] = true
+}
+
+// Merge adds every reference from other.
+func (u *InUse) Merge(other *InUse) {
+	if other == nil {
+		return
+	}
+	for key, sources := range other.refs {
+		for s := range sources {
+			u.add(key, s)
+		}
+	}
+}
+
+// Len returns the number of distinct references recorded.
+func (u *InUse) Len() int {
+	if u == nil {
+		return 0
+	}
+	return len(u.refs)
+}
+
+// Lookup returns the sorted sources using an image identified by its
+// repository, digest and tags, or nil if it is not deployed.
+func (u *InUse) Lookup(repo, digest string, tags []string) []string {
+	if u == nil {
+		return nil
+	}
+	found := make(map[string]bool)
+	for s := range u.refs[digest] {
+		found[s] = true
+	}
+	for _, tag := range tags {
+		for s := range u.refs[repo+":"+tag] {
+			found[s] = true
+		}
+	}
+	if len(found) == 0 {
+		return nil
+	}
+	sources := make([]string, 0, len(found))
+	for s := range found {
+		sources = append(sources, s)
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+// MarkInUse downgrades a finding for an image that is currently deployed and
+// records the workloads using it.
+func MarkInUse(f *Finding, sources []string) {
+	f.Severity = SeverityLow
+	f.Message += fmt.Sprintf(", in use by %s", strings.Join(sources, ", "))
+	AnnotateInUse(f, sources)
+}
+
+// AnnotateInUse records the workloads using a finding's image without
+// changing its severity.
+func AnnotateInUse(f *Finding, sources []string) {
+	if f.Metadata == nil {
+		f.Metadata = make(map[string]any)
+	}
+	f.Metadata["in_use"] = true
+	f.Metadata["in_use_by"] = sources
+}
+
+// IsInUse reports whether a finding was marked as affecting a deployed image.
+func IsInUse(f Finding) bool {
+	inUse, _ := f.Metadata["in_use"].(bool)
+	return inUse
+}
@@ -0,0 +1,54 @@
+package registry
+
+import "testing"
+
+func TestParseImageRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want ImageRef
+	}{
+		{"123456789012.dkr.ecr.us-east-1.amazonaws.com/team/api:v1", ImageRef{Host: "123456789012.dkr.ecr.us-east-1.amazonaws.com", Repository: "team/api", Tag: "v1"}},
+		{"123456789012.dkr.ecr.us-east-1.amazonaws.com/api@sha256:abc", ImageRef{Host: "123456789012.dkr.ecr.us-east-1.amazonaws.com", Repository: "api", Digest: "sha256:abc"}},
+		{"docker-pullable://registry:5000/api:v2@sha256:def", ImageRef{Host: "registry:5000", Repository: "api", Tag: "v2", Digest: "sha256:def"}},
+		{"nginx", ImageRef{Repository: "nginx", Tag: "latest"}},
+		{"library/nginx:1.27", ImageRef{Repository: "library/nginx", Tag: "1.27"}},
+	}
+	for _, tt := range tests {
+		if got := ParseImageRef(tt.ref); got != tt.want {
+			t.Errorf("ParseImageRef(%q) = %+v, want %+v", tt.ref, got, tt.want)
+		}
+	}
+}
+
+func TestInUseLookup(t *testing.T) {
+	u := NewInUse()
+	u.Add("host.example.com/team/api:v1", "ecs:prod/service:api")
+	u.Add("host.example.com/team/worker@sha256:w1", "lambda:worker")
+	u.Add("", "ignored")
+
+	if got := u.Lookup("team/api", "sha256:other", []string{"v0", "v1"}); len(got) != 1 || got[0] != "ecs:prod/service:api" {
+		t.Errorf("tag lookup = %v", got)
+	}
+	if got := u.Lookup("anything", "sha256:w1", nil); len(got) != 1 || got[0] != "lambda:worker" {
+		t.Errorf("digest lookup = %v", got)
+	}
+	if got := u.Lookup("team/api", "sha256:x", []string{"v2"}); got != nil {
+		t.Errorf("expected no match, got %v", got)
+	}
+	if u.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", u.Len())
+	}
+
+	var empty *InUse
+	if empty.Lookup("team/api", "sha256:w1", []string{"v1"}) != nil || empty.Len() != 0 {
+		t.Error("nil InUse should match nothing")
+	}
+}
+
+func TestMarkInUse(t *testing.T) {
+	f := Finding{Severity: SeverityHigh, Message: "Untagged image"}
+	MarkInUse(&f, []string{"k8s:prod/default/api"})
+	if f.Severity != SeverityLow || f.Metadata["in_use"] != true {
+		t.Errorf("finding not downgraded: %+v", f)
+	}
+}
@@ -0,0 +1,185 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Manifest media types accepted when fetching image manifests.
+var ManifestMediaTypes = []string{
+	"application/vnd.docker.distribution.manifest.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.index.v1+json",
+}
+
+// MinDuplicateLayerBytes is the smallest layer considered for DUPLICATE_LAYERS.
+const MinDuplicateLayerBytes = 10 * 1024 * 1024
+
+// largestLayersCount is how many of the largest layers are reported per image.
+const largestLayersCount = 3
+
+// Layer is a single layer (or config blob) referenced by an image manifest.
+// In an index, entries are child manifests and carry their platform.
+type Layer struct {
+	Digest    string    `json:"digest"`
+	Size      int64     `json:"size"`
+	MediaType string    `json:"mediaType,omitempty"`
+	Platform  *Platform `json:"platform,omitempty"`
+}
+
+// Platform identifies the target of a child manifest in an index.
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// String returns the platform as os/arch[/variant].
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// IsAttestation reports whether the entry is a build attestation rather than
+// a runnable image (buildx stores these as unknown/unknown).
+func (p Platform) IsAttestation() bool {
+	return p.OS == "unknown" && p.Architecture == "unknown"
+}
+
+// PlatformUsed reports whether platform p is in the used list. "linux/arm64"
+// matches any variant of that platform.
+func PlatformUsed(used []string, p Platform) bool {
+	s := p.String()
+	for _, u := range used {
+		if s == u || strings.HasPrefix(s, u+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// Manifest is the subset of a Docker v2 / OCI image manifest used for layer analysis.
+type Manifest struct {
+	MediaType string  `json:"mediaType"`
+	Config    Layer   `json:"config"`
+	Layers    []Layer `json:"layers"`
+	Manifests []Layer `json:"manifests"`
+	// ArtifactType and Subject are set on OCI referrer artifacts such as
+	// signatures; Subject is the manifest the artifact refers to.
+	ArtifactType string `json:"artifactType,omitempty"`
+	Subject      *Layer `json:"subject,omitempty"`
+}
+
+// ParseManifest decodes an image manifest document.
+func ParseManifest(text string) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal([]byte(text), &m); err != nil {
+		return nil, fmt.Errorf("parse manifest: %w", err)
+	}
+	return &m, nil
+}
+
+// IsIndex reports whether the manifest is a multi-platform index. Indexes
+// reference other manifests rather than layers.
+func (m *Manifest) IsIndex() bool {
+	return len(m.Manifests) > 0 || strings.Contains(m.MediaType, "manifest.list") || strings.Contains(m.MediaType, "image.index")
+}
+
+// LayerAnalysis summarizes the layers of one image manifest.
+type LayerAnalysis struct {
+	// ConfigDigest is the digest of the image config blob, which holds the
+	// build date.
+	ConfigDigest string `json:"config_digest,omitempty"`
+	// CompressedBytes is the sum of layer and config blob sizes in the manifest.
+	CompressedBytes int64   `json:"compressed_bytes"`
+	LayerCount      int     `json:"layer_count"`
+	Largest         []Layer `json:"largest_layers"`
+	// DuplicateBytes is the size of layer copies beyond the first for large
+	// layers that repeat inside the image, typically from a Dockerfile that
+	// re-adds or re-permissions the same files in a later step.
+	DuplicateBytes  int64 `json:"duplicate_bytes"`
+	DuplicateLayers int   `json:"duplicate_layers"`
+}
+
+// AnalyzeLayers computes the layer analysis for a manifest. Large layers are
+// treated as duplicates when they share a digest.
+func AnalyzeLayers(m *Manifest) LayerAnalysis {
+	a := LayerAnalysis{ConfigDigest: m.Config.Digest, LayerCount: len(m.Layers), CompressedBytes: m.Config.Size}
+	for _, l := range m.Layers {
+		a.CompressedBytes += l.Size
+	}
+
+	sorted := make([]Layer, len(m.Layers))
+	copy(sorted, m.Layers)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Size > sorted[j].Size })
+	if len(sorted) > largestLayersCount {
+		sorted = sorted[:largestLayersCount]
+	}
+	a.Largest = sorted
+
+	seen := make(map[string]bool)
+	for _, l := range m.Layers {
+		if l.Size < MinDuplicateLayerBytes {
+			continue
+		}
+		if seen[l.Digest] {
+			a.DuplicateLayers++
+			a.DuplicateBytes += l.Size
+			continue
+		}
+		seen[l.Digest] = true
+	}
+	return a
+}
+
+// ManifestRef describes one manifest of a repository for orphan detection.
+type ManifestRef struct {
+	Digest string
+	Tagged bool
+	// IsIndex is set for multi-arch indexes; Index holds the resolved
+	// index manifest, or nil if it could not be fetched.
+	IsIndex bool
+	Index   *Manifest
+}
+
+// OrphanedManifests returns the digests of untagged platform manifests that
+// no multi-arch index in the repository references: child manifests left
+// behind after a pipeline rebuilt or dropped its indexes. It returns nil
+// unless the repository has at least one index and every index was
+// resolved, since an unresolved index may reference any manifest.
+func OrphanedManifests(refs []ManifestRef) map[string]bool {
+	referenced := make(map[string]bool)
+	indexes := 0
+	for _, r := range refs {
+		if !r.IsIndex {
+			continue
+		}
+		if r.Index == nil {
+			return nil
+		}
+		indexes++
+		for _, child := range r.Index.Manifests {
+			referenced[child.Digest] = true
+		}
+	}
+	if indexes == 0 {
+		return nil
+	}
+	var orphans map[string]bool
+	for _, r := range refs {
+		if r.IsIndex || r.Tagged || r.Digest == "" || referenced[r.Digest] {
+			continue
+		}
+		if orphans == nil {
+			orphans = make(map[string]bool)
+		}
+		orphans[r.Digest] = true
+	}
+	return orphans
+}
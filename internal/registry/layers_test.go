@@ -0,0 +1,101 @@
+package registry
+
+import "testing"
+
+const mb = 1024 * 1024
+
+func TestParseManifestIndex(t *testing.T) {
+	m, err := ParseManifest(`{"mediaType":"application/vnd.oci.image.index.v1+json","manifests":[{"digest":"sha256:a","size":500}]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !m.IsIndex() {
+		t.Error("expected index")
+	}
+	if _, err := ParseManifest("{"); err == nil {
+		t.Error("expected error for invalid manifest")
+	}
+}
+
+func TestPlatformUsed(t *testing.T) {
+	arm := Platform{OS: "linux", Architecture: "arm64", Variant: "v8"}
+	if arm.String() != "linux/arm64/v8" {
+		t.Errorf("String() = %q", arm.String())
+	}
+	tests := []struct {
+		used []string
+		want bool
+	}{
+		{[]string{"linux/amd64"}, false},
+		{[]string{"linux/arm64"}, true},
+		{[]string{"linux/arm64/v8"}, true},
+		{[]string{"linux/arm64/v7"}, false},
+	}
+	for _, tt := range tests {
+		if got := PlatformUsed(tt.used, arm); got != tt.want {
+			t.Errorf("PlatformUsed(%v) = %v, want %v", tt.used, got, tt.want)
+		}
+	}
+	if !(Platform{OS: "unknown", Architecture: "unknown"}).IsAttestation() {
+		t.Error("unknown/unknown should be an attestation")
+	}
+}
+
+func TestAnalyzeLayers(t *testing.T) {
+	m := &Manifest{
+		Config: Layer{Digest: "sha256:cfg", Size: 1000},
+		Layers: []Layer{
+			{Digest: "sha256:base", Size: 80 * mb},
+			{Digest: "sha256:app", Size: 200 * mb},
+			{Digest: "sha256:app2", Size: 200 * mb}, // same size, different content
+			{Digest: "sha256:small", Size: 1 * mb},
+			{Digest: "sha256:small2", Size: 1 * mb}, // below threshold, ignored
+			{Digest: "sha256:base", Size: 80 * mb},  // exact duplicate
+		},
+	}
+
+	a := AnalyzeLayers(m)
+	if a.LayerCount != 6 {
+		t.Errorf("LayerCount = %d, want 6", a.LayerCount)
+	}
+	if want := int64(562*mb + 1000); a.CompressedBytes != want {
+		t.Errorf("CompressedBytes = %d, want %d", a.CompressedBytes, want)
+	}
+	if len(a.Largest) != 3 || a.Largest[0].Digest != "sha256:app" {
+		t.Errorf("Largest = %+v", a.Largest)
+	}
+	if a.DuplicateLayers != 1 || a.DuplicateBytes != 80*mb {
+		t.Errorf("duplicates = %d/%d, want 1/%d", a.DuplicateLayers, a.DuplicateBytes, 80*mb)
+	}
+}
+
+func TestAnalyzeLayersNoDuplicates(t *testing.T) {
+	a := AnalyzeLayers(&Manifest{Layers: []Layer{{Digest: "sha256:a", Size: 50 * mb}, {Digest: "sha256:b", Size: 60 * mb}}})
+	if a.DuplicateLayers != 0 {
+		t.Errorf("DuplicateLayers = %d, want 0", a.DuplicateLayers)
+	}
+}
+
+func TestOrphanedManifests(t *testing.T) {
+	index := &Manifest{Manifests: []Layer{{Digest: "sha256:amd"}, {Digest: "sha256:arm"}}}
+	refs := []ManifestRef{
+		{Digest: "sha256:idx", Tagged: true, IsIndex: true, Index: index},
+		{Digest: "sha256:amd"},
+		{Digest: "sha256:arm"},
+		{Digest: "sha256:old-amd"},
+		{Digest: "sha256:single", Tagged: true},
+	}
+
+	got := OrphanedManifests(refs)
+	if len(got) != 1 || !got["sha256:old-amd"] {
+		t.Errorf("OrphanedManifests = %v, want only sha256:old-amd", got)
+	}
+
+	refs[0].Index = nil
+	if got := OrphanedManifests(refs); got != nil {
+		t.Errorf("unresolved index should disable detection, got %v", got)
+	}
+	if got := OrphanedManifests(refs[1:]); got != nil {
+		t.Errorf("repository without indexes should report no orphans, got %v", got)
+	}
+}
@@ -0,0 +1,71 @@
+package registry
+
+import "fmt"
+
+// MetadataPeriodWaste is the finding metadata key holding the finding's
+// waste in the report's cost period when that period is not a month.
+const MetadataPeriodWaste = "period_waste"
+
+// CostPeriod is the unit report costs are expressed in. Estimates are
+// monthly; other periods are converted from them.
+type CostPeriod string
+
+const (
+	CostPeriodDay   CostPeriod = "day"
+	CostPeriodMonth CostPeriod = "month"
+	CostPeriodYear  CostPeriod = "year"
+)
+
+// ParseCostPeriod parses a --cost-period value. An empty value is a month.
+func ParseCostPeriod(s string) (CostPeriod, error) {
+	switch CostPeriod(s) {
+	case "", CostPeriodMonth:
+		return CostPeriodMonth, nil
+	case CostPeriodDay, CostPeriodYear:
+		return CostPeriod(s), nil
+	}
+	return "", fmt.Errorf("unknown cost period %q (want day, month, or year)", s)
+}
+
+// FromMonthly converts a monthly cost to the period, taking a month as a
+// twelfth of a 365-day year.
+func (p CostPeriod) FromMonthly(monthly float64) float64 {
+	switch p {
+	case CostPeriodDay:
+		return monthly * 12 / 365
+	case CostPeriodYear:
+		return monthly * 12
+	}
+	return monthly
+}
+
+// Suffix returns the short unit appended to costs, such as "/mo".
+func (p CostPeriod) Suffix() string {
+	switch p {
+	case CostPeriodDay:
+		return "/day"
+	case CostPeriodYear:
+		return "/yr"
+	}
+	return "/mo"
+}
+
+// Adjective returns the period as used in "estimated monthly waste".
+func (p CostPeriod) Adjective() string {
+	switch p {
+	case CostPeriodDay:
+		return "daily"
+	case CostPeriodYear:
+		return "annual"
+	}
+	return "monthly"
+}
+
+// PeriodWaste returns the waste of f in the report's cost period: the value
+// the analyzer recorded, or the monthly estimate.
+func PeriodWaste(f Finding) float64 {
+	if v, ok := f.Metadata[MetadataPeriodWaste].(float64); ok {
+		return v
+	}
+	return f.EstimatedMonthlyWaste
+}
@@ -0,0 +1,49 @@
+package registry
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseCostPeriod(t *testing.T) {
+	for in, want := range map[string]CostPeriod{"": CostPeriodMonth, "month": CostPeriodMonth, "day": CostPeriodDay, "year": CostPeriodYear} {
+		got, err := ParseCostPeriod(in)
+		if err != nil || got != want {
+			t.Errorf("ParseCostPeriod(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseCostPeriod("week"); err == nil {
+		t.Error("ParseCostPeriod(week) succeeded")
+	}
+}
+
+func TestCostPeriodFromMonthly(t *testing.T) {
+	tests := []struct {
+		period CostPeriod
+		want   float64
+		suffix string
+	}{
+		{CostPeriodDay, 12.0 * 12 / 365, "/day"},
+		{CostPeriodMonth, 12, "/mo"},
+		{CostPeriodYear, 144, "/yr"},
+	}
+	for _, tt := range tests {
+		if got := tt.period.FromMonthly(12); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s FromMonthly(12) = %v, want %v", tt.period, got, tt.want)
+		}
+		if got := tt.period.Suffix(); got != tt.suffix {
+			t.Errorf("%s Suffix() = %q, want %q", tt.period, got, tt.suffix)
+		}
+	}
+}
+
+func TestPeriodWaste(t *testing.T) {
+	f := Finding{EstimatedMonthlyWaste: 3}
+	if got := PeriodWaste(f); got != 3 {
+		t.Errorf("PeriodWaste without metadata = %v, want 3", got)
+	}
+	f.Metadata = map[string]any{MetadataPeriodWaste: 36.0}
+	if got := PeriodWaste(f); got != 36 {
+		t.Errorf("PeriodWaste = %v, want 36", got)
+	}
+}
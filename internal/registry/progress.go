@@ -0,0 +1,83 @@
+package registry
+
+import "sync"
+
+// ProgressBuffer is the number of events a ProgressDispatcher holds before
+// Send blocks.
+const ProgressBuffer = 64
+
+// ProgressDispatcher fans progress events from any number of goroutines into
+// one consumer running on its own goroutine, so the consumer needs no
+// locking and a slow one (a terminal, a named pipe) does not hold up the
+// scan until the buffer fills.
+//
+// Ordering: events sent by one goroutine reach the consumer in the order
+// they were sent. Events from different goroutines are interleaved in the
+// order their sends complete, with no other guarantee between them. Every
+// event sent before Close is consumed before Close returns; events sent
+// after it are dropped. Send blocks while the buffer is full rather than
+// losing events.
+//
+// A nil dispatcher discards events, like a nil progress callback.
+type ProgressDispatcher[T any] struct {
+	events chan T
+	done   chan struct{}
+	mu     sync.RWMutex // held for reading by senders, for writing by Close
+	closed bool
+}
+
+// NewProgressDispatcher starts a dispatcher delivering events to consume.
+// It returns nil when consume is nil. consume must not call Send or Close
+// on its own dispatcher.
+func NewProgressDispatcher[T any](buffer int, consume func(T)) *ProgressDispatcher[T] {
+	if consume == nil {
+		return nil
+	}
+	d := &ProgressDispatcher[T]{events: make(chan T, buffer), done: make(chan struct{})}
+	go func() {
+		defer close(d.done)
+		for event := range d.events {
+			consume(event)
+		}
+	}()
+	return d
+}
+
+// Send queues an event for the consumer. It is safe for concurrent use.
+func (d *ProgressDispatcher[T]) Send(event T) {
+	if d == nil {
+		return
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if !d.closed {
+		d.events <- event
+	}
+}
+
+// Close waits for the consumer to handle every queued event and stops it.
+// It is safe to call more than once.
+func (d *ProgressDispatcher[T]) Close() {
+	if d == nil {
+		return
+	}
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.events)
+	}
+	d.mu.Unlock()
+	<-d.done
+}
+
+// DispatchProgress puts a dispatcher between a scanner and its progress
+// callback. The scanner reports to the returned function, which is safe for
+// concurrent use, and calls stop before returning so that every event has
+// reached progress by then. Both are no-ops when progress is nil.
+func DispatchProgress(progress func(ScanProgress)) (send func(ScanProgress), stop func()) {
+	d := NewProgressDispatcher(ProgressBuffer, progress)
+	if d == nil {
+		return nil, func() {}
+	}
+	return d.Send, d.Close
+}
@@ -0,0 +1,61 @@
+package registry
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestProgressDispatcher(t *testing.T) {
+	var got []int
+	d := NewProgressDispatcher(4, func(i int) { got = append(got, i) })
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				d.Send(g*1000 + i)
+			}
+		}()
+	}
+	wg.Wait()
+	d.Close()
+	d.Close()
+	d.Send(-1) // dropped after Close
+
+	if len(got) != 400 {
+		t.Fatalf("consumed %d events, want 400", len(got))
+	}
+	next := map[int]int{}
+	for _, v := range got {
+		g, i := v/1000, v%1000
+		if i != next[g] {
+			t.Fatalf("sender %d: got event %d, want %d", g, i, next[g])
+		}
+		next[g]++
+	}
+}
+
+func TestDispatchProgressNil(t *testing.T) {
+	if NewProgressDispatcher[int](1, nil) != nil {
+		t.Error("expected a nil dispatcher without a consumer")
+	}
+	var d *ProgressDispatcher[int]
+	d.Send(1)
+	d.Close()
+
+	send, stop := DispatchProgress(nil)
+	if send != nil {
+		t.Error("expected a nil send function without a callback")
+	}
+	stop()
+
+	var stages []string
+	send, stop = DispatchProgress(func(p ScanProgress) { stages = append(stages, p.Stage) })
+	send(ScanProgress{Stage: StageDiscover})
+	send(ScanProgress{Stage: StageDone})
+	stop()
+	if len(stages) != 2 || stages[1] != StageDone {
+		t.Errorf("stages = %v", stages)
+	}
+}
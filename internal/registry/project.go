@@ -0,0 +1,139 @@
+package registry
+
+import (
+	"fmt"
+	"maps"
+)
+
+// MetadataProject is the finding metadata key naming the project a finding
+// belongs to in multi-project scans.
+const MetadataProject = "project"
+
+// MetadataProvider and MetadataTarget are the finding metadata keys naming
+// the cloud provider ("aws" or "gcp") and the configured target a finding
+// belongs to in cross-provider scans.
+const (
+	MetadataProvider = "provider"
+	MetadataTarget   = "target"
+)
+
+// MergeProjectResults combines the results of scanning several projects in
+// the given order. Findings are tagged with their project, errors are
+// prefixed with it, and usage is keyed by project/location/repository so
+// repositories with the same name in different projects stay distinct. A single project's
+// result is returned unchanged.
+func MergeProjectResults(projects []string, results map[string]*ScanResult) *ScanResult {
+	if len(projects) == 1 {
+		return results[projects[0]]
+	}
+	merged := mergeResults(projects, results, MetadataProject)
+	merged.RepositoriesByProject = make(map[string]int, len(projects))
+	for _, project := range projects {
+		if r := results[project]; r != nil {
+			merged.RepositoriesByProject[project] = r.RepositoriesScanned
+		}
+	}
+	return merged
+}
+
+// MergeTargetResults combines the results of the targets of a cross-provider
+// scan like MergeProjectResults, tagging findings with their target and its
+// provider from providers. A single target is merged too, so every report
+// carries the attribution.
+func MergeTargetResults(targets []string, providers map[string]string, results map[string]*ScanResult) *ScanResult {
+	for _, target := range targets {
+		if r := results[target]; r != nil {
+			Annotate(r.Findings, map[string]string{MetadataProvider: providers[target]})
+		}
+	}
+	return mergeResults(targets, results, MetadataTarget)
+}
+
+// MergeRegionResults combines the results of scanning several regions of
+// one account. Findings, errors, timings and usage already name their
+// region, so nothing is prefixed. A single region's result is returned
+// unchanged.
+func MergeRegionResults(regions []string, results map[string]*ScanResult) *ScanResult {
+	if len(regions) == 1 {
+		return results[regions[0]]
+	}
+	return mergeResults(regions, results, "")
+}
+
+// mergeResults combines results in the given order, recording each name in
+// finding and usage metadata under key and prefixing errors, usage keys and
+// timings with it. With an empty key, nothing is prefixed.
+func mergeResults(names []string, results map[string]*ScanResult, key string) *ScanResult {
+	merged := &ScanResult{}
+	var planned, completed int
+	var covered float64
+	for _, name := range names {
+		r := results[name]
+		if r == nil {
+			continue
+		}
+		for _, f := range r.Findings {
+			if key != "" {
+				if f.Metadata == nil {
+					f.Metadata = make(map[string]any)
+				}
+				f.Metadata[key] = name
+			}
+			merged.Findings = append(merged.Findings, f)
+		}
+		for _, e := range r.Errors {
+			if key != "" {
+				e = fmt.Sprintf("%s: %s", name, e)
+			}
+			merged.Errors = append(merged.Errors, e)
+		}
+		for _, e := range r.TargetErrors {
+			if key != "" {
+				e.Target = name + "/" + e.Target
+			}
+			merged.TargetErrors = append(merged.TargetErrors, e)
+		}
+		merged.Targets += r.Targets
+		for k, u := range r.Usage {
+			if key != "" {
+				u.Metadata = maps.Clone(u.Metadata)
+				if u.Metadata == nil {
+					u.Metadata = make(map[string]string)
+				}
+				u.Metadata[key] = name
+				k = name + "/" + k
+			}
+			if merged.Usage == nil {
+				merged.Usage = make(map[string]RepoUsage)
+			}
+			merged.Usage[k] = u
+		}
+		for _, t := range r.Timings {
+			if key != "" {
+				t.Repository = name + "/" + t.Repository
+			}
+			merged.Timings = append(merged.Timings, t)
+		}
+		for _, rec := range r.Recommendations {
+			if key != "" {
+				rec.Repository = name + "/" + rec.Repository
+			}
+			merged.Recommendations = append(merged.Recommendations, rec)
+		}
+		merged.ResourcesScanned += r.ResourcesScanned
+		merged.RepositoriesScanned += r.RepositoriesScanned
+
+		planned += r.Coverage.RepositoriesPlanned
+		completed += r.Coverage.RepositoriesCompleted
+		covered += r.Coverage.Percent * float64(r.Coverage.RepositoriesPlanned)
+		merged.Coverage.Truncated = merged.Coverage.Truncated || r.Coverage.Truncated
+	}
+
+	merged.Coverage.RepositoriesPlanned = planned
+	merged.Coverage.RepositoriesCompleted = completed
+	merged.Coverage.Percent = 100
+	if planned > 0 {
+		merged.Coverage.Percent = covered / float64(planned)
+	}
+	return merged
+}
@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMergeProjectResultsSingle(t *testing.T) {
+	r := &ScanResult{Errors: []string{"boom"}}
+	if got := MergeProjectResults([]string{"a"}, map[string]*ScanResult{"a": r}); got != r {
+		t.Error("single project result should be returned unchanged")
+	}
+}
+
+func TestMergeProjectResults(t *testing.T) {
+	a := &ScanResult{
+		Findings:            []Finding{{ID: FindingStaleImage, ResourceID: "img"}},
+		Errors:              []string{"us-central1: denied"},
+		Targets:             2,
+		TargetErrors:        []TargetError{{Target: "us-central1", Err: "denied"}},
+		ResourcesScanned:    3,
+		RepositoriesScanned: 2,
+		Coverage:            Coverage{RepositoriesPlanned: 2, RepositoriesCompleted: 2, Percent: 100},
+	}
+	a.RecordUsage("api", "us-central1", 10)
+	b := &ScanResult{
+		Targets:             2,
+		RepositoriesScanned: 2,
+		Coverage:            Coverage{RepositoriesPlanned: 2, RepositoriesCompleted: 1, Percent: 50, Truncated: true},
+	}
+	b.RecordUsage("api", "europe-west1", 20)
+	b.RecordTiming("api", "europe-west1", 4, time.Second)
+
+	m := MergeProjectResults([]string{"a", "b"}, map[string]*ScanResult{"a": a, "b": b})
+
+	if len(m.Findings) != 1 || m.Findings[0].Metadata[MetadataProject] != "a" {
+		t.Errorf("findings = %+v", m.Findings)
+	}
+	if len(m.Errors) != 1 || m.Errors[0] != "a: us-central1: denied" {
+		t.Errorf("errors = %v", m.Errors)
+	}
+	if m.Targets != 4 || len(m.TargetErrors) != 1 || m.TargetErrors[0].Target != "a/us-central1" || m.AllTargetsFailed() {
+		t.Errorf("targets = %d, target errors = %+v", m.Targets, m.TargetErrors)
+	}
+	if u := m.Usage["a/us-central1/api"]; u.SizeBytes != 10 || u.Repository != "api" || u.Metadata[MetadataProject] != "a" {
+		t.Errorf("usage = %v", m.Usage)
+	}
+	if m.Usage["b/europe-west1/api"].SizeBytes != 20 {
+		t.Errorf("usage = %v", m.Usage)
+	}
+	if len(m.Timings) != 1 || m.Timings[0].Repository != "b/api" {
+		t.Errorf("timings = %+v", m.Timings)
+	}
+	if m.ResourcesScanned != 3 || m.RepositoriesScanned != 4 || m.RepositoriesByProject["b"] != 2 {
+		t.Errorf("counts = %d/%d/%v", m.ResourcesScanned, m.RepositoriesScanned, m.RepositoriesByProject)
+	}
+	c := m.Coverage
+	if !c.Truncated || c.RepositoriesPlanned != 4 || c.RepositoriesCompleted != 3 || c.Percent != 75 {
+		t.Errorf("coverage = %+v", c)
+	}
+}
+
+func TestMergeTargetResults(t *testing.T) {
+	prod := &ScanResult{Findings: []Finding{{ID: FindingStaleImage, ResourceID: "app@sha256:1", Metadata: map[string]any{MetadataProject: "p1"}}}, Targets: 1}
+	ecr := &ScanResult{Findings: []Finding{{ID: FindingUntaggedImage, ResourceID: "api@sha256:2"}}, Errors: []string{"slow"}, Targets: 2}
+
+	m := MergeTargetResults([]string{"prod", "gcp-prod"}, map[string]string{"prod": "aws", "gcp-prod": "gcp"},
+		map[string]*ScanResult{"prod": ecr, "gcp-prod": prod})
+
+	if len(m.Findings) != 2 {
+		t.Fatalf("findings = %+v", m.Findings)
+	}
+	if f := m.Findings[0]; f.Metadata[MetadataProvider] != "aws" || f.Metadata[MetadataTarget] != "prod" {
+		t.Errorf("aws finding metadata = %v", f.Metadata)
+	}
+	if f := m.Findings[1]; f.Metadata[MetadataProvider] != "gcp" || f.Metadata[MetadataTarget] != "gcp-prod" || f.Metadata[MetadataProject] != "p1" {
+		t.Errorf("gcp finding metadata = %v", f.Metadata)
+	}
+	if m.Targets != 3 || len(m.Errors) != 1 || m.Errors[0] != "prod: slow" || m.RepositoriesByProject != nil {
+		t.Errorf("merged = %+v", m)
+	}
+
+	single := MergeTargetResults([]string{"only"}, map[string]string{"only": "aws"}, map[string]*ScanResult{"only": {Findings: []Finding{{ID: FindingLargeImage}}}})
+	if single.Findings[0].Metadata[MetadataTarget] != "only" {
+		t.Error("a single target should still be attributed")
+	}
+}
+
+func TestMergeRegionResults(t *testing.T) {
+	east := &ScanResult{Findings: []Finding{{ID: FindingStaleImage, Region: "us-east-1"}}, Targets: 1}
+	east.RecordUsage("app", "us-east-1", 10)
+	west := &ScanResult{Targets: 1}
+	west.AddTargetError("us-west-2", errors.New("denied"))
+	west.RecordUsage("app", "us-west-2", 20)
+
+	if got := MergeRegionResults([]string{"us-east-1"}, map[string]*ScanResult{"us-east-1": east}); got != east {
+		t.Error("single region result should be returned unchanged")
+	}
+	m := MergeRegionResults([]string{"us-east-1", "us-west-2"}, map[string]*ScanResult{"us-east-1": east, "us-west-2": west})
+	if len(m.Findings) != 1 || m.Findings[0].Metadata != nil {
+		t.Errorf("findings = %+v", m.Findings)
+	}
+	if m.Usage["us-east-1/app"].SizeBytes != 10 || m.Usage["us-west-2/app"].SizeBytes != 20 {
+		t.Errorf("usage = %v", m.Usage)
+	}
+	if m.Targets != 2 || m.AllTargetsFailed() || m.TargetErrors[0].Target != "us-west-2" || m.Errors[0] != "us-west-2: denied" {
+		t.Errorf("targets = %d, errors = %+v", m.Targets, m.TargetErrors)
+	}
+}
@@ -0,0 +1,59 @@
+package registry
+
+import "time"
+
+// PullLog records the most recent pull of images observed outside the
+// registry API, e.g. in Artifact Registry audit logs, keyed by
+// repository@digest and repository:tag. A nil *PullLog knows no pulls.
+type PullLog struct {
+	// Since is the start of the observed window: images not seen since then
+	// were not pulled within it.
+	Since time.Time
+	last  map[string]time.Time
+}
+
+// NewPullLog creates an empty pull log covering pulls since the given time.
+func NewPullLog(since time.Time) *PullLog {
+	return &PullLog{Since: since, last: make(map[string]time.Time)}
+}
+
+// Record notes a pull of ref (e.g. project/repo/image@sha256:abc or
+// project/repo/image:v1) at t.
+func (p *PullLog) Record(ref string, t time.Time) {
+	r := ParseImageRef(ref)
+	if r.Digest != "" {
+		p.record(r.Repository+"@"+r.Digest, t)
+	}
+	if r.Tag != "" {
+		p.record(r.Repository+":"+r.Tag, t)
+	}
+}
+
+func (p *PullLog) record(key string, t time.Time) {
+	if t.After(p.last[key]) {
+		p.last[key] = t
+	}
+}
+
+// Len returns the number of distinct references pulled.
+func (p *PullLog) Len() int {
+	if p == nil {
+		return 0
+	}
+	return len(p.last)
+}
+
+// LastPull returns the latest pull of an image identified by its repository
+// path, digest and tags, or the zero time if none was recorded.
+func (p *PullLog) LastPull(repo, digest string, tags []string) time.Time {
+	if p == nil {
+		return time.Time{}
+	}
+	last := p.last[repo+"@"+digest]
+	for _, tag := range tags {
+		if t := p.last[repo+":"+tag]; t.After(last) {
+			last = t
+		}
+	}
+	return last
+}
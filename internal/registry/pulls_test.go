@@ -0,0 +1,29 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPullLogLastPull(t *testing.T) {
+	t1 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(48 * time.Hour)
+	p := NewPullLog(t1.AddDate(0, 0, -30))
+	p.Record("proj/repo/api@sha256:a", t1)
+	p.Record("proj/repo/api:v1", t2)
+	p.Record("proj/repo/api@sha256:a", t1.Add(-time.Hour))
+
+	if got := p.LastPull("proj/repo/api", "sha256:a", nil); !got.Equal(t1) {
+		t.Errorf("digest pull = %v, want %v", got, t1)
+	}
+	if got := p.LastPull("proj/repo/api", "sha256:a", []string{"v1"}); !got.Equal(t2) {
+		t.Errorf("tag pull = %v, want %v", got, t2)
+	}
+	if got := p.LastPull("proj/other/api", "sha256:a", []string{"v1"}); !got.IsZero() {
+		t.Errorf("other repository = %v, want zero", got)
+	}
+	var nilLog *PullLog
+	if !nilLog.LastPull("proj/repo/api", "sha256:a", nil).IsZero() || nilLog.Len() != 0 {
+		t.Error("nil PullLog should know no pulls")
+	}
+}
@@ -0,0 +1,64 @@
+package registry
+
+import "fmt"
+
+// DefaultQuotaThresholdPercent is the storage quota usage that triggers QUOTA_PRESSURE.
+const DefaultQuotaThresholdPercent = 80
+
+// QuotaConfig sets storage budgets checked for QUOTA_PRESSURE. Zero values
+// disable the corresponding check.
+type QuotaConfig struct {
+	// RepositoryBytes applies to every repository without an override.
+	RepositoryBytes int64
+	// Repositories overrides the quota for individual repositories by ID.
+	Repositories map[string]int64
+	// ProjectBytes applies to the total size of all scanned repositories.
+	ProjectBytes int64
+	// ThresholdPercent is the usage that triggers a finding (default 80).
+	ThresholdPercent float64
+}
+
+// RepositoryQuota returns the quota in bytes for a repository, or 0 if none.
+func (q QuotaConfig) RepositoryQuota(repoID string) int64 {
+	if b, ok := q.Repositories[repoID]; ok {
+		return b
+	}
+	return q.RepositoryBytes
+}
+
+// QuotaFinding returns a QUOTA_PRESSURE finding when used reaches the
+// configured share of quota, or nil. Usage at or over the quota is high
+// severity. The finding carries no waste estimate.
+func QuotaFinding(q QuotaConfig, resourceType ResourceType, resourceID, region string, used, quota int64) *Finding {
+	if quota <= 0 {
+		return nil
+	}
+	threshold := q.ThresholdPercent
+	if threshold <= 0 {
+		threshold = DefaultQuotaThresholdPercent
+	}
+	percent := float64(used) / float64(quota) * 100
+	if percent < threshold {
+		return nil
+	}
+
+	severity := SeverityMedium
+	if percent >= 100 {
+		severity = SeverityHigh
+	}
+	const gb = 1024 * 1024 * 1024
+	return &Finding{
+		ID:           FindingQuotaPressure,
+		Severity:     severity,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Region:       region,
+		Message:      fmt.Sprintf("Storage at %.0f%% of quota (%.1f of %.1f GB)", percent, float64(used)/gb, float64(quota)/gb),
+		Metadata: map[string]any{
+			"size_bytes":        used,
+			"quota_bytes":       quota,
+			"usage_percent":     percent,
+			"threshold_percent": threshold,
+		},
+	}
+}
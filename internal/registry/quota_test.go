@@ -0,0 +1,33 @@
+package registry
+
+import "testing"
+
+func TestQuotaFinding(t *testing.T) {
+	const gb = 1024 * 1024 * 1024
+	q := QuotaConfig{}
+
+	if f := QuotaFinding(q, ResourceRepository, "r", "us", 70*gb, 100*gb); f != nil {
+		t.Errorf("70%% usage should not trigger, got %v", f)
+	}
+	f := QuotaFinding(q, ResourceRepository, "r", "us", 85*gb, 100*gb)
+	if f == nil || f.Severity != SeverityMedium || f.ID != FindingQuotaPressure {
+		t.Fatalf("85%% usage should be medium QUOTA_PRESSURE, got %v", f)
+	}
+	if f = QuotaFinding(q, ResourceRepository, "r", "us", 120*gb, 100*gb); f == nil || f.Severity != SeverityHigh {
+		t.Errorf("over-quota usage should be high, got %v", f)
+	}
+	q.ThresholdPercent = 60
+	if QuotaFinding(q, ResourceRepository, "r", "us", 70*gb, 100*gb) == nil {
+		t.Error("custom threshold not applied")
+	}
+	if QuotaFinding(q, ResourceRepository, "r", "us", 70*gb, 0) != nil {
+		t.Error("zero quota disables the check")
+	}
+}
+
+func TestRepositoryQuota(t *testing.T) {
+	q := QuotaConfig{RepositoryBytes: 10, Repositories: map[string]int64{"models": 50}}
+	if q.RepositoryQuota("models") != 50 || q.RepositoryQuota("api") != 10 {
+		t.Errorf("override not applied")
+	}
+}
@@ -0,0 +1,129 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// RetentionRecommendation is a tag-based retention rule for one repository,
+// learned from which of its images are still pulled.
+type RetentionRecommendation struct {
+	Repository string `json:"repository"`
+	Region     string `json:"region"`
+	// TagPattern is the tag family the rule applies to, as an ECR lifecycle
+	// tagPatternList wildcard such as "v*" or "sha-*".
+	TagPattern string `json:"tag_pattern"`
+	Images     int    `json:"images"`
+	// PulledImages counts images of the family pulled within WindowDays.
+	PulledImages int `json:"pulled_images"`
+	WindowDays   int `json:"window_days"`
+	// KeepNewest is set when every pulled image is among the newest
+	// KeepNewest of the family; ExpireAfterDays when none was pulled.
+	KeepNewest      int    `json:"keep_newest,omitempty"`
+	ExpireAfterDays int    `json:"expire_after_days,omitempty"`
+	Message         string `json:"message"`
+}
+
+// TaggedImage is the tag and pull history of one image.
+type TaggedImage struct {
+	Tags     []string
+	PushedAt time.Time
+	// LastPull is zero for an image never pulled.
+	LastPull time.Time
+}
+
+// MinRecommendationImages is the number of images a tag family needs before
+// its pull pattern is trusted for a recommendation.
+const MinRecommendationImages = 5
+
+// minExpireDays is the shortest expiry recommended for a tag family.
+const minExpireDays = 14
+
+var (
+	versionTag = regexp.MustCompile(`^v[0-9]`)
+	prefixTag  = regexp.MustCompile(`^([A-Za-z]+[-_.])`)
+)
+
+// TagPattern returns the family of a tag as a lifecycle wildcard: "v*" for
+// v-prefixed versions and "<prefix>*" for tags such as sha-1a2b3c or
+// pr-123. Other tags (latest, main, bare versions or digests) are moving or
+// unique names without a usable family and yield "".
+func TagPattern(tag string) string {
+	if versionTag.MatchString(tag) {
+		return "v*"
+	}
+	if m := prefixTag.FindStringSubmatch(tag); m != nil {
+		return m[1] + "*"
+	}
+	return ""
+}
+
+// RecommendRetention groups a repository's images by tag family and, for
+// families of at least MinRecommendationImages images, recommends keeping
+// only the newest ones when every image pulled in the last windowDays is
+// among them, or expiring the family after a number of days when none was.
+func RecommendRetention(repo, region string, images []TaggedImage, windowDays int, now time.Time) []RetentionRecommendation {
+	if windowDays <= 0 {
+		return nil
+	}
+	families := make(map[string][]TaggedImage)
+	for _, img := range images {
+		seen := make(map[string]bool)
+		for _, tag := range img.Tags {
+			if p := TagPattern(tag); p != "" && !seen[p] {
+				seen[p] = true
+				families[p] = append(families[p], img)
+			}
+		}
+	}
+	patterns := make([]string, 0, len(families))
+	for p := range families {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+
+	since := now.AddDate(0, 0, -windowDays)
+	var recs []RetentionRecommendation
+	for _, p := range patterns {
+		family := families[p]
+		if len(family) < MinRecommendationImages {
+			continue
+		}
+		sort.SliceStable(family, func(i, j int) bool { return family[i].PushedAt.After(family[j].PushedAt) })
+
+		rec := RetentionRecommendation{Repository: repo, Region: region, TagPattern: p, Images: len(family), WindowDays: windowDays}
+		var newestPulled, longestUse int
+		for rank, img := range family {
+			if img.LastPull.IsZero() {
+				continue
+			}
+			if !img.LastPull.Before(since) {
+				rec.PulledImages++
+				newestPulled = rank + 1
+			}
+			longestUse = max(longestUse, int(img.LastPull.Sub(img.PushedAt).Hours()/24))
+		}
+
+		switch {
+		case rec.PulledImages == 0:
+			rec.ExpireAfterDays = max(minExpireDays, roundUpWeek(longestUse))
+			rec.Message = fmt.Sprintf("No %s image pulled in %d days; safe to expire %s tags after %d days",
+				p, windowDays, p, rec.ExpireAfterDays)
+		case newestPulled < len(family):
+			rec.KeepNewest = newestPulled
+			rec.Message = fmt.Sprintf("All %d %s images pulled in %d days are among the newest %d of %d; keep the newest %d %s tags",
+				rec.PulledImages, p, windowDays, newestPulled, len(family), newestPulled, p)
+		default:
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	return recs
+}
+
+// roundUpWeek rounds days up to whole weeks.
+func roundUpWeek(days int) int {
+	return (days + 6) / 7 * 7
+}
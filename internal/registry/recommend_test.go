@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestTagPattern(t *testing.T) {
+	tests := map[string]string{
+		"v1.2.3":     "v*",
+		"sha-1a2b3c": "sha-*",
+		"pr_42":      "pr_*",
+		"latest":     "",
+		"1.2.3":      "",
+		"9f8e7d6":    "",
+	}
+	for tag, want := range tests {
+		if got := TagPattern(tag); got != want {
+			t.Errorf("TagPattern(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestRecommendRetention(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	var images []TaggedImage
+	for i := range 10 {
+		pushed := now.AddDate(0, 0, -10*(i+1))
+		img := TaggedImage{Tags: []string{fmt.Sprintf("v1.%d.0", 10-i)}, PushedAt: pushed}
+		if i < 3 {
+			img.LastPull = now.AddDate(0, 0, -1)
+		} else {
+			img.LastPull = pushed.AddDate(0, 0, 2)
+		}
+		images = append(images, img)
+		built := pushed.AddDate(0, 0, -30)
+		images = append(images, TaggedImage{Tags: []string{fmt.Sprintf("sha-%03d", i), "latest"}, PushedAt: built, LastPull: built.AddDate(0, 0, 1)})
+	}
+	// Too few images to trust.
+	images = append(images, TaggedImage{Tags: []string{"pr-1"}, PushedAt: now})
+
+	recs := RecommendRetention("api", "us-east-1", images, 30, now)
+	if len(recs) != 2 {
+		t.Fatalf("recommendations = %+v", recs)
+	}
+	sha, v := recs[0], recs[1]
+	if sha.TagPattern != "sha-*" || sha.PulledImages != 0 || sha.ExpireAfterDays != 14 || sha.KeepNewest != 0 {
+		t.Errorf("sha-* = %+v", sha)
+	}
+	if v.TagPattern != "v*" || v.PulledImages != 3 || v.KeepNewest != 3 || v.Images != 10 {
+		t.Errorf("v* = %+v", v)
+	}
+}
+
+func TestRecommendRetentionAllPulled(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	var images []TaggedImage
+	for i := range 6 {
+		images = append(images, TaggedImage{Tags: []string{fmt.Sprintf("v%d", i)}, PushedAt: now.AddDate(0, 0, -i), LastPull: now})
+	}
+	if recs := RecommendRetention("api", "us-east-1", images, 30, now); len(recs) != 0 {
+		t.Errorf("recommendations = %+v, want none", recs)
+	}
+}
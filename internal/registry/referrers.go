@@ -0,0 +1,164 @@
+package registry
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// cosignTag matches the tags cosign stores an image's signature, attestation
+// and SBOM under: sha256-<hex>.sig, .att and .sbom.
+var cosignTag = regexp.MustCompile(`^sha256-([0-9a-f]{64})\.(sig|att|sbom)$`)
+
+// IsCosignTag reports whether a tag names a cosign artifact rather than a
+// release of the image.
+func IsCosignTag(tag string) bool {
+	return cosignTag.MatchString(tag)
+}
+
+// IsSignatureArtifact reports whether an OCI artifact type, or the config
+// media type of an artifact manifest, is a cosign or Notation signature.
+func IsSignatureArtifact(mediaType string) bool {
+	return strings.Contains(mediaType, "cosign.artifact.sig") || strings.Contains(mediaType, "notary.signature")
+}
+
+// IsSBOMArtifact reports whether an OCI artifact type, or the config media
+// type of an artifact manifest, is an SPDX, CycloneDX or Syft SBOM.
+func IsSBOMArtifact(mediaType string) bool {
+	for _, kind := range []string{"spdx", "cyclonedx", "syft", "sbom"} {
+		if strings.Contains(mediaType, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// IsReferrerArtifact reports whether an artifact type is one the scanners
+// resolve referrers for: a signature or an SBOM.
+func IsReferrerArtifact(mediaType string) bool {
+	return IsSignatureArtifact(mediaType) || IsSBOMArtifact(mediaType)
+}
+
+// Referrer is an OCI referrer artifact: what it is and the digest of the
+// manifest it refers to.
+type Referrer struct {
+	ArtifactType string `json:"artifact_type"`
+	Subject      string `json:"subject"`
+}
+
+// Referrer returns the artifact type and subject of a referrer artifact
+// manifest, or false if the manifest has no subject.
+func (m *Manifest) Referrer() (Referrer, bool) {
+	if m.Subject == nil || m.Subject.Digest == "" {
+		return Referrer{}, false
+	}
+	artifactType := m.ArtifactType
+	if artifactType == "" {
+		artifactType = m.Config.MediaType
+	}
+	return Referrer{ArtifactType: artifactType, Subject: m.Subject.Digest}, true
+}
+
+// SignedDigests returns the digests that carry a signature: those named by a
+// cosign "sha256-<hex>.sig" tag in tags, plus the subjects of signature
+// referrers.
+func SignedDigests(tags []string, referrers []Referrer) map[string]bool {
+	return attachedDigests(tags, "sig", referrers, IsSignatureArtifact)
+}
+
+// SBOMDigests returns the digests that have an SBOM attached: those named by
+// a cosign "sha256-<hex>.sbom" tag in tags, plus the subjects of SBOM
+// referrers.
+func SBOMDigests(tags []string, referrers []Referrer) map[string]bool {
+	return attachedDigests(tags, "sbom", referrers, IsSBOMArtifact)
+}
+
+func attachedDigests(tags []string, suffix string, referrers []Referrer, match func(string) bool) map[string]bool {
+	digests := make(map[string]bool)
+	for _, tag := range tags {
+		if m := cosignTag.FindStringSubmatch(tag); m != nil && m[2] == suffix {
+			digests["sha256:"+m[1]] = true
+		}
+	}
+	for _, r := range referrers {
+		if match(r.ArtifactType) {
+			digests[r.Subject] = true
+		}
+	}
+	return digests
+}
+
+// releaseTags returns tags without cosign artifact tags.
+func releaseTags(tags []string) []string {
+	var release []string
+	for _, t := range tags {
+		if !IsCosignTag(t) {
+			release = append(release, t)
+		}
+	}
+	return release
+}
+
+// RequiresSignature reports whether an image with these tags is checked for
+// UNSIGNED_IMAGE: signatures are required, and the image has a release tag
+// (not a cosign artifact tag) matching SignedTags, or any release tag when
+// SignedTags is empty.
+func (c ScanConfig) RequiresSignature(tags []string) bool {
+	if !c.RequireSignatures {
+		return false
+	}
+	release := releaseTags(tags)
+	if len(release) == 0 {
+		return false
+	}
+	return len(c.SignedTags) == 0 || c.SignedTags.Match(release)
+}
+
+// RequiresSBOM reports whether an image is checked for MISSING_SBOM: SBOMs
+// are required and the image has a release tag and was pushed within
+// StaleDays of now (any time when StaleDays is 0). Older images are left to
+// the waste findings.
+func (c ScanConfig) RequiresSBOM(tags []string, pushed, now time.Time) bool {
+	if !c.RequireSBOM || len(releaseTags(tags)) == 0 {
+		return false
+	}
+	return c.StaleDays <= 0 || pushed.After(now.AddDate(0, 0, -c.StaleDays))
+}
+
+// UnsignedFinding builds the UNSIGNED_IMAGE finding for a tagged image with
+// no signature.
+func UnsignedFinding(resourceID, resourceName, region, digest string) Finding {
+	return Finding{
+		ID:           FindingUnsignedImage,
+		Severity:     SeverityMedium,
+		ResourceType: ResourceImage,
+		ResourceID:   resourceID,
+		ResourceName: resourceName,
+		Region:       region,
+		Message:      "Tagged image has no cosign or OCI referrer signature",
+		Metadata: map[string]any{
+			"digest": digest,
+		},
+	}
+}
+
+// MissingSBOMFinding builds the MISSING_SBOM finding for a recent tagged
+// image with no SBOM attached, at the configured severity.
+func MissingSBOMFinding(cfg ScanConfig, resourceID, resourceName, region, digest string) Finding {
+	severity := cfg.SBOMSeverity
+	if severity == "" {
+		severity = SeverityMedium
+	}
+	return Finding{
+		ID:           FindingMissingSBOM,
+		Severity:     severity,
+		ResourceType: ResourceImage,
+		ResourceID:   resourceID,
+		ResourceName: resourceName,
+		Region:       region,
+		Message:      "Recent tagged image has no SBOM attached",
+		Metadata: map[string]any{
+			"digest": digest,
+		},
+	}
+}
@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignedDigests(t *testing.T) {
+	hex := strings.Repeat("0f", 32)
+	referrers := []Referrer{
+		{ArtifactType: "application/vnd.cncf.notary.signature", Subject: "sha256:referred"},
+		{ArtifactType: "application/spdx+json", Subject: "sha256:withsbom"},
+	}
+	tags := []string{"v1", "sha256-" + hex + ".sig", "sha256-" + strings.Repeat("1", 64) + ".att"}
+
+	signed := SignedDigests(tags, referrers)
+	if !signed["sha256:"+hex] || !signed["sha256:referred"] || len(signed) != 2 {
+		t.Errorf("signed = %v", signed)
+	}
+	sboms := SBOMDigests(append(tags, "sha256-"+strings.Repeat("2", 64)+".sbom"), referrers)
+	if !sboms["sha256:"+strings.Repeat("2", 64)] || !sboms["sha256:withsbom"] || len(sboms) != 2 {
+		t.Errorf("sboms = %v", sboms)
+	}
+}
+
+func TestManifestReferrer(t *testing.T) {
+	m, err := ParseManifest(`{"artifactType":"application/vnd.dev.cosign.artifact.sig.v1+json","subject":{"digest":"sha256:img","size":1}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := m.Referrer(); !ok || r.Subject != "sha256:img" || !IsSignatureArtifact(r.ArtifactType) {
+		t.Errorf("Referrer = %+v, %v", r, ok)
+	}
+	legacy, _ := ParseManifest(`{"config":{"mediaType":"application/vnd.cyclonedx+json"},"subject":{"digest":"sha256:img","size":1}}`)
+	if r, ok := legacy.Referrer(); !ok || !IsSBOMArtifact(r.ArtifactType) {
+		t.Errorf("config media type should identify the artifact, got %+v", r)
+	}
+	image, _ := ParseManifest(`{"layers":[{"digest":"sha256:l","size":1}]}`)
+	if _, ok := image.Referrer(); ok {
+		t.Error("an image manifest is not a referrer")
+	}
+}
+
+func TestRequiresSignature(t *testing.T) {
+	release, _ := NewTagPatterns([]string{"^prod-"})
+	cfg := ScanConfig{RequireSignatures: true, SignedTags: release}
+	if !cfg.RequiresSignature([]string{"latest", "prod-eu"}) {
+		t.Error("prod tag should require a signature")
+	}
+	if cfg.RequiresSignature([]string{"dev-1"}) || cfg.RequiresSignature(nil) {
+		t.Error("unmatched or untagged images should not require a signature")
+	}
+	cfg.SignedTags = nil
+	if !cfg.RequiresSignature([]string{"dev-1"}) {
+		t.Error("any tag should require a signature without signed_tags")
+	}
+	if cfg.RequiresSignature([]string{"sha256-" + strings.Repeat("a", 64) + ".sig"}) {
+		t.Error("cosign artifact tags should not require a signature")
+	}
+	if (ScanConfig{}).RequiresSignature([]string{"prod-eu"}) {
+		t.Error("signatures should not be required by default")
+	}
+}
+
+func TestRequiresSBOM(t *testing.T) {
+	now := time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC)
+	cfg := ScanConfig{RequireSBOM: true, StaleDays: 90}
+	if !cfg.RequiresSBOM([]string{"v1"}, now.AddDate(0, 0, -10), now) {
+		t.Error("recent tagged image should require an SBOM")
+	}
+	if cfg.RequiresSBOM([]string{"v1"}, now.AddDate(0, 0, -200), now) || cfg.RequiresSBOM(nil, now, now) {
+		t.Error("old or untagged images should not require an SBOM")
+	}
+	if (ScanConfig{}).RequiresSBOM([]string{"v1"}, now, now) {
+		t.Error("SBOMs should not be required by default")
+	}
+}
@@ -0,0 +1,110 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retained is an image (or package version) considered by keep_latest
+// retention.
+type Retained struct {
+	ID string
+	// Scope separates images that share a repository but not a name, such
+	// as the image paths of an Artifact Registry repository or the
+	// packages of a Maven repository.
+	Scope   string
+	Tags    []string
+	Created time.Time
+}
+
+// TagFamily groups the versions of one release line: a semantic version tag
+// belongs to its major version ("v1.4.2" → "v1.*"), and every other tag
+// ("latest", "main-3f2a1c", build IDs) to the single family "*".
+func TagFamily(tag string) string {
+	v, ok := parseSemver(tag)
+	if !ok {
+		return "*"
+	}
+	prefix := ""
+	if strings.HasPrefix(tag, "v") {
+		prefix = "v"
+	}
+	return prefix + strconv.Itoa(v.parts[0]) + ".*"
+}
+
+// KeepLatest returns the IDs of the newest n images, by creation time, in
+// each scope and tag family. These are the images a rollback would reach for,
+// so they are never reported as stale. Untagged images belong to no family.
+func KeepLatest(images []Retained, n int) map[string]bool {
+	if n <= 0 {
+		return nil
+	}
+	families := make(map[string][]Retained)
+	for _, img := range images {
+		seen := make(map[string]bool)
+		for _, tag := range img.Tags {
+			key := img.Scope + "\x00" + TagFamily(tag)
+			if !seen[key] {
+				seen[key] = true
+				families[key] = append(families[key], img)
+			}
+		}
+	}
+	keep := make(map[string]bool)
+	for _, members := range families {
+		sort.SliceStable(members, func(i, j int) bool {
+			return members[i].Created.After(members[j].Created)
+		})
+		for i := 0; i < len(members) && i < n; i++ {
+			keep[members[i].ID] = true
+		}
+	}
+	return keep
+}
+
+// MetadataProtected is the finding metadata key set on findings for images
+// carrying a protected tag, so cleanup tooling leaves them alone.
+const MetadataProtected = "protected"
+
+// TagPatterns holds tag regular expressions such as protected_tags. The zero
+// value matches nothing.
+type TagPatterns []*regexp.Regexp
+
+// NewTagPatterns compiles tag patterns.
+func NewTagPatterns(patterns []string) (TagPatterns, error) {
+	var p TagPatterns
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag pattern %q: %w", pattern, err)
+		}
+		p = append(p, re)
+	}
+	return p, nil
+}
+
+// Match reports whether any of tags matches a pattern.
+func (p TagPatterns) Match(tags []string) bool {
+	for _, re := range p {
+		for _, t := range tags {
+			if re.MatchString(t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MarkProtected flags findings as belonging to a protected image.
+func MarkProtected(findings []Finding) {
+	for i := range findings {
+		if findings[i].Metadata == nil {
+			findings[i].Metadata = make(map[string]any)
+		}
+		findings[i].Metadata[MetadataProtected] = true
+	}
+}
@@ -0,0 +1,70 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTagFamily(t *testing.T) {
+	cases := map[string]string{
+		"v1.4.2":     "v1.*",
+		"v1.10":      "v1.*",
+		"2.0.1-rc1":  "2.*",
+		"latest":     "*",
+		"main-3f2a1": "*",
+		"20240101":   "*",
+	}
+	for tag, want := range cases {
+		if got := TagFamily(tag); got != want {
+			t.Errorf("TagFamily(%q) = %q, want %q", tag, got, want)
+		}
+	}
+}
+
+func TestKeepLatest(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	images := []Retained{
+		{ID: "a", Tags: []string{"v1.0.0"}, Created: day(1)},
+		{ID: "b", Tags: []string{"v1.1.0"}, Created: day(2)},
+		{ID: "c", Tags: []string{"v1.2.0"}, Created: day(3)},
+		{ID: "d", Tags: []string{"v2.0.0"}, Created: day(4)},
+		{ID: "e", Tags: []string{"sha-1"}, Created: day(5)},
+		{ID: "f", Tags: []string{"sha-2", "latest"}, Created: day(6)},
+		{ID: "g", Created: day(7)},
+		{ID: "h", Scope: "other", Tags: []string{"v1.0.0"}, Created: day(1)},
+	}
+
+	got := KeepLatest(images, 2)
+	for _, id := range []string{"b", "c", "d", "e", "f", "h"} {
+		if !got[id] {
+			t.Errorf("%s should be kept", id)
+		}
+	}
+	for _, id := range []string{"a", "g"} {
+		if got[id] {
+			t.Errorf("%s should not be kept", id)
+		}
+	}
+	if KeepLatest(images, 0) != nil {
+		t.Error("keep_latest 0 should keep nothing")
+	}
+}
+
+func TestTagPatterns(t *testing.T) {
+	p, err := NewTagPatterns([]string{`^v\d+\.\d+\.\d+$`, "^prod-"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Match([]string{"latest", "v1.2.3"}) || !p.Match([]string{"prod-eu"}) {
+		t.Error("release and prod tags should be protected")
+	}
+	if p.Match([]string{"v1.2", "staging-prod-1"}) || p.Match(nil) {
+		t.Error("unmatched tags should not be protected")
+	}
+	if (TagPatterns)(nil).Match([]string{"v1.2.3"}) {
+		t.Error("zero value should match nothing")
+	}
+	if _, err := NewTagPatterns([]string{"("}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
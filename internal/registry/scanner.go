@@ -3,6 +3,9 @@ package registry
 import "context"
 
 // RegistryScanner is the interface for cloud-specific container registry scanners.
+// Scan reports progress through a ProgressDispatcher: progress is called from
+// the dispatcher's goroutine, one event at a time and in the order the scan
+// produced them, and never after Scan returns.
 type RegistryScanner interface {
 	Scan(ctx context.Context, cfg ScanConfig, progress func(ScanProgress)) (*ScanResult, error)
 }
@@ -0,0 +1,289 @@
+package registry
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MetadataSchemaKey is the JSON key under which a finding's metadata is also
+// serialized as the typed struct for its ID. The metadata map stays alongside
+// it for keys outside the schema, such as extra attribution tags. A change
+// that renames a field or changes its type bumps the version in the key.
+const MetadataSchemaKey = "metadata_v1"
+
+// CommonMetadata holds the keys any finding may carry: attribution, the push
+// time of its image and the annotations added by retention, deployment and
+// suppression checks.
+type CommonMetadata struct {
+	Team     string `json:"team,omitempty"`
+	Owner    string `json:"owner,omitempty"`
+	Project  string `json:"project,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	Target   string `json:"target,omitempty"`
+	// PushedAt is RFC 3339, like every time in the schema.
+	PushedAt           string   `json:"pushed_at,omitempty"`
+	Tags               []string `json:"tags,omitempty"`
+	Protected          bool     `json:"protected,omitempty"`
+	InUse              bool     `json:"in_use,omitempty"`
+	InUseBy            []string `json:"in_use_by,omitempty"`
+	SuppressionExpired string   `json:"suppression_expired,omitempty"`
+	SuppressedByWindow string   `json:"suppressed_by_window,omitempty"`
+	Baseline           string   `json:"baseline,omitempty"`
+	SelfResolvingRule  int      `json:"self_resolving_rule,omitempty"`
+	PeriodWaste        float64  `json:"period_waste,omitempty"`
+}
+
+// StaleImageMetadata describes STALE_IMAGE findings. ECR sets LastPull;
+// Artifact Registry sets UploadTime, or CreateTime and Format for package
+// versions.
+type StaleImageMetadata struct {
+	CommonMetadata
+	DaysStale    int    `json:"days_stale"`
+	StaleDays    int    `json:"stale_days"`
+	SizeBytes    int64  `json:"size_bytes"`
+	LastPull     string `json:"last_pull,omitempty"`
+	UploadTime   string `json:"upload_time,omitempty"`
+	CreateTime   string `json:"create_time,omitempty"`
+	FetchTime    string `json:"fetch_time,omitempty"`
+	PullSource   string `json:"pull_source,omitempty"`
+	PullsSince   string `json:"pulls_since,omitempty"`
+	LastPullTime string `json:"last_pull_time,omitempty"`
+	Note         string `json:"note,omitempty"`
+	Format       string `json:"format,omitempty"`
+	FileCount    int    `json:"file_count,omitempty"`
+}
+
+// ImageMetadata describes UNTAGGED_IMAGE, ORPHANED_MANIFEST, UNSIGNED_IMAGE
+// and MISSING_SBOM findings.
+type ImageMetadata struct {
+	CommonMetadata
+	SizeBytes int64  `json:"size_bytes,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	URI       string `json:"uri,omitempty"`
+	MediaType string `json:"media_type,omitempty"`
+}
+
+// LargeImageMetadata describes LARGE_IMAGE findings.
+type LargeImageMetadata struct {
+	CommonMetadata
+	SizeBytes          int64   `json:"size_bytes"`
+	ThresholdBytes     int64   `json:"threshold_bytes"`
+	CompressedBytes    int64   `json:"compressed_bytes,omitempty"`
+	LargestLayers      []Layer `json:"largest_layers,omitempty"`
+	MonthlyPulls       int64   `json:"monthly_pulls,omitempty"`
+	RepositoryPulls    int64   `json:"repository_monthly_pulls,omitempty"`
+	PullCountScope     string  `json:"pull_count_scope,omitempty"`
+	EgressModel        string  `json:"egress_model,omitempty"`
+	EgressMonthlyWaste float64 `json:"egress_monthly_waste,omitempty"`
+	Format             string  `json:"format,omitempty"`
+	FileCount          int     `json:"file_count,omitempty"`
+}
+
+// DuplicateLayersMetadata describes DUPLICATE_LAYERS findings.
+type DuplicateLayersMetadata struct {
+	CommonMetadata
+	DuplicateLayers int     `json:"duplicate_layers"`
+	DuplicateBytes  int64   `json:"duplicate_bytes"`
+	CompressedBytes int64   `json:"compressed_bytes"`
+	ReportedBytes   int64   `json:"reported_bytes"`
+	LargestLayers   []Layer `json:"largest_layers,omitempty"`
+}
+
+// UnusedPlatform is one child manifest of a MULTI_ARCH_BLOAT finding.
+type UnusedPlatform struct {
+	Platform  string `json:"platform"`
+	Digest    string `json:"digest"`
+	SizeBytes int64  `json:"size_bytes"`
+	Reason    string `json:"reason"`
+	LastPull  string `json:"last_pull,omitempty"`
+}
+
+// MultiArchBloatMetadata describes MULTI_ARCH_BLOAT findings.
+type MultiArchBloatMetadata struct {
+	CommonMetadata
+	UnusedPlatforms []UnusedPlatform `json:"unused_platforms"`
+	PlatformCount   int              `json:"platform_count"`
+	SizeBytes       int64            `json:"size_bytes"`
+}
+
+// VulnerableImageMetadata describes VULNERABLE_IMAGE findings.
+type VulnerableImageMetadata struct {
+	CommonMetadata
+	TotalFindings  int            `json:"total_findings"`
+	CriticalCount  int            `json:"critical_count"`
+	HighCount      int            `json:"high_count"`
+	SeverityCounts map[string]int `json:"severity_counts,omitempty"`
+}
+
+// RepositoryMetadata describes NO_LIFECYCLE_POLICY and UNUSED_REPO findings.
+type RepositoryMetadata struct {
+	CommonMetadata
+	ImageCount      int    `json:"image_count,omitempty"`
+	VersionCount    int    `json:"version_count,omitempty"`
+	Format          string `json:"format,omitempty"`
+	CleanupPolicies int    `json:"cleanup_policies,omitempty"`
+	CleanupDryRun   bool   `json:"cleanup_dry_run,omitempty"`
+}
+
+// UntaggedAccumulationMetadata describes UNTAGGED_ACCUMULATION findings.
+type UntaggedAccumulationMetadata struct {
+	CommonMetadata
+	UntaggedCount int    `json:"untagged_count"`
+	StaleCount    int    `json:"stale_count"`
+	SizeBytes     int64  `json:"size_bytes"`
+	Threshold     int    `json:"threshold"`
+	OldestPush    string `json:"oldest_push,omitempty"`
+	NewestPush    string `json:"newest_push,omitempty"`
+}
+
+// StaleRemoteCacheMetadata describes STALE_REMOTE_CACHE findings.
+type StaleRemoteCacheMetadata struct {
+	CommonMetadata
+	Mode            string `json:"mode"`
+	Format          string `json:"format"`
+	CachedItems     int    `json:"cached_items"`
+	StaleItems      int    `json:"stale_items"`
+	CacheBytes      int64  `json:"cache_bytes"`
+	StaleBytes      int64  `json:"stale_bytes"`
+	StaleDays       int    `json:"stale_days"`
+	CleanupPolicies int    `json:"cleanup_policies"`
+}
+
+// StaleReleaseTrainMetadata describes STALE_RELEASE_TRAIN findings.
+type StaleReleaseTrainMetadata struct {
+	CommonMetadata
+	ReleaseCadence   string  `json:"release_cadence"`
+	CadenceDays      float64 `json:"cadence_days"`
+	DaysSinceRelease int     `json:"days_since_release"`
+	NewestPush       string  `json:"newest_push"`
+}
+
+// AgingDeployedImageMetadata describes AGING_DEPLOYED_IMAGE findings.
+type AgingDeployedImageMetadata struct {
+	CommonMetadata
+	Digest       string `json:"digest,omitempty"`
+	BuiltAt      string `json:"built_at"`
+	BuildAgeDays int    `json:"build_age_days"`
+	MaxAgeDays   int    `json:"max_age_days"`
+}
+
+// QuotaPressureMetadata describes QUOTA_PRESSURE findings.
+type QuotaPressureMetadata struct {
+	CommonMetadata
+	SizeBytes        int64   `json:"size_bytes"`
+	QuotaBytes       int64   `json:"quota_bytes"`
+	UsagePercent     float64 `json:"usage_percent"`
+	ThresholdPercent float64 `json:"threshold_percent"`
+}
+
+// StorageSpikeMetadata describes STORAGE_SPIKE findings.
+type StorageSpikeMetadata struct {
+	CommonMetadata
+	PreviousBytes int64   `json:"previous_bytes"`
+	CurrentBytes  int64   `json:"current_bytes"`
+	DeltaBytes    int64   `json:"delta_bytes"`
+	PreviousScan  string  `json:"previous_scan"`
+	GrowthPercent float64 `json:"growth_percent,omitempty"`
+	Sigma         float64 `json:"sigma,omitempty"`
+}
+
+// TieringCandidateMetadata describes TIERING_CANDIDATE findings.
+type TieringCandidateMetadata struct {
+	CommonMetadata
+	SizeBytes          int64   `json:"size_bytes"`
+	DaysStale          int     `json:"days_stale"`
+	RetainedBy         string  `json:"retained_by"`
+	ArchiveTier        string  `json:"archive_tier"`
+	HotMonthlyCost     float64 `json:"hot_monthly_cost"`
+	ArchiveMonthlyCost float64 `json:"archive_monthly_cost"`
+}
+
+// LongTailWasteMetadata describes LONG_TAIL_WASTE findings.
+type LongTailWasteMetadata struct {
+	CommonMetadata
+	FindingCount   int            `json:"finding_count"`
+	FindingsByID   map[string]int `json:"findings_by_id"`
+	MinMonthlyCost float64        `json:"min_monthly_cost"`
+}
+
+// CustomRuleMetadata describes findings of custom rules from config.
+type CustomRuleMetadata struct {
+	CommonMetadata
+	Digest    string `json:"digest,omitempty"`
+	SizeBytes int64  `json:"size_bytes"`
+	Rule      string `json:"rule"`
+}
+
+// newTypedMetadata returns a pointer to the empty metadata struct of a
+// finding ID, or nil for a custom rule.
+func newTypedMetadata(id FindingID) any {
+	switch id {
+	case FindingStaleImage:
+		return &StaleImageMetadata{}
+	case FindingUntaggedImage, FindingOrphanedManifest, FindingUnsignedImage, FindingMissingSBOM:
+		return &ImageMetadata{}
+	case FindingLargeImage:
+		return &LargeImageMetadata{}
+	case FindingDuplicateLayers:
+		return &DuplicateLayersMetadata{}
+	case FindingMultiArchBloat:
+		return &MultiArchBloatMetadata{}
+	case FindingVulnerableImage:
+		return &VulnerableImageMetadata{}
+	case FindingNoLifecyclePolicy, FindingUnusedRepo:
+		return &RepositoryMetadata{}
+	case FindingUntaggedAccumulation:
+		return &UntaggedAccumulationMetadata{}
+	case FindingStaleRemoteCache:
+		return &StaleRemoteCacheMetadata{}
+	case FindingStaleReleaseTrain:
+		return &StaleReleaseTrainMetadata{}
+	case FindingAgingDeployedImage:
+		return &AgingDeployedImageMetadata{}
+	case FindingQuotaPressure:
+		return &QuotaPressureMetadata{}
+	case FindingStorageSpike:
+		return &StorageSpikeMetadata{}
+	case FindingTieringCandidate:
+		return &TieringCandidateMetadata{}
+	case FindingLongTailWaste:
+		return &LongTailWasteMetadata{}
+	}
+	return nil
+}
+
+// TypedMetadata converts the metadata map of f into the struct for its ID,
+// such as *StaleImageMetadata, so numbers have one type whether the finding
+// came from a scan or was read back from a JSON report. Custom rule findings
+// yield *CustomRuleMetadata. A finding without metadata yields nil.
+func (f Finding) TypedMetadata() (any, error) {
+	if len(f.Metadata) == 0 {
+		return nil, nil
+	}
+	typed := newTypedMetadata(f.ID)
+	if typed == nil {
+		typed = &CustomRuleMetadata{}
+	}
+	raw, err := json.Marshal(f.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("%s metadata: %w", f.ID, err)
+	}
+	if err := json.Unmarshal(raw, typed); err != nil {
+		return nil, fmt.Errorf("%s metadata: %w", f.ID, err)
+	}
+	return typed, nil
+}
+
+// MarshalJSON adds the typed metadata under MetadataSchemaKey. Metadata that
+// does not fit the schema is left out of the typed block but kept in the map.
+func (f Finding) MarshalJSON() ([]byte, error) {
+	type plain Finding
+	out := struct {
+		plain
+		Typed any `json:"metadata_v1,omitempty"`
+	}{plain: plain(f)}
+	if typed, err := f.TypedMetadata(); err == nil && typed != nil {
+		out.Typed = typed
+	}
+	return json.Marshal(out)
+}
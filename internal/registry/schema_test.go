@@ -0,0 +1,105 @@
+package registry
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestTypedMetadata(t *testing.T) {
+	f := Finding{
+		ID: FindingStaleImage,
+		Metadata: map[string]any{
+			"days_stale": 120,
+			"stale_days": 90,
+			"size_bytes": int64(1 << 20),
+			"last_pull":  "2026-01-02T00:00:00Z",
+			"team":       "payments",
+			"in_use_by":  []string{"prod/api"},
+			"cost_code":  "cc-1",
+		},
+	}
+	typed, err := f.TypedMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, ok := typed.(*StaleImageMetadata)
+	if !ok {
+		t.Fatalf("TypedMetadata = %T, want *StaleImageMetadata", typed)
+	}
+	if m.DaysStale != 120 || m.StaleDays != 90 || m.SizeBytes != 1<<20 || m.LastPull != "2026-01-02T00:00:00Z" {
+		t.Errorf("typed = %+v", m)
+	}
+	if m.Team != "payments" || len(m.InUseBy) != 1 {
+		t.Errorf("common = %+v", m.CommonMetadata)
+	}
+}
+
+func TestTypedMetadataCustomRuleAndEmpty(t *testing.T) {
+	f := Finding{ID: "OLD_BASE", Metadata: map[string]any{"rule": "age > 30d", "size_bytes": 10}}
+	typed, err := f.TypedMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m, ok := typed.(*CustomRuleMetadata); !ok || m.Rule != "age > 30d" {
+		t.Errorf("TypedMetadata = %#v", typed)
+	}
+	if typed, err := (Finding{ID: FindingStaleImage}).TypedMetadata(); typed != nil || err != nil {
+		t.Errorf("empty metadata = %v, %v", typed, err)
+	}
+}
+
+func TestFindingJSONSchemaRoundTrip(t *testing.T) {
+	f := Finding{
+		ID:       FindingTieringCandidate,
+		Severity: SeverityLow,
+		Metadata: map[string]any{"days_stale": 200, "size_bytes": int64(5 << 30), "retained_by": "keep_latest"},
+	}
+	raw, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Read back, the map holds float64 numbers; the typed block does not.
+	var back Finding
+	if err := json.Unmarshal(raw, &back); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := back.Metadata["days_stale"].(float64); !ok {
+		t.Fatalf("days_stale decoded as %T", back.Metadata["days_stale"])
+	}
+	typed, err := back.TypedMetadata()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := typed.(*TieringCandidateMetadata); m.DaysStale != 200 || m.SizeBytes != 5<<30 {
+		t.Errorf("typed = %+v", m)
+	}
+
+	var doc struct {
+		Typed TieringCandidateMetadata `json:"metadata_v1"`
+	}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Typed.RetainedBy != "keep_latest" || doc.Typed.DaysStale != 200 {
+		t.Errorf("metadata_v1 = %+v", doc.Typed)
+	}
+}
+
+func TestFindingJSONSkipsMismatchedSchema(t *testing.T) {
+	f := Finding{ID: FindingStaleImage, Metadata: map[string]any{"days_stale": "many"}}
+	raw, err := json.Marshal(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := doc[MetadataSchemaKey]; ok {
+		t.Error("metadata_v1 written for metadata outside the schema")
+	}
+	if doc["metadata"] == nil {
+		t.Error("metadata map dropped")
+	}
+}
@@ -0,0 +1,97 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LabelSelector selects AWS accounts by their organization tags or GCP
+// projects by their labels. Terms are "key=value", or "key" for any value.
+// An item is selected when, for every include key, its value is one of the
+// listed values (terms with the same key are alternatives), and no exclude
+// term matches. The zero value selects everything.
+type LabelSelector struct {
+	include map[string][]string
+	exclude map[string][]string
+}
+
+// NewLabelSelector parses include and exclude terms.
+func NewLabelSelector(include, exclude []string) (LabelSelector, error) {
+	var s LabelSelector
+	var err error
+	if s.include, err = parseLabelTerms(include); err != nil {
+		return LabelSelector{}, err
+	}
+	if s.exclude, err = parseLabelTerms(exclude); err != nil {
+		return LabelSelector{}, err
+	}
+	return s, nil
+}
+
+// Empty reports whether the selector selects everything, so callers can
+// skip fetching tags or labels.
+func (s LabelSelector) Empty() bool {
+	return len(s.include) == 0 && len(s.exclude) == 0
+}
+
+// Match reports whether an item with the given tags or labels is selected.
+func (s LabelSelector) Match(labels map[string]string) bool {
+	for key, values := range s.exclude {
+		if labelMatches(labels, key, values) {
+			return false
+		}
+	}
+	for key, values := range s.include {
+		if !labelMatches(labels, key, values) {
+			return false
+		}
+	}
+	return true
+}
+
+// String renders the selector for logs, e.g. "env=prod, !team=sandbox".
+func (s LabelSelector) String() string {
+	var terms []string
+	for prefix, m := range map[string]map[string][]string{"": s.include, "!": s.exclude} {
+		for key, values := range m {
+			for _, v := range values {
+				term := key
+				if v != "" {
+					term += "=" + v
+				}
+				terms = append(terms, prefix+term)
+			}
+		}
+	}
+	sort.Strings(terms)
+	return strings.Join(terms, ", ")
+}
+
+func labelMatches(labels map[string]string, key string, values []string) bool {
+	got, ok := labels[key]
+	if !ok {
+		return false
+	}
+	for _, v := range values {
+		if v == "" || v == got {
+			return true
+		}
+	}
+	return false
+}
+
+func parseLabelTerms(terms []string) (map[string][]string, error) {
+	if len(terms) == 0 {
+		return nil, nil
+	}
+	out := make(map[string][]string, len(terms))
+	for _, t := range terms {
+		key, value, _ := strings.Cut(strings.TrimSpace(t), "=")
+		if key == "" {
+			return nil, fmt.Errorf("invalid label selector %q (use key=value or key)", t)
+		}
+		out[key] = append(out[key], value)
+	}
+	return out, nil
+}
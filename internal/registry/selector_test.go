@@ -0,0 +1,58 @@
+package registry
+
+import "testing"
+
+func TestLabelSelectorMatch(t *testing.T) {
+	prod := map[string]string{"env": "prod", "team": "payments"}
+	sandbox := map[string]string{"env": "sandbox"}
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		labels  map[string]string
+		want    bool
+	}{
+		{"empty selects all", nil, nil, nil, true},
+		{"include value", []string{"env=prod"}, nil, prod, true},
+		{"include value miss", []string{"env=prod"}, nil, sandbox, false},
+		{"include missing key", []string{"env=prod"}, nil, nil, false},
+		{"same key is either", []string{"env=prod", "env=staging"}, nil, prod, true},
+		{"different keys are both", []string{"env=prod", "team=data"}, nil, prod, false},
+		{"key only", []string{"team"}, nil, prod, true},
+		{"key only miss", []string{"team"}, nil, sandbox, false},
+		{"exclude", nil, []string{"env=sandbox"}, sandbox, false},
+		{"exclude keeps others", nil, []string{"env=sandbox"}, prod, true},
+		{"exclude wins", []string{"env=prod"}, []string{"team=payments"}, prod, false},
+		{"exclude key only", nil, []string{"team"}, prod, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewLabelSelector(tt.include, tt.exclude)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.Match(tt.labels); got != tt.want {
+				t.Errorf("Match(%v) = %v, want %v", tt.labels, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLabelSelectorParse(t *testing.T) {
+	if _, err := NewLabelSelector([]string{"=prod"}, nil); err == nil {
+		t.Error("expected error for a term without a key")
+	}
+	s, err := NewLabelSelector([]string{"env=prod", "team"}, []string{"tier=dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Empty() {
+		t.Error("selector with terms reported empty")
+	}
+	if got := s.String(); got != "!tier=dev, env=prod, team" {
+		t.Errorf("String() = %q", got)
+	}
+	if !(LabelSelector{}).Empty() {
+		t.Error("zero selector should be empty")
+	}
+}
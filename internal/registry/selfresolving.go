@@ -0,0 +1,24 @@
+package registry
+
+// MetadataSelfResolving is the finding metadata key set on findings for
+// images a lifecycle policy will expire within ScanConfig.SelfResolvingDays.
+// It holds the priority of the expiring rule.
+const MetadataSelfResolving = "self_resolving_rule"
+
+// MarkSelfResolving records that the image behind findings will be expired
+// by the lifecycle rule with the given priority.
+func MarkSelfResolving(findings []Finding, rulePriority int) {
+	for i := range findings {
+		if findings[i].Metadata == nil {
+			findings[i].Metadata = make(map[string]any)
+		}
+		findings[i].Metadata[MetadataSelfResolving] = rulePriority
+	}
+}
+
+// IsSelfResolving reports whether a lifecycle policy will expire the image
+// behind f soon.
+func IsSelfResolving(f Finding) bool {
+	_, ok := f.Metadata[MetadataSelfResolving]
+	return ok
+}
@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"sort"
+	"time"
+)
+
+// RepoTiming is how long one repository took to scan.
+type RepoTiming struct {
+	Repository string  `json:"repository"`
+	Region     string  `json:"region"`
+	Images     int     `json:"images"`
+	Seconds    float64 `json:"seconds"`
+}
+
+// RegionTiming totals the repository scan time of one region.
+type RegionTiming struct {
+	Region       string  `json:"region"`
+	Repositories int     `json:"repositories"`
+	Images       int     `json:"images"`
+	Seconds      float64 `json:"seconds"`
+}
+
+// ScanStats is the scan_stats section of a report. Repositories are listed
+// slowest first so repositories with huge pagination can be excluded or
+// sharded into a separate run.
+type ScanStats struct {
+	Regions      []RegionTiming `json:"regions"`
+	Repositories []RepoTiming   `json:"repositories"`
+	// QuotaDenials lists the GCP projects whose API requests were denied
+	// for quota (RESOURCE_EXHAUSTED) and slowed down, most denials first.
+	QuotaDenials []QuotaDenial `json:"quota_denials,omitempty"`
+}
+
+// QuotaDenial counts the quota denials of one project's API requests, the
+// retries they caused, and the requests per second the project settled at.
+type QuotaDenial struct {
+	Project           string  `json:"project"`
+	Denials           int     `json:"denials"`
+	Retries           int     `json:"retries"`
+	RequestsPerSecond float64 `json:"requests_per_second"`
+}
+
+// RecordTiming stores the scan duration of a repository.
+func (r *ScanResult) RecordTiming(repo, region string, images int, elapsed time.Duration) {
+	r.Timings = append(r.Timings, RepoTiming{Repository: repo, Region: region, Images: images, Seconds: elapsed.Seconds()})
+}
+
+// NewScanStats summarizes repository timings per region. It returns nil if
+// no repository was timed.
+func NewScanStats(timings []RepoTiming) *ScanStats {
+	if len(timings) == 0 {
+		return nil
+	}
+	stats := &ScanStats{Repositories: append([]RepoTiming(nil), timings...)}
+	sort.SliceStable(stats.Repositories, func(i, j int) bool {
+		return stats.Repositories[i].Seconds > stats.Repositories[j].Seconds
+	})
+
+	byRegion := make(map[string]*RegionTiming)
+	for _, t := range timings {
+		rt := byRegion[t.Region]
+		if rt == nil {
+			rt = &RegionTiming{Region: t.Region}
+			byRegion[t.Region] = rt
+		}
+		rt.Repositories++
+		rt.Images += t.Images
+		rt.Seconds += t.Seconds
+	}
+	for _, rt := range byRegion {
+		stats.Regions = append(stats.Regions, *rt)
+	}
+	sort.Slice(stats.Regions, func(i, j int) bool {
+		return stats.Regions[i].Region < stats.Regions[j].Region
+	})
+	return stats
+}
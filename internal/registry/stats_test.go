@@ -0,0 +1,28 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewScanStats(t *testing.T) {
+	if NewScanStats(nil) != nil {
+		t.Error("no timings should yield no stats")
+	}
+
+	var r ScanResult
+	r.RecordTiming("small", "us-east-1", 3, time.Second)
+	r.RecordTiming("huge", "us-east-1", 5000, 90*time.Second)
+	r.RecordTiming("api", "eu-west-1", 20, 2*time.Second)
+
+	stats := NewScanStats(r.Timings)
+	if got := stats.Repositories[0]; got.Repository != "huge" || got.Seconds != 90 {
+		t.Errorf("slowest repository = %+v, want huge at 90s", got)
+	}
+	if len(stats.Regions) != 2 || stats.Regions[0].Region != "eu-west-1" {
+		t.Fatalf("regions = %+v", stats.Regions)
+	}
+	if us := stats.Regions[1]; us.Repositories != 2 || us.Images != 5003 || us.Seconds != 91 {
+		t.Errorf("us-east-1 totals = %+v", us)
+	}
+}
@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// MetadataSuppressionExpired is the finding metadata key set on findings
+// matched by an expired suppression, holding the expiry date.
+const MetadataSuppressionExpired = "suppression_expired"
+
+// Suppression hides findings with an ID (or any ID for "*") on resources
+// matching a pattern until it expires. Owner and Reason record who accepted
+// the finding and why.
+type Suppression struct {
+	ID       FindingID
+	Resource string
+	Owner    string
+	Reason   string
+	// Expires is the last day the suppression applies.
+	Expires time.Time
+	pattern *regexp.Regexp
+}
+
+// NewSuppression validates one suppression. resource is a glob or re:regex
+// pattern matched against the finding's resource ID and name, and expires is
+// a YYYY-MM-DD date.
+func NewSuppression(id, resource, owner, reason, expires string) (Suppression, error) {
+	if id == "" {
+		return Suppression{}, fmt.Errorf("suppression is missing id")
+	}
+	if id != "*" && !customIDPattern.MatchString(id) {
+		return Suppression{}, fmt.Errorf("suppression id %q is not a finding ID", id)
+	}
+	if resource == "" {
+		return Suppression{}, fmt.Errorf("suppression %s is missing resource", id)
+	}
+	if strings.TrimSpace(owner) == "" {
+		return Suppression{}, fmt.Errorf("suppression %s %s is missing owner", id, resource)
+	}
+	if strings.TrimSpace(reason) == "" {
+		return Suppression{}, fmt.Errorf("suppression %s %s is missing reason", id, resource)
+	}
+	if expires == "" {
+		return Suppression{}, fmt.Errorf("suppression %s %s is missing expires", id, resource)
+	}
+	day, err := time.Parse(time.DateOnly, expires)
+	if err != nil {
+		return Suppression{}, fmt.Errorf("suppression %s %s: invalid expires %q (want YYYY-MM-DD)", id, resource, expires)
+	}
+	pattern, err := compileRepoPattern(resource)
+	if err != nil {
+		return Suppression{}, fmt.Errorf("suppression %s: %w", id, err)
+	}
+	return Suppression{
+		ID:       FindingID(id),
+		Resource: resource,
+		Owner:    owner,
+		Reason:   reason,
+		Expires:  day,
+		pattern:  pattern,
+	}, nil
+}
+
+// Match reports whether the suppression covers f, expired or not.
+func (s Suppression) Match(f Finding) bool {
+	if s.ID != "*" && s.ID != f.ID {
+		return false
+	}
+	return s.pattern != nil && (s.pattern.MatchString(f.ResourceID) || s.pattern.MatchString(f.ResourceName))
+}
+
+// Expired reports whether the expiry day has passed at now (UTC).
+func (s Suppression) Expired(now time.Time) bool {
+	return !now.UTC().Before(s.Expires.AddDate(0, 0, 1))
+}
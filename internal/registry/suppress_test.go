@@ -0,0 +1,71 @@
+package registry
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewSuppressionValidates(t *testing.T) {
+	tests := []struct {
+		id, resource, owner, reason, expires string
+		want                                 string
+	}{
+		{"", "repo", "me", "why", "2026-01-01", "missing id"},
+		{"stale", "repo", "me", "why", "2026-01-01", "not a finding ID"},
+		{"STALE_IMAGE", "", "me", "why", "2026-01-01", "missing resource"},
+		{"STALE_IMAGE", "repo", " ", "why", "2026-01-01", "missing owner"},
+		{"STALE_IMAGE", "repo", "me", "", "2026-01-01", "missing reason"},
+		{"STALE_IMAGE", "repo", "me", "why", "", "missing expires"},
+		{"STALE_IMAGE", "repo", "me", "why", "01/02/2026", "invalid expires"},
+		{"STALE_IMAGE", "re:(", "me", "why", "2026-01-01", "invalid repository pattern"},
+	}
+	for _, tt := range tests {
+		_, err := NewSuppression(tt.id, tt.resource, tt.owner, tt.reason, tt.expires)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("NewSuppression(%q, %q) error = %v, want %q", tt.id, tt.resource, err, tt.want)
+		}
+	}
+}
+
+func TestSuppressionMatch(t *testing.T) {
+	s, err := NewSuppression("STALE_IMAGE", "legacy/*", "platform", "frozen until migration", "2026-06-30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wildcard, err := NewSuppression("*", "re:^sandbox/", "platform", "sandbox", "2026-06-30")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		s    Suppression
+		f    Finding
+		want bool
+	}{
+		{s, Finding{ID: FindingStaleImage, ResourceID: "legacy/api@sha256:abc"}, true},
+		{s, Finding{ID: FindingStaleImage, ResourceID: "arn:aws:ecr:x", ResourceName: "legacy/api"}, true},
+		{s, Finding{ID: FindingLargeImage, ResourceID: "legacy/api"}, false},
+		{s, Finding{ID: FindingStaleImage, ResourceID: "app/legacy"}, false},
+		{wildcard, Finding{ID: FindingLargeImage, ResourceID: "sandbox/tool"}, true},
+		{wildcard, Finding{ID: FindingLargeImage, ResourceID: "prod/sandbox"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.s.Match(tt.f); got != tt.want {
+			t.Errorf("%s %s Match(%s %s/%s) = %v, want %v", tt.s.ID, tt.s.Resource, tt.f.ID, tt.f.ResourceID, tt.f.ResourceName, got, tt.want)
+		}
+	}
+}
+
+func TestSuppressionExpired(t *testing.T) {
+	s, err := NewSuppression("STALE_IMAGE", "*", "platform", "accepted", "2026-06-30")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Expired(time.Date(2026, 6, 30, 23, 59, 0, 0, time.UTC)) {
+		t.Error("suppression expired on its last day")
+	}
+	if !s.Expired(time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Error("suppression not expired the day after")
+	}
+}
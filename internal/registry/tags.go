@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// AttributionKeys are resource tag/label keys copied into finding metadata
+// for cost attribution. Matching is case-insensitive.
+var AttributionKeys = []string{"team", "owner"}
+
+// MatchesTags reports whether a resource with the given tags is excluded.
+// An exclude entry with an empty value matches any value for that key.
+func (e ExcludeConfig) MatchesTags(tags map[string]string) bool {
+	for k, want := range e.Tags {
+		got, ok := tags[k]
+		if !ok {
+			continue
+		}
+		if want == "" || got == want {
+			return true
+		}
+	}
+	return false
+}
+
+// Attribution extracts the attribution keys, and any extra keys (such as a
+// --group-by key), from resource tags. Returns nil when none are present.
+func Attribution(tags map[string]string, extra ...string) map[string]string {
+	keys := AttributionKeys
+	if len(extra) > 0 {
+		keys = append(slices.Clone(AttributionKeys), extra...)
+	}
+	var attrs map[string]string
+	for k, v := range tags {
+		for _, key := range keys {
+			if strings.EqualFold(k, key) && v != "" {
+				if attrs == nil {
+					attrs = make(map[string]string)
+				}
+				attrs[key] = v
+			}
+		}
+	}
+	return attrs
+}
+
+// Annotate copies attribution values into the metadata of each finding.
+func Annotate(findings []Finding, attrs map[string]string) {
+	if len(attrs) == 0 {
+		return
+	}
+	for i := range findings {
+		if findings[i].Metadata == nil {
+			findings[i].Metadata = make(map[string]any, len(attrs))
+		}
+		for k, v := range attrs {
+			findings[i].Metadata[k] = v
+		}
+	}
+}
+
+// Unattributed is the group of findings without a value for the group key.
+const Unattributed = "(unattributed)"
+
+// GroupByRepo is the group key that groups findings by repository.
+const GroupByRepo = "repo"
+
+// GroupKey returns the attribution group of a finding. groupBy is "region",
+// GroupByRepo or a finding metadata key such as "team", "owner" or "project".
+func GroupKey(f Finding, groupBy string) string {
+	switch groupBy {
+	case "region":
+		if f.Region == "" {
+			return Unattributed
+		}
+		return f.Region
+	case GroupByRepo:
+		if repo := FindingRepository(f); repo != "" {
+			return repo
+		}
+		return Unattributed
+	}
+	v, ok := f.Metadata[groupBy]
+	if !ok || v == nil || fmt.Sprint(v) == "" {
+		return Unattributed
+	}
+	return fmt.Sprint(v)
+}
+
+// FindingRepository returns the repository a finding belongs to, or "" for
+// project-level findings.
+func FindingRepository(f Finding) string {
+	if f.Repository == "" && f.ResourceType == ResourceRepository {
+		return f.ResourceID
+	}
+	return f.Repository
+}
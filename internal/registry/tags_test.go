@@ -0,0 +1,90 @@
+package registry
+
+import "testing"
+
+func TestExcludeMatchesTags(t *testing.T) {
+	cfg := ExcludeConfig{Tags: map[string]string{"env": "production", "keep": ""}}
+
+	tests := []struct {
+		tags map[string]string
+		want bool
+	}{
+		{map[string]string{"env": "production"}, true},
+		{map[string]string{"env": "staging"}, false},
+		{map[string]string{"keep": "anything"}, true},
+		{map[string]string{"team": "platform"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		if got := cfg.MatchesTags(tt.tags); got != tt.want {
+			t.Errorf("MatchesTags(%v) = %v, want %v", tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestExcludeMatchesTagsEmptyConfig(t *testing.T) {
+	if (ExcludeConfig{}).MatchesTags(map[string]string{"env": "prod"}) {
+		t.Error("empty exclude config should match nothing")
+	}
+}
+
+func TestAttribution(t *testing.T) {
+	attrs := Attribution(map[string]string{"Team": "payments", "owner": "alice", "env": "prod"})
+	if attrs["team"] != "payments" {
+		t.Errorf("team = %q, want payments", attrs["team"])
+	}
+	if attrs["owner"] != "alice" {
+		t.Errorf("owner = %q, want alice", attrs["owner"])
+	}
+	if _, ok := attrs["env"]; ok {
+		t.Error("env should not be an attribution key")
+	}
+	if Attribution(map[string]string{"env": "prod"}) != nil {
+		t.Error("expected nil attribution without team/owner tags")
+	}
+	if attrs := Attribution(map[string]string{"Cost-Center": "cc-42"}, "cost-center"); attrs["cost-center"] != "cc-42" {
+		t.Errorf("extra key attribution = %v, want cost-center=cc-42", attrs)
+	}
+	if len(AttributionKeys) != 2 {
+		t.Errorf("AttributionKeys modified: %v", AttributionKeys)
+	}
+}
+
+func TestGroupKey(t *testing.T) {
+	f := Finding{Region: "us-east-1", Metadata: map[string]any{"team": "web", "empty": ""}}
+	for groupBy, want := range map[string]string{"team": "web", "region": "us-east-1", "owner": Unattributed, "empty": Unattributed} {
+		if got := GroupKey(f, groupBy); got != want {
+			t.Errorf("GroupKey(%q) = %q, want %q", groupBy, got, want)
+		}
+	}
+}
+
+func TestGroupKeyRepo(t *testing.T) {
+	tests := []struct {
+		f    Finding
+		want string
+	}{
+		{Finding{ResourceType: ResourceImage, ResourceID: "app@sha256:1", Repository: "app"}, "app"},
+		{Finding{ResourceType: ResourceRepository, ResourceID: "tools"}, "tools"},
+		{Finding{ResourceType: ResourceProject, ResourceID: "my-project"}, Unattributed},
+	}
+	for _, tt := range tests {
+		if got := GroupKey(tt.f, GroupByRepo); got != tt.want {
+			t.Errorf("GroupKey(%s, repo) = %q, want %q", tt.f.ResourceID, got, tt.want)
+		}
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	findings := []Finding{{ID: FindingStaleImage}, {ID: FindingLargeImage, Metadata: map[string]any{"size_bytes": 1}}}
+	Annotate(findings, map[string]string{"team": "payments"})
+
+	for _, f := range findings {
+		if f.Metadata["team"] != "payments" {
+			t.Errorf("%s team = %v, want payments", f.ID, f.Metadata["team"])
+		}
+	}
+	if findings[1].Metadata["size_bytes"] != 1 {
+		t.Error("existing metadata should be preserved")
+	}
+}
@@ -0,0 +1,61 @@
+package registry
+
+import "fmt"
+
+// DefaultTieringMinBytes is the size from which a stale retained image is
+// worth archiving when ScanConfig.TieringMinBytes is unset.
+const DefaultTieringMinBytes int64 = 100 * 1024 * 1024
+
+// ColdImage is a stale image that retention (keep_latest or protected_tags)
+// keeps in the registry, priced hot and in the provider's archive tier.
+type ColdImage struct {
+	ID        string
+	Name      string
+	Region    string
+	SizeBytes int64
+	DaysStale int
+	// RetainedBy is "protected_tags" or "keep_latest".
+	RetainedBy  string
+	HotCost     float64
+	ArchiveCost float64
+	ArchiveTier string
+}
+
+// TieringCandidate returns a TIERING_CANDIDATE finding recommending that a
+// cold image be exported to archive storage before it is deleted from the
+// registry. Its waste is the monthly saving over keeping it hot. It returns
+// nil when the check is disabled, the image is smaller than
+// cfg.TieringMinBytes (DefaultTieringMinBytes when unset) or archiving
+// saves nothing.
+func TieringCandidate(cfg ScanConfig, img ColdImage) *Finding {
+	if !cfg.CheckEnabled(FindingTieringCandidate) {
+		return nil
+	}
+	minBytes := cfg.TieringMinBytes
+	if minBytes <= 0 {
+		minBytes = DefaultTieringMinBytes
+	}
+	savings := img.HotCost - img.ArchiveCost
+	if img.SizeBytes < minBytes || savings <= 0 {
+		return nil
+	}
+	return &Finding{
+		ID:           FindingTieringCandidate,
+		Severity:     SeverityLow,
+		ResourceType: ResourceImage,
+		ResourceID:   img.ID,
+		ResourceName: img.Name,
+		Region:       img.Region,
+		Message: fmt.Sprintf("Kept by %s but not pulled in %d days (%.0f MB); %s would save $%.2f/mo",
+			img.RetainedBy, img.DaysStale, float64(img.SizeBytes)/(1024*1024), img.ArchiveTier, savings),
+		EstimatedMonthlyWaste: savings,
+		Metadata: map[string]any{
+			"size_bytes":           img.SizeBytes,
+			"days_stale":           img.DaysStale,
+			"retained_by":          img.RetainedBy,
+			"archive_tier":         img.ArchiveTier,
+			"hot_monthly_cost":     img.HotCost,
+			"archive_monthly_cost": img.ArchiveCost,
+		},
+	}
+}
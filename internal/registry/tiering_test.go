@@ -0,0 +1,32 @@
+package registry
+
+import "testing"
+
+func TestTieringCandidate(t *testing.T) {
+	img := ColdImage{
+		ID: "app@sha256:1", Name: "app:v1.0.0", Region: "us-east-1", SizeBytes: 2 << 30,
+		DaysStale: 200, RetainedBy: "protected_tags", HotCost: 0.20, ArchiveCost: 0.002, ArchiveTier: "s3-glacier-deep-archive",
+	}
+	f := TieringCandidate(ScanConfig{}, img)
+	if f == nil || f.ID != FindingTieringCandidate || f.Severity != SeverityLow {
+		t.Fatalf("TieringCandidate() = %+v", f)
+	}
+	if f.EstimatedMonthlyWaste != 0.198 || f.Metadata["hot_monthly_cost"] != 0.20 {
+		t.Errorf("savings = %f, metadata = %v", f.EstimatedMonthlyWaste, f.Metadata)
+	}
+	if want := "Kept by protected_tags but not pulled in 200 days (2048 MB); s3-glacier-deep-archive would save $0.20/mo"; f.Message != want {
+		t.Errorf("Message = %q, want %q", f.Message, want)
+	}
+
+	small := img
+	small.SizeBytes = DefaultTieringMinBytes - 1
+	if TieringCandidate(ScanConfig{}, small) != nil {
+		t.Error("image under the default minimum size reported")
+	}
+	if TieringCandidate(ScanConfig{TieringMinBytes: 4 << 30}, img) != nil {
+		t.Error("image under TieringMinBytes reported")
+	}
+	if TieringCandidate(ScanConfig{DisabledChecks: map[FindingID]bool{FindingTieringCandidate: true}}, img) != nil {
+		t.Error("disabled check reported")
+	}
+}
@@ -1,6 +1,9 @@
 package registry
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // Severity levels for findings.
 type Severity string
@@ -12,12 +15,25 @@ const (
 	SeverityLow      Severity = "low"
 )
 
+// ParseSeverity validates a severity name such as "high".
+func ParseSeverity(s string) (Severity, error) {
+	switch sev := Severity(s); sev {
+	case SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow:
+		return sev, nil
+	}
+	return "", fmt.Errorf("unknown severity %q (use critical, high, medium or low)", s)
+}
+
 // ResourceType identifies the registry resource being audited.
 type ResourceType string
 
 const (
 	ResourceImage      ResourceType = "image"
 	ResourceRepository ResourceType = "repository"
+	ResourceProject    ResourceType = "project"
+	// ResourcePackageVersion is a version of a Maven, npm, Python or generic
+	// package in Artifact Registry.
+	ResourcePackageVersion ResourceType = "package_version"
 )
 
 // FindingID identifies the type of waste detected.
@@ -31,6 +47,27 @@ const (
 	FindingVulnerableImage   FindingID = "VULNERABLE_IMAGE"
 	FindingUnusedRepo        FindingID = "UNUSED_REPO"
 	FindingMultiArchBloat    FindingID = "MULTI_ARCH_BLOAT"
+	FindingDuplicateLayers   FindingID = "DUPLICATE_LAYERS"
+	FindingQuotaPressure     FindingID = "QUOTA_PRESSURE"
+	FindingStorageSpike      FindingID = "STORAGE_SPIKE"
+	FindingStaleRemoteCache  FindingID = "STALE_REMOTE_CACHE"
+	FindingLongTailWaste     FindingID = "LONG_TAIL_WASTE"
+	FindingOrphanedManifest  FindingID = "ORPHANED_MANIFEST"
+	FindingUnsignedImage     FindingID = "UNSIGNED_IMAGE"
+	FindingMissingSBOM       FindingID = "MISSING_SBOM"
+	// FindingUntaggedAccumulation replaces per-image UNTAGGED_IMAGE findings
+	// for repositories holding more untagged images than
+	// ScanConfig.UntaggedAccumulation, typically build caches.
+	FindingUntaggedAccumulation FindingID = "UNTAGGED_ACCUMULATION"
+	// FindingStaleReleaseTrain flags repositories with a configured release
+	// cadence whose newest image is far older than that cadence.
+	FindingStaleReleaseTrain FindingID = "STALE_RELEASE_TRAIN"
+	// FindingTieringCandidate recommends archiving large stale images that
+	// retention keeps, with the savings over keeping them in the registry.
+	FindingTieringCandidate FindingID = "TIERING_CANDIDATE"
+	// FindingAgingDeployedImage flags deployed images built longer ago than
+	// ScanConfig.MaxDeployedAge.
+	FindingAgingDeployedImage FindingID = "AGING_DEPLOYED_IMAGE"
 )
 
 // Finding represents a single waste detection result.
@@ -40,10 +77,14 @@ type Finding struct {
 	ResourceType          ResourceType   `json:"resource_type"`
 	ResourceID            string         `json:"resource_id"`
 	ResourceName          string         `json:"resource_name,omitempty"`
+	Repository            string         `json:"repository,omitempty"`
 	Region                string         `json:"region"`
 	Message               string         `json:"message"`
 	EstimatedMonthlyWaste float64        `json:"estimated_monthly_waste"`
 	Metadata              map[string]any `json:"metadata,omitempty"`
+	// Score is the priority score the analyzer gives the finding, from 0 to
+	// 100; the highest scores are triaged first.
+	Score float64 `json:"score,omitempty"`
 }
 
 // ScanResult holds all findings from scanning a set of resources.
@@ -52,6 +93,77 @@ type ScanResult struct {
 	Errors              []string  `json:"errors,omitempty"`
 	ResourcesScanned    int       `json:"resources_scanned"`
 	RepositoriesScanned int       `json:"repositories_scanned"`
+	Coverage            Coverage  `json:"coverage"`
+	// Detail is set only for single-repository scans.
+	Detail *RepositoryDetail `json:"detail,omitempty"`
+	// Usage is the storage of each scanned repository, keyed by UsageKey.
+	Usage map[string]RepoUsage `json:"usage,omitempty"`
+	// RepositoriesByProject is set only for multi-project scans.
+	RepositoriesByProject map[string]int `json:"repositories_by_project,omitempty"`
+	// Timings records how long each repository took to scan.
+	Timings []RepoTiming `json:"timings,omitempty"`
+	// Targets counts the regions (ECR) or locations (Artifact Registry) the
+	// scan tried to list, and TargetErrors holds each one that failed.
+	Targets      int           `json:"targets,omitempty"`
+	TargetErrors []TargetError `json:"target_errors,omitempty"`
+	// Recommendations are tag-based retention rules learned from pull
+	// activity (ECR only).
+	Recommendations []RetentionRecommendation `json:"recommendations,omitempty"`
+}
+
+// TargetError is the failure to list one region or location.
+type TargetError struct {
+	Target string `json:"target"`
+	Err    string `json:"error"`
+}
+
+// AddTargetError records that target could not be listed, also as a report
+// error prefixed with the target.
+func (r *ScanResult) AddTargetError(target string, err error) {
+	r.TargetErrors = append(r.TargetErrors, TargetError{Target: target, Err: err.Error()})
+	r.Errors = append(r.Errors, fmt.Sprintf("%s: %v", target, err))
+}
+
+// AllTargetsFailed reports whether every region or location the scan tried
+// failed, leaving nothing scanned.
+func (r *ScanResult) AllTargetsFailed() bool {
+	return r.Targets > 0 && len(r.TargetErrors) >= r.Targets
+}
+
+// RepoUsage is the storage a repository used at scan time.
+type RepoUsage struct {
+	// Repository is the repository ID. Records written before it existed
+	// carry the ID as their key only.
+	Repository string `json:"repository,omitempty"`
+	Region     string `json:"region"`
+	SizeBytes  int64  `json:"size_bytes"`
+	// Metadata names the project and target of merged scans, under
+	// MetadataProject and MetadataTarget.
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Name returns the repository ID, or key for records without one.
+func (u RepoUsage) Name(key string) string {
+	if u.Repository != "" {
+		return u.Repository
+	}
+	return key
+}
+
+// UsageKey returns the key of a repository in ScanResult.Usage:
+// region/repository, so repositories of the same name in different regions
+// or locations stay distinct. Merged scans prefix it with the project or
+// target.
+func UsageKey(region, repo string) string {
+	return region + "/" + repo
+}
+
+// RecordUsage stores the size of a scanned repository.
+func (r *ScanResult) RecordUsage(repo, region string, sizeBytes int64) {
+	if r.Usage == nil {
+		r.Usage = make(map[string]RepoUsage)
+	}
+	r.Usage[UsageKey(region, repo)] = RepoUsage{Repository: repo, Region: region, SizeBytes: sizeBytes}
 }
 
 // ScanConfig holds parameters that control scanning behavior.
@@ -59,7 +171,75 @@ type ScanConfig struct {
 	StaleDays      int
 	MaxSizeBytes   int64
 	MinMonthlyCost float64
+	EgressModel    string // "", "inter-region", or "internet"
 	Exclude        ExcludeConfig
+	// RepoPriority weights repositories (typically by estimated monthly spend)
+	// so the most expensive ones are scanned first under a tight timeout.
+	RepoPriority map[string]float64
+	// Repos limits the scan to repositories matching include/exclude patterns.
+	Repos RepoFilter
+	// DeepLayers fetches image manifests to analyze layers and detect DUPLICATE_LAYERS.
+	DeepLayers bool
+	// MaxDeployedAge reports images in InUse whose build date, read from
+	// their config blob with DeepLayers, is older than this as
+	// AGING_DEPLOYED_IMAGE (0 disables).
+	MaxDeployedAge time.Duration
+	// UsedPlatforms lists the os/arch platforms the fleet runs; other
+	// platforms in multi-arch indexes are reported as MULTI_ARCH_BLOAT.
+	UsedPlatforms []string
+	// InUse lists deployed images; STALE_IMAGE is suppressed and
+	// UNTAGGED_IMAGE downgraded for images found in it.
+	InUse *InUse
+	// Pulls holds last-pull times observed outside the registry API (e.g.
+	// GCP audit logs); when set, staleness is measured from the last pull.
+	Pulls *PullLog
+	// Quota sets storage budgets checked for QUOTA_PRESSURE.
+	Quota QuotaConfig
+	// TagPriority lists tag globs preferred when choosing the canonical tag
+	// that names a multi-tagged image.
+	TagPriority []string
+	// KeepLatest protects the newest N images of each tag family (see
+	// TagFamily) from STALE_IMAGE so rollback targets are never flagged.
+	KeepLatest int
+	// ProtectedTags exempts images with a matching tag from STALE_IMAGE and
+	// marks their other findings as protected.
+	ProtectedTags TagPatterns
+	// RequireSignatures reports tagged images without a cosign or OCI
+	// referrer signature as UNSIGNED_IMAGE. SignedTags limits the check to
+	// images with a matching tag; when empty every tagged image is checked.
+	RequireSignatures bool
+	SignedTags        TagPatterns
+	// RequireSBOM reports recent tagged images without an attached SBOM
+	// as MISSING_SBOM, at SBOMSeverity (medium when unset).
+	RequireSBOM  bool
+	SBOMSeverity Severity
+	// AttributionKeys are resource tag/label keys copied into finding
+	// metadata in addition to team and owner, e.g. a --group-by key.
+	AttributionKeys []string
+	// SelfResolvingDays marks findings on images that a lifecycle policy
+	// will expire within this many days as self-resolving (0 disables).
+	SelfResolvingDays int
+	// UntaggedAccumulation is the number of untagged images above which a
+	// repository gets one UNTAGGED_ACCUMULATION finding instead of a finding
+	// per untagged image (0 disables).
+	UntaggedAccumulation int
+	// ReleaseCadences declare how often matching repositories ship; those
+	// whose newest image is far older are reported as STALE_RELEASE_TRAIN.
+	ReleaseCadences []ReleaseCadence
+	// TieringMinBytes is the size from which a stale image kept by
+	// KeepLatest or ProtectedTags is reported as TIERING_CANDIDATE
+	// (DefaultTieringMinBytes when 0).
+	TieringMinBytes int64
+	// Rules are user-defined checks evaluated against every image.
+	Rules []CustomRule
+	// DisabledChecks lists finding IDs turned off in config. Scanners skip
+	// the API calls that only serve a disabled check.
+	DisabledChecks map[FindingID]bool
+}
+
+// CheckEnabled reports whether findings with the given ID are wanted.
+func (c ScanConfig) CheckEnabled(id FindingID) bool {
+	return !c.DisabledChecks[id]
 }
 
 // ExcludeConfig holds resource exclusion rules.
@@ -68,10 +248,29 @@ type ExcludeConfig struct {
 	Tags        map[string]string
 }
 
-// ScanProgress reports scanning progress to callers.
+// Scan progress stages.
+const (
+	StageDiscover = "discover" // listing repositories
+	StageScan     = "scan"     // scanning one repository
+	StageReuse    = "reuse"    // reusing an unchanged repository's cached inventory
+	StageSkip     = "skip"     // skipping a repository that stores nothing
+	StageDone     = "done"     // all repositories handled
+)
+
+// ScanProgress reports scanning progress to callers. ReposDone and
+// ReposTotal count the repositories of the current scan once discovery is
+// complete; ImagesScanned counts the images inventoried so far. Findings
+// holds the findings added since the previous event, for live displays; it is
+// not serialized.
 type ScanProgress struct {
-	Region    string
-	Scanner   string
-	Message   string
-	Timestamp time.Time
+	Region        string    `json:"region"`
+	Scanner       string    `json:"scanner"`
+	Stage         string    `json:"stage"`
+	Repository    string    `json:"repository,omitempty"`
+	ReposDone     int       `json:"repos_done"`
+	ReposTotal    int       `json:"repos_total"`
+	ImagesScanned int       `json:"images_scanned"`
+	Message       string    `json:"message"`
+	Timestamp     time.Time `json:"timestamp"`
+	Findings      []Finding `json:"-"`
 }
@@ -19,6 +19,15 @@ func TestSeverityConstants(t *testing.T) {
 	}
 }
 
+func TestParseSeverity(t *testing.T) {
+	if got, err := ParseSeverity("high"); err != nil || got != SeverityHigh {
+		t.Errorf("ParseSeverity(high) = %q, %v", got, err)
+	}
+	if _, err := ParseSeverity("urgent"); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
+
 func TestResourceTypeConstants(t *testing.T) {
 	if string(ResourceImage) != "image" {
 		t.Errorf("ResourceImage = %q, want %q", ResourceImage, "image")
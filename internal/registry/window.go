@@ -0,0 +1,99 @@
+package registry
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+)
+
+// MetadataSuppressedByWindow is the finding metadata key set on findings
+// reported during a migration window, holding the window's name. Such
+// findings stay in the report but do not fail the scan.
+const MetadataSuppressedByWindow = "suppressed_by_window"
+
+// MigrationWindow is a planned maintenance or migration period during which
+// findings on matching repositories are expected and should not alert.
+type MigrationWindow struct {
+	Name string
+	// Start and End are the first and last day (UTC) of the window.
+	Start    time.Time
+	End      time.Time
+	Reason   string
+	patterns []*regexp.Regexp
+}
+
+// NewMigrationWindow validates one migration window. start and end are
+// YYYY-MM-DD dates, both included, and repos are glob or re:regex patterns;
+// without any the window covers every finding.
+func NewMigrationWindow(name, start, end, reason string, repos []string) (MigrationWindow, error) {
+	if name == "" {
+		return MigrationWindow{}, fmt.Errorf("migration window is missing name")
+	}
+	from, err := time.Parse(time.DateOnly, start)
+	if err != nil {
+		return MigrationWindow{}, fmt.Errorf("migration window %s: invalid start %q (want YYYY-MM-DD)", name, start)
+	}
+	to, err := time.Parse(time.DateOnly, end)
+	if err != nil {
+		return MigrationWindow{}, fmt.Errorf("migration window %s: invalid end %q (want YYYY-MM-DD)", name, end)
+	}
+	if to.Before(from) {
+		return MigrationWindow{}, fmt.Errorf("migration window %s: end %s is before start %s", name, end, start)
+	}
+	w := MigrationWindow{Name: name, Start: from, End: to, Reason: reason}
+	for _, p := range repos {
+		re, err := compileRepoPattern(p)
+		if err != nil {
+			return MigrationWindow{}, fmt.Errorf("migration window %s: %w", name, err)
+		}
+		w.patterns = append(w.patterns, re)
+	}
+	return w, nil
+}
+
+// Active reports whether now (UTC) falls on a day of the window.
+func (w MigrationWindow) Active(now time.Time) bool {
+	now = now.UTC()
+	return !now.Before(w.Start) && now.Before(w.End.AddDate(0, 0, 1))
+}
+
+// Match reports whether the window covers f's repository. A window without
+// repository patterns covers every finding, project-level ones included.
+func (w MigrationWindow) Match(f Finding) bool {
+	if len(w.patterns) == 0 {
+		return true
+	}
+	repo := FindingRepository(f)
+	for _, re := range w.patterns {
+		if repo != "" && re.MatchString(repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkWindowed tags the findings covered by a window active at now with its
+// name and returns how many were tagged.
+func MarkWindowed(findings []Finding, windows []MigrationWindow, now time.Time) int {
+	n := 0
+	for i := range findings {
+		for _, w := range windows {
+			if !w.Active(now) || !w.Match(findings[i]) {
+				continue
+			}
+			if findings[i].Metadata == nil {
+				findings[i].Metadata = make(map[string]any)
+			}
+			findings[i].Metadata[MetadataSuppressedByWindow] = w.Name
+			n++
+			break
+		}
+	}
+	return n
+}
+
+// SuppressedByWindow reports whether f was found during a migration window.
+func SuppressedByWindow(f Finding) bool {
+	_, ok := f.Metadata[MetadataSuppressedByWindow]
+	return ok
+}
@@ -0,0 +1,66 @@
+package registry
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewMigrationWindow(t *testing.T) {
+	w, err := NewMigrationWindow("gar-move", "2026-03-01", "2026-03-14", "moving to GAR", []string{"legacy/*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct {
+		now  time.Time
+		want bool
+	}{
+		{time.Date(2026, 2, 28, 23, 59, 0, 0, time.UTC), false},
+		{time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), true},
+		{time.Date(2026, 3, 14, 23, 59, 0, 0, time.UTC), true},
+		{time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), false},
+	} {
+		if got := w.Active(tt.now); got != tt.want {
+			t.Errorf("Active(%s) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+	bad := [][3]string{
+		{"", "2026-03-01", "2026-03-14"},
+		{"w", "March", "2026-03-14"},
+		{"w", "2026-03-01", ""},
+		{"w", "2026-03-14", "2026-03-01"},
+	}
+	for _, b := range bad {
+		if _, err := NewMigrationWindow(b[0], b[1], b[2], "", nil); err == nil {
+			t.Errorf("NewMigrationWindow(%q, %q, %q) succeeded", b[0], b[1], b[2])
+		}
+	}
+	if _, err := NewMigrationWindow("w", "2026-03-01", "2026-03-01", "", []string{"re:("}); err == nil {
+		t.Error("invalid repo pattern accepted")
+	}
+}
+
+func TestMarkWindowed(t *testing.T) {
+	now := time.Date(2026, 3, 5, 0, 0, 0, 0, time.UTC)
+	legacy, _ := NewMigrationWindow("gar-move", "2026-03-01", "2026-03-14", "", []string{"legacy/*"})
+	past, _ := NewMigrationWindow("old", "2026-01-01", "2026-01-31", "", nil)
+	findings := []Finding{
+		{ID: FindingStaleImage, Repository: "legacy/api", ResourceID: "legacy/api@sha256:1"},
+		{ID: FindingNoLifecyclePolicy, ResourceType: ResourceRepository, ResourceID: "legacy/web"},
+		{ID: FindingStaleImage, Repository: "app/api", ResourceID: "app/api@sha256:2"},
+	}
+
+	if n := MarkWindowed(findings, []MigrationWindow{past, legacy}, now); n != 2 {
+		t.Fatalf("MarkWindowed() = %d, want 2", n)
+	}
+	if findings[0].Metadata[MetadataSuppressedByWindow] != "gar-move" || !SuppressedByWindow(findings[1]) {
+		t.Errorf("legacy findings not tagged: %+v", findings[:2])
+	}
+	if SuppressedByWindow(findings[2]) {
+		t.Errorf("app/api tagged: %+v", findings[2])
+	}
+
+	all, _ := NewMigrationWindow("freeze", "2026-03-01", "2026-03-31", "", nil)
+	if n := MarkWindowed(findings[2:], []MigrationWindow{all}, now); n != 1 {
+		t.Errorf("window without repos tagged %d findings, want 1", n)
+	}
+}
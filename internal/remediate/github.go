@@ -0,0 +1,234 @@
+package remediate
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Default GitHub endpoints.
+const (
+	DefaultAPIURL   = "https://api.github.com"
+	DefaultCloneURL = "https://github.com"
+)
+
+// ErrNoChanges is returned by OpenPR when the repository already holds the
+// generated files unchanged.
+var ErrNoChanges = errors.New("the repository already contains these policies")
+
+// GitHub opens pull requests in a GitHub repository, cloning and pushing
+// with the git CLI.
+type GitHub struct {
+	HTTPClient *http.Client
+	APIURL     string
+	// CloneURL is the base of clone URLs: <CloneURL>/<owner>/<repo>.git.
+	CloneURL string
+	Token    string
+}
+
+// NewGitHub returns a client for github.com authenticating with token.
+func NewGitHub(token string) *GitHub {
+	return &GitHub{
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		APIURL:     DefaultAPIURL,
+		CloneURL:   DefaultCloneURL,
+		Token:      token,
+	}
+}
+
+// PullRequest is a pull request opened by OpenPR.
+type PullRequest struct {
+	Number int    `json:"number"`
+	URL    string `json:"html_url"`
+}
+
+// Change is a set of files to propose in a repository.
+type Change struct {
+	// Repo is owner/name.
+	Repo string
+	// Base is the branch to merge into; the default branch when empty.
+	Base   string
+	Branch string
+	// Files maps slash-separated paths in the repository to their content.
+	Files map[string][]byte
+	Title string
+	Body  string
+}
+
+// OpenPR clones c.Repo, commits c.Files to a new branch, pushes it and opens
+// a pull request against the base branch.
+func (g *GitHub) OpenPR(ctx context.Context, c Change) (*PullRequest, error) {
+	base := c.Base
+	if base == "" {
+		var err error
+		if base, err = g.DefaultBranch(ctx, c.Repo); err != nil {
+			return nil, err
+		}
+	}
+	work, err := os.MkdirTemp("", "ecrspectre-remediate-")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.RemoveAll(work) }()
+
+	cloneURL := strings.TrimRight(g.CloneURL, "/") + "/" + c.Repo + ".git"
+	if _, err := g.git(ctx, "", "clone", "--quiet", "--depth", "1", "--branch", base, cloneURL, work); err != nil {
+		return nil, err
+	}
+	if _, err := g.git(ctx, work, "checkout", "--quiet", "-b", c.Branch); err != nil {
+		return nil, err
+	}
+	for name, content := range c.Files {
+		p := filepath.Join(work, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(p, content, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := g.git(ctx, work, "add", "--all"); err != nil {
+		return nil, err
+	}
+	status, err := g.git(ctx, work, "status", "--porcelain")
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(status) == "" {
+		return nil, ErrNoChanges
+	}
+
+	// Commit as the configured git user, or as ecrspectre in CI runners
+	// that have none.
+	if _, err := g.git(ctx, work, "config", "user.email"); err != nil {
+		for _, kv := range [][2]string{{"user.name", "ecrspectre"}, {"user.email", "ecrspectre@users.noreply.github.com"}} {
+			if _, err := g.git(ctx, work, "config", kv[0], kv[1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if _, err := g.git(ctx, work, "commit", "--quiet", "-m", c.Title); err != nil {
+		return nil, err
+	}
+	if _, err := g.git(ctx, work, "push", "--quiet", "origin", c.Branch); err != nil {
+		return nil, err
+	}
+	return g.CreatePullRequest(ctx, c.Repo, c.Branch, base, c.Title, c.Body)
+}
+
+// DefaultBranch returns the default branch of repo.
+func (g *GitHub) DefaultBranch(ctx context.Context, repo string) (string, error) {
+	var info struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	if err := g.api(ctx, http.MethodGet, "/repos/"+repo, nil, &info); err != nil {
+		return "", fmt.Errorf("look up %s: %w", repo, err)
+	}
+	if info.DefaultBranch == "" {
+		return "", fmt.Errorf("look up %s: no default branch", repo)
+	}
+	return info.DefaultBranch, nil
+}
+
+// CreatePullRequest opens a pull request merging head into base.
+func (g *GitHub) CreatePullRequest(ctx context.Context, repo, head, base, title, body string) (*PullRequest, error) {
+	req := map[string]string{"title": title, "head": head, "base": base, "body": body}
+	var pr PullRequest
+	if err := g.api(ctx, http.MethodPost, "/repos/"+repo+"/pulls", req, &pr); err != nil {
+		return nil, fmt.Errorf("open pull request in %s: %w", repo, err)
+	}
+	return &pr, nil
+}
+
+func (g *GitHub) api(ctx context.Context, method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(g.APIURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "ecrspectre")
+	if g.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+g.Token)
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var apiErr struct {
+			Message string `json:"message"`
+		}
+		_ = json.Unmarshal(data, &apiErr)
+		return fmt.Errorf("%s %s: HTTP %d %s", method, path, resp.StatusCode, apiErr.Message)
+	}
+	return json.Unmarshal(data, out)
+}
+
+// git runs a git command in dir and returns its output. The token is passed
+// as an HTTP header through git's GIT_CONFIG_* environment variables, so it
+// is not in the clone URL, the repository config or the process arguments
+// that other users can list, and it is redacted from error messages.
+func (g *GitHub) git(ctx context.Context, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Dir = dir
+	cmd.Env = gitEnv(os.Environ(), g.Token)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if g.Token != "" {
+			msg = strings.ReplaceAll(msg, g.Token, "[REDACTED]")
+		}
+		return "", fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+	}
+	return string(out), nil
+}
+
+// gitEnv returns environ with prompts disabled and, when token is set, an
+// Authorization header added after any GIT_CONFIG_* entries already there.
+func gitEnv(environ []string, token string) []string {
+	env := append(slices.Clone(environ), "GIT_TERMINAL_PROMPT=0")
+	if token == "" {
+		return env
+	}
+	n := 0
+	for _, kv := range environ {
+		if v, ok := strings.CutPrefix(kv, "GIT_CONFIG_COUNT="); ok {
+			n, _ = strconv.Atoi(v)
+		}
+	}
+	auth := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + token))
+	return append(env,
+		fmt.Sprintf("GIT_CONFIG_COUNT=%d", n+1),
+		fmt.Sprintf("GIT_CONFIG_KEY_%d=http.extraHeader", n),
+		fmt.Sprintf("GIT_CONFIG_VALUE_%d=Authorization: Basic %s", n, auth))
+}
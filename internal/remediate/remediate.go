@@ -0,0 +1,176 @@
+// Package remediate turns scan findings into infrastructure-as-code fixes:
+// Terraform lifecycle policies for ECR repositories that have none, and a
+// GitHub pull request adding them to an infrastructure repository.
+package remediate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// DefaultUntaggedDays is how long untagged images are kept by generated
+// policies unless Options says otherwise.
+const DefaultUntaggedDays = 14
+
+// Options tune the generated lifecycle policies.
+type Options struct {
+	// UntaggedDays expires untagged images this many days after push
+	// (DefaultUntaggedDays when 0).
+	UntaggedDays int
+	// KeepTagged, when positive, adds a final rule keeping only the newest
+	// KeepTagged images of any tag.
+	KeepTagged int
+}
+
+// Repository is the lifecycle policy proposed for one ECR repository and
+// the waste behind it.
+type Repository struct {
+	Name   string
+	Region string
+	// MonthlyWaste counts each image of the repository once, at its largest
+	// finding, like the report summary.
+	MonthlyWaste float64
+	Findings     int
+	Policy       Policy
+}
+
+// Policy is an ECR lifecycle policy document.
+type Policy struct {
+	Rules []Rule `json:"rules"`
+}
+
+// Rule is one ECR lifecycle policy rule.
+type Rule struct {
+	RulePriority int       `json:"rulePriority"`
+	Description  string    `json:"description"`
+	Selection    Selection `json:"selection"`
+	Action       Action    `json:"action"`
+}
+
+// Selection chooses the images a rule expires.
+type Selection struct {
+	TagStatus      string   `json:"tagStatus"`
+	TagPatternList []string `json:"tagPatternList,omitempty"`
+	CountType      string   `json:"countType"`
+	CountUnit      string   `json:"countUnit,omitempty"`
+	CountNumber    int      `json:"countNumber"`
+}
+
+// Action is what a rule does with the selected images.
+type Action struct {
+	Type string `json:"type"`
+}
+
+// Plan proposes a lifecycle policy for every ECR repository reported as
+// NO_LIFECYCLE_POLICY. Retention recommendations for the repository become
+// tag rules, ahead of a rule expiring untagged images. Repositories are
+// ordered by waste, largest first.
+func Plan(findings []registry.Finding, recs []registry.RetentionRecommendation, opts Options) []Repository {
+	type repoKey struct{ name, region string }
+	var order []repoKey
+	missing := make(map[repoKey]bool)
+	for _, f := range findings {
+		if f.ID != registry.FindingNoLifecyclePolicy {
+			continue
+		}
+		k := repoKey{registry.FindingRepository(f), f.Region}
+		if k.name != "" && !missing[k] {
+			missing[k] = true
+			order = append(order, k)
+		}
+	}
+
+	// Each image counts once, at its largest finding.
+	waste := make(map[repoKey]map[string]float64)
+	counts := make(map[repoKey]int)
+	for _, f := range findings {
+		k := repoKey{registry.FindingRepository(f), f.Region}
+		if !missing[k] {
+			continue
+		}
+		counts[k]++
+		if waste[k] == nil {
+			waste[k] = make(map[string]float64)
+		}
+		waste[k][f.ResourceID] = max(waste[k][f.ResourceID], f.EstimatedMonthlyWaste)
+	}
+	byRepo := make(map[repoKey][]registry.RetentionRecommendation)
+	for _, r := range recs {
+		k := repoKey{r.Repository, r.Region}
+		byRepo[k] = append(byRepo[k], r)
+	}
+
+	repos := make([]Repository, 0, len(order))
+	for _, k := range order {
+		repo := Repository{Name: k.name, Region: k.region, Findings: counts[k], Policy: policyFor(byRepo[k], opts)}
+		for _, w := range waste[k] {
+			repo.MonthlyWaste += w
+		}
+		repos = append(repos, repo)
+	}
+	sort.SliceStable(repos, func(i, j int) bool { return repos[i].MonthlyWaste > repos[j].MonthlyWaste })
+	return repos
+}
+
+// policyFor builds the rules of one repository. ECR evaluates rules by
+// priority and requires a tagStatus "any" rule to come last.
+func policyFor(recs []registry.RetentionRecommendation, opts Options) Policy {
+	days := opts.UntaggedDays
+	if days <= 0 {
+		days = DefaultUntaggedDays
+	}
+	var rules []Rule
+	add := func(description string, sel Selection) {
+		rules = append(rules, Rule{RulePriority: len(rules) + 1, Description: description, Selection: sel, Action: Action{Type: "expire"}})
+	}
+
+	add(fmt.Sprintf("Expire untagged images after %d days", days), Selection{TagStatus: "untagged", CountType: "sinceImagePushed", CountUnit: "days", CountNumber: days})
+	for _, r := range recs {
+		switch {
+		case r.KeepNewest > 0:
+			add(r.Message, Selection{TagStatus: "tagged", TagPatternList: []string{r.TagPattern}, CountType: "imageCountMoreThan", CountNumber: r.KeepNewest})
+		case r.ExpireAfterDays > 0:
+			add(r.Message, Selection{TagStatus: "tagged", TagPatternList: []string{r.TagPattern}, CountType: "sinceImagePushed", CountUnit: "days", CountNumber: r.ExpireAfterDays})
+		}
+	}
+	if opts.KeepTagged > 0 {
+		add(fmt.Sprintf("Keep the newest %d images of any tag", opts.KeepTagged), Selection{TagStatus: "any", CountType: "imageCountMoreThan", CountNumber: opts.KeepTagged})
+	}
+	return Policy{Rules: rules}
+}
+
+// TotalWaste sums the monthly waste of repos.
+func TotalWaste(repos []Repository) float64 {
+	var total float64
+	for _, r := range repos {
+		total += r.MonthlyWaste
+	}
+	return total
+}
+
+// PullRequestText returns the title and Markdown description of a pull
+// request adding the policies of repos, with the waste behind each.
+func PullRequestText(repos []Repository, runID string) (title, body string) {
+	total := TotalWaste(repos)
+	title = fmt.Sprintf("Add ECR lifecycle policies to %d repositories ($%.2f/mo waste)", len(repos), total)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "ecrspectre found %d ECR repositories without a lifecycle policy, holding $%.2f/mo of registry waste. ", len(repos), total)
+	b.WriteString("This adds an `aws_ecr_lifecycle_policy` to each so untagged and unused images expire on their own.\n\n")
+	b.WriteString("| Repository | Region | Waste/mo | Findings | Rules |\n|---|---|---:|---:|---|\n")
+	for _, r := range repos {
+		rules := make([]string, len(r.Policy.Rules))
+		for i, rule := range r.Policy.Rules {
+			rules[i] = rule.Description
+		}
+		fmt.Fprintf(&b, "| `%s` | %s | $%.2f | %d | %s |\n", r.Name, r.Region, r.MonthlyWaste, r.Findings, strings.Join(rules, "; "))
+	}
+	b.WriteString("\nWaste is the scan's estimate of storage the policies will reclaim over time. Review the tag rules before merging: images expired by a policy cannot be restored unless archived first (`ecrspectre archive`).\n")
+	if runID != "" {
+		fmt.Fprintf(&b, "\nGenerated from scan run `%s`.\n", runID)
+	}
+	return title, b.String()
+}
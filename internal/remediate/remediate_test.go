@@ -0,0 +1,113 @@
+package remediate
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+func testFindings() []registry.Finding {
+	return []registry.Finding{
+		{ID: registry.FindingNoLifecyclePolicy, ResourceType: registry.ResourceRepository, ResourceID: "team/api", Region: "us-east-1"},
+		{ID: registry.FindingStaleImage, ResourceID: "team/api@sha256:1", Repository: "team/api", Region: "us-east-1", EstimatedMonthlyWaste: 3},
+		{ID: registry.FindingLargeImage, ResourceID: "team/api@sha256:1", Repository: "team/api", Region: "us-east-1", EstimatedMonthlyWaste: 2},
+		{ID: registry.FindingUntaggedImage, ResourceID: "team/api@sha256:2", Repository: "team/api", Region: "us-east-1", EstimatedMonthlyWaste: 1.5},
+		{ID: registry.FindingNoLifecyclePolicy, ResourceType: registry.ResourceRepository, ResourceID: "web", Region: "eu-west-1"},
+		{ID: registry.FindingStaleImage, ResourceID: "web@sha256:3", Repository: "web", Region: "eu-west-1", EstimatedMonthlyWaste: 9},
+		// Has a policy: not remediated.
+		{ID: registry.FindingStaleImage, ResourceID: "batch@sha256:4", Repository: "batch", Region: "us-east-1", EstimatedMonthlyWaste: 50},
+	}
+}
+
+func TestPlan(t *testing.T) {
+	recs := []registry.RetentionRecommendation{
+		{Repository: "team/api", Region: "us-east-1", TagPattern: "v*", KeepNewest: 5, Message: "Keep the newest 5 v* images"},
+		{Repository: "team/api", Region: "us-east-1", TagPattern: "sha-*", ExpireAfterDays: 14, Message: "Expire sha-* images after 14 days"},
+		{Repository: "team/api", Region: "eu-west-1", TagPattern: "v*", KeepNewest: 3, Message: "other region"},
+	}
+	repos := Plan(testFindings(), recs, Options{UntaggedDays: 7, KeepTagged: 50})
+	if len(repos) != 2 || repos[0].Name != "web" || repos[1].Name != "team/api" {
+		t.Fatalf("Plan = %+v, want web then team/api", repos)
+	}
+	api := repos[1]
+	if api.MonthlyWaste != 4.5 || api.Findings != 4 {
+		t.Errorf("team/api waste %.2f in %d findings, want 4.50 in 4", api.MonthlyWaste, api.Findings)
+	}
+	rules := api.Policy.Rules
+	if len(rules) != 4 {
+		t.Fatalf("team/api rules = %+v, want 4", rules)
+	}
+	if s := rules[0].Selection; s.TagStatus != "untagged" || s.CountNumber != 7 || rules[0].RulePriority != 1 {
+		t.Errorf("rule 1 = %+v, want untagged after 7 days", rules[0])
+	}
+	if s := rules[1].Selection; s.TagPatternList[0] != "v*" || s.CountType != "imageCountMoreThan" || s.CountNumber != 5 {
+		t.Errorf("rule 2 = %+v, want keep newest 5 v*", rules[1])
+	}
+	if s := rules[2].Selection; s.TagPatternList[0] != "sha-*" || s.CountType != "sinceImagePushed" || s.CountNumber != 14 {
+		t.Errorf("rule 3 = %+v, want sha-* after 14 days", rules[2])
+	}
+	if s := rules[3].Selection; s.TagStatus != "any" || rules[3].RulePriority != 4 {
+		t.Errorf("last rule = %+v, want tagStatus any", rules[3])
+	}
+	if got := len(repos[0].Policy.Rules); got != 2 {
+		t.Errorf("web has %d rules, want untagged and keep-tagged only", got)
+	}
+}
+
+func TestWriteTerraform(t *testing.T) {
+	repos := Plan(testFindings(), nil, Options{})
+	var buf bytes.Buffer
+	if err := WriteTerraform(&buf, repos, "run-1"); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"from scan run run-1",
+		"# Region eu-west-1",
+		`resource "aws_ecr_lifecycle_policy" "team_api_us_east_1" {`,
+		`  repository = "team/api"`,
+		"$4.50/mo of waste in 4 findings",
+		"  policy     = <<-EOT\n    {\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Terraform missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Index(out, "eu-west-1") > strings.Index(out, "us-east-1") {
+		t.Errorf("regions not sorted:\n%s", out)
+	}
+
+	// The heredoc holds a valid policy document.
+	_, rest, _ := strings.Cut(out, "<<-EOT\n")
+	doc, _, _ := strings.Cut(rest, "  EOT")
+	var p Policy
+	if err := json.Unmarshal([]byte(doc), &p); err != nil || len(p.Rules) != 1 || p.Rules[0].Selection.CountNumber != DefaultUntaggedDays {
+		t.Errorf("policy document %q = %+v, %v", doc, p, err)
+	}
+}
+
+func TestResourceName(t *testing.T) {
+	for _, tt := range []struct{ name, region, want string }{
+		{"Team/API.v2", "us-east-1", "team_api_v2_us_east_1"},
+		{"1st", "eu-west-1", "_1st_eu_west_1"},
+	} {
+		if got := ResourceName(Repository{Name: tt.name, Region: tt.region}); got != tt.want {
+			t.Errorf("ResourceName(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPullRequestText(t *testing.T) {
+	title, body := PullRequestText(Plan(testFindings(), nil, Options{}), "run-1")
+	if title != "Add ECR lifecycle policies to 2 repositories ($13.50/mo waste)" {
+		t.Errorf("title = %q", title)
+	}
+	for _, want := range []string{"| `web` | eu-west-1 | $9.00 | 2 | Expire untagged images after 14 days |", "scan run `run-1`"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body missing %q:\n%s", want, body)
+		}
+	}
+}
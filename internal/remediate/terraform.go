@@ -0,0 +1,87 @@
+package remediate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Regions returns the regions of repos in sorted order.
+func Regions(repos []Repository) []string {
+	seen := make(map[string]bool)
+	var regions []string
+	for _, r := range repos {
+		if !seen[r.Region] {
+			seen[r.Region] = true
+			regions = append(regions, r.Region)
+		}
+	}
+	sort.Strings(regions)
+	return regions
+}
+
+// FileName is the Terraform file holding the policies of one region.
+func FileName(region string) string {
+	return "ecrspectre-lifecycle-" + region + ".tf"
+}
+
+// WriteTerraform writes an aws_ecr_lifecycle_policy resource for each of
+// repos, grouped by region. runID, when set, is recorded in the header.
+func WriteTerraform(w io.Writer, repos []Repository, runID string) error {
+	ew := &errWriter{w: w}
+	ew.printf("# Generated by ecrspectre remediate")
+	if runID != "" {
+		ew.printf(" from scan run %s", runID)
+	}
+	ew.printf(".\n# Lifecycle policies for ECR repositories that have none. Apply each region\n# with a provider configured for that region.\n")
+	for _, region := range Regions(repos) {
+		ew.printf("\n# Region %s\n", region)
+		for _, r := range repos {
+			if r.Region == region {
+				writeResource(ew, r)
+			}
+		}
+	}
+	return ew.err
+}
+
+func writeResource(ew *errWriter, r Repository) {
+	policy, err := json.MarshalIndent(r.Policy, "    ", "  ")
+	if err != nil {
+		ew.err = err
+		return
+	}
+	ew.printf("\n# %s: $%.2f/mo of waste in %d findings\n", r.Name, r.MonthlyWaste, r.Findings)
+	ew.printf("resource \"aws_ecr_lifecycle_policy\" %q {\n", ResourceName(r))
+	ew.printf("  repository = %q\n", r.Name)
+	ew.printf("  policy     = <<-EOT\n    %s\n  EOT\n}\n", policy)
+}
+
+var nonIdentifier = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// ResourceName is the Terraform resource name of a repository's policy,
+// unique per repository and region.
+func ResourceName(r Repository) string {
+	name := nonIdentifier.ReplaceAllString(strings.ToLower(r.Name+"_"+r.Region), "_")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "_" + name
+	}
+	return name
+}
+
+// errWriter remembers the first write error so rendering code can write
+// unconditionally and check once at the end.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (ew *errWriter) printf(format string, args ...any) {
+	if ew.err != nil {
+		return
+	}
+	_, ew.err = fmt.Fprintf(ew.w, format, args...)
+}
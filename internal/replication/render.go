@@ -0,0 +1,40 @@
+package replication
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+)
+
+// WriteText renders the plan as a table, most expensive route first.
+func WriteText(w io.Writer, plan Plan) error {
+	if len(plan.Routes) == 0 {
+		_, err := fmt.Fprintln(w, "No routes to estimate.")
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "ROUTE\tREPOS\tIMAGES\tSTORED\tSTORAGE/MO\tPUSHED/MO\tTRANSFER/MO\tTOTAL/MO")
+	for _, d := range plan.Routes {
+		_, _ = fmt.Fprintf(tw, "%s -> %s\t%d\t%d\t%s\t$%.2f\t%s\t$%.2f\t$%.2f\n",
+			d.Source, d.Destination, d.Repositories, d.Images, gb(d.StoredBytes),
+			d.MonthlyStorageCost, gb(d.PushedBytes), d.MonthlyTransferCost, d.MonthlyCost)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "\nEstimated additional cost: $%.2f/mo for %s of replicas.\n"+
+		"Replication copies only images pushed after a rule is enabled: transfer starts at once,\n"+
+		"storage grows to the estimate as the destinations fill up under the same lifecycle policies.\n",
+		plan.MonthlyCost, gb(plan.StoredBytes))
+	return err
+}
+
+// WriteJSON renders the plan as indented JSON.
+func WriteJSON(w io.Writer, plan Plan) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(plan)
+}
+
+func gb(b int64) string { return fmt.Sprintf("%.1f GB", float64(b)/(1<<30)) }
@@ -0,0 +1,154 @@
+// Package replication estimates what enabling ECR cross-region replication
+// would add to the monthly bill: storage of the replicas in each destination
+// and inter-region transfer of the images pushed to the source.
+package replication
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/pricing"
+)
+
+// Route replicates the repositories of one source region to the
+// destination regions.
+type Route struct {
+	Source       string   `json:"source"`
+	Destinations []string `json:"destinations"`
+}
+
+// ParseRoute parses "source=dest1,dest2" (":" also separates source and
+// destinations).
+func ParseRoute(s string) (Route, error) {
+	src, dests, ok := strings.Cut(s, "=")
+	if !ok {
+		src, dests, ok = strings.Cut(s, ":")
+	}
+	src = strings.TrimSpace(src)
+	if !ok || src == "" || strings.TrimSpace(dests) == "" {
+		return Route{}, fmt.Errorf("invalid route %q (use source=destination[,destination...])", s)
+	}
+	r := Route{Source: src}
+	seen := make(map[string]bool)
+	for _, d := range strings.Split(dests, ",") {
+		d = strings.TrimSpace(d)
+		switch {
+		case d == "":
+			continue
+		case d == src:
+			return Route{}, fmt.Errorf("route %q replicates %s to itself", s, src)
+		case seen[d]:
+			continue
+		}
+		seen[d] = true
+		r.Destinations = append(r.Destinations, d)
+	}
+	if len(r.Destinations) == 0 {
+		return Route{}, fmt.Errorf("invalid route %q: no destination region", s)
+	}
+	return r, nil
+}
+
+// ParseRoutes parses routes and merges those sharing a source region.
+func ParseRoutes(specs []string) ([]Route, error) {
+	var routes []Route
+	index := make(map[string]int)
+	for _, s := range specs {
+		r, err := ParseRoute(s)
+		if err != nil {
+			return nil, err
+		}
+		i, ok := index[r.Source]
+		if !ok {
+			index[r.Source] = len(routes)
+			routes = append(routes, r)
+			continue
+		}
+		for _, d := range r.Destinations {
+			if !slices.Contains(routes[i].Destinations, d) {
+				routes[i].Destinations = append(routes[i].Destinations, d)
+			}
+		}
+	}
+	return routes, nil
+}
+
+// Repository is the current inventory of one source repository.
+type Repository struct {
+	Name      string
+	Region    string
+	Images    int
+	SizeBytes int64
+	// PushedBytes is the size of the images pushed in the last 30 days.
+	PushedBytes int64
+}
+
+// Destination is the cost of replicating one source region to one
+// destination region.
+type Destination struct {
+	Source       string `json:"source"`
+	Destination  string `json:"destination"`
+	Repositories int    `json:"repositories"`
+	Images       int    `json:"images"`
+	// StoredBytes is the storage the replicas reach once the destination
+	// holds the same images as the source.
+	StoredBytes         int64   `json:"stored_bytes"`
+	MonthlyStorageCost  float64 `json:"monthly_storage_cost"`
+	PushedBytes         int64   `json:"monthly_pushed_bytes"`
+	MonthlyTransferCost float64 `json:"monthly_transfer_cost"`
+	MonthlyCost         float64 `json:"monthly_cost"`
+}
+
+// Plan is the estimated cost of a replication topology.
+type Plan struct {
+	Routes       []Destination `json:"routes"`
+	MonthlyCost  float64       `json:"total_monthly_cost"`
+	StoredBytes  int64         `json:"total_stored_bytes"`
+	Repositories int           `json:"source_repositories"`
+}
+
+// Estimate prices routes for the repositories of their source regions.
+// Replicas are charged at the destination's storage rate, and each image
+// pushed to the source is transferred once to every destination at the
+// inter-region rate.
+func Estimate(routes []Route, repos []Repository) Plan {
+	bySource := make(map[string][]Repository)
+	for _, r := range repos {
+		bySource[r.Region] = append(bySource[r.Region], r)
+	}
+
+	var plan Plan
+	for _, route := range routes {
+		sources := bySource[route.Source]
+		plan.Repositories += len(sources)
+		var images int
+		var stored, pushed int64
+		for _, r := range sources {
+			images += r.Images
+			stored += r.SizeBytes
+			pushed += r.PushedBytes
+		}
+		for _, dest := range route.Destinations {
+			d := Destination{
+				Source:              route.Source,
+				Destination:         dest,
+				Repositories:        len(sources),
+				Images:              images,
+				StoredBytes:         stored,
+				MonthlyStorageCost:  pricing.MonthlyStorageCost("ecr", dest, stored),
+				PushedBytes:         pushed,
+				MonthlyTransferCost: pricing.MonthlyTransferCost("ecr", "inter-region", pushed, 1),
+			}
+			d.MonthlyCost = d.MonthlyStorageCost + d.MonthlyTransferCost
+			plan.Routes = append(plan.Routes, d)
+			plan.MonthlyCost += d.MonthlyCost
+			plan.StoredBytes += d.StoredBytes
+		}
+	}
+	sort.SliceStable(plan.Routes, func(i, j int) bool {
+		return plan.Routes[i].MonthlyCost > plan.Routes[j].MonthlyCost
+	})
+	return plan
+}
@@ -0,0 +1,60 @@
+package replication
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseRoutes(t *testing.T) {
+	routes, err := ParseRoutes([]string{"us-east-1=eu-west-1,ap-south-1", "us-east-1:eu-west-1,us-west-2", "eu-west-1=us-east-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(routes) != 2 {
+		t.Fatalf("routes = %+v", routes)
+	}
+	if got := strings.Join(routes[0].Destinations, ","); routes[0].Source != "us-east-1" || got != "eu-west-1,ap-south-1,us-west-2" {
+		t.Errorf("route 0 = %+v", routes[0])
+	}
+
+	for _, bad := range []string{"us-east-1", "=eu-west-1", "us-east-1=", "us-east-1=us-east-1"} {
+		if _, err := ParseRoute(bad); err == nil {
+			t.Errorf("ParseRoute(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	const gib = 1 << 30
+	repos := []Repository{
+		{Name: "api", Region: "us-east-1", Images: 10, SizeBytes: 60 * gib, PushedBytes: 10 * gib},
+		{Name: "web", Region: "us-east-1", Images: 5, SizeBytes: 40 * gib, PushedBytes: 0},
+		{Name: "batch", Region: "us-west-2", Images: 3, SizeBytes: 500 * gib},
+	}
+	plan := Estimate([]Route{{Source: "us-east-1", Destinations: []string{"eu-west-1", "ap-south-1"}}}, repos)
+	if len(plan.Routes) != 2 || plan.Repositories != 2 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	for _, d := range plan.Routes {
+		if d.StoredBytes != 100*gib || d.Images != 15 || d.PushedBytes != 10*gib {
+			t.Errorf("route = %+v", d)
+		}
+		// 100 GB at $0.10 plus 10 GB of inter-region transfer at $0.02.
+		if math.Abs(d.MonthlyStorageCost-10) > 0.01 || math.Abs(d.MonthlyTransferCost-0.2) > 0.001 {
+			t.Errorf("%s costs = %.2f + %.2f", d.Destination, d.MonthlyStorageCost, d.MonthlyTransferCost)
+		}
+	}
+	if math.Abs(plan.MonthlyCost-20.4) > 0.01 || plan.StoredBytes != 200*gib {
+		t.Errorf("total = %.2f, %d bytes", plan.MonthlyCost, plan.StoredBytes)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, plan); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "us-east-1 -> eu-west-1") || !strings.Contains(buf.String(), "$20.40/mo") {
+		t.Errorf("text = %s", buf.String())
+	}
+}
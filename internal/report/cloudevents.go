@@ -0,0 +1,142 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// CloudEvents types posted by CloudEventsReporter.
+const (
+	EventScanCompleted     = "io.github.ppiankov.ecrspectre.scan.completed"
+	EventThresholdBreached = "io.github.ppiankov.ecrspectre.threshold.breached"
+)
+
+// cloudEventsContentType marks a structured-mode CloudEvents request.
+const cloudEventsContentType = "application/cloudevents+json"
+
+// CloudEvent is a CloudEvents 1.0 event in structured JSON mode. RunID is
+// the runid extension attribute.
+type CloudEvent struct {
+	SpecVersion     string    `json:"specversion"`
+	ID              string    `json:"id"`
+	Source          string    `json:"source"`
+	Type            string    `json:"type"`
+	Subject         string    `json:"subject,omitempty"`
+	Time            time.Time `json:"time"`
+	DataContentType string    `json:"datacontenttype"`
+	RunID           string    `json:"runid,omitempty"`
+	Data            any       `json:"data"`
+}
+
+// ScanCompletedData is the data of a scan.completed event.
+type ScanCompletedData struct {
+	Provider            string         `json:"provider"`
+	TotalFindings       int            `json:"total_findings"`
+	TotalMonthlyWaste   float64        `json:"total_monthly_waste"`
+	RepositoriesScanned int            `json:"repositories_scanned"`
+	ResourcesScanned    int            `json:"resources_scanned"`
+	BySeverity          map[string]int `json:"by_severity"`
+	Errors              int            `json:"errors"`
+	// TopFindings are the costliest findings, at most SlackMaxFindings.
+	TopFindings []registry.Finding `json:"top_findings,omitempty"`
+}
+
+// ThresholdBreachedData is the data of a threshold.breached event. Threshold
+// is "monthly_waste" (Limit and Value in dollars) or "severity" (Limit a
+// severity and Value the number of findings at least that severe).
+type ThresholdBreachedData struct {
+	Provider  string `json:"provider"`
+	Threshold string `json:"threshold"`
+	Limit     any    `json:"limit"`
+	Value     any    `json:"value"`
+}
+
+// CloudEvents builds the events of a scan: always scan.completed, and a
+// threshold.breached event for each threshold of r the scan reached. Event
+// IDs derive from the run ID, so a retried delivery can be deduplicated.
+func (r *CloudEventsReporter) CloudEvents(data Data) []CloudEvent {
+	event := func(kind, typ string, payload any) CloudEvent {
+		return CloudEvent{
+			SpecVersion:     "1.0",
+			ID:              data.RunID + "/" + kind,
+			Source:          "urn:ecrspectre:" + data.Target.Type + ":" + data.Target.URIHash,
+			Type:            typ,
+			Subject:         data.Config.Provider,
+			Time:            data.Timestamp.UTC(),
+			DataContentType: "application/json",
+			RunID:           data.RunID,
+			Data:            payload,
+		}
+	}
+
+	events := []CloudEvent{event("scan.completed", EventScanCompleted, ScanCompletedData{
+		Provider:            data.Config.Provider,
+		TotalFindings:       data.Summary.TotalFindings,
+		TotalMonthlyWaste:   data.Summary.TotalMonthlyWaste,
+		RepositoriesScanned: data.Summary.RepositoriesScanned,
+		ResourcesScanned:    data.Summary.TotalResourcesScanned,
+		BySeverity:          data.Summary.BySeverity,
+		Errors:              len(data.Errors),
+		TopFindings:         slackTopFindings(data.Findings),
+	})}
+
+	if r.WasteThreshold > 0 && data.Summary.TotalMonthlyWaste >= r.WasteThreshold {
+		events = append(events, event("threshold.monthly_waste", EventThresholdBreached, ThresholdBreachedData{
+			Provider: data.Config.Provider, Threshold: "monthly_waste", Limit: r.WasteThreshold, Value: data.Summary.TotalMonthlyWaste,
+		}))
+	}
+	if r.SeverityThreshold != "" {
+		count := 0
+		for _, sev := range []registry.Severity{registry.SeverityCritical, registry.SeverityHigh, registry.SeverityMedium, registry.SeverityLow} {
+			count += data.Summary.BySeverity[string(sev)]
+			if sev == r.SeverityThreshold {
+				break
+			}
+		}
+		if count > 0 {
+			events = append(events, event("threshold.severity", EventThresholdBreached, ThresholdBreachedData{
+				Provider: data.Config.Provider, Threshold: "severity", Limit: r.SeverityThreshold, Value: count,
+			}))
+		}
+	}
+	return events
+}
+
+// Generate posts each event of the scan to the webhook, one structured-mode
+// request per event.
+func (r *CloudEventsReporter) Generate(data Data) error {
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	for _, e := range r.CloudEvents(data) {
+		body, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("encode CloudEvent: %w", err)
+		}
+		resp, err := client.Post(r.URL, cloudEventsContentType, bytes.NewReader(body))
+		if err != nil {
+			// The webhook URL may embed a secret; keep it out of the error.
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			return fmt.Errorf("post %s event: %w", e.Type, err)
+		}
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		_ = resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("post %s event: %s: %s", e.Type, resp.Status, strings.TrimSpace(string(msg)))
+		}
+	}
+	return nil
+}
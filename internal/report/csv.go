@@ -0,0 +1,96 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// CSVMetadataColumns are the finding metadata keys exported as CSV columns,
+// after the finding fields: attribution, age and size first, then the
+// suppression and policy tags.
+var CSVMetadataColumns = []string{
+	registry.MetadataProvider,
+	registry.MetadataTarget,
+	registry.MetadataProject,
+	"team",
+	"owner",
+	registry.MetadataPushedAt,
+	"size_bytes",
+	"digest",
+	registry.MetadataPeriodWaste,
+	registry.MetadataProtected,
+	registry.MetadataSelfResolving,
+	registry.MetadataSuppressionExpired,
+	registry.MetadataSuppressedByWindow,
+	registry.MetadataBaseline,
+}
+
+var csvFindingColumns = []string{
+	"id", "severity", "resource_type", "resource_id", "resource_name",
+	"repository", "region", "message", "estimated_monthly_waste", "score",
+}
+
+// Generate writes one CSV row per finding under a header row. The finding
+// fields come first, then CSVMetadataColumns and the --group-by key when it
+// is another metadata key; a finding without a key leaves its cell empty.
+func (r *CSVReporter) Generate(data Data) error {
+	meta := CSVMetadataColumns
+	if g := data.Summary.GroupBy; g != "" && g != "region" && g != registry.GroupByRepo && !slices.Contains(meta, g) {
+		meta = append(slices.Clone(meta), g)
+	}
+
+	w := csv.NewWriter(r.Writer)
+	if err := w.Write(append(slices.Clone(csvFindingColumns), meta...)); err != nil {
+		return fmt.Errorf("encode CSV report: %w", err)
+	}
+	for _, f := range data.Findings {
+		row := []string{
+			string(f.ID),
+			string(f.Severity),
+			string(f.ResourceType),
+			f.ResourceID,
+			f.ResourceName,
+			registry.FindingRepository(f),
+			f.Region,
+			f.Message,
+			strconv.FormatFloat(f.EstimatedMonthlyWaste, 'f', 2, 64),
+			strconv.FormatFloat(f.Score, 'f', 1, 64),
+		}
+		for _, key := range meta {
+			row = append(row, csvValue(f.Metadata[key]))
+		}
+		if err := w.Write(row); err != nil {
+			return fmt.Errorf("encode CSV report: %w", err)
+		}
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("encode CSV report: %w", err)
+	}
+	return nil
+}
+
+// csvValue flattens a metadata value into one cell: scalars as text, lists
+// and maps as JSON.
+func csvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool, int, int64:
+		return fmt.Sprint(v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
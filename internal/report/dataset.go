@@ -0,0 +1,255 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/pricing"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// ObjectStore stores the files of a dataset under keys relative to its
+// root. The archive stores (directory, S3, GCS) implement it.
+type ObjectStore interface {
+	Put(ctx context.Context, key string, body io.ReadSeeker, size int64) error
+	URL(key string) string
+}
+
+// datasetColumn is a CSV column with its BigQuery and Athena types.
+type datasetColumn struct {
+	name     string
+	bigquery string
+	athena   string
+	required bool
+}
+
+// The dataset tables. The Hive partition keys, provider and dt, are part of
+// the object path rather than the files, as BigQuery requires.
+var (
+	datasetFindingColumns = []datasetColumn{
+		{"scan_time", "TIMESTAMP", "string", true},
+		{"run_id", "STRING", "string", false},
+		{"target", "STRING", "string", true},
+		{"finding_id", "STRING", "string", true},
+		{"severity", "STRING", "string", true},
+		{"resource_type", "STRING", "string", true},
+		{"resource_id", "STRING", "string", true},
+		{"resource_name", "STRING", "string", false},
+		{"repository", "STRING", "string", false},
+		{"region", "STRING", "string", false},
+		{"project", "STRING", "string", false},
+		{"team", "STRING", "string", false},
+		{"message", "STRING", "string", false},
+		{"estimated_monthly_waste", "FLOAT64", "double", true},
+		{"score", "FLOAT64", "double", false},
+		{"size_bytes", "INT64", "bigint", false},
+		{"metadata", "JSON", "string", false},
+	}
+	datasetRepositoryColumns = []datasetColumn{
+		{"scan_time", "TIMESTAMP", "string", true},
+		{"run_id", "STRING", "string", false},
+		{"target", "STRING", "string", true},
+		{"repository", "STRING", "string", true},
+		{"region", "STRING", "string", false},
+		{"size_bytes", "INT64", "bigint", true},
+		{"monthly_storage_cost", "FLOAT64", "double", false},
+	}
+)
+
+// datasetTimeFormat is a timestamp BigQuery loads as TIMESTAMP and Athena
+// casts to timestamp.
+const datasetTimeFormat = "2006-01-02 15:04:05"
+
+// Generate writes the findings and the repository inventory of the scan as
+// Hive-partitioned CSV files, findings/provider=P/dt=YYYY-MM-DD/RUN.csv and
+// repositories/provider=P/dt=YYYY-MM-DD/RUN.csv, so every scan adds one file
+// per table. The BigQuery schemas and the Athena table definitions are
+// written alongside under schema/.
+func (r *DatasetReporter) Generate(data Data) error {
+	ctx := context.Background()
+	provider := data.Config.Provider
+	if provider == "" {
+		provider = "unknown"
+	}
+	run := data.RunID
+	if run == "" {
+		run = data.Timestamp.UTC().Format("20060102T150405Z")
+	}
+	partition := fmt.Sprintf("provider=%s/dt=%s/%s.csv", provider, data.Timestamp.UTC().Format("2006-01-02"), run)
+
+	findings, err := datasetCSV(datasetFindingColumns, datasetFindingRows(data))
+	if err != nil {
+		return err
+	}
+	repos, err := datasetCSV(datasetRepositoryColumns, datasetRepositoryRows(data))
+	if err != nil {
+		return err
+	}
+	files := []struct {
+		key  string
+		body []byte
+	}{
+		{"findings/" + partition, findings},
+		{"repositories/" + partition, repos},
+		{"schema/findings.bigquery.json", bigQuerySchema(datasetFindingColumns)},
+		{"schema/repositories.bigquery.json", bigQuerySchema(datasetRepositoryColumns)},
+		{"schema/athena.sql", []byte(athenaDDL(r.Store))},
+	}
+	for _, f := range files {
+		if err := r.Store.Put(ctx, f.key, bytes.NewReader(f.body), int64(len(f.body))); err != nil {
+			return fmt.Errorf("write dataset %s: %w", r.Store.URL(f.key), err)
+		}
+	}
+	return nil
+}
+
+func datasetFindingRows(data Data) [][]string {
+	scanTime := data.Timestamp.UTC().Format(datasetTimeFormat)
+	rows := make([][]string, 0, len(data.Findings))
+	for _, f := range data.Findings {
+		var score, metadata string
+		if f.Score > 0 {
+			score = strconv.FormatFloat(f.Score, 'f', -1, 64)
+		}
+		if len(f.Metadata) > 0 {
+			if b, err := json.Marshal(f.Metadata); err == nil {
+				metadata = string(b)
+			}
+		}
+		rows = append(rows, []string{
+			scanTime,
+			data.RunID,
+			data.Target.URIHash,
+			string(f.ID),
+			string(f.Severity),
+			string(f.ResourceType),
+			f.ResourceID,
+			f.ResourceName,
+			registry.FindingRepository(f),
+			f.Region,
+			metadataString(f.Metadata[registry.MetadataProject]),
+			metadataString(f.Metadata["team"]),
+			f.Message,
+			strconv.FormatFloat(f.EstimatedMonthlyWaste, 'f', 4, 64),
+			score,
+			metadataString(f.Metadata["size_bytes"]),
+			metadata,
+		})
+	}
+	return rows
+}
+
+// datasetRepositoryRows lists the storage of every scanned repository, by
+// repository. The storage cost is left empty for multi-provider scans.
+func datasetRepositoryRows(data Data) [][]string {
+	pricingProvider := map[string]string{"aws": "ecr", "gcp": "artifactregistry"}[data.Config.Provider]
+	scanTime := data.Timestamp.UTC().Format(datasetTimeFormat)
+	names := make([]string, 0, len(data.Usage))
+	for name := range data.Usage {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rows := make([][]string, 0, len(names))
+	for _, name := range names {
+		u := data.Usage[name]
+		var cost string
+		if pricingProvider != "" {
+			cost = strconv.FormatFloat(pricing.MonthlyStorageCost(pricingProvider, u.Region, u.SizeBytes), 'f', 4, 64)
+		}
+		rows = append(rows, []string{scanTime, data.RunID, data.Target.URIHash, u.Name(name), u.Region, strconv.FormatInt(u.SizeBytes, 10), cost})
+	}
+	return rows
+}
+
+// metadataString formats a metadata value for a CSV cell.
+func metadataString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func datasetCSV(columns []datasetColumn, rows [][]string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	if err := w.Write(header); err != nil {
+		return nil, fmt.Errorf("encode dataset: %w", err)
+	}
+	if err := w.WriteAll(rows); err != nil {
+		return nil, fmt.Errorf("encode dataset: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// bigQuerySchema returns the BigQuery JSON schema of a table, as taken by
+// bq load --schema and bq mkdef.
+func bigQuerySchema(columns []datasetColumn) []byte {
+	type field struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+		Mode string `json:"mode"`
+	}
+	fields := make([]field, len(columns))
+	for i, c := range columns {
+		fields[i] = field{Name: c.name, Type: c.bigquery, Mode: "NULLABLE"}
+		if c.required {
+			fields[i].Mode = "REQUIRED"
+		}
+	}
+	b, _ := json.MarshalIndent(fields, "", "  ")
+	return append(b, '\n')
+}
+
+// athenaDDL returns the Athena definitions of the tables, partitioned with
+// partition projection so new scans need no MSCK REPAIR TABLE.
+func athenaDDL(store ObjectStore) string {
+	var b strings.Builder
+	b.WriteString("-- Athena tables over the ecrspectre dataset. Timestamps are strings;\n")
+	b.WriteString("-- query them with CAST(scan_time AS timestamp).\n")
+	for _, t := range []struct {
+		name    string
+		columns []datasetColumn
+	}{{"findings", datasetFindingColumns}, {"repositories", datasetRepositoryColumns}} {
+		location := strings.TrimSuffix(store.URL(t.name), "/") + "/"
+		fmt.Fprintf(&b, "\nCREATE EXTERNAL TABLE IF NOT EXISTS ecrspectre_%s (\n", t.name)
+		for i, c := range t.columns {
+			sep := ","
+			if i == len(t.columns)-1 {
+				sep = ""
+			}
+			fmt.Fprintf(&b, "  `%s` %s%s\n", c.name, c.athena, sep)
+		}
+		fmt.Fprintf(&b, `)
+PARTITIONED BY (provider string, dt string)
+ROW FORMAT SERDE 'org.apache.hadoop.hive.serde2.OpenCSVSerde'
+LOCATION '%s'
+TBLPROPERTIES (
+  'skip.header.line.count' = '1',
+  'projection.enabled' = 'true',
+  'projection.provider.type' = 'enum',
+  'projection.provider.values' = 'aws,gcp,all',
+  'projection.dt.type' = 'date',
+  'projection.dt.format' = 'yyyy-MM-dd',
+  'projection.dt.range' = '2024-01-01,NOW',
+  'storage.location.template' = '%s'
+);
+`, location, location+"provider=${provider}/dt=${dt}/")
+	}
+	return b.String()
+}
@@ -0,0 +1,81 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// GitHubMaxAnnotations caps the annotations written per scan. GitHub shows
+// only the first 50 annotations of a job; the rest are in the job summary.
+const GitHubMaxAnnotations = 50
+
+// Generate writes one workflow command per finding (::error for critical and
+// high, ::warning for medium, ::notice for low and migration-window
+// findings) and one ::error per scan error, then appends the Markdown report
+// to the job summary file.
+func (r *GitHubReporter) Generate(data Data) error {
+	var b strings.Builder
+	for i, f := range data.Findings {
+		if i == GitHubMaxAnnotations {
+			fmt.Fprintf(&b, "::notice title=ecrspectre::%s\n", githubData(fmt.Sprintf("%d more findings are listed in the job summary", len(data.Findings)-GitHubMaxAnnotations)))
+			break
+		}
+		name := f.ResourceID
+		if f.ResourceName != "" {
+			name = f.ResourceName
+		}
+		msg := fmt.Sprintf("%s (%s, $%.2f%s)", f.Message, f.Region, registry.PeriodWaste(f), costPeriod(data.Summary).Suffix())
+		fmt.Fprintf(&b, "::%s title=%s::%s\n", githubLevel(f), githubProperty(string(f.ID)+" "+name), githubData(msg))
+	}
+	for _, e := range data.Errors {
+		fmt.Fprintf(&b, "::error title=ecrspectre scan error::%s\n", githubData(e))
+	}
+	if _, err := fmt.Fprint(r.Writer, b.String()); err != nil {
+		return err
+	}
+	if r.SummaryPath == "" {
+		return nil
+	}
+
+	var summary bytes.Buffer
+	if err := (&MarkdownReporter{Writer: &summary}).Generate(data); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.SummaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("open job summary: %w", err)
+	}
+	if _, err := f.Write(summary.Bytes()); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("write job summary: %w", err)
+	}
+	return f.Close()
+}
+
+func githubLevel(f registry.Finding) string {
+	if registry.SuppressedByWindow(f) {
+		return "notice"
+	}
+	switch f.Severity {
+	case registry.SeverityCritical, registry.SeverityHigh:
+		return "error"
+	case registry.SeverityMedium:
+		return "warning"
+	default:
+		return "notice"
+	}
+}
+
+// githubData escapes the message of a workflow command.
+func githubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// githubProperty escapes a workflow command property value.
+func githubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -0,0 +1,132 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Error     *junitFailure `xml:"error,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// junitScanSuite names the suite holding scan errors.
+const junitScanSuite = "ecrspectre scan"
+
+// Generate writes JUnit XML: one test suite per repository whose findings
+// are failed test cases, so CI systems that only read JUnit show waste in
+// their test report UI. Scan errors become errored cases of a final suite.
+func (r *JUnitReporter) Generate(data Data) error {
+	byRepo := make(map[string][]registry.Finding)
+	for _, f := range data.Findings {
+		repo := registry.GroupKey(f, registry.GroupByRepo)
+		byRepo[repo] = append(byRepo[repo], f)
+	}
+	repos := make([]string, 0, len(byRepo))
+	for repo := range byRepo {
+		repos = append(repos, repo)
+	}
+	sort.Strings(repos)
+
+	timestamp := ""
+	if !data.Timestamp.IsZero() {
+		timestamp = data.Timestamp.UTC().Format("2006-01-02T15:04:05")
+	}
+	doc := junitTestSuites{Name: data.Tool}
+	for _, repo := range repos {
+		suite := junitTestSuite{Name: repo, Timestamp: timestamp}
+		for _, f := range byRepo[repo] {
+			name := f.ResourceID
+			if f.ResourceName != "" {
+				name = f.ResourceName
+			}
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      fmt.Sprintf("%s %s", f.ID, name),
+				ClassName: repo,
+				Failure: &junitFailure{
+					Message: f.Message,
+					Type:    string(f.ID),
+					Text:    junitDetail(f),
+				},
+			})
+		}
+		suite.Tests, suite.Failures = len(suite.Cases), len(suite.Cases)
+		doc.Suites = append(doc.Suites, suite)
+	}
+	if len(data.Errors) > 0 {
+		suite := junitTestSuite{Name: junitScanSuite, Timestamp: timestamp}
+		for i, e := range data.Errors {
+			suite.Cases = append(suite.Cases, junitTestCase{
+				Name:      fmt.Sprintf("scan error %d", i+1),
+				ClassName: junitScanSuite,
+				Error:     &junitFailure{Message: e, Type: "ScanError"},
+			})
+		}
+		suite.Tests, suite.Errors = len(suite.Cases), len(suite.Cases)
+		doc.Suites = append(doc.Suites, suite)
+	}
+	for _, s := range doc.Suites {
+		doc.Tests += s.Tests
+		doc.Failures += s.Failures
+		doc.Errors += s.Errors
+	}
+
+	if _, err := fmt.Fprint(r.Writer, xml.Header); err != nil {
+		return fmt.Errorf("encode JUnit report: %w", err)
+	}
+	enc := xml.NewEncoder(r.Writer)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("encode JUnit report: %w", err)
+	}
+	if _, err := fmt.Fprintln(r.Writer); err != nil {
+		return fmt.Errorf("encode JUnit report: %w", err)
+	}
+	return nil
+}
+
+// junitDetail is the failure body: the finding's severity, resource, region
+// and waste, one per line.
+func junitDetail(f registry.Finding) string {
+	lines := []string{
+		"severity: " + string(f.Severity),
+		"resource: " + f.ResourceID,
+	}
+	if f.Region != "" {
+		lines = append(lines, "region: "+f.Region)
+	}
+	if f.EstimatedMonthlyWaste > 0 {
+		lines = append(lines, fmt.Sprintf("estimated monthly waste: $%.2f", f.EstimatedMonthlyWaste))
+	}
+	return strings.Join(lines, "\n")
+}
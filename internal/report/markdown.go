@@ -0,0 +1,119 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// MarkdownMaxRows caps the findings listed in each collapsible section, so
+// a large scan still fits in one GitHub or GitLab comment.
+const MarkdownMaxRows = 50
+
+// Generate writes a compact Markdown summary for PR and issue comments: a
+// headline, a table of finding types, then one collapsible section of
+// findings per type, costliest first.
+func (r *MarkdownReporter) Generate(data Data) error {
+	var b strings.Builder
+	period := costPeriod(data.Summary)
+	suffix := period.Suffix()
+
+	b.WriteString("### ecrspectre — Container Registry Waste Report\n\n")
+	if len(data.Findings) == 0 {
+		fmt.Fprintf(&b, "No waste found across %d repositories.\n", data.Summary.RepositoriesScanned)
+	} else {
+		fmt.Fprintf(&b, "**%d findings** with estimated %s waste of **$%.2f** across %d repositories.\n",
+			data.Summary.TotalFindings, period.Adjective(), periodTotal(data.Summary), data.Summary.RepositoriesScanned)
+	}
+	if data.Summary.Top > 0 {
+		fmt.Fprintf(&b, "Showing the top %d by %s; totals cover all findings.\n", data.Summary.Top, data.Summary.Sort)
+	}
+	if co2e := data.Summary.CO2eKgPerMonth; co2e > 0 {
+		fmt.Fprintf(&b, "Estimated emissions: %.2f kg CO2e/mo.\n", co2e)
+	}
+
+	groups := markdownGroups(data.Findings)
+	if len(groups) > 0 {
+		fmt.Fprintf(&b, "\n| Finding | Severity | Count | Waste%s |\n", suffix)
+		b.WriteString("|---------|----------|------:|-----:|\n")
+		for _, g := range groups {
+			fmt.Fprintf(&b, "| `%s` | %s | %d | $%.2f |\n", g.id, g.severity, len(g.findings), g.waste)
+		}
+	}
+
+	for _, g := range groups {
+		fmt.Fprintf(&b, "\n<details>\n<summary><code>%s</code> — %d findings, $%.2f%s</summary>\n\n", g.id, len(g.findings), g.waste, suffix)
+		fmt.Fprintf(&b, "| Severity | Resource | Region | Waste%s | Message |\n", suffix)
+		b.WriteString("|----------|----------|--------|-----:|---------|\n")
+		for i, f := range g.findings {
+			if i == MarkdownMaxRows {
+				fmt.Fprintf(&b, "\n…and %d more.\n", len(g.findings)-MarkdownMaxRows)
+				break
+			}
+			name := f.ResourceID
+			if f.ResourceName != "" {
+				name = f.ResourceName
+			}
+			fmt.Fprintf(&b, "| %s | `%s` | %s | $%.2f | %s |\n", f.Severity, markdownCell(name), f.Region, registry.PeriodWaste(f), markdownCell(f.Message))
+		}
+		b.WriteString("\n</details>\n")
+	}
+
+	if len(data.Errors) > 0 {
+		fmt.Fprintf(&b, "\n<details>\n<summary>%d errors</summary>\n\n", len(data.Errors))
+		for _, e := range data.Errors {
+			fmt.Fprintf(&b, "- %s\n", markdownCell(e))
+		}
+		b.WriteString("\n</details>\n")
+	}
+
+	_, err := fmt.Fprint(r.Writer, b.String())
+	return err
+}
+
+// markdownGroup is the findings of one finding ID.
+type markdownGroup struct {
+	id       registry.FindingID
+	severity registry.Severity
+	waste    float64
+	findings []registry.Finding
+}
+
+// markdownGroups groups findings by ID, costliest group first and each
+// group's findings costliest first. A group's severity is that of its first
+// finding in scan order.
+func markdownGroups(findings []registry.Finding) []*markdownGroup {
+	byID := make(map[registry.FindingID]*markdownGroup)
+	var groups []*markdownGroup
+	for _, f := range findings {
+		g, ok := byID[f.ID]
+		if !ok {
+			g = &markdownGroup{id: f.ID, severity: f.Severity}
+			byID[f.ID] = g
+			groups = append(groups, g)
+		}
+		g.waste += registry.PeriodWaste(f)
+		g.findings = append(g.findings, f)
+	}
+	for _, g := range groups {
+		sort.SliceStable(g.findings, func(i, j int) bool {
+			return registry.PeriodWaste(g.findings[i]) > registry.PeriodWaste(g.findings[j])
+		})
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].waste != groups[j].waste {
+			return groups[i].waste > groups[j].waste
+		}
+		return len(groups[i].findings) > len(groups[j].findings)
+	})
+	return groups
+}
+
+// markdownCell keeps text inside one table cell: pipes are escaped and line
+// breaks become spaces.
+func markdownCell(s string) string {
+	s = strings.ReplaceAll(s, "|", `\|`)
+	return strings.Join(strings.Fields(s), " ")
+}
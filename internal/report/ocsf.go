@@ -0,0 +1,215 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// OCSFVersion is the OCSF schema version of the events OCSFReporter writes.
+const OCSFVersion = "1.1.0"
+
+// OCSF Detection Finding class, in the Findings category, created by a scan.
+const (
+	ocsfCategoryFindings = 2
+	ocsfClassDetection   = 2004
+	ocsfActivityCreate   = 1
+)
+
+// ocsfEvent is an OCSF Detection Finding. Attributes without an OCSF
+// equivalent, such as the waste estimate, go under unmapped.
+type ocsfEvent struct {
+	ActivityID   int    `json:"activity_id"`
+	ActivityName string `json:"activity_name"`
+	CategoryUID  int    `json:"category_uid"`
+	CategoryName string `json:"category_name"`
+	ClassUID     int    `json:"class_uid"`
+	ClassName    string `json:"class_name"`
+	TypeUID      int    `json:"type_uid"`
+	TypeName     string `json:"type_name"`
+	SeverityID   int    `json:"severity_id"`
+	Severity     string `json:"severity"`
+	StatusID     int    `json:"status_id"`
+	Status       string `json:"status"`
+	// Time is the scan time in milliseconds since the epoch.
+	Time        int64           `json:"time"`
+	Message     string          `json:"message"`
+	Metadata    ocsfMetadata    `json:"metadata"`
+	FindingInfo ocsfFindingInfo `json:"finding_info"`
+	Cloud       ocsfCloud       `json:"cloud"`
+	Resources   []ocsfResource  `json:"resources"`
+	Unmapped    map[string]any  `json:"unmapped,omitempty"`
+}
+
+type ocsfMetadata struct {
+	Version        string      `json:"version"`
+	Product        ocsfProduct `json:"product"`
+	CorrelationUID string      `json:"correlation_uid,omitempty"`
+}
+
+type ocsfProduct struct {
+	Name       string `json:"name"`
+	VendorName string `json:"vendor_name"`
+	Version    string `json:"version,omitempty"`
+}
+
+type ocsfFindingInfo struct {
+	UID         string       `json:"uid"`
+	Title       string       `json:"title"`
+	Desc        string       `json:"desc,omitempty"`
+	Types       []string     `json:"types"`
+	CreatedTime int64        `json:"created_time"`
+	Analytic    ocsfAnalytic `json:"analytic"`
+}
+
+// ocsfAnalytic names the check that produced a finding; type 1 is a rule.
+type ocsfAnalytic struct {
+	UID    string `json:"uid"`
+	Name   string `json:"name"`
+	TypeID int    `json:"type_id"`
+	Type   string `json:"type"`
+}
+
+type ocsfCloud struct {
+	Provider  string `json:"provider"`
+	Region    string `json:"region,omitempty"`
+	ProjectID string `json:"project_uid,omitempty"`
+}
+
+type ocsfResource struct {
+	UID    string          `json:"uid"`
+	Name   string          `json:"name,omitempty"`
+	Type   string          `json:"type"`
+	Region string          `json:"region,omitempty"`
+	Group  *ocsfGroup      `json:"group,omitempty"`
+	Labels []string        `json:"labels,omitempty"`
+	Data   json.RawMessage `json:"data,omitempty"`
+}
+
+type ocsfGroup struct {
+	Name string `json:"name"`
+}
+
+// Generate writes one OCSF Detection Finding per finding as JSON Lines, the
+// layout data lake pipelines such as Amazon Security Lake custom sources
+// and Firehose ingest.
+func (r *OCSFReporter) Generate(data Data) error {
+	titles := make(map[string]string)
+	for _, rule := range buildSARIFRules() {
+		titles[rule.ID] = rule.ShortDescription.Text
+	}
+	ts := data.Timestamp.UnixMilli()
+
+	enc := json.NewEncoder(r.Writer)
+	for _, f := range data.Findings {
+		sevID, sev := ocsfSeverity(f.Severity)
+		title := titles[string(f.ID)]
+		if title == "" {
+			title = string(f.ID)
+		}
+		event := ocsfEvent{
+			ActivityID:   ocsfActivityCreate,
+			ActivityName: "Create",
+			CategoryUID:  ocsfCategoryFindings,
+			CategoryName: "Findings",
+			ClassUID:     ocsfClassDetection,
+			ClassName:    "Detection Finding",
+			TypeUID:      ocsfClassDetection*100 + ocsfActivityCreate,
+			TypeName:     "Detection Finding: Create",
+			SeverityID:   sevID,
+			Severity:     sev,
+			StatusID:     1,
+			Status:       "New",
+			Time:         ts,
+			Message:      f.Message,
+			Metadata: ocsfMetadata{
+				Version:        OCSFVersion,
+				Product:        ocsfProduct{Name: data.Tool, VendorName: "ppiankov", Version: data.Version},
+				CorrelationUID: data.RunID,
+			},
+			FindingInfo: ocsfFindingInfo{
+				UID:         ocsfFindingUID(f),
+				Title:       title,
+				Desc:        f.Message,
+				Types:       []string{string(f.ID)},
+				CreatedTime: ts,
+				Analytic:    ocsfAnalytic{UID: string(f.ID), Name: string(f.ID), TypeID: 1, Type: "Rule"},
+			},
+			Cloud:     ocsfCloudOf(f, data.Config.Provider),
+			Resources: []ocsfResource{ocsfResourceOf(f)},
+			Unmapped:  map[string]any{"estimated_monthly_waste": f.EstimatedMonthlyWaste},
+		}
+		if f.Score > 0 {
+			event.Unmapped["score"] = f.Score
+		}
+		if err := enc.Encode(event); err != nil {
+			return fmt.Errorf("encode OCSF finding: %w", err)
+		}
+	}
+	return nil
+}
+
+// ocsfSeverity maps a severity to its OCSF severity_id and caption.
+func ocsfSeverity(s registry.Severity) (int, string) {
+	switch s {
+	case registry.SeverityCritical:
+		return 5, "Critical"
+	case registry.SeverityHigh:
+		return 4, "High"
+	case registry.SeverityMedium:
+		return 3, "Medium"
+	case registry.SeverityLow:
+		return 2, "Low"
+	}
+	return 0, "Unknown"
+}
+
+// ocsfFindingUID identifies a finding across scans, so a data lake can
+// follow the same waste from one scan to the next.
+func ocsfFindingUID(f registry.Finding) string {
+	sum := sha256.Sum256([]byte(strings.Join([]string{string(f.ID), f.Region, string(f.ResourceType), f.ResourceID}, "\x00")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// ocsfCloudOf returns the cloud of a finding: its provider metadata in
+// multi-provider scans, else the scanned provider.
+func ocsfCloudOf(f registry.Finding, provider string) ocsfCloud {
+	if p, ok := f.Metadata[registry.MetadataProvider].(string); ok && p != "" {
+		provider = p
+	}
+	cloud := ocsfCloud{Region: f.Region}
+	switch provider {
+	case "aws":
+		cloud.Provider = "AWS"
+	case "gcp":
+		cloud.Provider = "GCP"
+	default:
+		cloud.Provider = provider
+	}
+	if project, ok := f.Metadata[registry.MetadataProject].(string); ok && project != "" {
+		cloud.ProjectID = project
+	}
+	return cloud
+}
+
+// ocsfResourceOf describes the image, repository or package version of a
+// finding, with its metadata as resource data.
+func ocsfResourceOf(f registry.Finding) ocsfResource {
+	res := ocsfResource{UID: f.ResourceID, Name: f.ResourceName, Type: string(f.ResourceType), Region: f.Region}
+	if repo := registry.FindingRepository(f); repo != "" {
+		res.Group = &ocsfGroup{Name: repo}
+	}
+	if tags, ok := f.Metadata["tags"].([]string); ok {
+		res.Labels = tags
+	}
+	if len(f.Metadata) > 0 {
+		if b, err := json.Marshal(f.Metadata); err == nil {
+			res.Data = b
+		}
+	}
+	return res
+}
@@ -0,0 +1,92 @@
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// promSeries is one labeled sample of a metric.
+type promSeries struct {
+	labels string
+	value  float64
+}
+
+// Generate writes the scan as Prometheus text exposition format (version
+// 0.0.4), for the node_exporter textfile collector or any scraper of a file
+// served over HTTP. Per-repository gauges sum the waste of the reported
+// findings, so a resource with several findings counts each; the
+// ecrspectre_total_monthly_waste_dollars gauge counts it once.
+func (r *PrometheusReporter) Generate(data Data) error {
+	waste := make(map[string]float64)
+	counts := make(map[string]float64)
+	for _, f := range data.Findings {
+		waste[promLabels(
+			"repo", registry.GroupKey(f, registry.GroupByRepo),
+			"region", f.Region,
+			"severity", string(f.Severity),
+			"finding", string(f.ID),
+		)] += f.EstimatedMonthlyWaste
+		counts[promLabels("severity", string(f.Severity), "finding", string(f.ID))]++
+	}
+
+	var b strings.Builder
+	writePromMetric(&b, "ecrspectre_monthly_waste_dollars", "Estimated monthly waste of the reported findings in USD.", sortedSeries(waste))
+	writePromMetric(&b, "ecrspectre_findings_total", "Number of reported findings.", sortedSeries(counts))
+	writePromMetric(&b, "ecrspectre_total_monthly_waste_dollars", "Estimated monthly waste of the scan in USD, each resource counted once.",
+		[]promSeries{{value: data.Summary.TotalMonthlyWaste}})
+	writePromMetric(&b, "ecrspectre_repositories_scanned", "Number of repositories scanned.",
+		[]promSeries{{value: float64(data.Summary.RepositoriesScanned)}})
+	writePromMetric(&b, "ecrspectre_resources_scanned", "Number of images and repositories scanned.",
+		[]promSeries{{value: float64(data.Summary.TotalResourcesScanned)}})
+	writePromMetric(&b, "ecrspectre_scan_errors", "Number of regions, locations or repositories that failed to scan.",
+		[]promSeries{{value: float64(len(data.Errors))}})
+	writePromMetric(&b, "ecrspectre_scan_timestamp_seconds", "Unix time the scan finished.",
+		[]promSeries{{value: float64(data.Timestamp.Unix())}})
+	if data.Duration > 0 {
+		writePromMetric(&b, "ecrspectre_scan_duration_seconds", "Wall-clock duration of the scan in seconds.",
+			[]promSeries{{value: data.Duration.Seconds()}})
+	}
+
+	_, err := fmt.Fprint(r.Writer, b.String())
+	return err
+}
+
+func writePromMetric(b *strings.Builder, name, help string, series []promSeries) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	for _, s := range series {
+		if s.labels == "" {
+			fmt.Fprintf(b, "%s %s\n", name, promValue(s.value))
+			continue
+		}
+		fmt.Fprintf(b, "%s{%s} %s\n", name, s.labels, promValue(s.value))
+	}
+}
+
+// promLabels renders key/value pairs as a label set, escaping values.
+func promLabels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, pairs[i]+`="`+promEscaper.Replace(pairs[i+1])+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+// promEscaper escapes label values as the exposition format requires.
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func sortedSeries(m map[string]float64) []promSeries {
+	series := make([]promSeries, 0, len(m))
+	for labels, v := range m {
+		series = append(series, promSeries{labels: labels, value: v})
+	}
+	sort.Slice(series, func(i, j int) bool { return series[i].labels < series[j].labels })
+	return series
+}
+
+func promValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
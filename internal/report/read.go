@@ -0,0 +1,35 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ReadJSON decodes a spectre/v1 JSON report produced by JSONReporter.
+func ReadJSON(r io.Reader) (Data, error) {
+	var envelope jsonEnvelope
+	if err := json.NewDecoder(r).Decode(&envelope); err != nil {
+		return Data{}, fmt.Errorf("decode JSON report: %w", err)
+	}
+	if envelope.Schema != "spectre/v1" {
+		return Data{}, fmt.Errorf("unsupported report schema %q (want spectre/v1)", envelope.Schema)
+	}
+	return envelope.Data, nil
+}
+
+// ReadJSONFile reads a spectre/v1 JSON report from disk.
+func ReadJSONFile(path string) (Data, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Data{}, fmt.Errorf("open report %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	data, err := ReadJSON(f)
+	if err != nil {
+		return Data{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return data, nil
+}
@@ -2,13 +2,28 @@ package report
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 	"time"
 
+	"gopkg.in/yaml.v3"
+
 	"github.com/ppiankov/ecrspectre/internal/analyzer"
+	"github.com/ppiankov/ecrspectre/internal/history"
 	"github.com/ppiankov/ecrspectre/internal/registry"
+	"github.com/ppiankov/ecrspectre/internal/sqlite"
 )
 
 func sampleData() Data {
@@ -84,6 +99,63 @@ func TestJSONReporter(t *testing.T) {
 	}
 }
 
+func TestYAMLReporterMatchesJSON(t *testing.T) {
+	var yamlBuf, jsonBuf bytes.Buffer
+	if err := (&YAMLReporter{Writer: &yamlBuf}).Generate(sampleData()); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if err := (&JSONReporter{Writer: &jsonBuf}).Generate(sampleData()); err != nil {
+		t.Fatal(err)
+	}
+
+	output := yamlBuf.String()
+	if !strings.HasPrefix(output, "$schema: spectre/v1\ntool: ecrspectre\nversion: 0.1.0\n") {
+		t.Errorf("keys should follow the JSON order:\n%s", output)
+	}
+	if strings.Contains(output, "{") || strings.Contains(output, `"STALE_IMAGE"`) {
+		t.Errorf("expected block style without JSON quoting:\n%s", output)
+	}
+
+	var fromYAML, fromJSON map[string]any
+	if err := yaml.Unmarshal(yamlBuf.Bytes(), &fromYAML); err != nil {
+		t.Fatalf("invalid YAML: %v", err)
+	}
+	if err := json.Unmarshal(jsonBuf.Bytes(), &fromJSON); err != nil {
+		t.Fatal(err)
+	}
+	// Round-trip both through JSON to normalize number and time types.
+	normalize := func(v map[string]any) string {
+		b, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	if normalize(fromYAML) != normalize(fromJSON) {
+		t.Errorf("YAML and JSON reports differ:\n%s\n%s", normalize(fromYAML), normalize(fromJSON))
+	}
+}
+
+func TestFeaturesUsedOmittedWhenEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&JSONReporter{Writer: &buf}).Generate(sampleData()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "features_used") {
+		t.Error("features_used should be omitted when empty")
+	}
+
+	data := sampleData()
+	data.FeaturesUsed = []string{"format:sarif", "provider:aws"}
+	buf.Reset()
+	if err := (&SARIFReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"featuresUsed"`) {
+		t.Error("SARIF run properties should carry featuresUsed")
+	}
+}
+
 func TestTextReporterWithFindings(t *testing.T) {
 	var buf bytes.Buffer
 	r := &TextReporter{Writer: &buf}
@@ -107,6 +179,79 @@ func TestTextReporterWithFindings(t *testing.T) {
 	}
 }
 
+func TestTextReporterAlignsWideNames(t *testing.T) {
+	data := sampleData()
+	data.Findings = data.Findings[:1]
+	wide := data.Findings[0]
+	wide.ResourceName = "チーム/アプリ:v1"
+	emoji := data.Findings[0]
+	emoji.ResourceName = "rocket🚀:v1"
+	data.Findings = append(data.Findings, wide, emoji)
+
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	// The REGION column starts in the same terminal cell on every row.
+	col := -1
+	for _, line := range strings.Split(buf.String(), "\n") {
+		i := strings.Index(line, data.Findings[0].Region)
+		if i < 0 || !strings.Contains(line, ":v1") {
+			continue
+		}
+		if w := displayWidth(line[:i]); col < 0 {
+			col = w
+		} else if w != col {
+			t.Errorf("region at cell %d, want %d: %q", w, col, line)
+		}
+	}
+	if col < 0 {
+		t.Fatal("no finding rows rendered")
+	}
+}
+
+func TestDisplayWidth(t *testing.T) {
+	tests := map[string]int{
+		"myapp":     5,
+		"チーム":       6,
+		"한국어":       6,
+		"rocket🚀":   8,
+		"e\u0301":   1,
+		"ａｂ":        4,
+		"café-repo": 9,
+	}
+	for s, want := range tests {
+		if got := displayWidth(s); got != want {
+			t.Errorf("displayWidth(%q) = %d, want %d", s, got, want)
+		}
+	}
+}
+
+func TestTextReporterRecommendations(t *testing.T) {
+	data := sampleData()
+	data.Recommendations = []registry.RetentionRecommendation{{
+		Repository: "myapp", Region: "us-east-1", TagPattern: "sha-*", Images: 40, WindowDays: 90, ExpireAfterDays: 14,
+		Message: "No sha-* image pulled in 90 days; safe to expire sha-* tags after 14 days",
+	}}
+
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Retention recommendations") || !strings.Contains(buf.String(), "myapp (us-east-1): No sha-* image pulled") {
+		t.Errorf("output missing recommendations:\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := (&JSONReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"tag_pattern": "sha-*"`) {
+		t.Error("JSON report should carry recommendations")
+	}
+}
+
 func TestTextReporterNoFindings(t *testing.T) {
 	data := sampleData()
 	data.Findings = nil
@@ -159,6 +304,9 @@ func TestSARIFReporter(t *testing.T) {
 	if !strings.Contains(output, "registry://") {
 		t.Error("missing registry URI")
 	}
+	if strings.Contains(output, "automationDetails") {
+		t.Error("automationDetails without a run ID")
+	}
 
 	var parsed map[string]any
 	if err := json.Unmarshal(buf.Bytes(), &parsed); err != nil {
@@ -166,6 +314,169 @@ func TestSARIFReporter(t *testing.T) {
 	}
 }
 
+func TestSARIFRunID(t *testing.T) {
+	data := sampleData()
+	data.RunID = "0b7e5c1a-4f3d-4a8e-9c2b-1d6f0e9a7b35"
+	var buf bytes.Buffer
+	if err := (&SARIFReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"guid": "0b7e5c1a-4f3d-4a8e-9c2b-1d6f0e9a7b35"`) {
+		t.Errorf("run ID missing from automationDetails:\n%s", buf.String())
+	}
+}
+
+func TestCSVReporter(t *testing.T) {
+	data := sampleData()
+	data.Findings[0].Repository = "myapp"
+	data.Findings[0].Message = "Image not pulled, 120 days"
+	data.Findings[0].Metadata = map[string]any{
+		"team":                              "payments",
+		"size_bytes":                        float64(1048576),
+		"cost_center":                       "cc-42",
+		registry.MetadataSuppressedByWindow: "gar-move",
+		"largest_layers":                    []string{"sha256:a"},
+	}
+	data.Summary.GroupBy = "cost_center"
+
+	var buf bytes.Buffer
+	if err := (&CSVReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("rows = %d, want header and 2 findings", len(rows))
+	}
+	header := rows[0]
+	if header[0] != "id" || header[len(header)-1] != "cost_center" {
+		t.Errorf("header = %v", header)
+	}
+	cell := func(row []string, col string) string {
+		return row[slices.Index(header, col)]
+	}
+	first := rows[1]
+	for col, want := range map[string]string{
+		"id":                      "STALE_IMAGE",
+		"repository":              "myapp",
+		"message":                 "Image not pulled, 120 days",
+		"estimated_monthly_waste": "5.50",
+		"team":                    "payments",
+		"size_bytes":              "1048576",
+		"suppressed_by_window":    "gar-move",
+		"cost_center":             "cc-42",
+	} {
+		if got := cell(first, col); got != want {
+			t.Errorf("%s = %q, want %q", col, got, want)
+		}
+	}
+	if slices.Contains(header, "largest_layers") {
+		t.Error("unselected metadata key exported")
+	}
+	if got := cell(rows[2], "team"); got != "" {
+		t.Errorf("missing metadata = %q, want empty", got)
+	}
+}
+
+func TestMarkdownReporter(t *testing.T) {
+	data := sampleData()
+	data.Findings[1].Message = "Image has | no tags\nat all"
+	data.Findings = append(data.Findings, registry.Finding{
+		ID: registry.FindingUntaggedImage, Severity: registry.SeverityHigh, ResourceType: registry.ResourceImage,
+		ResourceID: "sha256:f00d", Region: "us-east-1", Message: "Image has no tags", EstimatedMonthlyWaste: 4.00,
+	})
+	data.Errors = []string{"us-west-2: access denied"}
+
+	var buf bytes.Buffer
+	if err := (&MarkdownReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"**2 findings** with estimated monthly waste of **$7.80** across 3 repositories.",
+		"| `UNTAGGED_IMAGE` | high | 2 | $6.30 |\n| `STALE_IMAGE` | high | 1 | $5.50 |",
+		"<summary><code>UNTAGGED_IMAGE</code> — 2 findings, $6.30/mo</summary>",
+		"| high | `sha256:f00d` | us-east-1 | $4.00 | Image has no tags |\n| high | `sha256:cafebabe` | us-east-1 | $2.30 | Image has \\| no tags at all |",
+		"<summary>1 errors</summary>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestMarkdownReporterMaxRows(t *testing.T) {
+	data := sampleData()
+	data.Findings = nil
+	for i := range MarkdownMaxRows + 3 {
+		data.Findings = append(data.Findings, registry.Finding{ID: registry.FindingStaleImage, Severity: registry.SeverityHigh, ResourceID: fmt.Sprintf("img-%d", i)})
+	}
+	var buf bytes.Buffer
+	if err := (&MarkdownReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(buf.String(), "`img-"); n != MarkdownMaxRows {
+		t.Errorf("listed %d findings, want %d", n, MarkdownMaxRows)
+	}
+	if !strings.Contains(buf.String(), "…and 3 more.") {
+		t.Errorf("missing overflow note:\n%s", buf.String())
+	}
+}
+
+func TestJUnitReporter(t *testing.T) {
+	data := sampleData()
+	data.Findings[0].Repository = "myapp"
+	data.Findings[1].Repository = "myapp"
+	data.Findings = append(data.Findings, registry.Finding{
+		ID: registry.FindingNoLifecyclePolicy, Severity: registry.SeverityMedium, ResourceType: registry.ResourceRepository,
+		ResourceID: "api", Region: "us-east-1", Message: "No lifecycle policy <configured>",
+	})
+	data.Errors = []string{"us-west-2: access denied"}
+
+	var buf bytes.Buffer
+	if err := (&JUnitReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	var doc struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Errors   int `xml:"errors,attr"`
+		Suites   []struct {
+			Name     string `xml:"name,attr"`
+			Failures int    `xml:"failures,attr"`
+			Cases    []struct {
+				Name    string `xml:"name,attr"`
+				Failure *struct {
+					Message string `xml:"message,attr"`
+					Type    string `xml:"type,attr"`
+					Text    string `xml:",chardata"`
+				} `xml:"failure"`
+			} `xml:"testcase"`
+		} `xml:"testsuite"`
+	}
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+	if doc.Tests != 4 || doc.Failures != 3 || doc.Errors != 1 || len(doc.Suites) != 3 {
+		t.Fatalf("tests=%d failures=%d errors=%d suites=%d", doc.Tests, doc.Failures, doc.Errors, len(doc.Suites))
+	}
+	if s := doc.Suites[1]; s.Name != "myapp" || s.Failures != 2 || s.Cases[0].Name != "STALE_IMAGE myapp:v1.0" {
+		t.Errorf("myapp suite = %+v", s)
+	}
+	f := doc.Suites[0].Cases[0].Failure
+	if doc.Suites[0].Name != "api" || f == nil || f.Type != "NO_LIFECYCLE_POLICY" || f.Message != "No lifecycle policy <configured>" {
+		t.Errorf("api suite = %+v", doc.Suites[0])
+	}
+	if doc.Suites[2].Name != "ecrspectre scan" {
+		t.Errorf("last suite = %q, want the scan errors", doc.Suites[2].Name)
+	}
+	if !strings.Contains(doc.Suites[1].Cases[0].Failure.Text, "estimated monthly waste: $5.50") {
+		t.Errorf("failure text = %q", doc.Suites[1].Cases[0].Failure.Text)
+	}
+}
+
 func TestSpectreHubReporter(t *testing.T) {
 	var buf bytes.Buffer
 	r := &SpectreHubReporter{Writer: &buf}
@@ -203,8 +514,36 @@ func TestSARIFLevelMapping(t *testing.T) {
 
 func TestBuildSARIFRules(t *testing.T) {
 	rules := buildSARIFRules()
-	if len(rules) != 7 {
-		t.Errorf("buildSARIFRules() len = %d, want 7", len(rules))
+	if len(rules) != 19 {
+		t.Errorf("buildSARIFRules() len = %d, want 19", len(rules))
+	}
+}
+
+func TestSARIFCustomRule(t *testing.T) {
+	data := sampleData()
+	data.Findings = append(data.Findings, registry.Finding{
+		ID:           "SANDBOX_EXPIRED",
+		Severity:     registry.SeverityLow,
+		ResourceType: registry.ResourceImage,
+		ResourceID:   "team/sandbox@sha256:abc",
+		Region:       "us-east-1",
+		Message:      "Sandbox image older than 30 days",
+	})
+	var buf bytes.Buffer
+	if err := (&SARIFReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	var parsed sarifReport
+	if err := json.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	rules := parsed.Runs[0].Tool.Driver.Rules
+	if len(rules) != 20 {
+		t.Fatalf("rules = %d, want 19 built-in + 1 custom", len(rules))
+	}
+	if last := rules[19]; last.ID != "SANDBOX_EXPIRED" || last.DefaultConfig.Level != "note" {
+		t.Errorf("custom rule = %+v", last)
 	}
 }
 
@@ -224,3 +563,718 @@ func TestJSONReporterNoFindings(t *testing.T) {
 		t.Fatalf("invalid JSON: %v", err)
 	}
 }
+
+func TestReadJSONRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	if err := (&JSONReporter{Writer: &buf}).Generate(sampleData()); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	data, err := ReadJSON(&buf)
+	if err != nil {
+		t.Fatalf("ReadJSON() error: %v", err)
+	}
+	if len(data.Findings) != 2 {
+		t.Errorf("Findings len = %d, want 2", len(data.Findings))
+	}
+	if data.Summary.TotalMonthlyWaste != 7.80 {
+		t.Errorf("TotalMonthlyWaste = %f, want 7.80", data.Summary.TotalMonthlyWaste)
+	}
+}
+
+func TestReadJSONRejectsUnknownSchema(t *testing.T) {
+	if _, err := ReadJSON(strings.NewReader(`{"$schema": "other/v2"}`)); err == nil {
+		t.Error("expected error for unknown schema")
+	}
+}
+
+func TestTextReporterCoverageWhenTruncated(t *testing.T) {
+	data := sampleData()
+	data.Summary.Coverage = registry.Coverage{RepositoriesPlanned: 10, RepositoriesCompleted: 4, Percent: 87.5, Truncated: true}
+
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "87.5% (4 of 10 repositories") {
+		t.Errorf("missing coverage line:\n%s", buf.String())
+	}
+}
+
+func TestTextReporterBelowMinCost(t *testing.T) {
+	data := sampleData()
+	data.Summary.FilteredFindingsCount = 1240
+	data.Summary.FilteredWasteTotal = 38
+
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "1240 findings under $1.00 totaling $38.00/mo") {
+		t.Errorf("missing below-threshold line:\n%s", buf.String())
+	}
+}
+
+func TestTextReporterByProject(t *testing.T) {
+	data := sampleData()
+	data.Summary.ByProject = map[string]analyzer.ProjectSummary{
+		"prod": {RepositoriesScanned: 4, TotalFindings: 2, TotalMonthlyWaste: 3.5},
+	}
+
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if !strings.Contains(buf.String(), "prod                   2 findings, $3.50/mo, 4 repositories") {
+		t.Errorf("missing per-project line:\n%s", buf.String())
+	}
+}
+
+func TestTextReporterByGroup(t *testing.T) {
+	data := sampleData()
+	data.Summary.GroupBy = "team"
+	data.Summary.ByGroup = map[string]analyzer.GroupSummary{
+		"web":            {TotalFindings: 1, TotalMonthlyWaste: 1.25},
+		"payments":       {TotalFindings: 3, TotalMonthlyWaste: 9.00},
+		"(unattributed)": {TotalFindings: 2, TotalMonthlyWaste: 0.50},
+	}
+
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	out := buf.String()
+	payments := strings.Index(out, "payments               3 findings, $9.00/mo")
+	web := strings.Index(out, "web                    1 findings, $1.25/mo")
+	if !strings.Contains(out, "By team:") || payments < 0 || web < payments {
+		t.Errorf("missing or misordered per-team lines:\n%s", out)
+	}
+}
+
+func TestTextReporterCostPeriod(t *testing.T) {
+	data := sampleData()
+	data.Summary.CostPeriod = registry.CostPeriodDay
+	data.Summary.TotalPeriodWaste = 0.5
+	data.Findings[0].Metadata = map[string]any{registry.MetadataPeriodWaste: 0.25}
+
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{"estimated daily waste of $0.50", "WASTE/DAY", "$0.25", "Estimated daily waste:   $0.50"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestTextReporterProjection(t *testing.T) {
+	data := sampleData()
+	data.Summary.Projection = &analyzer.Projection{
+		MonthlyGrowth: -2.5,
+		NextYearWaste: 9120,
+		Scans:         5,
+		Since:         time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+	}
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if want := "Projected next year:     ~$9120 at -$2.50/mo per month (5 scans since 2026-03-01)"; !strings.Contains(buf.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, buf.String())
+	}
+}
+
+func TestTextReporterTop(t *testing.T) {
+	data := sampleData()
+	data.Summary.Sort = analyzer.SortWaste
+	data.Summary.Top = 20
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if want := "Showing:                 top 20 by waste; totals cover all findings"; !strings.Contains(buf.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, buf.String())
+	}
+}
+
+func TestTextReporterCarbon(t *testing.T) {
+	data := sampleData()
+	data.Summary.WastedGB = 78
+	data.Summary.CO2eKgPerMonth = 0.0467
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if want := "Carbon estimate:         0.05 kg CO2e/mo from 78.0 GB of wasted storage"; !strings.Contains(buf.String(), want) {
+		t.Errorf("output missing %q:\n%s", want, buf.String())
+	}
+}
+
+func TestTextReporterGroupByRepo(t *testing.T) {
+	data := sampleData()
+	data.Findings[0].Repository = "web"
+	data.Findings[1].Repository = "api"
+	data.Findings = append(data.Findings, registry.Finding{
+		ID:                    registry.FindingNoLifecyclePolicy,
+		Severity:              registry.SeverityMedium,
+		ResourceType:          registry.ResourceRepository,
+		ResourceID:            "api",
+		Region:                "us-east-1",
+		Message:               "No lifecycle policy configured",
+		EstimatedMonthlyWaste: 0,
+	})
+	data.Summary.GroupBy = registry.GroupByRepo
+	data.Summary.ByGroup = map[string]analyzer.GroupSummary{
+		"web": {TotalFindings: 1, TotalMonthlyWaste: 5.50},
+		"api": {TotalFindings: 3, TotalMonthlyWaste: 9.10},
+	}
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	out := buf.String()
+	api := strings.Index(out, "api — 3 findings, $9.10/mo\n")
+	web := strings.Index(out, "web — 1 findings, $5.50/mo\n")
+	if api < 0 || web < 0 || web < api {
+		t.Fatalf("repository headings missing or not most expensive first:\n%s", out)
+	}
+	// The untagged image and the lifecycle finding nest under api.
+	if section := out[api:web]; !strings.Contains(section, "  high      0.0    image       sha256:cafebabe") || !strings.Contains(section, "No lifecycle policy") {
+		t.Errorf("api section:\n%s", section)
+	}
+	if !strings.Contains(out[web:], "myapp:v1.0") {
+		t.Errorf("web section missing myapp:v1.0:\n%s", out[web:])
+	}
+	if strings.Contains(out, "By repo:") {
+		t.Errorf("repo breakdown repeated in the summary:\n%s", out)
+	}
+}
+
+func TestSARIFCostBreakdown(t *testing.T) {
+	data := sampleData()
+	data.Summary.GroupBy = "team"
+	data.Summary.ByGroup = map[string]analyzer.GroupSummary{"web": {TotalFindings: 1, TotalMonthlyWaste: 1.25}}
+	var buf bytes.Buffer
+	if err := (&SARIFReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	var parsed sarifReport
+	if err := json.Unmarshal(buf.Bytes(), &parsed); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	breakdown, ok := parsed.Runs[0].Props["costBreakdown"].(map[string]any)
+	if !ok || breakdown["groupBy"] != "team" {
+		t.Errorf("run properties = %v", parsed.Runs[0].Props)
+	}
+}
+
+func TestTextReporterRepositoryDetail(t *testing.T) {
+	pushed := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	data := sampleData()
+	data.Repository = &registry.RepositoryDetail{
+		Name:                   "myapp",
+		Region:                 "us-east-1",
+		ImageCount:             2,
+		HasLifecyclePolicy:     true,
+		LifecycleSimulated:     true,
+		ExpiringImages:         1,
+		ExpiringMonthlySavings: 0.05,
+		Images: []registry.ImageDetail{
+			{Digest: "sha256:aaaaaaaaaaaaaaaaaaaaaaaa", Tags: []string{"v1"}, PushedAt: &pushed},
+			{Digest: "sha256:bbbbbbbbbbbbbbbbbbbbbbbb", ExpiredByRule: 2, Findings: []registry.FindingID{registry.FindingUntaggedImage}},
+		},
+	}
+
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"Repository myapp (us-east-1)",
+		"would expire 1 images",
+		"sha256:aaaaaaaaaaaa",
+		"2026-01-02",
+		"expire (rule 2)",
+		"UNTAGGED_IMAGE",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("missing %q in output:\n%s", want, out)
+		}
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	cases := []struct {
+		in   []float64
+		want string
+	}{
+		{nil, ""},
+		{[]float64{5}, ""},
+		{[]float64{3, 3, 3}, "▁▁▁"},
+		{[]float64{0, 7, 14}, "▁▅█"},
+		{[]float64{10, 0}, "█▁"},
+	}
+	for _, c := range cases {
+		if got := Sparkline(c.in); got != c.want {
+			t.Errorf("Sparkline(%v) = %q, want %q", c.in, got, c.want)
+		}
+	}
+}
+
+func TestTextReporterTrend(t *testing.T) {
+	data := sampleData()
+	data.Trend = &Trend{MonthlyWaste: []float64{9, 4, 1}, Findings: []float64{1, 2, 3}}
+
+	var buf bytes.Buffer
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "▁▅█ (last 3 scans)") || !strings.Contains(out, "█▄▁ (last 3 scans)") {
+		t.Errorf("missing trend sparklines:\n%s", out)
+	}
+
+	data.Trend = nil
+	buf.Reset()
+	if err := (&TextReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	if strings.Contains(buf.String(), "last") {
+		t.Errorf("no sparkline expected without history:\n%s", buf.String())
+	}
+}
+
+func TestPrometheusReporter(t *testing.T) {
+	data := sampleData()
+	data.Findings[0].Repository = "myapp"
+	data.Findings[1].Repository = "myapp"
+	data.Findings = append(data.Findings, registry.Finding{
+		ID: registry.FindingStaleImage, Severity: registry.SeverityHigh, ResourceType: registry.ResourceImage,
+		Repository: `team/"odd"`, Region: "us-east-1", EstimatedMonthlyWaste: 1.25,
+	})
+	data.Errors = []string{"us-west-2: access denied"}
+	data.Duration = 90 * time.Second
+
+	var buf bytes.Buffer
+	if err := (&PrometheusReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE ecrspectre_monthly_waste_dollars gauge\n",
+		`ecrspectre_monthly_waste_dollars{repo="myapp",region="us-east-1",severity="high",finding="STALE_IMAGE"} 5.5` + "\n",
+		`ecrspectre_monthly_waste_dollars{repo="team/\"odd\"",region="us-east-1",severity="high",finding="STALE_IMAGE"} 1.25` + "\n",
+		`ecrspectre_findings_total{severity="high",finding="STALE_IMAGE"} 2` + "\n",
+		`ecrspectre_findings_total{severity="high",finding="UNTAGGED_IMAGE"} 1` + "\n",
+		"ecrspectre_total_monthly_waste_dollars 7.8\n",
+		"ecrspectre_scan_errors 1\n",
+		fmt.Sprintf("ecrspectre_scan_timestamp_seconds %d\n", data.Timestamp.Unix()),
+		"ecrspectre_scan_duration_seconds 90\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestGitHubReporter(t *testing.T) {
+	data := sampleData()
+	data.Findings = append(data.Findings, registry.Finding{
+		ID: registry.FindingNoLifecyclePolicy, Severity: registry.SeverityMedium, ResourceType: registry.ResourceRepository,
+		ResourceID: "api", Region: "us-east-1", Message: "100% untidy\nsecond line",
+	})
+	data.Errors = []string{"us-west-2: access denied"}
+	summaryPath := filepath.Join(t.TempDir(), "summary.md")
+	if err := os.WriteFile(summaryPath, []byte("earlier step\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := (&GitHubReporter{Writer: &buf, SummaryPath: summaryPath}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	want := []string{
+		"::error title=STALE_IMAGE myapp%3Av1.0::Image not pulled in 120 days (us-east-1, $5.50/mo)",
+		"::error title=UNTAGGED_IMAGE sha256%3Acafebabe::Image has no tags (us-east-1, $2.30/mo)",
+		"::warning title=NO_LIFECYCLE_POLICY api::100%25 untidy%0Asecond line (us-east-1, $0.00/mo)",
+		"::error title=ecrspectre scan error::us-west-2: access denied",
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("got %d lines:\n%s", len(lines), buf.String())
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Errorf("line %d = %q\nwant     %q", i, lines[i], want[i])
+		}
+	}
+
+	summary, err := os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(summary), "earlier step\n### ecrspectre") {
+		t.Errorf("job summary not appended:\n%s", summary)
+	}
+}
+
+func TestGitHubReporterCapsAnnotations(t *testing.T) {
+	data := sampleData()
+	data.Findings = nil
+	for i := 0; i < GitHubMaxAnnotations+5; i++ {
+		data.Findings = append(data.Findings, registry.Finding{ID: registry.FindingStaleImage, Severity: registry.SeverityLow, ResourceID: fmt.Sprintf("img%d", i)})
+	}
+	var buf bytes.Buffer
+	if err := (&GitHubReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != GitHubMaxAnnotations+1 || !strings.Contains(lines[GitHubMaxAnnotations], "5 more findings") {
+		t.Errorf("got %d lines, last %q", len(lines), lines[len(lines)-1])
+	}
+}
+
+func TestSlackReporter(t *testing.T) {
+	var got slackMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode payload: %v", err)
+		}
+		_, _ = fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	data := sampleData()
+	data.RunID = "run-1"
+	data.Findings[0].Message = "Pulled <never> & stale"
+	if err := (&SlackReporter{WebhookURL: srv.URL, Client: srv.Client()}).Generate(data); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(got.Text, "findings with estimated monthly waste") {
+		t.Errorf("fallback text = %q", got.Text)
+	}
+	if len(got.Blocks) != 3 {
+		t.Fatalf("blocks = %+v, want headline, findings and context", got.Blocks)
+	}
+	if top := got.Blocks[1].Text.Text; !strings.Contains(top, "`STALE_IMAGE`") || !strings.Contains(top, "Pulled &lt;never&gt; &amp; stale") {
+		t.Errorf("top findings = %q", top)
+	}
+	if scope := got.Blocks[2].Elements[0].Text; !strings.Contains(scope, "run run-1") {
+		t.Errorf("context = %q, want the run ID", scope)
+	}
+}
+
+func TestSlackReporterTopFindings(t *testing.T) {
+	var findings []registry.Finding
+	for i := range SlackMaxFindings + 3 {
+		findings = append(findings, registry.Finding{ID: registry.FindingStaleImage, ResourceID: fmt.Sprint(i), EstimatedMonthlyWaste: float64(i)})
+	}
+	top := slackTopFindings(findings)
+	if len(top) != SlackMaxFindings || top[0].ResourceID != "7" || top[SlackMaxFindings-1].ResourceID != "3" {
+		t.Errorf("top findings = %+v", top)
+	}
+}
+
+func TestSlackReporterError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	err := (&SlackReporter{WebhookURL: srv.URL + "/services/T000/B000/secret"}).Generate(sampleData())
+	if err == nil || !strings.Contains(err.Error(), "invalid_token") {
+		t.Errorf("Generate() error = %v, want the Slack response", err)
+	}
+	if err != nil && strings.Contains(err.Error(), "secret") {
+		t.Errorf("error leaks the webhook URL: %v", err)
+	}
+}
+
+func TestCloudEventsReporter(t *testing.T) {
+	var got []CloudEvent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ct := r.Header.Get("Content-Type"); ct != "application/cloudevents+json" {
+			t.Errorf("Content-Type = %q", ct)
+		}
+		var e CloudEvent
+		if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+			t.Errorf("decode event: %v", err)
+		}
+		got = append(got, e)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	data := sampleData()
+	data.RunID = "run-1"
+	data.Summary.BySeverity = map[string]int{"high": 1, "low": 2}
+	r := &CloudEventsReporter{URL: srv.URL, Client: srv.Client(), WasteThreshold: data.Summary.TotalMonthlyWaste, SeverityThreshold: registry.SeverityMedium}
+	if err := r.Generate(data); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d events, want scan.completed and two threshold.breached", len(got))
+	}
+	completed := got[0]
+	if completed.SpecVersion != "1.0" || completed.Type != EventScanCompleted || completed.ID != "run-1/scan.completed" ||
+		completed.RunID != "run-1" || completed.Source != "urn:ecrspectre:"+data.Target.Type+":"+data.Target.URIHash {
+		t.Errorf("scan.completed = %+v", completed)
+	}
+	if d := completed.Data.(map[string]any); d["total_findings"] != float64(data.Summary.TotalFindings) || len(d["top_findings"].([]any)) != len(data.Findings) {
+		t.Errorf("scan.completed data = %v", d)
+	}
+	if got[1].Type != EventThresholdBreached || got[1].Data.(map[string]any)["threshold"] != "monthly_waste" {
+		t.Errorf("event 2 = %+v, want the monthly waste breach", got[1])
+	}
+	if d := got[2].Data.(map[string]any); d["threshold"] != "severity" || d["limit"] != "medium" || d["value"] != float64(1) {
+		t.Errorf("event 3 data = %v, want 1 finding of at least medium severity", d)
+	}
+}
+
+func TestCloudEventsBelowThresholds(t *testing.T) {
+	data := sampleData()
+	data.Summary.BySeverity = map[string]int{"low": 2}
+	r := &CloudEventsReporter{WasteThreshold: data.Summary.TotalMonthlyWaste + 1, SeverityThreshold: registry.SeverityHigh}
+	if events := r.CloudEvents(data); len(events) != 1 || events[0].Type != EventScanCompleted {
+		t.Errorf("events = %+v, want only scan.completed", events)
+	}
+}
+
+func TestCloudEventsReporterError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "no route", http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	err := (&CloudEventsReporter{URL: srv.URL}).Generate(sampleData())
+	if err == nil || !strings.Contains(err.Error(), EventScanCompleted) || !strings.Contains(err.Error(), "no route") {
+		t.Errorf("Generate() error = %v, want the event type and response", err)
+	}
+}
+
+func TestTemplateReporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "wiki.tmpl")
+	text := `h1. {{upper .Config.Provider}} registry waste: {{money .Summary.TotalMonthlyWaste}}
+{{range .Findings}}|| {{.ID}} | {{or .ResourceName (shortDigest .ResourceID)}} | {{money .EstimatedMonthlyWaste}} ||
+{{end}}{{bytes 1610612736}} {{toJSON .Summary.BySeverity}}`
+	if err := os.WriteFile(path, []byte(text), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := ParseTemplate(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := (&TemplateReporter{Writer: &buf, Template: tmpl}).Generate(sampleData()); err != nil {
+		t.Fatal(err)
+	}
+	want := `h1. AWS registry waste: $7.80
+|| STALE_IMAGE | myapp:v1.0 | $5.50 ||
+|| UNTAGGED_IMAGE | sha256:cafebabe | $2.30 ||
+1.5 GiB {"high":2}`
+	if got := buf.String(); got != want {
+		t.Errorf("template output:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTemplateReporterErrors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.tmpl")
+	if err := os.WriteFile(bad, []byte("{{.Summary"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseTemplate(bad); err == nil || !strings.Contains(err.Error(), "parse template") {
+		t.Errorf("ParseTemplate(bad) error = %v, want a parse error", err)
+	}
+
+	// A field that does not exist fails without writing a partial report.
+	missing := filepath.Join(dir, "missing.tmpl")
+	if err := os.WriteFile(missing, []byte("total: {{.Summary.NoSuchField}}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tmpl, err := ParseTemplate(missing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := (&TemplateReporter{Writer: &buf, Template: tmpl}).Generate(sampleData()); err == nil || buf.Len() > 0 {
+		t.Errorf("Generate error = %v with output %q, want an error and no output", err, buf.String())
+	}
+}
+
+func TestOCSFReporter(t *testing.T) {
+	data := sampleData()
+	data.RunID = "run-1"
+	data.Findings[0].Repository = "myapp"
+	data.Findings[0].Score = 61.5
+	var buf bytes.Buffer
+	if err := (&OCSFReporter{Writer: &buf}).Generate(data); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d events, want one per finding:\n%s", len(lines), buf.String())
+	}
+	var e map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]any{
+		"class_uid": 2004.0, "category_uid": 2.0, "type_uid": 200401.0, "activity_id": 1.0,
+		"severity_id": 4.0, "severity": "High", "status": "New", "time": float64(data.Timestamp.UnixMilli()),
+	} {
+		if e[key] != want {
+			t.Errorf("%s = %v, want %v", key, e[key], want)
+		}
+	}
+	info := e["finding_info"].(map[string]any)
+	if info["title"] != "Stale container image" || info["types"].([]any)[0] != "STALE_IMAGE" {
+		t.Errorf("finding_info = %v", info)
+	}
+	if md := e["metadata"].(map[string]any); md["version"] != OCSFVersion || md["correlation_uid"] != "run-1" {
+		t.Errorf("metadata = %v", md)
+	}
+	if c := e["cloud"].(map[string]any); c["provider"] != "AWS" || c["region"] != "us-east-1" {
+		t.Errorf("cloud = %v", c)
+	}
+	res := e["resources"].([]any)[0].(map[string]any)
+	if res["uid"] != "sha256:deadbeef" || res["group"].(map[string]any)["name"] != "myapp" {
+		t.Errorf("resource = %v", res)
+	}
+	if u := e["unmapped"].(map[string]any); u["estimated_monthly_waste"] != 5.5 || u["score"] != 61.5 {
+		t.Errorf("unmapped = %v", u)
+	}
+
+	// The finding UID is stable across scans and distinct per finding.
+	var again bytes.Buffer
+	data.Timestamp = data.Timestamp.Add(24 * time.Hour)
+	if err := (&OCSFReporter{Writer: &again}).Generate(data); err != nil {
+		t.Fatal(err)
+	}
+	var e2 map[string]any
+	_ = json.Unmarshal([]byte(strings.SplitN(again.String(), "\n", 2)[0]), &e2)
+	uid := info["uid"]
+	if e2["finding_info"].(map[string]any)["uid"] != uid || ocsfFindingUID(data.Findings[1]) == uid {
+		t.Errorf("finding uid %v not stable or not unique", uid)
+	}
+}
+
+func TestSQLiteReporter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "findings.db")
+	r := &SQLiteReporter{Path: path}
+	data := sampleData()
+	data.RunID = "run-1"
+	data.Findings[0].Metadata = map[string]any{"team": "payments"}
+	if err := r.Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	data.RunID = "run-2"
+	data.Findings = data.Findings[:1]
+	if err := r.Generate(data); err != nil {
+		t.Fatalf("second Generate() error: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sqlite.Read(raw)
+	if err != nil {
+		t.Fatalf("read database: %v", err)
+	}
+	if db.UserVersion != SQLiteSchemaVersion {
+		t.Errorf("user_version = %d", db.UserVersion)
+	}
+	scans, findings := db.Table("scans").Rows, db.Table("findings").Rows
+	if len(scans) != 2 || scans[1].ID != 2 || scans[1].Values[1] != "run-2" || scans[0].Values[2] != "2026-02-28T12:00:00Z" {
+		t.Fatalf("scans = %v", scans)
+	}
+	if scans[0].Values[7] != `["us-east-1"]` || scans[0].Values[12] != 7.8 {
+		t.Errorf("scan 1 = %v", scans[0].Values)
+	}
+	if len(findings) != 3 || findings[2].ID != 3 || findings[2].Values[1] != int64(2) {
+		t.Fatalf("findings = %v", findings)
+	}
+	f := findings[0].Values
+	if f[2] != "STALE_IMAGE" || f[5] != "sha256:deadbeef" || f[6] != "myapp:v1.0" || f[10] != 5.5 || f[12] != `{"team":"payments"}` {
+		t.Errorf("finding 1 = %v", f)
+	}
+	if findings[1].Values[6] != nil || findings[1].Values[12] != nil {
+		t.Errorf("finding 2 empty columns = %v", findings[1].Values)
+	}
+
+	all, err := LoadSQLiteHistory(path)
+	if err != nil {
+		t.Fatalf("LoadSQLiteHistory() error: %v", err)
+	}
+	records := all[data.Target.URIHash]
+	if len(all) != 1 || len(records) != 2 || records[1].RunID != "run-2" || records[0].TotalMonthlyWaste != 7.8 {
+		t.Fatalf("history = %+v", all)
+	}
+	if len(records[0].Findings) != 2 || len(records[1].Findings) != 1 || records[1].Findings[history.FindingKey(data.Findings[0])] != 5.5 {
+		t.Errorf("history findings = %v, %v", records[0].Findings, records[1].Findings)
+	}
+
+	if err := os.WriteFile(path, []byte("not a database"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Generate(data); err == nil {
+		t.Error("Generate() over a non-database file succeeded")
+	}
+}
+
+type memStore map[string]string
+
+func (m memStore) Put(_ context.Context, key string, body io.ReadSeeker, _ int64) error {
+	b, err := io.ReadAll(body)
+	m[key] = string(b)
+	return err
+}
+
+func (m memStore) URL(key string) string { return "s3://bucket/registry/" + key }
+
+func TestDatasetReporter(t *testing.T) {
+	store := memStore{}
+	data := sampleData()
+	data.RunID = "run-1"
+	data.Findings[0].Metadata = map[string]any{"team": "payments", "size_bytes": int64(2048)}
+	data.Usage = map[string]registry.RepoUsage{"myapp": {Region: "us-east-1", SizeBytes: 10 << 30}}
+	if err := (&DatasetReporter{Store: store}).Generate(data); err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(store["findings/provider=aws/dt=2026-02-28/run-1.csv"])).ReadAll()
+	if err != nil {
+		t.Fatalf("findings CSV: %v (files %v)", err, slices.Collect(maps.Keys(store)))
+	}
+	if len(rows) != 3 || len(rows[0]) != len(datasetFindingColumns) {
+		t.Fatalf("findings rows = %v", rows)
+	}
+	want := []string{"2026-02-28 12:00:00", "run-1", "sha256:abc123", "STALE_IMAGE", "high", "image", "sha256:deadbeef", "myapp:v1.0", "", "us-east-1", "", "payments", "Image not pulled in 120 days", "5.5000", "", "2048", `{"size_bytes":2048,"team":"payments"}`}
+	if !slices.Equal(rows[1], want) {
+		t.Errorf("finding row = %q, want %q", rows[1], want)
+	}
+
+	repos, err := csv.NewReader(strings.NewReader(store["repositories/provider=aws/dt=2026-02-28/run-1.csv"])).ReadAll()
+	if err != nil || len(repos) != 2 {
+		t.Fatalf("repository rows = %v, %v", repos, err)
+	}
+	if got := repos[1][3:]; !slices.Equal(got, []string{"myapp", "us-east-1", "10737418240", "1.0000"}) {
+		t.Errorf("repository row = %q", got)
+	}
+
+	var schema []map[string]string
+	if err := json.Unmarshal([]byte(store["schema/findings.bigquery.json"]), &schema); err != nil || len(schema) != len(datasetFindingColumns) {
+		t.Fatalf("BigQuery schema = %v, %v", schema, err)
+	}
+	if schema[0]["type"] != "TIMESTAMP" || schema[0]["mode"] != "REQUIRED" || schema[1]["mode"] != "NULLABLE" {
+		t.Errorf("schema = %v", schema[:2])
+	}
+	if ddl := store["schema/athena.sql"]; !strings.Contains(ddl, "LOCATION 's3://bucket/registry/findings/'") || !strings.Contains(ddl, "ecrspectre_repositories") {
+		t.Errorf("Athena DDL = %s", ddl)
+	}
+}
@@ -17,8 +17,15 @@ type sarifReport struct {
 }
 
 type sarifRun struct {
-	Tool    sarifTool     `json:"tool"`
-	Results []sarifResult `json:"results"`
+	Tool       sarifTool        `json:"tool"`
+	Automation *sarifAutomation `json:"automationDetails,omitempty"`
+	Results    []sarifResult    `json:"results"`
+	Props      map[string]any   `json:"properties,omitempty"`
+}
+
+// sarifAutomation identifies the scan run that produced the results.
+type sarifAutomation struct {
+	GUID string `json:"guid"`
 }
 
 type sarifTool struct {
@@ -68,9 +75,22 @@ type sarifArtifact struct {
 // Generate writes SARIF v2.1.0 output.
 func (r *SARIFReporter) Generate(data Data) error {
 	rules := buildSARIFRules()
+	known := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		known[rule.ID] = true
+	}
 	results := make([]sarifResult, 0, len(data.Findings))
 
 	for _, f := range data.Findings {
+		// Custom rules from config are declared on first use.
+		if !known[string(f.ID)] {
+			known[string(f.ID)] = true
+			rules = append(rules, sarifRule{
+				ID:               string(f.ID),
+				ShortDescription: sarifMessage{Text: f.Message},
+				DefaultConfig:    sarifDefaultLevel{Level: sarifLevel(f.Severity)},
+			})
+		}
 		results = append(results, sarifResult{
 			RuleID:  string(f.ID),
 			Level:   sarifLevel(f.Severity),
@@ -92,6 +112,22 @@ func (r *SARIFReporter) Generate(data Data) error {
 		})
 	}
 
+	runProps := make(map[string]any)
+	if len(data.FeaturesUsed) > 0 {
+		runProps["featuresUsed"] = data.FeaturesUsed
+	}
+	if len(data.Summary.ByGroup) > 0 {
+		runProps["costBreakdown"] = map[string]any{"groupBy": data.Summary.GroupBy, "groups": data.Summary.ByGroup}
+	}
+	if len(runProps) == 0 {
+		runProps = nil
+	}
+
+	var automation *sarifAutomation
+	if data.RunID != "" {
+		automation = &sarifAutomation{GUID: data.RunID}
+	}
+
 	report := sarifReport{
 		Schema:  sarifSchema,
 		Version: "2.1.0",
@@ -104,7 +140,9 @@ func (r *SARIFReporter) Generate(data Data) error {
 						Rules:   rules,
 					},
 				},
-				Results: results,
+				Automation: automation,
+				Results:    results,
+				Props:      runProps,
 			},
 		},
 	}
@@ -139,5 +177,17 @@ func buildSARIFRules() []sarifRule {
 		{ID: string(registry.FindingVulnerableImage), ShortDescription: sarifMessage{Text: "Vulnerable container image"}, DefaultConfig: sarifDefaultLevel{Level: "error"}},
 		{ID: string(registry.FindingUnusedRepo), ShortDescription: sarifMessage{Text: "Unused container repository"}, DefaultConfig: sarifDefaultLevel{Level: "note"}},
 		{ID: string(registry.FindingMultiArchBloat), ShortDescription: sarifMessage{Text: "Multi-architecture bloat"}, DefaultConfig: sarifDefaultLevel{Level: "note"}},
+		{ID: string(registry.FindingDuplicateLayers), ShortDescription: sarifMessage{Text: "Duplicate image layers"}, DefaultConfig: sarifDefaultLevel{Level: "warning"}},
+		{ID: string(registry.FindingQuotaPressure), ShortDescription: sarifMessage{Text: "Storage quota nearly exhausted"}, DefaultConfig: sarifDefaultLevel{Level: "warning"}},
+		{ID: string(registry.FindingStorageSpike), ShortDescription: sarifMessage{Text: "Anomalous repository storage growth"}, DefaultConfig: sarifDefaultLevel{Level: "warning"}},
+		{ID: string(registry.FindingStaleRemoteCache), ShortDescription: sarifMessage{Text: "Stale remote repository cache without cleanup"}, DefaultConfig: sarifDefaultLevel{Level: "warning"}},
+		{ID: string(registry.FindingLongTailWaste), ShortDescription: sarifMessage{Text: "Many small findings adding up in one repository"}, DefaultConfig: sarifDefaultLevel{Level: "note"}},
+		{ID: string(registry.FindingOrphanedManifest), ShortDescription: sarifMessage{Text: "Platform manifest orphaned from its multi-arch index"}, DefaultConfig: sarifDefaultLevel{Level: "error"}},
+		{ID: string(registry.FindingUnsignedImage), ShortDescription: sarifMessage{Text: "Tagged image without a signature"}, DefaultConfig: sarifDefaultLevel{Level: "warning"}},
+		{ID: string(registry.FindingMissingSBOM), ShortDescription: sarifMessage{Text: "Recent tagged image without an SBOM"}, DefaultConfig: sarifDefaultLevel{Level: "warning"}},
+		{ID: string(registry.FindingAgingDeployedImage), ShortDescription: sarifMessage{Text: "Deployed image built longer ago than the maximum age"}, DefaultConfig: sarifDefaultLevel{Level: "warning"}},
+		{ID: string(registry.FindingUntaggedAccumulation), ShortDescription: sarifMessage{Text: "Repository accumulating untagged images (build cache)"}, DefaultConfig: sarifDefaultLevel{Level: "error"}},
+		{ID: string(registry.FindingStaleReleaseTrain), ShortDescription: sarifMessage{Text: "Repository stopped shipping at its release cadence"}, DefaultConfig: sarifDefaultLevel{Level: "warning"}},
+		{ID: string(registry.FindingTieringCandidate), ShortDescription: sarifMessage{Text: "Large stale retained image cheaper in archive storage"}, DefaultConfig: sarifDefaultLevel{Level: "note"}},
 	}
 }
@@ -0,0 +1,138 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/ppiankov/ecrspectre/internal/registry"
+)
+
+// SlackMaxFindings is the number of costliest findings listed in a Slack
+// message; the totals cover all findings.
+const SlackMaxFindings = 5
+
+// slackMessage is an incoming webhook payload. Text is the notification
+// fallback for clients that do not render blocks.
+type slackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Generate posts a summary of the scan with its costliest findings to the
+// incoming webhook.
+func (r *SlackReporter) Generate(data Data) error {
+	body, err := json.Marshal(slackPayload(data))
+	if err != nil {
+		return fmt.Errorf("encode Slack message: %w", err)
+	}
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Post(r.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		// The webhook URL is a secret; keep it out of the error.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("post Slack message: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("post Slack message: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// slackPayload builds the message: a headline with the totals, the
+// costliest findings, and a context line identifying the scan.
+func slackPayload(data Data) slackMessage {
+	period := costPeriod(data.Summary)
+	suffix := period.Suffix()
+
+	var headline string
+	if len(data.Findings) == 0 {
+		headline = fmt.Sprintf("No waste found across %d repositories.", data.Summary.RepositoriesScanned)
+	} else {
+		headline = fmt.Sprintf("%d findings with estimated %s waste of $%.2f across %d repositories.",
+			data.Summary.TotalFindings, period.Adjective(), periodTotal(data.Summary), data.Summary.RepositoriesScanned)
+	}
+	if n := len(data.Errors); n > 0 {
+		headline += fmt.Sprintf(" %d scan errors, see the report.", n)
+	}
+
+	msg := slackMessage{
+		Text: "ecrspectre: " + headline,
+		Blocks: []slackBlock{{
+			Type: "section",
+			Text: &slackText{Type: "mrkdwn", Text: "*ecrspectre — Container Registry Waste Report*\n" + slackEscape(headline)},
+		}},
+	}
+
+	if top := slackTopFindings(data.Findings); len(top) > 0 {
+		var b strings.Builder
+		b.WriteString("*Top findings*")
+		for _, f := range top {
+			name := f.ResourceID
+			if f.ResourceName != "" {
+				name = f.ResourceName
+			}
+			fmt.Fprintf(&b, "\n• `%s` %s (%s) — $%.2f%s: %s", f.ID, slackEscape(name), f.Region, registry.PeriodWaste(f), suffix, slackEscape(f.Message))
+		}
+		msg.Blocks = append(msg.Blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: b.String()}})
+	}
+
+	scope := []string{data.Config.Provider}
+	if len(data.Config.Projects) > 0 {
+		scope = append(scope, strings.Join(data.Config.Projects, ", "))
+	}
+	if len(data.Config.Regions) > 0 {
+		scope = append(scope, strings.Join(data.Config.Regions, ", "))
+	}
+	if data.RunID != "" {
+		scope = append(scope, "run "+data.RunID)
+	}
+	msg.Blocks = append(msg.Blocks, slackBlock{
+		Type:     "context",
+		Elements: []slackText{{Type: "mrkdwn", Text: slackEscape(strings.Join(scope, " · "))}},
+	})
+	return msg
+}
+
+// slackTopFindings returns the SlackMaxFindings costliest findings.
+func slackTopFindings(findings []registry.Finding) []registry.Finding {
+	top := append([]registry.Finding(nil), findings...)
+	sort.SliceStable(top, func(i, j int) bool {
+		return registry.PeriodWaste(top[i]) > registry.PeriodWaste(top[j])
+	})
+	if len(top) > SlackMaxFindings {
+		top = top[:SlackMaxFindings]
+	}
+	return top
+}
+
+// slackEscape escapes the characters Slack's mrkdwn treats as control
+// sequences.
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,31 @@
+package report
+
+import (
+	"math"
+	"strings"
+)
+
+// sparkBlocks are the eight block heights used by Sparkline.
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline renders values as a row of block characters scaled between their
+// minimum and maximum. A constant series renders at the lowest height; fewer
+// than two values render as an empty string.
+func Sparkline(values []float64) string {
+	if len(values) < 2 {
+		return ""
+	}
+	lo, hi := math.Inf(1), math.Inf(-1)
+	for _, v := range values {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	var b strings.Builder
+	for _, v := range values {
+		i := 0
+		if hi > lo {
+			i = int(math.Round((v - lo) / (hi - lo) * float64(len(sparkBlocks)-1)))
+		}
+		b.WriteRune(sparkBlocks[i])
+	}
+	return b.String()
+}
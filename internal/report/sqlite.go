@@ -0,0 +1,285 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/history"
+	"github.com/ppiankov/ecrspectre/internal/registry"
+	"github.com/ppiankov/ecrspectre/internal/sqlite"
+)
+
+// SQLiteSchemaVersion is the user_version of findings databases. It changes
+// only when the scans or findings tables change incompatibly.
+const SQLiteSchemaVersion = 1
+
+// The tables of a findings database. Lists and nested values, such as the
+// scanned regions, the summary and finding metadata, are JSON text that
+// SQLite's JSON functions can query.
+const (
+	sqliteScansSQL = `CREATE TABLE scans (
+  id INTEGER PRIMARY KEY,
+  run_id TEXT,
+  timestamp TEXT NOT NULL,
+  tool TEXT NOT NULL,
+  version TEXT NOT NULL,
+  provider TEXT NOT NULL,
+  target TEXT NOT NULL,
+  regions TEXT,
+  projects TEXT,
+  resources_scanned INTEGER NOT NULL,
+  repositories_scanned INTEGER NOT NULL,
+  total_findings INTEGER NOT NULL,
+  total_monthly_waste REAL NOT NULL,
+  errors INTEGER NOT NULL,
+  summary TEXT
+)`
+	sqliteFindingsSQL = `CREATE TABLE findings (
+  id INTEGER PRIMARY KEY,
+  scan_id INTEGER NOT NULL REFERENCES scans (id),
+  finding_id TEXT NOT NULL,
+  severity TEXT NOT NULL,
+  resource_type TEXT NOT NULL,
+  resource_id TEXT NOT NULL,
+  resource_name TEXT,
+  repository TEXT,
+  region TEXT,
+  message TEXT,
+  estimated_monthly_waste REAL NOT NULL,
+  score REAL,
+  metadata TEXT
+)`
+)
+
+// Generate adds the scan and its findings to the database at Path, creating
+// it on first use, so one file accumulates every scan. The database is
+// rewritten through a temporary file and renamed into place, so a failed
+// write leaves the previous scans intact.
+func (r *SQLiteReporter) Generate(data Data) error {
+	db, err := loadFindingsDB(r.Path)
+	if err != nil {
+		return err
+	}
+	scans, findings := db.Table("scans"), db.Table("findings")
+
+	scanID := nextRowID(scans.Rows)
+	summary, err := json.Marshal(data.Summary)
+	if err != nil {
+		return fmt.Errorf("encode summary: %w", err)
+	}
+	scans.Rows = append(scans.Rows, sqlite.Row{ID: scanID, Values: []any{
+		nil,
+		nullString(data.RunID),
+		data.Timestamp.UTC().Format(time.RFC3339),
+		data.Tool,
+		data.Version,
+		data.Config.Provider,
+		data.Target.URIHash,
+		jsonText(data.Config.Regions),
+		jsonText(data.Config.Projects),
+		int64(data.Summary.TotalResourcesScanned),
+		int64(data.Summary.RepositoriesScanned),
+		int64(data.Summary.TotalFindings),
+		data.Summary.TotalMonthlyWaste,
+		int64(len(data.Errors)),
+		string(summary),
+	}})
+
+	id := nextRowID(findings.Rows)
+	for _, f := range data.Findings {
+		var score any
+		if f.Score > 0 {
+			score = f.Score
+		}
+		findings.Rows = append(findings.Rows, sqlite.Row{ID: id, Values: []any{
+			nil,
+			scanID,
+			string(f.ID),
+			string(f.Severity),
+			string(f.ResourceType),
+			f.ResourceID,
+			nullString(f.ResourceName),
+			nullString(registry.FindingRepository(f)),
+			nullString(f.Region),
+			f.Message,
+			f.EstimatedMonthlyWaste,
+			score,
+			jsonText(f.Metadata),
+		}})
+		id++
+	}
+
+	var buf bytes.Buffer
+	if err := sqlite.Write(&buf, *db); err != nil {
+		return fmt.Errorf("encode SQLite database: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(r.Path), ".ecrspectre-sqlite-*")
+	if err != nil {
+		return fmt.Errorf("create SQLite database: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	if err := tmp.Chmod(0o644); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write SQLite database: %w", err)
+	}
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write SQLite database: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write SQLite database: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), r.Path); err != nil {
+		return fmt.Errorf("write SQLite database: %w", err)
+	}
+	return nil
+}
+
+// loadFindingsDB reads the findings database at path, or returns an empty
+// one when the file does not exist or is empty.
+func loadFindingsDB(path string) (*sqlite.Database, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) || (err == nil && len(data) == 0) {
+		return &sqlite.Database{UserVersion: SQLiteSchemaVersion, Objects: []sqlite.Object{
+			{Type: "table", Name: "scans", SQL: sqliteScansSQL},
+			{Type: "table", Name: "findings", SQL: sqliteFindingsSQL},
+		}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read SQLite database: %w", err)
+	}
+	// Changes a SQLite session has not checkpointed yet live in the
+	// write-ahead log, which rewriting the file would lose.
+	if fi, err := os.Stat(path + "-wal"); err == nil && fi.Size() > 0 {
+		return nil, fmt.Errorf("%s has a write-ahead log; close the SQLite sessions using it first", path)
+	}
+	db, err := sqlite.Read(data)
+	if err != nil {
+		return nil, fmt.Errorf("read SQLite database %s: %w", path, err)
+	}
+	if db.Table("scans") == nil || db.Table("findings") == nil {
+		return nil, fmt.Errorf("%s is not an ecrspectre findings database", path)
+	}
+	if db.UserVersion != SQLiteSchemaVersion {
+		return nil, fmt.Errorf("%s has schema version %d, this version of ecrspectre writes %d", path, db.UserVersion, SQLiteSchemaVersion)
+	}
+	return db, nil
+}
+
+// LoadSQLiteHistory reads the scans of the findings database at path as
+// scan history, keyed by target like history.LoadAll. Each record carries its
+// findings, so scans can be compared finding by finding.
+func LoadSQLiteHistory(path string) (map[string][]history.Record, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read SQLite database: %w", err)
+	}
+	db, err := sqlite.Read(data)
+	if err != nil {
+		return nil, fmt.Errorf("read SQLite database %s: %w", path, err)
+	}
+	scans, findings := db.Table("scans"), db.Table("findings")
+	if scans == nil || findings == nil {
+		return nil, fmt.Errorf("%s is not an ecrspectre findings database", path)
+	}
+
+	records := make(map[int64]*history.Record, len(scans.Rows))
+	for _, row := range scans.Rows {
+		ts, err := time.Parse(time.RFC3339, sqliteText(row.Values, 2))
+		if err != nil {
+			return nil, fmt.Errorf("scan %d: invalid timestamp: %w", row.ID, err)
+		}
+		records[row.ID] = &history.Record{
+			Schema:            history.RecordSchema,
+			Timestamp:         ts,
+			RunID:             sqliteText(row.Values, 1),
+			Provider:          sqliteText(row.Values, 5),
+			Target:            sqliteText(row.Values, 6),
+			TotalFindings:     int(sqliteNumber(row.Values, 11)),
+			TotalMonthlyWaste: sqliteNumber(row.Values, 12),
+			FindingsByType:    make(map[string]int),
+			RepositoryWaste:   make(map[string]float64),
+			Findings:          make(map[string]float64),
+		}
+	}
+	for _, row := range findings.Rows {
+		r := records[int64(sqliteNumber(row.Values, 1))]
+		if r == nil {
+			continue
+		}
+		f := registry.Finding{
+			ID:         registry.FindingID(sqliteText(row.Values, 2)),
+			ResourceID: sqliteText(row.Values, 5),
+			Region:     sqliteText(row.Values, 8),
+		}
+		waste := sqliteNumber(row.Values, 10)
+		r.FindingsByType[string(f.ID)]++
+		r.Findings[history.FindingKey(f)] += waste
+		if repo := sqliteText(row.Values, 7); repo != "" {
+			r.RepositoryWaste[repo] += waste
+		}
+	}
+
+	all := make(map[string][]history.Record)
+	for _, row := range scans.Rows {
+		r := records[row.ID]
+		all[r.Target] = append(all[r.Target], *r)
+	}
+	for _, rs := range all {
+		sort.SliceStable(rs, func(i, j int) bool { return rs[i].Timestamp.Before(rs[j].Timestamp) })
+	}
+	return all, nil
+}
+
+// sqliteText returns column i of a row as text, or "" for NULL.
+func sqliteText(values []any, i int) string {
+	if i < len(values) {
+		if s, ok := values[i].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+// sqliteNumber returns column i of a row as a number, or 0 for NULL.
+func sqliteNumber(values []any, i int) float64 {
+	if i < len(values) {
+		switch v := values[i].(type) {
+		case int64:
+			return float64(v)
+		case float64:
+			return v
+		}
+	}
+	return 0
+}
+
+// nextRowID returns the row ID after the last of rows.
+func nextRowID(rows []sqlite.Row) int64 {
+	if len(rows) == 0 {
+		return 1
+	}
+	return rows[len(rows)-1].ID + 1
+}
+
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
+// jsonText encodes v as JSON text, or NULL for an empty value.
+func jsonText(v any) any {
+	b, err := json.Marshal(v)
+	if err != nil || string(b) == "null" || string(b) == "[]" || string(b) == "{}" {
+		return nil
+	}
+	return string(b)
+}
@@ -0,0 +1,122 @@
+package report
+
+import (
+	"io"
+	"strings"
+	"unicode"
+)
+
+// table aligns columns by display width rather than byte or rune count, so
+// names with CJK characters or emoji line up in a terminal. Like the
+// tabwriter it replaces, the last column is not padded. A row with a single
+// cell, such as a group heading, spans the table and does not widen the first
+// column.
+type table struct {
+	rows [][]string
+}
+
+// columnGap is the number of spaces between columns.
+const columnGap = 2
+
+func (t *table) row(cells ...string) {
+	t.rows = append(t.rows, cells)
+}
+
+func (t *table) write(w io.Writer) error {
+	var widths []int
+	for _, r := range t.rows {
+		if len(r) == 1 {
+			continue
+		}
+		for i, c := range r {
+			if i == len(widths) {
+				widths = append(widths, 0)
+			}
+			widths[i] = max(widths[i], displayWidth(c))
+		}
+	}
+	var b strings.Builder
+	for _, r := range t.rows {
+		for i, c := range r {
+			b.WriteString(c)
+			if i < len(r)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-displayWidth(c)+columnGap))
+			}
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// padRight pads s with spaces to the given display width.
+func padRight(s string, width int) string {
+	if n := width - displayWidth(s); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+	return s
+}
+
+// displayWidth returns the number of terminal cells s occupies: two for
+// East Asian wide and emoji characters, none for combining marks and
+// zero-width joiners, one otherwise.
+func displayWidth(s string) int {
+	n := 0
+	for _, r := range s {
+		switch {
+		case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		case unicode.Is(wideRunes, r):
+			n += 2
+		default:
+			n++
+		}
+	}
+	return n
+}
+
+// wideRunes approximates the East Asian Wide and Fullwidth ranges of Unicode
+// Standard Annex #11 plus the emoji blocks terminals render double-width.
+var wideRunes = &unicode.RangeTable{
+	R16: []unicode.Range16{
+		{Lo: 0x1100, Hi: 0x115f, Stride: 1}, // Hangul Jamo initials
+		{Lo: 0x231a, Hi: 0x231b, Stride: 1}, // watch, hourglass
+		{Lo: 0x23e9, Hi: 0x23ec, Stride: 1}, // media controls
+		{Lo: 0x25fd, Hi: 0x25fe, Stride: 1}, // medium small squares
+		{Lo: 0x2614, Hi: 0x2615, Stride: 1}, // umbrella, hot beverage
+		{Lo: 0x26a1, Hi: 0x26a1, Stride: 1}, // high voltage
+		{Lo: 0x26bd, Hi: 0x26be, Stride: 1}, // soccer ball, baseball
+		{Lo: 0x2705, Hi: 0x2705, Stride: 1}, // check mark button
+		{Lo: 0x270a, Hi: 0x270b, Stride: 1}, // raised fists
+		{Lo: 0x274c, Hi: 0x274c, Stride: 1}, // cross mark
+		{Lo: 0x2753, Hi: 0x2755, Stride: 1}, // question and exclamation marks
+		{Lo: 0x2795, Hi: 0x2797, Stride: 1}, // heavy plus, minus, division
+		{Lo: 0x2b1b, Hi: 0x2b1c, Stride: 1}, // large squares
+		{Lo: 0x2b50, Hi: 0x2b50, Stride: 1}, // star
+		{Lo: 0x2e80, Hi: 0x303e, Stride: 1}, // CJK radicals, punctuation
+		{Lo: 0x3041, Hi: 0x33ff, Stride: 1}, // kana, CJK compatibility
+		{Lo: 0x3400, Hi: 0x4dbf, Stride: 1}, // CJK extension A
+		{Lo: 0x4e00, Hi: 0x9fff, Stride: 1}, // CJK unified ideographs
+		{Lo: 0xa000, Hi: 0xa4cf, Stride: 1}, // Yi
+		{Lo: 0xa960, Hi: 0xa97f, Stride: 1}, // Hangul Jamo extended A
+		{Lo: 0xac00, Hi: 0xd7a3, Stride: 1}, // Hangul syllables
+		{Lo: 0xf900, Hi: 0xfaff, Stride: 1}, // CJK compatibility ideographs
+		{Lo: 0xfe10, Hi: 0xfe19, Stride: 1}, // vertical forms
+		{Lo: 0xfe30, Hi: 0xfe6f, Stride: 1}, // CJK compatibility forms
+		{Lo: 0xff00, Hi: 0xff60, Stride: 1}, // fullwidth forms
+		{Lo: 0xffe0, Hi: 0xffe6, Stride: 1}, // fullwidth signs
+	},
+	R32: []unicode.Range32{
+		{Lo: 0x16fe0, Hi: 0x18aff, Stride: 1}, // Tangut
+		{Lo: 0x1b000, Hi: 0x1b2ff, Stride: 1}, // kana supplement
+		{Lo: 0x1f004, Hi: 0x1f004, Stride: 1}, // mahjong tile
+		{Lo: 0x1f0cf, Hi: 0x1f0cf, Stride: 1}, // joker
+		{Lo: 0x1f18e, Hi: 0x1f18e, Stride: 1}, // AB button
+		{Lo: 0x1f191, Hi: 0x1f19a, Stride: 1}, // squared words
+		{Lo: 0x1f200, Hi: 0x1f2ff, Stride: 1}, // enclosed ideographs
+		{Lo: 0x1f300, Hi: 0x1f64f, Stride: 1}, // pictographs, emoticons
+		{Lo: 0x1f680, Hi: 0x1f6ff, Stride: 1}, // transport and map symbols
+		{Lo: 0x1f7e0, Hi: 0x1f7eb, Stride: 1}, // colored circles and squares
+		{Lo: 0x1f900, Hi: 0x1faff, Stride: 1}, // supplemental pictographs
+		{Lo: 0x20000, Hi: 0x3fffd, Stride: 1}, // CJK extensions B and later
+	},
+}
@@ -0,0 +1,71 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// templateFuncs are available to report templates in addition to the
+// text/template builtins.
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+	"replace": func(old, replacement, s string) string {
+		return strings.ReplaceAll(s, old, replacement)
+	},
+	// money formats a dollar amount, e.g. {{money .Summary.TotalMonthlyWaste}}.
+	"money": func(v float64) string { return fmt.Sprintf("$%.2f", v) },
+	// bytes formats a size in bytes with a binary unit.
+	"bytes": formatBytes,
+	// toJSON embeds a value as compact JSON.
+	"toJSON": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	"shortDigest": shortDigest,
+}
+
+// ParseTemplate reads a Go text/template for TemplateReporter. The template
+// is parsed up front so a syntax error is reported before a scan starts.
+func ParseTemplate(path string) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read template: %w", err)
+	}
+	tmpl, err := template.New(filepath.Base(path)).Funcs(templateFuncs).Option("missingkey=error").Parse(string(text))
+	if err != nil {
+		return nil, fmt.Errorf("parse template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// Generate renders data through the template. The whole output is built
+// before anything is written, so a failing template leaves no partial
+// report behind on stdout.
+func (r *TemplateReporter) Generate(data Data) error {
+	var b strings.Builder
+	if err := r.Template.Execute(&b, data); err != nil {
+		return fmt.Errorf("execute template: %w", err)
+	}
+	_, err := r.Writer.Write([]byte(b.String()))
+	return err
+}
+
+// formatBytes formats n with a binary unit, e.g. 1.5 GiB.
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
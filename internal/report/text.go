@@ -3,14 +3,18 @@ package report
 import (
 	"fmt"
 	"io"
+	"math"
 	"sort"
+	"strconv"
 	"strings"
-	"text/tabwriter"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/analyzer"
+	"github.com/ppiankov/ecrspectre/internal/registry"
 )
 
 // Generate writes human-readable terminal output.
 func (r *TextReporter) Generate(data Data) error {
-	tw := tabwriter.NewWriter(r.Writer, 0, 4, 2, ' ', 0)
 	w := &errWriter{w: r.Writer}
 
 	w.println("ecrspectre — Container Registry Waste Report")
@@ -20,41 +24,286 @@ func (r *TextReporter) Generate(data Data) error {
 	if len(data.Findings) == 0 {
 		w.println("No waste found in container registries.")
 		w.println("")
+		if err := writeRepositoryDetail(w, data.Repository); err != nil {
+			return err
+		}
+		writeRecommendations(w, data.Recommendations)
 		writeTextSummary(w, data)
 		return w.err
 	}
 
-	w.printf("Found %d issues with estimated monthly waste of $%.2f\n\n",
-		data.Summary.TotalFindings, data.Summary.TotalMonthlyWaste)
-
-	tw2 := &errWriter{w: tw}
-	tw2.printf("SEVERITY\tTYPE\tRESOURCE\tREGION\tWASTE/MO\tMESSAGE\n")
-	tw2.printf("--------\t----\t--------\t------\t--------\t-------\n")
+	period := costPeriod(data.Summary)
+	w.printf("Found %d issues with estimated %s waste of $%.2f\n\n",
+		data.Summary.TotalFindings, period.Adjective(), periodTotal(data.Summary))
 
-	for _, f := range data.Findings {
-		name := f.ResourceID
-		if f.ResourceName != "" {
-			name = f.ResourceName
+	if w.err != nil {
+		return w.err
+	}
+	var t table
+	if data.Summary.GroupBy == registry.GroupByRepo && len(data.Summary.ByGroup) > 0 {
+		repoFindingRows(&t, data, period)
+	} else {
+		t.row("SEVERITY", "SCORE", "TYPE", "RESOURCE", "REGION", "WASTE"+strings.ToUpper(period.Suffix()), "MESSAGE")
+		t.row("--------", "-----", "----", "--------", "------", "--------", "-------")
+		for _, f := range data.Findings {
+			t.row(findingCells("", f)...)
 		}
-		tw2.printf("%s\t%s\t%s\t%s\t$%.2f\t%s\n",
-			f.Severity, f.ResourceType, name, f.Region, f.EstimatedMonthlyWaste, f.Message)
 	}
-	if err := tw.Flush(); err != nil {
+	if err := t.write(r.Writer); err != nil {
 		return err
 	}
 
 	w.println("")
+	if err := writeRepositoryDetail(w, data.Repository); err != nil {
+		return err
+	}
+	writeRecommendations(w, data.Recommendations)
 	writeTextSummary(w, data)
 	return w.err
 }
 
+// findingCells returns the finding table columns of f, the first indented
+// by indent.
+func findingCells(indent string, f registry.Finding) []string {
+	name := f.ResourceID
+	if f.ResourceName != "" {
+		name = f.ResourceName
+	}
+	return []string{indent + string(f.Severity), strconv.FormatFloat(f.Score, 'f', 1, 64), string(f.ResourceType), name, f.Region, fmt.Sprintf("$%.2f", registry.PeriodWaste(f)), f.Message}
+}
+
+// repoFindingRows nests the findings under a subtotal heading for each
+// repository, most expensive first, for --group-by repo. Subtotals cover
+// every finding of the repository, including those cut by --top.
+func repoFindingRows(t *table, data Data, period registry.CostPeriod) {
+	byRepo := make(map[string][]registry.Finding)
+	for _, f := range data.Findings {
+		repo := registry.GroupKey(f, registry.GroupByRepo)
+		byRepo[repo] = append(byRepo[repo], f)
+	}
+	t.row("  SEVERITY", "SCORE", "TYPE", "RESOURCE", "REGION", "WASTE"+strings.ToUpper(period.Suffix()), "MESSAGE")
+	t.row("  --------", "-----", "----", "--------", "------", "--------", "-------")
+	for _, repo := range sortedGroups(data.Summary.ByGroup) {
+		findings := byRepo[repo]
+		if len(findings) == 0 {
+			continue
+		}
+		s := data.Summary.ByGroup[repo]
+		t.row("")
+		t.row(fmt.Sprintf("%s — %d findings, $%.2f%s", repo, s.TotalFindings, groupWaste(s, period), period.Suffix()))
+		for _, f := range findings {
+			t.row(findingCells("  ", f)...)
+		}
+	}
+}
+
+// sortedGroups returns the group names most expensive first, the way a
+// chargeback report is read.
+func sortedGroups(groups map[string]analyzer.GroupSummary) []string {
+	names := make([]string, 0, len(groups))
+	for g := range groups {
+		names = append(names, g)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		gi, gj := groups[names[i]], groups[names[j]]
+		if gi.TotalMonthlyWaste != gj.TotalMonthlyWaste {
+			return gi.TotalMonthlyWaste > gj.TotalMonthlyWaste
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// groupWaste returns the waste of a group in the report's cost period.
+func groupWaste(s analyzer.GroupSummary, period registry.CostPeriod) float64 {
+	if period != registry.CostPeriodMonth {
+		return s.TotalPeriodWaste
+	}
+	return s.TotalMonthlyWaste
+}
+
+// writeRepositoryDetail renders the per-image breakdown of a single-repository scan.
+func writeRepositoryDetail(w *errWriter, d *registry.RepositoryDetail) error {
+	if d == nil {
+		return nil
+	}
+	w.printf("Repository %s (%s)\n", d.Name, d.Region)
+	w.println(strings.Repeat("-", 11+displayWidth(d.Name)+displayWidth(d.Region)+3))
+	w.printf("Images: %d, total %.0f MB, $%.2f/mo\n", d.ImageCount, float64(d.TotalSizeBytes)/(1024*1024), d.MonthlyCost)
+	switch {
+	case d.LifecycleSimulated:
+		w.printf("Lifecycle policy would expire %d images, saving $%.2f/mo\n", d.ExpiringImages, d.ExpiringMonthlySavings)
+	case d.HasLifecyclePolicy:
+		w.println("Lifecycle policy present (not simulated)")
+	default:
+		w.println("No lifecycle policy")
+	}
+	w.println("")
+	if w.err != nil {
+		return w.err
+	}
+
+	var t table
+	t.row("DIGEST", "TAGS", "SIZE MB", "PUSHED", "LAST PULL", "COST/MO", "LIFECYCLE", "FINDINGS")
+	t.row("------", "----", "-------", "------", "---------", "-------", "---------", "--------")
+	for _, img := range d.Images {
+		lifecycle := "keep"
+		if img.ExpiredByRule > 0 {
+			lifecycle = fmt.Sprintf("expire (rule %d)", img.ExpiredByRule)
+		} else if !d.LifecycleSimulated {
+			lifecycle = "-"
+		}
+		findings := make([]string, 0, len(img.Findings))
+		for _, id := range img.Findings {
+			findings = append(findings, string(id))
+		}
+		t.row(shortDigest(img.Digest), orDash(tagsLabel(img)), fmt.Sprintf("%.0f", float64(img.SizeBytes)/(1024*1024)),
+			formatDate(img.PushedAt), formatDate(img.LastPulledAt), fmt.Sprintf("$%.2f", img.MonthlyCost), lifecycle, orDash(strings.Join(findings, ",")))
+	}
+	if err := t.write(w.w); err != nil {
+		return err
+	}
+	w.println("")
+	return nil
+}
+
+func shortDigest(digest string) string {
+	if len(digest) > 19 {
+		return digest[:19]
+	}
+	return digest
+}
+
+func formatDate(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02")
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// ageWindow describes the --older-than / --newer-than filter, or "" without one.
+func ageWindow(c ReportConfig) string {
+	switch {
+	case c.OlderThan != "" && c.NewerThan != "":
+		return fmt.Sprintf("between %s and %s ago", c.OlderThan, c.NewerThan)
+	case c.OlderThan != "":
+		return fmt.Sprintf("more than %s ago", c.OlderThan)
+	case c.NewerThan != "":
+		return fmt.Sprintf("within the last %s", c.NewerThan)
+	}
+	return ""
+}
+
+// expiredSuppressions describes the expired suppressions that matched
+// findings, which are reported again until the entry is renewed or removed.
+func expiredSuppressions(statuses []analyzer.SuppressionStatus) []string {
+	var out []string
+	for _, s := range statuses {
+		if s.Expired && s.Matched > 0 {
+			out = append(out, fmt.Sprintf("%s %s (%s, expired %s)", s.ID, s.Resource, s.Owner, s.Expires))
+		}
+	}
+	return out
+}
+
+// costPeriod returns the cost period the summary was analyzed with.
+func costPeriod(s analyzer.Summary) registry.CostPeriod {
+	if s.CostPeriod == "" {
+		return registry.CostPeriodMonth
+	}
+	return s.CostPeriod
+}
+
+// periodTotal returns the total waste in the summary's cost period.
+func periodTotal(s analyzer.Summary) float64 {
+	if s.CostPeriod == "" {
+		return s.TotalMonthlyWaste
+	}
+	return s.TotalPeriodWaste
+}
+
+// writeRecommendations lists the retention recommendations by repository.
+func writeRecommendations(w *errWriter, recs []registry.RetentionRecommendation) {
+	if len(recs) == 0 {
+		return
+	}
+	w.println("Retention recommendations")
+	w.println("-------------------------")
+	for _, r := range recs {
+		w.printf("  %s (%s): %s\n", r.Repository, r.Region, r.Message)
+	}
+	w.println("")
+}
+
 func writeTextSummary(w *errWriter, data Data) {
 	w.println("Summary")
 	w.println("-------")
 	w.printf("Resources scanned:       %d\n", data.Summary.TotalResourcesScanned)
 	w.printf("Repositories scanned:    %d\n", data.Summary.RepositoriesScanned)
-	w.printf("Total findings:          %d\n", data.Summary.TotalFindings)
-	w.printf("Estimated monthly waste: $%.2f\n", data.Summary.TotalMonthlyWaste)
+	var findingsTrend, wasteTrend string
+	if data.Trend != nil {
+		findingsTrend = trendSuffix(data.Trend.Findings)
+		wasteTrend = trendSuffix(data.Trend.MonthlyWaste)
+	}
+	w.printf("Total findings:          %d%s\n", data.Summary.TotalFindings, findingsTrend)
+	if data.Summary.Top > 0 {
+		w.printf("Showing:                 top %d by %s; totals cover all findings\n", data.Summary.Top, data.Summary.Sort)
+	}
+	w.printf("Estimated monthly waste: $%.2f%s\n", data.Summary.TotalMonthlyWaste, wasteTrend)
+	period := costPeriod(data.Summary)
+	if period != registry.CostPeriodMonth {
+		w.printf("%s $%.2f\n", padRight("Estimated "+period.Adjective()+" waste:", 24), data.Summary.TotalPeriodWaste)
+	}
+	if p := data.Summary.Projection; p != nil {
+		sign := "+"
+		if p.MonthlyGrowth < 0 {
+			sign = "-"
+		}
+		w.printf("Projected next year:     ~$%.0f at %s$%.2f/mo per month (%d scans since %s)\n",
+			p.NextYearWaste, sign, math.Abs(p.MonthlyGrowth), p.Scans, p.Since.Format(time.DateOnly))
+	}
+	if b := data.Summary.Baseline; b != nil {
+		w.printf("Since baseline:          %d new ($%.2f/mo), %d unchanged, %d resolved ($%.2f/mo)\n",
+			b.New, b.NewMonthlyWaste, b.Unchanged, b.Resolved, b.ResolvedMonthlyWaste)
+	}
+	if overlap := data.Summary.OverlappingWaste; overlap > 0 {
+		w.printf("Counted once:            $%.2f/mo of finding waste repeats another finding on the same resource\n", overlap)
+	}
+	if n := data.Summary.FilteredFindingsCount; n > 0 {
+		w.printf("Below min cost:          %d findings under $%.2f totaling $%.2f/mo\n", n, data.Config.MinMonthlyCost, data.Summary.FilteredWasteTotal)
+	}
+	if co2e := data.Summary.CO2eKgPerMonth; co2e > 0 {
+		w.printf("Carbon estimate:         %.2f kg CO2e/mo from %.1f GB of wasted storage\n", co2e, data.Summary.WastedGB)
+	}
+	if data.Summary.InUseFindings > 0 {
+		w.printf("On deployed images:      %d findings ($%.2f/mo)\n", data.Summary.InUseFindings, data.Summary.InUseMonthlyWaste)
+	}
+	if window := ageWindow(data.Config); window != "" {
+		w.printf("Age window:              pushed %s (%d findings outside)\n", window, data.Summary.AgeFilteredFindings)
+	}
+	if n := data.Summary.SelfResolvingFindings; n > 0 {
+		w.printf("Self-resolving:          %d findings ($%.2f/mo) expire by lifecycle policy within %d days\n", n, data.Summary.SelfResolvingMonthlyWaste, data.Config.SelfResolvingDays)
+	}
+	if n := data.Summary.SuppressedFindings; n > 0 {
+		w.printf("Suppressed:              %d findings ($%.2f/mo)\n", n, data.Summary.SuppressedMonthlyWaste)
+	}
+	if n := data.Summary.WindowedFindings; n > 0 {
+		w.printf("In migration window:     %d findings (%s), not failing the scan\n", n, strings.Join(data.Summary.MigrationWindows, ", "))
+	}
+	if expired := expiredSuppressions(data.Suppressions); len(expired) > 0 {
+		w.printf("Expired suppressions:    %s\n", strings.Join(expired, ", "))
+	}
+	if c := data.Summary.Coverage; c.Truncated {
+		w.printf("Coverage:                %.1f%% (%d of %d repositories, scan timed out)\n",
+			c.Percent, c.RepositoriesCompleted, c.RepositoriesPlanned)
+	}
 
 	if len(data.Summary.BySeverity) > 0 {
 		parts := formatMapSorted(data.Summary.BySeverity)
@@ -64,6 +313,31 @@ func writeTextSummary(w *errWriter, data Data) {
 		parts := formatMapSorted(data.Summary.ByResourceType)
 		w.printf("By resource type:        %s\n", strings.Join(parts, ", "))
 	}
+	if len(data.Summary.ByProject) > 0 {
+		projects := make([]string, 0, len(data.Summary.ByProject))
+		for p := range data.Summary.ByProject {
+			projects = append(projects, p)
+		}
+		sort.Strings(projects)
+		w.println("By project:")
+		for _, p := range projects {
+			s := data.Summary.ByProject[p]
+			waste := s.TotalMonthlyWaste
+			if period != registry.CostPeriodMonth {
+				waste = s.TotalPeriodWaste
+			}
+			w.printf("  %s %d findings, $%.2f%s, %d repositories\n", padRight(p, 22), s.TotalFindings, waste, period.Suffix(), s.RepositoriesScanned)
+		}
+	}
+
+	// --group-by repo shows its subtotals with the findings instead.
+	if len(data.Summary.ByGroup) > 0 && data.Summary.GroupBy != registry.GroupByRepo {
+		w.printf("By %s:\n", data.Summary.GroupBy)
+		for _, g := range sortedGroups(data.Summary.ByGroup) {
+			s := data.Summary.ByGroup[g]
+			w.printf("  %s %d findings, $%.2f%s\n", padRight(g, 22), s.TotalFindings, groupWaste(s, period), period.Suffix())
+		}
+	}
 
 	if len(data.Errors) > 0 {
 		w.printf("\nWarnings (%d):\n", len(data.Errors))
@@ -73,6 +347,15 @@ func writeTextSummary(w *errWriter, data Data) {
 	}
 }
 
+// trendSuffix renders a sparkline of recent scans for a summary line.
+func trendSuffix(values []float64) string {
+	spark := Sparkline(values)
+	if spark == "" {
+		return ""
+	}
+	return fmt.Sprintf("  %s (last %d scans)", spark, len(values))
+}
+
 // errWriter wraps an io.Writer and captures the first error.
 type errWriter struct {
 	w   io.Writer
@@ -106,3 +389,14 @@ func formatMapSorted(m map[string]int) []string {
 	}
 	return parts
 }
+
+// tagsLabel shows an image's canonical tag and how many aliases it has.
+func tagsLabel(img registry.ImageDetail) string {
+	if img.CanonicalTag == "" {
+		return strings.Join(img.Tags, ",")
+	}
+	if n := len(img.Tags) - 1; n > 0 {
+		return fmt.Sprintf("%s (+%d)", img.CanonicalTag, n)
+	}
+	return img.CanonicalTag
+}
@@ -2,6 +2,8 @@ package report
 
 import (
 	"io"
+	"net/http"
+	"text/template"
 	"time"
 
 	"github.com/ppiankov/ecrspectre/internal/analyzer"
@@ -13,16 +15,62 @@ type Reporter interface {
 	Generate(data Data) error
 }
 
+// MultiReporter generates the same data with several reporters in turn, so
+// one scan can produce e.g. a JSON report for CI and a text report to read.
+// It stops at the first error.
+type MultiReporter []Reporter
+
+// Generate implements Reporter.
+func (m MultiReporter) Generate(data Data) error {
+	for _, r := range m {
+		if err := r.Generate(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Data holds all information needed to generate a report.
 type Data struct {
-	Tool      string             `json:"tool"`
-	Version   string             `json:"version"`
-	Timestamp time.Time          `json:"timestamp"`
-	Target    Target             `json:"target"`
-	Config    ReportConfig       `json:"config"`
-	Findings  []registry.Finding `json:"findings"`
-	Summary   analyzer.Summary   `json:"summary"`
-	Errors    []string           `json:"errors,omitempty"`
+	Tool      string    `json:"tool"`
+	Version   string    `json:"version"`
+	Timestamp time.Time `json:"timestamp"`
+	// RunID is the unique ID of the scan run, shared with its logs, progress
+	// events, history record and attestation.
+	RunID    string             `json:"run_id,omitempty"`
+	Target   Target             `json:"target"`
+	Config   ReportConfig       `json:"config"`
+	Findings []registry.Finding `json:"findings"`
+	Summary  analyzer.Summary   `json:"summary"`
+	Errors   []string           `json:"errors,omitempty"`
+	// Repository is the per-image breakdown of a single-repository scan.
+	Repository *registry.RepositoryDetail `json:"repository,omitempty"`
+	// FeaturesUsed lists the provider, format, checks and flag names used for
+	// the scan (never flag values) so adoption can be aggregated from reports.
+	FeaturesUsed []string `json:"features_used,omitempty"`
+	// ScanStats records per-region and per-repository scan durations.
+	ScanStats *registry.ScanStats `json:"scan_stats,omitempty"`
+	// Suppressions is the audit trail of the suppression file: every entry
+	// with its owner, reason, expiry and match count.
+	Suppressions []analyzer.SuppressionStatus `json:"suppressions,omitempty"`
+	// Recommendations are tag-based retention rules learned from which
+	// images are still pulled.
+	Recommendations []registry.RetentionRecommendation `json:"recommendations,omitempty"`
+	// Usage is the storage of each scanned repository. It is exported only
+	// by the dataset format.
+	Usage map[string]registry.RepoUsage `json:"-"`
+	// Duration is the wall-clock time of the scan. It is exported only by
+	// the prometheus format.
+	Duration time.Duration `json:"-"`
+	// Trend holds totals of recent scans from the scan history, oldest first
+	// and ending with this scan. It is shown only in the text report.
+	Trend *Trend `json:"-"`
+}
+
+// Trend is the series of summary totals over recent scans.
+type Trend struct {
+	MonthlyWaste []float64
+	Findings     []float64
 }
 
 // Target identifies the registry being audited.
@@ -35,9 +83,17 @@ type Target struct {
 type ReportConfig struct {
 	Provider       string   `json:"provider"`
 	Regions        []string `json:"regions"`
+	Projects       []string `json:"projects,omitempty"`
 	StaleDays      int      `json:"stale_days"`
 	MaxSizeMB      int      `json:"max_size_mb"`
 	MinMonthlyCost float64  `json:"min_monthly_cost"`
+	// OlderThan and NewerThan are the --older-than / --newer-than values
+	// the findings were filtered by.
+	OlderThan string `json:"older_than,omitempty"`
+	NewerThan string `json:"newer_than,omitempty"`
+	// SelfResolvingDays is the lifecycle expiry horizon of self-resolving
+	// findings.
+	SelfResolvingDays int `json:"self_resolving_days,omitempty"`
 }
 
 // TextReporter generates human-readable terminal output.
@@ -50,12 +106,90 @@ type JSONReporter struct {
 	Writer io.Writer
 }
 
+// YAMLReporter generates the spectre/v1 envelope as YAML.
+type YAMLReporter struct {
+	Writer io.Writer
+}
+
 // SpectreHubReporter generates SpectreHub envelope JSON output.
 type SpectreHubReporter struct {
 	Writer io.Writer
 }
 
+// CSVReporter generates one CSV row per finding for spreadsheets and BI
+// tools.
+type CSVReporter struct {
+	Writer io.Writer
+}
+
+// JUnitReporter generates JUnit XML with each finding as a failed test case.
+type JUnitReporter struct {
+	Writer io.Writer
+}
+
+// MarkdownReporter generates a compact Markdown summary for PR and issue
+// comments.
+type MarkdownReporter struct {
+	Writer io.Writer
+}
+
+// GitHubReporter writes GitHub Actions workflow annotations to Writer and
+// appends a Markdown job summary to SummaryPath when it is set (normally
+// $GITHUB_STEP_SUMMARY).
+type GitHubReporter struct {
+	Writer      io.Writer
+	SummaryPath string
+}
+
+// SlackReporter posts a scan summary with the costliest findings to a Slack
+// incoming webhook.
+type SlackReporter struct {
+	WebhookURL string
+	Client     *http.Client
+}
+
+// CloudEventsReporter posts scan.completed and threshold.breached
+// CloudEvents to a webhook.
+type CloudEventsReporter struct {
+	URL    string
+	Client *http.Client
+	// WasteThreshold, when positive, is the total monthly waste that
+	// triggers a threshold.breached event.
+	WasteThreshold float64
+	// SeverityThreshold, when set, triggers a threshold.breached event for
+	// findings of at least this severity.
+	SeverityThreshold registry.Severity
+}
+
+// PrometheusReporter generates Prometheus text exposition format metrics.
+type PrometheusReporter struct {
+	Writer io.Writer
+}
+
 // SARIFReporter generates SARIF v2.1.0 output.
 type SARIFReporter struct {
 	Writer io.Writer
 }
+
+// OCSFReporter generates OCSF Detection Finding events as JSON Lines.
+type OCSFReporter struct {
+	Writer io.Writer
+}
+
+// TemplateReporter renders Data through a user-supplied Go text/template
+// parsed by ParseTemplate.
+type TemplateReporter struct {
+	Writer   io.Writer
+	Template *template.Template
+}
+
+// SQLiteReporter adds each scan and its findings to a SQLite database file.
+type SQLiteReporter struct {
+	Path string
+}
+
+// DatasetReporter writes findings and repository inventory as a
+// Hive-partitioned CSV dataset for BigQuery and Athena.
+type DatasetReporter struct {
+	Store ObjectStore
+}
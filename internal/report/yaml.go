@@ -0,0 +1,42 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Generate writes the spectre/v1 envelope as YAML. The document mirrors the
+// JSON report key for key: it is built from the JSON encoding, so field
+// names and order match and map keys are sorted.
+func (r *YAMLReporter) Generate(data Data) error {
+	raw, err := json.Marshal(jsonEnvelope{Schema: "spectre/v1", Data: data})
+	if err != nil {
+		return fmt.Errorf("encode YAML report: %w", err)
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("encode YAML report: %w", err)
+	}
+	blockStyle(&doc)
+
+	enc := yaml.NewEncoder(r.Writer)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("encode YAML report: %w", err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("encode YAML report: %w", err)
+	}
+	return nil
+}
+
+// blockStyle clears the flow and quoting styles the JSON source left on the
+// node tree, so the encoder emits block YAML and quotes only where needed.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
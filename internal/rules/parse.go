@@ -0,0 +1,633 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// kind is the static type of an expression.
+type kind int
+
+const (
+	kBool kind = iota
+	kInt
+	kDouble
+	kString
+	kList // list of strings
+)
+
+func (k kind) String() string {
+	return [...]string{"bool", "int", "double", "string", "list(string)"}[k]
+}
+
+func (k kind) numeric() bool { return k == kInt || k == kDouble }
+
+// node is a type-checked expression. Evaluation cannot fail: every operand
+// type is known when the expression is compiled.
+type node struct {
+	kind kind
+	eval func(a *activation) any
+}
+
+// activation holds the values of the variables while evaluating.
+type activation struct {
+	vars map[string]any
+}
+
+type tokKind int
+
+const (
+	tEOF tokKind = iota
+	tIdent
+	tInt
+	tFloat
+	tString
+	tOp
+)
+
+type token struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "(", ")", "[", "]", ",", "."}
+
+func lex(src string) ([]token, error) {
+	var toks []token
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			j := i
+			for j < len(src) && (src[j] == '_' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			toks = append(toks, token{tIdent, src[i:j], i})
+			i = j
+		case unicode.IsDigit(c):
+			j, float := i, false
+			for j < len(src) && (unicode.IsDigit(rune(src[j])) || src[j] == '.') {
+				if src[j] == '.' {
+					// "1.x" is a method call on an int, not a float.
+					if j+1 >= len(src) || !unicode.IsDigit(rune(src[j+1])) || float {
+						break
+					}
+					float = true
+				}
+				j++
+			}
+			if float {
+				toks = append(toks, token{tFloat, src[i:j], i})
+			} else {
+				toks = append(toks, token{tInt, src[i:j], i})
+			}
+			i = j
+		case c == '"' || c == '\'':
+			var sb strings.Builder
+			j := i + 1
+			for ; j < len(src) && src[j] != src[i]; j++ {
+				if src[j] == '\\' && j+1 < len(src) {
+					j++
+					switch src[j] {
+					case 'n':
+						sb.WriteByte('\n')
+					case 't':
+						sb.WriteByte('\t')
+					default:
+						sb.WriteByte(src[j])
+					}
+					continue
+				}
+				sb.WriteByte(src[j])
+			}
+			if j >= len(src) {
+				return nil, fmt.Errorf("at position %d: unterminated string", i)
+			}
+			toks = append(toks, token{tString, sb.String(), i})
+			i = j + 1
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[i:], op) {
+					toks = append(toks, token{tOp, op, i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("at position %d: unexpected character %q", i, c)
+			}
+		}
+	}
+	return append(toks, token{tEOF, "", len(src)}), nil
+}
+
+type parser struct {
+	toks  []token
+	pos   int
+	scope map[string]kind
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.kind != tEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) accept(op string) bool {
+	if t := p.peek(); t.kind == tOp && t.text == op {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.accept(op) {
+		t := p.peek()
+		return fmt.Errorf("at position %d: expected %q, found %s", t.pos, op, describe(t))
+	}
+	return nil
+}
+
+func describe(t token) string {
+	if t.kind == tEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+func (p *parser) errorf(t token, format string, args ...any) error {
+	return fmt.Errorf("at position %d: %s", t.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept("||") {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return node{}, err
+		}
+		if left.kind != kBool || right.kind != kBool {
+			return node{}, p.errorf(t, "|| needs bool operands, got %s and %s", left.kind, right.kind)
+		}
+		l, r := left.eval, right.eval
+		left = node{kBool, func(a *activation) any { return l(a).(bool) || r(a).(bool) }}
+	}
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseRelation()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept("&&") {
+			return left, nil
+		}
+		right, err := p.parseRelation()
+		if err != nil {
+			return node{}, err
+		}
+		if left.kind != kBool || right.kind != kBool {
+			return node{}, p.errorf(t, "&& needs bool operands, got %s and %s", left.kind, right.kind)
+		}
+		l, r := left.eval, right.eval
+		left = node{kBool, func(a *activation) any { return l(a).(bool) && r(a).(bool) }}
+	}
+}
+
+func (p *parser) parseRelation() (node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return node{}, err
+	}
+	t := p.peek()
+	op := t.text
+	switch {
+	case t.kind == tIdent && op == "in":
+	case t.kind == tOp && (op == "==" || op == "!=" || op == "<" || op == "<=" || op == ">" || op == ">="):
+	default:
+		return left, nil
+	}
+	p.next()
+	right, err := p.parseAdditive()
+	if err != nil {
+		return node{}, err
+	}
+	l, r := left.eval, right.eval
+
+	if op == "in" {
+		if left.kind != kString || right.kind != kList {
+			return node{}, p.errorf(t, "in needs a string and a list, got %s and %s", left.kind, right.kind)
+		}
+		return node{kBool, func(a *activation) any {
+			s := l(a).(string)
+			for _, v := range r(a).([]string) {
+				if v == s {
+					return true
+				}
+			}
+			return false
+		}}, nil
+	}
+
+	switch {
+	case left.kind.numeric() && right.kind.numeric():
+		return node{kBool, func(a *activation) any { return compare(op, toFloat(l(a)), toFloat(r(a))) }}, nil
+	case left.kind == kString && right.kind == kString:
+		return node{kBool, func(a *activation) any { return compare(op, l(a).(string), r(a).(string)) }}, nil
+	case left.kind == kBool && right.kind == kBool && (op == "==" || op == "!="):
+		return node{kBool, func(a *activation) any { return (l(a).(bool) == r(a).(bool)) == (op == "==") }}, nil
+	}
+	return node{}, p.errorf(t, "cannot compare %s %s %s", left.kind, op, right.kind)
+}
+
+func compare[T float64 | string](op string, a, b T) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	}
+	return a >= b
+}
+
+func toFloat(v any) float64 {
+	if i, ok := v.(int64); ok {
+		return float64(i)
+	}
+	return v.(float64)
+}
+
+func (p *parser) parseAdditive() (node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if t.kind != tOp || (t.text != "+" && t.text != "-") {
+			return left, nil
+		}
+		p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return node{}, err
+		}
+		if t.text == "+" && left.kind == kString && right.kind == kString {
+			l, r := left.eval, right.eval
+			left = node{kString, func(a *activation) any { return l(a).(string) + r(a).(string) }}
+			continue
+		}
+		if left, err = arithmetic(p, t, left, right); err != nil {
+			return node{}, err
+		}
+	}
+}
+
+func (p *parser) parseMultiplicative() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return node{}, err
+	}
+	for {
+		t := p.peek()
+		if !p.accept("*") {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return node{}, err
+		}
+		if left, err = arithmetic(p, t, left, right); err != nil {
+			return node{}, err
+		}
+	}
+}
+
+// arithmetic builds +, - or * over numbers. Two ints give an int; any
+// double makes the result a double.
+func arithmetic(p *parser, t token, left, right node) (node, error) {
+	if !left.kind.numeric() || !right.kind.numeric() {
+		return node{}, p.errorf(t, "%s needs numbers, got %s and %s", t.text, left.kind, right.kind)
+	}
+	l, r := left.eval, right.eval
+	if left.kind == kInt && right.kind == kInt {
+		return node{kInt, func(a *activation) any {
+			x, y := l(a).(int64), r(a).(int64)
+			switch t.text {
+			case "+":
+				return x + y
+			case "-":
+				return x - y
+			}
+			return x * y
+		}}, nil
+	}
+	return node{kDouble, func(a *activation) any {
+		x, y := toFloat(l(a)), toFloat(r(a))
+		switch t.text {
+		case "+":
+			return x + y
+		case "-":
+			return x - y
+		}
+		return x * y
+	}}, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	t := p.peek()
+	switch {
+	case p.accept("!"):
+		operand, err := p.parseUnary()
+		if err != nil {
+			return node{}, err
+		}
+		if operand.kind != kBool {
+			return node{}, p.errorf(t, "! needs a bool, got %s", operand.kind)
+		}
+		e := operand.eval
+		return node{kBool, func(a *activation) any { return !e(a).(bool) }}, nil
+	case p.accept("-"):
+		operand, err := p.parseUnary()
+		if err != nil {
+			return node{}, err
+		}
+		e := operand.eval
+		switch operand.kind {
+		case kInt:
+			return node{kInt, func(a *activation) any { return -e(a).(int64) }}, nil
+		case kDouble:
+			return node{kDouble, func(a *activation) any { return -e(a).(float64) }}, nil
+		}
+		return node{}, p.errorf(t, "- needs a number, got %s", operand.kind)
+	}
+	return p.parseMember()
+}
+
+func (p *parser) parseMember() (node, error) {
+	target, err := p.parsePrimary()
+	if err != nil {
+		return node{}, err
+	}
+	for p.accept(".") {
+		name := p.next()
+		if name.kind != tIdent {
+			return node{}, p.errorf(name, "expected a method name, found %s", describe(name))
+		}
+		if err := p.expect("("); err != nil {
+			return node{}, err
+		}
+		if target, err = p.method(name, target); err != nil {
+			return node{}, err
+		}
+	}
+	return target, nil
+}
+
+// method parses the arguments and closing parenthesis of target.name(...).
+func (p *parser) method(name token, target node) (node, error) {
+	t := target.eval
+	if target.kind == kList && (name.text == "exists" || name.text == "all") {
+		return p.macro(name, t)
+	}
+
+	if target.kind == kString && name.text == "matches" {
+		// The pattern must be a literal so it is validated at compile time.
+		pattern := p.next()
+		if pattern.kind != tString {
+			return node{}, p.errorf(pattern, "matches needs a string literal pattern")
+		}
+		re, err := regexp.Compile(pattern.text)
+		if err != nil {
+			return node{}, p.errorf(pattern, "invalid pattern: %v", err)
+		}
+		if err := p.expect(")"); err != nil {
+			return node{}, err
+		}
+		return node{kBool, func(a *activation) any { return re.MatchString(t(a).(string)) }}, nil
+	}
+
+	args, err := p.arguments()
+	if err != nil {
+		return node{}, err
+	}
+	if name.text == "size" && len(args) == 0 {
+		return sizeOf(p, name, target)
+	}
+	if target.kind != kString || len(args) != 1 || args[0].kind != kString {
+		return node{}, p.errorf(name, "unknown method %s.%s with %d argument(s)", target.kind, name.text, len(args))
+	}
+	arg := args[0].eval
+	switch name.text {
+	case "startsWith":
+		return node{kBool, func(a *activation) any { return strings.HasPrefix(t(a).(string), arg(a).(string)) }}, nil
+	case "endsWith":
+		return node{kBool, func(a *activation) any { return strings.HasSuffix(t(a).(string), arg(a).(string)) }}, nil
+	case "contains":
+		return node{kBool, func(a *activation) any { return strings.Contains(t(a).(string), arg(a).(string)) }}, nil
+	}
+	return node{}, p.errorf(name, "unknown method string.%s", name.text)
+}
+
+// macro parses the rest of list.exists(x, pred) or list.all(x, pred).
+func (p *parser) macro(name token, list func(*activation) any) (node, error) {
+	v := p.next()
+	if v.kind != tIdent {
+		return node{}, p.errorf(v, "%s needs a variable name, found %s", name.text, describe(v))
+	}
+	if err := p.expect(","); err != nil {
+		return node{}, err
+	}
+	outer, shadowed := p.scope[v.text]
+	p.scope[v.text] = kString
+	pred, err := p.parseOr()
+	if shadowed {
+		p.scope[v.text] = outer
+	} else {
+		delete(p.scope, v.text)
+	}
+	if err != nil {
+		return node{}, err
+	}
+	if pred.kind != kBool {
+		return node{}, p.errorf(name, "%s predicate must be bool, got %s", name.text, pred.kind)
+	}
+	if err := p.expect(")"); err != nil {
+		return node{}, err
+	}
+
+	want := name.text == "exists"
+	return node{kBool, func(a *activation) any {
+		prev, had := a.vars[v.text]
+		defer func() {
+			if had {
+				a.vars[v.text] = prev
+			} else {
+				delete(a.vars, v.text)
+			}
+		}()
+		for _, elem := range list(a).([]string) {
+			a.vars[v.text] = elem
+			if pred.eval(a).(bool) == want {
+				return want
+			}
+		}
+		return !want
+	}}, nil
+}
+
+// arguments parses a comma-separated argument list and the closing parenthesis.
+func (p *parser) arguments() ([]node, error) {
+	var args []node
+	if p.accept(")") {
+		return args, nil
+	}
+	for {
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(")") {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func sizeOf(p *parser, t token, target node) (node, error) {
+	e := target.eval
+	switch target.kind {
+	case kString:
+		return node{kInt, func(a *activation) any { return int64(len([]rune(e(a).(string)))) }}, nil
+	case kList:
+		return node{kInt, func(a *activation) any { return int64(len(e(a).([]string))) }}, nil
+	}
+	return node{}, p.errorf(t, "size needs a string or list, got %s", target.kind)
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tInt:
+		n, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return node{}, p.errorf(t, "invalid int %s", t.text)
+		}
+		return node{kInt, func(*activation) any { return n }}, nil
+	case tFloat:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return node{}, p.errorf(t, "invalid double %s", t.text)
+		}
+		return node{kDouble, func(*activation) any { return f }}, nil
+	case tString:
+		s := t.text
+		return node{kString, func(*activation) any { return s }}, nil
+	case tIdent:
+		switch t.text {
+		case "true", "false":
+			b := t.text == "true"
+			return node{kBool, func(*activation) any { return b }}, nil
+		case "size", "int", "double", "string":
+			if p.accept("(") {
+				return p.function(t)
+			}
+		}
+		k, ok := p.scope[t.text]
+		if !ok {
+			return node{}, p.errorf(t, "undeclared reference %q", t.text)
+		}
+		name := t.text
+		return node{k, func(a *activation) any { return a.vars[name] }}, nil
+	case tOp:
+		switch t.text {
+		case "(":
+			n, err := p.parseOr()
+			if err != nil {
+				return node{}, err
+			}
+			return n, p.expect(")")
+		case "[":
+			return p.list()
+		}
+	}
+	return node{}, p.errorf(t, "unexpected %s", describe(t))
+}
+
+// list parses a list literal; only lists of strings are supported.
+func (p *parser) list() (node, error) {
+	var elems []string
+	for !p.accept("]") {
+		if len(elems) > 0 {
+			if err := p.expect(","); err != nil {
+				return node{}, err
+			}
+		}
+		t := p.next()
+		if t.kind != tString {
+			return node{}, p.errorf(t, "list elements must be string literals, found %s", describe(t))
+		}
+		elems = append(elems, t.text)
+	}
+	return node{kList, func(*activation) any { return elems }}, nil
+}
+
+// function parses the global functions size, int, double and string.
+func (p *parser) function(name token) (node, error) {
+	args, err := p.arguments()
+	if err != nil {
+		return node{}, err
+	}
+	if len(args) != 1 {
+		return node{}, p.errorf(name, "%s takes one argument, got %d", name.text, len(args))
+	}
+	arg := args[0]
+	e := arg.eval
+	switch {
+	case name.text == "size":
+		return sizeOf(p, name, arg)
+	case name.text == "int" && arg.kind.numeric():
+		return node{kInt, func(a *activation) any {
+			if f, ok := e(a).(float64); ok {
+				return int64(f)
+			}
+			return e(a)
+		}}, nil
+	case name.text == "double" && arg.kind.numeric():
+		return node{kDouble, func(a *activation) any { return toFloat(e(a)) }}, nil
+	case name.text == "string" && arg.kind != kList:
+		return node{kString, func(a *activation) any { return fmt.Sprint(e(a)) }}, nil
+	}
+	return node{}, p.errorf(name, "%s does not accept %s", name.text, arg.kind)
+}
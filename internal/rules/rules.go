@@ -0,0 +1,117 @@
+// Package rules evaluates user-defined image rules written in a subset of
+// the Common Expression Language (CEL).
+//
+// Supported syntax: bool, int, double and string literals, lists of string
+// literals, the operators ! && || == != < <= > >= in + - *, the functions
+// size, int, double and string, the string methods startsWith, endsWith,
+// contains and matches (literal RE2 pattern), and the list macros
+// exists(x, pred) and all(x, pred). Ints and doubles compare and combine
+// freely. Expressions are type-checked when compiled, so evaluation never
+// fails.
+package rules
+
+import (
+	"fmt"
+	"time"
+)
+
+// Image holds the attributes a rule can inspect.
+type Image struct {
+	Repository string
+	Region     string
+	Digest     string
+	MediaType  string
+	Tags       []string
+	SizeBytes  int64
+	Pushed     time.Time
+	LastPull   time.Time // zero when never pulled
+}
+
+// Variables lists the variables available to expressions and their types.
+var Variables = map[string]string{
+	"repo":       "string",
+	"region":     "string",
+	"digest":     "string",
+	"media_type": "string",
+	"tags":       "list(string)",
+	"size_bytes": "int",
+	"size_mb":    "double",
+	"age_days":   "int",
+	"idle_days":  "int",
+}
+
+var variableKinds = map[string]kind{
+	"repo":       kString,
+	"region":     kString,
+	"digest":     kString,
+	"media_type": kString,
+	"tags":       kList,
+	"size_bytes": kInt,
+	"size_mb":    kDouble,
+	"age_days":   kInt,
+	"idle_days":  kInt,
+}
+
+// Program is a compiled boolean expression.
+type Program struct {
+	source string
+	root   node
+}
+
+// Compile parses and type-checks a boolean expression.
+func Compile(expression string) (*Program, error) {
+	toks, err := lex(expression)
+	if err != nil {
+		return nil, err
+	}
+	scope := make(map[string]kind, len(variableKinds))
+	for name, k := range variableKinds {
+		scope[name] = k
+	}
+	p := &parser{toks: toks, scope: scope}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tEOF {
+		return nil, p.errorf(t, "unexpected %s", describe(t))
+	}
+	if root.kind != kBool {
+		return nil, fmt.Errorf("expression must be bool, got %s", root.kind)
+	}
+	return &Program{source: expression, root: root}, nil
+}
+
+// String returns the expression source.
+func (p *Program) String() string { return p.source }
+
+// Match reports whether the image satisfies the expression at time now.
+func (p *Program) Match(img Image, now time.Time) bool {
+	tags := img.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	lastActivity := img.Pushed
+	if img.LastPull.After(lastActivity) {
+		lastActivity = img.LastPull
+	}
+	a := &activation{vars: map[string]any{
+		"repo":       img.Repository,
+		"region":     img.Region,
+		"digest":     img.Digest,
+		"media_type": img.MediaType,
+		"tags":       tags,
+		"size_bytes": img.SizeBytes,
+		"size_mb":    float64(img.SizeBytes) / (1024 * 1024),
+		"age_days":   days(now, img.Pushed),
+		"idle_days":  days(now, lastActivity),
+	}}
+	return p.root.eval(a).(bool)
+}
+
+func days(now, t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return int64(now.Sub(t).Hours() / 24)
+}
@@ -0,0 +1,98 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+func sampleImage() Image {
+	return Image{
+		Repository: "team/sandbox",
+		Region:     "us-east-1",
+		Digest:     "sha256:abc",
+		MediaType:  "application/vnd.oci.image.manifest.v1+json",
+		Tags:       []string{"v1.2.0", "latest"},
+		SizeBytes:  512 * 1024 * 1024,
+		Pushed:     now.AddDate(0, 0, -45),
+		LastPull:   now.AddDate(0, 0, -10),
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		{`repo.endsWith("/sandbox") && age_days > 30`, true},
+		{`repo.matches("^team/") && age_days > 60`, false},
+		{`"latest" in tags`, true},
+		{`"stable" in tags`, false},
+		{`tags.exists(t, t.startsWith("v1."))`, true},
+		{`tags.all(t, t.startsWith("v"))`, false},
+		{`size(tags) == 0`, false},
+		{`tags.size() == 2`, true},
+		{`size_mb >= 512 && size_bytes == 536870912`, true},
+		{`size_mb > 500.5 || false`, true},
+		{`idle_days == 10`, true},
+		{`!(region == "us-east-1")`, false},
+		{`media_type.contains("oci") && digest != ""`, true},
+		{`region in ["us-east-1", "eu-west-1"]`, true},
+		{`age_days * 2 - 10 > 79`, true},
+		{`double(age_days) > 44.5 && int(size_mb) == 512`, true},
+		{`repo + ":" + "x" == "team/sandbox:x"`, true},
+		{`string(age_days) == "45"`, true},
+		{`'single' == "single"`, true},
+	}
+	for _, tt := range tests {
+		p, err := Compile(tt.expr)
+		if err != nil {
+			t.Errorf("Compile(%s) error: %v", tt.expr, err)
+			continue
+		}
+		if got := p.Match(sampleImage(), now); got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestMatchUntaggedNeverPulled(t *testing.T) {
+	img := sampleImage()
+	img.Tags = nil
+	img.LastPull = time.Time{}
+	p, err := Compile(`size(tags) == 0 && idle_days == age_days && !tags.exists(t, t == "x")`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Match(img, now) {
+		t.Error("untagged image should match")
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{`age_days`, "must be bool"},
+		{`repo > 3`, "cannot compare"},
+		{`owner == "x"`, "undeclared reference"},
+		{`repo.matches(repo)`, "string literal pattern"},
+		{`repo.matches("(")`, "invalid pattern"},
+		{`tags.exists(t, t)`, "predicate must be bool"},
+		{`age_days > 3 &&`, "unexpected"},
+		{`repo == "x`, "unterminated string"},
+		{`age_days > 3 )`, "unexpected"},
+		{`repo.shout()`, "unknown method"},
+		{`1 in tags`, "in needs"},
+		{`t == "x" || tags.exists(t, t == "y")`, "undeclared reference"},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Compile(%s) error = %v, want %q", tt.expr, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+// Package runid generates the unique ID of a scan run. The ID is attached to
+// logs, progress events, reports, history records and archive entries so the
+// traces one audit leaves in several systems can be correlated.
+package runid
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
+// New returns a random RFC 4122 version 4 UUID.
+func New() string {
+	id, err := newFrom(rand.Reader)
+	if err != nil {
+		// crypto/rand does not fail on supported platforms.
+		panic(err)
+	}
+	return id
+}
+
+func newFrom(r io.Reader) (string, error) {
+	var b [16]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return "", fmt.Errorf("read random bytes: %w", err)
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
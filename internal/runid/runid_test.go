@@ -0,0 +1,33 @@
+package runid
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var uuidV4 = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNew(t *testing.T) {
+	a, b := New(), New()
+	if !uuidV4.MatchString(a) {
+		t.Errorf("New() = %q, want a version 4 UUID", a)
+	}
+	if a == b {
+		t.Errorf("New() returned %q twice", a)
+	}
+}
+
+func TestNewFrom(t *testing.T) {
+	id, err := newFrom(bytes.NewReader(bytes.Repeat([]byte{0xff}, 16)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "ffffffff-ffff-4fff-bfff-ffffffffffff"; id != want {
+		t.Errorf("newFrom() = %q, want %q", id, want)
+	}
+	if _, err := newFrom(strings.NewReader("short")); err == nil {
+		t.Error("newFrom() on a short reader: want error")
+	}
+}
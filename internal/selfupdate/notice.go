@@ -0,0 +1,40 @@
+package selfupdate
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CheckInterval is how long a cached latest-version lookup stays valid.
+const CheckInterval = 24 * time.Hour
+
+type checkCache struct {
+	CheckedAt time.Time `json:"checked_at"`
+	Latest    string    `json:"latest"`
+}
+
+// CachedLatest returns the latest release version, consulting GitHub at most
+// once per CheckInterval. The result is cached in cachePath.
+func (u *Updater) CachedLatest(ctx context.Context, cachePath string, now time.Time) (string, error) {
+	if data, err := os.ReadFile(cachePath); err == nil {
+		var c checkCache
+		if json.Unmarshal(data, &c) == nil && now.Sub(c.CheckedAt) < CheckInterval && c.Latest != "" {
+			return c.Latest, nil
+		}
+	}
+
+	rel, err := u.Latest(ctx)
+	if err != nil {
+		return "", err
+	}
+	c := checkCache{CheckedAt: now, Latest: rel.Version()}
+	if data, err := json.Marshal(c); err == nil {
+		if err := os.MkdirAll(filepath.Dir(cachePath), 0o700); err == nil {
+			_ = os.WriteFile(cachePath, data, 0o600)
+		}
+	}
+	return c.Latest, nil
+}
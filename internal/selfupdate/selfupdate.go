@@ -0,0 +1,328 @@
+// Package selfupdate checks GitHub releases for newer versions and replaces the
+// running binary after verifying the release checksum and optional signature.
+package selfupdate
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRepo is the GitHub repository releases are published to.
+	DefaultRepo = "ppiankov/ecrspectre"
+	// DefaultBaseURL is the GitHub API endpoint.
+	DefaultBaseURL = "https://api.github.com"
+
+	binaryName    = "ecrspectre"
+	checksumsName = "checksums.txt"
+	signatureName = "checksums.txt.sig"
+	maxAssetBytes = 200 << 20
+)
+
+// Release is a published GitHub release.
+type Release struct {
+	TagName string  `json:"tag_name"`
+	HTMLURL string  `json:"html_url"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Version returns the release version without the leading "v".
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+func (r *Release) asset(name string) (Asset, bool) {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return Asset{}, false
+}
+
+// Updater queries releases and installs them.
+type Updater struct {
+	HTTPClient *http.Client
+	BaseURL    string
+	Repo       string
+	// PublicKey, when set, requires checksums.txt to carry a valid Ed25519
+	// signature (checksums.txt.sig, base64).
+	PublicKey ed25519.PublicKey
+	GOOS      string
+	GOARCH    string
+}
+
+// New returns an Updater for the default repository and current platform.
+func New() *Updater {
+	return &Updater{
+		HTTPClient: &http.Client{Timeout: 60 * time.Second},
+		BaseURL:    DefaultBaseURL,
+		Repo:       DefaultRepo,
+		GOOS:       runtime.GOOS,
+		GOARCH:     runtime.GOARCH,
+	}
+}
+
+// Latest returns the latest published release.
+func (u *Updater) Latest(ctx context.Context) (*Release, error) {
+	body, err := u.get(ctx, fmt.Sprintf("%s/repos/%s/releases/latest", strings.TrimRight(u.BaseURL, "/"), u.Repo))
+	if err != nil {
+		return nil, fmt.Errorf("fetch latest release: %w", err)
+	}
+	var rel Release
+	if err := json.Unmarshal(body, &rel); err != nil {
+		return nil, fmt.Errorf("decode release: %w", err)
+	}
+	if rel.TagName == "" {
+		return nil, fmt.Errorf("latest release has no tag")
+	}
+	return &rel, nil
+}
+
+// ArchiveName returns the release archive name for a version on this platform,
+// matching the goreleaser name template.
+func (u *Updater) ArchiveName(version string) string {
+	ext := "tar.gz"
+	if u.GOOS == "windows" {
+		ext = "zip"
+	}
+	return fmt.Sprintf("%s_%s_%s_%s.%s", binaryName, version, u.GOOS, u.GOARCH, ext)
+}
+
+// Download fetches the release archive for this platform, verifies it against
+// the release checksums (and signature, if a public key is set), and returns
+// the extracted binary.
+func (u *Updater) Download(ctx context.Context, rel *Release) ([]byte, error) {
+	name := u.ArchiveName(rel.Version())
+	archiveAsset, ok := rel.asset(name)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no archive %s", rel.TagName, name)
+	}
+	sumsAsset, ok := rel.asset(checksumsName)
+	if !ok {
+		return nil, fmt.Errorf("release %s has no %s", rel.TagName, checksumsName)
+	}
+
+	sums, err := u.get(ctx, sumsAsset.URL)
+	if err != nil {
+		return nil, fmt.Errorf("download checksums: %w", err)
+	}
+	if u.PublicKey != nil {
+		sigAsset, ok := rel.asset(signatureName)
+		if !ok {
+			return nil, fmt.Errorf("release %s has no %s", rel.TagName, signatureName)
+		}
+		sig, err := u.get(ctx, sigAsset.URL)
+		if err != nil {
+			return nil, fmt.Errorf("download signature: %w", err)
+		}
+		if err := VerifySignature(sums, sig, u.PublicKey); err != nil {
+			return nil, err
+		}
+	}
+
+	archive, err := u.get(ctx, archiveAsset.URL)
+	if err != nil {
+		return nil, fmt.Errorf("download %s: %w", name, err)
+	}
+	if err := VerifyChecksum(sums, name, archive); err != nil {
+		return nil, err
+	}
+
+	if strings.HasSuffix(name, ".zip") {
+		return extractZip(archive)
+	}
+	return extractTarGz(archive)
+}
+
+// Install replaces the executable at exePath with binary. The new file is
+// written next to the old one and renamed over it so a failed write never
+// leaves a truncated binary.
+func Install(exePath string, binary []byte) error {
+	info, err := os.Stat(exePath)
+	if err != nil {
+		return fmt.Errorf("stat executable: %w", err)
+	}
+	dir := filepath.Dir(exePath)
+	tmp, err := os.CreateTemp(dir, ".ecrspectre-update-*")
+	if err != nil {
+		return fmt.Errorf("create temp binary: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer func() { _ = os.Remove(tmpPath) }()
+
+	if _, err := tmp.Write(binary); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("write temp binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("close temp binary: %w", err)
+	}
+	if err := os.Chmod(tmpPath, info.Mode().Perm()|0o111); err != nil {
+		return fmt.Errorf("chmod temp binary: %w", err)
+	}
+
+	// Windows cannot overwrite a running executable but can rename it.
+	old := exePath + ".old"
+	_ = os.Remove(old)
+	if err := os.Rename(exePath, old); err != nil {
+		return fmt.Errorf("move current binary aside: %w", err)
+	}
+	if err := os.Rename(tmpPath, exePath); err != nil {
+		_ = os.Rename(old, exePath)
+		return fmt.Errorf("install new binary: %w", err)
+	}
+	_ = os.Remove(old)
+	return nil
+}
+
+// VerifyChecksum checks data against the entry for name in a checksums.txt file.
+func VerifyChecksum(sums []byte, name string, data []byte) error {
+	sum := sha256.Sum256(data)
+	got := hex.EncodeToString(sum[:])
+	for _, line := range strings.Split(string(sums), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[1] == name {
+			if !strings.EqualFold(fields[0], got) {
+				return fmt.Errorf("checksum mismatch for %s", name)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("no checksum for %s", name)
+}
+
+// VerifySignature checks a base64 Ed25519 signature over the checksums file.
+func VerifySignature(sums, sig []byte, pub ed25519.PublicKey) error {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+	if !ed25519.Verify(pub, sums, raw) {
+		return fmt.Errorf("invalid signature on %s", checksumsName)
+	}
+	return nil
+}
+
+// Newer reports whether latest is a higher semantic version than current.
+// Development builds ("dev", commit hashes) never report an update.
+func Newer(current, latest string) bool {
+	cur, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+	lat, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	for i := range cur {
+		if lat[i] != cur[i] {
+			return lat[i] > cur[i]
+		}
+	}
+	return false
+}
+
+// IsRelease reports whether v is a semantic release version rather than a
+// development build.
+func IsRelease(v string) bool {
+	_, ok := parseVersion(v)
+	return ok
+}
+
+func parseVersion(v string) ([3]int, bool) {
+	var out [3]int
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "-")
+	parts := strings.Split(v, ".")
+	if len(parts) != 3 {
+		return out, false
+	}
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return out, false
+		}
+		out[i] = n
+	}
+	return out, true
+}
+
+func (u *Updater) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", binaryName)
+	resp, err := u.HTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: HTTP %d", url, resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, maxAssetBytes))
+}
+
+func extractTarGz(data []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("read archive: %w", err)
+		}
+		if filepath.Base(hdr.Name) == binaryName && hdr.Typeflag == tar.TypeReg {
+			return io.ReadAll(io.LimitReader(tr, maxAssetBytes))
+		}
+	}
+	return nil, fmt.Errorf("archive does not contain %s", binaryName)
+}
+
+func extractZip(data []byte) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("open archive: %w", err)
+	}
+	for _, f := range zr.File {
+		if filepath.Base(f.Name) == binaryName+".exe" {
+			rc, err := f.Open()
+			if err != nil {
+				return nil, fmt.Errorf("read archive: %w", err)
+			}
+			defer func() { _ = rc.Close() }()
+			return io.ReadAll(io.LimitReader(rc, maxAssetBytes))
+		}
+	}
+	return nil, fmt.Errorf("archive does not contain %s.exe", binaryName)
+}
@@ -0,0 +1,226 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewer(t *testing.T) {
+	tests := []struct {
+		current, latest string
+		want            bool
+	}{
+		{"1.2.3", "1.2.4", true},
+		{"1.2.3", "v1.3.0", true},
+		{"v1.2.3", "2.0.0", true},
+		{"1.2.3", "1.2.3", false},
+		{"1.10.0", "1.9.9", false},
+		{"dev", "1.0.0", false},
+		{"1.0.0", "garbage", false},
+		{"1.0.0-rc1", "1.0.1", true},
+	}
+	for _, tt := range tests {
+		if got := Newer(tt.current, tt.latest); got != tt.want {
+			t.Errorf("Newer(%q, %q) = %v, want %v", tt.current, tt.latest, got, tt.want)
+		}
+	}
+	if IsRelease("dev") || !IsRelease("v0.1.0") {
+		t.Error("IsRelease mismatch")
+	}
+}
+
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("archive")
+	sum := sha256.Sum256(data)
+	sums := []byte(hex.EncodeToString(sum[:]) + "  a.tar.gz\nffff  b.tar.gz\n")
+
+	if err := VerifyChecksum(sums, "a.tar.gz", data); err != nil {
+		t.Errorf("valid checksum: %v", err)
+	}
+	if err := VerifyChecksum(sums, "b.tar.gz", data); err == nil {
+		t.Error("expected mismatch")
+	}
+	if err := VerifyChecksum(sums, "c.tar.gz", data); err == nil {
+		t.Error("expected missing entry error")
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	sums := []byte("abc  file\n")
+	sig := []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, sums)))
+
+	if err := VerifySignature(sums, sig, pub); err != nil {
+		t.Errorf("valid signature: %v", err)
+	}
+	if err := VerifySignature([]byte("tampered"), sig, pub); err == nil {
+		t.Error("expected invalid signature")
+	}
+	if err := VerifySignature(sums, []byte("!!"), pub); err == nil {
+		t.Error("expected decode error")
+	}
+}
+
+// releaseServer serves a fake GitHub release for linux/amd64.
+func releaseServer(t *testing.T, binary []byte, priv ed25519.PrivateKey, corrupt bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var archive bytes.Buffer
+	gz := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gz)
+	_ = tw.WriteHeader(&tar.Header{Name: "ecrspectre", Mode: 0o755, Size: int64(len(binary)), Typeflag: tar.TypeReg})
+	_, _ = tw.Write(binary)
+	_ = tw.Close()
+	_ = gz.Close()
+
+	name := "ecrspectre_1.5.0_linux_amd64.tar.gz"
+	sum := sha256.Sum256(archive.Bytes())
+	sums := fmt.Sprintf("%s  %s\n", hex.EncodeToString(sum[:]), name)
+	if corrupt {
+		sums = strings.Repeat("0", 64) + "  " + name + "\n"
+	}
+
+	var releaseCalls atomic.Int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/ppiankov/ecrspectre/releases/latest":
+			releaseCalls.Add(1)
+			assets := []Asset{
+				{Name: name, URL: srv.URL + "/dl/" + name},
+				{Name: "checksums.txt", URL: srv.URL + "/dl/checksums.txt"},
+			}
+			if priv != nil {
+				assets = append(assets, Asset{Name: "checksums.txt.sig", URL: srv.URL + "/dl/checksums.txt.sig"})
+			}
+			_ = json.NewEncoder(w).Encode(Release{TagName: "v1.5.0", Assets: assets})
+		case "/dl/" + name:
+			_, _ = w.Write(archive.Bytes())
+		case "/dl/checksums.txt":
+			_, _ = w.Write([]byte(sums))
+		case "/dl/checksums.txt.sig":
+			_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(sums)))))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &releaseCalls
+}
+
+func testUpdater(srv *httptest.Server) *Updater {
+	u := New()
+	u.BaseURL = srv.URL
+	u.GOOS = "linux"
+	u.GOARCH = "amd64"
+	return u
+}
+
+func TestDownloadVerifiesAndExtracts(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(rand.Reader)
+	srv, _ := releaseServer(t, []byte("new-binary"), priv, false)
+	u := testUpdater(srv)
+	u.PublicKey = pub
+
+	rel, err := u.Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rel.Version() != "1.5.0" {
+		t.Errorf("Version() = %s", rel.Version())
+	}
+	bin, err := u.Download(context.Background(), rel)
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if string(bin) != "new-binary" {
+		t.Errorf("binary = %q", bin)
+	}
+}
+
+func TestDownloadRejectsBadChecksum(t *testing.T) {
+	srv, _ := releaseServer(t, []byte("new-binary"), nil, true)
+	u := testUpdater(srv)
+	rel, err := u.Latest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := u.Download(context.Background(), rel); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Errorf("expected checksum error, got %v", err)
+	}
+}
+
+func TestDownloadRequiresSignatureWhenKeySet(t *testing.T) {
+	pub, _, _ := ed25519.GenerateKey(rand.Reader)
+	srv, _ := releaseServer(t, []byte("new-binary"), nil, false)
+	u := testUpdater(srv)
+	u.PublicKey = pub
+	rel, _ := u.Latest(context.Background())
+	if _, err := u.Download(context.Background(), rel); err == nil {
+		t.Error("expected error for unsigned release")
+	}
+}
+
+func TestArchiveNameWindows(t *testing.T) {
+	u := &Updater{GOOS: "windows", GOARCH: "arm64"}
+	if got := u.ArchiveName("1.0.0"); got != "ecrspectre_1.0.0_windows_arm64.zip" {
+		t.Errorf("ArchiveName = %s", got)
+	}
+}
+
+func TestInstall(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "ecrspectre")
+	if err := os.WriteFile(exe, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := Install(exe, []byte("new")); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	got, _ := os.ReadFile(exe)
+	if string(got) != "new" {
+		t.Errorf("binary = %q, want new", got)
+	}
+	if _, err := os.Stat(exe + ".old"); !os.IsNotExist(err) {
+		t.Error("backup binary should be removed")
+	}
+}
+
+func TestCachedLatest(t *testing.T) {
+	srv, calls := releaseServer(t, []byte("x"), nil, false)
+	u := testUpdater(srv)
+	cache := filepath.Join(t.TempDir(), "check.json")
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 2; i++ {
+		latest, err := u.CachedLatest(context.Background(), cache, now)
+		if err != nil || latest != "1.5.0" {
+			t.Fatalf("CachedLatest = %q, %v", latest, err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("release API called %d times, want 1 (cached)", calls.Load())
+	}
+
+	if _, err := u.CachedLatest(context.Background(), cache, now.Add(CheckInterval+time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expired cache should refetch, calls = %d", calls.Load())
+	}
+}
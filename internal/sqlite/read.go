@@ -0,0 +1,243 @@
+package sqlite
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrNotDatabase is returned by Read for data that is not a SQLite 3 file.
+var ErrNotDatabase = errors.New("not a SQLite 3 database")
+
+// Read parses a database file written by Write or by SQLite itself, with the
+// rows of every table. Indexes are not supported: a file with one, such as
+// one created by hand or by a UNIQUE constraint, is rejected rather than
+// written back without it.
+func Read(data []byte) (*Database, error) {
+	if len(data) < 100 || string(data[:16]) != magic {
+		return nil, ErrNotDatabase
+	}
+	pageSize := int(binary.BigEndian.Uint16(data[16:]))
+	if pageSize == 1 {
+		pageSize = 65536
+	}
+	if pageSize < 512 || pageSize&(pageSize-1) != 0 {
+		return nil, fmt.Errorf("invalid page size %d", pageSize)
+	}
+	if enc := binary.BigEndian.Uint32(data[56:]); enc > 1 {
+		return nil, fmt.Errorf("text encoding %d is not supported, only UTF-8", enc)
+	}
+	r := &reader{data: data, pageSize: pageSize, usable: pageSize - int(data[20])}
+	db := &Database{UserVersion: binary.BigEndian.Uint32(data[60:])}
+
+	schema, err := r.table(1)
+	if err != nil {
+		return nil, fmt.Errorf("schema: %w", err)
+	}
+	for _, row := range schema {
+		obj, root, err := schemaObject(row.Values)
+		if err != nil {
+			return nil, err
+		}
+		switch obj.Type {
+		case "table":
+			if obj.Rows, err = r.table(root); err != nil {
+				return nil, fmt.Errorf("table %s: %w", obj.Name, err)
+			}
+		case "view", "trigger":
+		default:
+			return nil, fmt.Errorf("%s %s is not supported; drop it to write to this file", obj.Type, obj.Name)
+		}
+		db.Objects = append(db.Objects, obj)
+	}
+	return db, nil
+}
+
+// schemaObject decodes a row of the schema table: type, name, tbl_name,
+// rootpage and sql.
+func schemaObject(values []any) (Object, int, error) {
+	if len(values) < 5 {
+		return Object{}, 0, errors.New("malformed schema row")
+	}
+	typ, _ := values[0].(string)
+	name, _ := values[1].(string)
+	tbl, _ := values[2].(string)
+	root, _ := values[3].(int64)
+	sql, _ := values[4].(string)
+	return Object{Type: typ, Name: name, TableName: tbl, SQL: sql}, int(root), nil
+}
+
+type reader struct {
+	data     []byte
+	pageSize int
+	usable   int
+}
+
+func (r *reader) page(n int) ([]byte, error) {
+	if n < 1 || n*r.pageSize > len(r.data) {
+		return nil, fmt.Errorf("page %d is out of range", n)
+	}
+	return r.data[(n-1)*r.pageSize : n*r.pageSize], nil
+}
+
+// table reads the rows of the table b-tree rooted at page root, in row ID
+// order.
+func (r *reader) table(root int) ([]Row, error) {
+	var rows []Row
+	visited := make(map[int]bool)
+	var walk func(n int) error
+	walk = func(n int) error {
+		if visited[n] {
+			return fmt.Errorf("page %d is referenced twice", n)
+		}
+		visited[n] = true
+		p, err := r.page(n)
+		if err != nil {
+			return err
+		}
+		off := 0
+		if n == 1 {
+			off = 100
+		}
+		ncells := int(binary.BigEndian.Uint16(p[off+3:]))
+		switch p[off] {
+		case pageLeafTable:
+			for i := 0; i < ncells; i++ {
+				row, err := r.leafCell(p, int(binary.BigEndian.Uint16(p[off+8+2*i:])))
+				if err != nil {
+					return fmt.Errorf("page %d: %w", n, err)
+				}
+				rows = append(rows, row)
+			}
+		case pageInteriorTable:
+			for i := 0; i < ncells; i++ {
+				ptr := int(binary.BigEndian.Uint16(p[off+12+2*i:]))
+				if ptr+4 > len(p) {
+					return fmt.Errorf("page %d: cell out of range", n)
+				}
+				if err := walk(int(binary.BigEndian.Uint32(p[ptr:]))); err != nil {
+					return err
+				}
+			}
+			return walk(int(binary.BigEndian.Uint32(p[off+8:])))
+		case pageInteriorIndex, pageLeafIndex:
+			return fmt.Errorf("page %d is an index page", n)
+		default:
+			return fmt.Errorf("page %d has unknown type %#x", n, p[off])
+		}
+		return nil
+	}
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// leafCell decodes the table leaf cell at offset ptr of page p, following
+// its overflow chain.
+func (r *reader) leafCell(p []byte, ptr int) (Row, error) {
+	if ptr >= len(p) {
+		return Row{}, errors.New("cell out of range")
+	}
+	size, n := readVarint(p[ptr:])
+	if n == 0 {
+		return Row{}, errors.New("truncated cell")
+	}
+	ptr += n
+	id, n := readVarint(p[ptr:])
+	if n == 0 {
+		return Row{}, errors.New("truncated cell")
+	}
+	ptr += n
+	if size > uint64(len(r.data)) {
+		return Row{}, errors.New("payload larger than the file")
+	}
+	local := localPayload(int(size), r.usable)
+	if ptr+local > len(p) {
+		return Row{}, errors.New("truncated cell")
+	}
+	payload := append([]byte(nil), p[ptr:ptr+local]...)
+	if local < int(size) {
+		if ptr+local+4 > len(p) {
+			return Row{}, errors.New("truncated cell")
+		}
+		next := int(binary.BigEndian.Uint32(p[ptr+local:]))
+		for len(payload) < int(size) {
+			op, err := r.page(next)
+			if err != nil {
+				return Row{}, fmt.Errorf("overflow: %w", err)
+			}
+			chunk := min(int(size)-len(payload), r.usable-4)
+			payload = append(payload, op[4:4+chunk]...)
+			next = int(binary.BigEndian.Uint32(op))
+		}
+	}
+	values, err := decodeRecord(payload)
+	if err != nil {
+		return Row{}, fmt.Errorf("row %d: %w", int64(id), err)
+	}
+	return Row{ID: int64(id), Values: values}, nil
+}
+
+// decodeRecord decodes the values of a record.
+func decodeRecord(rec []byte) ([]any, error) {
+	hdrSize, n := readVarint(rec)
+	if n == 0 || hdrSize > uint64(len(rec)) {
+		return nil, errors.New("malformed record header")
+	}
+	var values []any
+	body := rec[hdrSize:]
+	for pos := n; pos < int(hdrSize); {
+		t, n := readVarint(rec[pos:int(hdrSize)])
+		if n == 0 {
+			return nil, errors.New("malformed record header")
+		}
+		pos += n
+		size := serialSize(t)
+		if size > len(body) {
+			return nil, errors.New("record shorter than its header")
+		}
+		v := body[:size]
+		body = body[size:]
+		switch {
+		case t == 0:
+			values = append(values, nil)
+		case t >= 1 && t <= 6:
+			// Sign-extend the big-endian two's complement integer.
+			x := int64(int8(v[0]))
+			for _, b := range v[1:] {
+				x = x<<8 | int64(b)
+			}
+			values = append(values, x)
+		case t == 7:
+			values = append(values, math.Float64frombits(binary.BigEndian.Uint64(v)))
+		case t == 8:
+			values = append(values, int64(0))
+		case t == 9:
+			values = append(values, int64(1))
+		case t >= 12 && t%2 == 0:
+			values = append(values, append([]byte(nil), v...))
+		case t >= 13:
+			values = append(values, string(v))
+		default:
+			return nil, fmt.Errorf("reserved serial type %d", t)
+		}
+	}
+	return values, nil
+}
+
+// serialSize is the size of a value of serial type t.
+func serialSize(t uint64) int {
+	switch {
+	case t <= 4:
+		return []int{0, 1, 2, 3, 4}[t]
+	case t == 5:
+		return 6
+	case t == 6, t == 7:
+		return 8
+	case t >= 12:
+		return int((t - 12) / 2)
+	}
+	return 0
+}
@@ -0,0 +1,406 @@
+// Package sqlite reads and writes SQLite 3 database files without a SQLite
+// engine. It covers what the findings database needs: rowid tables, views
+// and triggers in a UTF-8 database. A file is always written whole; adding
+// rows means reading the database, appending to its tables and writing it
+// again.
+//
+// The file format is described at https://www.sqlite.org/fileformat2.html.
+package sqlite
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// PageSize is the page size of the files Write produces.
+const PageSize = 4096
+
+// sqliteVersion is the SQLITE_VERSION_NUMBER recorded in written files.
+const sqliteVersion = 3046000
+
+const magic = "SQLite format 3\x00"
+
+// B-tree page types.
+const (
+	pageInteriorTable = 0x05
+	pageLeafTable     = 0x0d
+	pageInteriorIndex = 0x02
+	pageLeafIndex     = 0x0a
+)
+
+// Database is the content of a database file.
+type Database struct {
+	// UserVersion is the PRAGMA user_version of the file, which applications
+	// use to version their schema.
+	UserVersion uint32
+	Objects     []Object
+}
+
+// Object is one entry of the schema: a table with its rows, or a view or
+// trigger.
+type Object struct {
+	// Type is "table", "view" or "trigger".
+	Type string
+	Name string
+	// TableName is the table a trigger belongs to; tables and views name
+	// themselves.
+	TableName string
+	SQL       string
+	Rows      []Row
+}
+
+// Row is a table row. Values are nil, int64, float64, string or []byte, in
+// column order. A column declared INTEGER PRIMARY KEY holds nil: its value
+// is the row ID.
+type Row struct {
+	ID     int64
+	Values []any
+}
+
+// Table returns the table called name, or nil.
+func (db *Database) Table(name string) *Object {
+	for i := range db.Objects {
+		if db.Objects[i].Type == "table" && db.Objects[i].Name == name {
+			return &db.Objects[i]
+		}
+	}
+	return nil
+}
+
+// Write writes db as a database file. The rows of each table must be in
+// ascending row ID order.
+func Write(w io.Writer, db Database) error {
+	b := &builder{}
+	b.alloc() // page 1: the file header and the schema table
+
+	schema := make([]Row, 0, len(db.Objects))
+	for i, obj := range db.Objects {
+		var root int64
+		switch obj.Type {
+		case "table":
+			for j := 1; j < len(obj.Rows); j++ {
+				if obj.Rows[j].ID <= obj.Rows[j-1].ID {
+					return fmt.Errorf("table %s: row IDs are not ascending at %d", obj.Name, obj.Rows[j].ID)
+				}
+			}
+			page, err := b.table(obj.Rows)
+			if err != nil {
+				return fmt.Errorf("table %s: %w", obj.Name, err)
+			}
+			root = int64(page)
+		case "view", "trigger":
+		default:
+			return fmt.Errorf("%s %s: unsupported schema object", obj.Type, obj.Name)
+		}
+		tbl := obj.TableName
+		if tbl == "" {
+			tbl = obj.Name
+		}
+		schema = append(schema, Row{ID: int64(i + 1), Values: []any{obj.Type, obj.Name, tbl, root, obj.SQL}})
+	}
+
+	// The schema table is rooted in page 1, after the 100-byte file header,
+	// and has to fit in it.
+	cells := make([][]byte, len(schema))
+	for i, r := range schema {
+		cell, err := b.leafCell(r)
+		if err != nil {
+			return fmt.Errorf("schema: %w", err)
+		}
+		cells[i] = cell
+	}
+	if cellsSize(cells) > PageSize-100-8 {
+		return errors.New("schema does not fit in the first page")
+	}
+	writeLeaf(b.pages[0], 100, cells)
+	writeHeader(b.pages[0], len(b.pages), db.UserVersion)
+
+	for _, p := range b.pages {
+		if _, err := w.Write(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeHeader(p []byte, pages int, userVersion uint32) {
+	copy(p, magic)
+	binary.BigEndian.PutUint16(p[16:], PageSize)
+	p[18], p[19] = 1, 1 // legacy rollback journal
+	p[20] = 0           // reserved bytes per page
+	p[21], p[22], p[23] = 64, 32, 32
+	binary.BigEndian.PutUint32(p[24:], 1) // file change counter
+	binary.BigEndian.PutUint32(p[28:], uint32(pages))
+	binary.BigEndian.PutUint32(p[40:], 1) // schema cookie
+	binary.BigEndian.PutUint32(p[44:], 4) // schema format
+	binary.BigEndian.PutUint32(p[56:], 1) // UTF-8
+	binary.BigEndian.PutUint32(p[60:], userVersion)
+	binary.BigEndian.PutUint32(p[92:], 1) // version-valid-for, matching the change counter
+	binary.BigEndian.PutUint32(p[96:], sqliteVersion)
+}
+
+// builder lays out pages in memory; page n is pages[n-1].
+type builder struct {
+	pages [][]byte
+}
+
+func (b *builder) alloc() (int, []byte) {
+	p := make([]byte, PageSize)
+	b.pages = append(b.pages, p)
+	return len(b.pages), p
+}
+
+// maxChildren is how many children an interior page holds with keys of the
+// largest varint size: a 2-byte pointer and a 4-byte page number plus a
+// 9-byte key per cell, and the right-most pointer.
+const maxChildren = (PageSize-12)/15 + 1
+
+// child is a page of a b-tree level with the largest row ID under it.
+type child struct {
+	page  int
+	maxID int64
+}
+
+// table writes the b-tree of rows and returns its root page.
+func (b *builder) table(rows []Row) (int, error) {
+	var level []child
+	var cells [][]byte
+	flush := func(maxID int64) {
+		n, p := b.alloc()
+		writeLeaf(p, 0, cells)
+		level = append(level, child{n, maxID})
+		cells = nil
+	}
+	for i, r := range rows {
+		cell, err := b.leafCell(r)
+		if err != nil {
+			return 0, err
+		}
+		if len(cells) > 0 && cellsSize(cells)+len(cell)+2 > PageSize-8 {
+			flush(rows[i-1].ID)
+		}
+		cells = append(cells, cell)
+	}
+	if len(cells) > 0 || len(level) == 0 {
+		var maxID int64
+		if len(rows) > 0 {
+			maxID = rows[len(rows)-1].ID
+		}
+		flush(maxID)
+	}
+
+	// Interior levels: each page points to its children through one cell per
+	// child, keyed by the child's largest row ID, except for the last child,
+	// which is the page's right-most pointer. Children are spread evenly so
+	// that no page is left with a single child.
+	for len(level) > 1 {
+		groups := (len(level) + maxChildren - 1) / maxChildren
+		var next []child
+		for g, start := 0, 0; g < groups; g++ {
+			end := start + (len(level)-start)/(groups-g)
+			var cells [][]byte
+			for _, c := range level[start : end-1] {
+				cells = append(cells, interiorCell(c))
+			}
+			right := level[end-1]
+			n, p := b.alloc()
+			writeInterior(p, cells, right.page)
+			next = append(next, child{n, right.maxID})
+			start = end
+		}
+		level = next
+	}
+	return level[0].page, nil
+}
+
+// leafCell encodes a table leaf cell, spilling payload that does not fit in
+// the page to overflow pages.
+func (b *builder) leafCell(r Row) ([]byte, error) {
+	payload, err := encodeRecord(r.Values)
+	if err != nil {
+		return nil, err
+	}
+	cell := appendVarint(nil, uint64(len(payload)))
+	cell = appendVarint(cell, uint64(r.ID))
+	local := localPayload(len(payload), PageSize)
+	cell = append(cell, payload[:local]...)
+	if local == len(payload) {
+		return cell, nil
+	}
+
+	rest := payload[local:]
+	first, p := b.alloc()
+	for {
+		n := copy(p[4:], rest)
+		rest = rest[n:]
+		if len(rest) == 0 {
+			break
+		}
+		next, np := b.alloc()
+		binary.BigEndian.PutUint32(p, uint32(next))
+		p = np
+	}
+	return binary.BigEndian.AppendUint32(cell, uint32(first)), nil
+}
+
+// localPayload is how much of a table leaf payload of size n is stored in
+// the page itself, for a usable page size u.
+func localPayload(n, u int) int {
+	maxLocal := u - 35
+	if n <= maxLocal {
+		return n
+	}
+	minLocal := (u-12)*32/255 - 23
+	k := minLocal + (n-minLocal)%(u-4)
+	if k <= maxLocal {
+		return k
+	}
+	return minLocal
+}
+
+func interiorCell(c child) []byte {
+	cell := binary.BigEndian.AppendUint32(nil, uint32(c.page))
+	return appendVarint(cell, uint64(c.maxID))
+}
+
+func cellsSize(cells [][]byte) int {
+	n := 0
+	for _, c := range cells {
+		n += len(c) + 2
+	}
+	return n
+}
+
+// writeLeaf writes a table leaf page whose b-tree header starts at off.
+func writeLeaf(p []byte, off int, cells [][]byte) {
+	p[off] = pageLeafTable
+	writeCells(p, off, 8, cells)
+}
+
+func writeInterior(p []byte, cells [][]byte, right int) {
+	p[0] = pageInteriorTable
+	binary.BigEndian.PutUint32(p[8:], uint32(right))
+	writeCells(p, 0, 12, cells)
+}
+
+// writeCells stores cells from the end of the page downwards, with the cell
+// pointer array after the hdr-byte page header.
+func writeCells(p []byte, off, hdr int, cells [][]byte) {
+	content := len(p)
+	for i, c := range cells {
+		content -= len(c)
+		copy(p[content:], c)
+		binary.BigEndian.PutUint16(p[off+hdr+2*i:], uint16(content))
+	}
+	binary.BigEndian.PutUint16(p[off+3:], uint16(len(cells)))
+	// A content area starting at 65536 is recorded as 0; it never does with
+	// 4096-byte pages.
+	binary.BigEndian.PutUint16(p[off+5:], uint16(content))
+}
+
+// encodeRecord encodes values in the record format: a header of serial
+// types followed by the values.
+func encodeRecord(values []any) ([]byte, error) {
+	var types, body []byte
+	for _, v := range values {
+		switch v := v.(type) {
+		case nil:
+			types = appendVarint(types, 0)
+		case int64:
+			t, n := intSerialType(v)
+			types = appendVarint(types, t)
+			for i := n - 1; i >= 0; i-- {
+				body = append(body, byte(v>>(8*i)))
+			}
+		case float64:
+			types = appendVarint(types, 7)
+			body = binary.BigEndian.AppendUint64(body, math.Float64bits(v))
+		case string:
+			types = appendVarint(types, uint64(len(v))*2+13)
+			body = append(body, v...)
+		case []byte:
+			types = appendVarint(types, uint64(len(v))*2+12)
+			body = append(body, v...)
+		default:
+			return nil, fmt.Errorf("unsupported value type %T", v)
+		}
+	}
+	// The header size counts itself, so its varint length depends on it.
+	size := len(types) + 1
+	for varintLen(uint64(size)) != size-len(types) {
+		size = len(types) + varintLen(uint64(size))
+	}
+	rec := appendVarint(nil, uint64(size))
+	rec = append(rec, types...)
+	return append(rec, body...), nil
+}
+
+// intSerialType returns the smallest serial type holding v and its size.
+func intSerialType(v int64) (uint64, int) {
+	switch {
+	case v == 0:
+		return 8, 0
+	case v == 1:
+		return 9, 0
+	case v >= math.MinInt8 && v <= math.MaxInt8:
+		return 1, 1
+	case v >= math.MinInt16 && v <= math.MaxInt16:
+		return 2, 2
+	case v >= -1<<23 && v < 1<<23:
+		return 3, 3
+	case v >= math.MinInt32 && v <= math.MaxInt32:
+		return 4, 4
+	case v >= -1<<47 && v < 1<<47:
+		return 5, 6
+	}
+	return 6, 8
+}
+
+// appendVarint appends v as a SQLite varint: big-endian groups of 7 bits,
+// with a ninth byte of 8 bits for values above 56 bits.
+func appendVarint(b []byte, v uint64) []byte {
+	if v > 1<<56-1 {
+		var buf [9]byte
+		buf[8] = byte(v)
+		v >>= 8
+		for i := 7; i >= 0; i-- {
+			buf[i] = byte(v&0x7f) | 0x80
+			v >>= 7
+		}
+		return append(b, buf[:]...)
+	}
+	var buf [8]byte
+	i := len(buf) - 1
+	buf[i] = byte(v & 0x7f)
+	for v >>= 7; v > 0; v >>= 7 {
+		i--
+		buf[i] = byte(v&0x7f) | 0x80
+	}
+	return append(b, buf[i:]...)
+}
+
+func varintLen(v uint64) int {
+	return len(appendVarint(nil, v))
+}
+
+// readVarint decodes a varint, returning its value and length, or a length
+// of 0 when b is too short.
+func readVarint(b []byte) (uint64, int) {
+	var v uint64
+	for i := 0; i < 9; i++ {
+		if i >= len(b) {
+			return 0, 0
+		}
+		if i == 8 {
+			return v<<8 | uint64(b[i]), 9
+		}
+		v = v<<7 | uint64(b[i]&0x7f)
+		if b[i]&0x80 == 0 {
+			return v, i + 1
+		}
+	}
+	return v, 9
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	// Enough rows for two interior levels, with payloads that spill to
+	// overflow chains.
+	var rows []Row
+	for i := int64(1); i <= 60000; i++ {
+		var long any
+		if i%5000 == 0 {
+			long = strings.Repeat("x", int(i))
+		}
+		rows = append(rows, Row{ID: i, Values: []any{nil, fmt.Sprintf("name-%d", i), i * 1000003, -i, float64(i) / 4, long, []byte{0, 1}}})
+	}
+	db := Database{UserVersion: 3, Objects: []Object{
+		{Type: "table", Name: "t", SQL: "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT, n INTEGER, neg INTEGER, f REAL, long TEXT, b BLOB)", Rows: rows},
+		{Type: "table", Name: "empty", SQL: "CREATE TABLE empty (a TEXT)"},
+		{Type: "view", Name: "v", SQL: "CREATE VIEW v AS SELECT name FROM t"},
+	}}
+	var buf bytes.Buffer
+	if err := Write(&buf, db); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len()%PageSize != 0 {
+		t.Fatalf("file size %d is not a multiple of the page size", buf.Len())
+	}
+
+	got, err := Read(buf.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.UserVersion != 3 || len(got.Objects) != 3 || got.Objects[2].SQL != db.Objects[2].SQL {
+		t.Fatalf("database = %d, %+v", got.UserVersion, got.Objects[2])
+	}
+	if e := got.Table("empty"); e == nil || len(e.Rows) != 0 {
+		t.Errorf("empty table = %+v", e)
+	}
+	back := got.Table("t").Rows
+	if len(back) != len(rows) {
+		t.Fatalf("read %d rows, want %d", len(back), len(rows))
+	}
+	for i, r := range back {
+		if r.ID != rows[i].ID || fmt.Sprint(r.Values) != fmt.Sprint(rows[i].Values) {
+			t.Fatalf("row %d = %v, want %v", rows[i].ID, r.Values, rows[i].Values)
+		}
+	}
+}
+
+func TestRecord(t *testing.T) {
+	values := []any{nil, int64(0), int64(1), int64(-129), int64(1 << 40), int64(math.MinInt64), 2.5, "héllo", []byte{}}
+	rec, err := encodeRecord(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := decodeRecord(rec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(got) != fmt.Sprint(values) {
+		t.Errorf("decoded %v, want %v", got, values)
+	}
+	if _, err := encodeRecord([]any{3}); err == nil {
+		t.Error("encoding an int succeeded")
+	}
+
+	for _, v := range []uint64{0, 127, 128, 1<<56 - 1, 1 << 56, math.MaxUint64} {
+		b := appendVarint(nil, v)
+		if got, n := readVarint(b); got != v || n != len(b) {
+			t.Errorf("varint %d round-tripped to %d (%d of %d bytes)", v, got, n, len(b))
+		}
+	}
+}
+
+func TestReadRejects(t *testing.T) {
+	if _, err := Read([]byte("not a database")); err != ErrNotDatabase {
+		t.Errorf("Read(garbage) error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, Database{Objects: []Object{{Type: "index", Name: "i"}}}); err == nil {
+		t.Error("Write with an index succeeded")
+	}
+	if err := Write(&buf, Database{Objects: []Object{{Type: "table", Name: "t", Rows: []Row{{ID: 2}, {ID: 1}}}}}); err == nil {
+		t.Error("Write with unordered rows succeeded")
+	}
+}
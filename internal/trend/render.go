@@ -0,0 +1,66 @@
+package trend
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/ppiankov/ecrspectre/internal/report"
+)
+
+const dateFormat = "2006-01-02"
+
+// WriteText renders the period's totals, the waste of every provider and
+// the repositories that changed the most, then one row per scan.
+func WriteText(w io.Writer, t Trend) error {
+	if len(t.Scans) == 0 {
+		_, err := fmt.Fprintf(w, "No scans were recorded between %s and %s.\n", t.From.Format(dateFormat), t.To.Format(dateFormat))
+		return err
+	}
+	_, _ = fmt.Fprintf(w, "Waste trend %s – %s: %d scans\n\n", t.From.Format(dateFormat), t.To.Format(dateFormat), len(t.Scans))
+	_, _ = fmt.Fprintf(w, "Monthly waste: %s → %s (%s)\n", usd(t.StartWaste), usd(t.EndWaste), signedUSD(t.NetDelta()))
+	_, _ = fmt.Fprintf(w, "New findings: %d (%s/mo)\n", t.New, usd(t.NewWaste))
+	_, _ = fmt.Fprintf(w, "Resolved findings: %d (%s/mo)\n", t.Resolved, usd(t.ResolvedWaste))
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(tw, "\nPROVIDER\tSTART\tEND\tDELTA\tTREND")
+	for _, s := range t.Providers {
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", s.Provider, usd(s.Start()), usd(s.End()), signedUSD(s.Delta()), report.Sparkline(s.Values()))
+	}
+	if len(t.Repositories) > 0 {
+		_, _ = fmt.Fprintln(tw, "\nREPOSITORY\tSTART\tEND\tDELTA\tTREND")
+		for _, s := range t.Repositories {
+			_, _ = fmt.Fprintf(tw, "%s/%s\t%s\t%s\t%s\t%s\n", s.Provider, s.Repository, usd(s.Start()), usd(s.End()), signedUSD(s.Delta()), report.Sparkline(s.Values()))
+		}
+	}
+	_, _ = fmt.Fprintln(tw, "\nSCANNED\tPROVIDER\tFINDINGS\tWASTE/MO\tDELTA\tNEW\tRESOLVED")
+	for _, s := range t.Scans {
+		newCount, resolved := "-", "-"
+		if s.Compared {
+			newCount, resolved = fmt.Sprint(s.New), fmt.Sprint(s.Resolved)
+		}
+		_, _ = fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\t%s\t%s\n", s.Time.UTC().Format("2006-01-02 15:04"), s.Provider,
+			s.Findings, usd(s.MonthlyWaste), signedUSD(s.WasteDelta), newCount, resolved)
+	}
+	return tw.Flush()
+}
+
+// WriteJSON renders the trend as indented JSON.
+func WriteJSON(w io.Writer, t Trend) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		Trend
+		NetDelta float64 `json:"net_monthly_waste_delta"`
+	}{t, t.NetDelta()})
+}
+
+func usd(v float64) string { return fmt.Sprintf("$%.2f", v) }
+
+func signedUSD(v float64) string {
+	if v < 0 {
+		return fmt.Sprintf("-$%.2f", -v)
+	}
+	return fmt.Sprintf("+$%.2f", v)
+}
@@ -0,0 +1,257 @@
+// Package trend follows waste through the scan history: each scan's new and
+// resolved findings and net waste change, and the waste of every provider
+// and repository over time.
+package trend
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/history"
+)
+
+// DefaultTop is the number of repositories listed.
+const DefaultTop = 10
+
+// Scan is one scan of a target, compared with the target's previous scan.
+type Scan struct {
+	Time         time.Time `json:"time"`
+	Provider     string    `json:"provider"`
+	Target       string    `json:"target"`
+	Findings     int       `json:"findings"`
+	MonthlyWaste float64   `json:"monthly_waste"`
+	// WasteDelta is the change in monthly waste since the previous scan.
+	WasteDelta float64 `json:"waste_delta"`
+	// New and Resolved count the findings that appeared and disappeared
+	// since the previous scan, with their monthly waste. Compared is false
+	// when either scan predates per-finding history or there is no previous
+	// scan, leaving them zero.
+	Compared      bool    `json:"compared"`
+	New           int     `json:"new"`
+	Resolved      int     `json:"resolved"`
+	NewWaste      float64 `json:"new_waste"`
+	ResolvedWaste float64 `json:"resolved_waste"`
+}
+
+// Point is a monthly waste at a point in time.
+type Point struct {
+	Time         time.Time `json:"time"`
+	MonthlyWaste float64   `json:"monthly_waste"`
+}
+
+// Series is the waste of a provider or repository over the period.
+type Series struct {
+	Provider   string  `json:"provider"`
+	Repository string  `json:"repository,omitempty"`
+	Points     []Point `json:"points"`
+}
+
+// Start is the first waste of the series.
+func (s Series) Start() float64 { return s.Points[0].MonthlyWaste }
+
+// End is the last waste of the series.
+func (s Series) End() float64 { return s.Points[len(s.Points)-1].MonthlyWaste }
+
+// Delta is the change in waste over the series.
+func (s Series) Delta() float64 { return s.End() - s.Start() }
+
+// Values returns the waste of each point, for sparklines.
+func (s Series) Values() []float64 {
+	v := make([]float64, len(s.Points))
+	for i, p := range s.Points {
+		v[i] = p.MonthlyWaste
+	}
+	return v
+}
+
+// Trend is the scan history of a period.
+type Trend struct {
+	From  time.Time `json:"from"`
+	To    time.Time `json:"to"`
+	Scans []Scan    `json:"scans"`
+	// New, Resolved and their waste total the compared scans.
+	New           int     `json:"new"`
+	Resolved      int     `json:"resolved"`
+	NewWaste      float64 `json:"new_waste"`
+	ResolvedWaste float64 `json:"resolved_waste"`
+	// StartWaste and EndWaste total every target's waste before its first
+	// scan in the period (or at it, without an earlier scan) and at its last.
+	StartWaste float64 `json:"start_monthly_waste"`
+	EndWaste   float64 `json:"end_monthly_waste"`
+	// Providers holds the waste of each provider, summed over its targets
+	// after every scan. Repositories holds the repositories whose waste
+	// changed the most, largest change first.
+	Providers    []Series `json:"providers"`
+	Repositories []Series `json:"repositories"`
+}
+
+// NetDelta is the change in monthly waste over the period.
+func (t Trend) NetDelta() float64 { return t.EndWaste - t.StartWaste }
+
+// Build follows the history of every target (as returned by
+// history.LoadAll) between from and to. top limits the repositories listed;
+// 0 lists all that changed.
+func Build(all map[string][]history.Record, from, to time.Time, top int) Trend {
+	t := Trend{From: from, To: to}
+
+	targets := make([]string, 0, len(all))
+	for target := range all {
+		targets = append(targets, target)
+	}
+	sort.Strings(targets)
+
+	type scan struct {
+		target string
+		record history.Record
+	}
+	var window []scan
+	latest := make(map[string]float64)
+	providerOf := make(map[string]string)
+	baseline := make(map[string]bool) // providers with scans before from
+	var repos []*Series
+	for _, target := range targets {
+		var prev *history.Record
+		// series holds the target's repositories; a repository is at zero
+		// in the scans that do not list its waste.
+		series := make(map[string]*Series)
+		var times []time.Time
+		for i := range all[target] {
+			r := all[target][i]
+			if r.Timestamp.After(to) {
+				break
+			}
+			if !r.Timestamp.After(from) {
+				prev = &all[target][i]
+				continue
+			}
+			if len(times) == 0 {
+				base := r
+				if prev != nil {
+					base = *prev
+					baseline[r.Provider] = true
+					times = append(times, base.Timestamp)
+					for repo, w := range base.RepositoryWaste {
+						series[repo] = &Series{Provider: r.Provider, Repository: repo, Points: []Point{{base.Timestamp, w}}}
+					}
+				}
+				t.StartWaste += base.TotalMonthlyWaste
+				latest[target] = base.TotalMonthlyWaste
+				providerOf[target] = r.Provider
+			}
+			window = append(window, scan{target, r})
+			t.Scans = append(t.Scans, compare(target, prev, r))
+			for repo := range r.RepositoryWaste {
+				if series[repo] == nil {
+					series[repo] = &Series{Provider: r.Provider, Repository: repo}
+					for _, at := range times {
+						series[repo].Points = append(series[repo].Points, Point{at, 0})
+					}
+				}
+			}
+			for repo, s := range series {
+				s.Points = append(s.Points, Point{r.Timestamp, r.RepositoryWaste[repo]})
+			}
+			times = append(times, r.Timestamp)
+			prev = &all[target][i]
+		}
+		if len(times) == 0 {
+			continue
+		}
+		t.EndWaste += prev.TotalMonthlyWaste
+		for _, s := range series {
+			repos = append(repos, s)
+		}
+	}
+
+	sort.SliceStable(t.Scans, func(i, j int) bool { return t.Scans[i].Time.Before(t.Scans[j].Time) })
+	for _, s := range t.Scans {
+		t.New += s.New
+		t.Resolved += s.Resolved
+		t.NewWaste += s.NewWaste
+		t.ResolvedWaste += s.ResolvedWaste
+	}
+
+	// Each provider's series sums its targets' latest waste after every
+	// scan, starting from their baseline.
+	sort.SliceStable(window, func(i, j int) bool { return window[i].record.Timestamp.Before(window[j].record.Timestamp) })
+	providers := make(map[string]*Series)
+	for p := range baseline {
+		providers[p] = &Series{Provider: p, Points: []Point{{from, providerWaste(latest, providerOf, p)}}}
+	}
+	for _, s := range window {
+		latest[s.target] = s.record.TotalMonthlyWaste
+		p := s.record.Provider
+		if providers[p] == nil {
+			providers[p] = &Series{Provider: p}
+		}
+		providers[p].Points = append(providers[p].Points, Point{s.record.Timestamp, providerWaste(latest, providerOf, p)})
+	}
+	for _, s := range providers {
+		t.Providers = append(t.Providers, *s)
+	}
+	sort.Slice(t.Providers, func(i, j int) bool { return t.Providers[i].Provider < t.Providers[j].Provider })
+
+	for _, s := range repos {
+		if s.Delta() != 0 {
+			t.Repositories = append(t.Repositories, *s)
+		}
+	}
+	sort.Slice(t.Repositories, func(i, j int) bool {
+		a, b := t.Repositories[i], t.Repositories[j]
+		if math.Abs(a.Delta()) != math.Abs(b.Delta()) {
+			return math.Abs(a.Delta()) > math.Abs(b.Delta())
+		}
+		if a.Provider != b.Provider {
+			return a.Provider < b.Provider
+		}
+		return a.Repository < b.Repository
+	})
+	if top > 0 && len(t.Repositories) > top {
+		t.Repositories = t.Repositories[:top]
+	}
+	return t
+}
+
+// providerWaste sums the latest waste of the targets of provider.
+func providerWaste(latest map[string]float64, providerOf map[string]string, provider string) float64 {
+	total := 0.0
+	for target, w := range latest {
+		if providerOf[target] == provider {
+			total += w
+		}
+	}
+	return total
+}
+
+// compare describes scan r of target against its previous scan prev.
+func compare(target string, prev *history.Record, r history.Record) Scan {
+	s := Scan{
+		Time:         r.Timestamp,
+		Provider:     r.Provider,
+		Target:       target,
+		Findings:     r.TotalFindings,
+		MonthlyWaste: r.TotalMonthlyWaste,
+	}
+	if prev == nil {
+		return s
+	}
+	s.WasteDelta = r.TotalMonthlyWaste - prev.TotalMonthlyWaste
+	if prev.Findings == nil || r.Findings == nil {
+		return s
+	}
+	s.Compared = true
+	for key, w := range r.Findings {
+		if _, ok := prev.Findings[key]; !ok {
+			s.New++
+			s.NewWaste += w
+		}
+	}
+	for key, w := range prev.Findings {
+		if _, ok := r.Findings[key]; !ok {
+			s.Resolved++
+			s.ResolvedWaste += w
+		}
+	}
+	return s
+}
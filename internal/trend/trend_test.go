@@ -0,0 +1,157 @@
+package trend
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ppiankov/ecrspectre/internal/history"
+)
+
+var t0 = time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+
+func record(day int, provider string, repoWaste, findings map[string]float64) history.Record {
+	total := 0.0
+	for _, w := range repoWaste {
+		total += w
+	}
+	return history.Record{
+		Timestamp:         t0.AddDate(0, 0, day),
+		Provider:          provider,
+		TotalFindings:     len(findings),
+		TotalMonthlyWaste: total,
+		RepositoryWaste:   repoWaste,
+		Findings:          findings,
+	}
+}
+
+func sampleHistory() map[string][]history.Record {
+	return map[string][]history.Record{
+		"aaaa": {
+			// The baseline, before the period.
+			record(-3, "aws", map[string]float64{"api": 10, "ci": 40},
+				map[string]float64{"STALE_IMAGE|us-east-1|api@a": 10, "STALE_IMAGE|us-east-1|ci@b": 25, "UNTAGGED_IMAGE|us-east-1|ci@c": 15}),
+			record(10, "aws", map[string]float64{"api": 10, "ci": 5},
+				map[string]float64{"STALE_IMAGE|us-east-1|api@a": 10, "UNTAGGED_IMAGE|us-east-1|ci@d": 5}),
+			record(20, "aws", map[string]float64{"api": 10, "web": 8},
+				map[string]float64{"STALE_IMAGE|us-east-1|api@a": 10, "STALE_IMAGE|us-east-1|web@e": 8}),
+			// After the period.
+			record(40, "aws", map[string]float64{"api": 100}, nil),
+		},
+		"bbbb": {
+			// Recorded before per-finding history.
+			record(5, "gcp", map[string]float64{"images": 12}, nil),
+			record(15, "gcp", map[string]float64{"images": 9}, nil),
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	tr := Build(sampleHistory(), t0, t0.AddDate(0, 0, 30), DefaultTop)
+
+	if len(tr.Scans) != 4 {
+		t.Fatalf("scans = %d, want 4", len(tr.Scans))
+	}
+	if tr.StartWaste != 62 || tr.EndWaste != 27 || tr.NetDelta() != -35 {
+		t.Errorf("waste = %.0f -> %.0f (%.0f), want 62 -> 27 (-35)", tr.StartWaste, tr.EndWaste, tr.NetDelta())
+	}
+	if tr.New != 2 || tr.Resolved != 3 || tr.NewWaste != 13 || tr.ResolvedWaste != 45 {
+		t.Errorf("new/resolved = %d ($%.0f) / %d ($%.0f), want 2 ($13) / 3 ($45)", tr.New, tr.NewWaste, tr.Resolved, tr.ResolvedWaste)
+	}
+
+	if gcp := tr.Scans[0]; gcp.Compared || gcp.WasteDelta != 0 {
+		t.Errorf("first gcp scan = %+v, want no comparison", gcp)
+	}
+	aws := tr.Scans[1]
+	if aws.Target != "aaaa" || !aws.Compared || aws.New != 1 || aws.Resolved != 2 || aws.WasteDelta != -35 {
+		t.Errorf("first aws scan = %+v", aws)
+	}
+	if gcp := tr.Scans[2]; gcp.Compared || gcp.WasteDelta != -3 {
+		t.Errorf("second gcp scan = %+v, want delta -3 without finding comparison", gcp)
+	}
+
+	if len(tr.Providers) != 2 {
+		t.Fatalf("providers = %+v", tr.Providers)
+	}
+	if aws := tr.Providers[0]; aws.Provider != "aws" || aws.Start() != 50 || aws.End() != 18 || len(aws.Points) != 3 {
+		t.Errorf("aws = %+v", aws)
+	}
+	if gcp := tr.Providers[1]; gcp.Start() != 12 || gcp.End() != 9 {
+		t.Errorf("gcp = %+v", gcp)
+	}
+
+	// ci fell the most, then web appeared, then images fell; api is unchanged.
+	want := []string{"ci", "web", "images"}
+	if len(tr.Repositories) != len(want) {
+		t.Fatalf("repositories = %+v", tr.Repositories)
+	}
+	for i, repo := range want {
+		if tr.Repositories[i].Repository != repo {
+			t.Errorf("repositories[%d] = %s, want %s", i, tr.Repositories[i].Repository, repo)
+		}
+	}
+	if ci := tr.Repositories[0]; ci.Delta() != -40 || len(ci.Points) != 3 {
+		t.Errorf("ci = %+v, want delta -40 over 3 points", ci)
+	}
+	if web := tr.Repositories[1]; web.Start() != 0 || web.End() != 8 {
+		t.Errorf("web = %+v, want 0 -> 8", web)
+	}
+
+	if top := Build(sampleHistory(), t0, t0.AddDate(0, 0, 30), 1); len(top.Repositories) != 1 {
+		t.Errorf("top 1 lists %d repositories", len(top.Repositories))
+	}
+}
+
+func TestBuildEmpty(t *testing.T) {
+	tr := Build(sampleHistory(), t0.AddDate(1, 0, 0), t0.AddDate(1, 1, 0), DefaultTop)
+	if len(tr.Scans) != 0 || tr.StartWaste != 0 || len(tr.Providers) != 0 {
+		t.Errorf("trend = %+v, want empty", tr)
+	}
+	var buf bytes.Buffer
+	if err := WriteText(&buf, tr); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "No scans were recorded") {
+		t.Errorf("text = %q", buf.String())
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tr := Build(sampleHistory(), t0, t0.AddDate(0, 0, 30), DefaultTop)
+
+	var buf bytes.Buffer
+	if err := WriteText(&buf, tr); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"Monthly waste: $62.00 → $27.00 (-$35.00)",
+		"New findings: 2 ($13.00/mo)",
+		"Resolved findings: 3 ($45.00/mo)",
+		"aws/ci",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("text missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	if err := WriteJSON(&buf, tr); err != nil {
+		t.Fatal(err)
+	}
+	var got struct {
+		NetDelta  float64 `json:"net_monthly_waste_delta"`
+		Resolved  int     `json:"resolved"`
+		Providers []struct {
+			Provider string `json:"provider"`
+		} `json:"providers"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.NetDelta != -35 || got.Resolved != 3 || len(got.Providers) != 2 {
+		t.Errorf("json = %+v", got)
+	}
+}